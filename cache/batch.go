@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"context"
+	"errors"
+)
+
+// 批量命令类型
+const (
+	BatchSet    = "set"
+	BatchGet    = "get"
+	BatchDel    = "del"
+	BatchIncr   = "incr" // Delta为负数时为自减
+	BatchExpire = "expire"
+	BatchHSet   = "hset"
+	BatchHGet   = "hget"
+	BatchHDel   = "hdel"
+	BatchHIncr  = "hincr"
+)
+
+var errBatchNotExec = errors.New("Cache: Batch command is not executed")
+
+// Batcher 支持批量命令的缓存
+type Batcher interface {
+	Batch(isTx bool) *Batch
+}
+
+// BatchExecFunc 适配器执行批量命令的函数，执行后需要调用每个命令的SetResult
+//   参数
+//     ctx:  上下文
+//     cmds: 要执行的命令
+//     isTx: 是否事务模式
+//   返回
+//     第一个出错命令的错误信息，key不存在不算错误
+type BatchExecFunc func(ctx context.Context, cmds []*BatchCmd, isTx bool) error
+
+// Batch 批量命令，命令先加入队列，调用Exec时一次执行
+// 与Pipeliner不同，key前缀、序列化、压缩和加密与适配器的同名方法一致
+//   实例：
+//     b := c.(cache.Batcher).Batch(false)
+//     b.Set("k1", "v1", 60)
+//     get := b.Get("k2")
+//     incr := b.Incr("n1")
+//     err := b.Exec()
+//     var v2 string
+//     err, exist := get.Scan(&v2)
+//     n, err := incr.Int()
+type Batch struct {
+	isTx bool
+	exec BatchExecFunc
+	cmds []*BatchCmd
+}
+
+// NewBatch 新建一个批量命令，由适配器调用
+//   参数
+//     isTx: 是否事务模式
+//     exec: 适配器执行批量命令的函数
+//   返回
+//     批量命令对象
+func NewBatch(isTx bool, exec BatchExecFunc) *Batch {
+	return &Batch{isTx: isTx, exec: exec}
+}
+
+// add 加入一个命令
+func (b *Batch) add(cmd *BatchCmd) *BatchCmd {
+	cmd.err = errBatchNotExec
+	b.cmds = append(b.cmds, cmd)
+	return cmd
+}
+
+// Set 设置一个值，参数同Cache.Set
+func (b *Batch) Set(key string, val interface{}, expire int32, encode ...bool) *BatchCmd {
+	encode = append(encode, false)
+	return b.add(&BatchCmd{Op: BatchSet, Key: key, Val: val, Expire: expire, Encode: encode[0]})
+}
+
+// Get 获取一个值，执行后用BatchCmd.Scan取结果
+func (b *Batch) Get(key string) *BatchCmd {
+	return b.add(&BatchCmd{Op: BatchGet, Key: key})
+}
+
+// Del 删除一个值
+func (b *Batch) Del(key string) *BatchCmd {
+	return b.add(&BatchCmd{Op: BatchDel, Key: key})
+}
+
+// Incr 自增，执行后用BatchCmd.Int取结果
+func (b *Batch) Incr(key string, delta ...uint64) *BatchCmd {
+	delta = append(delta, 1)
+	return b.add(&BatchCmd{Op: BatchIncr, Key: key, Delta: int64(delta[0])})
+}
+
+// Decr 自减，执行后用BatchCmd.Int取结果
+func (b *Batch) Decr(key string, delta ...uint64) *BatchCmd {
+	delta = append(delta, 1)
+	return b.add(&BatchCmd{Op: BatchIncr, Key: key, Delta: -int64(delta[0])})
+}
+
+// Expire 设置过期时间，单位秒
+func (b *Batch) Expire(key string, expire int32) *BatchCmd {
+	return b.add(&BatchCmd{Op: BatchExpire, Key: key, Expire: expire})
+}
+
+// HSet 设置哈希表的值，参数同Cache.HSet
+func (b *Batch) HSet(key string, field string, val interface{}, expire int32) *BatchCmd {
+	return b.add(&BatchCmd{Op: BatchHSet, Key: key, Fields: []string{field}, Val: val, Expire: expire})
+}
+
+// HGet 获取哈希表的值，执行后用BatchCmd.Scan取结果
+func (b *Batch) HGet(key string, field string) *BatchCmd {
+	return b.add(&BatchCmd{Op: BatchHGet, Key: key, Fields: []string{field}})
+}
+
+// HDel 删除哈希表的值
+func (b *Batch) HDel(key string, fields ...string) *BatchCmd {
+	return b.add(&BatchCmd{Op: BatchHDel, Key: key, Fields: fields})
+}
+
+// HIncr 哈希表的值自增，执行后用BatchCmd.Int取结果
+func (b *Batch) HIncr(key, field string, delta ...uint64) *BatchCmd {
+	delta = append(delta, 1)
+	return b.add(&BatchCmd{Op: BatchHIncr, Key: key, Fields: []string{field}, Delta: int64(delta[0])})
+}
+
+// Len 返回队列中的命令数
+func (b *Batch) Len() int {
+	return len(b.cmds)
+}
+
+// Exec 执行队列中的所有命令，执行后清空队列
+//   参数
+//
+//   返回
+//     第一个出错命令的错误信息，key不存在不算错误
+func (b *Batch) Exec() error {
+	return b.ExecCtx(context.Background())
+}
+
+// ExecCtx 同Exec，ctx用于控制超时和取消
+func (b *Batch) ExecCtx(ctx context.Context) error {
+	if len(b.cmds) == 0 {
+		return nil
+	}
+
+	cmds := b.cmds
+	b.cmds = nil
+	return b.exec(ctx, cmds, b.isTx)
+}
+
+// BatchCmd 批量命令中的一个命令
+type BatchCmd struct {
+	Op     string      // 命令类型
+	Key    string      // key值，不含前缀
+	Fields []string    // 哈希表field值
+	Val    interface{} // 要设置的值
+	Delta  int64       // 自增的量
+	Expire int32       // 过期时间，单位秒
+	Encode bool        // 是否加密
+
+	data  []byte
+	val   int64
+	exist bool
+	err   error
+}
+
+// SetResult 设置命令的执行结果，由适配器调用
+//   参数
+//     data:  Get、HGet的结果，已解密和解压
+//     val:   Incr、HIncr等返回数字的结果
+//     exist: key是否存在
+//     err:   错误信息，go-redis的错误用RedisError转换
+//   返回
+//
+func (c *BatchCmd) SetResult(data []byte, val int64, exist bool, err error) {
+	c.data, c.val, c.exist, c.err = data, val, exist, RedisError(err)
+}
+
+// Err 返回命令的错误信息
+func (c *BatchCmd) Err() error {
+	return c.err
+}
+
+// Int 返回数字结果，如Incr后的值、Del删除的个数
+func (c *BatchCmd) Int() (int64, error) {
+	return c.val, c.err
+}
+
+// Scan 将Get、HGet的结果转换到val，与Cache.Get的返回一致
+//   参数
+//     val: 保存结果地址
+//   返回
+//     错误信息，是否存在
+func (c *BatchCmd) Scan(val interface{}) (error, bool) {
+	if c.err != nil || !c.exist {
+		return c.err, c.exist
+	}
+
+	err := ByteToInter(c.data, val)
+	if err != nil {
+		return err, true
+	}
+
+	return nil, true
+}
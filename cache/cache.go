@@ -1,38 +1,120 @@
 package cache
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v7"
 	"github.com/lixy529/gotools/utils"
+	"io"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
-	ENCODE_FLAG = "ENC1_"
-	ENCODE_LEN  = 5
+	ENCODE_FLAG      = "ENC1_" // 旧的AES-CBC加密标识，只用于解密
+	ENCODE2_FLAG     = "ENC2_" // AES-GCM加密标识
+	ENCODE_LEN       = 5
+	ENCODE2_HEAD_LEN = ENCODE_LEN + 4 + 12 // 加密标识+密钥ID+nonce
+)
+
+// 适配器名称
+const (
+	AdapterMemcache = "memcache"
+	AdapterRedism   = "redism"
+	AdapterRedisd   = "redisd"
+	AdapterRedisc   = "redisc"
+	AdapterRediss   = "rediss"
+	AdapterMemory   = "memory"
+)
+
+// TTL的特殊返回值，与redis一致
+const (
+	TTL_PERSIST   = time.Duration(-1) // key存在但没有过期时间
+	TTL_NOT_EXIST = time.Duration(-2) // key不存在
 )
 
 type BitCount struct {
 	Start, End int64
 }
 
+// XReadArgs XRead的参数
+type XReadArgs struct {
+	Streams []string      // 流的key和开始id，key在前id在后，如[]string{"k1", "k2", "0", "0"}，id为$时只读新消息
+	Count   int64         // 每个流最多返回的消息数，0表示不限制
+	Block   time.Duration // 没有消息时的阻塞时间，小于等于0时不阻塞
+}
+
+// XReadGroupArgs XReadGroup的参数
+type XReadGroupArgs struct {
+	Group    string        // 消费组
+	Consumer string        // 消费者
+	Streams  []string      // 流的key和开始id，key在前id在后，id为>时读未分配的消息，为0时读自己未确认的消息
+	Count    int64         // 每个流最多返回的消息数，0表示不限制
+	Block    time.Duration // 没有消息时的阻塞时间，小于等于0时不阻塞
+	NoAck    bool          // 是否不需要确认
+}
+
+// XMessage 流中的消息
+type XMessage struct {
+	ID     string                 // 消息id
+	Values map[string]interface{} // 消息内容
+}
+
+// XStream 从一个流读取的消息
+type XStream struct {
+	Stream   string     // 流的key，不含前缀
+	Messages []XMessage // 消息
+}
+
+// Version GetWithVersion返回的数据版本，用于CompareAndSwap
+type Version struct {
+	Key   string      // key值，不含前缀
+	Token interface{} // 版本标识，由适配器生成，memcache为带CAS唯一值的*memcache.Item，redis为数据的sha1
+}
+
 // Cache 所有缓存的接口
 type Cache interface {
 	Init(config string) error
+	AddHook(hook Hook)
 	Set(key string, val interface{}, expire int32, encode ...bool) error
 	Get(key string, val interface{}) (error, bool)
+	Lookup(key string, val interface{}) (bool, error)
 	Del(key string) error
 
+	// 条件写，Add只在key不存在时设置，Replace只在key存在时设置，CompareAndSwap只在版本未变化时设置
+	// CompareAndSwap的expire为0时保留key原来的过期时间，memcache读取不到过期时间，expire为0时key不再过期
+	Add(key string, val interface{}, expire int32, encode ...bool) (bool, error)
+	Replace(key string, val interface{}, expire int32, encode ...bool) (bool, error)
+	GetWithVersion(key string, val interface{}) (*Version, error)
+	CompareAndSwap(ver *Version, val interface{}, expire int32, encode ...bool) (bool, error)
+
 	MSet(mList map[string]interface{}, expire int32, encode ...bool) error
 	MGet(keys ...string) (map[string]interface{}, error)
 	MDel(keys ...string) error
 
 	Incr(key string, delta ...uint64) (int64, error)
 	Decr(key string, delta ...uint64) (int64, error)
+	IncrEx(key string, delta int64, expire int32) (int64, error)
 	IsExist(key string) (bool, error)
 	ClearAll() error
 
+	// 过期时间
+	TTL(key string) (time.Duration, error)
+	Expire(key string, expire time.Duration) (bool, error)
+	ExpireAt(key string, tm time.Time) (bool, error)
+	Persist(key string) (bool, error)
+	Touch(key string, expire int32) (bool, error)
+	GetEx(key string, val interface{}, expire int32) (error, bool)
+
 	// 哈希表操作(redis支持)
 	HSet(key string, field string, val interface{}, expire int32) (int64, error)
 	HGet(key string, field string, val interface{}) (error, bool)
@@ -62,10 +144,129 @@ type Cache interface {
 	PFAdd(key string, expire int32, vals ...interface{}) (int64, error)
 	PFCount(key string) (int64, error)
 
+	// 列表操作(redis支持)
+	LPush(key string, expire int32, vals ...interface{}) (int64, error)
+	RPush(key string, expire int32, vals ...interface{}) (int64, error)
+	LPop(key string, val interface{}) (error, bool)
+	BRPop(timeout int32, val interface{}, keys ...string) (string, error)
+	LRange(key string, start, stop int64) ([]interface{}, error)
+	LTrim(key string, start, stop int64) error
+
+	// 集合操作(redis支持)
+	SAdd(key string, expire int32, members ...interface{}) (int64, error)
+	SRem(key string, members ...interface{}) (int64, error)
+	SIsMember(key string, member interface{}) (bool, error)
+	SMembers(key string) ([]string, error)
+	SInter(keys ...string) ([]string, error)
+	SUnion(keys ...string) ([]string, error)
+
+	// 流操作(redis支持)
+	XAdd(key string, maxLen int64, expire int32, vals map[string]interface{}) (string, error)
+	XRead(args *XReadArgs) ([]XStream, error)
+	XGroupCreate(key, group, start string) error
+	XReadGroup(args *XReadGroupArgs) ([]XStream, error)
+	XAck(key, group string, ids ...string) (int64, error)
+
+	// lua脚本(redis支持)
+	Eval(script string, keys []string, args ...interface{}) (interface{}, error)
+	EvalSha(sha1 string, keys []string, args ...interface{}) (interface{}, error)
+	ScriptLoad(script string) (string, error)
+
 	// pipeline(redis支持)
 	Pipeline(isTx bool) Pipeliner
 }
 
+// ContextCache 支持context.Context的缓存接口
+// 每个方法与Cache接口的同名方法对应，ctx用于控制超时和取消
+type ContextCache interface {
+	Cache
+
+	SetCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) error
+	GetCtx(ctx context.Context, key string, val interface{}) (error, bool)
+	LookupCtx(ctx context.Context, key string, val interface{}) (bool, error)
+	DelCtx(ctx context.Context, key string) error
+
+	AddCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (bool, error)
+	ReplaceCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (bool, error)
+	GetWithVersionCtx(ctx context.Context, key string, val interface{}) (*Version, error)
+	CompareAndSwapCtx(ctx context.Context, ver *Version, val interface{}, expire int32, encode ...bool) (bool, error)
+
+	MSetCtx(ctx context.Context, mList map[string]interface{}, expire int32, encode ...bool) error
+	MGetCtx(ctx context.Context, keys ...string) (map[string]interface{}, error)
+	MDelCtx(ctx context.Context, keys ...string) error
+
+	IncrCtx(ctx context.Context, key string, delta ...uint64) (int64, error)
+	DecrCtx(ctx context.Context, key string, delta ...uint64) (int64, error)
+	IncrExCtx(ctx context.Context, key string, delta int64, expire int32) (int64, error)
+	IsExistCtx(ctx context.Context, key string) (bool, error)
+	ClearAllCtx(ctx context.Context) error
+
+	// 过期时间
+	TTLCtx(ctx context.Context, key string) (time.Duration, error)
+	ExpireCtx(ctx context.Context, key string, expire time.Duration) (bool, error)
+	ExpireAtCtx(ctx context.Context, key string, tm time.Time) (bool, error)
+	PersistCtx(ctx context.Context, key string) (bool, error)
+	TouchCtx(ctx context.Context, key string, expire int32) (bool, error)
+	GetExCtx(ctx context.Context, key string, val interface{}, expire int32) (error, bool)
+
+	// 哈希表操作(redis支持)
+	HSetCtx(ctx context.Context, key string, field string, val interface{}, expire int32) (int64, error)
+	HGetCtx(ctx context.Context, key string, field string, val interface{}) (error, bool)
+	HDelCtx(ctx context.Context, key string, fields ...string) error
+	HGetAllCtx(ctx context.Context, key string) (map[string]interface{}, error)
+	HMSetCtx(ctx context.Context, key string, fields map[string]interface{}, expire int32) error
+	HMGetCtx(ctx context.Context, key string, fields ...string) (map[string]interface{}, error)
+	HValsCtx(ctx context.Context, key string) ([]interface{}, error)
+	HIncrCtx(ctx context.Context, key, fields string, delta ...uint64) (int64, error)
+	HDecrCtx(ctx context.Context, key, fields string, delta ...uint64) (int64, error)
+
+	// 有序集合操作(redis支持)
+	ZSetCtx(ctx context.Context, key string, expire int32, val ...interface{}) (int64, error)
+	ZGetCtx(ctx context.Context, key string, start, stop int, withScores bool, isRev bool) ([]string, error)
+	ZDelCtx(ctx context.Context, key string, field ...string) (int64, error)
+	ZCardCtx(ctx context.Context, key string) (int64, error)
+	ZRemRangeByRankCtx(ctx context.Context, key string, start, end int64) (int64, error)
+	ZRemRangeByScoreCtx(ctx context.Context, key string, start, end string) (int64, error)
+	ZRemRangeByLexCtx(ctx context.Context, key string, start, end string) (int64, error)
+
+	// 位图操作(redis支持)
+	SetBitCtx(ctx context.Context, key string, offset int64, value int, expire int32) (int64, error)
+	GetBitCtx(ctx context.Context, key string, offset int64) (int64, error)
+	BitCountCtx(ctx context.Context, key string, bitCount *BitCount) (int64, error)
+
+	// HyperLogLog操作(redis支持)
+	PFAddCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error)
+	PFCountCtx(ctx context.Context, key string) (int64, error)
+
+	// 列表操作(redis支持)
+	LPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error)
+	RPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error)
+	LPopCtx(ctx context.Context, key string, val interface{}) (error, bool)
+	BRPopCtx(ctx context.Context, timeout int32, val interface{}, keys ...string) (string, error)
+	LRangeCtx(ctx context.Context, key string, start, stop int64) ([]interface{}, error)
+	LTrimCtx(ctx context.Context, key string, start, stop int64) error
+
+	// 集合操作(redis支持)
+	SAddCtx(ctx context.Context, key string, expire int32, members ...interface{}) (int64, error)
+	SRemCtx(ctx context.Context, key string, members ...interface{}) (int64, error)
+	SIsMemberCtx(ctx context.Context, key string, member interface{}) (bool, error)
+	SMembersCtx(ctx context.Context, key string) ([]string, error)
+	SInterCtx(ctx context.Context, keys ...string) ([]string, error)
+	SUnionCtx(ctx context.Context, keys ...string) ([]string, error)
+
+	// 流操作(redis支持)
+	XAddCtx(ctx context.Context, key string, maxLen int64, expire int32, vals map[string]interface{}) (string, error)
+	XReadCtx(ctx context.Context, args *XReadArgs) ([]XStream, error)
+	XGroupCreateCtx(ctx context.Context, key, group, start string) error
+	XReadGroupCtx(ctx context.Context, args *XReadGroupArgs) ([]XStream, error)
+	XAckCtx(ctx context.Context, key, group string, ids ...string) (int64, error)
+
+	// lua脚本(redis支持)
+	EvalCtx(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+	EvalShaCtx(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error)
+	ScriptLoadCtx(ctx context.Context, script string) (string, error)
+}
+
 // IJson 生成与解析json串接口，如果参数实现了此接口，则生成与解析json串就使用参数的函数
 type IJson interface {
 	MarshalJSON() ([]byte, error)
@@ -73,19 +274,101 @@ type IJson interface {
 }
 
 // Pipeliner redis管道
+// 直接使用redis命令，不会处理key前缀、序列化和加密，建议使用Batch
 type Pipeliner struct {
 	Pipe redis.Pipeliner
 }
 
+// Instance 创建Cache适配器实例的函数
+type Instance func() Cache
+
+var (
+	adapterLock sync.RWMutex
+	adapters    = make(map[string]Instance) // 已注册的适配器
+)
+
+// Adapters 已初始化的Cache对象，GetCache从这里获取
+// NewCache和SetCache会加锁写入，直接读写时需要自己保证并发安全，建议使用SetCache
 var Adapters = make(map[string]Cache)
 
-// GetCache 获取一个Cache适配器
-// 如果adapterName返回第一个找到的适配器，这个顺序是不确定的，一般只有一个适配器时不需要传值
+// Register 注册一个Cache适配器，一般在适配器包的init函数里调用
+// 名称重复或instance为nil时会panic
 //   参数
-//     adapterName: 适配器名称
+//     name:     适配器名称
+//     instance: 创建适配器实例的函数
+//   返回
+//
+func Register(name string, instance Instance) {
+	adapterLock.Lock()
+	defer adapterLock.Unlock()
+
+	if instance == nil {
+		panic("Cache: Register adapter is nil")
+	}
+	if _, dup := adapters[name]; dup {
+		panic("Cache: Register called twice for adapter " + name)
+	}
+	adapters[name] = instance
+}
+
+// NewCache 新建一个Cache对象并初始化，每次调用都返回一个新的实例
+// 适配器名称下还没有Cache对象时，新建的对象同时保存到Adapters，可以用GetCache(adapterName)获取，需要替换时使用SetCache
+// 适配器包需要先导入，如: import _ "github.com/lixy529/gotools/cache/redis/redism"
+//   参数
+//     adapterName: 适配器名称，如memcache、redism、redisd、redisc、memory
+//     config:      适配器的配置json串
+//   返回
+//     成功时返回Cache对象，失败返回错误信息
+func NewCache(adapterName, config string) (Cache, error) {
+	adapterLock.RLock()
+	instance, ok := adapters[adapterName]
+	adapterLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Cache: unknown adapter name %q (forgotten import?)", adapterName)
+	}
+
+	adapter := instance()
+	err := adapter.Init(config)
+	if err != nil {
+		return nil, err
+	}
+
+	adapterLock.Lock()
+	if _, ok := Adapters[adapterName]; !ok {
+		Adapters[adapterName] = adapter
+	}
+	adapterLock.Unlock()
+
+	return adapter, nil
+}
+
+// SetCache 保存一个已初始化的Cache对象，供GetCache获取
+//   参数
+//     name:    名称
+//     adapter: Cache对象
+//   返回
+//
+func SetCache(name string, adapter Cache) {
+	adapterLock.Lock()
+	defer adapterLock.Unlock()
+
+	if adapter == nil {
+		delete(Adapters, name)
+		return
+	}
+	Adapters[name] = adapter
+}
+
+// GetCache 获取一个通过NewCache或SetCache保存的Cache对象
+// 如果adapterName返回第一个找到的对象，这个顺序是不确定的，一般只有一个对象时不需要传值
+//   参数
+//     adapterName: 名称
 //   返回
 //     成功时返回Cache对象，失败返回错误信息
 func GetCache(adapterName ...string) (Cache, error) {
+	adapterLock.RLock()
+	defer adapterLock.RUnlock()
+
 	if len(adapterName) == 0 {
 		for _, adapter := range Adapters {
 			return adapter, nil
@@ -102,83 +385,199 @@ func GetCache(adapterName ...string) (Cache, error) {
 	return adapter, nil
 }
 
-// Encode 加密数据
+// ParseEncodeKeys 解析配置里的加密密钥，多个密钥用逗号分割
+//   参数
+//     keys: 配置的加密密钥，如"newkey,oldkey"
+//   返回
+//     密钥列表，第一个用于加密，全部用于解密
+func ParseEncodeKeys(keys string) [][]byte {
+	var list [][]byte
+	for _, key := range strings.Split(keys, ",") {
+		key = strings.TrimSpace(key)
+		if key != "" {
+			list = append(list, []byte(key))
+		}
+	}
+	return list
+}
+
+// aesKey 与utils.AesEncode一致，不足16位后面补x，超过16位截取到16的倍数
+func aesKey(key []byte) []byte {
+	l := len(key)
+	if l < 16 {
+		return append(append([]byte{}, key...), bytes.Repeat([]byte("x"), 16-l)...)
+	} else if l%16 != 0 {
+		return key[:l/16*16]
+	}
+	return key
+}
+
+// keyId 密钥ID，取HMAC-SHA256(密钥, "key-id")的前4字节
+// 不能使用密钥本身的校验值，否则密文里的ID可以用来离线暴力破解密钥
+func keyId(key []byte) uint32 {
+	mac := hmac.New(sha256.New, aesKey(key))
+	mac.Write([]byte("key-id"))
+	return binary.BigEndian.Uint32(mac.Sum(nil))
+}
+
+// gcmOpen 用指定密钥解密ENC2_格式的数据
+func gcmOpen(data, key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, data[ENCODE_LEN+4:ENCODE2_HEAD_LEN], data[ENCODE2_HEAD_LEN:], data[:ENCODE_LEN+4])
+}
+
+// newGCM 创建AES-GCM对象
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(aesKey(key))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encode 加密数据，使用AES-GCM，格式为ENC2_+4字节密钥ID+12字节随机nonce+密文
 //   参数
 //     data: 要加密的数据
-//     key:  加密密钥
+//     keys: 加密密钥，使用第一个密钥加密
 //   返回
 //     成功返回加密串，失败返回错误信息
-func Encode(data, key []byte) ([]byte, error) {
-	encodeText, err := utils.AesEncode(data, key)
+func Encode(data []byte, keys ...[]byte) ([]byte, error) {
+	if len(keys) == 0 || len(keys[0]) == 0 {
+		return nil, errors.New("Cache: Encode error, encode key is empty")
+	}
+
+	gcm, err := newGCM(keys[0])
 	if err != nil {
 		return nil, fmt.Errorf("Cache: Encode error, %s", err.Error())
 	}
-	encode := []byte(ENCODE_FLAG)
-	encode = append(encode, encodeText...)
-	return encode, nil
+
+	encode := make([]byte, ENCODE2_HEAD_LEN, ENCODE2_HEAD_LEN+len(data)+gcm.Overhead())
+	copy(encode, ENCODE2_FLAG)
+	binary.BigEndian.PutUint32(encode[ENCODE_LEN:], keyId(keys[0]))
+	nonce := encode[ENCODE_LEN+4 : ENCODE2_HEAD_LEN]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("Cache: Encode error, %s", err.Error())
+	}
+
+	return gcm.Seal(encode, nonce, data, encode[:ENCODE_LEN+4]), nil
 }
 
-// Decode 解密数据
+// Decode 解密数据，支持ENC2_和旧的ENC1_格式，没有加密标识的数据原样返回
+// ENC2_按数据头的密钥ID选择密钥，没有匹配的ID时依次尝试每个密钥，ENC1_依次尝试每个密钥
 //   参数
 //     data: 要解密的数据
-//     key:  解密密钥
+//     keys: 解密密钥，可以传多个，用于密钥轮换
 //   返回
-//     成功返回加密串，失败返回错误信息
-func Decode(data, key []byte) ([]byte, error) {
+//     成功返回解密串，失败返回ErrDecode类型的错误
+func Decode(data []byte, keys ...[]byte) ([]byte, error) {
 	if len(data) < ENCODE_LEN {
 		return data, nil
 	}
 
-	encodeFlag := data[:ENCODE_LEN]
-	if string(encodeFlag) == ENCODE_FLAG {
-		decode, err := utils.AesDecode(data[ENCODE_LEN:], []byte(key))
-		if err != nil {
-			return nil, fmt.Errorf("Cache: Decode error, %s", err.Error())
+	switch string(data[:ENCODE_LEN]) {
+	case ENCODE2_FLAG:
+		if len(data) < ENCODE2_HEAD_LEN {
+			return nil, NewError(ErrDecode, "Cache: Decode error, data is too short")
+		}
+		id := binary.BigEndian.Uint32(data[ENCODE_LEN:])
+		for _, key := range keys {
+			if len(key) == 0 || keyId(key) != id {
+				continue
+			}
+			decode, err := gcmOpen(data, key)
+			if err != nil {
+				return nil, WrapError(ErrDecode, fmt.Errorf("Cache: Decode error, %s", err.Error()))
+			}
+			return decode, nil
+		}
+
+		// 旧版本的密钥ID，GCM有校验，解密成功即密钥正确
+		for _, key := range keys {
+			if len(key) == 0 {
+				continue
+			}
+			if decode, err := gcmOpen(data, key); err == nil {
+				return decode, nil
+			}
+		}
+		return nil, WrapError(ErrDecode, fmt.Errorf("Cache: Decode error, no key for key id %08x", id))
+	case ENCODE_FLAG:
+		text := data[ENCODE_LEN:]
+		if len(text) == 0 || len(text)%aes.BlockSize != 0 {
+			return nil, NewError(ErrDecode, "Cache: Decode error, invalid data length")
+		}
+		for _, key := range keys {
+			if len(key) == 0 {
+				continue
+			}
+			decode, err := utils.AesDecode(text, key)
+			if err != nil {
+				return nil, WrapError(ErrDecode, fmt.Errorf("Cache: Decode error, %s", err.Error()))
+			}
+			// CBC没有校验，重新加密比较以判断填充是否正确，即密钥是否匹配
+			if len(keys) == 1 {
+				return decode, nil
+			} else if check, err := utils.AesEncode(decode, key); err == nil && bytes.Equal(check, text) {
+				return decode, nil
+			}
 		}
-		return decode, nil
+		return nil, NewError(ErrDecode, "Cache: Decode error, no key matched")
 	}
+
 	return data, nil
 }
 
 // InterToByte 将interface{}类型转成[]byte
+// 字符串和数字直接转换，其它类型使用serializer序列化，不传或为json时使用json
 //   参数
-//     src: 要转换的数据
+//     src:        要转换的数据
+//     serializer: 序列化，不传时使用json
 //   返回
 //     转换后的数据，错误信息
-func InterToByte(src interface{}) ([]byte, error) {
-	var data []byte
-	var err error
+func InterToByte(src interface{}, serializer ...Serializer) ([]byte, error) {
 	if str, ok := src.(string); ok {
-		data = []byte(str)
+		return []byte(str), nil
 	} else if str, ok := src.(*string); ok {
-		data = []byte(*str)
-	} else if inter, ok := src.(IJson); ok {
-		data, err = inter.MarshalJSON()
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		data, err = json.Marshal(src)
-		if err != nil {
-			return nil, err
-		}
+		return []byte(*str), nil
 	}
-	return data, nil
+
+	if len(serializer) == 0 || serializer[0] == nil || serializer[0].Name() == SerializerJson || isPlain(src) {
+		return JsonSerializer{}.Marshal(src)
+	}
+
+	// 加上序列化头信息
+	s := serializer[0]
+	data, err := s.Marshal(src)
+	if err != nil {
+		return nil, err
+	}
+	head := append([]byte(SERIALIZE_FLAG), s.Id())
+	return append(head, data...), nil
 }
 
 // ByteToInter 将[]byte类型转成interface{}
+// 数据有序列化头信息时按头信息选择序列化，否则使用json
 //   参数
 //     src: 要转换的数据
 //     dst: 转换后的数据
 //   返回
-//     转换后的数据，错误信息
+//     转换失败返回ErrDecode类型的错误
 func ByteToInter(src []byte, dst interface{}) error {
 	if str, ok := dst.(*string); ok {
 		*str = string(src)
 		return nil
-	} else if inter, ok := dst.(IJson); ok {
-		return inter.UnmarshalJSON(src)
 	}
 
-	return json.Unmarshal(src, dst)
+	if len(src) >= SERIALIZE_LEN && string(src[:SERIALIZE_LEN-1]) == SERIALIZE_FLAG {
+		s, ok := serializerById(src[SERIALIZE_LEN-1])
+		if !ok {
+			return NewError(ErrDecode, fmt.Sprintf("Cache: unknown serializer id %q", src[SERIALIZE_LEN-1]))
+		}
+		return WrapError(ErrDecode, s.Unmarshal(src[SERIALIZE_LEN:], dst))
+	}
+
+	return WrapError(ErrDecode, JsonSerializer{}.Unmarshal(src, dst))
 }
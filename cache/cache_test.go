@@ -1,8 +1,11 @@
 package cache
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"github.com/lixy529/gotools/utils"
+	"hash/crc32"
 	"testing"
 )
 
@@ -27,6 +30,74 @@ func TestEncode(t *testing.T) {
 
 }
 
+// TestEncodeRotate 密钥轮换测试
+func TestEncodeRotate(t *testing.T) {
+	src := "HelloWorld!"
+	oldKey := []byte("abcdefghij123456")
+	newKey := []byte("1234567890abcdef")
+
+	// 旧密钥加密的数据，轮换后仍能解密
+	enc, err := Encode([]byte(src), oldKey)
+	if err != nil {
+		t.Errorf("Encode failed. err: %s.", err.Error())
+		return
+	} else if string(enc[:ENCODE_LEN]) != ENCODE2_FLAG {
+		t.Errorf("Encode failed. Got flag %s, expected %s.", string(enc[:ENCODE_LEN]), ENCODE2_FLAG)
+		return
+	}
+	keys := ParseEncodeKeys(string(newKey) + "," + string(oldKey))
+	dec, err := Decode(enc, keys...)
+	if err != nil || string(dec) != src {
+		t.Errorf("Decode failed. Got %s, err: %v.", string(dec), err)
+		return
+	}
+
+	// 相同数据每次加密结果不同
+	enc2, _ := Encode([]byte(src), keys...)
+	enc3, _ := Encode([]byte(src), keys...)
+	if string(enc2) == string(enc3) {
+		t.Error("Encode failed. nonce is not random.")
+	}
+
+	// 没有对应的密钥
+	_, err = Decode(enc, newKey)
+	if err == nil {
+		t.Error("Decode failed. expected no key error.")
+	}
+
+	// 数据被篡改
+	enc[len(enc)-1] ^= 0xff
+	_, err = Decode(enc, keys...)
+	if err == nil {
+		t.Error("Decode failed. expected authentication error.")
+	}
+
+	// 旧的ENC1_格式
+	text, _ := utils.AesEncode([]byte(src), oldKey)
+	enc1 := append([]byte(ENCODE_FLAG), text...)
+	dec, err = Decode(enc1, keys...)
+	if err != nil || string(dec) != src {
+		t.Errorf("Decode ENC1 failed. Got %s, err: %v.", string(dec), err)
+	}
+
+	// 密钥ID不能是密钥的校验值
+	enc, _ = Encode([]byte(src), oldKey)
+	id := binary.BigEndian.Uint32(enc[ENCODE_LEN:])
+	if id == crc32.ChecksumIEEE(aesKey(oldKey)) {
+		t.Error("Encode failed. key id is the crc32 of the key.")
+	}
+
+	// 旧版本crc32密钥ID的数据仍能解密
+	gcm, _ := newGCM(oldKey)
+	head := append([]byte(ENCODE2_FLAG), make([]byte, 16)...)
+	binary.BigEndian.PutUint32(head[ENCODE_LEN:], crc32.ChecksumIEEE(aesKey(oldKey)))
+	old := gcm.Seal(head, head[ENCODE_LEN+4:], []byte(src), head[:ENCODE_LEN+4])
+	dec, err = Decode(old, keys...)
+	if err != nil || string(dec) != src {
+		t.Errorf("Decode old key id failed. Got %s, err: %v.", string(dec), err)
+	}
+}
+
 // TestTo InterToByte和ByteToInter测试
 func TestTo(t *testing.T) {
 	fmt.Println("string start >>>")
@@ -176,3 +247,158 @@ func (this *user2) UnmarshalJSON(data []byte) error {
 	this.Name = name.(string)
 	return nil
 }
+
+// TestSerializer 序列化测试
+func TestSerializer(t *testing.T) {
+	type user3 struct {
+		Uid  int32
+		Name string
+		Tags []string
+	}
+	src := user3{Uid: 100, Name: "Diego", Tags: []string{"a", "b"}}
+
+	for _, name := range []string{SerializerJson, SerializerGob, SerializerMsgpack} {
+		fmt.Println(name + " start >>>")
+		s, err := GetSerializer(name)
+		if err != nil {
+			t.Errorf("GetSerializer failed. err: %s.", err.Error())
+			return
+		}
+
+		b, err := InterToByte(src, s)
+		if err != nil {
+			t.Errorf("InterToByte failed. err: %s.", err.Error())
+			return
+		}
+		if name != SerializerJson && string(b[:SERIALIZE_LEN]) != SERIALIZE_FLAG+string(s.Id()) {
+			t.Errorf("InterToByte failed. header is %q.", b[:SERIALIZE_LEN])
+			return
+		}
+
+		// 读取时不需要知道写入时的序列化
+		dst := user3{}
+		err = ByteToInter(b, &dst)
+		if err != nil {
+			t.Errorf("ByteToInter failed. err: %s.", err.Error())
+			return
+		} else if dst.Uid != src.Uid || dst.Name != src.Name || len(dst.Tags) != 2 || dst.Tags[1] != "b" {
+			t.Errorf("ByteToInter failed. Got %v, expected %v.", dst, src)
+			return
+		}
+
+		// 字符串和数字不序列化
+		b, _ = InterToByte(100, s)
+		if string(b) != "100" {
+			t.Errorf("InterToByte failed. Got %s, expected 100.", string(b))
+		}
+		b, _ = InterToByte("HelloWorld!", s)
+		if string(b) != "HelloWorld!" {
+			t.Errorf("InterToByte failed. Got %s, expected HelloWorld!.", string(b))
+		}
+	}
+
+	// raw
+	s, _ := GetSerializer(SerializerRaw)
+	b, err := InterToByte([]byte{0, 1, 2}, s)
+	if err != nil {
+		t.Errorf("InterToByte failed. err: %s.", err.Error())
+		return
+	}
+	var raw []byte
+	err = ByteToInter(b, &raw)
+	if err != nil || len(raw) != 3 || raw[2] != 2 {
+		t.Errorf("ByteToInter failed. Got %v, expected [0 1 2].", raw)
+	}
+	_, err = InterToByte(src, s)
+	if err == nil {
+		t.Error("InterToByte failed. raw serializer should not support struct.")
+	}
+
+	_, err = GetSerializer("xml")
+	if err == nil {
+		t.Error("GetSerializer failed. xml should be unknown.")
+	}
+}
+
+// TestCompress 压缩测试
+func TestCompress(t *testing.T) {
+	src := []byte(`{"uid":100,"name":"Diego","desc":"HelloWorld HelloWorld HelloWorld HelloWorld"}`)
+	for _, compressType := range []string{CompressZlib, CompressGzip, CompressSnappy} {
+		data, err := Compress(src, compressType, 10)
+		if err != nil {
+			t.Errorf("Compress %s failed. err: %s.", compressType, err.Error())
+			return
+		} else if string(data[:COMPRESS_LEN-1]) != COMPRESS_FLAG {
+			t.Errorf("Compress %s failed. header is %q.", compressType, data[:COMPRESS_LEN])
+			return
+		}
+
+		res, err := Uncompress(data)
+		if err != nil {
+			t.Errorf("Uncompress %s failed. err: %s.", compressType, err.Error())
+			return
+		} else if string(res) != string(src) {
+			t.Errorf("Uncompress %s failed. Got %s, expected %s.", compressType, string(res), string(src))
+			return
+		}
+	}
+
+	// 不超过阀值不压缩
+	data, _ := Compress(src, CompressSnappy, len(src))
+	if string(data) != string(src) {
+		t.Errorf("Compress failed. Got %q, expected not compressed.", data)
+	}
+
+	// 没有压缩标识原样返回
+	data, err := Uncompress(src)
+	if err != nil || string(data) != string(src) {
+		t.Errorf("Uncompress failed. Got %s, err: %v.", string(data), err)
+	}
+
+	if CheckCompressType("lzma") == nil {
+		t.Error("CheckCompressType failed. lzma should not be supported.")
+	}
+}
+
+// TestIterator 迭代器测试
+func TestIterator(t *testing.T) {
+	if p := ScanPattern("le_", ""); p != "le_*" {
+		t.Errorf("ScanPattern failed. Got %s, expected le_*.", p)
+	}
+	if p := ScanPattern("a*b?[c]_", "user_*"); p != `a\*b\?\[c\]_user_*` {
+		t.Errorf("ScanPattern failed. Got %s.", p)
+	}
+
+	// 模拟两个节点，每个节点分两批返回
+	node := func(batches ...[]string) ScanFunc {
+		return func(cursor uint64) ([]string, uint64, error) {
+			next := cursor + 1
+			if int(next) == len(batches) {
+				next = 0
+			}
+			return batches[cursor], next, nil
+		}
+	}
+	it := NewIterator("le_", node([]string{"le_k1", "le_k2"}, []string{}), node([]string{}, []string{"le_k3"}))
+	var keys []string
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	if it.Err() != nil {
+		t.Errorf("Iterator failed. err: %s.", it.Err().Error())
+	} else if fmt.Sprint(keys) != "[k1 k2 k3]" {
+		t.Errorf("Iterator failed. Got %v, expected [k1 k2 k3].", keys)
+	}
+
+	// 出错时停止遍历
+	it = NewIterator("", node([]string{"k1"}, []string{"k2"}), func(cursor uint64) ([]string, uint64, error) {
+		return nil, 0, fmt.Errorf("scan error")
+	})
+	keys = keys[:0]
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	if it.Err() == nil || len(keys) != 2 {
+		t.Errorf("Iterator failed. Got %v, err %v.", keys, it.Err())
+	}
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/golang/snappy"
+	"github.com/lixy529/gotools/utils"
+	"io/ioutil"
+)
+
+const (
+	COMPRESS_FLAG = "CMP1_"
+	COMPRESS_LEN  = 6 // COMPRESS_FLAG加1字节的压缩类型
+)
+
+// 压缩类型
+const (
+	CompressZlib   = "zlib"
+	CompressGzip   = "gzip"
+	CompressSnappy = "snappy"
+)
+
+// compressIds 压缩类型对应的数据头标识
+var compressIds = map[string]byte{
+	CompressZlib:   'z',
+	CompressGzip:   'g',
+	CompressSnappy: 's',
+}
+
+// CheckCompressType 检查压缩类型是否支持，为空表示不压缩
+//   参数
+//     compressType: 压缩类型，支持zlib、gzip、snappy
+//   返回
+//     支持返回nil，不支持返回错误信息
+func CheckCompressType(compressType string) error {
+	if compressType == "" {
+		return nil
+	}
+	if _, ok := compressIds[compressType]; !ok {
+		return fmt.Errorf("Cache: Compress type don't support %s", compressType)
+	}
+	return nil
+}
+
+// Compress 压缩数据，数据长度超过threshold时才压缩，压缩后的数据加上COMPRESS_FLAG和压缩类型
+//   参数
+//     data:         要压缩的数据
+//     compressType: 压缩类型，为空时不压缩
+//     threshold:    压缩阀值，单位字节
+//   返回
+//     成功返回压缩后的数据，失败返回错误信息
+func Compress(data []byte, compressType string, threshold int) ([]byte, error) {
+	if compressType == "" || len(data) <= threshold {
+		return data, nil
+	}
+
+	id, ok := compressIds[compressType]
+	if !ok {
+		return nil, fmt.Errorf("Cache: Compress type don't support %s", compressType)
+	}
+
+	var body []byte
+	var err error
+	switch compressType {
+	case CompressZlib:
+		body, err = utils.ZlibEncode(data)
+	case CompressGzip:
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		if _, err = w.Write(data); err == nil {
+			err = w.Close()
+		}
+		body = b.Bytes()
+	case CompressSnappy:
+		body = snappy.Encode(nil, data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cache: Compress error, %s", err.Error())
+	}
+
+	head := append([]byte(COMPRESS_FLAG), id)
+	return append(head, body...), nil
+}
+
+// Uncompress 解压数据，按数据头判断压缩类型，没有压缩标识的数据原样返回
+//   参数
+//     data: 要解压的数据
+//   返回
+//     成功返回解压后的数据，失败返回错误信息
+func Uncompress(data []byte) ([]byte, error) {
+	if len(data) < COMPRESS_LEN || string(data[:COMPRESS_LEN-1]) != COMPRESS_FLAG {
+		return data, nil
+	}
+
+	var res []byte
+	var err error
+	body := data[COMPRESS_LEN:]
+	switch data[COMPRESS_LEN-1] {
+	case compressIds[CompressZlib]:
+		res, err = utils.ZlibDecode(body)
+	case compressIds[CompressGzip]:
+		var r *gzip.Reader
+		r, err = gzip.NewReader(bytes.NewReader(body))
+		if err == nil {
+			res, err = ioutil.ReadAll(r)
+			r.Close()
+		}
+	case compressIds[CompressSnappy]:
+		res, err = snappy.Decode(nil, body)
+	default:
+		return nil, NewError(ErrDecode, fmt.Sprintf("Cache: Uncompress error, unknown compress type %q", data[COMPRESS_LEN-1]))
+	}
+	if err != nil {
+		return nil, WrapError(ErrDecode, fmt.Errorf("Cache: Uncompress error, %s", err.Error()))
+	}
+
+	return res, nil
+}
+
+// UncompressString 解压字符串，用于MGet、HGetAll等返回字符串的结果
+//   参数
+//     val: 要解压的数据，不是字符串时原样返回
+//   返回
+//     成功返回解压后的数据，失败返回错误信息
+func UncompressString(val interface{}) (interface{}, error) {
+	str, ok := val.(string)
+	if !ok {
+		return val, nil
+	}
+
+	data, err := Uncompress([]byte(str))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"errors"
+	"github.com/go-redis/redis/v7"
+)
+
+// 错误类型，适配器返回的错误可以用errors.Is判断，如: errors.Is(err, cache.ErrMiss)
+var (
+	ErrMiss        = errors.New("cache: miss")                    // key不存在，如memcache自增不存在的key
+	ErrUnsupported = errors.New("cache: unsupported")             // 适配器不支持的操作
+	ErrDecode      = errors.New("cache: decode failed")           // 解密、解压或反序列化失败
+	ErrPoolTimeout = errors.New("cache: connection pool timeout") // 连接池所有连接都忙，等待超时
+)
+
+// redisPoolTimeout go-redis连接池超时的错误信息，go-redis的错误变量在internal包里，只能比较字符串
+const redisPoolTimeout = "redis: connection pool timeout"
+
+// Error 带错误类型的错误，错误信息与原错误一致
+type Error struct {
+	Kind error // 错误类型，如ErrMiss
+	Err  error // 原错误
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Is 用于errors.Is判断错误类型
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap 返回原错误，errors.Is(err, redis.Nil)、errors.Is(err, memcache.ErrCacheMiss)依然可以使用
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewError 新建带错误类型的错误
+//   参数
+//     kind: 错误类型
+//     msg:  错误信息
+//   返回
+//     错误
+func NewError(kind error, msg string) error {
+	return &Error{Kind: kind, Err: errors.New(msg)}
+}
+
+// WrapError 给错误加上错误类型，错误信息不变
+//   参数
+//     kind: 错误类型
+//     err:  原错误，为nil或已经是该类型时直接返回
+//   返回
+//     错误
+func WrapError(kind, err error) error {
+	if err == nil || errors.Is(err, kind) {
+		return err
+	}
+
+	return &Error{Kind: kind, Err: err}
+}
+
+// IsMiss 判断是否为key不存在的错误
+func IsMiss(err error) bool {
+	return err == redis.Nil || errors.Is(err, ErrMiss)
+}
+
+// RedisError 转换go-redis的错误，redis.Nil转为ErrMiss，连接池超时转为ErrPoolTimeout
+//   参数
+//     err: go-redis的错误
+//   返回
+//     错误
+func RedisError(err error) error {
+	if err == nil {
+		return nil
+	} else if err == redis.Nil {
+		return WrapError(ErrMiss, err)
+	} else if err.Error() == redisPoolTimeout {
+		return WrapError(ErrPoolTimeout, err)
+	}
+
+	return err
+}
+
+// ConvRedisError 用RedisError转换*err，redis适配器的方法用defer调用，只转换方法返回的错误，不修改go-redis的命令
+//   参数
+//     err: 错误信息的地址
+//   返回
+//
+func ConvRedisError(err *error) {
+	*err = RedisError(*err)
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"errors"
+	"github.com/go-redis/redis/v7"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	err := WrapError(ErrMiss, redis.Nil)
+	if !errors.Is(err, ErrMiss) || !errors.Is(err, redis.Nil) || err.Error() != "redis: nil" {
+		t.Errorf("WrapError failed. Got %v.", err)
+	}
+	if !IsMiss(err) || !IsMiss(redis.Nil) || IsMiss(errors.New("redis: nil")) || IsMiss(nil) {
+		t.Errorf("IsMiss failed.")
+	}
+	if WrapError(ErrMiss, nil) != nil || WrapError(ErrMiss, err) != err {
+		t.Errorf("WrapError failed. nil or wrapped error should be returned directly.")
+	}
+
+	err = NewError(ErrUnsupported, "MemcCache: Memcache don't support HSet")
+	if !errors.Is(err, ErrUnsupported) || errors.Is(err, ErrMiss) || err.Error() != "MemcCache: Memcache don't support HSet" {
+		t.Errorf("NewError failed. Got %v.", err)
+	}
+
+	if err = RedisError(errors.New(redisPoolTimeout)); !errors.Is(err, ErrPoolTimeout) {
+		t.Errorf("RedisError failed. Got %v, expected ErrPoolTimeout.", err)
+	}
+	if err = RedisError(redis.Nil); !errors.Is(err, ErrMiss) {
+		t.Errorf("RedisError failed. Got %v, expected ErrMiss.", err)
+	}
+}
+
+func TestErrDecode(t *testing.T) {
+	var n int
+	if err := ByteToInter([]byte("abc"), &n); !errors.Is(err, ErrDecode) {
+		t.Errorf("ByteToInter failed. Got %v, expected ErrDecode.", err)
+	}
+	if _, err := Decode([]byte("ENC2_abc"), []byte("1234567890123456")); !errors.Is(err, ErrDecode) {
+		t.Errorf("Decode failed. Got %v, expected ErrDecode.", err)
+	}
+}
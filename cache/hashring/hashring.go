@@ -0,0 +1,198 @@
+// Consistent hash ring (ketama)
+package hashring
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DEFAULT_REPLICAS 权重为1的节点对应的虚拟节点数，与ketama一致
+const DEFAULT_REPLICAS = 160
+
+// memcachedPort memcache默认端口，libmemcached计算虚拟节点时省略此端口
+const memcachedPort = "11211"
+
+// Ring 一致性哈希环，兼容ketama算法
+// 每个节点按权重生成虚拟节点，增删节点时只有相邻区间的key会移动
+type Ring struct {
+	lock     sync.RWMutex
+	replicas int            // 权重为1的节点对应的虚拟节点数
+	weights  map[string]int // 节点对应的权重
+	hashes   []uint32       // 排好序的虚拟节点哈希值
+	owners   map[uint32]string
+
+	libmemcached bool // 是否兼容libmemcached
+}
+
+// New 新建一个哈希环
+//   参数
+//     replicas: 权重为1的节点对应的虚拟节点数，小于等于0时使用DEFAULT_REPLICAS
+//   返回
+//     哈希环对象
+func New(replicas int) *Ring {
+	if replicas <= 0 {
+		replicas = DEFAULT_REPLICAS
+	}
+
+	return &Ring{
+		replicas: replicas,
+		weights:  make(map[string]int),
+		owners:   make(map[uint32]string),
+	}
+}
+
+// NewLibmemcached 新建一个与libmemcached的ketama weighted分布一致的哈希环
+// php-memcached开启OPT_LIBKETAMA_COMPATIBLE时使用这种分布，Go和PHP可以共用一组memcache
+// 与New的区别：虚拟节点数按权重在所有节点中的占比分配，默认端口的节点名不带端口
+//   参数
+//
+//   返回
+//     哈希环对象
+func NewLibmemcached() *Ring {
+	r := New(DEFAULT_REPLICAS)
+	r.libmemcached = true
+	return r
+}
+
+// Add 添加节点，节点已存在时更新权重
+//   参数
+//     node:   节点名，一般为主机和端口，如127.0.0.1:6379
+//     weight: 权重，小于等于0时为1
+//   返回
+//
+func (r *Ring) Add(node string, weight int) {
+	if weight <= 0 {
+		weight = 1
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.weights[node] = weight
+	r.build()
+}
+
+// Remove 删除节点
+//   参数
+//     node: 节点名
+//   返回
+//
+func (r *Ring) Remove(node string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.weights[node]; !ok {
+		return
+	}
+	delete(r.weights, node)
+	r.build()
+}
+
+// Get 获取key所在的节点
+//   参数
+//     key: key值
+//   返回
+//     节点名，没有节点时返回空串
+func (r *Ring) Get(key string) string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if len(r.hashes) == 0 {
+		return ""
+	}
+
+	h := Hash(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+
+	return r.owners[r.hashes[i]]
+}
+
+// Nodes 返回所有节点名，按名称排序
+func (r *Ring) Nodes() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.sortedNodes()
+}
+
+// Len 返回节点数
+func (r *Ring) Len() int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return len(r.weights)
+}
+
+// build 重建虚拟节点，调用方需要加锁
+// 每个节点生成replicas*weight个虚拟节点，每次md5得到4个
+func (r *Ring) build() {
+	total := 0
+	for _, weight := range r.weights {
+		total += weight
+	}
+
+	r.hashes = r.hashes[:0]
+	r.owners = make(map[uint32]string)
+	for _, node := range r.sortedNodes() {
+		points := (r.replicas*r.weights[node] + 3) / 4
+		name := node
+		if r.libmemcached {
+			points = libmemcachedPoints(r.weights[node], total, len(r.weights))
+			name = libmemcachedName(node)
+		}
+		for i := 0; i < points; i++ {
+			digest := md5.Sum([]byte(name + "-" + strconv.Itoa(i)))
+			for j := 0; j < 4; j++ {
+				h := binary.LittleEndian.Uint32(digest[j*4:])
+				if _, ok := r.owners[h]; ok {
+					continue
+				}
+				r.owners[h] = node
+				r.hashes = append(r.hashes, h)
+			}
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+}
+
+// sortedNodes 返回排好序的节点名，保证哈希冲突时结果稳定，调用方需要加锁
+func (r *Ring) sortedNodes() []string {
+	nodes := make([]string, 0, len(r.weights))
+	for node := range r.weights {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// libmemcachedPoints 计算节点的md5次数，每次得到4个虚拟节点
+// 与libmemcached的floor(weight/total*160/4*count)一致，使用float32计算以保证取整结果相同
+//   参数
+//     weight: 节点权重
+//     total:  所有节点的权重之和
+//     count:  节点数
+//   返回
+//     md5次数
+func libmemcachedPoints(weight, total, count int) int {
+	pct := float32(weight) / float32(total)
+	return int(math.Floor(float64(pct*DEFAULT_REPLICAS/4*float32(count) + 0.0000000001)))
+}
+
+// libmemcachedName 虚拟节点名的前缀，默认端口为host，其它为host:port
+func libmemcachedName(node string) string {
+	host, port, err := net.SplitHostPort(node)
+	if err == nil && port == memcachedPort {
+		return host
+	}
+
+	return node
+}
+
+// Hash 计算key的哈希值，取md5的前4个字节，与ketama一致
+func Hash(key string) uint32 {
+	digest := md5.Sum([]byte(key))
+	return binary.LittleEndian.Uint32(digest[:4])
+}
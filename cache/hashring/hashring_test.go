@@ -0,0 +1,126 @@
+package hashring
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	r := New(0)
+	if r.Get("k1") != "" {
+		t.Error("Ring Get failed. expected empty node.")
+		return
+	}
+
+	nodes := []string{"127.0.0.1:6379", "127.0.0.2:6379", "127.0.0.3:6379"}
+	for _, node := range nodes {
+		r.Add(node, 1)
+	}
+	if r.Len() != 3 {
+		t.Errorf("Ring Len failed. Got %d, expected 3.", r.Len())
+		return
+	}
+
+	// 同一个key总是在同一个节点
+	keys := make([]string, 10000)
+	owner := make(map[string]string)
+	count := make(map[string]int)
+	for i := range keys {
+		keys[i] = "key_" + strconv.Itoa(i)
+		owner[keys[i]] = r.Get(keys[i])
+		count[owner[keys[i]]]++
+	}
+	for i := range keys {
+		if r.Get(keys[i]) != owner[keys[i]] {
+			t.Errorf("Ring Get failed. %s moved.", keys[i])
+			return
+		}
+	}
+
+	// 分布大致均匀
+	for _, node := range nodes {
+		if count[node] < 2500 || count[node] > 4200 {
+			t.Errorf("Ring distribution failed. %s has %d keys.", node, count[node])
+		}
+	}
+
+	// 添加节点时只有移到新节点的key会变
+	r.Add("127.0.0.4:6379", 1)
+	moved := 0
+	for _, key := range keys {
+		node := r.Get(key)
+		if node != owner[key] {
+			moved++
+			if node != "127.0.0.4:6379" {
+				t.Errorf("Ring Add failed. %s moved from %s to %s.", key, owner[key], node)
+				return
+			}
+		}
+	}
+	if moved < 1500 || moved > 3500 {
+		t.Errorf("Ring Add failed. %d keys moved, expected about 2500.", moved)
+	}
+
+	// 删除节点后恢复原来的分布
+	r.Remove("127.0.0.4:6379")
+	for _, key := range keys {
+		if r.Get(key) != owner[key] {
+			t.Errorf("Ring Remove failed. %s is not restored.", key)
+			return
+		}
+	}
+}
+
+func TestRingWeight(t *testing.T) {
+	r := New(0)
+	r.Add("127.0.0.1:6379", 3)
+	r.Add("127.0.0.2:6379", 1)
+
+	count := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		count[r.Get("key_"+strconv.Itoa(i))]++
+	}
+	if count["127.0.0.1:6379"] < 6500 || count["127.0.0.1:6379"] > 8500 {
+		t.Errorf("Ring weight failed. Got %v, expected about 3:1.", count)
+	}
+}
+
+func TestRingLibmemcached(t *testing.T) {
+	// 权重相同时每台160个虚拟节点，非默认端口的节点名与New一致
+	r := NewLibmemcached()
+	k := New(0)
+	for _, node := range []string{"127.0.0.1:11212", "127.0.0.2:11212", "127.0.0.3:11212"} {
+		r.Add(node, 1)
+		k.Add(node, 1)
+	}
+	if len(r.hashes) != len(k.hashes) || len(r.hashes) < 470 {
+		t.Errorf("Ring libmemcached points failed. Got %d, expected %d.", len(r.hashes), len(k.hashes))
+		return
+	}
+	for i := 0; i < 1000; i++ {
+		key := "key_" + strconv.Itoa(i)
+		if r.Get(key) != k.Get(key) {
+			t.Errorf("Ring libmemcached Get failed. %s Got %s, expected %s.", key, r.Get(key), k.Get(key))
+			return
+		}
+	}
+
+	// 默认端口的节点名不带端口
+	if name := libmemcachedName("127.0.0.1:11211"); name != "127.0.0.1" {
+		t.Errorf("Ring libmemcached name failed. Got %s, expected 127.0.0.1.", name)
+	}
+	if name := libmemcachedName("/tmp/memcached.sock"); name != "/tmp/memcached.sock" {
+		t.Errorf("Ring libmemcached name failed. Got %s, expected /tmp/memcached.sock.", name)
+	}
+
+	// 虚拟节点数按权重占比分配，总数为160*节点数
+	if n := libmemcachedPoints(3, 4, 2); n != 60 {
+		t.Errorf("Ring libmemcached points failed. Got %d, expected 60.", n)
+	}
+	if n := libmemcachedPoints(1, 4, 2); n != 20 {
+		t.Errorf("Ring libmemcached points failed. Got %d, expected 20.", n)
+	}
+	if n := libmemcachedPoints(1, 3, 3); n != 40 {
+		t.Errorf("Ring libmemcached points failed. Got %d, expected 40.", n)
+	}
+}
@@ -0,0 +1,274 @@
+package cache
+
+import (
+	"context"
+	"github.com/go-redis/redis/v7"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HookInfo 一次缓存命令的信息
+type HookInfo struct {
+	Adapter  string        // 适配器名称，如redism、memcache
+	Cmd      string        // 命令名称，小写，如get、set
+	Key      string        // 命令的第一个key，不含前缀，没有key时为空
+	Start    time.Time     // 开始时间
+	Duration time.Duration // 执行时间，AfterCmd时有效
+	Err      error         // 错误信息，key不存在不算错误
+	Read     bool          // 是否为读数据的命令，只有读命令的Hit有意义
+	Hit      bool          // 读命令是否命中
+}
+
+// Hook 缓存命令的钩子，适配器在执行每个命令前后调用
+// 钩子在命令执行的协程里同步调用，实现时不要做耗时的操作
+type Hook interface {
+	// BeforeCmd 命令执行前调用，返回的ctx会传给AfterCmd
+	BeforeCmd(ctx context.Context, info *HookInfo) context.Context
+	// AfterCmd 命令执行后调用，info里的Duration、Err、Hit已设置
+	AfterCmd(ctx context.Context, info *HookInfo)
+}
+
+// Hooks 适配器的钩子列表，可以并发使用，零值可用
+type Hooks struct {
+	lock  sync.RWMutex
+	hooks []Hook
+}
+
+// AddHook 添加钩子，按添加顺序调用
+//   参数
+//     hook: 钩子
+//   返回
+//
+func (hs *Hooks) AddHook(hook Hook) {
+	if hook == nil {
+		return
+	}
+
+	hs.lock.Lock()
+	hs.hooks = append(hs.hooks, hook)
+	hs.lock.Unlock()
+}
+
+// list 返回当前的钩子列表
+func (hs *Hooks) list() []Hook {
+	hs.lock.RLock()
+	defer hs.lock.RUnlock()
+
+	return hs.hooks
+}
+
+// BeforeCmd 调用所有钩子的BeforeCmd，没有钩子时返回nil
+//   参数
+//     ctx:     上下文，为nil时使用context.Background()
+//     adapter: 适配器名称
+//     cmd:     命令名称
+//     key:     key值，不含前缀
+//   返回
+//     上下文、命令信息，命令信息为nil时不需要调用AfterCmd
+func (hs *Hooks) BeforeCmd(ctx context.Context, adapter, cmd, key string) (context.Context, *HookInfo) {
+	hooks := hs.list()
+	if len(hooks) == 0 {
+		return ctx, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	info := &HookInfo{Adapter: adapter, Cmd: cmd, Key: key, Start: time.Now()}
+	for _, h := range hooks {
+		ctx = h.BeforeCmd(ctx, info)
+	}
+
+	return ctx, info
+}
+
+// AfterCmd 设置执行结果并调用所有钩子的AfterCmd
+//   参数
+//     ctx:  BeforeCmd返回的上下文
+//     info: BeforeCmd返回的命令信息，为nil时不处理
+//     err:  命令的错误信息
+//     hit:  读命令是否命中，写命令传nil
+//   返回
+//
+func (hs *Hooks) AfterCmd(ctx context.Context, info *HookInfo, err error, hit *bool) {
+	if info == nil {
+		return
+	}
+
+	info.Duration = time.Since(info.Start)
+	info.Err = err
+	if hit != nil {
+		info.Read, info.Hit = true, *hit
+	}
+	for _, h := range hs.list() {
+		h.AfterCmd(ctx, info)
+	}
+}
+
+// Process 执行命令前调用BeforeCmd，返回的函数在命令执行后调用AfterCmd，一般和defer一起使用，如:
+//   defer c.hooks.Process(ctx, cache.AdapterMemory, "get", key)(&err, &exist)
+//   参数
+//     ctx:     上下文
+//     adapter: 适配器名称
+//     cmd:     命令名称
+//     key:     key值，不含前缀
+//   返回
+//     命令执行后调用的函数，参数为错误信息和是否命中的指针，写命令的hit传nil
+func (hs *Hooks) Process(ctx context.Context, adapter, cmd, key string) func(err *error, hit *bool) {
+	ctx, info := hs.BeforeCmd(ctx, adapter, cmd, key)
+	return func(err *error, hit *bool) {
+		if info == nil {
+			return
+		}
+
+		var e error
+		if err != nil {
+			e = *err
+		}
+		hs.AfterCmd(ctx, info, e, hit)
+	}
+}
+
+// hookInfoKey redis钩子在ctx里保存命令信息使用的key
+type hookInfoKey struct{}
+
+// redisHook 把go-redis的钩子转换为Hook调用，redis适配器的每个命令都会经过此钩子
+// 不修改命令的错误，通过Pipeline直接使用的命令依然返回redis.Nil，适配器在方法返回时用ConvRedisError转换
+type redisHook struct {
+	adapter string
+	prefix  string
+	hooks   *Hooks
+}
+
+// NewRedisHook 新建go-redis钩子，redis适配器创建连接后通过AddHook添加
+//   参数
+//     adapter: 适配器名称
+//     prefix:  key前缀，钩子里的key会去掉此前缀
+//     hooks:   适配器的钩子列表
+//   返回
+//     go-redis钩子
+func NewRedisHook(adapter, prefix string, hooks *Hooks) redis.Hook {
+	return &redisHook{adapter: adapter, prefix: prefix, hooks: hooks}
+}
+
+func (h *redisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, info := h.hooks.BeforeCmd(ctx, h.adapter, cmd.Name(), h.cmdKey(cmd))
+	if info != nil {
+		ctx = context.WithValue(ctx, hookInfoKey{}, []*HookInfo{info})
+	}
+
+	return ctx, nil
+}
+
+func (h *redisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.after(ctx, []redis.Cmder{cmd})
+	return nil
+}
+
+func (h *redisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if len(h.hooks.list()) == 0 {
+		return ctx, nil
+	}
+
+	// 事务的multi、exec不做统计
+	infos := make([]*HookInfo, len(cmds))
+	for i, cmd := range cmds {
+		if name := cmd.Name(); name != "multi" && name != "exec" {
+			ctx, infos[i] = h.hooks.BeforeCmd(ctx, h.adapter, name, h.cmdKey(cmd))
+		}
+	}
+
+	return context.WithValue(ctx, hookInfoKey{}, infos), nil
+}
+
+func (h *redisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	h.after(ctx, cmds)
+	return nil
+}
+
+// after 调用命令的AfterCmd，BeforeCmd时没有钩子则不处理
+func (h *redisHook) after(ctx context.Context, cmds []redis.Cmder) {
+	infos, _ := ctx.Value(hookInfoKey{}).([]*HookInfo)
+	if len(infos) != len(cmds) {
+		return
+	}
+
+	for i, cmd := range cmds {
+		err := cmd.Err()
+		if IsMiss(err) {
+			err = nil
+		}
+		h.hooks.AfterCmd(ctx, infos[i], err, redisHit(cmd))
+	}
+}
+
+// cmdKey 返回命令的第一个key，eval、evalsha返回第一个KEYS
+func (h *redisHook) cmdKey(cmd redis.Cmder) string {
+	args := cmd.Args()
+	idx := 1
+	switch cmd.Name() {
+	case "eval", "evalsha":
+		if len(args) < 3 {
+			return ""
+		}
+		if n, _ := strconv.Atoi(redisArg(args[2])); n <= 0 {
+			return ""
+		}
+		idx = 3
+	case "script", "flushdb", "flushall", "ping", "publish", "subscribe":
+		return ""
+	}
+	if len(args) <= idx {
+		return ""
+	}
+
+	return strings.TrimPrefix(redisArg(args[idx]), h.prefix)
+}
+
+// redisArg 命令参数转为字符串
+func redisArg(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+
+	return ""
+}
+
+// redisHit 读命令是否命中，不是读命令时返回nil
+// mget、hmget所有值都存在才算命中，hgetall没有字段时算未命中
+func redisHit(cmd redis.Cmder) *bool {
+	var hit bool
+	switch cmd.Name() {
+	case "get", "getset", "hget", "lpop", "rpop", "lindex":
+		hit = cmd.Err() == nil
+	case "mget", "hmget":
+		c, ok := cmd.(*redis.SliceCmd)
+		if !ok || c.Err() != nil {
+			break
+		}
+		hit = true
+		for _, v := range c.Val() {
+			if v == nil {
+				hit = false
+				break
+			}
+		}
+	case "hgetall":
+		if c, ok := cmd.(*redis.StringStringMapCmd); ok {
+			hit = c.Err() == nil && len(c.Val()) > 0
+		}
+	default:
+		return nil
+	}
+
+	return &hit
+}
@@ -0,0 +1,264 @@
+// Sorted set helpers shared by the memory and memcache adapters
+package zset
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lixy529/gotools/cache"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Member 有序集合成员
+type Member struct {
+	Member string
+	Score  float64
+}
+
+// ToFloat 将分值转成float64
+func ToFloat(v interface{}) (float64, error) {
+	switch s := v.(type) {
+	case float64:
+		return s, nil
+	case float32:
+		return float64(s), nil
+	case int:
+		return float64(s), nil
+	case int32:
+		return float64(s), nil
+	case int64:
+		return float64(s), nil
+	case uint32:
+		return float64(s), nil
+	case uint64:
+		return float64(s), nil
+	case string:
+		return strconv.ParseFloat(s, 64)
+	}
+
+	return 0, fmt.Errorf("ZSet: score %v is not a float", v)
+}
+
+// ParseMembers 解析ZSet的参数
+//   参数
+//     val: 数据为成对出来，前面为score(整数值或双精度浮点数), 后面为变量
+//   返回
+//     成员列表，失败返回错误信息
+func ParseMembers(val []interface{}) ([]Member, error) {
+	valLen := len(val)
+	if valLen < 2 || valLen%2 != 0 {
+		return nil, errors.New("val param error")
+	}
+
+	members := make([]Member, 0, valLen/2)
+	for i := 0; i < valLen-1; i += 2 {
+		score, err := ToFloat(val[i])
+		if err != nil {
+			return nil, err
+		}
+		data, err := cache.InterToByte(val[i+1])
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, Member{Member: string(data), Score: score})
+	}
+
+	return members, nil
+}
+
+// Sort 按分值从小到大排序，分值相同时按成员字典序排序
+//   参数
+//     z: 有序集合
+//   返回
+//     排序后的成员
+func Sort(z map[string]float64) []Member {
+	members := make([]Member, 0, len(z))
+	for m, s := range z {
+		members = append(members, Member{Member: m, Score: s})
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score < members[j].Score
+		}
+		return members[i].Member < members[j].Member
+	})
+
+	return members
+}
+
+// Range 查询有序集合指定下标区间的成员，同ZGet
+//   参数
+//     z:          有序集合
+//     start:      开始下标，0表示第一个，-1表示最后一个
+//     stop:       结束下标，0表示第一个，-1表示最后一个
+//     withScores: 是否带上score，score在成员之前
+//     isRev:      true-递减排列 false-递增排列
+//   返回
+//     查询的结果数据
+func Range(z map[string]float64, start, stop int, withScores bool, isRev bool) []string {
+	members := Sort(z)
+	if isRev {
+		for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+			members[i], members[j] = members[j], members[i]
+		}
+	}
+
+	res := []string{}
+	from, to := RangeIndex(int64(start), int64(stop), int64(len(members)))
+	for _, m := range members[from:to] {
+		if withScores {
+			res = append(res, fmt.Sprintf("%f", m.Score))
+		}
+		res = append(res, m.Member)
+	}
+
+	return res
+}
+
+// RangeIndex 将redis风格的下标(支持负数)转成切片的下标范围
+//   参数
+//     start:  开始下标
+//     stop:   结束下标
+//     length: 总长度
+//   返回
+//     切片的开始、结束(不包含)下标，范围为空时开始下标大于等于结束下标
+func RangeIndex(start, stop, length int64) (int64, int64) {
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	if start > stop || start >= length {
+		return 0, 0
+	}
+
+	return start, stop + 1
+}
+
+// ScoreRange 分值区间，同ZREMRANGEBYSCORE的min、max
+type ScoreRange struct {
+	min, max     float64
+	minEx, maxEx bool // 是否开区间
+}
+
+// ParseScoreRange 解析分值区间，支持-inf、+inf和表示开区间的"("
+//   参数
+//     start: 开始值
+//     end:   结束值
+//   返回
+//     分值区间，失败返回错误信息
+func ParseScoreRange(start, end string) (r ScoreRange, err error) {
+	if r.min, r.minEx, err = parseScore(start); err != nil {
+		return r, err
+	}
+	r.max, r.maxEx, err = parseScore(end)
+
+	return r, err
+}
+
+// Contains 分值是否在区间内
+func (r ScoreRange) Contains(score float64) bool {
+	if score < r.min || (r.minEx && score == r.min) {
+		return false
+	}
+	if score > r.max || (r.maxEx && score == r.max) {
+		return false
+	}
+
+	return true
+}
+
+// parseScore 解析分值
+//   参数
+//     s: 分值
+//   返回
+//     分值、是否开区间、错误信息
+func parseScore(s string) (float64, bool, error) {
+	exclusive := false
+	if strings.HasPrefix(s, "(") {
+		exclusive = true
+		s = s[1:]
+	}
+
+	switch s {
+	case "-inf":
+		return math.Inf(-1), exclusive, nil
+	case "+inf", "inf":
+		return math.Inf(1), exclusive, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, errors.New("ZSet: min or max is not a float")
+	}
+
+	return f, exclusive, nil
+}
+
+// LexRange 字典区间，同ZREMRANGEBYLEX的min、max
+type LexRange struct {
+	min, max lexBound
+}
+
+// lexBound 字典区间的边界
+type lexBound struct {
+	value     string
+	exclusive bool
+	inf       int // -1: 负无穷，1: 正无穷，0: 有限值
+}
+
+// ParseLexRange 解析字典区间，支持"-"、"+"、"["、"("
+//   参数
+//     start: 开始值
+//     end:   结束值
+//   返回
+//     字典区间，失败返回错误信息
+func ParseLexRange(start, end string) (r LexRange, err error) {
+	if r.min, err = parseLex(start); err != nil {
+		return r, err
+	}
+	r.max, err = parseLex(end)
+
+	return r, err
+}
+
+// Contains 成员是否在区间内
+func (r LexRange) Contains(member string) bool {
+	if r.min.inf == 1 || r.max.inf == -1 {
+		return false
+	}
+	if r.min.inf == 0 && (member < r.min.value || (r.min.exclusive && member == r.min.value)) {
+		return false
+	}
+	if r.max.inf == 0 && (member > r.max.value || (r.max.exclusive && member == r.max.value)) {
+		return false
+	}
+
+	return true
+}
+
+// parseLex 解析字典区间的边界
+func parseLex(s string) (lexBound, error) {
+	switch {
+	case s == "-":
+		return lexBound{inf: -1}, nil
+	case s == "+":
+		return lexBound{inf: 1}, nil
+	case strings.HasPrefix(s, "["):
+		return lexBound{value: s[1:]}, nil
+	case strings.HasPrefix(s, "("):
+		return lexBound{value: s[1:], exclusive: true}, nil
+	}
+
+	return lexBound{}, errors.New("ZSet: min or max not valid string range item")
+}
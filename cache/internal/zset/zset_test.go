@@ -0,0 +1,112 @@
+package zset
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	z := map[string]float64{"c": 2, "a": 1, "b": 1, "d": 3}
+	cases := []struct {
+		start, stop int
+		withScores  bool
+		isRev       bool
+		expected    []string
+	}{
+		{0, -1, false, false, []string{"a", "b", "c", "d"}},
+		{1, 2, false, false, []string{"b", "c"}},
+		{0, 1, true, false, []string{"1.000000", "a", "1.000000", "b"}},
+		{0, 0, false, true, []string{"d"}},
+		{-2, -1, false, true, []string{"b", "a"}},
+		{5, 10, false, false, []string{}},
+		{2, 1, false, false, []string{}},
+	}
+	for _, c := range cases {
+		res := Range(z, c.start, c.stop, c.withScores, c.isRev)
+		if !reflect.DeepEqual(res, c.expected) {
+			t.Errorf("Range(%d, %d, %v, %v) failed. Got %v, expected %v.", c.start, c.stop, c.withScores, c.isRev, res, c.expected)
+		}
+	}
+
+	if from, to := RangeIndex(-100, 100, 3); from != 0 || to != 3 {
+		t.Errorf("RangeIndex failed. Got %d-%d, expected 0-3.", from, to)
+	}
+}
+
+func TestParseMembers(t *testing.T) {
+	members, err := ParseMembers([]interface{}{1, "a", "2.5", "b", int64(3), 100})
+	if err != nil {
+		t.Errorf("ParseMembers failed. err: %s.", err.Error())
+		return
+	}
+	expected := []Member{{"a", 1}, {"b", 2.5}, {"100", 3}}
+	if !reflect.DeepEqual(members, expected) {
+		t.Errorf("ParseMembers failed. Got %v, expected %v.", members, expected)
+	}
+
+	for _, val := range [][]interface{}{{1}, {1, "a", 2}, {"x", "a"}, {true, "a"}} {
+		if _, err = ParseMembers(val); err == nil {
+			t.Errorf("ParseMembers failed. %v expected error.", val)
+		}
+	}
+}
+
+func TestScoreRange(t *testing.T) {
+	cases := []struct {
+		start, end string
+		score      float64
+		expected   bool
+	}{
+		{"1", "2", 1, true},
+		{"(1", "2", 1, false},
+		{"1", "(2", 2, false},
+		{"-inf", "+inf", math.Inf(-1), true},
+		{"(-inf", "inf", math.Inf(-1), false},
+		{"3", "1", 2, false},
+	}
+	for _, c := range cases {
+		r, err := ParseScoreRange(c.start, c.end)
+		if err != nil {
+			t.Errorf("ParseScoreRange(%s, %s) failed. err: %s.", c.start, c.end, err.Error())
+			continue
+		}
+		if r.Contains(c.score) != c.expected {
+			t.Errorf("ScoreRange(%s, %s) Contains %v failed. expected %v.", c.start, c.end, c.score, c.expected)
+		}
+	}
+
+	if _, err := ParseScoreRange("a", "1"); err == nil {
+		t.Error("ParseScoreRange failed. a expected error.")
+	}
+}
+
+func TestLexRange(t *testing.T) {
+	cases := []struct {
+		start, end string
+		member     string
+		expected   bool
+	}{
+		{"-", "+", "a", true},
+		{"[a", "[c", "a", true},
+		{"(a", "[c", "a", false},
+		{"[a", "(c", "c", false},
+		{"+", "+", "a", false},
+		{"-", "-", "a", false},
+		{"[b", "+", "a", false},
+	}
+	for _, c := range cases {
+		r, err := ParseLexRange(c.start, c.end)
+		if err != nil {
+			t.Errorf("ParseLexRange(%s, %s) failed. err: %s.", c.start, c.end, err.Error())
+			continue
+		}
+		if r.Contains(c.member) != c.expected {
+			t.Errorf("LexRange(%s, %s) Contains %s failed. expected %v.", c.start, c.end, c.member, c.expected)
+		}
+	}
+
+	if _, err := ParseLexRange("a", "+"); err == nil {
+		t.Error("ParseLexRange failed. a expected error.")
+	}
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"context"
+	"strings"
+)
+
+// CLEAR_BATCH ClearAll每次SCAN和UNLINK的key个数
+const CLEAR_BATCH = 500
+
+// Iterator key迭代器，由Scanner.Scan返回
+//   实例：
+//     it := c.(cache.Scanner).Scan("user_*", 100)
+//     for it.Next() {
+//       fmt.Println(it.Key())
+//     }
+//     if err := it.Err(); err != nil {
+//       ...
+//     }
+type Iterator interface {
+	Next() bool  // 移动到下一个key，没有key或出错时返回false
+	Key() string // 当前key，已去掉适配器的前缀
+	Err() error  // 遍历过程中的错误
+}
+
+// Scanner 支持按模式遍历key的缓存(redis、memory支持)
+// 使用SCAN命令，不会阻塞服务器，遍历期间修改的key可能重复返回或不返回
+type Scanner interface {
+	Scan(pattern string, batch int64) Iterator
+	ScanCtx(ctx context.Context, pattern string, batch int64) Iterator
+}
+
+// ScanFunc 执行一次SCAN，返回本批key和下一次的游标，游标为0时表示遍历结束
+type ScanFunc func(cursor uint64) ([]string, uint64, error)
+
+// NewIterator 新建一个迭代器，依次遍历每个ScanFunc，用于集群等多个节点的情况
+//   参数
+//     prefix: key前缀，返回的key会去掉此前缀
+//     scans:  每个节点的ScanFunc
+//   返回
+//     迭代器
+func NewIterator(prefix string, scans ...ScanFunc) Iterator {
+	return &scanIterator{prefix: prefix, scans: scans}
+}
+
+// ErrIterator 返回一个只有错误信息的迭代器
+func ErrIterator(err error) Iterator {
+	return &scanIterator{err: err}
+}
+
+// ScanPattern 生成SCAN使用的匹配模式，前缀中的通配符会被转义
+//   参数
+//     prefix:  key前缀
+//     pattern: 匹配模式，为空时匹配所有key
+//   返回
+//     加上前缀的匹配模式
+func ScanPattern(prefix, pattern string) string {
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	var b strings.Builder
+	for _, c := range prefix {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	b.WriteString(pattern)
+
+	return b.String()
+}
+
+// scanIterator 基于ScanFunc的迭代器
+type scanIterator struct {
+	prefix  string
+	scans   []ScanFunc
+	cursor  uint64
+	started bool     // 当前ScanFunc是否已执行过
+	keys    []string // 当前批次的key
+	pos     int
+	key     string
+	err     error
+}
+
+func (it *scanIterator) Next() bool {
+	for it.err == nil {
+		if it.pos < len(it.keys) {
+			it.key = strings.TrimPrefix(it.keys[it.pos], it.prefix)
+			it.pos++
+			return true
+		}
+
+		if len(it.scans) == 0 {
+			return false
+		}
+
+		// 当前节点遍历结束，切换到下一个节点
+		if it.started && it.cursor == 0 {
+			it.scans = it.scans[1:]
+			it.started = false
+			continue
+		}
+
+		keys, cursor, err := it.scans[0](it.cursor)
+		if err != nil {
+			it.err = RedisError(err)
+			return false
+		}
+		it.keys, it.pos, it.cursor, it.started = keys, 0, cursor, true
+	}
+
+	return false
+}
+
+func (it *scanIterator) Key() string {
+	return it.key
+}
+
+func (it *scanIterator) Err() error {
+	return it.err
+}
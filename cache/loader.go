@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// NOT_FOUND_FLAG 负缓存标识，缓存值等于此值时表示数据不存在
+const NOT_FOUND_FLAG = "\x00NOTFOUND_"
+
+// ErrNotFound 数据不存在，LoaderFunc返回此错误时会缓存"不存在"的结果
+var ErrNotFound = errors.New("Cache: not found")
+
+// LoaderFunc 缓存未命中时加载数据的函数
+// 数据不存在时返回ErrNotFound
+type LoaderFunc func() (interface{}, error)
+
+// loadCall 正在进行中的一次加载
+type loadCall struct {
+	wg   sync.WaitGroup
+	data []byte
+	err  error
+}
+
+// Loader 读穿透缓存
+// 缓存未命中时调用LoaderFunc加载数据并写回缓存，同一个key的并发请求只会调用一次LoaderFunc
+type Loader struct {
+	cache          Cache
+	negativeExpire int32 // 负缓存的过期时间，单位秒，为0时不缓存"不存在"的结果
+
+	lock  sync.Mutex
+	calls map[string]*loadCall
+}
+
+// NewLoader 新建一个Loader对象
+//   参数
+//     adapter:        Cache对象，可以是任意适配器
+//     negativeExpire: 负缓存的过期时间，单位秒，为0时不缓存"不存在"的结果
+//   返回
+//     Loader对象
+func NewLoader(adapter Cache, negativeExpire int32) *Loader {
+	return &Loader{
+		cache:          adapter,
+		negativeExpire: negativeExpire,
+		calls:          make(map[string]*loadCall),
+	}
+}
+
+// GetOrLoad 从缓存获取数据，未命中时调用loaderFunc加载并写入缓存
+// 同一个key的并发未命中只会调用一次loaderFunc，其它请求等待并共享其结果
+// 缓存读写失败不影响结果，读失败时按未命中处理，写失败时忽略
+//   参数
+//     key:        key值
+//     dst:        获取到的数据，需要传指针
+//     expire:     写入缓存的过期时间，单位秒
+//     loaderFunc: 加载数据的函数
+//   返回
+//     成功返回nil，数据不存在返回ErrNotFound，失败返回错误信息
+func (l *Loader) GetOrLoad(key string, dst interface{}, expire int32, loaderFunc LoaderFunc) error {
+	if loaderFunc == nil {
+		return errors.New("Cache: GetOrLoad loaderFunc is nil")
+	}
+
+	var raw string
+	err, ok := l.cache.Get(key, &raw)
+	if err == nil && ok {
+		if raw == NOT_FOUND_FLAG {
+			return ErrNotFound
+		}
+		return ByteToInter([]byte(raw), dst)
+	}
+
+	data, err := l.load(key, expire, loaderFunc)
+	if err != nil {
+		return err
+	}
+
+	return ByteToInter(data, dst)
+}
+
+// load 调用loaderFunc加载数据并写入缓存，同一个key同时只有一个加载在进行
+//   参数
+//     key:        key值
+//     expire:     写入缓存的过期时间，单位秒
+//     loaderFunc: 加载数据的函数
+//   返回
+//     加载到的数据，错误信息
+func (l *Loader) load(key string, expire int32, loaderFunc LoaderFunc) (data []byte, err error) {
+	l.lock.Lock()
+	if c, ok := l.calls[key]; ok {
+		l.lock.Unlock()
+		c.wg.Wait()
+		return c.data, c.err
+	}
+	c := &loadCall{}
+	c.wg.Add(1)
+	l.calls[key] = c
+	l.lock.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("Cache: GetOrLoad loaderFunc panic, %v", r)
+			data, err = nil, c.err
+		}
+		c.wg.Done()
+
+		l.lock.Lock()
+		delete(l.calls, key)
+		l.lock.Unlock()
+	}()
+
+	val, err := loaderFunc()
+	if err == ErrNotFound {
+		if l.negativeExpire > 0 {
+			l.cache.Set(key, NOT_FOUND_FLAG, l.negativeExpire)
+		}
+		c.err = ErrNotFound
+		return nil, c.err
+	} else if err != nil {
+		c.err = err
+		return nil, c.err
+	}
+
+	c.data, c.err = InterToByte(val)
+	if c.err != nil {
+		return nil, c.err
+	}
+	l.cache.Set(key, string(c.data), expire)
+
+	return c.data, nil
+}
@@ -0,0 +1,96 @@
+package cache_test
+
+import (
+	"errors"
+	"github.com/lixy529/gotools/cache"
+	"github.com/lixy529/gotools/cache/memory"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestGetOrLoad GetOrLoad测试
+func TestGetOrLoad(t *testing.T) {
+	adapter, err := cache.NewCache(cache.AdapterMemory, `{"interval":"60"}`)
+	if err != nil {
+		t.Errorf("NewCache failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.(*memory.MemoryCache).Close()
+
+	type user struct {
+		Uid  int32
+		Name string
+	}
+	loader := cache.NewLoader(adapter, 10)
+
+	// 并发未命中只加载一次
+	var calls int32
+	loadUser := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return user{Uid: 100, Name: "Diego"}, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u := user{}
+			err := loader.GetOrLoad("user_100", &u, 60, loadUser)
+			if err != nil {
+				t.Errorf("GetOrLoad failed. err: %s.", err.Error())
+			} else if u.Uid != 100 || u.Name != "Diego" {
+				t.Errorf("GetOrLoad failed. Got %d-%s, expected 100-Diego.", u.Uid, u.Name)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("GetOrLoad failed. loaderFunc called %d times, expected 1.", calls)
+	}
+
+	// 命中缓存不再加载
+	u := user{}
+	err = loader.GetOrLoad("user_100", &u, 60, loadUser)
+	if err != nil || u.Name != "Diego" || calls != 1 {
+		t.Errorf("GetOrLoad cache hit failed. err: %v, calls: %d.", err, calls)
+	}
+
+	// 负缓存
+	var nfCalls int32
+	loadNone := func() (interface{}, error) {
+		atomic.AddInt32(&nfCalls, 1)
+		return nil, cache.ErrNotFound
+	}
+	for i := 0; i < 3; i++ {
+		err = loader.GetOrLoad("user_200", &u, 60, loadNone)
+		if err != cache.ErrNotFound {
+			t.Errorf("GetOrLoad failed. Got %v, expected ErrNotFound.", err)
+		}
+	}
+	if nfCalls != 1 {
+		t.Errorf("GetOrLoad negative cache failed. loaderFunc called %d times, expected 1.", nfCalls)
+	}
+
+	// 加载失败不缓存
+	loadErr := func() (interface{}, error) {
+		return nil, errors.New("db error")
+	}
+	err = loader.GetOrLoad("user_300", &u, 60, loadErr)
+	if err == nil || err.Error() != "db error" {
+		t.Errorf("GetOrLoad failed. Got %v, expected db error.", err)
+	}
+	exist, _ := adapter.IsExist("user_300")
+	if exist {
+		t.Errorf("GetOrLoad failed. error result is cached.")
+	}
+
+	// 字符串
+	var str string
+	err = loader.GetOrLoad("str", &str, 60, func() (interface{}, error) { return "HelloWorld!", nil })
+	if err != nil || str != "HelloWorld!" {
+		t.Errorf("GetOrLoad failed. Got %s, expected HelloWorld!.", str)
+	}
+}
@@ -0,0 +1,395 @@
+// Distributed lock
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/lixy529/gotools/cache"
+	"sync"
+	"time"
+)
+
+// 获取锁失败时重试的默认退避时间
+const (
+	DEFAULT_RETRY_MIN = 10 * time.Millisecond
+	DEFAULT_RETRY_MAX = 500 * time.Millisecond
+)
+
+var (
+	ErrNotObtained = errors.New("Lock: lock not obtained")
+	ErrNotHeld     = errors.New("Lock: lock not held")
+	ErrUnsupported = errors.New("Lock: adapter does not support lock")
+)
+
+// QuorumError Redlock模式下没有超过半数节点获取到锁，并且有节点出错
+// errors.Is(err, ErrNotObtained)为true，Lock会继续重试
+type QuorumError struct {
+	Errs []error // 出错节点的错误信息
+}
+
+func (e *QuorumError) Error() string {
+	return fmt.Sprintf("Lock: lock not obtained, %d node errors, last: %v", len(e.Errs), e.Errs[len(e.Errs)-1])
+}
+
+// Unwrap 返回ErrNotObtained
+func (e *QuorumError) Unwrap() error {
+	return ErrNotObtained
+}
+
+// Client 分布式锁客户端
+// 只有一个节点时直接在节点上加锁，有多个节点时使用Redlock算法，超过半数节点加锁成功才算获取到锁
+//   实例：
+//     client, err := lock.New(c)
+//     l, err := client.Lock("order_1001", 10*time.Second)
+//     if err != nil {
+//       ...
+//     }
+//     defer l.Unlock()
+type Client struct {
+	nodes    []cache.Locker
+	quorum   int           // 获取锁需要成功的节点数
+	retryMin time.Duration // 重试的最小间隔
+	retryMax time.Duration // 重试的最大间隔，每次重试间隔翻倍直到此值
+}
+
+// New 新建一个锁客户端，锁只保存在key所在的一个节点上
+//   参数
+//     adapter: Cache对象，需要实现cache.Locker，redis、memcache、memory适配器支持
+//   返回
+//     成功返回锁客户端，适配器不支持时返回ErrUnsupported
+func New(adapter cache.Cache) (*Client, error) {
+	locker, ok := adapter.(cache.Locker)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	return newClient([]cache.Locker{locker}), nil
+}
+
+// NewRedlock 新建一个Redlock模式的锁客户端，在适配器的每个节点上加锁
+//   参数
+//     adapter: Cache对象，需要实现cache.MultiLocker，redisd适配器支持
+//   返回
+//     成功返回锁客户端，适配器不支持时返回ErrUnsupported
+func NewRedlock(adapter cache.Cache) (*Client, error) {
+	multi, ok := adapter.(cache.MultiLocker)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	nodes := multi.LockNodes()
+	if len(nodes) == 0 {
+		return nil, errors.New("Lock: adapter has no lock node")
+	}
+
+	return newClient(nodes), nil
+}
+
+// NewWithLockers 使用指定的节点新建锁客户端，多个节点时使用Redlock算法
+//   参数
+//     nodes: 各个节点
+//   返回
+//     锁客户端
+func NewWithLockers(nodes ...cache.Locker) *Client {
+	return newClient(nodes)
+}
+
+func newClient(nodes []cache.Locker) *Client {
+	return &Client{
+		nodes:    nodes,
+		quorum:   len(nodes)/2 + 1,
+		retryMin: DEFAULT_RETRY_MIN,
+		retryMax: DEFAULT_RETRY_MAX,
+	}
+}
+
+// SetRetry 设置获取锁失败时的重试间隔，每次重试间隔翻倍，最大为max
+//   参数
+//     min: 最小间隔，小于等于0时使用DEFAULT_RETRY_MIN
+//     max: 最大间隔，小于min时使用min
+//   返回
+//
+func (c *Client) SetRetry(min, max time.Duration) {
+	if min <= 0 {
+		min = DEFAULT_RETRY_MIN
+	}
+	if max < min {
+		max = min
+	}
+	c.retryMin, c.retryMax = min, max
+}
+
+// Lock 获取锁，锁被占用时按退避间隔重试，直到获取到锁
+//   参数
+//     key: 锁的key值
+//     ttl: 锁的过期时间
+//   返回
+//     成功返回锁对象，失败返回错误信息
+func (c *Client) Lock(key string, ttl time.Duration) (*Lock, error) {
+	return c.LockCtx(context.Background(), key, ttl)
+}
+
+// LockCtx 同Lock，ctx取消或超时时停止等待，返回ErrNotObtained
+// Redlock模式下部分节点出错时继续重试，停止等待时返回最后一次的*QuorumError
+func (c *Client) LockCtx(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	backoff := c.retryMin
+	for {
+		l, err := c.TryLockCtx(ctx, key, ttl)
+		if !errors.Is(err, ErrNotObtained) {
+			return l, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > c.retryMax {
+			backoff = c.retryMax
+		}
+	}
+}
+
+// TryLock 尝试获取锁，不等待
+//   参数
+//     key: 锁的key值
+//     ttl: 锁的过期时间
+//   返回
+//     成功返回锁对象，锁被占用返回ErrNotObtained，Redlock模式下有节点出错时返回*QuorumError，失败返回错误信息
+func (c *Client) TryLock(key string, ttl time.Duration) (*Lock, error) {
+	return c.TryLockCtx(context.Background(), key, ttl)
+}
+
+// TryLockCtx 同TryLock，ctx用于控制超时和取消
+func (c *Client) TryLockCtx(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ok, errs := c.each(ctx, ttl, func(node cache.Locker) (bool, error) {
+		return node.AcquireLock(ctx, key, token, ttl)
+	})
+	if !ok {
+		// 释放部分节点上已经获取到的锁
+		c.each(context.Background(), 0, func(node cache.Locker) (bool, error) {
+			return node.ReleaseLock(context.Background(), key, token)
+		})
+		switch {
+		case len(errs) == 0:
+			err = ErrNotObtained
+		case len(c.nodes) == 1:
+			err = errs[0]
+		default:
+			// 少数节点不可用时不影响重试
+			err = &QuorumError{Errs: errs}
+		}
+		return nil, err
+	}
+
+	return &Lock{client: c, key: key, token: token, ttl: ttl}, nil
+}
+
+// each 在每个节点上执行fn，超过半数节点成功且锁的有效时间未用完时返回true
+//   参数
+//     ctx: 上下文
+//     ttl: 锁的过期时间，大于0时扣除执行时间和时钟漂移后需要有剩余
+//     fn:  在一个节点上执行的函数
+//   返回
+//     是否成功，没有成功时返回出错节点的错误信息
+func (c *Client) each(ctx context.Context, ttl time.Duration, fn func(node cache.Locker) (bool, error)) (bool, []error) {
+	start := time.Now()
+	n := 0
+	var errs []error
+	for _, node := range c.nodes {
+		ok, err := fn(node)
+		if err != nil {
+			errs = append(errs, err)
+		} else if ok {
+			n++
+		}
+	}
+
+	if n < c.quorum {
+		return false, errs
+	}
+
+	// 有效时间需要扣除时钟漂移，与Redlock一致取ttl的1%加2毫秒
+	if ttl > 0 && len(c.nodes) > 1 {
+		drift := ttl/100 + 2*time.Millisecond
+		if time.Since(start)+drift >= ttl {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// Lock 获取到的锁
+type Lock struct {
+	client *Client
+	key    string
+	token  string
+	ttl    time.Duration
+
+	lock sync.Mutex
+	stop chan struct{} // 停止自动续期
+	done chan struct{} // 自动续期已结束
+}
+
+// Key 返回锁的key值
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token 返回持有者的随机token
+func (l *Lock) Token() string {
+	return l.token
+}
+
+// Unlock 释放锁，同时停止自动续期
+//   参数
+//
+//   返回
+//     成功返回nil，锁已过期或被其它持有者获取返回ErrNotHeld，失败返回错误信息
+func (l *Lock) Unlock() error {
+	return l.UnlockCtx(context.Background())
+}
+
+// UnlockCtx 同Unlock，ctx用于控制超时和取消
+func (l *Lock) UnlockCtx(ctx context.Context) error {
+	l.stopRefresh()
+
+	ok, errs := l.client.each(ctx, 0, func(node cache.Locker) (bool, error) {
+		return node.ReleaseLock(ctx, l.key, l.token)
+	})
+
+	return heldErr(ok, errs)
+}
+
+// Refresh 更新锁的过期时间
+//   参数
+//     ttl: 新的过期时间，小于等于0时使用获取锁时的过期时间
+//   返回
+//     成功返回nil，锁已过期或被其它持有者获取返回ErrNotHeld，失败返回错误信息
+func (l *Lock) Refresh(ttl time.Duration) error {
+	return l.RefreshCtx(context.Background(), ttl)
+}
+
+// RefreshCtx 同Refresh，ctx用于控制超时和取消
+func (l *Lock) RefreshCtx(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		l.lock.Lock()
+		ttl = l.ttl
+		l.lock.Unlock()
+	}
+
+	ok, errs := l.client.each(ctx, ttl, func(node cache.Locker) (bool, error) {
+		return node.RefreshLock(ctx, l.key, l.token, ttl)
+	})
+	err := heldErr(ok, errs)
+	if err == nil {
+		l.lock.Lock()
+		l.ttl = ttl
+		l.lock.Unlock()
+	}
+
+	return err
+}
+
+// heldErr 返回Unlock、Refresh的错误信息
+//   参数
+//     ok:   是否超过半数节点成功
+//     errs: 出错节点的错误信息
+//   返回
+//     成功返回nil，有节点出错时返回最后一个错误，否则返回ErrNotHeld
+func heldErr(ok bool, errs []error) error {
+	if ok {
+		return nil
+	}
+	if len(errs) > 0 {
+		return errs[len(errs)-1]
+	}
+
+	return ErrNotHeld
+}
+
+// AutoRefresh 启动一个goroutine自动续期，每隔过期时间的1/3续期一次，直到Unlock
+//   参数
+//     onLost: 续期失败时的回调，锁可能已经丢失，可以为nil
+//             回调前已停止续期，回调里可以调用Unlock或重新AutoRefresh
+//   返回
+//
+func (l *Lock) AutoRefresh(onLost func(err error)) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.stop != nil {
+		return
+	}
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	l.stop, l.done = stop, done
+	interval := l.ttl / 3
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				close(done)
+				return
+			case <-ticker.C:
+				err := l.Refresh(0)
+				if err == nil {
+					continue
+				}
+
+				// 先结束续期再回调，回调里调用Unlock时stopRefresh不会等待自己
+				l.lock.Lock()
+				if l.stop == stop {
+					l.stop, l.done = nil, nil
+				}
+				l.lock.Unlock()
+				close(done)
+
+				if onLost != nil {
+					onLost(err)
+				}
+				return
+			}
+		}
+	}()
+}
+
+// stopRefresh 停止自动续期并等待goroutine结束
+func (l *Lock) stopRefresh() {
+	l.lock.Lock()
+	stop, done := l.stop, l.done
+	l.stop, l.done = nil, nil
+	l.lock.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// newToken 生成随机token
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
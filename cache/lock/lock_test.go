@@ -0,0 +1,241 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"github.com/lixy529/gotools/cache"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memLocker 测试用的内存锁节点
+type memLocker struct {
+	lock  sync.Mutex
+	items map[string]string
+	down  bool // 模拟节点不可用
+}
+
+func newMemLocker() *memLocker {
+	return &memLocker{items: make(map[string]string)}
+}
+
+func (m *memLocker) AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.down {
+		return false, context.DeadlineExceeded
+	}
+	if _, ok := m.items[key]; ok {
+		return false, nil
+	}
+	m.items[key] = token
+	return true, nil
+}
+
+func (m *memLocker) ReleaseLock(ctx context.Context, key, token string) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.items[key] != token {
+		return false, nil
+	}
+	delete(m.items, key)
+	return true, nil
+}
+
+func (m *memLocker) RefreshLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.items[key] == token, nil
+}
+
+func TestLock(t *testing.T) {
+	node := newMemLocker()
+	client := NewWithLockers(node)
+
+	l, err := client.TryLock("lock_k1", time.Second)
+	if err != nil {
+		t.Errorf("TryLock failed. err: %s.", err.Error())
+		return
+	}
+
+	_, err = client.TryLock("lock_k1", time.Second)
+	if err != ErrNotObtained {
+		t.Errorf("TryLock failed. Got %v, expected %v.", err, ErrNotObtained)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.LockCtx(ctx, "lock_k1", time.Second)
+	if err != ErrNotObtained {
+		t.Errorf("LockCtx failed. Got %v, expected %v.", err, ErrNotObtained)
+	}
+
+	err = l.Refresh(2 * time.Second)
+	if err != nil {
+		t.Errorf("Refresh failed. err: %s.", err.Error())
+	}
+
+	// 其它goroutine等待锁释放
+	ch := make(chan error)
+	go func() {
+		l2, err := client.Lock("lock_k1", time.Second)
+		if err == nil {
+			err = l2.Unlock()
+		}
+		ch <- err
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	err = l.Unlock()
+	if err != nil {
+		t.Errorf("Unlock failed. err: %s.", err.Error())
+	}
+	if err = <-ch; err != nil {
+		t.Errorf("Lock failed. err: %s.", err.Error())
+	}
+
+	err = l.Unlock()
+	if err != ErrNotHeld {
+		t.Errorf("Unlock failed. Got %v, expected %v.", err, ErrNotHeld)
+	}
+	err = l.Refresh(0)
+	if err != ErrNotHeld {
+		t.Errorf("Refresh failed. Got %v, expected %v.", err, ErrNotHeld)
+	}
+}
+
+func TestRedlock(t *testing.T) {
+	nodes := []*memLocker{newMemLocker(), newMemLocker(), newMemLocker()}
+	client := NewWithLockers(nodes[0], nodes[1], nodes[2])
+
+	// 一个节点不可用时仍然可以获取锁
+	nodes[2].down = true
+	l, err := client.TryLock("lock_k1", time.Second)
+	if err != nil {
+		t.Errorf("Redlock TryLock failed. err: %s.", err.Error())
+		return
+	}
+	l.Unlock()
+
+	// 少于半数节点获取到锁时失败，并释放已获取到的锁
+	nodes[0].items["lock_k1"] = "other"
+	_, err = client.TryLock("lock_k1", time.Second)
+	if err == nil {
+		t.Errorf("Redlock TryLock failed. expected error.")
+	}
+	if _, ok := nodes[1].items["lock_k1"]; ok {
+		t.Errorf("Redlock TryLock failed. lock_k1 is not released.")
+	}
+
+	// 节点出错导致不足半数时返回QuorumError，Lock继续重试
+	delete(nodes[0].items, "lock_k1")
+	nodes[1].down = true
+	_, err = client.TryLock("lock_k1", time.Second)
+	qerr, ok := err.(*QuorumError)
+	if !ok || !errors.Is(err, ErrNotObtained) || len(qerr.Errs) != 2 {
+		t.Errorf("Redlock TryLock failed. Got %v, expected QuorumError.", err)
+	}
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		nodes[1].lock.Lock()
+		nodes[1].down = false
+		nodes[1].lock.Unlock()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	l, err = client.LockCtx(ctx, "lock_k1", time.Second)
+	if err != nil {
+		t.Errorf("Redlock LockCtx failed. err: %s.", err.Error())
+		return
+	}
+	l.Unlock()
+}
+
+func TestAutoRefresh(t *testing.T) {
+	node := newMemLocker()
+	client := NewWithLockers(node)
+
+	l, err := client.TryLock("lock_k1", 30*time.Millisecond)
+	if err != nil {
+		t.Errorf("TryLock failed. err: %s.", err.Error())
+		return
+	}
+
+	lost := make(chan error, 1)
+	l.AutoRefresh(func(err error) {
+		lost <- err
+	})
+
+	// 锁被删除后续期失败
+	time.Sleep(50 * time.Millisecond)
+	node.lock.Lock()
+	delete(node.items, "lock_k1")
+	node.lock.Unlock()
+
+	select {
+	case err = <-lost:
+		if err != ErrNotHeld {
+			t.Errorf("AutoRefresh failed. Got %v, expected %v.", err, ErrNotHeld)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("AutoRefresh failed. onLost is not called.")
+	}
+}
+
+func TestAutoRefreshUnlock(t *testing.T) {
+	node := newMemLocker()
+	client := NewWithLockers(node)
+
+	l, err := client.TryLock("lock_k1", 30*time.Millisecond)
+	if err != nil {
+		t.Errorf("TryLock failed. err: %s.", err.Error())
+		return
+	}
+
+	// 回调里调用Unlock不会死锁
+	lost := make(chan error, 1)
+	l.AutoRefresh(func(err error) {
+		lost <- l.Unlock()
+	})
+	node.lock.Lock()
+	delete(node.items, "lock_k1")
+	node.lock.Unlock()
+
+	select {
+	case err = <-lost:
+		if err != ErrNotHeld {
+			t.Errorf("Unlock failed. Got %v, expected %v.", err, ErrNotHeld)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("AutoRefresh failed. Unlock in onLost is blocked.")
+		return
+	}
+
+	// 丢失后可以重新启动自动续期
+	node.lock.Lock()
+	node.items["lock_k1"] = l.Token()
+	node.lock.Unlock()
+	l.AutoRefresh(nil)
+	l.lock.Lock()
+	running := l.stop != nil
+	l.lock.Unlock()
+	if !running {
+		t.Errorf("AutoRefresh failed. Can't restart after lost.")
+	}
+	if err = l.Unlock(); err != nil {
+		t.Errorf("Unlock failed. err: %s.", err.Error())
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	// 只实现了cache.Cache的适配器
+	var c struct{ cache.Cache }
+	if _, err := New(c); err != ErrUnsupported {
+		t.Errorf("New failed. Got %v, expected %v.", err, ErrUnsupported)
+	}
+	if _, err := NewRedlock(c); err != ErrUnsupported {
+		t.Errorf("NewRedlock failed. Got %v, expected %v.", err, ErrUnsupported)
+	}
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"github.com/go-redis/redis/v7"
+	"time"
+)
+
+// Locker 支持分布式锁的缓存(redis、memcache支持)，由cache/lock包使用
+// 锁的值为持有者的随机token，释放和续期时比较token，只有持有者才能操作
+type Locker interface {
+	// AcquireLock 锁不存在时设置锁，获取到返回true
+	AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	// ReleaseLock token一致时删除锁，删除成功返回true
+	ReleaseLock(ctx context.Context, key, token string) (bool, error)
+	// RefreshLock token一致时更新锁的过期时间，更新成功返回true
+	RefreshLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+}
+
+// MultiLocker 有多个独立节点的缓存(redisd支持)，用于Redlock
+type MultiLocker interface {
+	LockNodes() []Locker
+}
+
+var (
+	// LockReleaseScript 比较token后删除锁
+	//   KEYS[1]: 锁的key
+	//   ARGV[1]: token
+	LockReleaseScript = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`)
+
+	// LockRefreshScript 比较token后更新锁的过期时间
+	//   KEYS[1]: 锁的key
+	//   ARGV[1]: token
+	//   ARGV[2]: 过期时间，单位毫秒
+	LockRefreshScript = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`)
+)
+
+// ScriptRunner 支持执行lua脚本的缓存(redis支持)，由cache/ratelimit等包使用
+type ScriptRunner interface {
+	// RunScript 执行lua脚本，优先使用EVALSHA，脚本未加载时使用EVAL，KEYS会添加适配器的key前缀
+	RunScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error)
+}
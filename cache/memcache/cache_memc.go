@@ -1,11 +1,13 @@
 package memcache
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/lixy529/gotools/cache"
+	"github.com/lixy529/gotools/cache/internal/zset"
 	"github.com/lixy529/gotools/utils"
 	"strconv"
 	"strings"
@@ -14,6 +16,9 @@ import (
 
 const (
 	COMPRESSION_ZLIB        = "zlib"
+	COMPRESSION_FASTLZ      = "fastlz"
+	DISTRIBUTION_MODULA     = "modula" // 按key的crc32取模选择服务器，gomemcache默认的分布
+	DISTRIBUTION_KETAMA     = "ketama" // 一致性哈希，与libmemcached(php-memcached)的ketama weighted分布一致
 	FLAGES_INT_UNCOMPRESS   = 1
 	FLAGES_FLOAT_UNCOMPRESS = 2
 	FLAGES_JSON_UNCOMPRESS  = 6
@@ -21,22 +26,65 @@ const (
 	FLAGES_STR_UNCOMPRESS   = 0
 	FLAGES_STR_COMPRESS     = 48
 
+	// php-memcached的flags，0-3位为类型，4-6位为压缩标识，16-31位为用户flags
+	FLAGES_TYPE_MASK          = 0xf
+	FLAGES_BOOL               = 3 // 值为1或空
+	FLAGES_SERIALIZED         = 4 // PHP serialize
+	FLAGES_IGBINARY           = 5
+	FLAGES_MSGPACK            = 7
+	FLAGES_COMPRESSED         = 1 << 4 // 值的前4字节为小端的原始长度
+	FLAGES_COMPRESSION_ZLIB   = 1 << 5
+	FLAGES_COMPRESSION_FASTLZ = 1 << 6
+
+	// 哈希表、有序集合、位图和HyperLogLog的结构类型
+	STRUCT_HASH = "hash"
+	STRUCT_ZSET = "zset"
+	STRUCT_BIT  = "bit"
+	STRUCT_HLL  = "hll"
+
+	MAX_ITEM_SIZE = 1024 * 1024 // 数据结构序列化后的默认最大长度，与memcache默认的item大小限制(-I)一致
+	MAX_CAS_RETRY = 10          // 数据结构CAS冲突时的最大重试次数
+
+	DEFAULT_FAILURE_LIMIT = 2 // 一致性哈希时服务器连续失败多少次暂时摘除
+	DEFAULT_RETRY_TIMEOUT = 2 // 一致性哈希时摘除的服务器多少秒后重新加入
+
+	// Deprecated: 使用cache.IsMiss或errors.Is(err, cache.ErrMiss)判断
 	NOT_EXIST = "cache miss"
 )
 
+var (
+	errWrongType = errors.New("MemcCache: WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNotInt    = errors.New("MemcCache: hash value is not an integer or out of range")
+)
+
 // MemcCache memcache缓存
 type MemcCache struct {
-	conn      *memcache.Client
+	conn      *memcClient
 	connCfg   []string
 	maxIdle   int           // 最大空闲连接数，默认为2，如果配置值小于1则使用默认值
 	ioTimeOut time.Duration // io超时时间，默认为100毫秒，传0为默认时间，单位毫秒
 	prefix    string        // key前缀，如果配置里有，则所有key前自动添加此前缀
 
-	serializer        string // 序列化，目前只支持json
-	compressType      string // 压缩类型，目前只支持zlib
-	compressThreshold int    // 超过大小就进行压缩
+	serializer        cache.Serializer // 序列化，默认为json
+	compressType      string           // 压缩类型，支持zlib和fastlz
+	compressThreshold int              // 超过大小就进行压缩
+
+	encodeKey [][]byte // 加解密密钥，第一个用于加密，全部用于解密
+
+	phpCompat bool // 兼容php-memcached，布尔值按PHP的bool类型保存，非字符串和数字的值使用PHP serialize序列化
+
+	maxItemSize int // 哈希表、有序集合、位图和HyperLogLog序列化(和压缩)后的最大长度，默认为1M，超过时返回错误
+
+	distribution string        // 分布方式，modula或ketama，默认为modula
+	weights      []int         // 服务器对应的权重，ketama时有效，默认为1
+	failureLimit int           // ketama时服务器连续失败多少次暂时摘除，默认为2，0表示不摘除
+	retryTimeout time.Duration // ketama时摘除的服务器多久后重新加入，默认为2秒
+
+	hooks cache.Hooks // 命令钩子
+}
 
-	encodeKey []byte // 加解密密钥，使用Aes加密，长度为16的倍数
+func init() {
+	cache.Register(cache.AdapterMemcache, NewMemcCache)
 }
 
 // NewMemcCache 新建一个MemcCache适配器.
@@ -45,19 +93,38 @@ func NewMemcCache() cache.Cache {
 }
 
 // connect 连接memcache
+// memcache客户端不支持context，只在执行命令前检查ctx是否已取消或超时
 //   参数
-//     void
+//     ctx: 上下文
 //   返回
 //     成功返回nil，失败返回错误信息
-func (mc *MemcCache) connect() error {
+func (mc *MemcCache) connect(ctx context.Context) error {
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
 	if mc.conn != nil {
 		return nil
 	}
 
-	mc.conn = memcache.New(mc.connCfg...)
-	if mc.conn == nil {
+	var client *memcache.Client
+	var selector *ketamaSelector
+	if mc.distribution == DISTRIBUTION_KETAMA {
+		var err error
+		selector, err = newKetamaSelector(mc.connCfg, mc.weights, mc.failureLimit, mc.retryTimeout)
+		if err != nil {
+			return fmt.Errorf("MemcCache Connect memcache [%s] failed, %s", strings.Join(mc.connCfg, ","), err.Error())
+		}
+		client = memcache.NewFromSelector(selector)
+	} else {
+		client = memcache.New(mc.connCfg...)
+	}
+	if client == nil {
 		return fmt.Errorf("MemcCache Connect memcache [%s] failed", strings.Join(mc.connCfg, ","))
 	}
+	mc.conn = &memcClient{Client: client, prefix: mc.prefix, hooks: &mc.hooks, selector: selector}
 
 	if mc.maxIdle > 0 {
 		mc.conn.MaxIdleConns = mc.maxIdle
@@ -82,6 +149,12 @@ func (mc *MemcCache) connect() error {
 //       "compressType":"zlib",
 //       "compressThreshold":"256",
 //       "encodeKey":"abcdefghij123456",
+//       "maxItemSize":"1048576",
+//       "distribution":"ketama",
+//       "weights":"2,1",
+//       "failureLimit":"2",
+//       "retryTimeout":"2",
+//       "phpCompat":"true",
 //       }
 //   返回
 //     成功返回nil，失败返回错误信息
@@ -113,13 +186,16 @@ func (mc *MemcCache) Init(config string) error {
 		mc.prefix = prefix
 	}
 
-	// 序列化，目前只支持json
-	mc.serializer = "json"
+	// 序列化，支持json、gob、msgpack、raw，默认为json
+	mc.serializer, err = cache.GetSerializer(mapCfg["serializer"])
+	if err != nil {
+		return err
+	}
 
-	// 压缩，压缩类型目前只支持zlib
+	// 压缩，压缩类型支持zlib和fastlz
 	mc.compressType, _ = mapCfg["compressType"]
 	if mc.compressType != "" {
-		if mc.compressType != COMPRESSION_ZLIB {
+		if mc.compressType != COMPRESSION_ZLIB && mc.compressType != COMPRESSION_FASTLZ {
 			return fmt.Errorf("MemcCache: Compress type don't support %s", mc.compressType)
 		}
 
@@ -135,10 +211,56 @@ func (mc *MemcCache) Init(config string) error {
 
 	// 加密密钥
 	if tmp, ok := mapCfg["encodeKey"]; ok {
-		mc.encodeKey = []byte(tmp)
+		mc.encodeKey = cache.ParseEncodeKeys(tmp)
+	}
+
+	// 兼容php-memcached，读取时总是按flags解析PHP的类型
+	mc.phpCompat = false
+	if tmp := mapCfg["phpCompat"]; tmp != "" {
+		mc.phpCompat, err = strconv.ParseBool(tmp)
+		if err != nil {
+			return fmt.Errorf("MemcCache: PHP compat error, %s", tmp)
+		}
+	}
+
+	// 数据结构的最大长度，需要与memcache的-I配置一致
+	mc.maxItemSize = MAX_ITEM_SIZE
+	if tmp, ok := mapCfg["maxItemSize"]; ok {
+		mc.maxItemSize, err = strconv.Atoi(tmp)
+		if err != nil || mc.maxItemSize <= 0 {
+			return fmt.Errorf("MemcCache: Max item size error, %s", tmp)
+		}
+	}
+
+	// 分布方式，ketama时按权重一致性哈希，并暂时摘除连续失败的服务器
+	mc.distribution = DISTRIBUTION_MODULA
+	if tmp := mapCfg["distribution"]; tmp != "" {
+		if tmp != DISTRIBUTION_MODULA && tmp != DISTRIBUTION_KETAMA {
+			return fmt.Errorf("MemcCache: Distribution don't support %s", tmp)
+		}
+		mc.distribution = tmp
+	}
+	mc.weights = nil
+	if tmp := mapCfg["weights"]; tmp != "" {
+		for _, v := range strings.Split(tmp, ",") {
+			w, _ := strconv.Atoi(strings.TrimSpace(v))
+			mc.weights = append(mc.weights, w)
+		}
+	}
+	failureLimit, err := strconv.Atoi(mapCfg["failureLimit"])
+	if err != nil || failureLimit < 0 {
+		mc.failureLimit = DEFAULT_FAILURE_LIMIT
+	} else {
+		mc.failureLimit = failureLimit
+	}
+	retryTimeout, err := strconv.Atoi(mapCfg["retryTimeout"])
+	if err != nil || retryTimeout <= 0 {
+		mc.retryTimeout = DEFAULT_RETRY_TIMEOUT * time.Second
+	} else {
+		mc.retryTimeout = time.Duration(retryTimeout) * time.Second
 	}
 
-	err = mc.connect()
+	err = mc.connect(context.Background())
 	if err != nil {
 		return err
 	}
@@ -146,6 +268,20 @@ func (mc *MemcCache) Init(config string) error {
 	return nil
 }
 
+// AddHook 添加命令钩子
+//   参数
+//     hook: 钩子
+//   返回
+//
+func (mc *MemcCache) AddHook(hook cache.Hook) {
+	mc.hooks.AddHook(hook)
+}
+
+// Serializer 返回配置的序列化，实现cache.SerializerGetter
+func (mc *MemcCache) Serializer() cache.Serializer {
+	return mc.serializer
+}
+
 // Set 向缓存设置一个值
 //   参数
 //     key:    key值
@@ -155,10 +291,32 @@ func (mc *MemcCache) Init(config string) error {
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (mc *MemcCache) Set(key string, val interface{}, expire int32, encode ...bool) error {
-	if err := mc.connect(); err != nil {
+	return mc.SetCtx(context.Background(), key, val, expire, encode...)
+}
+
+// SetCtx 同Set，ctx用于控制超时和取消
+func (mc *MemcCache) SetCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) error {
+	if err := mc.connect(ctx); err != nil {
+		return err
+	}
+
+	item, err := mc.newItem(key, val, expire, encode...)
+	if err != nil {
 		return err
 	}
 
+	return mc.conn.Set(ctx, item)
+}
+
+// newItem 生成要保存的memcache数据，处理前缀、序列化、加密和压缩
+//   参数
+//     key:    key值，不含前缀
+//     val:    value值
+//     expire: 过期时间，单位秒
+//     encode: 是否加密标识
+//   返回
+//     memcache数据，失败返回错误信息
+func (mc *MemcCache) newItem(key string, val interface{}, expire int32, encode ...bool) (*memcache.Item, error) {
 	if mc.prefix != "" {
 		key = mc.prefix + key
 	}
@@ -169,18 +327,26 @@ func (mc *MemcCache) Set(key string, val interface{}, expire int32, encode ...bo
 	}
 	item := memcache.Item{Key: key, Expiration: expire}
 
-	// 类型转换
-	data, err := cache.InterToByte(val)
+	// 类型转换，兼容PHP时布尔值、[]byte和其它非字符串、数字的值按php-memcached的类型保存
+	var data []byte
+	var err error
+	phpFlags, isPhp := uint32(0), false
+	if mc.phpCompat {
+		phpFlags, data, isPhp, err = phpValue(val)
+	}
+	if !isPhp {
+		data, err = cache.InterToByte(val, mc.serializer)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// 加密判断
 	encode = append(encode, false)
 	if encode[0] {
-		data, err = cache.Encode(data, mc.encodeKey)
+		data, err = cache.Encode(data, mc.encodeKey...)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -203,19 +369,30 @@ func (mc *MemcCache) Set(key string, val interface{}, expire int32, encode ...bo
 		valType = "int"
 		flags = FLAGES_INT_UNCOMPRESS
 	}
+	if isPhp {
+		valType = "php"
+		flags = int(phpFlags)
+	}
 
-	// 目前只支持zlib压缩
-	if mc.compressType == COMPRESSION_ZLIB {
+	// 压缩，前4字节为小端的原始长度，不兼容PHP时压缩后只区分字符串和json
+	if mc.compressType != "" {
 		dataLen := len(data)
 		if dataLen > mc.compressThreshold {
 			if valType == "string" {
 				flags = FLAGES_STR_COMPRESS
-			} else {
+			} else if !mc.phpCompat {
 				flags = FLAGES_JSON_COMPRESS
+			} else {
+				flags |= FLAGES_COMPRESSED | FLAGES_COMPRESSION_ZLIB
 			}
-			data, err = utils.ZlibEncode(data)
-			if err != nil {
-				return err
+			if mc.compressType == COMPRESSION_FASTLZ {
+				flags = flags&^FLAGES_COMPRESSION_ZLIB | FLAGES_COMPRESSION_FASTLZ
+				data = utils.FastlzEncode(data)
+			} else {
+				data, err = utils.ZlibEncode(data)
+				if err != nil {
+					return nil, err
+				}
 			}
 			data = []byte(string(utils.Int32ToByte(int32(dataLen), false)) + string(data))
 		}
@@ -223,7 +400,7 @@ func (mc *MemcCache) Set(key string, val interface{}, expire int32, encode ...bo
 
 	item.Flags = uint32(flags)
 	item.Value = data
-	return mc.conn.Set(&item)
+	return &item, nil
 }
 
 // Get 从缓存取一个值
@@ -232,32 +409,27 @@ func (mc *MemcCache) Set(key string, val interface{}, expire int32, encode ...bo
 //   返回
 //     错误信息，是否存在
 func (mc *MemcCache) Get(key string, val interface{}) (error, bool) {
-	if err := mc.connect(); err != nil {
+	return mc.GetCtx(context.Background(), key, val)
+}
+
+// GetCtx 同Get，ctx用于控制超时和取消
+func (mc *MemcCache) GetCtx(ctx context.Context, key string, val interface{}) (error, bool) {
+	if err := mc.connect(ctx); err != nil {
 		return err, false
 	}
 
 	if mc.prefix != "" {
 		key = mc.prefix + key
 	}
-	item, err := mc.conn.Get(key)
+	item, err := mc.conn.Get(ctx, key)
 	if err != nil {
-		if strings.Contains(err.Error(), NOT_EXIST) {
+		if cache.IsMiss(err) {
 			return nil, false
 		}
 		return err, false
 	}
 
-	// 解压
-	data := item.Value
-	if item.Flags == FLAGES_JSON_COMPRESS || item.Flags == FLAGES_STR_COMPRESS {
-		data, err = utils.ZlibDecode(data[4:])
-		if err != nil {
-			return err, true
-		}
-	}
-
-	// 解密判断
-	data, err = cache.Decode(data, mc.encodeKey)
+	data, err := mc.itemData(item)
 	if err != nil {
 		return err, true
 	}
@@ -271,13 +443,56 @@ func (mc *MemcCache) Get(key string, val interface{}) (error, bool) {
 	return nil, true
 }
 
+// Lookup 从缓存取一个值，同Get，返回值顺序为(是否存在, 错误信息)
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     是否存在，错误信息，key不存在时返回false和nil
+func (mc *MemcCache) Lookup(key string, val interface{}) (bool, error) {
+	return mc.LookupCtx(context.Background(), key, val)
+}
+
+// LookupCtx 同Lookup，ctx用于控制超时和取消
+func (mc *MemcCache) LookupCtx(ctx context.Context, key string, val interface{}) (bool, error) {
+	err, exist := mc.GetCtx(ctx, key, val)
+	return exist, err
+}
+
+// itemData 获取memcache数据的值，处理解压、解密和PHP的类型转换
+//   参数
+//     item: memcache数据
+//   返回
+//     解压和解密后的值，失败返回错误信息
+func (mc *MemcCache) itemData(item *memcache.Item) ([]byte, error) {
+	// 解压
+	data, err := uncompressValue(item.Flags, item.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	// 解密判断
+	data, err = cache.Decode(data, mc.encodeKey...)
+	if err != nil {
+		return nil, err
+	}
+
+	// PHP的类型转换
+	return typedValue(item.Flags, data)
+}
+
 // Del 从缓存删除一个值
 //   参数
 //     key:    key值
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (mc *MemcCache) Del(key string) error {
-	if err := mc.connect(); err != nil {
+	return mc.DelCtx(context.Background(), key)
+}
+
+// DelCtx 同Del，ctx用于控制超时和取消
+func (mc *MemcCache) DelCtx(ctx context.Context, key string) error {
+	if err := mc.connect(ctx); err != nil {
 		return err
 	}
 
@@ -285,14 +500,157 @@ func (mc *MemcCache) Del(key string) error {
 		key = mc.prefix + key
 	}
 
-	err := mc.conn.Delete(key)
-	if err == nil || strings.Contains(err.Error(), NOT_EXIST) {
+	err := mc.conn.Delete(ctx, key)
+	if err == nil || cache.IsMiss(err) {
 		return nil
 	}
 
 	return err
 }
 
+// Add key不存在时设置一个值
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key已存在返回false，失败返回错误信息
+func (mc *MemcCache) Add(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return mc.AddCtx(context.Background(), key, val, expire, encode...)
+}
+
+// AddCtx 同Add，ctx用于控制超时和取消
+func (mc *MemcCache) AddCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	if err := mc.connect(ctx); err != nil {
+		return false, err
+	}
+
+	item, err := mc.newItem(key, val, expire, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	return storeResult(mc.conn.Add(ctx, item))
+}
+
+// Replace key存在时设置一个值
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key不存在返回false，失败返回错误信息
+func (mc *MemcCache) Replace(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return mc.ReplaceCtx(context.Background(), key, val, expire, encode...)
+}
+
+// ReplaceCtx 同Replace，ctx用于控制超时和取消
+func (mc *MemcCache) ReplaceCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	if err := mc.connect(ctx); err != nil {
+		return false, err
+	}
+
+	item, err := mc.newItem(key, val, expire, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	return storeResult(mc.conn.Replace(ctx, item))
+}
+
+// GetWithVersion 从缓存取一个值，同时返回数据的版本，用于CompareAndSwap
+// memcache的版本为gets命令返回的CAS唯一值
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     数据的版本，key不存在时返回nil，失败返回错误信息
+func (mc *MemcCache) GetWithVersion(key string, val interface{}) (*cache.Version, error) {
+	return mc.GetWithVersionCtx(context.Background(), key, val)
+}
+
+// GetWithVersionCtx 同GetWithVersion，ctx用于控制超时和取消
+func (mc *MemcCache) GetWithVersionCtx(ctx context.Context, key string, val interface{}) (*cache.Version, error) {
+	if err := mc.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	pKey := key
+	if mc.prefix != "" {
+		pKey = mc.prefix + key
+	}
+	item, err := mc.conn.Get(ctx, pKey)
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	ver := &cache.Version{Key: key, Token: item}
+	data, err := mc.itemData(item)
+	if err != nil {
+		return ver, err
+	}
+
+	// 类型转换
+	return ver, cache.ByteToInter(data, val)
+}
+
+// CompareAndSwap 数据的CAS唯一值与GetWithVersion返回的版本一致时设置新值
+// memcache读取不到剩余的过期时间，expire为0时不能保留原来的过期时间，key不再过期，需要过期时传入过期时间
+//   参数
+//     ver:    GetWithVersion返回的版本
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，数据已被修改或删除返回false，失败返回错误信息
+func (mc *MemcCache) CompareAndSwap(ver *cache.Version, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return mc.CompareAndSwapCtx(context.Background(), ver, val, expire, encode...)
+}
+
+// CompareAndSwapCtx 同CompareAndSwap，ctx用于控制超时和取消
+func (mc *MemcCache) CompareAndSwapCtx(ctx context.Context, ver *cache.Version, val interface{}, expire int32, encode ...bool) (bool, error) {
+	if ver == nil {
+		return false, errors.New("MemcCache: CompareAndSwap invalid version")
+	}
+	old, ok := ver.Token.(*memcache.Item)
+	if !ok {
+		return false, errors.New("MemcCache: CompareAndSwap invalid version")
+	}
+
+	if err := mc.connect(ctx); err != nil {
+		return false, err
+	}
+
+	item, err := mc.newItem(ver.Key, val, expire, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	// 复制一份，保留CAS唯一值
+	cas := *old
+	cas.Value, cas.Flags, cas.Expiration = item.Value, item.Flags, item.Expiration
+	err = mc.conn.CompareAndSwap(ctx, &cas)
+	if cache.IsMiss(err) || err == memcache.ErrCASConflict {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// storeResult 转换add、replace的返回，没有保存时返回false
+func storeResult(err error) (bool, error) {
+	if err == memcache.ErrNotStored {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
 // MSet 同时设置一个或多个key-value对
 //   参数
 //     mList:  key-value对
@@ -301,8 +659,13 @@ func (mc *MemcCache) Del(key string) error {
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (mc *MemcCache) MSet(mList map[string]interface{}, expire int32, encode ...bool) error {
+	return mc.MSetCtx(context.Background(), mList, expire, encode...)
+}
+
+// MSetCtx 同MSet，ctx用于控制超时和取消
+func (mc *MemcCache) MSetCtx(ctx context.Context, mList map[string]interface{}, expire int32, encode ...bool) error {
 	for key, val := range mList {
-		err := mc.Set(key, val, expire, encode...)
+		err := mc.SetCtx(ctx, key, val, expire, encode...)
 		if err != nil {
 			return err
 		}
@@ -316,9 +679,14 @@ func (mc *MemcCache) MSet(mList map[string]interface{}, expire int32, encode ...
 //   返回
 //     查询结果
 func (mc *MemcCache) MGet(keys ...string) (map[string]interface{}, error) {
+	return mc.MGetCtx(context.Background(), keys...)
+}
+
+// MGetCtx 同MGet，ctx用于控制超时和取消
+func (mc *MemcCache) MGetCtx(ctx context.Context, keys ...string) (map[string]interface{}, error) {
 	mList := make(map[string]interface{})
 
-	if err := mc.connect(); err != nil {
+	if err := mc.connect(ctx); err != nil {
 		return mList, err
 	}
 
@@ -328,28 +696,32 @@ func (mc *MemcCache) MGet(keys ...string) (map[string]interface{}, error) {
 		}
 	}
 
-	mv, err := mc.conn.GetMulti(keys)
+	mv, err := mc.conn.GetMulti(ctx, keys)
 	if err != nil {
 		return mList, err
 	}
 
 	for key, val := range mv {
 		// 解压
-		data := val.Value
-		if val.Flags == FLAGES_JSON_COMPRESS || val.Flags == FLAGES_STR_COMPRESS {
-			data, err = utils.ZlibDecode(data[4:])
-			if err != nil {
-				mList[key] = nil
-				continue
-			}
+		data, err := uncompressValue(val.Flags, val.Value)
+		if err != nil {
+			mList[key] = nil
+			continue
 		}
 
 		// 解密判断
-		data, err = cache.Decode(data, mc.encodeKey)
+		data, err = cache.Decode(data, mc.encodeKey...)
 		if err != nil {
 			return mList, err
 		}
 
+		// PHP的类型转换
+		data, err = typedValue(val.Flags, data)
+		if err != nil {
+			mList[key] = nil
+			continue
+		}
+
 		if mc.prefix != "" {
 			key = key[len(mc.prefix):]
 		}
@@ -365,8 +737,13 @@ func (mc *MemcCache) MGet(keys ...string) (map[string]interface{}, error) {
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (mc *MemcCache) MDel(keys ...string) error {
+	return mc.MDelCtx(context.Background(), keys...)
+}
+
+// MDelCtx 同MDel，ctx用于控制超时和取消
+func (mc *MemcCache) MDelCtx(ctx context.Context, keys ...string) error {
 	for _, key := range keys {
-		err := mc.Del(key)
+		err := mc.DelCtx(ctx, key)
 		if err != nil {
 			return err
 		}
@@ -382,7 +759,12 @@ func (mc *MemcCache) MDel(keys ...string) error {
 //   返回
 //     递增后的结果，失败返回错误信息
 func (mc *MemcCache) Incr(key string, delta ...uint64) (int64, error) {
-	if err := mc.connect(); err != nil {
+	return mc.IncrCtx(context.Background(), key, delta...)
+}
+
+// IncrCtx 同Incr，ctx用于控制超时和取消
+func (mc *MemcCache) IncrCtx(ctx context.Context, key string, delta ...uint64) (int64, error) {
+	if err := mc.connect(ctx); err != nil {
 		return 0, err
 	}
 
@@ -390,7 +772,7 @@ func (mc *MemcCache) Incr(key string, delta ...uint64) (int64, error) {
 		key = mc.prefix + key
 	}
 	delta = append(delta, 1)
-	v, err := mc.conn.Increment(key, delta[0])
+	v, err := mc.conn.Increment(ctx, key, delta[0])
 	return int64(v), err
 }
 
@@ -403,7 +785,12 @@ func (mc *MemcCache) Incr(key string, delta ...uint64) (int64, error) {
 //   返回
 //     递减后的结果，失败返回错误信息
 func (mc *MemcCache) Decr(key string, delta ...uint64) (int64, error) {
-	if err := mc.connect(); err != nil {
+	return mc.DecrCtx(context.Background(), key, delta...)
+}
+
+// DecrCtx 同Decr，ctx用于控制超时和取消
+func (mc *MemcCache) DecrCtx(ctx context.Context, key string, delta ...uint64) (int64, error) {
+	if err := mc.connect(ctx); err != nil {
 		return 0, err
 	}
 
@@ -411,26 +798,86 @@ func (mc *MemcCache) Decr(key string, delta ...uint64) (int64, error) {
 		key = mc.prefix + key
 	}
 	delta = append(delta, 1)
-	v, err := mc.conn.Decrement(key, delta[0])
+	v, err := mc.conn.Decrement(ctx, key, delta[0])
 	return int64(v), err
 }
 
+// IncrEx 缓存里的值自增并设置过期时间
+// key不存在时会新建一个，memcache的值不能小于0，递减到0后保持为0
+// memcache不能在自增时设置过期时间，key存在时自增后使用touch更新过期时间，不是原子操作
+//   参数
+//     key:    递增的key值
+//     delta:  递增的量，负数时为递减
+//     expire: 过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     递增后的结果，失败返回错误信息
+func (mc *MemcCache) IncrEx(key string, delta int64, expire int32) (int64, error) {
+	return mc.IncrExCtx(context.Background(), key, delta, expire)
+}
+
+// IncrExCtx 同IncrEx，ctx用于控制超时和取消
+func (mc *MemcCache) IncrExCtx(ctx context.Context, key string, delta int64, expire int32) (int64, error) {
+	if err := mc.connect(ctx); err != nil {
+		return 0, err
+	}
+
+	if mc.prefix != "" {
+		key = mc.prefix + key
+	}
+	for {
+		var v uint64
+		var err error
+		if delta >= 0 {
+			v, err = mc.conn.Increment(ctx, key, uint64(delta))
+		} else {
+			v, err = mc.conn.Decrement(ctx, key, uint64(-delta))
+		}
+
+		if cache.IsMiss(err) {
+			// key不存在时新建，其它请求已经新建时重新自增
+			n := delta
+			if n < 0 {
+				n = 0
+			}
+			err = mc.conn.Add(ctx, &memcache.Item{Key: key, Value: []byte(strconv.FormatInt(n, 10)), Expiration: expire})
+			if err == memcache.ErrNotStored {
+				continue
+			} else if err != nil {
+				return 0, err
+			}
+			return n, nil
+		} else if err != nil {
+			return 0, err
+		}
+
+		if expire > 0 {
+			err = mc.conn.Touch(ctx, key, expire)
+		}
+		return int64(v), err
+	}
+}
+
 // IsExist 判断key值是否存在
 //   参数
 //     key:  要查询的key值
 //   返回
 //     存在返回true，不存在返回false
 func (mc *MemcCache) IsExist(key string) (bool, error) {
-	if err := mc.connect(); err != nil {
+	return mc.IsExistCtx(context.Background(), key)
+}
+
+// IsExistCtx 同IsExist，ctx用于控制超时和取消
+func (mc *MemcCache) IsExistCtx(ctx context.Context, key string) (bool, error) {
+	if err := mc.connect(ctx); err != nil {
 		return false, err
 	}
 
 	if mc.prefix != "" {
 		key = mc.prefix + key
 	}
-	_, err := mc.conn.Get(key)
+	_, err := mc.conn.Get(ctx, key)
 	if err != nil {
-		if strings.Contains(err.Error(), NOT_EXIST) {
+		if cache.IsMiss(err) {
 			return false, nil
 		}
 		return false, err
@@ -445,119 +892,1512 @@ func (mc *MemcCache) IsExist(key string) (bool, error) {
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (mc *MemcCache) ClearAll() error {
-	if err := mc.connect(); err != nil {
+	return mc.ClearAllCtx(context.Background())
+}
+
+// ClearAllCtx 同ClearAll，ctx用于控制超时和取消
+func (mc *MemcCache) ClearAllCtx(ctx context.Context) error {
+	if err := mc.connect(ctx); err != nil {
 		return err
 	}
 
-	return mc.conn.FlushAll()
-}
-
-// Hset 添加哈希表，memcache没有哈希表
-func (mc *MemcCache) HSet(key string, field string, val interface{}, expire int32) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support HSet")
+	return mc.conn.FlushAll(ctx)
 }
 
-// HGet 查询哈希表数据，memcache没有哈希表
-func (mc *MemcCache) HGet(key string, field string, val interface{}) (error, bool) {
-	return errors.New("MemcCache: Memcache don't support HGet"), false
+// TTL 查询key的剩余过期时间，memcache不支持
+func (mc *MemcCache) TTL(key string) (time.Duration, error) {
+	return mc.TTLCtx(context.Background(), key)
 }
 
-// HDel 删除哈希表数据，memcache没有哈希表
-func (mc *MemcCache) HDel(key string, fields ...string) error {
-	return errors.New("MemcCache: Memcache don't support HDel")
+// TTLCtx 同TTL，ctx用于控制超时和取消
+func (mc *MemcCache) TTLCtx(ctx context.Context, key string) (time.Duration, error) {
+	return 0, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support TTL")
 }
 
-// HGetAll 返回哈希表 key 中，所有的域和值，memcache没有哈希表
-func (mc *MemcCache) HGetAll(key string) (map[string]interface{}, error) {
-	return nil, errors.New("MemcCache: Memcache don't support HGetAll")
+// Expire 设置key的过期时间，使用touch命令，memcache以秒为单位，不足1秒按1秒
+//   参数
+//     key:    key值
+//     expire: 过期时间，小于等于0时key立即过期
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (mc *MemcCache) Expire(key string, expire time.Duration) (bool, error) {
+	return mc.ExpireCtx(context.Background(), key, expire)
 }
 
-// HMSet 同时添加多个哈希表，memcache没有哈希表
-func (c *MemcCache) HMSet(key string, fields map[string]interface{}, expire int32) error {
-	return errors.New("MemcCache: Memcache don't support HMSet")
-}
+// ExpireCtx 同Expire，ctx用于控制超时和取消
+func (mc *MemcCache) ExpireCtx(ctx context.Context, key string, expire time.Duration) (bool, error) {
+	if expire <= 0 {
+		return mc.touch(ctx, key, -1)
+	}
 
-// HMGet 返回哈希表 key 中，一个或多个给定域的值，memcache没有哈希表
-func (mc *MemcCache) HMGet(key string, fields ...string) (map[string]interface{}, error) {
-	return nil, errors.New("MemcCache: Memcache don't support HMGet")
+	return mc.touch(ctx, key, durationExpire(expire))
 }
 
-// HVals 删除哈希表数据，memcache没有哈希表
-func (mc *MemcCache) HVals(key string) ([]interface{}, error) {
-	return nil, errors.New("MemcCache: Memcache don't support HVals")
+// ExpireAt 设置key在指定时间过期，使用touch命令
+//   参数
+//     key: key值
+//     tm:  过期的时间点，早于当前时间时key立即过期
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (mc *MemcCache) ExpireAt(key string, tm time.Time) (bool, error) {
+	return mc.ExpireAtCtx(context.Background(), key, tm)
 }
 
-// HIncr 哈希表的值自增，memcache没有哈希表
-func (mc *MemcCache) HIncr(key, fields string, delta ...uint64) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support HIncr")
-}
+// ExpireAtCtx 同ExpireAt，ctx用于控制超时和取消
+func (mc *MemcCache) ExpireAtCtx(ctx context.Context, key string, tm time.Time) (bool, error) {
+	if !tm.After(time.Now()) {
+		return mc.touch(ctx, key, -1)
+	}
 
-// HDecr 哈希表的值自减，memcache没有哈希表
-func (mc *MemcCache) HDecr(key, fields string, delta ...uint64) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support HDecr")
+	// 使用Unix纪元时间
+	return mc.touch(ctx, key, int32(tm.Unix()))
 }
 
-// ZSet 添加有序集合，memcache没有有序集合
-func (mc *MemcCache) ZSet(key string, expire int32, val ...interface{}) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support ZSet")
+// Persist 删除key的过期时间，使用touch命令
+//   参数
+//     key: key值
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (mc *MemcCache) Persist(key string) (bool, error) {
+	return mc.PersistCtx(context.Background(), key)
 }
 
-// ZGet 查询有序集合，memcache没有有序集合
-func (mc *MemcCache) ZGet(key string, start, stop int, withScores bool, isRev bool) ([]string, error) {
-	return nil, errors.New("MemcCache: Memcache don't support ZGet")
+// PersistCtx 同Persist，ctx用于控制超时和取消
+func (mc *MemcCache) PersistCtx(ctx context.Context, key string) (bool, error) {
+	return mc.touch(ctx, key, 0)
 }
 
-// ZDel 删除有序集合数据，memcache没有有序集合
-func (rc *MemcCache) ZDel(key string, field ...string) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support ZDel")
+// Touch 更新key的过期时间，与Set的expire参数一致
+//   参数
+//     key:    key值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (mc *MemcCache) Touch(key string, expire int32) (bool, error) {
+	return mc.TouchCtx(context.Background(), key, expire)
 }
 
-// ZRemRangeByRank 删除有序集合数据，memcache没有有序集合
-func (rc *MemcCache) ZRemRangeByRank(key string, start, end int64) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support ZRemRangeByRank")
-}
+// TouchCtx 同Touch，ctx用于控制超时和取消
+func (mc *MemcCache) TouchCtx(ctx context.Context, key string, expire int32) (bool, error) {
+	// 超过30天使用Unix纪元时间
+	if expire > 86400*30 {
+		expire = int32(time.Now().Unix()) + expire
+	}
 
-// ZRemRangeByScore 删除有序集合数据，memcache没有有序集合
-func (rc *MemcCache) ZRemRangeByScore(key string, start, end string) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support ZRemRangeByScore")
+	return mc.touch(ctx, key, expire)
 }
 
-// ZRemRangeByLex 删除有序集合数据，memcache没有有序集合
-func (rc *MemcCache) ZRemRangeByLex(key string, start, end string) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support ZRemRangeByLex")
+// GetEx 从缓存取一个值，同时更新过期时间，用于滑动过期
+// memcache客户端不支持gat命令，读取后使用touch更新过期时间，需要两次请求
+//   参数
+//     key:    key值
+//     val:    保存结果地址
+//     expire: 新的过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     错误信息，是否存在
+func (mc *MemcCache) GetEx(key string, val interface{}, expire int32) (error, bool) {
+	return mc.GetExCtx(context.Background(), key, val, expire)
 }
 
-// ZCard 返回有序集 key 的基数，memcache没有有序集合
-func (mc *MemcCache) ZCard(key string) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support ZCard")
-}
+// GetExCtx 同GetEx，ctx用于控制超时和取消
+func (mc *MemcCache) GetExCtx(ctx context.Context, key string, val interface{}, expire int32) (error, bool) {
+	err, exist := mc.GetCtx(ctx, key, val)
+	if err != nil || !exist || expire <= 0 {
+		return err, exist
+	}
 
-// SetBit 设置或清除指定偏移量上的位(bit)，memcache没有位图
-func (mc *MemcCache) SetBit(key string, offset int64, value int, expire int32) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support Bit")
+	_, err = mc.TouchCtx(ctx, key, expire)
+	return err, true
 }
 
-// GetBit 获取指定偏移量上的位(bit)，memcache没有位图
-func (mc *MemcCache) GetBit(key string, offset int64) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support Bit")
-}
+// touch 执行touch命令
+//   参数
+//     ctx:    上下文
+//     key:    key值，不含前缀
+//     expire: memcache的过期时间
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (mc *MemcCache) touch(ctx context.Context, key string, expire int32) (bool, error) {
+	if err := mc.connect(ctx); err != nil {
+		return false, err
+	}
+
+	if mc.prefix != "" {
+		key = mc.prefix + key
+	}
+	err := mc.conn.Touch(ctx, key, expire)
+	if cache.IsMiss(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// memcStruct 哈希表、有序集合、位图和HyperLogLog在memcache里的存储结构
+// 整个结构序列化为json保存在一个key里，修改时用gets+cas读改写，冲突时重试
+type memcStruct struct {
+	Type     string             `json:"type"`               // 结构类型
+	Deadline int64              `json:"deadline,omitempty"` // 过期的Unix时间戳，0表示没有到期时间
+	Hash     map[string][]byte  `json:"hash,omitempty"`     // 哈希表
+	ZSet     map[string]float64 `json:"zset,omitempty"`     // 有序集合，成员-分值
+	Bits     []byte             `json:"bits,omitempty"`     // 位图
+	Members  map[string]bool    `json:"members,omitempty"`  // HyperLogLog的成员，memcache版为精确计数
+
+	isNew bool // key不存在，新建的数据结构
+}
+
+// empty 数据结构是否为空，为空时删除key
+func (s *memcStruct) empty() bool {
+	switch s.Type {
+	case STRUCT_HASH:
+		return len(s.Hash) == 0
+	case STRUCT_ZSET:
+		return len(s.ZSet) == 0
+	}
+
+	return false
+}
+
+// loadStruct 查询数据结构
+//   参数
+//     ctx:  上下文
+//     key:  添加前缀后的key值
+//     typ:  结构类型
+//   返回
+//     memcache数据、数据结构，key不存在时都返回nil，类型不一致时返回errWrongType
+func (mc *MemcCache) loadStruct(ctx context.Context, key, typ string) (*memcache.Item, *memcStruct, error) {
+	if err := mc.connect(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	item, err := mc.conn.Get(ctx, key)
+	if cache.IsMiss(err) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := mc.itemData(item)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s := &memcStruct{}
+	if err := json.Unmarshal(data, s); err != nil || s.Type != typ {
+		return nil, nil, errWrongType
+	}
+
+	return item, s, nil
+}
+
+// updateStruct 用CAS读改写数据结构，其它请求同时修改时重新读取后再修改，最多重试MAX_CAS_RETRY次
+//   参数
+//     ctx:    上下文
+//     key:    key值，不含前缀
+//     typ:    结构类型
+//     expire: 过期时间，以秒为单位，“0”表示保留原来的过期时间
+//     fn:     修改数据结构的函数，key不存在时传入空的数据结构，返回数据是否有修改
+//   返回
+//     成功返回nil，失败返回错误信息
+func (mc *MemcCache) updateStruct(ctx context.Context, key, typ string, expire int32, fn func(s *memcStruct) (bool, error)) error {
+	pKey := key
+	if mc.prefix != "" {
+		pKey = mc.prefix + key
+	}
+
+	for i := 0; i < MAX_CAS_RETRY; i++ {
+		old, s, err := mc.loadStruct(ctx, pKey, typ)
+		if err != nil {
+			return err
+		} else if s == nil {
+			s = &memcStruct{Type: typ, isNew: true}
+		}
+
+		changed, err := fn(s)
+		if err != nil {
+			return err
+		} else if !changed && (old == nil || expire <= 0) {
+			return nil
+		}
+
+		if expire > 0 {
+			s.Deadline = time.Now().Unix() + int64(expire)
+		}
+
+		var item *memcache.Item
+		if s.empty() {
+			// 没有数据时让key立即过期
+			if old == nil {
+				return nil
+			}
+			item = &memcache.Item{Key: pKey, Expiration: -1}
+		} else {
+			data, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			item, err = mc.newItem(key, string(data), 0)
+			if err != nil {
+				return err
+			}
+			if len(item.Value) > mc.maxItemSize {
+				return fmt.Errorf("MemcCache: %s size %d exceeds the max item size %d", typ, len(item.Value), mc.maxItemSize)
+			}
+
+			// 过期时间统一使用Unix纪元时间，修改时可以保留原来的过期时间
+			item.Expiration = int32(s.Deadline)
+		}
+
+		if old == nil {
+			err = mc.conn.Add(ctx, item)
+		} else {
+			cas := *old
+			cas.Value, cas.Flags, cas.Expiration = item.Value, item.Flags, item.Expiration
+			err = mc.conn.CompareAndSwap(ctx, &cas)
+		}
+		if err == memcache.ErrNotStored || err == memcache.ErrCASConflict || cache.IsMiss(err) {
+			continue
+		}
+
+		return err
+	}
+
+	return fmt.Errorf("MemcCache: %s update conflicts more than %d times", key, MAX_CAS_RETRY)
+}
+
+// HSet 添加哈希表，哈希表序列化后保存在key里
+//   参数
+//     key:    哈希表key值
+//     field:  哈希表field值
+//     val:    哈希表value值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示保留原来的过期时间
+//   返回
+//     成功时返回新添加的field个数，失败返回错误信息
+func (mc *MemcCache) HSet(key string, field string, val interface{}, expire int32) (int64, error) {
+	return mc.HSetCtx(context.Background(), key, field, val, expire)
+}
+
+// HSetCtx 同HSet，ctx用于控制超时和取消
+func (mc *MemcCache) HSetCtx(ctx context.Context, key string, field string, val interface{}, expire int32) (int64, error) {
+	return mc.hSet(ctx, key, map[string]interface{}{field: val}, expire)
+}
+
+// hSet 同时设置多个field-value对
+//   参数
+//     ctx:    上下文
+//     key:    哈希表key值
+//     fields: field-value对
+//     expire: 缓存过期时间，以秒为单位，“0”表示保留原来的过期时间
+//   返回
+//     成功时返回新添加的field个数，失败返回错误信息
+func (mc *MemcCache) hSet(ctx context.Context, key string, fields map[string]interface{}, expire int32) (int64, error) {
+	vals := make(map[string][]byte, len(fields))
+	for field, val := range fields {
+		data, err := cache.InterToByte(val, mc.serializer)
+		if err != nil {
+			return -1, err
+		}
+		vals[field] = data
+	}
+
+	var n int64
+	err := mc.updateStruct(ctx, key, STRUCT_HASH, expire, func(s *memcStruct) (bool, error) {
+		if s.Hash == nil {
+			s.Hash = make(map[string][]byte, len(vals))
+		}
+
+		n = 0
+		for field, data := range vals {
+			if _, ok := s.Hash[field]; !ok {
+				n++
+			}
+			s.Hash[field] = data
+		}
+		return true, nil
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return n, nil
+}
+
+// HGet 查询哈希表数据
+//   参数
+//     key:   哈希表key值
+//     field: 哈希表field值
+//     val:   保存结果地址
+//   返回
+//     错误信息，是否存在
+func (mc *MemcCache) HGet(key string, field string, val interface{}) (error, bool) {
+	return mc.HGetCtx(context.Background(), key, field, val)
+}
+
+// HGetCtx 同HGet，ctx用于控制超时和取消
+func (mc *MemcCache) HGetCtx(ctx context.Context, key string, field string, val interface{}) (error, bool) {
+	s, err := mc.getStruct(ctx, key, STRUCT_HASH)
+	if err != nil || s == nil {
+		return err, false
+	}
+
+	data, ok := s.Hash[field]
+	if !ok {
+		return nil, false
+	}
+
+	// 类型转换
+	err = cache.ByteToInter(data, val)
+	if err != nil {
+		return err, true
+	}
+
+	return nil, true
+}
+
+// getStruct 查询数据结构
+//   参数
+//     ctx: 上下文
+//     key: key值，不含前缀
+//     typ: 结构类型
+//   返回
+//     数据结构，key不存在时返回nil，失败返回错误信息
+func (mc *MemcCache) getStruct(ctx context.Context, key, typ string) (*memcStruct, error) {
+	if mc.prefix != "" {
+		key = mc.prefix + key
+	}
+
+	_, s, err := mc.loadStruct(ctx, key, typ)
+	return s, err
+}
+
+// HDel 删除哈希表数据，没有数据时删除key
+//   参数
+//     key:    哈希表key值
+//     fields: 哈希表field值
+//   返回
+//     成功返回nil，失败返回错误信息
+func (mc *MemcCache) HDel(key string, fields ...string) error {
+	return mc.HDelCtx(context.Background(), key, fields...)
+}
+
+// HDelCtx 同HDel，ctx用于控制超时和取消
+func (mc *MemcCache) HDelCtx(ctx context.Context, key string, fields ...string) error {
+	return mc.updateStruct(ctx, key, STRUCT_HASH, 0, func(s *memcStruct) (bool, error) {
+		changed := false
+		for _, field := range fields {
+			if _, ok := s.Hash[field]; ok {
+				delete(s.Hash, field)
+				changed = true
+			}
+		}
+		return changed, nil
+	})
+}
+
+// HGetAll 返回哈希表 key 中，所有的域和值，struct、map类型需要业务层调用json.Unmarshal
+//   参数
+//     key: 哈希表key值
+//   返回
+//     查询的结果数据和错误码
+func (mc *MemcCache) HGetAll(key string) (map[string]interface{}, error) {
+	return mc.HGetAllCtx(context.Background(), key)
+}
+
+// HGetAllCtx 同HGetAll，ctx用于控制超时和取消
+func (mc *MemcCache) HGetAllCtx(ctx context.Context, key string) (map[string]interface{}, error) {
+	s, err := mc.getStruct(ctx, key, STRUCT_HASH)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]interface{})
+	if s == nil {
+		return res, nil
+	}
+	for k, v := range s.Hash {
+		res[k] = string(v)
+	}
+
+	return res, nil
+}
+
+// HMSet 同时将多个 field-value (域-值)对设置到哈希表 key 中
+//   参数
+//     key:    哈希表key值
+//     fields: field-value 对
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示保留原来的过期时间
+//   返回
+//     执行结果
+func (mc *MemcCache) HMSet(key string, fields map[string]interface{}, expire int32) error {
+	return mc.HMSetCtx(context.Background(), key, fields, expire)
+}
+
+// HMSetCtx 同HMSet，ctx用于控制超时和取消
+func (mc *MemcCache) HMSetCtx(ctx context.Context, key string, fields map[string]interface{}, expire int32) error {
+	_, err := mc.hSet(ctx, key, fields, expire)
+	return err
+}
+
+// HMGet 返回哈希表 key 中，一个或多个给定域的值，struct、map类型需要业务层调用json.Unmarshal
+//   参数
+//     key:    哈希表key值
+//     fields: 给定域的集合
+//   返回
+//     查询的结果数据和错误码，field不存在时对应的val为nil
+func (mc *MemcCache) HMGet(key string, fields ...string) (map[string]interface{}, error) {
+	return mc.HMGetCtx(context.Background(), key, fields...)
+}
+
+// HMGetCtx 同HMGet，ctx用于控制超时和取消
+func (mc *MemcCache) HMGetCtx(ctx context.Context, key string, fields ...string) (map[string]interface{}, error) {
+	s, err := mc.getStruct(ctx, key, STRUCT_HASH)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]interface{})
+	for _, field := range fields {
+		res[field] = nil
+		if s == nil {
+			continue
+		}
+		if v, ok := s.Hash[field]; ok {
+			res[field] = string(v)
+		}
+	}
+
+	return res, nil
+}
+
+// HVals 返回哈希表 key 中，所有域的值，struct、map类型需要业务层调用json.Unmarshal
+//   参数
+//     key: 哈希表key值
+//   返回
+//     查询的结果数据和错误码
+func (mc *MemcCache) HVals(key string) ([]interface{}, error) {
+	return mc.HValsCtx(context.Background(), key)
+}
+
+// HValsCtx 同HVals，ctx用于控制超时和取消
+func (mc *MemcCache) HValsCtx(ctx context.Context, key string) ([]interface{}, error) {
+	s, err := mc.getStruct(ctx, key, STRUCT_HASH)
+	if err != nil {
+		return nil, err
+	} else if s == nil {
+		return []interface{}{}, nil
+	}
+
+	res := make([]interface{}, 0, len(s.Hash))
+	for _, v := range s.Hash {
+		res = append(res, string(v))
+	}
+
+	return res, nil
+}
+
+// hIncrBy 哈希表的值增加指定的量，field不存在时从0开始
+//   参数
+//     ctx:   上下文
+//     key:   哈希表key值
+//     field: 哈希表field值
+//     delta: 增加的量
+//   返回
+//     增加后的结果，失败返回错误信息
+func (mc *MemcCache) hIncrBy(ctx context.Context, key, field string, delta int64) (int64, error) {
+	var n int64
+	err := mc.updateStruct(ctx, key, STRUCT_HASH, 0, func(s *memcStruct) (bool, error) {
+		if s.Hash == nil {
+			s.Hash = make(map[string][]byte)
+		}
+
+		n = 0
+		if data, ok := s.Hash[field]; ok {
+			var err error
+			n, err = strconv.ParseInt(string(data), 10, 64)
+			if err != nil {
+				return false, errNotInt
+			}
+		}
+
+		n += delta
+		s.Hash[field] = []byte(strconv.FormatInt(n, 10))
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// HIncr 哈希表的值自增
+//   参数
+//     key:    哈希表key值
+//     fields: 哈希表field值
+//     delta:  递增的量，默认为1
+//   返回
+//     递增后的结果、失败返回错误信息
+func (mc *MemcCache) HIncr(key, fields string, delta ...uint64) (int64, error) {
+	return mc.HIncrCtx(context.Background(), key, fields, delta...)
+}
+
+// HIncrCtx 同HIncr，ctx用于控制超时和取消
+func (mc *MemcCache) HIncrCtx(ctx context.Context, key, fields string, delta ...uint64) (int64, error) {
+	delta = append(delta, 1)
+	return mc.hIncrBy(ctx, key, fields, int64(delta[0]))
+}
+
+// HDecr 哈希表的值自减
+//   参数
+//     key:    哈希表key值
+//     fields: 哈希表field值
+//     delta:  递减的量，默认为1
+//   返回
+//     递减后的结果、失败返回错误信息
+func (mc *MemcCache) HDecr(key, fields string, delta ...uint64) (int64, error) {
+	return mc.HDecrCtx(context.Background(), key, fields, delta...)
+}
+
+// HDecrCtx 同HDecr，ctx用于控制超时和取消
+func (mc *MemcCache) HDecrCtx(ctx context.Context, key, fields string, delta ...uint64) (int64, error) {
+	delta = append(delta, 1)
+	return mc.hIncrBy(ctx, key, fields, 0-int64(delta[0]))
+}
+
+// ZSet 添加有序集合，有序集合序列化后保存在key里
+//   参数
+//     key:    有序集合key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示保留原来的过期时间
+//     val:    有序集合值，数据为成对出来，前面为score(整数值或双精度浮点数), 后面为变量
+//   返回
+//     成功添加的数据个数和错误码
+func (mc *MemcCache) ZSet(key string, expire int32, val ...interface{}) (int64, error) {
+	return mc.ZSetCtx(context.Background(), key, expire, val...)
+}
+
+// ZSetCtx 同ZSet，ctx用于控制超时和取消
+func (mc *MemcCache) ZSetCtx(ctx context.Context, key string, expire int32, val ...interface{}) (int64, error) {
+	members, err := zset.ParseMembers(val)
+	if err != nil {
+		return -1, err
+	}
+
+	var n int64
+	err = mc.updateStruct(ctx, key, STRUCT_ZSET, expire, func(s *memcStruct) (bool, error) {
+		if s.ZSet == nil {
+			s.ZSet = make(map[string]float64, len(members))
+		}
+
+		n = 0
+		for _, m := range members {
+			if _, ok := s.ZSet[m.Member]; !ok {
+				n++
+			}
+			s.ZSet[m.Member] = m.Score
+		}
+		return true, nil
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return n, nil
+}
+
+// ZGet 查询有序集合
+//   参数
+//     key:        有序集合key值
+//     start:      要查询有序集开始下标，0表示第一个，-1表示最后一个，-2表示倒数第二个
+//     stop:       要查询有序集结束下标，0表示第一个，-1表示最后一个，-2表示倒数第二个
+//     withScores: 是否带上score
+//     isRev:      true-递减排列 false-递增排列
+//   返回
+//     查询的结果数据和错误码
+func (mc *MemcCache) ZGet(key string, start, stop int, withScores bool, isRev bool) ([]string, error) {
+	return mc.ZGetCtx(context.Background(), key, start, stop, withScores, isRev)
+}
+
+// ZGetCtx 同ZGet，ctx用于控制超时和取消
+func (mc *MemcCache) ZGetCtx(ctx context.Context, key string, start, stop int, withScores bool, isRev bool) ([]string, error) {
+	s, err := mc.getStruct(ctx, key, STRUCT_ZSET)
+	if err != nil || s == nil {
+		return []string{}, err
+	}
+
+	return zset.Range(s.ZSet, start, stop, withScores, isRev), nil
+}
+
+// zRem 删除有序集合里满足条件的成员，没有数据时删除key
+//   参数
+//     ctx: 上下文
+//     key: 有序集合key值
+//     fn:  判断是否要删除的函数，参数为排序后的成员列表、下标和成员
+//   返回
+//     成功删除的数据个数和错误码
+func (mc *MemcCache) zRem(ctx context.Context, key string, fn func(members []zset.Member, i int) bool) (int64, error) {
+	var n int64
+	err := mc.updateStruct(ctx, key, STRUCT_ZSET, 0, func(s *memcStruct) (bool, error) {
+		n = 0
+		members := zset.Sort(s.ZSet)
+		for i, m := range members {
+			if fn(members, i) {
+				delete(s.ZSet, m.Member)
+				n++
+			}
+		}
+		return n > 0, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// ZDel 删除有序集合数据
+//   参数
+//     key:   有序集合key值
+//     field: 要删除的数据
+//   返回
+//     成功删除的数据个数和错误码
+func (mc *MemcCache) ZDel(key string, field ...string) (int64, error) {
+	return mc.ZDelCtx(context.Background(), key, field...)
+}
+
+// ZDelCtx 同ZDel，ctx用于控制超时和取消
+func (mc *MemcCache) ZDelCtx(ctx context.Context, key string, field ...string) (int64, error) {
+	fields := make(map[string]bool, len(field))
+	for _, f := range field {
+		fields[f] = true
+	}
+
+	return mc.zRem(ctx, key, func(members []zset.Member, i int) bool {
+		return fields[members[i].Member]
+	})
+}
+
+// ZRemRangeByRank 删除指定排名区间内的有序集合数据
+//   参数
+//     key:   有序集合key值
+//     start: 开始值
+//     end:   结束值
+//   返回
+//     成功删除的数据个数和错误码
+func (mc *MemcCache) ZRemRangeByRank(key string, start, end int64) (int64, error) {
+	return mc.ZRemRangeByRankCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByRankCtx 同ZRemRangeByRank，ctx用于控制超时和取消
+func (mc *MemcCache) ZRemRangeByRankCtx(ctx context.Context, key string, start, end int64) (int64, error) {
+	return mc.zRem(ctx, key, func(members []zset.Member, i int) bool {
+		from, to := zset.RangeIndex(start, end, int64(len(members)))
+		return int64(i) >= from && int64(i) < to
+	})
+}
+
+// ZRemRangeByScore 删除指定分值区间内的有序集合数据
+//   参数
+//     key:   有序集合key值
+//     start: 开始值
+//     end:   结束值
+//   返回
+//     成功删除的数据个数和错误码
+func (mc *MemcCache) ZRemRangeByScore(key string, start, end string) (int64, error) {
+	return mc.ZRemRangeByScoreCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByScoreCtx 同ZRemRangeByScore，ctx用于控制超时和取消
+func (mc *MemcCache) ZRemRangeByScoreCtx(ctx context.Context, key string, start, end string) (int64, error) {
+	r, err := zset.ParseScoreRange(start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	return mc.zRem(ctx, key, func(members []zset.Member, i int) bool {
+		return r.Contains(members[i].Score)
+	})
+}
+
+// ZRemRangeByLex 删除指定变量区间内的有序集合数据
+// 对于一个所有成员的分值都相同的有序集合键 key 来说， 这个命令会移除该集合中， 成员介于 min 和 max 范围内的所有元素。
+//   参数
+//     key:   有序集合key值
+//     start: 开始值
+//     end:   结束值
+//   返回
+//     成功删除的数据个数和错误码
+func (mc *MemcCache) ZRemRangeByLex(key string, start, end string) (int64, error) {
+	return mc.ZRemRangeByLexCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByLexCtx 同ZRemRangeByLex，ctx用于控制超时和取消
+func (mc *MemcCache) ZRemRangeByLexCtx(ctx context.Context, key string, start, end string) (int64, error) {
+	r, err := zset.ParseLexRange(start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	return mc.zRem(ctx, key, func(members []zset.Member, i int) bool {
+		return r.Contains(members[i].Member)
+	})
+}
+
+// ZCard 返回有序集 key 的基数
+//   参数
+//     key: 有序集合key值
+//   返回
+//     有序集 key 的基数和错误码
+func (mc *MemcCache) ZCard(key string) (int64, error) {
+	return mc.ZCardCtx(context.Background(), key)
+}
+
+// ZCardCtx 同ZCard，ctx用于控制超时和取消
+func (mc *MemcCache) ZCardCtx(ctx context.Context, key string) (int64, error) {
+	s, err := mc.getStruct(ctx, key, STRUCT_ZSET)
+	if err != nil || s == nil {
+		return 0, err
+	}
+
+	return int64(len(s.ZSet)), nil
+}
+
+// SetBit 设置或清除指定偏移量上的位(bit)，位图序列化后保存在key里，偏移量受最大长度限制
+//   参数
+//     key:    位图key值
+//     offset: 位图偏移量
+//     value:  位图值，取值：0或1
+//     expire: 失效时长，以秒为单位：从现在开始的相对时间，“0”表示保留原来的过期时间
+//   返回
+//     指定偏移量原来储存的位、错误信息
+func (mc *MemcCache) SetBit(key string, offset int64, value int, expire int32) (int64, error) {
+	return mc.SetBitCtx(context.Background(), key, offset, value, expire)
+}
+
+// SetBitCtx 同SetBit，ctx用于控制超时和取消
+func (mc *MemcCache) SetBitCtx(ctx context.Context, key string, offset int64, value int, expire int32) (int64, error) {
+	if offset < 0 || offset >= int64(mc.maxItemSize)*8 {
+		return 0, errors.New("MemcCache: bit offset is not an integer or out of range")
+	}
+	if value != 0 && value != 1 {
+		return 0, errors.New("MemcCache: bit is not an integer or out of range")
+	}
+
+	var old int64
+	err := mc.updateStruct(ctx, key, STRUCT_BIT, expire, func(s *memcStruct) (bool, error) {
+		idx := offset / 8
+		if int64(len(s.Bits)) <= idx {
+			grown := make([]byte, idx+1)
+			copy(grown, s.Bits)
+			s.Bits = grown
+		}
+
+		mask := byte(1 << uint(7-offset%8))
+		old = 0
+		if s.Bits[idx]&mask != 0 {
+			old = 1
+		}
+		if value == 1 {
+			s.Bits[idx] |= mask
+		} else {
+			s.Bits[idx] &^= mask
+		}
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return old, nil
+}
+
+// GetBit 获取指定偏移量上的位(bit)
+//   参数
+//     key:    位图key值
+//     offset: 位图偏移量
+//   返回
+//     字符串值指定偏移量上的位(bit)、错误信息
+func (mc *MemcCache) GetBit(key string, offset int64) (int64, error) {
+	return mc.GetBitCtx(context.Background(), key, offset)
+}
 
-// BitCount 计算给定字符串中被设置为 1 的比特位的数量，memcache没有位图
+// GetBitCtx 同GetBit，ctx用于控制超时和取消
+func (mc *MemcCache) GetBitCtx(ctx context.Context, key string, offset int64) (int64, error) {
+	if offset < 0 {
+		return 0, errors.New("MemcCache: bit offset is not an integer or out of range")
+	}
+
+	s, err := mc.getStruct(ctx, key, STRUCT_BIT)
+	if err != nil || s == nil {
+		return 0, err
+	}
+
+	idx := offset / 8
+	if int64(len(s.Bits)) <= idx {
+		return 0, nil
+	}
+	if s.Bits[idx]&byte(1<<uint(7-offset%8)) != 0 {
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+// BitCount 计算给定字符串中被设置为 1 的比特位的数量
+//   参数
+//     key:      位图key值
+//     bitCount: 指定额外的 start 或 end 参数，以字节为单位，统计只在特定的位上进行，为nil时统计所有的
+//   返回
+//     给定字符串中被设置为 1 的比特位的数量、错误信息
 func (mc *MemcCache) BitCount(key string, bitCount *cache.BitCount) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support Bit")
+	return mc.BitCountCtx(context.Background(), key, bitCount)
+}
+
+// BitCountCtx 同BitCount，ctx用于控制超时和取消
+func (mc *MemcCache) BitCountCtx(ctx context.Context, key string, bitCount *cache.BitCount) (int64, error) {
+	s, err := mc.getStruct(ctx, key, STRUCT_BIT)
+	if err != nil || s == nil {
+		return 0, err
+	}
+
+	data := s.Bits
+	if bitCount != nil {
+		from, to := zset.RangeIndex(bitCount.Start, bitCount.End, int64(len(data)))
+		data = data[from:to]
+	}
+
+	var n int64
+	for _, b := range data {
+		for ; b != 0; b &= b - 1 {
+			n++
+		}
+	}
+
+	return n, nil
 }
 
-// PFAdd 添加基数(HyperLogLog)，memcache没有HyperLogLog
+// PFAdd 添加基数，memcache版保存所有成员，为精确计数，成员数受最大长度限制
+//   参数
+//     key:    HyperLogLog的key值
+//     expire: 失效时长，以秒为单位：从现在开始的相对时间，“0”表示保留原来的过期时间
+//     vals:   HyperLogLog的数据
+//   返回
+//     基数有变化返回1，否则返回0
+//     错误信息
 func (mc *MemcCache) PFAdd(key string, expire int32, vals ...interface{}) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support PFAdd")
+	return mc.PFAddCtx(context.Background(), key, expire, vals...)
+}
+
+// PFAddCtx 同PFAdd，ctx用于控制超时和取消
+func (mc *MemcCache) PFAddCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error) {
+	members := make([]string, 0, len(vals))
+	for _, v := range vals {
+		data, err := cache.InterToByte(v)
+		if err != nil {
+			return 0, err
+		}
+		members = append(members, string(data))
+	}
+
+	var res int64
+	err := mc.updateStruct(ctx, key, STRUCT_HLL, expire, func(s *memcStruct) (bool, error) {
+		res = 0
+		if s.isNew {
+			// 新建的HyperLogLog即使没有成员也返回1
+			res = 1
+		}
+		if s.Members == nil {
+			s.Members = make(map[string]bool, len(members))
+		}
+
+		for _, m := range members {
+			if !s.Members[m] {
+				s.Members[m] = true
+				res = 1
+			}
+		}
+		return res == 1, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return res, nil
 }
 
-// PFAdd 返回基数估算值(HyperLogLog)，memcache没有HyperLogLog
+// PFCount 返回基数估算值，memcache版为精确计数
+//   参数
+//     key: HyperLogLog的key值
+//   返回
+//     基数估算值
 func (mc *MemcCache) PFCount(key string) (int64, error) {
-	return 0, errors.New("MemcCache: Memcache don't support PFCount")
+	return mc.PFCountCtx(context.Background(), key)
+}
+
+// PFCountCtx 同PFCount，ctx用于控制超时和取消
+func (mc *MemcCache) PFCountCtx(ctx context.Context, key string) (int64, error) {
+	s, err := mc.getStruct(ctx, key, STRUCT_HLL)
+	if err != nil || s == nil {
+		return 0, err
+	}
+
+	return int64(len(s.Members)), nil
+}
+
+// LPush 从列表头部插入元素，memcache没有列表
+func (mc *MemcCache) LPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return mc.LPushCtx(context.Background(), key, expire, vals...)
+}
+
+// LPushCtx 同LPush，ctx用于控制超时和取消
+func (mc *MemcCache) LPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error) {
+	return 0, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support LPush")
+}
+
+// RPush 从列表尾部插入元素，memcache没有列表
+func (mc *MemcCache) RPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return mc.RPushCtx(context.Background(), key, expire, vals...)
+}
+
+// RPushCtx 同RPush，ctx用于控制超时和取消
+func (mc *MemcCache) RPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error) {
+	return 0, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support RPush")
+}
+
+// LPop 从列表头部取出一个元素，memcache没有列表
+func (mc *MemcCache) LPop(key string, val interface{}) (error, bool) {
+	return mc.LPopCtx(context.Background(), key, val)
+}
+
+// LPopCtx 同LPop，ctx用于控制超时和取消
+func (mc *MemcCache) LPopCtx(ctx context.Context, key string, val interface{}) (error, bool) {
+	return cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support LPop"), false
+}
+
+// BRPop 阻塞地从多个列表尾部取出一个元素，memcache没有列表
+func (mc *MemcCache) BRPop(timeout int32, val interface{}, keys ...string) (string, error) {
+	return mc.BRPopCtx(context.Background(), timeout, val, keys...)
+}
+
+// BRPopCtx 同BRPop，ctx用于控制超时和取消
+func (mc *MemcCache) BRPopCtx(ctx context.Context, timeout int32, val interface{}, keys ...string) (string, error) {
+	return "", cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support BRPop")
+}
+
+// LRange 查询列表指定区间的元素，memcache没有列表
+func (mc *MemcCache) LRange(key string, start, stop int64) ([]interface{}, error) {
+	return mc.LRangeCtx(context.Background(), key, start, stop)
+}
+
+// LRangeCtx 同LRange，ctx用于控制超时和取消
+func (mc *MemcCache) LRangeCtx(ctx context.Context, key string, start, stop int64) ([]interface{}, error) {
+	return nil, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support LRange")
+}
+
+// LTrim 只保留列表指定区间的元素，memcache没有列表
+func (mc *MemcCache) LTrim(key string, start, stop int64) error {
+	return mc.LTrimCtx(context.Background(), key, start, stop)
+}
+
+// LTrimCtx 同LTrim，ctx用于控制超时和取消
+func (mc *MemcCache) LTrimCtx(ctx context.Context, key string, start, stop int64) error {
+	return cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support LTrim")
+}
+
+// SAdd 向集合添加成员，memcache没有集合
+func (mc *MemcCache) SAdd(key string, expire int32, members ...interface{}) (int64, error) {
+	return mc.SAddCtx(context.Background(), key, expire, members...)
+}
+
+// SAddCtx 同SAdd，ctx用于控制超时和取消
+func (mc *MemcCache) SAddCtx(ctx context.Context, key string, expire int32, members ...interface{}) (int64, error) {
+	return 0, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support SAdd")
+}
+
+// SRem 删除集合的成员，memcache没有集合
+func (mc *MemcCache) SRem(key string, members ...interface{}) (int64, error) {
+	return mc.SRemCtx(context.Background(), key, members...)
+}
+
+// SRemCtx 同SRem，ctx用于控制超时和取消
+func (mc *MemcCache) SRemCtx(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	return 0, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support SRem")
+}
+
+// SIsMember 判断是否为集合的成员，memcache没有集合
+func (mc *MemcCache) SIsMember(key string, member interface{}) (bool, error) {
+	return mc.SIsMemberCtx(context.Background(), key, member)
+}
+
+// SIsMemberCtx 同SIsMember，ctx用于控制超时和取消
+func (mc *MemcCache) SIsMemberCtx(ctx context.Context, key string, member interface{}) (bool, error) {
+	return false, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support SIsMember")
+}
+
+// SMembers 查询集合的所有成员，memcache没有集合
+func (mc *MemcCache) SMembers(key string) ([]string, error) {
+	return mc.SMembersCtx(context.Background(), key)
+}
+
+// SMembersCtx 同SMembers，ctx用于控制超时和取消
+func (mc *MemcCache) SMembersCtx(ctx context.Context, key string) ([]string, error) {
+	return nil, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support SMembers")
+}
+
+// SInter 返回多个集合的交集，memcache没有集合
+func (mc *MemcCache) SInter(keys ...string) ([]string, error) {
+	return mc.SInterCtx(context.Background(), keys...)
+}
+
+// SInterCtx 同SInter，ctx用于控制超时和取消
+func (mc *MemcCache) SInterCtx(ctx context.Context, keys ...string) ([]string, error) {
+	return nil, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support SInter")
+}
+
+// SUnion 返回多个集合的并集，memcache没有集合
+func (mc *MemcCache) SUnion(keys ...string) ([]string, error) {
+	return mc.SUnionCtx(context.Background(), keys...)
+}
+
+// SUnionCtx 同SUnion，ctx用于控制超时和取消
+func (mc *MemcCache) SUnionCtx(ctx context.Context, keys ...string) ([]string, error) {
+	return nil, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support SUnion")
+}
+
+// XAdd 向流添加消息，memcache没有流
+func (mc *MemcCache) XAdd(key string, maxLen int64, expire int32, vals map[string]interface{}) (string, error) {
+	return mc.XAddCtx(context.Background(), key, maxLen, expire, vals)
+}
+
+// XAddCtx 同XAdd，ctx用于控制超时和取消
+func (mc *MemcCache) XAddCtx(ctx context.Context, key string, maxLen int64, expire int32, vals map[string]interface{}) (string, error) {
+	return "", cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support XAdd")
+}
+
+// XRead 读取流消息，memcache没有流
+func (mc *MemcCache) XRead(args *cache.XReadArgs) ([]cache.XStream, error) {
+	return mc.XReadCtx(context.Background(), args)
+}
+
+// XReadCtx 同XRead，ctx用于控制超时和取消
+func (mc *MemcCache) XReadCtx(ctx context.Context, args *cache.XReadArgs) ([]cache.XStream, error) {
+	return nil, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support XRead")
+}
+
+// XGroupCreate 创建消费组，流不存在时自动创建，memcache没有流
+func (mc *MemcCache) XGroupCreate(key, group, start string) error {
+	return mc.XGroupCreateCtx(context.Background(), key, group, start)
+}
+
+// XGroupCreateCtx 同XGroupCreate，ctx用于控制超时和取消
+func (mc *MemcCache) XGroupCreateCtx(ctx context.Context, key, group, start string) error {
+	return cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support XGroupCreate")
+}
+
+// XReadGroup 按消费组读取流消息，memcache没有流
+func (mc *MemcCache) XReadGroup(args *cache.XReadGroupArgs) ([]cache.XStream, error) {
+	return mc.XReadGroupCtx(context.Background(), args)
+}
+
+// XReadGroupCtx 同XReadGroup，ctx用于控制超时和取消
+func (mc *MemcCache) XReadGroupCtx(ctx context.Context, args *cache.XReadGroupArgs) ([]cache.XStream, error) {
+	return nil, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support XReadGroup")
+}
+
+// XAck 确认消费组的消息，memcache没有流
+func (mc *MemcCache) XAck(key, group string, ids ...string) (int64, error) {
+	return mc.XAckCtx(context.Background(), key, group, ids...)
+}
+
+// XAckCtx 同XAck，ctx用于控制超时和取消
+func (mc *MemcCache) XAckCtx(ctx context.Context, key, group string, ids ...string) (int64, error) {
+	return 0, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support XAck")
+}
+
+// Eval 执行lua脚本，memcache不支持
+func (mc *MemcCache) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return mc.EvalCtx(context.Background(), script, keys, args...)
+}
+
+// EvalCtx 同Eval，ctx用于控制超时和取消
+func (mc *MemcCache) EvalCtx(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return nil, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support Eval")
+}
+
+// EvalSha 按sha1执行lua脚本，memcache不支持
+func (mc *MemcCache) EvalSha(sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return mc.EvalShaCtx(context.Background(), sha1, keys, args...)
+}
+
+// EvalShaCtx 同EvalSha，ctx用于控制超时和取消
+func (mc *MemcCache) EvalShaCtx(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return nil, cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support EvalSha")
+}
+
+// ScriptLoad 加载lua脚本，memcache不支持
+func (mc *MemcCache) ScriptLoad(script string) (string, error) {
+	return mc.ScriptLoadCtx(context.Background(), script)
+}
+
+// ScriptLoadCtx 同ScriptLoad，ctx用于控制超时和取消
+func (mc *MemcCache) ScriptLoadCtx(ctx context.Context, script string) (string, error) {
+	return "", cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support ScriptLoad")
+}
+
+// AcquireLock 获取分布式锁，使用add命令，由cache/lock包使用
+//   参数
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 持有者的随机token
+//     ttl:   锁的过期时间，memcache以秒为单位，不足1秒按1秒
+//   返回
+//     获取到返回true，锁已存在返回false，失败返回错误信息
+func (mc *MemcCache) AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	if err := mc.connect(ctx); err != nil {
+		return false, err
+	}
+
+	if mc.prefix != "" {
+		key = mc.prefix + key
+	}
+	err := mc.conn.Add(ctx, &memcache.Item{Key: key, Value: []byte(token), Expiration: durationExpire(ttl)})
+	if err == memcache.ErrNotStored {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// ReleaseLock 释放分布式锁，token一致时用cas将锁设置为立即过期
+//   参数
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 持有者的随机token
+//   返回
+//     删除成功返回true，锁不存在或不是持有者返回false，失败返回错误信息
+func (mc *MemcCache) ReleaseLock(ctx context.Context, key, token string) (bool, error) {
+	return mc.casLock(ctx, key, token, -1)
+}
+
+// RefreshLock 更新分布式锁的过期时间，token一致时用cas更新
+//   参数
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 持有者的随机token
+//     ttl:   新的过期时间
+//   返回
+//     更新成功返回true，锁不存在或不是持有者返回false，失败返回错误信息
+func (mc *MemcCache) RefreshLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	return mc.casLock(ctx, key, token, durationExpire(ttl))
+}
+
+// casLock 比较token后用cas更新锁的过期时间，过期时间为负数时锁立即过期
+func (mc *MemcCache) casLock(ctx context.Context, key, token string, expire int32) (bool, error) {
+	if err := mc.connect(ctx); err != nil {
+		return false, err
+	}
+
+	if mc.prefix != "" {
+		key = mc.prefix + key
+	}
+	item, err := mc.conn.Get(ctx, key)
+	if cache.IsMiss(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if string(item.Value) != token {
+		return false, nil
+	}
+
+	item.Expiration = expire
+	err = mc.conn.CompareAndSwap(ctx, item)
+	if err == memcache.ErrCASConflict || err == memcache.ErrNotStored {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// durationExpire 时间间隔转为memcache的过期时间，单位秒，向上取整，最少1秒
+func durationExpire(ttl time.Duration) int32 {
+	expire := int32((ttl + time.Second - 1) / time.Second)
+	if expire < 1 {
+		expire = 1
+	}
+
+	// 超过30天使用Unix纪元时间
+	if expire > 86400*30 {
+		expire = int32(time.Now().Unix()) + expire
+	}
+
+	return expire
+}
+
+// Batch 新建批量命令，key前缀、序列化、压缩和加密与Set、Get等方法一致
+// memcache没有pipeline，命令按顺序执行，连续的Get合并为一次GetMulti
+// memcache不支持事务，isTx为true时Exec返回错误
+//   参数
+//     isTx: 是否事务模式
+//   返回
+//     批量命令
+func (mc *MemcCache) Batch(isTx bool) *cache.Batch {
+	return cache.NewBatch(isTx, mc.execBatch)
+}
+
+// execBatch 按顺序执行批量命令
+func (mc *MemcCache) execBatch(ctx context.Context, cmds []*cache.BatchCmd, isTx bool) error {
+	if isTx {
+		return cache.NewError(cache.ErrUnsupported, "MemcCache: Memcache don't support transaction")
+	}
+
+	for i := 0; i < len(cmds); {
+		if err := mc.connect(ctx); err != nil {
+			for _, cmd := range cmds[i:] {
+				cmd.SetResult(nil, 0, false, err)
+			}
+			break
+		}
+
+		// 连续的Get合并为一次GetMulti
+		j := i
+		for j < len(cmds) && cmds[j].Op == cache.BatchGet {
+			j++
+		}
+		if j > i {
+			mc.batchGet(ctx, cmds[i:j])
+			i = j
+			continue
+		}
+
+		mc.batchCmd(ctx, cmds[i])
+		i++
+	}
+
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// batchGet 使用GetMulti执行多个Get命令
+func (mc *MemcCache) batchGet(ctx context.Context, cmds []*cache.BatchCmd) {
+	keys := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		keys[i] = mc.prefix + cmd.Key
+	}
+
+	items, err := mc.conn.GetMulti(ctx, keys)
+	for i, cmd := range cmds {
+		if err != nil {
+			cmd.SetResult(nil, 0, false, err)
+			continue
+		}
+
+		item, ok := items[keys[i]]
+		if !ok {
+			cmd.SetResult(nil, 0, false, nil)
+			continue
+		}
+
+		data, dataErr := mc.itemData(item)
+		cmd.SetResult(data, 0, true, dataErr)
+	}
+}
+
+// batchCmd 执行一个命令，memcache没有哈希表，哈希表命令返回错误
+func (mc *MemcCache) batchCmd(ctx context.Context, cmd *cache.BatchCmd) {
+	key := mc.prefix + cmd.Key
+	switch cmd.Op {
+	case cache.BatchSet:
+		item, err := mc.newItem(cmd.Key, cmd.Val, cmd.Expire, cmd.Encode)
+		if err == nil {
+			err = mc.conn.Set(ctx, item)
+		}
+		cmd.SetResult(nil, 0, err == nil, err)
+	case cache.BatchDel:
+		err := mc.conn.Delete(ctx, key)
+		if err == nil {
+			cmd.SetResult(nil, 1, true, nil)
+		} else if cache.IsMiss(err) {
+			cmd.SetResult(nil, 0, false, nil)
+		} else {
+			cmd.SetResult(nil, 0, false, err)
+		}
+	case cache.BatchIncr:
+		var v uint64
+		var err error
+		if cmd.Delta >= 0 {
+			v, err = mc.conn.Increment(ctx, key, uint64(cmd.Delta))
+		} else {
+			v, err = mc.conn.Decrement(ctx, key, uint64(-cmd.Delta))
+		}
+		cmd.SetResult(nil, int64(v), err == nil, err)
+	case cache.BatchExpire:
+		// 超过30天使用Unix纪元时间
+		expire := cmd.Expire
+		if expire > 86400*30 {
+			expire = int32(time.Now().Unix()) + expire
+		}
+		err := mc.conn.Touch(ctx, key, expire)
+		if err == nil {
+			cmd.SetResult(nil, 1, true, nil)
+		} else if cache.IsMiss(err) {
+			cmd.SetResult(nil, 0, false, nil)
+		} else {
+			cmd.SetResult(nil, 0, false, err)
+		}
+	default:
+		cmd.SetResult(nil, 0, false, cache.NewError(cache.ErrUnsupported, fmt.Sprintf("MemcCache: Memcache don't support %s", cmd.Op)))
+	}
 }
 
 // Pipeline 执行pipeline命令，memcache不支持pipeline
 func (mc *MemcCache) Pipeline(isTx bool) cache.Pipeliner {
 	return cache.Pipeliner{}
 }
+
+// memcClient memcache客户端，执行命令前后调用钩子，memcache.ErrCacheMiss转为cache.ErrMiss
+// 未命中、未存储、CAS冲突是正常的执行结果，不作为钩子里的错误
+type memcClient struct {
+	*memcache.Client
+	prefix   string          // key前缀，钩子里的key会去掉此前缀
+	hooks    *cache.Hooks    // 适配器的钩子列表
+	selector *ketamaSelector // 一致性哈希选择器，单key命令的结果用于摘除失败的服务器，取模分布时为nil
+}
+
+// process 执行命令并调用钩子
+//   参数
+//     ctx:  上下文
+//     cmd:  命令名称
+//     key:  添加前缀后的key值
+//     read: 是否为读命令，读命令没有错误时为命中
+//     fn:   执行命令的函数
+//   返回
+//     命令的错误信息
+func (c *memcClient) process(ctx context.Context, cmd, key string, read bool, fn func() error) error {
+	ctx, info := c.hooks.BeforeCmd(ctx, cache.AdapterMemcache, cmd, strings.TrimPrefix(key, c.prefix))
+	err := fn()
+	if c.selector != nil && cmd != "get_multi" && cmd != "flush_all" {
+		// 使用gomemcache执行命令时选择的服务器，key不合法时没有选择
+		if addr := c.selector.picked(key); addr != nil {
+			c.selector.report(addr, err)
+		}
+	}
+	if err == memcache.ErrCacheMiss {
+		err = cache.WrapError(cache.ErrMiss, err)
+	}
+	if info == nil {
+		return err
+	}
+
+	var hit *bool
+	if read {
+		h := err == nil
+		hit = &h
+	}
+	e := err
+	if cache.IsMiss(e) || e == memcache.ErrNotStored || e == memcache.ErrCASConflict {
+		e = nil
+	}
+	c.hooks.AfterCmd(ctx, info, e, hit)
+
+	return err
+}
+
+func (c *memcClient) Get(ctx context.Context, key string) (item *memcache.Item, err error) {
+	err = c.process(ctx, "get", key, true, func() error {
+		item, err = c.Client.Get(key)
+		return err
+	})
+	return
+}
+
+// GetMulti 所有key都存在时为命中
+func (c *memcClient) GetMulti(ctx context.Context, keys []string) (items map[string]*memcache.Item, err error) {
+	key := ""
+	if len(keys) > 0 {
+		key = keys[0]
+	}
+	err = c.process(ctx, "get_multi", key, true, func() error {
+		items, err = c.Client.GetMulti(keys)
+		if c.selector != nil {
+			// 不记录结果，只取出选择的服务器，gomemcache遇到不合法的key时后面的key没有选择
+			for _, k := range keys {
+				if !legalKey(k) {
+					break
+				}
+				c.selector.picked(k)
+			}
+		}
+		if err == nil && len(items) < len(keys) {
+			return memcache.ErrCacheMiss
+		}
+		return err
+	})
+	if cache.IsMiss(err) {
+		err = nil
+	}
+	return
+}
+
+func (c *memcClient) Set(ctx context.Context, item *memcache.Item) error {
+	return c.process(ctx, "set", item.Key, false, func() error {
+		return c.Client.Set(item)
+	})
+}
+
+func (c *memcClient) Add(ctx context.Context, item *memcache.Item) error {
+	return c.process(ctx, "add", item.Key, false, func() error {
+		return c.Client.Add(item)
+	})
+}
+
+func (c *memcClient) Replace(ctx context.Context, item *memcache.Item) error {
+	return c.process(ctx, "replace", item.Key, false, func() error {
+		return c.Client.Replace(item)
+	})
+}
+
+func (c *memcClient) CompareAndSwap(ctx context.Context, item *memcache.Item) error {
+	return c.process(ctx, "cas", item.Key, false, func() error {
+		return c.Client.CompareAndSwap(item)
+	})
+}
+
+func (c *memcClient) Delete(ctx context.Context, key string) error {
+	return c.process(ctx, "delete", key, false, func() error {
+		return c.Client.Delete(key)
+	})
+}
+
+func (c *memcClient) Increment(ctx context.Context, key string, delta uint64) (v uint64, err error) {
+	err = c.process(ctx, "incr", key, false, func() error {
+		v, err = c.Client.Increment(key, delta)
+		return err
+	})
+	return
+}
+
+func (c *memcClient) Decrement(ctx context.Context, key string, delta uint64) (v uint64, err error) {
+	err = c.process(ctx, "decr", key, false, func() error {
+		v, err = c.Client.Decrement(key, delta)
+		return err
+	})
+	return
+}
+
+func (c *memcClient) Touch(ctx context.Context, key string, seconds int32) error {
+	return c.process(ctx, "touch", key, false, func() error {
+		return c.Client.Touch(key, seconds)
+	})
+}
+
+func (c *memcClient) FlushAll(ctx context.Context) error {
+	return c.process(ctx, "flush_all", "", false, func() error {
+		return c.Client.FlushAll()
+	})
+}
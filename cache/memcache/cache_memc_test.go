@@ -1,9 +1,15 @@
 package memcache
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/lixy529/gotools/cache"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestMemcCache(t *testing.T) {
@@ -438,3 +444,600 @@ func TestMemcIJson(t *testing.T) {
 		return
 	}
 }
+
+func TestMemcCtx(t *testing.T) {
+	var adapter cache.ContextCache = &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","maxIdle":"10","ioTimeOut":"300","prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	k1 := "ctx_k1"
+	v1 := "HelloWorld"
+	err = adapter.SetCtx(ctx, k1, v1, 10)
+	if err != nil {
+		t.Errorf("Memc SetCtx failed. err: %s.", err.Error())
+		return
+	}
+
+	var v11 string
+	err, _ = adapter.GetCtx(ctx, k1, &v11)
+	if err != nil {
+		t.Errorf("Memc GetCtx failed. err: %s.", err.Error())
+		return
+	} else if v11 != v1 {
+		t.Errorf("Memc GetCtx failed. Got %s, expected %s.", v11, v1)
+		return
+	}
+
+	// 已取消的ctx
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	cancel2()
+	err, _ = adapter.GetCtx(ctx2, k1, &v11)
+	if err == nil {
+		t.Errorf("Memc GetCtx failed. expected context error.")
+		return
+	}
+}
+
+func TestMemcBatch(t *testing.T) {
+	adapter := &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","prefix":"le_","compressType":"zlib","compressThreshold":"10","encodeKey":"abcdefghij123456"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.MDel("batch_k1", "batch_k2", "batch_n1")
+
+	type User struct {
+		Id   int
+		Name string
+	}
+	b := adapter.Batch(false)
+	b.Set("batch_k1", User{Id: 1001, Name: "HelloWorld HelloWorld"}, 60, true)
+	b.Set("batch_n1", 10, 60)
+	get := b.Get("batch_k1")
+	miss := b.Get("batch_k2")
+	incr := b.Incr("batch_n1", 2)
+	decr := b.Decr("batch_n1")
+	err = b.Exec()
+	if err != nil {
+		t.Errorf("Memc Batch Exec failed. err: %s.", err.Error())
+		return
+	}
+
+	var u User
+	err, exist := get.Scan(&u)
+	if err != nil || !exist || u.Id != 1001 || u.Name != "HelloWorld HelloWorld" {
+		t.Errorf("Memc Batch Get failed. Got %v, expected 1001-HelloWorld HelloWorld.", u)
+	}
+	var s string
+	if err, exist = miss.Scan(&s); err != nil || exist {
+		t.Error("Memc Batch Get failed. batch_k2 is exist.")
+	}
+	if n, _ := incr.Int(); n != 12 {
+		t.Errorf("Memc Batch Incr failed. Got %d, expected 12.", n)
+	}
+	if n, _ := decr.Int(); n != 11 {
+		t.Errorf("Memc Batch Decr failed. Got %d, expected 11.", n)
+	}
+
+	// 与普通方法使用相同的前缀和加密
+	u = User{}
+	err, exist = adapter.Get("batch_k1", &u)
+	if err != nil || !exist || u.Id != 1001 {
+		t.Errorf("Memc Get failed. Got %v, expected 1001.", u)
+	}
+
+	// 不支持哈希表和事务
+	b.HSet("batch_h1", "f1", "hv1", 60)
+	if err = b.Exec(); err == nil {
+		t.Error("Memc Batch HSet failed. expected error.")
+	}
+	tx := adapter.Batch(true)
+	tx.Del("batch_k1")
+	if err = tx.Exec(); err == nil {
+		t.Error("Memc Batch isTx failed. expected error.")
+	}
+
+	adapter.MDel("batch_k1", "batch_n1")
+}
+
+func TestMemcUnsupported(t *testing.T) {
+	adapter := &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","maxIdle":"10","ioTimeOut":"300","prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+
+	if _, err = adapter.LPush("list_l1", 60, "v1"); err == nil {
+		t.Errorf("Memc LPush failed. expected unsupported error.")
+	}
+	if _, err = adapter.SAdd("set_s1", 60, "a"); err == nil {
+		t.Errorf("Memc SAdd failed. expected unsupported error.")
+	}
+	if _, err = adapter.XAdd("stream_x1", 0, 60, map[string]interface{}{"k": "v"}); err == nil {
+		t.Errorf("Memc XAdd failed. expected unsupported error.")
+	}
+	if _, err = adapter.Eval("return 1", nil); err == nil {
+		t.Errorf("Memc Eval failed. expected unsupported error.")
+	}
+}
+
+func TestMemcIncrEx(t *testing.T) {
+	adapter := &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","maxIdle":"10","ioTimeOut":"300","prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("incr_k1")
+
+	n, err := adapter.IncrEx("incr_k1", 5, 100)
+	if err != nil || n != 5 {
+		t.Errorf("Memc IncrEx failed. Got %d, expected 5, err: %v.", n, err)
+	}
+	n, err = adapter.IncrEx("incr_k1", -2, 100)
+	if err != nil || n != 3 {
+		t.Errorf("Memc IncrEx failed. Got %d, expected 3, err: %v.", n, err)
+	}
+	n, err = adapter.IncrEx("incr_k1", 1, 0)
+	if err != nil || n != 4 {
+		t.Errorf("Memc IncrEx failed. Got %d, expected 4, err: %v.", n, err)
+	}
+	adapter.Del("incr_k1")
+}
+
+func TestMemcLock(t *testing.T) {
+	adapter := &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","maxIdle":"10","ioTimeOut":"300","prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("lock_k1")
+
+	ctx := context.Background()
+	ok, err := adapter.AcquireLock(ctx, "lock_k1", "token1", 500*time.Millisecond)
+	if err != nil || !ok {
+		t.Errorf("Memc AcquireLock failed. err: %v.", err)
+		return
+	}
+	ok, err = adapter.AcquireLock(ctx, "lock_k1", "token2", time.Second)
+	if err != nil || ok {
+		t.Errorf("Memc AcquireLock failed. lock_k1 is not locked.")
+	}
+
+	ok, err = adapter.RefreshLock(ctx, "lock_k1", "token2", time.Second)
+	if err != nil || ok {
+		t.Errorf("Memc RefreshLock failed. token2 is not owner.")
+	}
+	ok, err = adapter.RefreshLock(ctx, "lock_k1", "token1", 2*time.Second)
+	if err != nil || !ok {
+		t.Errorf("Memc RefreshLock failed. err: %v.", err)
+	}
+
+	ok, err = adapter.ReleaseLock(ctx, "lock_k1", "token2")
+	if err != nil || ok {
+		t.Errorf("Memc ReleaseLock failed. token2 is not owner.")
+	}
+	ok, err = adapter.ReleaseLock(ctx, "lock_k1", "token1")
+	if err != nil || !ok {
+		t.Errorf("Memc ReleaseLock failed. err: %v.", err)
+	}
+
+	ok, err = adapter.AcquireLock(ctx, "lock_k1", "token2", time.Second)
+	if err != nil || !ok {
+		t.Errorf("Memc AcquireLock failed. err: %v.", err)
+	}
+	adapter.Del("lock_k1")
+}
+
+func TestMemcCas(t *testing.T) {
+	adapter := &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","maxIdle":"10","ioTimeOut":"300","prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("cas_k1")
+
+	// Add、Replace
+	ok, err := adapter.Replace("cas_k1", "v0", 60)
+	if err != nil || ok {
+		t.Errorf("Memc Replace failed. cas_k1 is not exist, err: %v.", err)
+	}
+	ok, err = adapter.Add("cas_k1", "v1", 60)
+	if err != nil || !ok {
+		t.Errorf("Memc Add failed. err: %v.", err)
+	}
+	ok, err = adapter.Add("cas_k1", "v2", 60)
+	if err != nil || ok {
+		t.Errorf("Memc Add failed. cas_k1 is exist, err: %v.", err)
+	}
+	ok, err = adapter.Replace("cas_k1", map[string]int{"n": 1}, 60)
+	if err != nil || !ok {
+		t.Errorf("Memc Replace failed. err: %v.", err)
+	}
+
+	// GetWithVersion、CompareAndSwap
+	doc := map[string]int{}
+	ver, err := adapter.GetWithVersion("cas_k1", &doc)
+	if err != nil || ver == nil || doc["n"] != 1 {
+		t.Errorf("Memc GetWithVersion failed. Got %v, err: %v.", doc, err)
+		return
+	}
+	doc["n"]++
+	ok, err = adapter.CompareAndSwap(ver, doc, 60)
+	if err != nil || !ok {
+		t.Errorf("Memc CompareAndSwap failed. err: %v.", err)
+	}
+	ok, err = adapter.CompareAndSwap(ver, doc, 60)
+	if err != nil || ok {
+		t.Errorf("Memc CompareAndSwap failed. version is changed, err: %v.", err)
+	}
+
+	ver, err = adapter.GetWithVersion("cas_k1", &doc)
+	if err != nil || ver == nil || doc["n"] != 2 {
+		t.Errorf("Memc GetWithVersion failed. Got %v, err: %v.", doc, err)
+		return
+	}
+	adapter.Set("cas_k1", "v3", 60)
+	ok, err = adapter.CompareAndSwap(ver, "v4", 60)
+	if err != nil || ok {
+		t.Errorf("Memc CompareAndSwap failed. cas_k1 is modified, err: %v.", err)
+	}
+
+	adapter.Del("cas_k1")
+	ver, err = adapter.GetWithVersion("cas_k1", &doc)
+	if err != nil || ver != nil {
+		t.Errorf("Memc GetWithVersion failed. cas_k1 is not exist, err: %v.", err)
+	}
+}
+
+func TestMemcTTL(t *testing.T) {
+	adapter := &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","maxIdle":"10","ioTimeOut":"300","prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("ttl_k1")
+	adapter.Del("ttl_k2")
+
+	if _, err = adapter.TTL("ttl_k1"); err == nil {
+		t.Errorf("Memc TTL failed. Memcache don't support TTL.")
+	}
+
+	adapter.Set("ttl_k1", "v1", 0)
+	ok, err := adapter.Touch("ttl_k1", 100)
+	if err != nil || !ok {
+		t.Errorf("Memc Touch failed. err: %v.", err)
+	}
+	ok, err = adapter.Touch("ttl_k2", 100)
+	if err != nil || ok {
+		t.Errorf("Memc Touch failed. ttl_k2 is not exist, err: %v.", err)
+	}
+	ok, err = adapter.Expire("ttl_k1", 10*time.Second)
+	if err != nil || !ok {
+		t.Errorf("Memc Expire failed. err: %v.", err)
+	}
+	ok, err = adapter.ExpireAt("ttl_k1", time.Now().Add(time.Minute))
+	if err != nil || !ok {
+		t.Errorf("Memc ExpireAt failed. err: %v.", err)
+	}
+	ok, err = adapter.Persist("ttl_k1")
+	if err != nil || !ok {
+		t.Errorf("Memc Persist failed. err: %v.", err)
+	}
+
+	v := ""
+	err, exist := adapter.GetEx("ttl_k1", &v, 200)
+	if err != nil || !exist || v != "v1" {
+		t.Errorf("Memc GetEx failed. Got %s, err: %v.", v, err)
+	}
+	err, exist = adapter.GetEx("ttl_k2", &v, 200)
+	if err != nil || exist {
+		t.Errorf("Memc GetEx failed. ttl_k2 is not exist, err: %v.", err)
+	}
+	adapter.Del("ttl_k1")
+}
+
+func TestMemcHook(t *testing.T) {
+	adapter := &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","maxIdle":"10","ioTimeOut":"300","prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+	collector := cache.NewCollector("")
+	adapter.AddHook(collector)
+	adapter.Del("hook_k1")
+	adapter.Del("hook_k2")
+
+	v := ""
+	adapter.Set("hook_k1", "v1", 100)
+	adapter.Get("hook_k1", &v)
+	adapter.Get("hook_k2", &v)
+	adapter.Get("hook_k1", &v)
+	if r := collector.HitRatio(cache.AdapterMemcache); r < 0.66 || r > 0.67 {
+		t.Errorf("Memc HitRatio failed. Got %f, expected 0.67.", r)
+	}
+
+	body := string(collector.Bytes())
+	for _, line := range []string{
+		`cache_commands_total{adapter="memcache",cmd="set"} 1`,
+		`cache_hits_total{adapter="memcache",cmd="get"} 2`,
+		`cache_misses_total{adapter="memcache",cmd="get"} 1`,
+		`cache_errors_total{adapter="memcache",cmd="get"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Memc Hook failed. %s not found in:\n%s", line, body)
+		}
+	}
+	adapter.Del("hook_k1")
+}
+
+func TestMemcLookup(t *testing.T) {
+	adapter := &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","maxIdle":"10","ioTimeOut":"300","prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("lookup_k1")
+	adapter.Del("lookup_k2")
+
+	v := ""
+	exist, err := adapter.Lookup("lookup_k1", &v)
+	if err != nil || exist {
+		t.Errorf("Memc Lookup failed. lookup_k1 is not exist, err: %v.", err)
+	}
+	adapter.Set("lookup_k1", "v1", 100)
+	exist, err = adapter.LookupCtx(context.Background(), "lookup_k1", &v)
+	if err != nil || !exist || v != "v1" {
+		t.Errorf("Memc Lookup failed. Got %s, expected v1, err: %v.", v, err)
+	}
+
+	// 反序列化失败
+	n := 0
+	exist, err = adapter.Lookup("lookup_k1", &n)
+	if !errors.Is(err, cache.ErrDecode) {
+		t.Errorf("Memc Lookup failed. Got %v, expected cache.ErrDecode.", err)
+	}
+
+	// 不支持的操作、自增不存在的key
+	if _, err = adapter.LPush("lookup_k2", 0, 1); !errors.Is(err, cache.ErrUnsupported) {
+		t.Errorf("Memc LPush failed. Got %v, expected cache.ErrUnsupported.", err)
+	}
+	if _, err = adapter.Incr("lookup_k2"); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("Memc Incr failed. Got %v, expected cache.ErrMiss.", err)
+	}
+	adapter.Del("lookup_k1")
+}
+
+func TestMemcHash(t *testing.T) {
+	adapter := &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","maxIdle":"10","ioTimeOut":"300","prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+
+	key := "addr"
+	adapter.Del(key)
+	n, err := adapter.HSet(key, "baidu", "www.baidu.com", 60)
+	if err != nil || n != 1 {
+		t.Errorf("Memc HSet failed. Got %d, expected %d, err: %v.", n, 1, err)
+		return
+	}
+	adapter.HMSet(key, map[string]interface{}{"le": "www.le.com", "num": 10}, 60)
+
+	var v string
+	err, exist := adapter.HGet(key, "le", &v)
+	if err != nil || !exist || v != "www.le.com" {
+		t.Errorf("Memc HGet failed. Got %s, expected %s.", v, "www.le.com")
+		return
+	}
+
+	all, err := adapter.HGetAll(key)
+	if err != nil || len(all) != 3 || all["num"] != "10" {
+		t.Errorf("Memc HGetAll failed. Got %v.", all)
+		return
+	}
+
+	m, err := adapter.HMGet(key, "baidu", "none")
+	if err != nil || m["baidu"] != "www.baidu.com" || m["none"] != nil {
+		t.Errorf("Memc HMGet failed. Got %v.", m)
+		return
+	}
+
+	vals, err := adapter.HVals(key)
+	if err != nil || len(vals) != 3 {
+		t.Errorf("Memc HVals failed. Got %v.", vals)
+		return
+	}
+
+	n, err = adapter.HIncr(key, "num", 5)
+	if err != nil || n != 15 {
+		t.Errorf("Memc HIncr failed. Got %d, expected %d.", n, 15)
+		return
+	}
+
+	// 并发自增，CAS冲突时重试
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				if _, err := adapter.HDecr(key, "num"); err != nil {
+					t.Errorf("Memc HDecr failed. err: %v.", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	n, _ = adapter.HIncr(key, "num", 0)
+	if n != -5 {
+		t.Errorf("Memc HDecr failed. Got %d, expected %d.", n, -5)
+		return
+	}
+
+	adapter.HDel(key, "baidu", "le", "num")
+	if isExist, _ := adapter.IsExist(key); isExist {
+		t.Error("Memc HDel failed. key is exist.")
+		return
+	}
+
+	// 类型不对
+	adapter.Set("str", "abc", 0)
+	_, err = adapter.HSet("str", "f", "v", 0)
+	if err == nil {
+		t.Error("Memc HSet failed. expected WRONGTYPE error.")
+		return
+	}
+	adapter.Del("str")
+
+	// expire为0时保留原来的过期时间
+	adapter.HSet(key, "f1", "v1", 1)
+	adapter.HSet(key, "f2", "v2", 0)
+	time.Sleep(2 * time.Second)
+	if all, _ = adapter.HGetAll(key); len(all) != 0 {
+		t.Errorf("Memc HSet expire failed. Got %v.", all)
+		return
+	}
+}
+
+func TestMemcZSet(t *testing.T) {
+	adapter := &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","maxIdle":"10","ioTimeOut":"300","prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+
+	key := "rank"
+	adapter.Del(key)
+	adapter.Del("lex")
+	n, err := adapter.ZSet(key, 60, 3, "c", 1.0, "a", 2, "b", 4, "d")
+	if err != nil || n != 4 {
+		t.Errorf("Memc ZSet failed. Got %d, expected %d.", n, 4)
+		return
+	}
+
+	res, err := adapter.ZGet(key, 0, -1, false, false)
+	if err != nil || fmt.Sprint(res) != "[a b c d]" {
+		t.Errorf("Memc ZGet failed. Got %v.", res)
+		return
+	}
+
+	res, err = adapter.ZGet(key, 0, 0, true, true)
+	if err != nil || fmt.Sprint(res) != "[4.000000 d]" {
+		t.Errorf("Memc ZGet failed. Got %v.", res)
+		return
+	}
+
+	n, _ = adapter.ZRemRangeByScore(key, "(1", "2")
+	if n != 1 {
+		t.Errorf("Memc ZRemRangeByScore failed. Got %d, expected %d.", n, 1)
+		return
+	}
+
+	n, _ = adapter.ZRemRangeByRank(key, -1, -1)
+	if n != 1 {
+		t.Errorf("Memc ZRemRangeByRank failed. Got %d, expected %d.", n, 1)
+		return
+	}
+
+	n, _ = adapter.ZCard(key)
+	if n != 2 {
+		t.Errorf("Memc ZCard failed. Got %d, expected %d.", n, 2)
+		return
+	}
+
+	n, _ = adapter.ZDel(key, "a", "c")
+	if n != 2 {
+		t.Errorf("Memc ZDel failed. Got %d, expected %d.", n, 2)
+		return
+	}
+	if isExist, _ := adapter.IsExist(key); isExist {
+		t.Error("Memc ZDel failed. key is exist.")
+		return
+	}
+
+	adapter.ZSet("lex", 0, 0, "a", 0, "b", 0, "c")
+	n, _ = adapter.ZRemRangeByLex("lex", "[a", "(c")
+	if n != 2 {
+		t.Errorf("Memc ZRemRangeByLex failed. Got %d, expected %d.", n, 2)
+		return
+	}
+	adapter.Del("lex")
+}
+
+func TestMemcBit(t *testing.T) {
+	adapter := &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","maxIdle":"10","ioTimeOut":"300","prefix":"le_","maxItemSize":"256"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+
+	key := "bit"
+	adapter.Del(key)
+	adapter.Del("pf")
+	adapter.SetBit(key, 1, 1, 0)
+	adapter.SetBit(key, 9, 1, 0)
+	old, _ := adapter.SetBit(key, 9, 1, 0)
+	if old != 1 {
+		t.Errorf("Memc SetBit failed. Got %d, expected %d.", old, 1)
+		return
+	}
+
+	b, _ := adapter.GetBit(key, 9)
+	if b != 1 {
+		t.Errorf("Memc GetBit failed. Got %d, expected %d.", b, 1)
+		return
+	}
+
+	n, _ := adapter.BitCount(key, nil)
+	if n != 2 {
+		t.Errorf("Memc BitCount failed. Got %d, expected %d.", n, 2)
+		return
+	}
+
+	n, _ = adapter.BitCount(key, &cache.BitCount{Start: 1, End: 1})
+	if n != 1 {
+		t.Errorf("Memc BitCount failed. Got %d, expected %d.", n, 1)
+		return
+	}
+
+	// 超过最大长度
+	if _, err = adapter.SetBit(key, 8*200, 1, 0); err == nil {
+		t.Error("Memc SetBit failed. expected max item size error.")
+		return
+	}
+	adapter.Del(key)
+
+	////////////////////////HyperLogLog测试////////////////////////////
+	r, _ := adapter.PFAdd("pf", 0, "a", "b", "c")
+	if r != 1 {
+		t.Errorf("Memc PFAdd failed. Got %d, expected %d.", r, 1)
+		return
+	}
+	r, _ = adapter.PFAdd("pf", 0, "a")
+	if r != 0 {
+		t.Errorf("Memc PFAdd failed. Got %d, expected %d.", r, 0)
+		return
+	}
+	n, _ = adapter.PFCount("pf")
+	if n != 3 {
+		t.Errorf("Memc PFCount failed. Got %d, expected %d.", n, 3)
+		return
+	}
+	adapter.Del("pf")
+}
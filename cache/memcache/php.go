@@ -0,0 +1,712 @@
+package memcache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/lixy529/gotools/cache"
+	"github.com/lixy529/gotools/utils"
+	"github.com/vmihailenco/msgpack/v4"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// phpValue 按php-memcached的类型转换要保存的值，字符串和数字不转换
+//   参数
+//     val: 要保存的值
+//   返回
+//     flags中的类型，转换后的数据，是否转换，失败返回错误信息
+func phpValue(val interface{}) (uint32, []byte, bool, error) {
+	switch v := val.(type) {
+	case string, *string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return 0, nil, false, nil
+	case bool:
+		if v {
+			return FLAGES_BOOL, []byte("1"), true, nil
+		}
+		return FLAGES_BOOL, []byte{}, true, nil
+	case []byte:
+		return FLAGES_STR_UNCOMPRESS, v, true, nil
+	}
+
+	data, err := phpSerialize(val)
+	return FLAGES_SERIALIZED, data, err == nil, err
+}
+
+// uncompressValue 按php-memcached的flags解压数据
+// 压缩的数据前4字节为小端的原始长度，flags有fastlz位时使用fastlz，否则使用zlib
+//   参数
+//     flags: memcache的flags
+//     data:  memcache的值
+//   返回
+//     解压后的数据，失败返回ErrDecode类型的错误
+func uncompressValue(flags uint32, data []byte) ([]byte, error) {
+	if flags&FLAGES_COMPRESSED == 0 {
+		return data, nil
+	}
+	if len(data) < 4 {
+		return nil, cache.NewError(cache.ErrDecode, "MemcCache: compressed value too short")
+	}
+
+	var res []byte
+	var err error
+	if flags&FLAGES_COMPRESSION_FASTLZ != 0 {
+		res, err = utils.FastlzDecode(data[4:])
+	} else {
+		res, err = utils.ZlibDecode(data[4:])
+	}
+	if err != nil {
+		return nil, cache.WrapError(cache.ErrDecode, err)
+	}
+	if size := binary.LittleEndian.Uint32(data); int(size) != len(res) {
+		return nil, cache.NewError(cache.ErrDecode, fmt.Sprintf("MemcCache: uncompressed length %d, expected %d", len(res), size))
+	}
+
+	return res, nil
+}
+
+// typedValue 按php-memcached的flags类型转换数据，转换后可以用cache.ByteToInter读取
+// 布尔值转成true或false，PHP serialize、igbinary和msgpack的数据转成json，其它类型原样返回
+//   参数
+//     flags: memcache的flags
+//     data:  解压和解密后的数据
+//   返回
+//     转换后的数据，失败返回ErrDecode类型的错误
+func typedValue(flags uint32, data []byte) ([]byte, error) {
+	var v interface{}
+	var err error
+	switch flags & FLAGES_TYPE_MASK {
+	case FLAGES_BOOL:
+		if len(data) > 0 && string(data) != "0" {
+			return []byte("true"), nil
+		}
+		return []byte("false"), nil
+	case FLAGES_SERIALIZED:
+		v, err = phpUnserialize(data)
+	case FLAGES_IGBINARY:
+		v, err = igbinaryUnserialize(data)
+	case FLAGES_MSGPACK:
+		err = msgpack.Unmarshal(data, &v)
+	default:
+		return data, nil
+	}
+	if err != nil {
+		return nil, cache.WrapError(cache.ErrDecode, err)
+	}
+
+	data, err = phpValueBytes(v)
+	if err != nil {
+		return nil, cache.WrapError(cache.ErrDecode, err)
+	}
+	return data, nil
+}
+
+// phpValueBytes 将PHP反序列化的值转成[]byte，字符串原样返回，其它类型转成json
+func phpValueBytes(v interface{}) ([]byte, error) {
+	switch val := v.(type) {
+	case string:
+		return []byte(val), nil
+	case []byte:
+		return val, nil
+	case nil:
+		return []byte("null"), nil
+	case bool:
+		return []byte(strconv.FormatBool(val)), nil
+	case int64:
+		return []byte(strconv.FormatInt(val, 10)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(val, 'g', -1, 64)), nil
+	}
+
+	return json.Marshal(v)
+}
+
+// phpSerialize 将数据序列化成PHP serialize格式
+// 数据先转成json再转换，结构体和map转成关联数组，切片转成索引数组，[]byte转成字符串
+//   参数
+//     v: 要序列化的数据
+//   返回
+//     序列化后的数据，失败返回错误信息
+func phpSerialize(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	switch val := v.(type) {
+	case []byte:
+		phpWriteString(&buf, string(val))
+		return buf.Bytes(), nil
+	case string:
+		phpWriteString(&buf, val)
+		return buf.Bytes(), nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var jv interface{}
+	if err = dec.Decode(&jv); err != nil {
+		return nil, err
+	}
+
+	phpWrite(&buf, jv)
+	return buf.Bytes(), nil
+}
+
+// phpWrite 将json解析后的数据写成PHP serialize格式
+func phpWrite(buf *bytes.Buffer, v interface{}) {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString("N;")
+	case bool:
+		if val {
+			buf.WriteString("b:1;")
+		} else {
+			buf.WriteString("b:0;")
+		}
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			fmt.Fprintf(buf, "i:%d;", i)
+		} else {
+			f, _ := val.Float64()
+			fmt.Fprintf(buf, "d:%s;", strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	case string:
+		phpWriteString(buf, val)
+	case []interface{}:
+		fmt.Fprintf(buf, "a:%d:{", len(val))
+		for i, item := range val {
+			fmt.Fprintf(buf, "i:%d;", i)
+			phpWrite(buf, item)
+		}
+		buf.WriteByte('}')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(buf, "a:%d:{", len(val))
+		for _, k := range keys {
+			// 与PHP一致，十进制整数形式的key作为整数key
+			if i, err := strconv.ParseInt(k, 10, 64); err == nil && strconv.FormatInt(i, 10) == k {
+				fmt.Fprintf(buf, "i:%d;", i)
+			} else {
+				phpWriteString(buf, k)
+			}
+			phpWrite(buf, val[k])
+		}
+		buf.WriteByte('}')
+	}
+}
+
+// phpWriteString 写入PHP serialize格式的字符串
+func phpWriteString(buf *bytes.Buffer, s string) {
+	fmt.Fprintf(buf, "s:%d:\"%s\";", len(s), s)
+}
+
+// phpUnserialize 解析PHP serialize格式的数据
+// 整数转成int64，浮点数转成float64，key为0到n-1的数组转成[]interface{}，其它数组和对象转成map[string]interface{}
+// 对象只保留属性，私有和受保护属性去掉前缀，不支持实现了Serializable接口的对象(C:)
+//   参数
+//     data: PHP serialize格式的数据
+//   返回
+//     解析后的数据，失败返回错误信息
+func phpUnserialize(data []byte) (interface{}, error) {
+	p := &phpParser{data: data}
+	v, err := p.value(true)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.data) {
+		return nil, fmt.Errorf("MemcCache: unserialize error at offset %d", p.pos)
+	}
+	return v, nil
+}
+
+// phpParser PHP serialize格式的解析器
+type phpParser struct {
+	data []byte
+	pos  int
+	vars []interface{} // 引用(r:和R:)使用的值，从1开始编号
+}
+
+// errorf 返回带偏移量的解析错误
+func (p *phpParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("MemcCache: unserialize error at offset %d, %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// until 读取到分隔符sep为止的内容，并跳过分隔符
+func (p *phpParser) until(sep byte) (string, error) {
+	i := bytes.IndexByte(p.data[p.pos:], sep)
+	if i < 0 {
+		return "", p.errorf("missing %q", sep)
+	}
+	s := string(p.data[p.pos : p.pos+i])
+	p.pos += i + 1
+	return s, nil
+}
+
+// expect 跳过指定的字符
+func (p *phpParser) expect(c byte) error {
+	if p.pos >= len(p.data) || p.data[p.pos] != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+// int 读取到分隔符sep为止的整数
+func (p *phpParser) int(sep byte) (int64, error) {
+	s, err := p.until(sep)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, p.errorf("invalid integer %q", s)
+	}
+	return i, nil
+}
+
+// str 读取长度为n并用双引号包含的字符串
+func (p *phpParser) str(n int64) (string, error) {
+	if err := p.expect('"'); err != nil {
+		return "", err
+	}
+	if n < 0 || int64(len(p.data)-p.pos) < n {
+		return "", p.errorf("invalid string length %d", n)
+	}
+	s := string(p.data[p.pos : p.pos+int(n)])
+	p.pos += int(n)
+	if err := p.expect('"'); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+// value 读取一个值
+//   参数
+//     push: 是否加入引用表，数组的key和对象的属性名不加入
+//   返回
+//     解析后的值，失败返回错误信息
+func (p *phpParser) value(push bool) (interface{}, error) {
+	if p.pos+1 >= len(p.data) {
+		return nil, p.errorf("unexpected end")
+	}
+	typ := p.data[p.pos]
+	if typ == 'N' {
+		p.pos++
+		if err := p.expect(';'); err != nil {
+			return nil, err
+		}
+		if push {
+			p.vars = append(p.vars, nil)
+		}
+		return nil, nil
+	}
+	p.pos++
+	if err := p.expect(':'); err != nil {
+		return nil, err
+	}
+
+	// 数组和对象先占位，子元素的引用编号在它之后
+	idx := len(p.vars)
+	if push && typ != 'R' {
+		p.vars = append(p.vars, nil)
+	}
+
+	var v interface{}
+	var err error
+	switch typ {
+	case 'b':
+		var i int64
+		if i, err = p.int(';'); err == nil {
+			v = i != 0
+		}
+	case 'i':
+		v, err = p.int(';')
+	case 'd':
+		var s string
+		if s, err = p.until(';'); err == nil {
+			v, err = phpFloat(s)
+		}
+	case 's', 'E':
+		var n int64
+		if n, err = p.int(':'); err == nil {
+			if v, err = p.str(n); err == nil {
+				err = p.expect(';')
+			}
+		}
+	case 'a':
+		v, err = p.array()
+	case 'O':
+		var n int64
+		if n, err = p.int(':'); err == nil {
+			if _, err = p.str(n); err == nil {
+				if err = p.expect(':'); err == nil {
+					v, err = p.object()
+				}
+			}
+		}
+	case 'r', 'R':
+		var i int64
+		if i, err = p.int(';'); err == nil {
+			if i < 1 || i > int64(len(p.vars)) {
+				return nil, p.errorf("invalid reference %d", i)
+			}
+			v = p.vars[i-1]
+		}
+	default:
+		return nil, p.errorf("unsupported type %q", typ)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if push && typ != 'R' {
+		p.vars[idx] = v
+	}
+	return v, nil
+}
+
+// array 读取数组，格式为n:{key;value...}
+func (p *phpParser) array() (interface{}, error) {
+	n, err := p.int(':')
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > int64(len(p.data)) {
+		return nil, p.errorf("invalid array length %d", n)
+	}
+	if err = p.expect('{'); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, n)
+	vals := make([]interface{}, 0, n)
+	list := true
+	for i := int64(0); i < n; i++ {
+		k, err := p.value(false)
+		if err != nil {
+			return nil, err
+		}
+		var key string
+		switch kv := k.(type) {
+		case int64:
+			key = strconv.FormatInt(kv, 10)
+			list = list && kv == i
+		case string:
+			key = kv
+			list = false
+		default:
+			return nil, p.errorf("invalid array key %v", k)
+		}
+
+		v, err := p.value(true)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		vals = append(vals, v)
+	}
+	if err = p.expect('}'); err != nil {
+		return nil, err
+	}
+
+	if list {
+		return vals, nil
+	}
+	m := make(map[string]interface{}, n)
+	for i, k := range keys {
+		m[k] = vals[i]
+	}
+	return m, nil
+}
+
+// object 读取对象的属性，格式同数组，私有属性名为\0类名\0属性名，受保护属性名为\0*\0属性名
+func (p *phpParser) object() (interface{}, error) {
+	v, err := p.array()
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	switch props := v.(type) {
+	case []interface{}:
+		for i, prop := range props {
+			m[strconv.Itoa(i)] = prop
+		}
+	case map[string]interface{}:
+		for k, prop := range props {
+			m[phpPropName(k)] = prop
+		}
+	}
+	return m, nil
+}
+
+// phpPropName 去掉私有和受保护属性名的前缀
+func phpPropName(name string) string {
+	if len(name) > 0 && name[0] == 0 {
+		if i := strings.IndexByte(name[1:], 0); i >= 0 {
+			return name[i+2:]
+		}
+	}
+	return name
+}
+
+// phpFloat 解析PHP的浮点数，支持INF、-INF和NAN
+func phpFloat(s string) (float64, error) {
+	switch s {
+	case "INF":
+		return math.Inf(1), nil
+	case "-INF":
+		return math.Inf(-1), nil
+	case "NAN":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// igbinary的类型
+const (
+	igNull        = 0x00
+	igFalse       = 0x04
+	igTrue        = 0x05
+	igLong8P      = 0x06
+	igLong32N     = 0x0b
+	igDouble      = 0x0c
+	igStringEmpty = 0x0d
+	igStringId8   = 0x0e
+	igStringId32  = 0x10
+	igString8     = 0x11
+	igString32    = 0x13
+	igArray8      = 0x14
+	igArray32     = 0x16
+	igObject8     = 0x17
+	igObject32    = 0x19
+	igObjectId8   = 0x1a
+	igObjectId32  = 0x1c
+	igLong64P     = 0x20
+	igLong64N     = 0x21
+	igRef         = 0x25
+)
+
+// igbinaryUnserialize 解析igbinary格式(版本1和2)的数据，结果同phpUnserialize
+// 不支持引用(&$var和重复的对象)和自定义序列化的对象
+//   参数
+//     data: igbinary格式的数据
+//   返回
+//     解析后的数据，失败返回错误信息
+func igbinaryUnserialize(data []byte) (interface{}, error) {
+	if len(data) < 5 {
+		return nil, fmt.Errorf("MemcCache: igbinary data too short")
+	}
+	if version := binary.BigEndian.Uint32(data); version != 1 && version != 2 {
+		return nil, fmt.Errorf("MemcCache: igbinary version %d don't support", version)
+	}
+
+	p := &igParser{data: data, pos: 4}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.data) {
+		return nil, fmt.Errorf("MemcCache: igbinary error at offset %d", p.pos)
+	}
+	return v, nil
+}
+
+// igParser igbinary格式的解析器
+type igParser struct {
+	data    []byte
+	pos     int
+	strings []string // 已出现的字符串，后续用编号引用
+}
+
+// errorf 返回带偏移量的解析错误
+func (p *igParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("MemcCache: igbinary error at offset %d, %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// uint 读取size字节的大端无符号整数
+func (p *igParser) uint(size int) (uint64, error) {
+	if len(p.data)-p.pos < size {
+		return 0, p.errorf("unexpected end")
+	}
+	var n uint64
+	for _, b := range p.data[p.pos : p.pos+size] {
+		n = n<<8 | uint64(b)
+	}
+	p.pos += size
+	return n, nil
+}
+
+// size 按类型的偏移读取长度，offset为0、1、2时分别为1、2、4字节
+func (p *igParser) size(offset byte) (int, error) {
+	n, err := p.uint(1 << offset)
+	return int(n), err
+}
+
+// string 读取字符串，typ为字符串或字符串编号类型，新字符串加入字符串表
+func (p *igParser) string(typ byte) (string, error) {
+	switch {
+	case typ == igStringEmpty:
+		return "", nil
+	case typ >= igStringId8 && typ <= igStringId32:
+		id, err := p.size(typ - igStringId8)
+		if err != nil {
+			return "", err
+		}
+		if id >= len(p.strings) {
+			return "", p.errorf("invalid string id %d", id)
+		}
+		return p.strings[id], nil
+	case typ >= igString8 && typ <= igString32:
+		n, err := p.size(typ - igString8)
+		if err != nil {
+			return "", err
+		}
+		if len(p.data)-p.pos < n {
+			return "", p.errorf("unexpected end")
+		}
+		s := string(p.data[p.pos : p.pos+n])
+		p.pos += n
+		p.strings = append(p.strings, s)
+		return s, nil
+	}
+	return "", p.errorf("invalid string type 0x%02x", typ)
+}
+
+// value 读取一个值
+func (p *igParser) value() (interface{}, error) {
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end")
+	}
+	typ := p.data[p.pos]
+	p.pos++
+
+	switch {
+	case typ == igNull:
+		return nil, nil
+	case typ == igFalse:
+		return false, nil
+	case typ == igTrue:
+		return true, nil
+	case typ >= igLong8P && typ <= igLong32N:
+		// 正负交替，长度为1、2、4字节
+		n, err := p.uint(1 << ((typ - igLong8P) / 2))
+		if err != nil {
+			return nil, err
+		}
+		if (typ-igLong8P)%2 == 1 {
+			return -int64(n), nil
+		}
+		return int64(n), nil
+	case typ == igLong64P || typ == igLong64N:
+		n, err := p.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		if typ == igLong64N {
+			return -int64(n), nil
+		}
+		return int64(n), nil
+	case typ == igDouble:
+		n, err := p.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(n), nil
+	case typ >= igStringEmpty && typ <= igString32:
+		return p.string(typ)
+	case typ >= igArray8 && typ <= igArray32:
+		n, err := p.size(typ - igArray8)
+		if err != nil {
+			return nil, err
+		}
+		return p.array(n)
+	case typ >= igObject8 && typ <= igObjectId32:
+		// 类名，之后是属性数组
+		var err error
+		if typ <= igObject32 {
+			_, err = p.string(typ - igObject8 + igString8)
+		} else {
+			_, err = p.string(typ - igObjectId8 + igStringId8)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.data) || p.data[p.pos] < igArray8 || p.data[p.pos] > igArray32 {
+			return nil, p.errorf("invalid object properties")
+		}
+		props, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{})
+		switch pv := props.(type) {
+		case []interface{}:
+			for i, prop := range pv {
+				m[strconv.Itoa(i)] = prop
+			}
+		case map[string]interface{}:
+			for k, prop := range pv {
+				m[phpPropName(k)] = prop
+			}
+		}
+		return m, nil
+	case typ == igRef:
+		return p.value()
+	}
+
+	return nil, p.errorf("type 0x%02x don't support", typ)
+}
+
+// array 读取n个键值对，key为0到n-1时转成[]interface{}
+func (p *igParser) array(n int) (interface{}, error) {
+	if n > len(p.data) {
+		return nil, p.errorf("invalid array length %d", n)
+	}
+
+	keys := make([]string, 0, n)
+	vals := make([]interface{}, 0, n)
+	list := true
+	for i := 0; i < n; i++ {
+		k, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		var key string
+		switch kv := k.(type) {
+		case int64:
+			key = strconv.FormatInt(kv, 10)
+			list = list && kv == int64(i)
+		case string:
+			key = kv
+			list = false
+		default:
+			return nil, p.errorf("invalid array key %v", k)
+		}
+
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		vals = append(vals, v)
+	}
+
+	if list {
+		return vals, nil
+	}
+	m := make(map[string]interface{}, n)
+	for i, k := range keys {
+		m[k] = vals[i]
+	}
+	return m, nil
+}
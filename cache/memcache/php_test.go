@@ -0,0 +1,210 @@
+package memcache
+
+import (
+	"context"
+	"github.com/bradfitz/gomemcache/memcache"
+	"reflect"
+	"testing"
+)
+
+func TestPhpSerialize(t *testing.T) {
+	type user struct {
+		Name string   `json:"name"`
+		Age  int      `json:"age"`
+		Tags []string `json:"tags"`
+	}
+	tests := []struct {
+		val      interface{}
+		expected string
+	}{
+		{"abc", `s:3:"abc";`},
+		{[]byte("中"), `s:3:"中";`},
+		{nil, `N;`},
+		{[]int{1, 2}, `a:2:{i:0;i:1;i:1;i:2;}`},
+		{map[string]interface{}{"b": 1.5, "10": true, "a": nil}, `a:3:{i:10;b:1;s:1:"a";N;s:1:"b";d:1.5;}`},
+		{user{"lixy", 18, []string{"go"}}, `a:3:{s:3:"age";i:18;s:4:"name";s:4:"lixy";s:4:"tags";a:1:{i:0;s:2:"go";}}`},
+	}
+	for _, test := range tests {
+		data, err := phpSerialize(test.val)
+		if err != nil {
+			t.Errorf("phpSerialize failed. err: %s.", err.Error())
+			return
+		} else if string(data) != test.expected {
+			t.Errorf("phpSerialize failed. Got %s, expected %s.", data, test.expected)
+			return
+		}
+	}
+}
+
+func TestPhpUnserialize(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected interface{}
+	}{
+		{`i:-12;`, int64(-12)},
+		{`d:0.5;`, 0.5},
+		{`b:1;`, true},
+		{`N;`, nil},
+		{`s:6:"a";b"c";`, `a";b"c`},
+		{`a:2:{i:0;s:1:"x";i:1;i:2;}`, []interface{}{"x", int64(2)}},
+		{`a:2:{i:1;s:1:"x";s:1:"k";a:0:{}}`, map[string]interface{}{"1": "x", "k": []interface{}{}}},
+		{"O:3:\"Foo\":3:{s:6:\"\x00Foo\x00x\";i:1;s:4:\"\x00*\x00y\";a:1:{i:0;R:2;}s:1:\"z\";r:3;}", map[string]interface{}{"x": int64(1), "y": []interface{}{int64(1)}, "z": []interface{}{int64(1)}}},
+		{`E:7:"Foo:Bar";`, "Foo:Bar"},
+	}
+	for _, test := range tests {
+		v, err := phpUnserialize([]byte(test.data))
+		if err != nil {
+			t.Errorf("phpUnserialize failed. err: %s.", err.Error())
+			return
+		} else if !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("phpUnserialize failed. Got %#v, expected %#v.", v, test.expected)
+			return
+		}
+	}
+
+	for _, data := range []string{``, `i:1`, `s:5:"abc";`, `a:1:{i:0;i:1;`, `C:3:"Foo":0:{}`, `R:1;`, `i:1;i:2;`} {
+		if _, err := phpUnserialize([]byte(data)); err == nil {
+			t.Errorf("phpUnserialize failed. %q expected error.", data)
+			return
+		}
+	}
+}
+
+func TestIgbinaryUnserialize(t *testing.T) {
+	// ['a' => 1, 'b' => [true, null, -300, 1.5], 'c' => 'a']
+	data := []byte{
+		0x00, 0x00, 0x00, 0x02,
+		0x14, 0x03,
+		0x11, 0x01, 'a', 0x06, 0x01,
+		0x11, 0x01, 'b', 0x14, 0x04,
+		0x06, 0x00, 0x05,
+		0x06, 0x01, 0x00,
+		0x06, 0x02, 0x09, 0x01, 0x2c,
+		0x06, 0x03, 0x0c, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+		0x11, 0x01, 'c', 0x0e, 0x00,
+	}
+	expected := map[string]interface{}{"a": int64(1), "b": []interface{}{true, nil, int64(-300), 1.5}, "c": "a"}
+	v, err := igbinaryUnserialize(data)
+	if err != nil {
+		t.Errorf("igbinaryUnserialize failed. err: %s.", err.Error())
+		return
+	} else if !reflect.DeepEqual(v, expected) {
+		t.Errorf("igbinaryUnserialize failed. Got %#v, expected %#v.", v, expected)
+		return
+	}
+
+	// Foo对象，属性x=1
+	data = []byte{0x00, 0x00, 0x00, 0x02, 0x17, 0x03, 'F', 'o', 'o', 0x14, 0x01, 0x11, 0x06, 0, 'F', 'o', 'o', 0, 'x', 0x06, 0x01}
+	v, err = igbinaryUnserialize(data)
+	if err != nil {
+		t.Errorf("igbinaryUnserialize failed. err: %s.", err.Error())
+		return
+	} else if !reflect.DeepEqual(v, map[string]interface{}{"x": int64(1)}) {
+		t.Errorf("igbinaryUnserialize failed. Got %#v.", v)
+		return
+	}
+
+	if _, err = igbinaryUnserialize([]byte{0x00, 0x00, 0x00, 0x03, 0x00}); err == nil {
+		t.Error("igbinaryUnserialize failed. Version 3 expected error.")
+		return
+	}
+}
+
+func TestMemcPhp(t *testing.T) {
+	adapter := &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","prefix":"le_","phpCompat":"true","compressType":"fastlz","compressThreshold":"64"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+	ctx := context.Background()
+
+	// PHP写入的数据，PHP serialize、igbinary、bool和zlib压缩
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	items := []*memcache.Item{
+		{Key: "le_php_ser", Value: []byte(`a:2:{s:4:"name";s:4:"lixy";s:3:"age";i:18;}`), Flags: FLAGES_SERIALIZED},
+		{Key: "le_php_ig", Value: []byte{0, 0, 0, 2, 0x14, 0x02, 0x11, 0x04, 'n', 'a', 'm', 'e', 0x11, 0x04, 'l', 'i', 'x', 'y', 0x11, 0x03, 'a', 'g', 'e', 0x06, 0x12}, Flags: FLAGES_IGBINARY},
+		{Key: "le_php_zlib", Value: append([]byte{43, 0, 0, 0}, []byte{0x78, 0x9c, 0x4b, 0xb4, 0x32, 0xb2, 0xaa, 0x2e, 0xb6, 0x32, 0xb1, 0x52, 0xca, 0x4b, 0xcc, 0x4d, 0x55, 0xb2, 0x06, 0x33, 0x73, 0x32, 0x2b, 0x2a, 0x41, 0x4c, 0x63, 0x2b, 0xa5, 0xc4, 0x74, 0xa0, 0x60, 0xa6, 0x95, 0xa1, 0x85, 0x75, 0x2d, 0x00, 0x19, 0x25, 0x0c, 0xa8}...), Flags: FLAGES_SERIALIZED | FLAGES_COMPRESSED | FLAGES_COMPRESSION_ZLIB},
+	}
+	for _, item := range items {
+		if err = adapter.conn.Set(ctx, item); err != nil {
+			t.Errorf("Memc Set failed. err: %s.", err.Error())
+			return
+		}
+		var u user
+		if err, exist := adapter.Get(item.Key[3:], &u); err != nil || !exist {
+			t.Errorf("Memc Get %s failed. exist: %v, err: %v.", item.Key, exist, err)
+			return
+		} else if u.Name != "lixy" || u.Age != 18 {
+			t.Errorf("Memc Get %s failed. Got %+v.", item.Key, u)
+			return
+		}
+	}
+
+	if err = adapter.conn.Set(ctx, &memcache.Item{Key: "le_php_bool", Value: []byte("1"), Flags: FLAGES_BOOL}); err != nil {
+		t.Errorf("Memc Set failed. err: %s.", err.Error())
+		return
+	}
+	b := false
+	if err, _ := adapter.Get("php_bool", &b); err != nil || !b {
+		t.Errorf("Memc Get bool failed. Got %v, err: %v.", b, err)
+		return
+	}
+
+	// Go写入的数据按php-memcached的格式保存
+	u := user{"lixy", 18}
+	if err = adapter.Set("php_go", u, 60); err != nil {
+		t.Errorf("Memc Set failed. err: %s.", err.Error())
+		return
+	}
+	item, err := adapter.conn.Get(ctx, "le_php_go")
+	if err != nil {
+		t.Errorf("Memc Get failed. err: %s.", err.Error())
+		return
+	} else if item.Flags != FLAGES_SERIALIZED || string(item.Value) != `a:2:{s:3:"age";i:18;s:4:"name";s:4:"lixy";}` {
+		t.Errorf("Memc Set failed. Got flags %d, value %s.", item.Flags, item.Value)
+		return
+	}
+
+	if err = adapter.Set("php_go", false, 60); err != nil {
+		t.Errorf("Memc Set failed. err: %s.", err.Error())
+		return
+	}
+	b = true
+	if item, err = adapter.conn.Get(ctx, "le_php_go"); err != nil || item.Flags != FLAGES_BOOL || len(item.Value) != 0 {
+		t.Errorf("Memc Set bool failed. Got %+v, err: %v.", item, err)
+		return
+	} else if err, _ = adapter.Get("php_go", &b); err != nil || b {
+		t.Errorf("Memc Get bool failed. Got %v, err: %v.", b, err)
+		return
+	}
+
+	// 超过压缩阀值使用fastlz压缩
+	list := []string{"aaaaaaaaaa", "aaaaaaaaaa", "aaaaaaaaaa", "aaaaaaaaaa"}
+	if err = adapter.Set("php_go", list, 60); err != nil {
+		t.Errorf("Memc Set failed. err: %s.", err.Error())
+		return
+	}
+	if item, err = adapter.conn.Get(ctx, "le_php_go"); err != nil || item.Flags != FLAGES_SERIALIZED|FLAGES_COMPRESSED|FLAGES_COMPRESSION_FASTLZ {
+		t.Errorf("Memc Set compress failed. Got %+v, err: %v.", item, err)
+		return
+	}
+	var list2 []string
+	if err, _ = adapter.Get("php_go", &list2); err != nil || !reflect.DeepEqual(list, list2) {
+		t.Errorf("Memc Get compress failed. Got %v, err: %v.", list2, err)
+		return
+	}
+	mv, err := adapter.MGet("php_go", "php_bool")
+	if err != nil {
+		t.Errorf("Memc MGet failed. err: %s.", err.Error())
+		return
+	} else if string(mv["php_bool"].([]byte)) != "true" || string(mv["php_go"].([]byte)) != `["aaaaaaaaaa","aaaaaaaaaa","aaaaaaaaaa","aaaaaaaaaa"]` {
+		t.Errorf("Memc MGet failed. Got %s %s.", mv["php_go"], mv["php_bool"])
+		return
+	}
+
+	adapter.MDel("php_ser", "php_ig", "php_zlib", "php_bool", "php_go")
+}
@@ -0,0 +1,214 @@
+package memcache
+
+import (
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/lixy529/gotools/cache/hashring"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ketamaSelector 按一致性哈希选择memcache服务器，与libmemcached的ketama weighted分布一致
+// 连续失败failureLimit次的服务器暂时从哈希环上摘除，它的key分到其它服务器，retryTimeout后重新加入
+type ketamaSelector struct {
+	lock    sync.Mutex
+	ring    *hashring.Ring
+	addrs   map[string]net.Addr // 服务器对应的地址
+	nodes   map[string]string   // 地址对应的服务器
+	weights map[string]int      // 服务器对应的权重
+
+	failureLimit int                  // 连续失败多少次摘除服务器，小于等于0时不摘除
+	retryTimeout time.Duration        // 摘除后多久重新加入
+	failures     map[string]int       // 服务器连续失败的次数
+	ejected      map[string]time.Time // 摘除的服务器和重新加入的时间
+
+	// PickServer为key选择的服务器，执行命令后由picked取出，保证report的是gomemcache实际使用的服务器
+	// 同一个key的并发命令按选择的顺序取出
+	picks map[string][]net.Addr
+}
+
+// newKetamaSelector 新建一致性哈希选择器
+//   参数
+//     servers:      服务器列表，如127.0.0.1:11211，包含/时为unix socket
+//     weights:      服务器对应的权重，小于等于0或没有配置时为1
+//     failureLimit: 连续失败多少次摘除服务器，小于等于0时不摘除
+//     retryTimeout: 摘除后多久重新加入
+//   返回
+//     选择器，解析地址失败时返回错误信息
+func newKetamaSelector(servers []string, weights []int, failureLimit int, retryTimeout time.Duration) (*ketamaSelector, error) {
+	s := &ketamaSelector{
+		ring:         hashring.NewLibmemcached(),
+		addrs:        make(map[string]net.Addr, len(servers)),
+		nodes:        make(map[string]string, len(servers)),
+		weights:      make(map[string]int, len(servers)),
+		failureLimit: failureLimit,
+		retryTimeout: retryTimeout,
+		failures:     make(map[string]int),
+		ejected:      make(map[string]time.Time),
+		picks:        make(map[string][]net.Addr),
+	}
+
+	for i, server := range servers {
+		server = strings.TrimSpace(server)
+		var addr net.Addr
+		var err error
+		if strings.Contains(server, "/") {
+			addr, err = net.ResolveUnixAddr("unix", server)
+		} else {
+			addr, err = net.ResolveTCPAddr("tcp", server)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		weight := 1
+		if i < len(weights) && weights[i] > 0 {
+			weight = weights[i]
+		}
+		s.addrs[server] = addr
+		s.nodes[addr.String()] = server
+		s.weights[server] = weight
+		s.ring.Add(server, weight)
+	}
+
+	return s, nil
+}
+
+// PickServer 返回key所在的服务器，实现memcache.ServerSelector
+func (s *ketamaSelector) PickServer(key string) (net.Addr, error) {
+	s.restore()
+
+	node := s.ring.Get(key)
+	if node == "" {
+		return nil, memcache.ErrNoServers
+	}
+
+	addr := s.addrs[node]
+	if s.failureLimit > 0 {
+		s.lock.Lock()
+		s.picks[key] = append(s.picks[key], addr)
+		s.lock.Unlock()
+	}
+
+	return addr, nil
+}
+
+// picked 取出PickServer为key选择的服务器，执行命令后调用
+//   参数
+//     key: 添加前缀后的key值
+//   返回
+//     服务器地址，没有选择过时返回nil
+func (s *ketamaSelector) picked(key string) net.Addr {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	addrs := s.picks[key]
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	if len(addrs) == 1 {
+		delete(s.picks, key)
+	} else {
+		s.picks[key] = addrs[1:]
+	}
+
+	return addrs[0]
+}
+
+// Each 遍历没有摘除的服务器，实现memcache.ServerSelector
+func (s *ketamaSelector) Each(fn func(net.Addr) error) error {
+	s.restore()
+
+	for _, node := range s.ring.Nodes() {
+		if err := fn(s.addrs[node]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restore 摘除时间超过retryTimeout的服务器重新加入哈希环
+func (s *ketamaSelector) restore() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.ejected) == 0 {
+		return
+	}
+
+	now := time.Now()
+	for node, retry := range s.ejected {
+		if now.After(retry) {
+			delete(s.ejected, node)
+			s.ring.Add(node, s.weights[node])
+		}
+	}
+}
+
+// report 记录命令的执行结果，服务器连续失败failureLimit次时暂时摘除
+//   参数
+//     addr: 执行命令的服务器地址
+//     err:  命令的错误信息，gomemcache的原始错误
+//   返回
+//
+func (s *ketamaSelector) report(addr net.Addr, err error) {
+	if s.failureLimit <= 0 || addr == nil {
+		return
+	}
+
+	failed := isServerFailure(err)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	node, ok := s.nodes[addr.String()]
+	if !ok {
+		return
+	}
+	if !failed {
+		delete(s.failures, node)
+		return
+	}
+
+	s.failures[node]++
+	if s.failures[node] < s.failureLimit {
+		return
+	}
+
+	delete(s.failures, node)
+
+	// 至少保留一台服务器，全部摘除时所有命令都会失败
+	if _, ok := s.ejected[node]; ok || s.ring.Len() <= 1 {
+		return
+	}
+	s.ejected[node] = time.Now().Add(s.retryTimeout)
+	s.ring.Remove(node)
+}
+
+// legalKey 与gomemcache的key检查一致，不合法的key不会调用PickServer
+func legalKey(key string) bool {
+	if len(key) > 250 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isServerFailure 判断是否为服务器连接失败、超时等错误，未命中、未存储等协议层的结果不算失败
+func isServerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch err.(type) {
+	case net.Error, *memcache.ConnectTimeoutError:
+		return true
+	}
+
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
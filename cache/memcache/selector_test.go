@@ -0,0 +1,194 @@
+package memcache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestKetamaSelector(t *testing.T) {
+	s, err := newKetamaSelector([]string{"127.0.0.1:11211", "127.0.0.2:11211"}, []int{3, 1}, 2, time.Second)
+	if err != nil {
+		t.Errorf("newKetamaSelector failed. err: %s.", err.Error())
+		return
+	}
+
+	count := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		addr, err := s.PickServer("key_" + strconv.Itoa(i))
+		if err != nil {
+			t.Errorf("PickServer failed. err: %s.", err.Error())
+			return
+		}
+		count[addr.String()]++
+	}
+	if count["127.0.0.1:11211"] < 6500 || count["127.0.0.1:11211"] > 8500 {
+		t.Errorf("PickServer weight failed. Got %v, expected about 3:1.", count)
+	}
+
+	// 按PickServer的顺序取出选择的服务器
+	s.PickServer("k1")
+	s.PickServer("k1")
+	if s.picked("k1") == nil || s.picked("k1") == nil || s.picked("k1") != nil {
+		t.Error("picked failed. Expected 2 picks.")
+	}
+	if _, ok := s.picks["k1"]; ok {
+		t.Errorf("picked failed. Got %v, expected empty.", s.picks["k1"])
+	}
+}
+
+// TestKetamaGolden key对应的服务器与libmemcached一致
+// 向量由按libmemcached 1.0的update_continuum和dispatch_host移植的脚本生成，KETAMA_WEIGHTED、md5哈希，
+// 与php-memcached开启OPT_LIBKETAMA_COMPATIBLE时的分布相同
+func TestKetamaGolden(t *testing.T) {
+	tests := []struct {
+		servers []string
+		weights []int
+		keys    map[string]string
+	}{
+		{
+			[]string{"127.0.0.1:11211", "127.0.0.2:11211", "127.0.0.3:11211"},
+			[]int{1, 1, 1},
+			map[string]string{
+				"foo":            "127.0.0.1:11211",
+				"bar":            "127.0.0.3:11211",
+				"user_1001":      "127.0.0.2:11211",
+				"le_k1":          "127.0.0.3:11211",
+				"session:abcdef": "127.0.0.2:11211",
+				"a":              "127.0.0.1:11211",
+				"key_42":         "127.0.0.3:11211",
+				"中文key":          "127.0.0.2:11211",
+				"0":              "127.0.0.3:11211",
+				"zzzzzz":         "127.0.0.3:11211",
+			},
+		},
+		{
+			[]string{"127.0.0.1:11211", "127.0.0.2:11211", "127.0.0.3:11211"},
+			[]int{3, 1, 2},
+			map[string]string{
+				"foo":            "127.0.0.1:11211",
+				"bar":            "127.0.0.3:11211",
+				"user_1001":      "127.0.0.2:11211",
+				"le_k1":          "127.0.0.3:11211",
+				"session:abcdef": "127.0.0.2:11211",
+				"a":              "127.0.0.1:11211",
+				"key_42":         "127.0.0.3:11211",
+				"中文key":          "127.0.0.3:11211",
+				"0":              "127.0.0.3:11211",
+				"zzzzzz":         "127.0.0.3:11211",
+			},
+		},
+		{
+			[]string{"127.0.0.1:11212", "127.0.0.2:11211", "127.0.0.3:11213"},
+			[]int{1, 1, 1},
+			map[string]string{
+				"foo":            "127.0.0.3:11213",
+				"bar":            "127.0.0.1:11212",
+				"user_1001":      "127.0.0.1:11212",
+				"le_k1":          "127.0.0.1:11212",
+				"session:abcdef": "127.0.0.3:11213",
+				"a":              "127.0.0.1:11212",
+				"key_42":         "127.0.0.3:11213",
+				"中文key":          "127.0.0.2:11211",
+				"0":              "127.0.0.3:11213",
+				"zzzzzz":         "127.0.0.1:11212",
+			},
+		},
+		{
+			[]string{"127.0.0.1:11212", "127.0.0.2:11211", "127.0.0.3:11213"},
+			[]int{2, 1, 5},
+			map[string]string{
+				"foo":            "127.0.0.3:11213",
+				"bar":            "127.0.0.1:11212",
+				"user_1001":      "127.0.0.3:11213",
+				"le_k1":          "127.0.0.1:11212",
+				"session:abcdef": "127.0.0.3:11213",
+				"a":              "127.0.0.1:11212",
+				"key_42":         "127.0.0.3:11213",
+				"中文key":          "127.0.0.3:11213",
+				"0":              "127.0.0.3:11213",
+				"zzzzzz":         "127.0.0.1:11212",
+			},
+		},
+		{
+			[]string{"127.0.0.1:11211", "127.0.0.2:11212"},
+			[]int{1, 2},
+			map[string]string{
+				"foo":            "127.0.0.1:11211",
+				"bar":            "127.0.0.2:11212",
+				"user_1001":      "127.0.0.2:11212",
+				"le_k1":          "127.0.0.2:11212",
+				"session:abcdef": "127.0.0.2:11212",
+				"a":              "127.0.0.2:11212",
+				"key_42":         "127.0.0.2:11212",
+				"中文key":          "127.0.0.1:11211",
+				"0":              "127.0.0.2:11212",
+				"zzzzzz":         "127.0.0.2:11212",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		s, err := newKetamaSelector(tt.servers, tt.weights, 0, time.Second)
+		if err != nil {
+			t.Errorf("newKetamaSelector failed. err: %s.", err.Error())
+			return
+		}
+		for key, server := range tt.keys {
+			addr, err := s.PickServer(key)
+			if err != nil || addr.String() != server {
+				t.Errorf("PickServer failed. servers: %v, weights: %v, %s Got %v, expected %s.", tt.servers, tt.weights, key, addr, server)
+			}
+		}
+	}
+}
+
+func TestMemcKetama(t *testing.T) {
+	adapter := &MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211,127.0.0.1:1","prefix":"le_","distribution":"ketama","failureLimit":"2","retryTimeout":"1"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+
+	// 127.0.0.1:1连接失败，连续失败2次后摘除，key分到其它服务器
+	failed := 0
+	for i := 0; i < 50; i++ {
+		key := "ketama_" + strconv.Itoa(i)
+		if err = adapter.Set(key, i, 60); err != nil {
+			failed++
+			continue
+		}
+		v := 0
+		if err, exist := adapter.Get(key, &v); err != nil || !exist || v != i {
+			t.Errorf("Memc Get failed. Got %d, expected %d, err: %v.", v, i, err)
+			return
+		}
+	}
+	if failed != 2 {
+		t.Errorf("Memc ketama failover failed. %d commands failed, expected 2.", failed)
+		return
+	}
+	adapter.MGet("ketama_1", "ketama_2", "bad key", "ketama_3")
+	if n := len(adapter.conn.selector.picks); n != 0 {
+		t.Errorf("Memc ketama picks failed. %d keys are not taken.", n)
+		return
+	}
+	if n := adapter.conn.selector.ring.Len(); n != 1 {
+		t.Errorf("Memc ketama eject failed. Got %d servers, expected 1.", n)
+		return
+	}
+
+	// 超过retryTimeout后重新加入
+	time.Sleep(1100 * time.Millisecond)
+	adapter.conn.selector.PickServer("ketama_0")
+	adapter.conn.selector.picked("ketama_0")
+	if n := adapter.conn.selector.ring.Len(); n != 2 {
+		t.Errorf("Memc ketama restore failed. Got %d servers, expected 2.", n)
+		return
+	}
+
+	if err = adapter.Init(`{"addr":"127.0.0.1:11211","distribution":"crc"}`); err == nil {
+		t.Error("Memc Init failed. expected distribution error.")
+	}
+}
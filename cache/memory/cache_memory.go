@@ -0,0 +1,3505 @@
+// In-process memory adapter
+package memory
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lixy529/gotools/cache"
+	"github.com/lixy529/gotools/cache/internal/zset"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// blockInterval BRPop、XRead等阻塞命令没有数据时的轮询间隔
+const blockInterval = 10 * time.Millisecond
+
+var (
+	errWrongType    = errors.New("MemoryCache: WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNotInt       = errors.New("MemoryCache: value is not an integer or out of range")
+	errBlockTimeout = errors.New("MemoryCache: block timeout")
+)
+
+// entry 缓存项
+type entry struct {
+	key      string
+	value    interface{} // 数据，类型为[]byte、map[string][]byte、map[string]float64(有序集合)、hll、[][]byte(列表)、set、*stream
+	size     int         // 估算的内存占用，单位字节
+	expireAt int64       // 过期时间，UnixNano，0表示不过期
+	cas      uint64      // 数据的版本，用于CompareAndSwap
+}
+
+// hll HyperLogLog，内存版直接保存所有元素做精确计数
+type hll map[string]struct{}
+
+// set 集合
+type set map[string]struct{}
+
+// stream 流
+type stream struct {
+	entries []streamEntry           // 消息，按id排序
+	lastId  streamId                // 最后添加的消息id
+	groups  map[string]*streamGroup // 消费组
+}
+
+// streamEntry 流中的消息
+type streamEntry struct {
+	id     streamId
+	values map[string][]byte
+}
+
+// streamGroup 消费组
+type streamGroup struct {
+	lastId  streamId            // 最后分配的消息id
+	pending map[streamId]string // 已分配未确认的消息对应的消费者
+}
+
+// streamId 流消息id，格式为毫秒时间-序号
+type streamId struct {
+	ms, seq uint64
+}
+
+// streamNew XReadGroup中的>，表示读取未分配的新消息
+var streamNew = streamId{ms: math.MaxUint64, seq: math.MaxUint64}
+
+func (id streamId) String() string {
+	return strconv.FormatUint(id.ms, 10) + "-" + strconv.FormatUint(id.seq, 10)
+}
+
+// less 判断id是否小于other
+func (id streamId) less(other streamId) bool {
+	return id.ms < other.ms || (id.ms == other.ms && id.seq < other.seq)
+}
+
+// message 转换为返回的消息格式
+func (e streamEntry) message() cache.XMessage {
+	values := make(map[string]interface{}, len(e.values))
+	for field, data := range e.values {
+		values[field] = string(data)
+	}
+	return cache.XMessage{ID: e.id.String(), Values: values}
+}
+
+// MemoryCache 进程内内存缓存
+type MemoryCache struct {
+	lock  sync.Mutex
+	items map[string]*list.Element // key对应的LRU链表节点
+	ll    *list.List               // LRU链表，最近访问的在前面
+	used  int64                    // 当前占用内存数，单位字节
+	casId uint64                   // 数据版本计数，每次写入时加1
+
+	maxEntries int           // 最大缓存项数，超过时按LRU淘汰，0表示不限制
+	maxMemory  int64         // 最大占用内存，单位字节，超过时按LRU淘汰，0表示不限制
+	interval   time.Duration // 后台清理过期数据的间隔，单位秒，默认60秒
+	stop       chan struct{} // 停止后台清理
+
+	prefix     string           // key前缀，如果配置里有，则所有key前自动添加此前缀
+	encodeKey  [][]byte         // 加解密密钥，第一个用于加密，全部用于解密
+	serializer cache.Serializer // 序列化，默认为json
+
+	hooks cache.Hooks // 命令钩子
+}
+
+func init() {
+	cache.Register(cache.AdapterMemory, NewMemoryCache)
+}
+
+// NewMemoryCache 新建一个MemoryCache适配器.
+func NewMemoryCache() cache.Cache {
+	return &MemoryCache{}
+}
+
+// Init 初始化
+//   参数
+//     config: 配置josn串，可以为空
+//       {
+//         "maxEntries":"10000",
+//         "maxMemory":"67108864",
+//         "interval":"60",
+//         "prefix":"le_",
+//         "encodeKey":"abcdefghij123456",
+//         "serializer":"json",
+//       }
+//       maxEntries: 最大缓存项数，超过时按LRU淘汰，默认为0不限制
+//       maxMemory:  最大占用内存，单位字节，超过时按LRU淘汰，默认为0不限制
+//       interval:   后台清理过期数据的间隔，单位秒，默认60秒
+//       prefix:     key前缀，如果配置里有，则所有key前自动添加此前缀
+//       encodeKey:  数据如果要加密，传的加密密钥，多个用逗号分割，第一个用于加密，全部用于解密
+//       serializer: 序列化，支持json、gob、msgpack、raw，默认为json
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *MemoryCache) Init(config string) error {
+	mapCfg := make(map[string]string)
+	if config != "" {
+		err := json.Unmarshal([]byte(config), &mapCfg)
+		if err != nil {
+			return fmt.Errorf("MemoryCache: Unmarshal json[%s] error, %s", config, err.Error())
+		}
+	}
+
+	// 最大缓存项数
+	maxEntries, err := strconv.Atoi(mapCfg["maxEntries"])
+	if err != nil || maxEntries < 0 {
+		c.maxEntries = 0
+	} else {
+		c.maxEntries = maxEntries
+	}
+
+	// 最大占用内存
+	maxMemory, err := strconv.ParseInt(mapCfg["maxMemory"], 10, 64)
+	if err != nil || maxMemory < 0 {
+		c.maxMemory = 0
+	} else {
+		c.maxMemory = maxMemory
+	}
+
+	// 清理间隔
+	interval, err := strconv.Atoi(mapCfg["interval"])
+	if err != nil || interval <= 0 {
+		c.interval = 60
+	} else {
+		c.interval = time.Duration(interval)
+	}
+
+	// 前缀
+	if prefix, ok := mapCfg["prefix"]; ok {
+		c.prefix = prefix
+	}
+
+	// 加密密钥
+	if tmp, ok := mapCfg["encodeKey"]; ok && tmp != "" {
+		c.encodeKey = cache.ParseEncodeKeys(tmp)
+	}
+
+	// 序列化
+	c.serializer, err = cache.GetSerializer(mapCfg["serializer"])
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	c.items = make(map[string]*list.Element)
+	c.ll = list.New()
+	c.used = 0
+	c.lock.Unlock()
+
+	// 启动后台清理
+	c.Close()
+	c.stop = make(chan struct{})
+	go c.expirer(c.stop)
+
+	return nil
+}
+
+// AddHook 添加命令钩子
+//   参数
+//     hook: 钩子
+//   返回
+//
+func (c *MemoryCache) AddHook(hook cache.Hook) {
+	c.hooks.AddHook(hook)
+}
+
+// Serializer 返回配置的序列化，实现cache.SerializerGetter
+func (c *MemoryCache) Serializer() cache.Serializer {
+	return c.serializer
+}
+
+// Close 停止后台清理过期数据
+func (c *MemoryCache) Close() {
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
+
+// expirer 定期清理过期数据
+//   参数
+//     stop: 停止信号
+//   返回
+//
+func (c *MemoryCache) expirer(stop chan struct{}) {
+	ticker := time.NewTicker(c.interval * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.DeleteExpired()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// DeleteExpired 删除所有已过期的数据
+func (c *MemoryCache) DeleteExpired() {
+	now := time.Now().UnixNano()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, elem := range c.items {
+		e := elem.Value.(*entry)
+		if e.expireAt > 0 && e.expireAt <= now {
+			c.removeElement(elem)
+		}
+	}
+}
+
+// getKey 返回添加前缀后的key
+func (c *MemoryCache) getKey(key string) string {
+	if c.prefix != "" {
+		return c.prefix + key
+	}
+	return key
+}
+
+// firstKey 返回第一个key，用于钩子
+func firstKey(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0]
+}
+
+// verKey 返回版本的key，用于钩子
+func verKey(ver *cache.Version) string {
+	if ver == nil {
+		return ""
+	}
+	return ver.Key
+}
+
+// allHit 所有值都存在时为命中，用于钩子
+func allHit(m map[string]interface{}) bool {
+	for _, v := range m {
+		if v == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// get 查询缓存项，过期的直接删除，调用方需要加锁
+//   参数
+//     key: 添加前缀后的key值
+//   返回
+//     缓存项，不存在返回nil
+func (c *MemoryCache) get(key string) *entry {
+	elem, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+
+	e := elem.Value.(*entry)
+	if e.expireAt > 0 && e.expireAt <= time.Now().UnixNano() {
+		c.removeElement(elem)
+		return nil
+	}
+
+	c.ll.MoveToFront(elem)
+	return e
+}
+
+// add 添加或覆盖缓存项，调用方需要加锁
+//   参数
+//     key:    添加前缀后的key值
+//     value:  数据
+//     expire: 过期时间，以秒为单位，小于等于0表示不过期
+//   返回
+//     缓存项
+func (c *MemoryCache) add(key string, value interface{}, expire int32) *entry {
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+
+	e := &entry{key: key, value: value, cas: c.nextCas()}
+	c.setExpire(e, expire)
+	c.items[key] = c.ll.PushFront(e)
+	c.resize(e)
+
+	return e
+}
+
+// setExpire 设置过期时间
+//   参数
+//     e:      缓存项
+//     expire: 过期时间，以秒为单位，小于等于0表示不过期
+//   返回
+//
+func (c *MemoryCache) setExpire(e *entry, expire int32) {
+	if expire > 0 {
+		e.expireAt = time.Now().Add(time.Duration(expire) * time.Second).UnixNano()
+	} else {
+		e.expireAt = 0
+	}
+}
+
+// nextCas 生成新的数据版本，调用方需要加锁
+func (c *MemoryCache) nextCas() uint64 {
+	c.casId++
+	return c.casId
+}
+
+// resize 重新计算缓存项的内存占用，并按LRU淘汰数据，调用方需要加锁
+//   参数
+//     e: 缓存项
+//   返回
+//
+func (c *MemoryCache) resize(e *entry) {
+	size := len(e.key)
+	switch v := e.value.(type) {
+	case []byte:
+		size += len(v)
+	case map[string][]byte:
+		for f, d := range v {
+			size += len(f) + len(d)
+		}
+	case map[string]float64:
+		for m := range v {
+			size += len(m) + 8
+		}
+	case hll:
+		for m := range v {
+			size += len(m)
+		}
+	case [][]byte:
+		for _, d := range v {
+			size += len(d)
+		}
+	case set:
+		for m := range v {
+			size += len(m)
+		}
+	case *stream:
+		for _, item := range v.entries {
+			size += 16
+			for f, d := range item.values {
+				size += len(f) + len(d)
+			}
+		}
+	}
+
+	c.used += int64(size - e.size)
+	e.size = size
+
+	for c.ll.Len() > 0 {
+		if (c.maxEntries > 0 && c.ll.Len() > c.maxEntries) || (c.maxMemory > 0 && c.used > c.maxMemory) {
+			c.removeElement(c.ll.Back())
+		} else {
+			break
+		}
+	}
+}
+
+// removeElement 删除缓存项，调用方需要加锁
+func (c *MemoryCache) removeElement(elem *list.Element) {
+	e := elem.Value.(*entry)
+	c.ll.Remove(elem)
+	delete(c.items, e.key)
+	c.used -= int64(e.size)
+}
+
+// getBytes 查询字符串类型的数据，调用方需要加锁
+//   参数
+//     key: 添加前缀后的key值
+//   返回
+//     数据、是否存在、错误信息
+func (c *MemoryCache) getBytes(key string) ([]byte, bool, error) {
+	e := c.get(key)
+	if e == nil {
+		return nil, false, nil
+	}
+
+	data, ok := e.value.([]byte)
+	if !ok {
+		return nil, true, errWrongType
+	}
+
+	return data, true, nil
+}
+
+// getHash 查询哈希表，调用方需要加锁
+//   参数
+//     key:    添加前缀后的key值
+//     create: 不存在时是否新建
+//   返回
+//     缓存项、错误信息，不存在且不新建时返回nil
+func (c *MemoryCache) getHash(key string, create bool) (*entry, error) {
+	e := c.get(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		e = c.add(key, make(map[string][]byte), 0)
+	}
+
+	if _, ok := e.value.(map[string][]byte); !ok {
+		return nil, errWrongType
+	}
+
+	return e, nil
+}
+
+// getZSet 查询有序集合，调用方需要加锁
+//   参数
+//     key:    添加前缀后的key值
+//     create: 不存在时是否新建
+//   返回
+//     缓存项、错误信息，不存在且不新建时返回nil
+func (c *MemoryCache) getZSet(key string, create bool) (*entry, error) {
+	e := c.get(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		e = c.add(key, make(map[string]float64), 0)
+	}
+
+	if _, ok := e.value.(map[string]float64); !ok {
+		return nil, errWrongType
+	}
+
+	return e, nil
+}
+
+// Set 向缓存设置一个值
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 到期是缓存过期时间，以秒为单位：从现在开始的相对时间。“0”表示项目没有到期时间。
+//     encode: 是否加密标识
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *MemoryCache) Set(key string, val interface{}, expire int32, encode ...bool) (err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "set", key)(&err, nil)
+
+	data, err := c.encodeValue(val, encode...)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.add(c.getKey(key), data, expire)
+
+	return nil
+}
+
+// SetCtx 同Set，ctx用于控制超时和取消
+func (c *MemoryCache) SetCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.Set(key, val, expire, encode...)
+}
+
+// Get 从缓存取一个值
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     错误信息，是否存在
+func (c *MemoryCache) Get(key string, val interface{}) (err error, exist bool) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "get", key)(&err, &exist)
+
+	c.lock.Lock()
+	data, exist, err := c.getBytes(c.getKey(key))
+	c.lock.Unlock()
+	if err != nil || !exist {
+		return err, exist
+	}
+
+	return c.decodeValue(data, val), true
+}
+
+// encodeValue 生成要保存的数据，处理序列化和加密
+//   参数
+//     val:    value值
+//     encode: 是否加密标识
+//   返回
+//     要保存的数据，失败返回错误信息
+func (c *MemoryCache) encodeValue(val interface{}, encode ...bool) ([]byte, error) {
+	// 类型转换
+	data, err := cache.InterToByte(val, c.serializer)
+	if err != nil {
+		return nil, err
+	}
+
+	// 加密判断
+	encode = append(encode, false)
+	if encode[0] {
+		return cache.Encode(data, c.encodeKey...)
+	}
+
+	return data, nil
+}
+
+// decodeValue 解析保存的数据，处理解密和类型转换
+//   参数
+//     data: 保存的数据
+//     val:  保存结果地址
+//   返回
+//     失败返回错误信息
+func (c *MemoryCache) decodeValue(data []byte, val interface{}) error {
+	// 解密判断
+	data, err := cache.Decode(data, c.encodeKey...)
+	if err != nil {
+		return err
+	}
+
+	// 类型转换
+	return cache.ByteToInter(data, val)
+}
+
+// GetCtx 同Get，ctx用于控制超时和取消
+func (c *MemoryCache) GetCtx(ctx context.Context, key string, val interface{}) (error, bool) {
+	if err := ctx.Err(); err != nil {
+		return err, false
+	}
+
+	return c.Get(key, val)
+}
+
+// Lookup 从缓存取一个值，同Get，返回值顺序为(是否存在, 错误信息)
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     是否存在，错误信息，key不存在时返回false和nil
+func (c *MemoryCache) Lookup(key string, val interface{}) (bool, error) {
+	return c.LookupCtx(context.Background(), key, val)
+}
+
+// LookupCtx 同Lookup，ctx用于控制超时和取消
+func (c *MemoryCache) LookupCtx(ctx context.Context, key string, val interface{}) (bool, error) {
+	err, exist := c.GetCtx(ctx, key, val)
+	return exist, err
+}
+
+// Del 从缓存删除一个值
+//   参数
+//     key: key值
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *MemoryCache) Del(key string) (err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "del", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.items[c.getKey(key)]; ok {
+		c.removeElement(elem)
+	}
+
+	return nil
+}
+
+// DelCtx 同Del，ctx用于控制超时和取消
+func (c *MemoryCache) DelCtx(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.Del(key)
+}
+
+// Add key不存在时设置一个值
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key已存在返回false，失败返回错误信息
+func (c *MemoryCache) Add(key string, val interface{}, expire int32, encode ...bool) (ok bool, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "add", key)(&err, nil)
+
+	data, err := c.encodeValue(val, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	key = c.getKey(key)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.get(key) != nil {
+		return false, nil
+	}
+
+	c.add(key, data, expire)
+	return true, nil
+}
+
+// AddCtx 同Add，ctx用于控制超时和取消
+func (c *MemoryCache) AddCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return c.Add(key, val, expire, encode...)
+}
+
+// Replace key存在时设置一个值
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key不存在返回false，失败返回错误信息
+func (c *MemoryCache) Replace(key string, val interface{}, expire int32, encode ...bool) (ok bool, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "replace", key)(&err, nil)
+
+	data, err := c.encodeValue(val, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	key = c.getKey(key)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.get(key) == nil {
+		return false, nil
+	}
+
+	c.add(key, data, expire)
+	return true, nil
+}
+
+// ReplaceCtx 同Replace，ctx用于控制超时和取消
+func (c *MemoryCache) ReplaceCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return c.Replace(key, val, expire, encode...)
+}
+
+// GetWithVersion 从缓存取一个值，同时返回数据的版本，用于CompareAndSwap
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     数据的版本，key不存在时返回nil，失败返回错误信息
+func (c *MemoryCache) GetWithVersion(key string, val interface{}) (ver *cache.Version, err error) {
+	done := c.hooks.Process(context.Background(), cache.AdapterMemory, "gets", key)
+	defer func() {
+		hit := ver != nil
+		done(&err, &hit)
+	}()
+
+	c.lock.Lock()
+	e := c.get(c.getKey(key))
+	if e == nil {
+		c.lock.Unlock()
+		return nil, nil
+	}
+	data, ok := e.value.([]byte)
+	cas := e.cas
+	c.lock.Unlock()
+	if !ok {
+		return nil, errWrongType
+	}
+
+	return &cache.Version{Key: key, Token: cas}, c.decodeValue(data, val)
+}
+
+// GetWithVersionCtx 同GetWithVersion，ctx用于控制超时和取消
+func (c *MemoryCache) GetWithVersionCtx(ctx context.Context, key string, val interface{}) (*cache.Version, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.GetWithVersion(key, val)
+}
+
+// CompareAndSwap 数据的版本与GetWithVersion返回的版本一致时设置新值
+//   参数
+//     ver:    GetWithVersion返回的版本
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示保留原来的过期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，数据已被修改或删除返回false，失败返回错误信息
+func (c *MemoryCache) CompareAndSwap(ver *cache.Version, val interface{}, expire int32, encode ...bool) (ok bool, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "cas", verKey(ver))(&err, nil)
+
+	if ver == nil {
+		return false, errors.New("MemoryCache: CompareAndSwap invalid version")
+	}
+	cas, ok := ver.Token.(uint64)
+	if !ok {
+		return false, errors.New("MemoryCache: CompareAndSwap invalid version")
+	}
+
+	data, err := c.encodeValue(val, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	key := c.getKey(ver.Key)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e := c.get(key)
+	if e == nil || e.cas != cas {
+		return false, nil
+	}
+
+	expireAt := e.expireAt
+	e = c.add(key, data, expire)
+	if expire <= 0 {
+		e.expireAt = expireAt
+	}
+	return true, nil
+}
+
+// CompareAndSwapCtx 同CompareAndSwap，ctx用于控制超时和取消
+func (c *MemoryCache) CompareAndSwapCtx(ctx context.Context, ver *cache.Version, val interface{}, expire int32, encode ...bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return c.CompareAndSwap(ver, val, expire, encode...)
+}
+
+// MSet 同时设置一个或多个key-value对
+//   参数
+//     mList:  key-value对
+//     expire: 到期是缓存过期时间，以秒为单位：从现在开始的相对时间。“0”表示项目没有到期时间。
+//     encode: 是否加密标识
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *MemoryCache) MSet(mList map[string]interface{}, expire int32, encode ...bool) (err error) {
+	keys := make([]string, 0, len(mList))
+	for key := range mList {
+		keys = append(keys, key)
+	}
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "mset", firstKey(keys))(&err, nil)
+
+	// 先转换所有的值，有错误时不写入
+	list := make([][]byte, len(keys))
+	for i, key := range keys {
+		if list[i], err = c.encodeValue(mList[key], encode...); err != nil {
+			return err
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i, key := range keys {
+		c.add(c.getKey(key), list[i], expire)
+	}
+
+	return nil
+}
+
+// MSetCtx 同MSet，ctx用于控制超时和取消
+func (c *MemoryCache) MSetCtx(ctx context.Context, mList map[string]interface{}, expire int32, encode ...bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.MSet(mList, expire, encode...)
+}
+
+// MGet 同时获取一个或多个key的value，
+// struct、map类型返回的结果需要调用方做一下json.Unmarshal处理
+//   参数
+//     keys:  要查询的key值
+//   返回
+//     成功返回查询结果，失败返回错误信息，key不存在时对应的val为nil
+func (c *MemoryCache) MGet(keys ...string) (mList map[string]interface{}, err error) {
+	done := c.hooks.Process(context.Background(), cache.AdapterMemory, "mget", firstKey(keys))
+	defer func() {
+		hit := allHit(mList)
+		done(&err, &hit)
+	}()
+
+	mList = make(map[string]interface{})
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, key := range keys {
+		data, exist, err := c.getBytes(c.getKey(key))
+		if err != nil || !exist {
+			// 不存在或类型不对
+			mList[key] = nil
+			continue
+		}
+
+		// 解密判断
+		data, err = cache.Decode(data, c.encodeKey...)
+		if err != nil {
+			return mList, err
+		}
+
+		mList[key] = string(data)
+	}
+
+	return mList, nil
+}
+
+// MGetCtx 同MGet，ctx用于控制超时和取消
+func (c *MemoryCache) MGetCtx(ctx context.Context, keys ...string) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.MGet(keys...)
+}
+
+// MDel 同时删除一个或多个key
+//   参数
+//     keys:  要删除的key值
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *MemoryCache) MDel(keys ...string) (err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "del", firstKey(keys))(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, key := range keys {
+		if elem, ok := c.items[c.getKey(key)]; ok {
+			c.removeElement(elem)
+		}
+	}
+
+	return nil
+}
+
+// MDelCtx 同MDel，ctx用于控制超时和取消
+func (c *MemoryCache) MDelCtx(ctx context.Context, keys ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.MDel(keys...)
+}
+
+// incrBy 字符串类型数据增加指定的量，调用方需要加锁
+//   参数
+//     key:   添加前缀后的key值
+//     delta: 增加的量
+//   返回
+//     增加后的结果，失败返回错误信息
+func (c *MemoryCache) incrBy(key string, delta int64) (int64, error) {
+	e := c.get(key)
+	if e == nil {
+		e = c.add(key, []byte("0"), 0)
+	}
+
+	data, ok := e.value.([]byte)
+	if !ok {
+		return 0, errWrongType
+	}
+
+	n, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return 0, errNotInt
+	}
+
+	n += delta
+	e.value = []byte(strconv.FormatInt(n, 10))
+	e.cas = c.nextCas()
+	c.resize(e)
+
+	return n, nil
+}
+
+// Incr 缓存里的值自增
+// key不存在时会新建一个，再返回1
+//   参数
+//     key:   递增的key值
+//     delta: 递增的量
+//   返回
+//     递增后的结果，失败返回错误信息
+func (c *MemoryCache) Incr(key string, delta ...uint64) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "incr", key)(&err, nil)
+
+	delta = append(delta, 1)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.incrBy(c.getKey(key), int64(delta[0]))
+}
+
+// IncrCtx 同Incr，ctx用于控制超时和取消
+func (c *MemoryCache) IncrCtx(ctx context.Context, key string, delta ...uint64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.Incr(key, delta...)
+}
+
+// Decr 缓存里的值自减
+// key不存在时会新建一个，再返回-1
+//   参数
+//     key:   递减的key值
+//     delta: 递减的量
+//   返回
+//     递减后的结果，失败返回错误信息
+func (c *MemoryCache) Decr(key string, delta ...uint64) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "decr", key)(&err, nil)
+
+	delta = append(delta, 1)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.incrBy(c.getKey(key), 0-int64(delta[0]))
+}
+
+// DecrCtx 同Decr，ctx用于控制超时和取消
+func (c *MemoryCache) DecrCtx(ctx context.Context, key string, delta ...uint64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.Decr(key, delta...)
+}
+
+// IncrEx 缓存里的值自增并设置过期时间
+// key不存在时会新建一个，每次自增都会更新过期时间
+//   参数
+//     key:    递增的key值
+//     delta:  递增的量，负数时为递减
+//     expire: 过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     递增后的结果，失败返回错误信息
+func (c *MemoryCache) IncrEx(key string, delta int64, expire int32) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "incrby", key)(&err, nil)
+
+	key = c.getKey(key)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	n, err = c.incrBy(key, delta)
+	if err != nil {
+		return 0, err
+	}
+
+	if expire > 0 {
+		c.setExpire(c.get(key), expire)
+	}
+
+	return n, nil
+}
+
+// IncrExCtx 同IncrEx，ctx用于控制超时和取消
+func (c *MemoryCache) IncrExCtx(ctx context.Context, key string, delta int64, expire int32) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.IncrEx(key, delta, expire)
+}
+
+// IsExist 判断key值是否存在
+//   参数
+//     key:  要查询的key值
+//   返回
+//     存在返回true，不存在返回false
+func (c *MemoryCache) IsExist(key string) (exist bool, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "exists", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.get(c.getKey(key)) != nil, nil
+}
+
+// IsExistCtx 同IsExist，ctx用于控制超时和取消
+func (c *MemoryCache) IsExistCtx(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return c.IsExist(key)
+}
+
+// ClearAll 清空所有数据
+//   参数
+//
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *MemoryCache) ClearAll() (err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "flushdb", "")(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.ll.Init()
+	c.used = 0
+
+	return nil
+}
+
+// ClearAllCtx 同ClearAll，ctx用于控制超时和取消
+func (c *MemoryCache) ClearAllCtx(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.ClearAll()
+}
+
+// TTL 查询key的剩余过期时间，精确到毫秒
+//   参数
+//     key: key值
+//   返回
+//     剩余过期时间，没有过期时间返回cache.TTL_PERSIST，key不存在返回cache.TTL_NOT_EXIST
+func (c *MemoryCache) TTL(key string) (ttl time.Duration, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "pttl", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e := c.get(c.getKey(key))
+	if e == nil {
+		return cache.TTL_NOT_EXIST, nil
+	} else if e.expireAt == 0 {
+		return cache.TTL_PERSIST, nil
+	}
+
+	ttl = time.Duration(e.expireAt - time.Now().UnixNano())
+	return ttl.Truncate(time.Millisecond), nil
+}
+
+// TTLCtx 同TTL，ctx用于控制超时和取消
+func (c *MemoryCache) TTLCtx(ctx context.Context, key string) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.TTL(key)
+}
+
+// Expire 设置key的过期时间
+//   参数
+//     key:    key值
+//     expire: 过期时间，小于等于0时删除key
+//   返回
+//     key存在返回true，不存在返回false
+func (c *MemoryCache) Expire(key string, expire time.Duration) (ok bool, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "expire", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.setExpireAt(c.getKey(key), time.Now().Add(expire).UnixNano()), nil
+}
+
+// ExpireCtx 同Expire，ctx用于控制超时和取消
+func (c *MemoryCache) ExpireCtx(ctx context.Context, key string, expire time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return c.Expire(key, expire)
+}
+
+// ExpireAt 设置key在指定时间过期
+//   参数
+//     key: key值
+//     tm:  过期的时间点，早于当前时间时删除key
+//   返回
+//     key存在返回true，不存在返回false
+func (c *MemoryCache) ExpireAt(key string, tm time.Time) (ok bool, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "expireat", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.setExpireAt(c.getKey(key), tm.UnixNano()), nil
+}
+
+// ExpireAtCtx 同ExpireAt，ctx用于控制超时和取消
+func (c *MemoryCache) ExpireAtCtx(ctx context.Context, key string, tm time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return c.ExpireAt(key, tm)
+}
+
+// Persist 删除key的过期时间，key不再过期
+//   参数
+//     key: key值
+//   返回
+//     删除了过期时间返回true，key不存在或没有过期时间返回false
+func (c *MemoryCache) Persist(key string) (ok bool, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "persist", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e := c.get(c.getKey(key))
+	if e == nil || e.expireAt == 0 {
+		return false, nil
+	}
+
+	e.expireAt = 0
+	return true, nil
+}
+
+// PersistCtx 同Persist，ctx用于控制超时和取消
+func (c *MemoryCache) PersistCtx(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return c.Persist(key)
+}
+
+// Touch 更新key的过期时间，与Set的expire参数一致
+//   参数
+//     key:    key值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//   返回
+//     key存在返回true，不存在返回false
+func (c *MemoryCache) Touch(key string, expire int32) (ok bool, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "touch", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e := c.get(c.getKey(key))
+	if e == nil {
+		return false, nil
+	}
+
+	c.setExpire(e, expire)
+	return true, nil
+}
+
+// TouchCtx 同Touch，ctx用于控制超时和取消
+func (c *MemoryCache) TouchCtx(ctx context.Context, key string, expire int32) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return c.Touch(key, expire)
+}
+
+// GetEx 从缓存取一个值，同时更新过期时间，用于滑动过期
+//   参数
+//     key:    key值
+//     val:    保存结果地址
+//     expire: 新的过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     错误信息，是否存在
+func (c *MemoryCache) GetEx(key string, val interface{}, expire int32) (err error, exist bool) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "getex", key)(&err, &exist)
+
+	key = c.getKey(key)
+
+	c.lock.Lock()
+	data, exist, err := c.getBytes(key)
+	if err == nil && exist && expire > 0 {
+		c.setExpire(c.get(key), expire)
+	}
+	c.lock.Unlock()
+	if err != nil || !exist {
+		return err, exist
+	}
+
+	return c.decodeValue(data, val), true
+}
+
+// GetExCtx 同GetEx，ctx用于控制超时和取消
+func (c *MemoryCache) GetExCtx(ctx context.Context, key string, val interface{}, expire int32) (error, bool) {
+	if err := ctx.Err(); err != nil {
+		return err, false
+	}
+
+	return c.GetEx(key, val, expire)
+}
+
+// setExpireAt 设置key的过期时间点，调用方需要加锁
+//   参数
+//     key:      添加前缀后的key值
+//     expireAt: 过期时间，UnixNano，早于当前时间时删除key
+//   返回
+//     key存在返回true
+func (c *MemoryCache) setExpireAt(key string, expireAt int64) bool {
+	e := c.get(key)
+	if e == nil {
+		return false
+	}
+
+	if expireAt <= time.Now().UnixNano() {
+		c.removeElement(c.items[key])
+	} else {
+		e.expireAt = expireAt
+	}
+
+	return true
+}
+
+// Hset 添加哈希表
+//   参数
+//     key:    哈希表key值
+//     field:  哈希表field值
+//     val:    哈希表value值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//   返回
+//     成功时返回添加的个数，失败返回错误信息
+func (c *MemoryCache) HSet(key string, field string, val interface{}, expire int32) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "hset", key)(&err, nil)
+
+	// 类型转换
+	data, err := cache.InterToByte(val, c.serializer)
+	if err != nil {
+		return -1, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, err := c.getHash(c.getKey(key), true)
+	if err != nil {
+		return -1, err
+	}
+
+	e.value.(map[string][]byte)[field] = data
+	if expire > 0 {
+		c.setExpire(e, expire)
+	}
+	c.resize(e)
+
+	return 1, nil
+}
+
+// HSetCtx 同HSet，ctx用于控制超时和取消
+func (c *MemoryCache) HSetCtx(ctx context.Context, key string, field string, val interface{}, expire int32) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.HSet(key, field, val, expire)
+}
+
+// HGet 查询哈希表数据
+//   参数
+//     key:   哈希表key值
+//     field: 哈希表field值
+//     val:   保存结果地址
+//   返回
+//     错误信息，是否存在
+func (c *MemoryCache) HGet(key string, field string, val interface{}) (err error, exist bool) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "hget", key)(&err, &exist)
+
+	c.lock.Lock()
+	e, err := c.getHash(c.getKey(key), false)
+	if err != nil || e == nil {
+		c.lock.Unlock()
+		return err, false
+	}
+	data, ok := e.value.(map[string][]byte)[field]
+	c.lock.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	// 类型转换
+	err = cache.ByteToInter(data, val)
+	if err != nil {
+		return err, true
+	}
+
+	return nil, true
+}
+
+// HGetCtx 同HGet，ctx用于控制超时和取消
+func (c *MemoryCache) HGetCtx(ctx context.Context, key string, field string, val interface{}) (error, bool) {
+	if err := ctx.Err(); err != nil {
+		return err, false
+	}
+
+	return c.HGet(key, field, val)
+}
+
+// HDel 删除哈希表数据
+//   参数
+//     key:    哈希表key值
+//     fields: 哈希表field值
+//   返回
+//     成功返回nil，失败返回错误信息
+func (c *MemoryCache) HDel(key string, fields ...string) (err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "hdel", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key = c.getKey(key)
+	e, err := c.getHash(key, false)
+	if err != nil || e == nil {
+		return err
+	}
+
+	h := e.value.(map[string][]byte)
+	for _, field := range fields {
+		delete(h, field)
+	}
+
+	// 没有数据时删除key
+	if len(h) == 0 {
+		c.removeElement(c.items[key])
+	} else {
+		c.resize(e)
+	}
+
+	return nil
+}
+
+// HDelCtx 同HDel，ctx用于控制超时和取消
+func (c *MemoryCache) HDelCtx(ctx context.Context, key string, fields ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.HDel(key, fields...)
+}
+
+// HGetAll 返回哈希表 key 中，所有的域和值，struct、map类型需要业务层调用json.Unmarshal
+//   参数
+//     key: 哈希表key值
+//   返回
+//     查询的结果数据和错误码
+func (c *MemoryCache) HGetAll(key string) (res map[string]interface{}, err error) {
+	done := c.hooks.Process(context.Background(), cache.AdapterMemory, "hgetall", key)
+	defer func() {
+		hit := len(res) > 0
+		done(&err, &hit)
+	}()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	res = make(map[string]interface{})
+	e, err := c.getHash(c.getKey(key), false)
+	if err != nil {
+		return nil, err
+	} else if e == nil {
+		return res, nil
+	}
+
+	for k, v := range e.value.(map[string][]byte) {
+		res[k] = string(v)
+	}
+
+	return res, nil
+}
+
+// HGetAllCtx 同HGetAll，ctx用于控制超时和取消
+func (c *MemoryCache) HGetAllCtx(ctx context.Context, key string) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.HGetAll(key)
+}
+
+// HMSet 同时将多个 field-value (域-值)对设置到哈希表 key 中
+//   参数
+//     key:    哈希表key值
+//     fields: field-value 对
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//   返回
+//     执行结果
+func (c *MemoryCache) HMSet(key string, fields map[string]interface{}, expire int32) (err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "hmset", key)(&err, nil)
+
+	vals := make(map[string][]byte, len(fields))
+	for field, val := range fields {
+		data, err := cache.InterToByte(val, c.serializer)
+		if err != nil {
+			return err
+		}
+		vals[field] = data
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, err := c.getHash(c.getKey(key), true)
+	if err != nil {
+		return err
+	}
+
+	h := e.value.(map[string][]byte)
+	for field, data := range vals {
+		h[field] = data
+	}
+	if expire > 0 {
+		c.setExpire(e, expire)
+	}
+	c.resize(e)
+
+	return nil
+}
+
+// HMSetCtx 同HMSet，ctx用于控制超时和取消
+func (c *MemoryCache) HMSetCtx(ctx context.Context, key string, fields map[string]interface{}, expire int32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.HMSet(key, fields, expire)
+}
+
+// HMGet 返回哈希表 key 中，一个或多个给定域的值，struct、map类型需要业务层调用json.Unmarshal
+//   参数
+//     key:    哈希表key值
+//     fields: 给定域的集合
+//   返回
+//     查询的结果数据和错误码，field不存在时对应的val为nil
+func (c *MemoryCache) HMGet(key string, fields ...string) (res map[string]interface{}, err error) {
+	done := c.hooks.Process(context.Background(), cache.AdapterMemory, "hmget", key)
+	defer func() {
+		hit := allHit(res)
+		done(&err, &hit)
+	}()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	res = make(map[string]interface{})
+	e, err := c.getHash(c.getKey(key), false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		res[field] = nil
+		if e == nil {
+			continue
+		}
+		if v, ok := e.value.(map[string][]byte)[field]; ok {
+			res[field] = string(v)
+		}
+	}
+
+	return res, nil
+}
+
+// HMGetCtx 同HMGet，ctx用于控制超时和取消
+func (c *MemoryCache) HMGetCtx(ctx context.Context, key string, fields ...string) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.HMGet(key, fields...)
+}
+
+// HVals 返回哈希表 key 中，所有域的值，struct、map类型需要业务层调用json.Unmarshal
+//   参数
+//     key: 哈希表key值
+//   返回
+//     查询的结果数据和错误码
+func (c *MemoryCache) HVals(key string) (res []interface{}, err error) {
+	done := c.hooks.Process(context.Background(), cache.AdapterMemory, "hvals", key)
+	defer func() {
+		hit := len(res) > 0
+		done(&err, &hit)
+	}()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, err := c.getHash(c.getKey(key), false)
+	if err != nil {
+		return nil, err
+	} else if e == nil {
+		return []interface{}{}, nil
+	}
+
+	h := e.value.(map[string][]byte)
+	res = make([]interface{}, 0, len(h))
+	for _, v := range h {
+		res = append(res, string(v))
+	}
+
+	return res, nil
+}
+
+// HValsCtx 同HVals，ctx用于控制超时和取消
+func (c *MemoryCache) HValsCtx(ctx context.Context, key string) ([]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.HVals(key)
+}
+
+// hIncrBy 哈希表的值增加指定的量，调用方需要加锁
+//   参数
+//     key:   添加前缀后的key值
+//     field: 哈希表field值
+//     delta: 增加的量
+//   返回
+//     增加后的结果，失败返回错误信息
+func (c *MemoryCache) hIncrBy(key, field string, delta int64) (int64, error) {
+	e, err := c.getHash(key, true)
+	if err != nil {
+		return 0, err
+	}
+
+	h := e.value.(map[string][]byte)
+	var n int64
+	if data, ok := h[field]; ok {
+		n, err = strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return 0, errNotInt
+		}
+	}
+
+	n += delta
+	h[field] = []byte(strconv.FormatInt(n, 10))
+	c.resize(e)
+
+	return n, nil
+}
+
+// HIncr 哈希表的值自增
+//   参数
+//     key:    哈希表key值
+//     fields: 哈希表field值
+//     delta:  递增的量，默认为1
+//   返回
+//     递增后的结果、失败返回错误信息
+func (c *MemoryCache) HIncr(key, fields string, delta ...uint64) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "hincrby", key)(&err, nil)
+
+	delta = append(delta, 1)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.hIncrBy(c.getKey(key), fields, int64(delta[0]))
+}
+
+// HIncrCtx 同HIncr，ctx用于控制超时和取消
+func (c *MemoryCache) HIncrCtx(ctx context.Context, key, fields string, delta ...uint64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.HIncr(key, fields, delta...)
+}
+
+// HDecr 哈希表的值自减
+//   参数
+//     key:    哈希表key值
+//     fields: 哈希表field值
+//     delta:  递减的量，默认为1
+//   返回
+//     递减后的结果、失败返回错误信息
+func (c *MemoryCache) HDecr(key, fields string, delta ...uint64) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "hincrby", key)(&err, nil)
+
+	delta = append(delta, 1)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.hIncrBy(c.getKey(key), fields, 0-int64(delta[0]))
+}
+
+// HDecrCtx 同HDecr，ctx用于控制超时和取消
+func (c *MemoryCache) HDecrCtx(ctx context.Context, key, fields string, delta ...uint64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.HDecr(key, fields, delta...)
+}
+
+// ZSet 添加有序集合
+//   参数
+//     key:    有序集合key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     val:    有序集合值，数据为成对出来，前面为score(整数值或双精度浮点数), 后面为变量
+//   返回
+//     成功添加的数据个数和错误码
+func (c *MemoryCache) ZSet(key string, expire int32, val ...interface{}) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "zadd", key)(&err, nil)
+
+	members, err := zset.ParseMembers(val)
+	if err != nil {
+		return -1, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, err := c.getZSet(c.getKey(key), true)
+	if err != nil {
+		return -1, err
+	}
+
+	z := e.value.(map[string]float64)
+	for _, m := range members {
+		if _, ok := z[m.Member]; !ok {
+			n++
+		}
+		z[m.Member] = m.Score
+	}
+	if expire > 0 {
+		c.setExpire(e, expire)
+	}
+	c.resize(e)
+
+	return n, nil
+}
+
+// ZSetCtx 同ZSet，ctx用于控制超时和取消
+func (c *MemoryCache) ZSetCtx(ctx context.Context, key string, expire int32, val ...interface{}) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.ZSet(key, expire, val...)
+}
+
+// ZGet 查询有序集合
+//   参数
+//     key:        有序集合key值
+//     start:      要查询有序集开始下标，0表示第一个，-1表示最后一个，-2表示倒数第二个
+//     stop:       要查询有序集结束下标，0表示第一个，-1表示最后一个，-2表示倒数第二个
+//     withScores: 是否带上score
+//     isRev:      true-递减排列 false-递增排列
+//   返回
+//     查询的结果数据和错误码
+func (c *MemoryCache) ZGet(key string, start, stop int, withScores bool, isRev bool) (res []string, err error) {
+	done := c.hooks.Process(context.Background(), cache.AdapterMemory, "zrange", key)
+	defer func() {
+		hit := len(res) > 0
+		done(&err, &hit)
+	}()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, err := c.getZSet(c.getKey(key), false)
+	if err != nil {
+		return []string{}, err
+	} else if e == nil {
+		return []string{}, nil
+	}
+
+	return zset.Range(e.value.(map[string]float64), start, stop, withScores, isRev), nil
+}
+
+// ZGetCtx 同ZGet，ctx用于控制超时和取消
+func (c *MemoryCache) ZGetCtx(ctx context.Context, key string, start, stop int, withScores bool, isRev bool) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.ZGet(key, start, stop, withScores, isRev)
+}
+
+// zRem 删除有序集合里满足条件的成员，调用方需要加锁
+//   参数
+//     key: 有序集合key值
+//     fn:  判断是否要删除的函数，参数为排序后的下标和成员
+//   返回
+//     成功删除的数据个数和错误码
+func (c *MemoryCache) zRem(key string, fn func(i int, m zset.Member) bool) (int64, error) {
+	key = c.getKey(key)
+	e, err := c.getZSet(key, false)
+	if err != nil || e == nil {
+		return 0, err
+	}
+
+	z := e.value.(map[string]float64)
+	var n int64
+	for i, m := range zset.Sort(z) {
+		if fn(i, m) {
+			delete(z, m.Member)
+			n++
+		}
+	}
+
+	// 没有数据时删除key
+	if len(z) == 0 {
+		c.removeElement(c.items[key])
+	} else {
+		c.resize(e)
+	}
+
+	return n, nil
+}
+
+// ZDel 删除有序集合数据
+//   参数
+//     key:   有序集合key值
+//     field: 要删除的数据
+//   返回
+//     成功删除的数据个数和错误码
+func (c *MemoryCache) ZDel(key string, field ...string) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "zrem", key)(&err, nil)
+
+	fields := make(map[string]bool, len(field))
+	for _, f := range field {
+		fields[f] = true
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.zRem(key, func(i int, m zset.Member) bool {
+		return fields[m.Member]
+	})
+}
+
+// ZDelCtx 同ZDel，ctx用于控制超时和取消
+func (c *MemoryCache) ZDelCtx(ctx context.Context, key string, field ...string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.ZDel(key, field...)
+}
+
+// ZCard 返回有序集 key 的基数
+//   参数
+//     key: 有序集合key值
+//   返回
+//     有序集 key 的基数和错误码
+func (c *MemoryCache) ZCard(key string) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "zcard", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, err := c.getZSet(c.getKey(key), false)
+	if err != nil || e == nil {
+		return 0, err
+	}
+
+	return int64(len(e.value.(map[string]float64))), nil
+}
+
+// ZCardCtx 同ZCard，ctx用于控制超时和取消
+func (c *MemoryCache) ZCardCtx(ctx context.Context, key string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.ZCard(key)
+}
+
+// ZRemRangeByRank 删除指定排名区间内的有序集合数据
+//   参数
+//     key:   有序集合key值
+//     start: 开始值
+//     end:   结束值
+//   返回
+//     成功删除的数据个数和错误码
+func (c *MemoryCache) ZRemRangeByRank(key string, start, end int64) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "zremrangebyrank", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	length := int64(0)
+	if e, err := c.getZSet(c.getKey(key), false); err != nil {
+		return 0, err
+	} else if e != nil {
+		length = int64(len(e.value.(map[string]float64)))
+	}
+
+	from, to := zset.RangeIndex(start, end, length)
+	return c.zRem(key, func(i int, m zset.Member) bool {
+		return int64(i) >= from && int64(i) < to
+	})
+}
+
+// ZRemRangeByRankCtx 同ZRemRangeByRank，ctx用于控制超时和取消
+func (c *MemoryCache) ZRemRangeByRankCtx(ctx context.Context, key string, start, end int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.ZRemRangeByRank(key, start, end)
+}
+
+// ZRemRangeByScore 删除指定分值区间内的有序集合数据
+//   参数
+//     key:   有序集合key值
+//     start: 开始值
+//     end:   结束值
+//   返回
+//     成功删除的数据个数和错误码
+func (c *MemoryCache) ZRemRangeByScore(key string, start, end string) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "zremrangebyscore", key)(&err, nil)
+
+	r, err := zset.ParseScoreRange(start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.zRem(key, func(i int, m zset.Member) bool {
+		return r.Contains(m.Score)
+	})
+}
+
+// ZRemRangeByScoreCtx 同ZRemRangeByScore，ctx用于控制超时和取消
+func (c *MemoryCache) ZRemRangeByScoreCtx(ctx context.Context, key string, start, end string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.ZRemRangeByScore(key, start, end)
+}
+
+// ZRemRangeByLex 删除指定变量区间内的有序集合数据
+// 对于一个所有成员的分值都相同的有序集合键 key 来说， 这个命令会移除该集合中， 成员介于 min 和 max 范围内的所有元素。
+//   参数
+//     key:   有序集合key值
+//     start: 开始值
+//     end:   结束值
+//   返回
+//     成功删除的数据个数和错误码
+func (c *MemoryCache) ZRemRangeByLex(key string, start, end string) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "zremrangebylex", key)(&err, nil)
+
+	r, err := zset.ParseLexRange(start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.zRem(key, func(i int, m zset.Member) bool {
+		return r.Contains(m.Member)
+	})
+}
+
+// ZRemRangeByLexCtx 同ZRemRangeByLex，ctx用于控制超时和取消
+func (c *MemoryCache) ZRemRangeByLexCtx(ctx context.Context, key string, start, end string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.ZRemRangeByLex(key, start, end)
+}
+
+// SetBit 设置或清除指定偏移量上的位(bit)
+//   参数
+//     key:    位图key值
+//     offset: 位图偏移量
+//     value:  位图值，取值：0或1
+//     expire: 失效时长，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//   返回
+//     指定偏移量原来储存的位、错误信息
+func (c *MemoryCache) SetBit(key string, offset int64, value int, expire int32) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "setbit", key)(&err, nil)
+
+	if offset < 0 || offset >= 1<<32 {
+		return 0, errors.New("MemoryCache: bit offset is not an integer or out of range")
+	}
+	if value != 0 && value != 1 {
+		return 0, errors.New("MemoryCache: bit is not an integer or out of range")
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key = c.getKey(key)
+	e := c.get(key)
+	if e == nil {
+		e = c.add(key, []byte{}, 0)
+	}
+	data, ok := e.value.([]byte)
+	if !ok {
+		return 0, errWrongType
+	}
+
+	idx := offset / 8
+	if int64(len(data)) <= idx {
+		grown := make([]byte, idx+1)
+		copy(grown, data)
+		data = grown
+	}
+
+	mask := byte(1 << uint(7-offset%8))
+	var old int64
+	if data[idx]&mask != 0 {
+		old = 1
+	}
+	if value == 1 {
+		data[idx] |= mask
+	} else {
+		data[idx] &^= mask
+	}
+
+	e.value = data
+	e.cas = c.nextCas()
+	if expire > 0 {
+		c.setExpire(e, expire)
+	}
+	c.resize(e)
+
+	return old, nil
+}
+
+// SetBitCtx 同SetBit，ctx用于控制超时和取消
+func (c *MemoryCache) SetBitCtx(ctx context.Context, key string, offset int64, value int, expire int32) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.SetBit(key, offset, value, expire)
+}
+
+// GetBit 获取指定偏移量上的位(bit)
+//   参数
+//     key:    位图key值
+//     offset: 位图偏移量
+//   返回
+//     字符串值指定偏移量上的位(bit)、错误信息
+func (c *MemoryCache) GetBit(key string, offset int64) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "getbit", key)(&err, nil)
+
+	if offset < 0 {
+		return 0, errors.New("MemoryCache: bit offset is not an integer or out of range")
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	data, _, err := c.getBytes(c.getKey(key))
+	if err != nil {
+		return 0, err
+	}
+
+	idx := offset / 8
+	if int64(len(data)) <= idx {
+		return 0, nil
+	}
+	if data[idx]&byte(1<<uint(7-offset%8)) != 0 {
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+// GetBitCtx 同GetBit，ctx用于控制超时和取消
+func (c *MemoryCache) GetBitCtx(ctx context.Context, key string, offset int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.GetBit(key, offset)
+}
+
+// BitCount 计算给定字符串中被设置为 1 的比特位的数量
+//   参数
+//     key:      位图key值
+//     bitCount: 指定额外的 start 或 end 参数，统计只在特定的位上进行，为nil时统计所有的
+//   返回
+//     给定字符串中被设置为 1 的比特位的数量、错误信息
+func (c *MemoryCache) BitCount(key string, bitCount *cache.BitCount) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "bitcount", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	data, _, err := c.getBytes(c.getKey(key))
+	if err != nil {
+		return 0, err
+	}
+
+	if bitCount != nil {
+		from, to := zset.RangeIndex(bitCount.Start, bitCount.End, int64(len(data)))
+		data = data[from:to]
+	}
+
+	for _, b := range data {
+		for ; b != 0; b &= b - 1 {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// BitCountCtx 同BitCount，ctx用于控制超时和取消
+func (c *MemoryCache) BitCountCtx(ctx context.Context, key string, bitCount *cache.BitCount) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.BitCount(key, bitCount)
+}
+
+// PFAdd 添加基数，内存版为精确计数
+//   参数
+//     key:    HyperLogLog的key值
+//     expire: 失效时长，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   HyperLogLog的数据
+//   返回
+//     基数有变化返回1，否则返回0
+//     错误信息
+func (c *MemoryCache) PFAdd(key string, expire int32, vals ...interface{}) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "pfadd", key)(&err, nil)
+
+	members := make([]string, 0, len(vals))
+	for _, v := range vals {
+		data, err := cache.InterToByte(v)
+		if err != nil {
+			return 0, err
+		}
+		members = append(members, string(data))
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key = c.getKey(key)
+	var res int64
+	e := c.get(key)
+	if e == nil {
+		e = c.add(key, make(hll), 0)
+		res = 1
+	}
+	h, ok := e.value.(hll)
+	if !ok {
+		return 0, errWrongType
+	}
+
+	for _, m := range members {
+		if _, ok := h[m]; !ok {
+			h[m] = struct{}{}
+			res = 1
+		}
+	}
+	if expire > 0 {
+		c.setExpire(e, expire)
+	}
+	c.resize(e)
+
+	return res, nil
+}
+
+// PFAddCtx 同PFAdd，ctx用于控制超时和取消
+func (c *MemoryCache) PFAddCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.PFAdd(key, expire, vals...)
+}
+
+// PFCount 返回基数估算值，内存版为精确计数
+//   参数
+//     key: HyperLogLog的key值
+//   返回
+//     基数估算值
+func (c *MemoryCache) PFCount(key string) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "pfcount", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e := c.get(c.getKey(key))
+	if e == nil {
+		return 0, nil
+	}
+	h, ok := e.value.(hll)
+	if !ok {
+		return 0, errWrongType
+	}
+
+	return int64(len(h)), nil
+}
+
+// PFCountCtx 同PFCount，ctx用于控制超时和取消
+func (c *MemoryCache) PFCountCtx(ctx context.Context, key string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.PFCount(key)
+}
+
+// getList 查询列表，调用方需要加锁
+//   参数
+//     key:    添加前缀后的key值
+//     create: 不存在时是否新建
+//   返回
+//     缓存项、错误信息，不存在且不新建时返回nil
+func (c *MemoryCache) getList(key string, create bool) (*entry, error) {
+	e := c.get(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		e = c.add(key, [][]byte{}, 0)
+	}
+
+	if _, ok := e.value.([][]byte); !ok {
+		return nil, errWrongType
+	}
+
+	return e, nil
+}
+
+// push 向列表插入元素
+//   参数
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位
+//     isLeft: 是否从头部插入
+//     vals:   元素
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (c *MemoryCache) push(key string, expire int32, isLeft bool, vals []interface{}) (int64, error) {
+	items := make([][]byte, len(vals))
+	for i, val := range vals {
+		data, err := cache.InterToByte(val, c.serializer)
+		if err != nil {
+			return 0, err
+		}
+		items[i] = data
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, err := c.getList(c.getKey(key), true)
+	if err != nil {
+		return 0, err
+	}
+
+	l := e.value.([][]byte)
+	if isLeft {
+		// 与redis一致，按参数顺序依次插入头部
+		res := make([][]byte, 0, len(items)+len(l))
+		for i := len(items) - 1; i >= 0; i-- {
+			res = append(res, items[i])
+		}
+		l = append(res, l...)
+	} else {
+		l = append(l, items...)
+	}
+	e.value = l
+	if expire > 0 {
+		c.setExpire(e, expire)
+	}
+	c.resize(e)
+
+	return int64(len(l)), nil
+}
+
+// pop 从列表取出一个元素，取完后删除key，调用方需要加锁
+//   参数
+//     key:    添加前缀后的key值
+//     isLeft: 是否从头部取出
+//   返回
+//     元素、是否存在、错误信息
+func (c *MemoryCache) pop(key string, isLeft bool) ([]byte, bool, error) {
+	e, err := c.getList(key, false)
+	if err != nil || e == nil {
+		return nil, false, err
+	}
+
+	var data []byte
+	l := e.value.([][]byte)
+	if isLeft {
+		data, l = l[0], l[1:]
+	} else {
+		data, l = l[len(l)-1], l[:len(l)-1]
+	}
+
+	if len(l) == 0 {
+		c.removeElement(c.items[key])
+	} else {
+		e.value = l
+		c.resize(e)
+	}
+
+	return data, true, nil
+}
+
+// LPush 从列表头部插入元素
+//   参数
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (c *MemoryCache) LPush(key string, expire int32, vals ...interface{}) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "lpush", key)(&err, nil)
+
+	return c.push(key, expire, true, vals)
+}
+
+// LPushCtx 同LPush，ctx用于控制超时和取消
+func (c *MemoryCache) LPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.LPush(key, expire, vals...)
+}
+
+// RPush 从列表尾部插入元素
+//   参数
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (c *MemoryCache) RPush(key string, expire int32, vals ...interface{}) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "rpush", key)(&err, nil)
+
+	return c.push(key, expire, false, vals)
+}
+
+// RPushCtx 同RPush，ctx用于控制超时和取消
+func (c *MemoryCache) RPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.RPush(key, expire, vals...)
+}
+
+// LPop 从列表头部取出一个元素
+//   参数
+//     key: 列表key值
+//     val: 保存结果地址
+//   返回
+//     错误信息，是否存在
+func (c *MemoryCache) LPop(key string, val interface{}) (err error, exist bool) {
+	done := c.hooks.Process(context.Background(), cache.AdapterMemory, "lpop", key)
+	defer func() {
+		hit := exist
+		done(&err, &hit)
+	}()
+
+	c.lock.Lock()
+	data, exist, err := c.pop(c.getKey(key), true)
+	c.lock.Unlock()
+	if err != nil || !exist {
+		return err, exist
+	}
+
+	// 类型转换
+	err = cache.ByteToInter(data, val)
+	if err != nil {
+		return err, true
+	}
+
+	return nil, true
+}
+
+// LPopCtx 同LPop，ctx用于控制超时和取消
+func (c *MemoryCache) LPopCtx(ctx context.Context, key string, val interface{}) (error, bool) {
+	if err := ctx.Err(); err != nil {
+		return err, false
+	}
+
+	return c.LPop(key, val)
+}
+
+// BRPop 阻塞地从多个列表尾部取出一个元素，内存版按blockInterval轮询
+//   参数
+//     timeout: 阻塞时间，以秒为单位，“0”表示一直阻塞
+//     val:     保存结果地址
+//     keys:    列表key值，按顺序检查
+//   返回
+//     取到元素的列表key值，超时返回空串，错误信息
+func (c *MemoryCache) BRPop(timeout int32, val interface{}, keys ...string) (string, error) {
+	return c.BRPopCtx(context.Background(), timeout, val, keys...)
+}
+
+// BRPopCtx 同BRPop，ctx用于控制超时和取消
+func (c *MemoryCache) BRPopCtx(ctx context.Context, timeout int32, val interface{}, keys ...string) (res string, err error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	done := c.hooks.Process(ctx, cache.AdapterMemory, "brpop", firstKey(keys))
+	defer func() {
+		hit := res != ""
+		done(&err, &hit)
+	}()
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(time.Duration(timeout) * time.Second)
+	}
+
+	for {
+		c.lock.Lock()
+		for _, key := range keys {
+			data, exist, err := c.pop(c.getKey(key), false)
+			if err != nil || exist {
+				c.lock.Unlock()
+				if err != nil {
+					return "", err
+				}
+				return key, cache.ByteToInter(data, val)
+			}
+		}
+		c.lock.Unlock()
+
+		if err := waitBlock(ctx, deadline); err != nil {
+			if err == errBlockTimeout {
+				return "", nil
+			}
+			return "", err
+		}
+	}
+}
+
+// LRange 查询列表指定区间的元素
+//   参数
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
+//   返回
+//     成功时返回元素列表，失败返回错误信息
+func (c *MemoryCache) LRange(key string, start, stop int64) (res []interface{}, err error) {
+	done := c.hooks.Process(context.Background(), cache.AdapterMemory, "lrange", key)
+	defer func() {
+		hit := len(res) > 0
+		done(&err, &hit)
+	}()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	res = []interface{}{}
+	e, err := c.getList(c.getKey(key), false)
+	if err != nil || e == nil {
+		return res, err
+	}
+
+	l := e.value.([][]byte)
+	from, to := zset.RangeIndex(start, stop, int64(len(l)))
+	for _, data := range l[from:to] {
+		res = append(res, string(data))
+	}
+
+	return res, nil
+}
+
+// LRangeCtx 同LRange，ctx用于控制超时和取消
+func (c *MemoryCache) LRangeCtx(ctx context.Context, key string, start, stop int64) ([]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.LRange(key, start, stop)
+}
+
+// LTrim 只保留列表指定区间的元素
+//   参数
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *MemoryCache) LTrim(key string, start, stop int64) (err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "ltrim", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key = c.getKey(key)
+	e, err := c.getList(key, false)
+	if err != nil || e == nil {
+		return err
+	}
+
+	l := e.value.([][]byte)
+	from, to := zset.RangeIndex(start, stop, int64(len(l)))
+	if from >= to {
+		c.removeElement(c.items[key])
+		return nil
+	}
+	e.value = l[from:to]
+	c.resize(e)
+
+	return nil
+}
+
+// LTrimCtx 同LTrim，ctx用于控制超时和取消
+func (c *MemoryCache) LTrimCtx(ctx context.Context, key string, start, stop int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.LTrim(key, start, stop)
+}
+
+// getSet 查询集合，调用方需要加锁
+//   参数
+//     key:    添加前缀后的key值
+//     create: 不存在时是否新建
+//   返回
+//     缓存项、错误信息，不存在且不新建时返回nil
+func (c *MemoryCache) getSet(key string, create bool) (*entry, error) {
+	e := c.get(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		e = c.add(key, make(set), 0)
+	}
+
+	if _, ok := e.value.(set); !ok {
+		return nil, errWrongType
+	}
+
+	return e, nil
+}
+
+// toMembers 转换集合的成员，只做序列化
+func (c *MemoryCache) toMembers(members []interface{}) ([]string, error) {
+	res := make([]string, len(members))
+	for i, member := range members {
+		data, err := cache.InterToByte(member, c.serializer)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = string(data)
+	}
+
+	return res, nil
+}
+
+// SAdd 向集合添加成员
+//   参数
+//     key:     集合key值
+//     expire:  缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     members: 成员
+//   返回
+//     成功时返回新添加的个数，失败返回错误信息
+func (c *MemoryCache) SAdd(key string, expire int32, members ...interface{}) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "sadd", key)(&err, nil)
+
+	args, err := c.toMembers(members)
+	if err != nil {
+		return 0, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, err := c.getSet(c.getKey(key), true)
+	if err != nil {
+		return 0, err
+	}
+
+	s := e.value.(set)
+	for _, m := range args {
+		if _, ok := s[m]; !ok {
+			s[m] = struct{}{}
+			n++
+		}
+	}
+	if expire > 0 {
+		c.setExpire(e, expire)
+	}
+	c.resize(e)
+
+	return n, nil
+}
+
+// SAddCtx 同SAdd，ctx用于控制超时和取消
+func (c *MemoryCache) SAddCtx(ctx context.Context, key string, expire int32, members ...interface{}) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.SAdd(key, expire, members...)
+}
+
+// SRem 删除集合的成员
+//   参数
+//     key:     集合key值
+//     members: 成员
+//   返回
+//     成功时返回删除的个数，失败返回错误信息
+func (c *MemoryCache) SRem(key string, members ...interface{}) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "srem", key)(&err, nil)
+
+	args, err := c.toMembers(members)
+	if err != nil {
+		return 0, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key = c.getKey(key)
+	e, err := c.getSet(key, false)
+	if err != nil || e == nil {
+		return 0, err
+	}
+
+	s := e.value.(set)
+	for _, m := range args {
+		if _, ok := s[m]; ok {
+			delete(s, m)
+			n++
+		}
+	}
+
+	// 没有数据时删除key
+	if len(s) == 0 {
+		c.removeElement(c.items[key])
+	} else {
+		c.resize(e)
+	}
+
+	return n, nil
+}
+
+// SRemCtx 同SRem，ctx用于控制超时和取消
+func (c *MemoryCache) SRemCtx(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.SRem(key, members...)
+}
+
+// SIsMember 判断是否为集合的成员
+//   参数
+//     key:    集合key值
+//     member: 成员
+//   返回
+//     是成员返回true，不是返回false，失败返回错误信息
+func (c *MemoryCache) SIsMember(key string, member interface{}) (ok bool, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "sismember", key)(&err, nil)
+
+	args, err := c.toMembers([]interface{}{member})
+	if err != nil {
+		return false, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, err := c.getSet(c.getKey(key), false)
+	if err != nil || e == nil {
+		return false, err
+	}
+
+	_, ok = e.value.(set)[args[0]]
+	return ok, nil
+}
+
+// SIsMemberCtx 同SIsMember，ctx用于控制超时和取消
+func (c *MemoryCache) SIsMemberCtx(ctx context.Context, key string, member interface{}) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return c.SIsMember(key, member)
+}
+
+// SMembers 查询集合的所有成员
+//   参数
+//     key: 集合key值
+//   返回
+//     成功时返回所有成员，失败返回错误信息
+func (c *MemoryCache) SMembers(key string) (res []string, err error) {
+	done := c.hooks.Process(context.Background(), cache.AdapterMemory, "smembers", key)
+	defer func() {
+		hit := len(res) > 0
+		done(&err, &hit)
+	}()
+
+	return c.setOp([]string{key}, false)
+}
+
+// SMembersCtx 同SMembers，ctx用于控制超时和取消
+func (c *MemoryCache) SMembersCtx(ctx context.Context, key string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.SMembers(key)
+}
+
+// setOp 计算多个集合的交集或并集，结果按成员排序
+//   参数
+//     keys:    集合key值
+//     isInter: true-交集，false-并集
+//   返回
+//     成功时返回成员，失败返回错误信息
+func (c *MemoryCache) setOp(keys []string, isInter bool) ([]string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	count := make(map[string]int)
+	for _, key := range keys {
+		e, err := c.getSet(c.getKey(key), false)
+		if err != nil {
+			return nil, err
+		} else if e == nil {
+			continue
+		}
+		for m := range e.value.(set) {
+			count[m]++
+		}
+	}
+
+	res := make([]string, 0, len(count))
+	for m, n := range count {
+		if !isInter || n == len(keys) {
+			res = append(res, m)
+		}
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// SInter 返回多个集合的交集
+//   参数
+//     keys: 集合key值
+//   返回
+//     成功时返回交集的成员，失败返回错误信息
+func (c *MemoryCache) SInter(keys ...string) (res []string, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "sinter", firstKey(keys))(&err, nil)
+
+	return c.setOp(keys, true)
+}
+
+// SInterCtx 同SInter，ctx用于控制超时和取消
+func (c *MemoryCache) SInterCtx(ctx context.Context, keys ...string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.SInter(keys...)
+}
+
+// SUnion 返回多个集合的并集
+//   参数
+//     keys: 集合key值
+//   返回
+//     成功时返回并集的成员，失败返回错误信息
+func (c *MemoryCache) SUnion(keys ...string) (res []string, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "sunion", firstKey(keys))(&err, nil)
+
+	return c.setOp(keys, false)
+}
+
+// SUnionCtx 同SUnion，ctx用于控制超时和取消
+func (c *MemoryCache) SUnionCtx(ctx context.Context, keys ...string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.SUnion(keys...)
+}
+
+// getStream 查询流，调用方需要加锁
+//   参数
+//     key:    添加前缀后的key值
+//     create: 不存在时是否新建
+//   返回
+//     缓存项、错误信息，不存在且不新建时返回nil
+func (c *MemoryCache) getStream(key string, create bool) (*entry, error) {
+	e := c.get(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		e = c.add(key, &stream{groups: make(map[string]*streamGroup)}, 0)
+	}
+
+	if _, ok := e.value.(*stream); !ok {
+		return nil, errWrongType
+	}
+
+	return e, nil
+}
+
+// XAdd 向流添加消息
+//   参数
+//     key:    流key值
+//     maxLen: 流的最大长度，超过时删除旧消息，小于等于0时不限制
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   消息内容
+//   返回
+//     成功时返回消息id，失败返回错误信息
+func (c *MemoryCache) XAdd(key string, maxLen int64, expire int32, vals map[string]interface{}) (res string, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "xadd", key)(&err, nil)
+
+	values := make(map[string][]byte, len(vals))
+	for field, val := range vals {
+		data, err := cache.InterToByte(val, c.serializer)
+		if err != nil {
+			return "", err
+		}
+		values[field] = data
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, err := c.getStream(c.getKey(key), true)
+	if err != nil {
+		return "", err
+	}
+
+	s := e.value.(*stream)
+	id := streamId{ms: uint64(time.Now().UnixNano() / int64(time.Millisecond))}
+	if id.ms <= s.lastId.ms {
+		id = streamId{ms: s.lastId.ms, seq: s.lastId.seq + 1}
+	}
+	s.lastId = id
+	s.entries = append(s.entries, streamEntry{id: id, values: values})
+	if maxLen > 0 && int64(len(s.entries)) > maxLen {
+		s.entries = s.entries[int64(len(s.entries))-maxLen:]
+	}
+	if expire > 0 {
+		c.setExpire(e, expire)
+	}
+	c.resize(e)
+
+	return id.String(), nil
+}
+
+// XAddCtx 同XAdd，ctx用于控制超时和取消
+func (c *MemoryCache) XAddCtx(ctx context.Context, key string, maxLen int64, expire int32, vals map[string]interface{}) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	return c.XAdd(key, maxLen, expire, vals)
+}
+
+// XRead 读取流消息，内存版阻塞时按blockInterval轮询
+//   参数
+//     args: 读取参数，Streams前一半为key值，后一半为对应的开始id(不包含)，$表示只读新消息
+//   返回
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (c *MemoryCache) XRead(args *cache.XReadArgs) ([]cache.XStream, error) {
+	return c.XReadCtx(context.Background(), args)
+}
+
+// XReadCtx 同XRead，ctx用于控制超时和取消
+func (c *MemoryCache) XReadCtx(ctx context.Context, args *cache.XReadArgs) (res []cache.XStream, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer c.hooks.Process(ctx, cache.AdapterMemory, "xread", firstKey(args.Streams))(&err, nil)
+
+	keys, ids, err := c.streamIds(args.Streams)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.xread(ctx, args.Block, func() ([]cache.XStream, error) {
+		var res []cache.XStream
+		for i, key := range keys {
+			e, err := c.getStream(c.getKey(key), false)
+			if err != nil {
+				return nil, err
+			} else if e == nil {
+				continue
+			}
+
+			var msgs []cache.XMessage
+			for _, item := range e.value.(*stream).entries {
+				if args.Count > 0 && int64(len(msgs)) >= args.Count {
+					break
+				}
+				if ids[i].less(item.id) {
+					msgs = append(msgs, item.message())
+				}
+			}
+			if len(msgs) > 0 {
+				res = append(res, cache.XStream{Stream: key, Messages: msgs})
+			}
+		}
+		return res, nil
+	})
+}
+
+// XGroupCreate 创建消费组，流不存在时自动创建
+//   参数
+//     key:   流key值
+//     group: 消费组
+//     start: 开始读取的消息id，0表示从头开始，为空或$表示只读新消息
+//   返回
+//     成功或消费组已存在时返回nil，失败返回错误信息
+func (c *MemoryCache) XGroupCreate(key, group, start string) (err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "xgroup", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, err := c.getStream(c.getKey(key), true)
+	if err != nil {
+		return err
+	}
+
+	s := e.value.(*stream)
+	if _, ok := s.groups[group]; ok {
+		return nil
+	}
+
+	lastId := s.lastId
+	if start != "" && start != "$" {
+		lastId, err = parseStreamId(start)
+		if err != nil {
+			return err
+		}
+	}
+	s.groups[group] = &streamGroup{lastId: lastId, pending: make(map[streamId]string)}
+
+	return nil
+}
+
+// XGroupCreateCtx 同XGroupCreate，ctx用于控制超时和取消
+func (c *MemoryCache) XGroupCreateCtx(ctx context.Context, key, group, start string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.XGroupCreate(key, group, start)
+}
+
+// XReadGroup 按消费组读取流消息，内存版阻塞时按blockInterval轮询
+//   参数
+//     args: 读取参数，Streams前一半为key值，后一半为对应的id，>表示读取未分配给消费者的新消息
+//   返回
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (c *MemoryCache) XReadGroup(args *cache.XReadGroupArgs) ([]cache.XStream, error) {
+	return c.XReadGroupCtx(context.Background(), args)
+}
+
+// XReadGroupCtx 同XReadGroup，ctx用于控制超时和取消
+func (c *MemoryCache) XReadGroupCtx(ctx context.Context, args *cache.XReadGroupArgs) (res []cache.XStream, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	defer c.hooks.Process(ctx, cache.AdapterMemory, "xreadgroup", firstKey(args.Streams))(&err, nil)
+
+	keys, ids, err := c.streamIds(args.Streams)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.xread(ctx, args.Block, func() ([]cache.XStream, error) {
+		var res []cache.XStream
+		for i, key := range keys {
+			e, err := c.getStream(c.getKey(key), false)
+			if err != nil {
+				return nil, err
+			}
+			var g *streamGroup
+			if e != nil {
+				g = e.value.(*stream).groups[args.Group]
+			}
+			if g == nil {
+				return nil, fmt.Errorf("MemoryCache: NOGROUP No such key '%s' or consumer group '%s'", key, args.Group)
+			}
+
+			var msgs []cache.XMessage
+			for _, item := range e.value.(*stream).entries {
+				if args.Count > 0 && int64(len(msgs)) >= args.Count {
+					break
+				}
+				if ids[i] == streamNew {
+					// 读取未分配的新消息
+					if !g.lastId.less(item.id) {
+						continue
+					}
+					g.lastId = item.id
+					if !args.NoAck {
+						g.pending[item.id] = args.Consumer
+					}
+				} else if consumer, ok := g.pending[item.id]; !ok || consumer != args.Consumer || !ids[i].less(item.id) {
+					// 读取自己未确认的消息
+					continue
+				}
+				msgs = append(msgs, item.message())
+			}
+			if len(msgs) > 0 || ids[i] != streamNew {
+				res = append(res, cache.XStream{Stream: key, Messages: msgs})
+			}
+		}
+		return res, nil
+	})
+}
+
+// XAck 确认消费组的消息
+//   参数
+//     key:   流key值
+//     group: 消费组
+//     ids:   消息id
+//   返回
+//     成功时返回确认的个数，失败返回错误信息
+func (c *MemoryCache) XAck(key, group string, ids ...string) (n int64, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "xack", key)(&err, nil)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, err := c.getStream(c.getKey(key), false)
+	if err != nil || e == nil {
+		return 0, err
+	}
+
+	g := e.value.(*stream).groups[group]
+	if g == nil {
+		return 0, nil
+	}
+
+	for _, s := range ids {
+		id, err := parseStreamId(s)
+		if err != nil {
+			return n, err
+		}
+		if _, ok := g.pending[id]; ok {
+			delete(g.pending, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// XAckCtx 同XAck，ctx用于控制超时和取消
+func (c *MemoryCache) XAckCtx(ctx context.Context, key, group string, ids ...string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.XAck(key, group, ids...)
+}
+
+// Eval 执行lua脚本，内存版不支持
+func (c *MemoryCache) Eval(script string, keys []string, args ...interface{}) (res interface{}, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "eval", firstKey(keys))(&err, nil)
+
+	return nil, cache.NewError(cache.ErrUnsupported, "MemoryCache: Memory don't support Eval")
+}
+
+// EvalCtx 同Eval，ctx用于控制超时和取消
+func (c *MemoryCache) EvalCtx(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.Eval(script, keys, args...)
+}
+
+// EvalSha 按sha1执行lua脚本，内存版不支持
+func (c *MemoryCache) EvalSha(sha1 string, keys []string, args ...interface{}) (res interface{}, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "evalsha", firstKey(keys))(&err, nil)
+
+	return nil, cache.NewError(cache.ErrUnsupported, "MemoryCache: Memory don't support EvalSha")
+}
+
+// EvalShaCtx 同EvalSha，ctx用于控制超时和取消
+func (c *MemoryCache) EvalShaCtx(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.EvalSha(sha1, keys, args...)
+}
+
+// ScriptLoad 加载lua脚本，内存版不支持
+func (c *MemoryCache) ScriptLoad(script string) (res string, err error) {
+	defer c.hooks.Process(context.Background(), cache.AdapterMemory, "script", "")(&err, nil)
+
+	return "", cache.NewError(cache.ErrUnsupported, "MemoryCache: Memory don't support ScriptLoad")
+}
+
+// ScriptLoadCtx 同ScriptLoad，ctx用于控制超时和取消
+func (c *MemoryCache) ScriptLoadCtx(ctx context.Context, script string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	return c.ScriptLoad(script)
+}
+
+// streamIds 解析XRead参数中的key和id，$替换为流当前最后的id
+//   参数
+//     streams: 前一半为key值，后一半为对应的id
+//   返回
+//     key值、id、错误信息
+func (c *MemoryCache) streamIds(streams []string) ([]string, []streamId, error) {
+	if len(streams) == 0 || len(streams)%2 != 0 {
+		return nil, nil, errors.New("MemoryCache: streams must be key and id pairs")
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	n := len(streams) / 2
+	keys, ids := streams[:n], make([]streamId, n)
+	for i, s := range streams[n:] {
+		switch s {
+		case "$":
+			if e, _ := c.getStream(c.getKey(keys[i]), false); e != nil {
+				ids[i] = e.value.(*stream).lastId
+			}
+		case ">":
+			ids[i] = streamNew
+		default:
+			id, err := parseStreamId(s)
+			if err != nil {
+				return nil, nil, err
+			}
+			ids[i] = id
+		}
+	}
+
+	return keys, ids, nil
+}
+
+// xread 执行读取，没有消息时按blockInterval轮询直到超时
+//   参数
+//     ctx:   上下文
+//     block: 阻塞时间，小于等于0时不阻塞
+//     read:  读取函数，调用时已加锁
+//   返回
+//     读取到的消息，错误信息
+func (c *MemoryCache) xread(ctx context.Context, block time.Duration, read func() ([]cache.XStream, error)) ([]cache.XStream, error) {
+	var deadline time.Time
+	if block > 0 {
+		deadline = time.Now().Add(block)
+	}
+
+	for {
+		c.lock.Lock()
+		res, err := read()
+		c.lock.Unlock()
+		if err != nil || len(res) > 0 || block <= 0 {
+			return res, err
+		}
+
+		if err := waitBlock(ctx, deadline); err != nil {
+			if err == errBlockTimeout {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+// waitBlock 阻塞命令没有数据时等待blockInterval
+//   参数
+//     ctx:      上下文
+//     deadline: 超时时间，为零值时一直等待
+//   返回
+//     超时返回errBlockTimeout，ctx取消返回ctx的错误，否则返回nil
+func waitBlock(ctx context.Context, deadline time.Time) error {
+	if !deadline.IsZero() && !time.Now().Before(deadline) {
+		return errBlockTimeout
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(blockInterval):
+		return nil
+	}
+}
+
+// parseStreamId 解析流消息id，格式为毫秒时间-序号，序号可以省略
+func parseStreamId(s string) (streamId, error) {
+	var id streamId
+	var err error
+	parts := strings.SplitN(s, "-", 2)
+	id.ms, err = strconv.ParseUint(parts[0], 10, 64)
+	if err == nil && len(parts) == 2 {
+		id.seq, err = strconv.ParseUint(parts[1], 10, 64)
+	}
+	if err != nil {
+		return id, fmt.Errorf("MemoryCache: Invalid stream ID %q", s)
+	}
+
+	return id, nil
+}
+
+// Pipeline 执行pipeline命令，内存版不支持pipeline
+func (c *MemoryCache) Pipeline(isTx bool) cache.Pipeliner {
+	return cache.Pipeliner{}
+}
+
+// Scan 按模式遍历key，匹配规则同redis的SCAN
+// 第一次遍历时保存匹配的key，遍历期间修改的key可能重复返回或不返回
+//   参数
+//     pattern: 匹配模式，不含前缀，如user_*，为空时匹配所有key
+//     batch:   每批遍历的key个数，小于等于0时为10
+//   返回
+//     迭代器，返回的key已去掉前缀
+func (c *MemoryCache) Scan(pattern string, batch int64) cache.Iterator {
+	return c.ScanCtx(context.Background(), pattern, batch)
+}
+
+// ScanCtx 同Scan，ctx用于控制超时和取消
+func (c *MemoryCache) ScanCtx(ctx context.Context, pattern string, batch int64) cache.Iterator {
+	if batch <= 0 {
+		batch = 10
+	}
+
+	match := cache.ScanPattern(c.prefix, pattern)
+	var keys []string
+	return cache.NewIterator(c.prefix, func(cursor uint64) (page []string, next uint64, err error) {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		defer c.hooks.Process(ctx, cache.AdapterMemory, "scan", pattern)(&err, nil)
+
+		if cursor == 0 {
+			keys = c.matchKeys(match)
+		}
+		end := cursor + uint64(batch)
+		if end >= uint64(len(keys)) {
+			return keys[cursor:], 0, nil
+		}
+
+		return keys[cursor:end], end, nil
+	})
+}
+
+// matchKeys 返回所有匹配的key，不包含已过期的key
+//   参数
+//     pattern: 添加前缀后的匹配模式
+//   返回
+//     添加前缀的key值
+func (c *MemoryCache) matchKeys(pattern string) []string {
+	now := time.Now().UnixNano()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	keys := make([]string, 0)
+	for key, elem := range c.items {
+		e := elem.Value.(*entry)
+		if e.expireAt > 0 && e.expireAt <= now {
+			continue
+		}
+		if matchPattern(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// matchPattern 按redis的glob规则匹配，支持*、?、[abc]、[^a]、[a-z]和\转义
+//   参数
+//     pattern: 匹配模式
+//     s:       要匹配的字符串
+//   返回
+//     匹配返回true
+func matchPattern(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchPattern(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			p := pattern[1:]
+			not := len(p) > 0 && p[0] == '^'
+			if not {
+				p = p[1:]
+			}
+			match := false
+			for len(p) > 0 && p[0] != ']' {
+				if p[0] == '\\' && len(p) > 1 {
+					match = match || p[1] == s[0]
+					p = p[2:]
+				} else if len(p) > 2 && p[1] == '-' && p[2] != ']' {
+					lo, hi := p[0], p[2]
+					if lo > hi {
+						lo, hi = hi, lo
+					}
+					match = match || (s[0] >= lo && s[0] <= hi)
+					p = p[3:]
+				} else {
+					match = match || p[0] == s[0]
+					p = p[1:]
+				}
+			}
+			if match == not {
+				return false
+			}
+			// 没有]时匹配到模式结尾
+			if len(p) == 0 {
+				return len(s) == 1
+			}
+			pattern = p
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+
+	return len(s) == 0
+}
+
+// Batch 新建批量命令，key前缀、序列化和加密与Set、Get等方法一致
+// 所有命令在一次加锁中执行，事务模式和普通模式都不会被其它操作打断
+//   参数
+//     isTx: 是否事务模式，为true时有值转换出错则所有命令都不执行
+//   返回
+//     批量命令
+func (c *MemoryCache) Batch(isTx bool) *cache.Batch {
+	return cache.NewBatch(isTx, c.execBatch)
+}
+
+// execBatch 按顺序执行批量命令
+func (c *MemoryCache) execBatch(ctx context.Context, cmds []*cache.BatchCmd, isTx bool) (err error) {
+	defer c.hooks.Process(ctx, cache.AdapterMemory, "batch", cmds[0].Key)(&err, nil)
+
+	if err = ctx.Err(); err != nil {
+		for _, cmd := range cmds {
+			cmd.SetResult(nil, 0, false, err)
+		}
+		return err
+	}
+
+	// 加锁前先转换要设置的值，转换出错的命令不执行
+	vals := make([][]byte, len(cmds))
+	failed := make([]bool, len(cmds))
+	for i, cmd := range cmds {
+		var data []byte
+		var err error
+		switch cmd.Op {
+		case cache.BatchSet:
+			data, err = c.encodeValue(cmd.Val, cmd.Encode)
+		case cache.BatchHSet:
+			data, err = cache.InterToByte(cmd.Val, c.serializer)
+		}
+		if err != nil {
+			if isTx {
+				return err
+			}
+			cmd.SetResult(nil, 0, false, err)
+			failed[i] = true
+			continue
+		}
+		vals[i] = data
+	}
+
+	c.lock.Lock()
+	for i, cmd := range cmds {
+		if !failed[i] {
+			c.batchCmd(cmd, vals[i])
+		}
+	}
+	c.lock.Unlock()
+
+	for _, cmd := range cmds {
+		if err = cmd.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// batchCmd 执行一个命令，调用方需要加锁
+//   参数
+//     cmd:  批量命令中的一个命令
+//     data: Set、HSet转换后的值
+//   返回
+//
+func (c *MemoryCache) batchCmd(cmd *cache.BatchCmd, data []byte) {
+	key := c.getKey(cmd.Key)
+	switch cmd.Op {
+	case cache.BatchSet:
+		c.add(key, data, cmd.Expire)
+		cmd.SetResult(nil, 0, true, nil)
+	case cache.BatchGet:
+		data, exist, err := c.getBytes(key)
+		if err == nil && exist {
+			data, err = cache.Decode(data, c.encodeKey...)
+		}
+		cmd.SetResult(data, 0, exist, err)
+	case cache.BatchDel:
+		var n int64
+		if e := c.get(key); e != nil {
+			c.removeElement(c.items[key])
+			n = 1
+		}
+		cmd.SetResult(nil, n, true, nil)
+	case cache.BatchIncr:
+		n, err := c.incrBy(key, cmd.Delta)
+		cmd.SetResult(nil, n, err == nil, err)
+	case cache.BatchExpire:
+		ok := c.setExpireAt(key, time.Now().Add(time.Duration(cmd.Expire)*time.Second).UnixNano())
+		var n int64
+		if ok {
+			n = 1
+		}
+		cmd.SetResult(nil, n, ok, nil)
+	case cache.BatchHSet:
+		e, err := c.getHash(key, true)
+		if err != nil {
+			cmd.SetResult(nil, 0, false, err)
+			return
+		}
+		h := e.value.(map[string][]byte)
+		var n int64
+		if _, ok := h[cmd.Fields[0]]; !ok {
+			n = 1
+		}
+		h[cmd.Fields[0]] = data
+		if cmd.Expire > 0 {
+			c.setExpire(e, cmd.Expire)
+		}
+		c.resize(e)
+		cmd.SetResult(nil, n, true, nil)
+	case cache.BatchHGet:
+		e, err := c.getHash(key, false)
+		if err != nil || e == nil {
+			cmd.SetResult(nil, 0, false, err)
+			return
+		}
+		data, ok := e.value.(map[string][]byte)[cmd.Fields[0]]
+		cmd.SetResult(data, 0, ok, nil)
+	case cache.BatchHDel:
+		e, err := c.getHash(key, false)
+		if err != nil || e == nil {
+			cmd.SetResult(nil, 0, true, err)
+			return
+		}
+		h := e.value.(map[string][]byte)
+		var n int64
+		for _, field := range cmd.Fields {
+			if _, ok := h[field]; ok {
+				delete(h, field)
+				n++
+			}
+		}
+		// 没有数据时删除key
+		if len(h) == 0 {
+			c.removeElement(c.items[key])
+		} else {
+			c.resize(e)
+		}
+		cmd.SetResult(nil, n, true, nil)
+	case cache.BatchHIncr:
+		n, err := c.hIncrBy(key, cmd.Fields[0], cmd.Delta)
+		cmd.SetResult(nil, n, err == nil, err)
+	default:
+		cmd.SetResult(nil, 0, false, cache.NewError(cache.ErrUnsupported, fmt.Sprintf("MemoryCache: Batch don't support %s", cmd.Op)))
+	}
+}
+
+// AcquireLock 获取分布式锁，只在本进程内有效，由cache/lock包使用
+//   参数
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 锁的持有者标识
+//     ttl:   锁的过期时间
+//   返回
+//     获取到返回true，锁已存在返回false
+func (c *MemoryCache) AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (ok bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	defer c.hooks.Process(ctx, cache.AdapterMemory, "setnx", key)(&err, nil)
+
+	key = c.getKey(key)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.get(key) != nil {
+		return false, nil
+	}
+
+	e := c.add(key, []byte(token), 0)
+	e.expireAt = time.Now().Add(ttl).UnixNano()
+
+	return true, nil
+}
+
+// ReleaseLock token一致时删除锁
+//   参数
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 锁的持有者标识
+//   返回
+//     删除成功返回true
+func (c *MemoryCache) ReleaseLock(ctx context.Context, key, token string) (ok bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	defer c.hooks.Process(ctx, cache.AdapterMemory, "release", key)(&err, nil)
+
+	key = c.getKey(key)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	data, exist, _ := c.getBytes(key)
+	if !exist || string(data) != token {
+		return false, nil
+	}
+	c.removeElement(c.items[key])
+
+	return true, nil
+}
+
+// RefreshLock token一致时更新锁的过期时间
+//   参数
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 锁的持有者标识
+//     ttl:   新的过期时间
+//   返回
+//     更新成功返回true
+func (c *MemoryCache) RefreshLock(ctx context.Context, key, token string, ttl time.Duration) (ok bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	defer c.hooks.Process(ctx, cache.AdapterMemory, "refresh", key)(&err, nil)
+
+	key = c.getKey(key)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	data, exist, _ := c.getBytes(key)
+	if !exist || string(data) != token {
+		return false, nil
+	}
+
+	return c.setExpireAt(key, time.Now().Add(ttl).UnixNano()), nil
+}
@@ -0,0 +1,1104 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lixy529/gotools/cache"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	var err error
+	adapter := &MemoryCache{}
+	err = adapter.Init(`{"prefix":"le_","encodeKey":"abcdefghij123456"}`)
+	if err != nil {
+		t.Errorf("Memory Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+
+	////////////////////////string测试////////////////////////////
+	k1 := "k1"
+	v1 := "HelloWorld"
+	err = adapter.Set(k1, v1, 10)
+	if err != nil {
+		t.Errorf("Memory Set failed. err: %s.", err.Error())
+		return
+	}
+
+	var v11 string
+	err, exist := adapter.Get(k1, &v11)
+	if err != nil {
+		t.Errorf("Memory Get failed. err: %s.", err.Error())
+		return
+	} else if !exist {
+		t.Errorf("Memory Get failed. %s is not exist.", k1)
+		return
+	} else if v11 != v1 {
+		t.Errorf("Memory Get failed. Got %s, expected %s.", v11, v1)
+		return
+	}
+
+	err = adapter.Del(k1)
+	if err != nil {
+		t.Errorf("Memory Del failed. err: %s.", err.Error())
+		return
+	}
+
+	isExist, err := adapter.IsExist(k1)
+	if err != nil {
+		t.Errorf("Memory IsExist failed. err: %s.", err.Error())
+		return
+	} else if isExist {
+		t.Error("Memory IsExist failed. Got true, expected false.")
+		return
+	}
+
+	////////////////////////加密测试////////////////////////////
+	k2 := "k2"
+	v2 := 100
+	err = adapter.Set(k2, v2, 10, true)
+	if err != nil {
+		t.Errorf("Memory Set failed. err: %s.", err.Error())
+		return
+	}
+
+	var v22 int
+	err, _ = adapter.Get(k2, &v22)
+	if err != nil {
+		t.Errorf("Memory Get failed. err: %s.", err.Error())
+		return
+	} else if v22 != v2 {
+		t.Errorf("Memory Get failed. Got %d, expected %d.", v22, v2)
+		return
+	}
+
+	////////////////////////Incr、Decr测试////////////////////////////
+	n, err := adapter.Incr("k3", 10)
+	if err != nil || n != 10 {
+		t.Errorf("Memory Incr failed. Got %d, expected %d.", n, 10)
+		return
+	}
+	n, err = adapter.Decr("k3")
+	if err != nil || n != 9 {
+		t.Errorf("Memory Decr failed. Got %d, expected %d.", n, 9)
+		return
+	}
+
+	////////////////////////MSet、MGet测试////////////////////////////
+	err = adapter.MSet(map[string]interface{}{"m1": "aaa", "m2": 200}, 10)
+	if err != nil {
+		t.Errorf("Memory MSet failed. err: %s.", err.Error())
+		return
+	}
+	mList, err := adapter.MGet("m1", "m2", "m3")
+	if err != nil {
+		t.Errorf("Memory MGet failed. err: %s.", err.Error())
+		return
+	} else if mList["m1"] != "aaa" || mList["m2"] != "200" || mList["m3"] != nil {
+		t.Errorf("Memory MGet failed. Got %v.", mList)
+		return
+	}
+}
+
+func TestMemoryExpire(t *testing.T) {
+	adapter := &MemoryCache{}
+	err := adapter.Init(`{"interval":"1"}`)
+	if err != nil {
+		t.Errorf("Memory Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+
+	adapter.Set("k1", "v1", 1)
+	adapter.Set("k2", "v2", 0)
+	time.Sleep(2100 * time.Millisecond)
+
+	if adapter.ll.Len() != 1 {
+		t.Errorf("Memory expirer failed. Got %d items, expected %d.", adapter.ll.Len(), 1)
+		return
+	}
+
+	isExist, _ := adapter.IsExist("k2")
+	if !isExist {
+		t.Error("Memory IsExist failed. Got false, expected true.")
+		return
+	}
+
+	// IncrEx更新过期时间
+	n, err := adapter.IncrEx("n1", 2, 0)
+	if err != nil || n != 2 {
+		t.Errorf("Memory IncrEx failed. Got %d, expected 2, err: %v.", n, err)
+	}
+	n, err = adapter.IncrEx("n1", -3, 1)
+	if err != nil || n != -1 {
+		t.Errorf("Memory IncrEx failed. Got %d, expected -1, err: %v.", n, err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if isExist, _ = adapter.IsExist("n1"); isExist {
+		t.Error("Memory IncrEx failed. n1 is not expired.")
+	}
+}
+
+func TestMemoryLRU(t *testing.T) {
+	adapter := &MemoryCache{}
+	err := adapter.Init(`{"maxEntries":"3"}`)
+	if err != nil {
+		t.Errorf("Memory Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+
+	for i := 1; i <= 3; i++ {
+		adapter.Set(fmt.Sprintf("k%d", i), i, 0)
+	}
+
+	// 访问k1后，最久未访问的是k2
+	var v int
+	adapter.Get("k1", &v)
+	adapter.Set("k4", 4, 0)
+
+	if isExist, _ := adapter.IsExist("k2"); isExist {
+		t.Error("Memory LRU failed. k2 is exist.")
+		return
+	}
+	if isExist, _ := adapter.IsExist("k1"); !isExist {
+		t.Error("Memory LRU failed. k1 is not exist.")
+		return
+	}
+
+	// 内存限制
+	adapter = &MemoryCache{}
+	adapter.Init(`{"maxMemory":"20"}`)
+	defer adapter.Close()
+	adapter.Set("a", "123456789", 0)
+	adapter.Set("b", "123456789", 0)
+	adapter.Set("c", "123456789", 0)
+	if adapter.used > 20 {
+		t.Errorf("Memory LRU failed. Used %d, expected <= %d.", adapter.used, 20)
+		return
+	}
+	if isExist, _ := adapter.IsExist("a"); isExist {
+		t.Error("Memory LRU failed. a is exist.")
+		return
+	}
+}
+
+func TestMemoryHash(t *testing.T) {
+	adapter := &MemoryCache{}
+	adapter.Init("")
+	defer adapter.Close()
+
+	key := "addr"
+	adapter.HSet(key, "baidu", "www.baidu.com", 60)
+	adapter.HMSet(key, map[string]interface{}{"le": "www.le.com", "num": 10}, 60)
+
+	var v string
+	err, exist := adapter.HGet(key, "le", &v)
+	if err != nil || !exist || v != "www.le.com" {
+		t.Errorf("Memory HGet failed. Got %s, expected %s.", v, "www.le.com")
+		return
+	}
+
+	all, err := adapter.HGetAll(key)
+	if err != nil || len(all) != 3 {
+		t.Errorf("Memory HGetAll failed. Got %v.", all)
+		return
+	}
+
+	m, err := adapter.HMGet(key, "baidu", "none")
+	if err != nil || m["baidu"] != "www.baidu.com" || m["none"] != nil {
+		t.Errorf("Memory HMGet failed. Got %v.", m)
+		return
+	}
+
+	n, err := adapter.HIncr(key, "num", 5)
+	if err != nil || n != 15 {
+		t.Errorf("Memory HIncr failed. Got %d, expected %d.", n, 15)
+		return
+	}
+
+	adapter.HDel(key, "baidu", "le", "num")
+	if isExist, _ := adapter.IsExist(key); isExist {
+		t.Error("Memory HDel failed. key is exist.")
+		return
+	}
+
+	// 类型不对
+	adapter.Set("str", "abc", 0)
+	_, err = adapter.HSet("str", "f", "v", 0)
+	if err == nil {
+		t.Error("Memory HSet failed. expected WRONGTYPE error.")
+		return
+	}
+}
+
+func TestMemoryZSet(t *testing.T) {
+	adapter := &MemoryCache{}
+	adapter.Init("")
+	defer adapter.Close()
+
+	key := "rank"
+	n, err := adapter.ZSet(key, 60, 3, "c", 1.0, "a", 2, "b", 4, "d")
+	if err != nil || n != 4 {
+		t.Errorf("Memory ZSet failed. Got %d, expected %d.", n, 4)
+		return
+	}
+
+	res, err := adapter.ZGet(key, 0, -1, false, false)
+	if err != nil || fmt.Sprint(res) != "[a b c d]" {
+		t.Errorf("Memory ZGet failed. Got %v.", res)
+		return
+	}
+
+	res, err = adapter.ZGet(key, 0, 0, true, true)
+	if err != nil || fmt.Sprint(res) != "[4.000000 d]" {
+		t.Errorf("Memory ZGet failed. Got %v.", res)
+		return
+	}
+
+	n, _ = adapter.ZRemRangeByScore(key, "(1", "2")
+	if n != 1 {
+		t.Errorf("Memory ZRemRangeByScore failed. Got %d, expected %d.", n, 1)
+		return
+	}
+
+	n, _ = adapter.ZRemRangeByRank(key, -1, -1)
+	if n != 1 {
+		t.Errorf("Memory ZRemRangeByRank failed. Got %d, expected %d.", n, 1)
+		return
+	}
+
+	n, _ = adapter.ZCard(key)
+	if n != 2 {
+		t.Errorf("Memory ZCard failed. Got %d, expected %d.", n, 2)
+		return
+	}
+
+	adapter.ZSet("lex", 0, 0, "a", 0, "b", 0, "c")
+	n, _ = adapter.ZRemRangeByLex("lex", "[a", "(c")
+	if n != 2 {
+		t.Errorf("Memory ZRemRangeByLex failed. Got %d, expected %d.", n, 2)
+		return
+	}
+}
+
+func TestMemoryBit(t *testing.T) {
+	adapter := &MemoryCache{}
+	adapter.Init("")
+	defer adapter.Close()
+
+	key := "bit"
+	adapter.SetBit(key, 1, 1, 0)
+	adapter.SetBit(key, 9, 1, 0)
+	old, _ := adapter.SetBit(key, 9, 1, 0)
+	if old != 1 {
+		t.Errorf("Memory SetBit failed. Got %d, expected %d.", old, 1)
+		return
+	}
+
+	b, _ := adapter.GetBit(key, 9)
+	if b != 1 {
+		t.Errorf("Memory GetBit failed. Got %d, expected %d.", b, 1)
+		return
+	}
+
+	n, _ := adapter.BitCount(key, nil)
+	if n != 2 {
+		t.Errorf("Memory BitCount failed. Got %d, expected %d.", n, 2)
+		return
+	}
+
+	n, _ = adapter.BitCount(key, &cache.BitCount{Start: 1, End: 1})
+	if n != 1 {
+		t.Errorf("Memory BitCount failed. Got %d, expected %d.", n, 1)
+		return
+	}
+
+	////////////////////////HyperLogLog测试////////////////////////////
+	adapter.PFAdd("pf", 0, "a", "b", "c")
+	r, _ := adapter.PFAdd("pf", 0, "a")
+	if r != 0 {
+		t.Errorf("Memory PFAdd failed. Got %d, expected %d.", r, 0)
+		return
+	}
+	n, _ = adapter.PFCount("pf")
+	if n != 3 {
+		t.Errorf("Memory PFCount failed. Got %d, expected %d.", n, 3)
+		return
+	}
+}
+
+func TestNewCache(t *testing.T) {
+	c1, err := cache.NewCache(cache.AdapterMemory, `{"prefix":"c1_"}`)
+	if err != nil {
+		t.Errorf("NewCache failed. err: %s.", err.Error())
+		return
+	}
+	c2, err := cache.NewCache(cache.AdapterMemory, `{"prefix":"c2_"}`)
+	if err != nil {
+		t.Errorf("NewCache failed. err: %s.", err.Error())
+		return
+	}
+	defer c1.(*MemoryCache).Close()
+	defer c2.(*MemoryCache).Close()
+
+	c1.Set("k", "v1", 0)
+	if isExist, _ := c2.IsExist("k"); isExist {
+		t.Error("NewCache failed. instances share data.")
+		return
+	}
+
+	_, err = cache.NewCache("none", "")
+	if err == nil {
+		t.Error("NewCache failed. expected unknown adapter error.")
+		return
+	}
+
+	cache.SetCache("local", c1)
+	c, err := cache.GetCache("local")
+	if err != nil || c != c1 {
+		t.Error("GetCache failed. Got another instance.")
+		return
+	}
+}
+
+func TestMemoryCtx(t *testing.T) {
+	var adapter cache.ContextCache = &MemoryCache{}
+	err := adapter.Init(`{"prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memory Init failed. err: %s.", err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	k1 := "ctx_k1"
+	v1 := "HelloWorld"
+	err = adapter.SetCtx(ctx, k1, v1, 10)
+	if err != nil {
+		t.Errorf("Memory SetCtx failed. err: %s.", err.Error())
+		return
+	}
+
+	var v11 string
+	err, _ = adapter.GetCtx(ctx, k1, &v11)
+	if err != nil {
+		t.Errorf("Memory GetCtx failed. err: %s.", err.Error())
+		return
+	} else if v11 != v1 {
+		t.Errorf("Memory GetCtx failed. Got %s, expected %s.", v11, v1)
+		return
+	}
+
+	// 已取消的ctx
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	cancel2()
+	err, _ = adapter.GetCtx(ctx2, k1, &v11)
+	if err == nil {
+		t.Errorf("Memory GetCtx failed. expected context error.")
+		return
+	}
+
+	// 已取消的ctx不会取出元素
+	adapter.RPush("ctx_list", 0, "a")
+	if _, err = adapter.BRPopCtx(ctx2, 1, &v11, "ctx_list"); err != context.Canceled {
+		t.Errorf("Memory BRPopCtx failed. Got %v, expected context error.", err)
+		return
+	}
+	if n, _ := adapter.LRange("ctx_list", 0, -1); len(n) != 1 {
+		t.Errorf("Memory BRPopCtx failed. list length %d, expected 1.", len(n))
+		return
+	}
+	adapter.Del("ctx_list")
+}
+
+func TestMemorySerializer(t *testing.T) {
+	adapter := &MemoryCache{}
+	err := adapter.Init(`{"serializer":"gob"}`)
+	if err != nil {
+		t.Errorf("Memory Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+
+	type user struct {
+		Uid  int32
+		Name string
+	}
+	adapter.Set("u1", user{Uid: 100, Name: "Diego"}, 0)
+	u := user{}
+	err, exist := adapter.Get("u1", &u)
+	if err != nil || !exist || u.Uid != 100 || u.Name != "Diego" {
+		t.Errorf("Memory Get failed. Got %d-%s, expected 100-Diego.", u.Uid, u.Name)
+	}
+
+	// 数字不序列化，Incr可用
+	adapter.Set("n1", 1, 0)
+	n, err := adapter.Incr("n1")
+	if err != nil || n != 2 {
+		t.Errorf("Memory Incr failed. Got %d, expected 2.", n)
+	}
+
+	// json写入的数据gob配置也能读
+	other := &MemoryCache{}
+	other.Init("")
+	defer other.Close()
+	other.Set("u2", user{Uid: 200, Name: "Lily"}, 0)
+	var raw string
+	other.Get("u2", &raw)
+	adapter.Set("u2", raw, 0)
+	err, _ = adapter.Get("u2", &u)
+	if err != nil || u.Uid != 200 {
+		t.Errorf("Memory Get failed. Got %d, expected 200.", u.Uid)
+	}
+
+	err = adapter.Init(`{"serializer":"xml"}`)
+	if err == nil {
+		t.Error("Memory Init failed. xml serializer should be unknown.")
+	}
+}
+
+func TestMemoryList(t *testing.T) {
+	adapter := &MemoryCache{}
+	adapter.Init("")
+	defer adapter.Close()
+	adapter.MDel("list_l1", "list_l2")
+
+	n, err := adapter.RPush("list_l1", 60, "v1", "v2")
+	if err != nil || n != 2 {
+		t.Errorf("Memory RPush failed. Got %d, expected 2.", n)
+	}
+	n, err = adapter.LPush("list_l1", 60, strings.Repeat("a", 100), "v0")
+	if err != nil || n != 4 {
+		t.Errorf("Memory LPush failed. Got %d, expected 4.", n)
+	}
+
+	vals, err := adapter.LRange("list_l1", 0, -1)
+	if err != nil || len(vals) != 4 || vals[0] != "v0" || vals[1] != strings.Repeat("a", 100) || vals[3] != "v2" {
+		t.Errorf("Memory LRange failed. Got %v.", vals)
+	}
+
+	err = adapter.LTrim("list_l1", 0, 2)
+	if err != nil {
+		t.Errorf("Memory LTrim failed. err: %s.", err.Error())
+	}
+
+	var s string
+	err, exist := adapter.LPop("list_l1", &s)
+	if err != nil || !exist || s != "v0" {
+		t.Errorf("Memory LPop failed. Got %s, expected v0.", s)
+	}
+
+	key, err := adapter.BRPop(1, &s, "list_l2", "list_l1")
+	if err != nil || key != "list_l1" || s != "v1" {
+		t.Errorf("Memory BRPop failed. Got %s-%s, expected list_l1-v1.", key, s)
+	}
+
+	key, err = adapter.BRPop(1, &s, "list_l2")
+	if err != nil || key != "" {
+		t.Errorf("Memory BRPop failed. Got %s, expected empty.", key)
+	}
+
+	adapter.MDel("list_l1")
+	err, exist = adapter.LPop("list_l1", &s)
+	if err != nil || exist {
+		t.Errorf("Memory LPop failed. list_l1 is exist.")
+	}
+}
+
+func TestMemorySAdd(t *testing.T) {
+	adapter := &MemoryCache{}
+	adapter.Init("")
+	defer adapter.Close()
+	adapter.MDel("set_s1", "set_s2")
+
+	n, err := adapter.SAdd("set_s1", 60, "a", "b", "c", "a")
+	if err != nil || n != 3 {
+		t.Errorf("Memory SAdd failed. Got %d, expected 3.", n)
+	}
+	n, err = adapter.SAdd("set_s2", 60, "b", "c", "d")
+	if err != nil || n != 3 {
+		t.Errorf("Memory SAdd failed. Got %d, expected 3.", n)
+	}
+
+	ok, err := adapter.SIsMember("set_s1", "a")
+	if err != nil || !ok {
+		t.Errorf("Memory SIsMember failed. a is not member.")
+	}
+
+	n, err = adapter.SRem("set_s1", "a", "x")
+	if err != nil || n != 1 {
+		t.Errorf("Memory SRem failed. Got %d, expected 1.", n)
+	}
+
+	ok, err = adapter.SIsMember("set_s1", "a")
+	if err != nil || ok {
+		t.Errorf("Memory SIsMember failed. a is member.")
+	}
+
+	members, err := adapter.SMembers("set_s1")
+	sort.Strings(members)
+	if err != nil || strings.Join(members, ",") != "b,c" {
+		t.Errorf("Memory SMembers failed. Got %v, expected [b c].", members)
+	}
+
+	members, err = adapter.SInter("set_s1", "set_s2")
+	sort.Strings(members)
+	if err != nil || strings.Join(members, ",") != "b,c" {
+		t.Errorf("Memory SInter failed. Got %v, expected [b c].", members)
+	}
+
+	members, err = adapter.SUnion("set_s1", "set_s2")
+	sort.Strings(members)
+	if err != nil || strings.Join(members, ",") != "b,c,d" {
+		t.Errorf("Memory SUnion failed. Got %v, expected [b c d].", members)
+	}
+}
+
+func TestMemoryStream(t *testing.T) {
+	adapter := &MemoryCache{}
+	adapter.Init("")
+	defer adapter.Close()
+	adapter.MDel("stream_x1")
+
+	id1, err := adapter.XAdd("stream_x1", 100, 60, map[string]interface{}{"name": "Diego", "age": 20})
+	if err != nil || id1 == "" {
+		t.Errorf("Memory XAdd failed. err: %v.", err)
+		return
+	}
+	id2, err := adapter.XAdd("stream_x1", 100, 60, map[string]interface{}{"name": "Lily"})
+	if err != nil || id2 == "" {
+		t.Errorf("Memory XAdd failed. err: %v.", err)
+		return
+	}
+
+	res, err := adapter.XRead(&cache.XReadArgs{Streams: []string{"stream_x1", "0"}})
+	if err != nil || len(res) != 1 || res[0].Stream != "stream_x1" || len(res[0].Messages) != 2 {
+		t.Errorf("Memory XRead failed. Got %v, err: %v.", res, err)
+		return
+	}
+	msg := res[0].Messages[0]
+	if msg.ID != id1 || msg.Values["name"] != "Diego" || msg.Values["age"] != "20" {
+		t.Errorf("Memory XRead failed. Got %v.", msg)
+	}
+
+	res, err = adapter.XRead(&cache.XReadArgs{Streams: []string{"stream_x1", id2}})
+	if err != nil || len(res) != 0 {
+		t.Errorf("Memory XRead failed. Got %v, expected empty.", res)
+	}
+
+	err = adapter.XGroupCreate("stream_x1", "g1", "0")
+	if err != nil {
+		t.Errorf("Memory XGroupCreate failed. err: %s.", err.Error())
+	}
+	err = adapter.XGroupCreate("stream_x1", "g1", "0")
+	if err != nil {
+		t.Errorf("Memory XGroupCreate failed. err: %s.", err.Error())
+	}
+
+	res, err = adapter.XReadGroup(&cache.XReadGroupArgs{Group: "g1", Consumer: "c1", Streams: []string{"stream_x1", ">"}, Count: 1})
+	if err != nil || len(res) != 1 || len(res[0].Messages) != 1 || res[0].Messages[0].ID != id1 {
+		t.Errorf("Memory XReadGroup failed. Got %v, err: %v.", res, err)
+	}
+
+	n, err := adapter.XAck("stream_x1", "g1", id1)
+	if err != nil || n != 1 {
+		t.Errorf("Memory XAck failed. Got %d, expected 1.", n)
+	}
+
+	res, err = adapter.XReadGroup(&cache.XReadGroupArgs{Group: "g1", Consumer: "c1", Streams: []string{"stream_x1", ">"}})
+	if err != nil || len(res) != 1 || len(res[0].Messages) != 1 || res[0].Messages[0].ID != id2 {
+		t.Errorf("Memory XReadGroup failed. Got %v, err: %v.", res, err)
+	}
+}
+
+func TestMemoryCas(t *testing.T) {
+	adapter := &MemoryCache{}
+	err := adapter.Init(`{"prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memory Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+	adapter.Del("cas_k1")
+
+	// Add、Replace
+	ok, err := adapter.Replace("cas_k1", "v0", 60)
+	if err != nil || ok {
+		t.Errorf("Memory Replace failed. cas_k1 is not exist, err: %v.", err)
+	}
+	ok, err = adapter.Add("cas_k1", "v1", 60)
+	if err != nil || !ok {
+		t.Errorf("Memory Add failed. err: %v.", err)
+	}
+	ok, err = adapter.Add("cas_k1", "v2", 60)
+	if err != nil || ok {
+		t.Errorf("Memory Add failed. cas_k1 is exist, err: %v.", err)
+	}
+	ok, err = adapter.Replace("cas_k1", map[string]int{"n": 1}, 60)
+	if err != nil || !ok {
+		t.Errorf("Memory Replace failed. err: %v.", err)
+	}
+
+	// GetWithVersion、CompareAndSwap
+	doc := map[string]int{}
+	ver, err := adapter.GetWithVersion("cas_k1", &doc)
+	if err != nil || ver == nil || doc["n"] != 1 {
+		t.Errorf("Memory GetWithVersion failed. Got %v, err: %v.", doc, err)
+		return
+	}
+	doc["n"]++
+	ok, err = adapter.CompareAndSwap(ver, doc, 60)
+	if err != nil || !ok {
+		t.Errorf("Memory CompareAndSwap failed. err: %v.", err)
+	}
+	ok, err = adapter.CompareAndSwap(ver, doc, 60)
+	if err != nil || ok {
+		t.Errorf("Memory CompareAndSwap failed. version is changed, err: %v.", err)
+	}
+
+	ver, err = adapter.GetWithVersion("cas_k1", &doc)
+	if err != nil || ver == nil || doc["n"] != 2 {
+		t.Errorf("Memory GetWithVersion failed. Got %v, err: %v.", doc, err)
+		return
+	}
+	adapter.Set("cas_k1", "v3", 60)
+	ok, err = adapter.CompareAndSwap(ver, "v4", 60)
+	if err != nil || ok {
+		t.Errorf("Memory CompareAndSwap failed. cas_k1 is modified, err: %v.", err)
+	}
+
+	// expire为0时保留原来的过期时间
+	var s string
+	ver, _ = adapter.GetWithVersion("cas_k1", &s)
+	ok, err = adapter.CompareAndSwap(ver, "v5", 0)
+	if err != nil || !ok {
+		t.Errorf("Memory CompareAndSwap failed. err: %v.", err)
+	}
+	if ttl, _ := adapter.TTL("cas_k1"); ttl <= 0 || ttl > 60*time.Second {
+		t.Errorf("Memory CompareAndSwap failed. Got ttl %v, expected at most 60s.", ttl)
+	}
+	adapter.Persist("cas_k1")
+	ver, _ = adapter.GetWithVersion("cas_k1", &s)
+	adapter.CompareAndSwap(ver, "v6", 0)
+	if ttl, _ := adapter.TTL("cas_k1"); ttl != cache.TTL_PERSIST {
+		t.Errorf("Memory CompareAndSwap failed. Got ttl %v, expected %v.", ttl, cache.TTL_PERSIST)
+	}
+
+	adapter.Del("cas_k1")
+	ver, err = adapter.GetWithVersion("cas_k1", &doc)
+	if err != nil || ver != nil {
+		t.Errorf("Memory GetWithVersion failed. cas_k1 is not exist, err: %v.", err)
+	}
+}
+
+func TestMemoryTTL(t *testing.T) {
+	adapter := &MemoryCache{}
+	err := adapter.Init(`{"prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memory Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+	adapter.Del("ttl_k1")
+
+	ttl, err := adapter.TTL("ttl_k1")
+	if err != nil || ttl != cache.TTL_NOT_EXIST {
+		t.Errorf("Memory TTL failed. Got %v, expected %v, err: %v.", ttl, cache.TTL_NOT_EXIST, err)
+	}
+	adapter.Set("ttl_k1", "v1", 0)
+	ttl, err = adapter.TTL("ttl_k1")
+	if err != nil || ttl != cache.TTL_PERSIST {
+		t.Errorf("Memory TTL failed. Got %v, expected %v, err: %v.", ttl, cache.TTL_PERSIST, err)
+	}
+
+	// Expire、ExpireAt
+	ok, err := adapter.Expire("ttl_k1", 10*time.Second)
+	if err != nil || !ok {
+		t.Errorf("Memory Expire failed. err: %v.", err)
+	}
+	ttl, err = adapter.TTL("ttl_k1")
+	if err != nil || ttl <= 9*time.Second || ttl > 10*time.Second {
+		t.Errorf("Memory TTL failed. Got %v, expected 10s, err: %v.", ttl, err)
+	}
+	ok, err = adapter.ExpireAt("ttl_k1", time.Now().Add(time.Minute))
+	if err != nil || !ok {
+		t.Errorf("Memory ExpireAt failed. err: %v.", err)
+	}
+	ttl, err = adapter.TTL("ttl_k1")
+	if err != nil || ttl <= 58*time.Second || ttl > time.Minute {
+		t.Errorf("Memory TTL failed. Got %v, expected 1m, err: %v.", ttl, err)
+	}
+	ok, err = adapter.Expire("ttl_k2", time.Second)
+	if err != nil || ok {
+		t.Errorf("Memory Expire failed. ttl_k2 is not exist, err: %v.", err)
+	}
+
+	// Persist、Touch
+	ok, err = adapter.Persist("ttl_k1")
+	if err != nil || !ok {
+		t.Errorf("Memory Persist failed. err: %v.", err)
+	}
+	if ttl, _ = adapter.TTL("ttl_k1"); ttl != cache.TTL_PERSIST {
+		t.Errorf("Memory Persist failed. TTL got %v.", ttl)
+	}
+	ok, err = adapter.Touch("ttl_k1", 100)
+	if err != nil || !ok {
+		t.Errorf("Memory Touch failed. err: %v.", err)
+	}
+	if ttl, _ = adapter.TTL("ttl_k1"); ttl <= 99*time.Second || ttl > 100*time.Second {
+		t.Errorf("Memory Touch failed. TTL got %v.", ttl)
+	}
+	ok, err = adapter.Touch("ttl_k1", 0)
+	if err != nil || !ok {
+		t.Errorf("Memory Touch failed. err: %v.", err)
+	}
+	if ttl, _ = adapter.TTL("ttl_k1"); ttl != cache.TTL_PERSIST {
+		t.Errorf("Memory Touch failed. TTL got %v.", ttl)
+	}
+
+	// GetEx
+	v := ""
+	err, exist := adapter.GetEx("ttl_k1", &v, 200)
+	if err != nil || !exist || v != "v1" {
+		t.Errorf("Memory GetEx failed. Got %s, err: %v.", v, err)
+	}
+	if ttl, _ = adapter.TTL("ttl_k1"); ttl <= 199*time.Second || ttl > 200*time.Second {
+		t.Errorf("Memory GetEx failed. TTL got %v.", ttl)
+	}
+	err, exist = adapter.GetEx("ttl_k2", &v, 200)
+	if err != nil || exist {
+		t.Errorf("Memory GetEx failed. ttl_k2 is not exist, err: %v.", err)
+	}
+
+	// 过期时间小于等于0时删除
+	ok, err = adapter.Expire("ttl_k1", 0)
+	if err != nil || !ok {
+		t.Errorf("Memory Expire failed. err: %v.", err)
+	}
+	if exist, _ = adapter.IsExist("ttl_k1"); exist {
+		t.Errorf("Memory Expire failed. ttl_k1 is not deleted.")
+	}
+}
+
+func TestMemoryHook(t *testing.T) {
+	adapter := &MemoryCache{}
+	err := adapter.Init(`{"prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memory Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+	collector := cache.NewCollector("")
+	adapter.AddHook(collector)
+	adapter.Del("hook_k1")
+	adapter.Del("hook_k2")
+
+	v := ""
+	adapter.Set("hook_k1", "v1", 100)
+	adapter.Get("hook_k1", &v)
+	adapter.Get("hook_k2", &v)
+	adapter.Get("hook_k1", &v)
+	if r := collector.HitRatio(cache.AdapterMemory); r < 0.66 || r > 0.67 {
+		t.Errorf("Memory HitRatio failed. Got %f, expected 0.67.", r)
+	}
+
+	// 其它类型的命令也调用钩子
+	adapter.MSet(map[string]interface{}{"hook_m1": 1, "hook_m2": 2}, 100)
+	adapter.ZSet("hook_z1", 100, 1, "a")
+	adapter.LPush("hook_l1", 100, "a")
+	adapter.LPop("hook_l1", &v)
+	adapter.LPop("hook_l1", &v)
+	adapter.SAdd("hook_s1", 100, "a")
+	adapter.SMembers("hook_s1")
+	adapter.PFAdd("hook_p1", 100, "a")
+	adapter.SetBit("hook_b1", 1, 1, 100)
+	adapter.XAdd("hook_x1", 0, 100, map[string]interface{}{"f": "v"})
+	adapter.Eval("return 1", []string{"hook_k1"})
+	adapter.MDel("hook_m1", "hook_m2", "hook_z1", "hook_s1", "hook_p1", "hook_b1", "hook_x1")
+
+	body := string(collector.Bytes())
+	for _, line := range []string{
+		`cache_commands_total{adapter="memory",cmd="set"} 1`,
+		`cache_hits_total{adapter="memory",cmd="get"} 2`,
+		`cache_misses_total{adapter="memory",cmd="get"} 1`,
+		`cache_errors_total{adapter="memory",cmd="get"} 0`,
+		`cache_commands_total{adapter="memory",cmd="mset"} 1`,
+		`cache_commands_total{adapter="memory",cmd="zadd"} 1`,
+		`cache_commands_total{adapter="memory",cmd="lpush"} 1`,
+		`cache_hits_total{adapter="memory",cmd="lpop"} 1`,
+		`cache_misses_total{adapter="memory",cmd="lpop"} 1`,
+		`cache_hits_total{adapter="memory",cmd="smembers"} 1`,
+		`cache_commands_total{adapter="memory",cmd="pfadd"} 1`,
+		`cache_commands_total{adapter="memory",cmd="setbit"} 1`,
+		`cache_commands_total{adapter="memory",cmd="xadd"} 1`,
+		`cache_errors_total{adapter="memory",cmd="eval"} 1`,
+		`cache_commands_total{adapter="memory",cmd="del"} 3`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Memory Hook failed. %s not found in:\n%s", line, body)
+		}
+	}
+	adapter.Del("hook_k1")
+}
+
+func TestMemoryLookup(t *testing.T) {
+	adapter := &MemoryCache{}
+	err := adapter.Init(`{"prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memory Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+	adapter.Del("lookup_k1")
+	adapter.Del("lookup_k2")
+
+	v := ""
+	exist, err := adapter.Lookup("lookup_k1", &v)
+	if err != nil || exist {
+		t.Errorf("Memory Lookup failed. lookup_k1 is not exist, err: %v.", err)
+	}
+	adapter.Set("lookup_k1", "v1", 100)
+	exist, err = adapter.LookupCtx(context.Background(), "lookup_k1", &v)
+	if err != nil || !exist || v != "v1" {
+		t.Errorf("Memory Lookup failed. Got %s, expected v1, err: %v.", v, err)
+	}
+
+	// 反序列化失败
+	n := 0
+	exist, err = adapter.Lookup("lookup_k1", &n)
+	if !errors.Is(err, cache.ErrDecode) {
+		t.Errorf("Memory Lookup failed. Got %v, expected cache.ErrDecode.", err)
+	}
+
+	// 不支持的操作
+	if _, err = adapter.Eval("return 1", nil); !errors.Is(err, cache.ErrUnsupported) {
+		t.Errorf("Memory Eval failed. Got %v, expected cache.ErrUnsupported.", err)
+	}
+	adapter.Del("lookup_k1")
+}
+
+func TestMemoryBatch(t *testing.T) {
+	adapter := &MemoryCache{}
+	err := adapter.Init(`{"prefix":"le_","encodeKey":"abcdefghij123456"}`)
+	if err != nil {
+		t.Errorf("Memory Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+
+	type user struct {
+		Id   int
+		Name string
+	}
+	for i, isTx := range []bool{false, true} {
+		b := adapter.Batch(isTx)
+		b.Set("batch_k1", user{Id: 1001, Name: "Diego"}, 60, true)
+		b.HSet("batch_h1", "f1", "hv1", 60)
+		get := b.Get("batch_k1")
+		miss := b.Get("batch_k2")
+		hget := b.HGet("batch_h1", "f1")
+		incr := b.Incr("batch_n1", 2)
+		decr := b.Decr("batch_n1")
+		hincr := b.HIncr("batch_h1", "n1", 3)
+		if b.Len() != 8 {
+			t.Errorf("Memory Batch failed. Got %d commands, expected 8.", b.Len())
+		}
+		err = b.Exec()
+		if err != nil {
+			t.Errorf("Memory Batch Exec failed. err: %s.", err.Error())
+			return
+		}
+
+		var u user
+		err, exist := get.Scan(&u)
+		if err != nil || !exist || u.Id != 1001 || u.Name != "Diego" {
+			t.Errorf("Memory Batch Get failed. Got %v, expected 1001-Diego.", u)
+		}
+		var s string
+		if err, exist = miss.Scan(&s); err != nil || exist {
+			t.Errorf("Memory Batch Get failed. batch_k2 is exist.")
+		}
+		if err, exist = hget.Scan(&s); err != nil || !exist || s != "hv1" {
+			t.Errorf("Memory Batch HGet failed. Got %s, expected hv1.", s)
+		}
+		if n, _ := incr.Int(); n != int64(2+i) {
+			t.Errorf("Memory Batch Incr failed. Got %d, expected %d.", n, 2+i)
+		}
+		if n, _ := decr.Int(); n != int64(1+i) {
+			t.Errorf("Memory Batch Decr failed. Got %d, expected %d.", n, 1+i)
+		}
+		if n, _ := hincr.Int(); n != int64(3+3*i) {
+			t.Errorf("Memory Batch HIncr failed. Got %d, expected %d.", n, 3+3*i)
+		}
+	}
+
+	// 与普通方法使用相同的前缀和加密
+	var u user
+	err, exist := adapter.Get("batch_k1", &u)
+	if err != nil || !exist || u.Id != 1001 {
+		t.Errorf("Memory Get failed. Got %v, expected 1001.", u)
+	}
+
+	// 队列执行后清空
+	b := adapter.Batch(false)
+	del := b.Del("batch_k1")
+	hdel := b.HDel("batch_h1", "f1", "f2")
+	expire := b.Expire("batch_n1", 0)
+	b.Exec()
+	if b.Len() != 0 {
+		t.Errorf("Memory Batch failed. Got %d commands, expected 0.", b.Len())
+	}
+	if n, _ := del.Int(); n != 1 {
+		t.Errorf("Memory Batch Del failed. Got %d, expected 1.", n)
+	}
+	if n, _ := hdel.Int(); n != 1 {
+		t.Errorf("Memory Batch HDel failed. Got %d, expected 1.", n)
+	}
+	if n, _ := expire.Int(); n != 1 {
+		t.Errorf("Memory Batch Expire failed. Got %d, expected 1.", n)
+	}
+	for _, key := range []string{"batch_k1", "batch_n1"} {
+		if ok, _ := adapter.IsExist(key); ok {
+			t.Errorf("Memory Batch failed. %s is exist.", key)
+		}
+	}
+
+	// 事务模式值转换出错时所有命令都不执行
+	b = adapter.Batch(true)
+	b.Set("batch_k3", "v3", 60)
+	b.Set("batch_k4", make(chan int), 60)
+	if err = b.Exec(); err == nil {
+		t.Error("Memory Batch failed. Tx expected error.")
+	}
+	if ok, _ := adapter.IsExist("batch_k3"); ok {
+		t.Error("Memory Batch failed. batch_k3 is exist.")
+	}
+
+	// 普通模式只跳过出错的命令
+	b = adapter.Batch(false)
+	set := b.Set("batch_k3", "v3", 60)
+	b.Set("batch_k4", make(chan int), 60)
+	if err = b.Exec(); err == nil {
+		t.Error("Memory Batch failed. Expected error.")
+	}
+	if set.Err() != nil {
+		t.Errorf("Memory Batch Set failed. err: %s.", set.Err().Error())
+	}
+	if ok, _ := adapter.IsExist("batch_k3"); !ok {
+		t.Error("Memory Batch failed. batch_k3 is not exist.")
+	}
+}
+
+func TestMemoryScan(t *testing.T) {
+	adapter := &MemoryCache{}
+	err := adapter.Init(`{"prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memory Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+
+	for i := 0; i < 25; i++ {
+		adapter.Set(fmt.Sprintf("scan_k%d", i), i, 60)
+	}
+	adapter.Set("other", 1, 60)
+	adapter.Set("scan_expired", 1, 60)
+	adapter.Expire("scan_expired", time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+
+	var keys []string
+	it := adapter.Scan("scan_*", 10)
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	if err = it.Err(); err != nil {
+		t.Errorf("Memory Scan failed. err: %s.", err.Error())
+		return
+	}
+	if len(keys) != 25 {
+		t.Errorf("Memory Scan failed. Got %d keys, expected 25.", len(keys))
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "scan_k") {
+			t.Errorf("Memory Scan failed. Got %s.", key)
+		}
+	}
+
+	// ctx取消后返回错误
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it = adapter.ScanCtx(ctx, "", 10)
+	if it.Next() || it.Err() == nil {
+		t.Error("Memory ScanCtx failed. Expected error.")
+	}
+
+	// 匹配规则
+	cases := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"*", "", true},
+		{"a*c", "abbc", true},
+		{"a*c", "abbd", false},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"[ab]x", "bx", true},
+		{"[^ab]x", "bx", false},
+		{"[a-c]x", "cx", true},
+		{"[c-a]x", "bx", true},
+		{`a\*`, "a*", true},
+		{`a\*`, "ab", false},
+		{`le\[1\]_*`, "le[1]_k", true},
+	}
+	for _, c := range cases {
+		if matchPattern(c.pattern, c.s) != c.match {
+			t.Errorf("Memory matchPattern failed. %s %s, expected %v.", c.pattern, c.s, c.match)
+		}
+	}
+}
+
+func TestMemoryLock(t *testing.T) {
+	adapter := &MemoryCache{}
+	err := adapter.Init(`{"prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memory Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+
+	var locker cache.Locker = adapter
+	ctx := context.Background()
+	if ok, err := locker.AcquireLock(ctx, "lock_k1", "t1", time.Second); err != nil || !ok {
+		t.Errorf("Memory AcquireLock failed. Got %v, err: %v.", ok, err)
+		return
+	}
+	if ok, _ := locker.AcquireLock(ctx, "lock_k1", "t2", time.Second); ok {
+		t.Error("Memory AcquireLock failed. Lock is obtained twice.")
+	}
+	if ok, _ := locker.ReleaseLock(ctx, "lock_k1", "t2"); ok {
+		t.Error("Memory ReleaseLock failed. Released with wrong token.")
+	}
+	if ok, _ := locker.RefreshLock(ctx, "lock_k1", "t1", 50*time.Millisecond); !ok {
+		t.Error("Memory RefreshLock failed.")
+	}
+	if ttl, _ := adapter.TTL("lock_k1"); ttl <= 0 || ttl > 50*time.Millisecond {
+		t.Errorf("Memory RefreshLock failed. Got ttl %v.", ttl)
+	}
+	if ok, _ := locker.ReleaseLock(ctx, "lock_k1", "t1"); !ok {
+		t.Error("Memory ReleaseLock failed.")
+	}
+
+	// 过期后可以重新获取
+	locker.AcquireLock(ctx, "lock_k2", "t1", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if ok, _ := locker.AcquireLock(ctx, "lock_k2", "t2", time.Second); !ok {
+		t.Error("Memory AcquireLock failed. Expired lock is not obtained.")
+	}
+}
@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets 默认的执行时间直方图分桶，单位秒
+var DefBuckets = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// metricKey 统计的维度
+type metricKey struct {
+	adapter string
+	cmd     string
+}
+
+// metric 一个维度的统计数据
+type metric struct {
+	total   uint64   // 执行次数
+	errors  uint64   // 出错次数
+	hits    uint64   // 读命令命中次数
+	misses  uint64   // 读命令未命中次数
+	buckets []uint64 // 执行时间直方图，与Collector.buckets一一对应，不累加
+	sum     float64  // 总执行时间，单位秒
+}
+
+// Collector 缓存命令统计，实现了Hook和http.Handler
+// 按适配器和命令统计执行次数、出错次数、命中次数、未命中次数和执行时间直方图，
+// ServeHTTP按Prometheus文本格式输出，不依赖Prometheus客户端库，如:
+//   collector := cache.NewCollector("myapp")
+//   adapter.AddHook(collector)
+//   http.Handle("/metrics", collector)
+type Collector struct {
+	namespace string    // 指标名前缀
+	buckets   []float64 // 执行时间直方图分桶，单位秒，从小到大
+
+	lock    sync.Mutex
+	metrics map[metricKey]*metric
+}
+
+// NewCollector 新建统计
+//   参数
+//     namespace: 指标名前缀，如myapp时指标名为myapp_cache_commands_total，为空时没有前缀
+//     buckets:   执行时间直方图分桶，单位秒，为空时使用DefBuckets
+//   返回
+//     统计对象
+func NewCollector(namespace string, buckets ...float64) *Collector {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	b := make([]float64, len(buckets))
+	copy(b, buckets)
+	sort.Float64s(b)
+
+	return &Collector{
+		namespace: namespace,
+		buckets:   b,
+		metrics:   make(map[metricKey]*metric),
+	}
+}
+
+// BeforeCmd 实现Hook接口，不做处理
+func (c *Collector) BeforeCmd(ctx context.Context, info *HookInfo) context.Context {
+	return ctx
+}
+
+// AfterCmd 实现Hook接口，记录命令的执行结果
+func (c *Collector) AfterCmd(ctx context.Context, info *HookInfo) {
+	seconds := info.Duration.Seconds()
+	k := metricKey{adapter: info.Adapter, cmd: info.Cmd}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	m, ok := c.metrics[k]
+	if !ok {
+		m = &metric{buckets: make([]uint64, len(c.buckets))}
+		c.metrics[k] = m
+	}
+
+	m.total++
+	m.sum += seconds
+	if i := sort.SearchFloat64s(c.buckets, seconds); i < len(c.buckets) {
+		m.buckets[i]++
+	}
+	if info.Err != nil {
+		m.errors++
+	} else if info.Read {
+		if info.Hit {
+			m.hits++
+		} else {
+			m.misses++
+		}
+	}
+}
+
+// HitRatio 返回适配器读命令的命中率
+//   参数
+//     adapter: 适配器名称，为空时统计所有适配器
+//   返回
+//     命中率，0~1，没有读命令时返回0
+func (c *Collector) HitRatio(adapter string) float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var hits, misses uint64
+	for k, m := range c.metrics {
+		if adapter == "" || k.adapter == adapter {
+			hits += m.hits
+			misses += m.misses
+		}
+	}
+	if hits+misses == 0 {
+		return 0
+	}
+
+	return float64(hits) / float64(hits+misses)
+}
+
+// Reset 清空统计数据
+func (c *Collector) Reset() {
+	c.lock.Lock()
+	c.metrics = make(map[metricKey]*metric)
+	c.lock.Unlock()
+}
+
+// ServeHTTP 按Prometheus文本格式输出统计数据
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(c.Bytes())
+}
+
+// Bytes 返回Prometheus文本格式的统计数据
+//   参数
+//
+//   返回
+//     统计数据
+func (c *Collector) Bytes() []byte {
+	c.lock.Lock()
+	keys := make([]metricKey, 0, len(c.metrics))
+	ms := make(map[metricKey]metric, len(c.metrics))
+	for k, m := range c.metrics {
+		keys = append(keys, k)
+		cp := *m
+		cp.buckets = append([]uint64(nil), m.buckets...)
+		ms[k] = cp
+	}
+	c.lock.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].adapter != keys[j].adapter {
+			return keys[i].adapter < keys[j].adapter
+		}
+		return keys[i].cmd < keys[j].cmd
+	})
+
+	var buf bytes.Buffer
+	counters := []struct {
+		name, help string
+		val        func(m metric) uint64
+	}{
+		{"cache_commands_total", "Total number of cache commands.", func(m metric) uint64 { return m.total }},
+		{"cache_errors_total", "Total number of failed cache commands.", func(m metric) uint64 { return m.errors }},
+		{"cache_hits_total", "Total number of cache read hits.", func(m metric) uint64 { return m.hits }},
+		{"cache_misses_total", "Total number of cache read misses.", func(m metric) uint64 { return m.misses }},
+	}
+	for _, ct := range counters {
+		name := c.metricName(ct.name)
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s counter\n", name, ct.help, name)
+		for _, k := range keys {
+			fmt.Fprintf(&buf, "%s{%s} %d\n", name, labels(k), ct.val(ms[k]))
+		}
+	}
+
+	name := c.metricName("cache_command_duration_seconds")
+	fmt.Fprintf(&buf, "# HELP %s Cache command latency in seconds.\n# TYPE %s histogram\n", name, name)
+	for _, k := range keys {
+		m := ms[k]
+		var count uint64
+		for i, le := range c.buckets {
+			count += m.buckets[i]
+			fmt.Fprintf(&buf, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels(k), formatFloat(le), count)
+		}
+		fmt.Fprintf(&buf, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels(k), m.total)
+		fmt.Fprintf(&buf, "%s_sum{%s} %s\n", name, labels(k), formatFloat(m.sum))
+		fmt.Fprintf(&buf, "%s_count{%s} %d\n", name, labels(k), m.total)
+	}
+
+	return buf.Bytes()
+}
+
+// metricName 返回带前缀的指标名
+func (c *Collector) metricName(name string) string {
+	if c.namespace == "" {
+		return name
+	}
+
+	return c.namespace + "_" + name
+}
+
+// labels 返回指标的标签
+func labels(k metricKey) string {
+	return `adapter="` + escapeLabel(k.adapter) + `",cmd="` + escapeLabel(k.cmd) + `"`
+}
+
+// labelEscaper 标签值需要转义反斜杠、双引号和换行
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel 转义标签值
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// formatFloat 浮点数转为字符串
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
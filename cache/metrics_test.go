@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCollector 统计测试
+func TestCollector(t *testing.T) {
+	collector := NewCollector("test", 0.001, 0.01)
+	hooks := &Hooks{}
+	hooks.AddHook(collector)
+
+	hit, miss := true, false
+	ctx, info := hooks.BeforeCmd(context.Background(), AdapterMemory, "get", "k1")
+	hooks.AfterCmd(ctx, info, nil, &hit)
+	ctx, info = hooks.BeforeCmd(context.Background(), AdapterMemory, "get", "k2")
+	hooks.AfterCmd(ctx, info, nil, &miss)
+	ctx, info = hooks.BeforeCmd(context.Background(), AdapterMemory, "get", "k3")
+	hooks.AfterCmd(ctx, info, nil, &hit)
+	ctx, info = hooks.BeforeCmd(context.Background(), AdapterMemory, "set", "k1")
+	info.Start = info.Start.Add(-5 * time.Millisecond)
+	hooks.AfterCmd(ctx, info, errors.New("set error"), nil)
+
+	if r := collector.HitRatio(AdapterMemory); r < 0.66 || r > 0.67 {
+		t.Errorf("Collector HitRatio failed. Got %f, expected 0.67.", r)
+	}
+	if r := collector.HitRatio(AdapterRedism); r != 0 {
+		t.Errorf("Collector HitRatio failed. Got %f, expected 0.", r)
+	}
+
+	w := httptest.NewRecorder()
+	collector.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Collector ServeHTTP failed. Content-Type: %s.", ct)
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE test_cache_commands_total counter",
+		`test_cache_commands_total{adapter="memory",cmd="get"} 3`,
+		`test_cache_errors_total{adapter="memory",cmd="set"} 1`,
+		`test_cache_hits_total{adapter="memory",cmd="get"} 2`,
+		`test_cache_misses_total{adapter="memory",cmd="get"} 1`,
+		"# TYPE test_cache_command_duration_seconds histogram",
+		`test_cache_command_duration_seconds_bucket{adapter="memory",cmd="set",le="0.001"} 0`,
+		`test_cache_command_duration_seconds_bucket{adapter="memory",cmd="set",le="0.01"} 1`,
+		`test_cache_command_duration_seconds_bucket{adapter="memory",cmd="set",le="+Inf"} 1`,
+		`test_cache_command_duration_seconds_count{adapter="memory",cmd="get"} 3`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Collector ServeHTTP failed. %s not found in:\n%s", line, body)
+		}
+	}
+
+	collector.Reset()
+	if b := collector.Bytes(); strings.Contains(string(b), "adapter=") {
+		t.Errorf("Collector Reset failed. Got %s.", string(b))
+	}
+}
+
+// TestHooksEmpty 没有钩子时不调用
+func TestHooksEmpty(t *testing.T) {
+	hooks := &Hooks{}
+	if _, info := hooks.BeforeCmd(context.Background(), AdapterMemory, "get", "k1"); info != nil {
+		t.Errorf("Hooks BeforeCmd failed. info should be nil.")
+	}
+
+	var err error
+	hooks.Process(context.Background(), AdapterMemory, "get", "k1")(&err, nil)
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if s := escapeLabel("a\"b\\c\nd"); s != `a\"b\\c\nd` {
+		t.Errorf("escapeLabel failed. Got %s.", s)
+	}
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"github.com/go-redis/redis/v7"
+	"strings"
+	"sync"
+)
+
+// KeyEventExpired key过期的键空间通知模式，需要redis配置notify-keyspace-events包含Ex
+const KeyEventExpired = "__keyevent@*__:expired"
+
+// Message 订阅收到的消息
+type Message struct {
+	Channel string // 频道，过期通知时为__keyevent@<db>__:expired
+	Pattern string // 匹配的模式，Subscribe时为空
+	Payload string // 消息内容，过期通知时为不含前缀的key
+}
+
+// PubSuber 支持发布订阅的缓存(redism、redisd、redisc支持)
+// 频道不添加key前缀，订阅断线后自动重连并重新订阅
+type PubSuber interface {
+	// Publish 向频道发布消息，返回收到消息的订阅者数
+	Publish(channel string, msg interface{}) (int64, error)
+	PublishCtx(ctx context.Context, channel string, msg interface{}) (int64, error)
+	// Subscribe 订阅频道，ctx取消时取消订阅并关闭返回的channel
+	Subscribe(ctx context.Context, channels ...string) (<-chan *Message, error)
+	// SubscribeExpired 订阅带key前缀的key的过期通知，Payload为不含前缀的key
+	SubscribeExpired(ctx context.Context) (<-chan *Message, error)
+}
+
+// ReceiveMessages 等待所有订阅成功，然后把收到的消息转发到返回的channel
+// go-redis的PubSub断线后会自动重连并重新订阅，ctx取消时关闭所有订阅和返回的channel
+//   参数
+//     ctx:     上下文，用于取消订阅
+//     prefix:  key前缀，不为空时只转发Payload带此前缀的消息，并去掉前缀，用于过期通知
+//     pubsubs: go-redis的订阅
+//   返回
+//     消息channel，订阅失败时关闭所有订阅并返回错误信息
+func ReceiveMessages(ctx context.Context, prefix string, pubsubs ...*redis.PubSub) (<-chan *Message, error) {
+	for _, ps := range pubsubs {
+		if _, err := ps.Receive(); err != nil {
+			for _, ps := range pubsubs {
+				ps.Close()
+			}
+			return nil, RedisError(err)
+		}
+	}
+
+	out := make(chan *Message, 100)
+	var wg sync.WaitGroup
+	for _, ps := range pubsubs {
+		wg.Add(1)
+		go func(ps *redis.PubSub) {
+			defer wg.Done()
+			ForwardMessages(ctx, prefix, ps, out)
+		}(ps)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out, nil
+}
+
+// ForwardMessages 把订阅收到的消息转发到out，ctx取消或订阅关闭时返回，返回前关闭订阅
+//   参数
+//     ctx:    上下文，用于取消订阅
+//     prefix: key前缀，同ReceiveMessages
+//     ps:     go-redis的订阅
+//     out:    转发消息的channel，不会关闭
+//   返回
+//
+func ForwardMessages(ctx context.Context, prefix string, ps *redis.PubSub, out chan<- *Message) {
+	defer ps.Close()
+
+	ch := ps.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-ch:
+			if !ok {
+				return
+			}
+			msg := &Message{Channel: m.Channel, Pattern: m.Pattern, Payload: m.Payload}
+			if prefix != "" {
+				if !strings.HasPrefix(m.Payload, prefix) {
+					continue
+				}
+				msg.Payload = m.Payload[len(prefix):]
+			}
+
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
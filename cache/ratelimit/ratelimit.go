@@ -0,0 +1,417 @@
+// Rate limiter
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/lixy529/gotools/cache"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Result 限流结果
+type Result struct {
+	Allowed    bool          // 是否允许
+	Remaining  int64         // 剩余配额
+	RetryAfter time.Duration // 不允许时需要等待的时间，允许时为0
+}
+
+// Limiter 限流器
+// 适配器实现了cache.ScriptRunner(redis适配器)时使用lua脚本，多个进程共享配额且操作是原子的
+// 其它适配器使用进程内的锁保证原子性，只在同一个进程内有效
+//   实例：
+//     limiter := ratelimit.NewFixedWindow(c, 100, time.Minute)
+//     res, err := limiter.Allow("api_user_1001")
+//     if err == nil && !res.Allowed {
+//       // 超过限制，res.RetryAfter后再试
+//     }
+type Limiter interface {
+	Allow(key string) (*Result, error)
+	AllowN(key string, n int64) (*Result, error)
+	AllowNCtx(ctx context.Context, key string, n int64) (*Result, error)
+}
+
+// fixedWindowScript 固定窗口，窗口从第一次请求开始
+//   KEYS[1]: 计数的key
+//   ARGV[1]: 窗口内的最大请求数
+//   ARGV[2]: 窗口大小，单位毫秒
+//   ARGV[3]: 本次请求数
+//   返回: {是否允许, 剩余配额, 等待毫秒数}
+var fixedWindowScript = redis.NewScript(`
+local limit, window, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local count = tonumber(redis.call("get", KEYS[1]) or "0")
+if count + n > limit then
+	local ttl = redis.call("pttl", KEYS[1])
+	if ttl < 0 then
+		ttl = window
+	end
+	return {0, limit - count, ttl}
+end
+count = redis.call("incrby", KEYS[1], n)
+if count == n then
+	redis.call("pexpire", KEYS[1], window)
+end
+return {1, limit - count, 0}
+`)
+
+// slidingWindowScript 滑动窗口日志，每个请求作为有序集合的一个成员，分数为请求时间
+//   KEYS[1]: 有序集合的key
+//   ARGV[1]: 窗口内的最大请求数
+//   ARGV[2]: 窗口大小，单位毫秒
+//   ARGV[3]: 当前时间，单位毫秒
+//   ARGV[4]: 本次请求数
+//   ARGV[5]: 成员前缀，保证成员唯一
+//   返回: {是否允许, 剩余配额, 等待毫秒数}
+var slidingWindowScript = redis.NewScript(`
+local limit, window, now, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+redis.call("zremrangebyscore", KEYS[1], "-inf", now - window)
+local count = redis.call("zcard", KEYS[1])
+if count + n > limit then
+	local retry = window
+	local idx = count + n - limit - 1
+	local e = redis.call("zrange", KEYS[1], idx, idx, "withscores")
+	if e[2] then
+		retry = tonumber(e[2]) + window - now
+	end
+	return {0, limit - count, retry}
+end
+for i = 1, n do
+	redis.call("zadd", KEYS[1], now, ARGV[5] .. i)
+end
+redis.call("pexpire", KEYS[1], window)
+return {1, limit - count - n, 0}
+`)
+
+// tokenBucketScript 令牌桶，按时间补充令牌，最多burst个
+//   KEYS[1]: 保存令牌数和时间的哈希表key
+//   ARGV[1]: 每秒补充的令牌数
+//   ARGV[2]: 桶的容量
+//   ARGV[3]: 当前时间，单位毫秒
+//   ARGV[4]: 本次需要的令牌数
+//   返回: {是否允许, 剩余令牌数, 等待毫秒数}
+var tokenBucketScript = redis.NewScript(`
+local rate, burst, now, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local state = redis.call("hmget", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate / 1000)
+	ts = now
+end
+local allowed, retry = 0, 0
+if tokens >= n then
+	tokens = tokens - n
+	allowed = 1
+else
+	retry = math.ceil((n - tokens) * 1000 / rate)
+end
+redis.call("hmset", KEYS[1], "tokens", tokens, "ts", ts)
+redis.call("pexpire", KEYS[1], math.ceil(burst * 1000 / rate))
+return {allowed, math.floor(tokens), retry}
+`)
+
+// FixedWindow 固定窗口限流，窗口从第一次请求开始，窗口内最多limit个请求
+type FixedWindow struct {
+	adapter cache.Cache
+	limit   int64
+	window  time.Duration
+	lock    sync.Mutex // 不支持lua脚本时使用
+}
+
+// NewFixedWindow 新建一个固定窗口限流器
+//   参数
+//     adapter: Cache对象
+//     limit:   窗口内的最大请求数
+//     window:  窗口大小，最小1毫秒
+//   返回
+//     限流器
+func NewFixedWindow(adapter cache.Cache, limit int64, window time.Duration) *FixedWindow {
+	return &FixedWindow{adapter: adapter, limit: limit, window: window}
+}
+
+// Allow 判断一个请求是否允许
+//   参数
+//     key: 限流的key值，如用户id、接口名
+//   返回
+//     限流结果，失败返回错误信息
+func (l *FixedWindow) Allow(key string) (*Result, error) {
+	return l.AllowNCtx(context.Background(), key, 1)
+}
+
+// AllowN 判断n个请求是否允许，不允许时不占用配额
+func (l *FixedWindow) AllowN(key string, n int64) (*Result, error) {
+	return l.AllowNCtx(context.Background(), key, n)
+}
+
+// AllowNCtx 同AllowN，ctx用于控制超时和取消
+func (l *FixedWindow) AllowNCtx(ctx context.Context, key string, n int64) (*Result, error) {
+	if runner, ok := l.adapter.(cache.ScriptRunner); ok {
+		return runScript(ctx, runner, fixedWindowScript, key, l.limit, l.window.Milliseconds(), n)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// 窗口开始时间和计数
+	var state struct {
+		Count int64 `json:"count"`
+		Start int64 `json:"start"`
+	}
+	now := nowMs()
+	win := l.window.Milliseconds()
+	err, exist := l.adapter.Get(key, &state)
+	if err != nil {
+		return nil, err
+	}
+	if !exist || now >= state.Start+win {
+		state.Count, state.Start = 0, now
+	}
+
+	reset := time.Duration(state.Start+win-now) * time.Millisecond
+	if state.Count+n > l.limit {
+		return &Result{Allowed: false, Remaining: l.limit - state.Count, RetryAfter: reset}, nil
+	}
+
+	state.Count += n
+	err = l.adapter.Set(key, state, expireSeconds(reset))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Allowed: true, Remaining: l.limit - state.Count}, nil
+}
+
+// SlidingWindow 滑动窗口日志限流，任意window时间内最多limit个请求
+// 每个请求作为有序集合的一个成员，适配器需要支持有序集合
+type SlidingWindow struct {
+	adapter cache.Cache
+	limit   int64
+	window  time.Duration
+	lock    sync.Mutex // 不支持lua脚本时使用
+}
+
+// NewSlidingWindow 新建一个滑动窗口限流器
+//   参数
+//     adapter: Cache对象，需要支持有序集合
+//     limit:   窗口内的最大请求数
+//     window:  窗口大小，最小1毫秒
+//   返回
+//     限流器
+func NewSlidingWindow(adapter cache.Cache, limit int64, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{adapter: adapter, limit: limit, window: window}
+}
+
+// Allow 判断一个请求是否允许
+//   参数
+//     key: 限流的key值，如用户id、接口名
+//   返回
+//     限流结果，失败返回错误信息
+func (l *SlidingWindow) Allow(key string) (*Result, error) {
+	return l.AllowNCtx(context.Background(), key, 1)
+}
+
+// AllowN 判断n个请求是否允许，不允许时不占用配额
+func (l *SlidingWindow) AllowN(key string, n int64) (*Result, error) {
+	return l.AllowNCtx(context.Background(), key, n)
+}
+
+// AllowNCtx 同AllowN，ctx用于控制超时和取消
+func (l *SlidingWindow) AllowNCtx(ctx context.Context, key string, n int64) (*Result, error) {
+	member, err := newMember()
+	if err != nil {
+		return nil, err
+	}
+
+	now := nowMs()
+	win := l.window.Milliseconds()
+	if runner, ok := l.adapter.(cache.ScriptRunner); ok {
+		return runScript(ctx, runner, slidingWindowScript, key, l.limit, win, now, n, member)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// 删除窗口外的请求
+	_, err = l.adapter.ZRemRangeByScore(key, "-inf", strconv.FormatInt(now-win, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := l.adapter.ZCard(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if count+n > l.limit {
+		// 等到足够多的请求移出窗口
+		retry := win
+		idx := int(count + n - l.limit - 1)
+		res, err := l.adapter.ZGet(key, idx, idx, true, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(res) == 2 {
+			if score, err := strconv.ParseFloat(res[0], 64); err == nil {
+				retry = int64(score) + win - now
+			}
+		}
+		return &Result{Allowed: false, Remaining: l.limit - count, RetryAfter: time.Duration(retry) * time.Millisecond}, nil
+	}
+
+	vals := make([]interface{}, 0, n*2)
+	for i := int64(1); i <= n; i++ {
+		vals = append(vals, now, member+strconv.FormatInt(i, 10))
+	}
+	_, err = l.adapter.ZSet(key, expireSeconds(l.window), vals...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Allowed: true, Remaining: l.limit - count - n}, nil
+}
+
+// TokenBucket 令牌桶限流，每秒补充rate个令牌，最多burst个，每个请求消耗一个令牌
+type TokenBucket struct {
+	adapter cache.Cache
+	rate    float64
+	burst   int64
+	lock    sync.Mutex // 不支持lua脚本时使用
+}
+
+// NewTokenBucket 新建一个令牌桶限流器
+//   参数
+//     adapter: Cache对象
+//     rate:    每秒补充的令牌数，需要大于0
+//     burst:   桶的容量，即允许的突发请求数
+//   返回
+//     限流器
+func NewTokenBucket(adapter cache.Cache, rate float64, burst int64) *TokenBucket {
+	return &TokenBucket{adapter: adapter, rate: rate, burst: burst}
+}
+
+// Allow 判断一个请求是否允许
+//   参数
+//     key: 限流的key值，如用户id、接口名
+//   返回
+//     限流结果，失败返回错误信息
+func (l *TokenBucket) Allow(key string) (*Result, error) {
+	return l.AllowNCtx(context.Background(), key, 1)
+}
+
+// AllowN 判断n个请求是否允许，不允许时不消耗令牌
+func (l *TokenBucket) AllowN(key string, n int64) (*Result, error) {
+	return l.AllowNCtx(context.Background(), key, n)
+}
+
+// AllowNCtx 同AllowN，ctx用于控制超时和取消
+func (l *TokenBucket) AllowNCtx(ctx context.Context, key string, n int64) (*Result, error) {
+	now := nowMs()
+	if runner, ok := l.adapter.(cache.ScriptRunner); ok {
+		return runScript(ctx, runner, tokenBucketScript, key, l.rate, l.burst, now, n)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// 令牌数和最后补充时间
+	var state struct {
+		Tokens float64 `json:"tokens"`
+		Ts     int64   `json:"ts"`
+	}
+	err, exist := l.adapter.Get(key, &state)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		state.Tokens, state.Ts = float64(l.burst), now
+	}
+	if now > state.Ts {
+		state.Tokens = math.Min(float64(l.burst), state.Tokens+float64(now-state.Ts)*l.rate/1000)
+		state.Ts = now
+	}
+
+	res := &Result{}
+	if state.Tokens >= float64(n) {
+		state.Tokens -= float64(n)
+		res.Allowed = true
+	} else {
+		retry := math.Ceil((float64(n) - state.Tokens) * 1000 / l.rate)
+		res.RetryAfter = time.Duration(retry) * time.Millisecond
+	}
+	res.Remaining = int64(state.Tokens)
+
+	full := time.Duration(math.Ceil(float64(l.burst)*1000/l.rate)) * time.Millisecond
+	err = l.adapter.Set(key, state, expireSeconds(full))
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// runScript 执行限流脚本并转换结果
+func runScript(ctx context.Context, runner cache.ScriptRunner, script *redis.Script, key string, args ...interface{}) (*Result, error) {
+	v, err := runner.RunScript(ctx, script, []string{key}, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// 返回值为{是否允许, 剩余配额, 等待毫秒数}
+	vals, ok := v.([]interface{})
+	if !ok || len(vals) != 3 {
+		return nil, fmt.Errorf("RateLimit: unexpected script result %v", v)
+	}
+	nums := make([]int64, len(vals))
+	for i, val := range vals {
+		if nums[i], ok = val.(int64); !ok {
+			return nil, fmt.Errorf("RateLimit: unexpected script result %v", v)
+		}
+	}
+
+	return &Result{
+		Allowed:    nums[0] == 1,
+		Remaining:  nums[1],
+		RetryAfter: time.Duration(nums[2]) * time.Millisecond,
+	}, nil
+}
+
+// nowMs 返回当前时间，单位毫秒
+func nowMs() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// expireSeconds 转为缓存的过期时间，单位秒，向上取整，最小1秒
+func expireSeconds(d time.Duration) int32 {
+	expire := int32((d + time.Second - 1) / time.Second)
+	if expire < 1 {
+		expire = 1
+	}
+	return expire
+}
+
+// newMember 生成随机的成员前缀
+func newMember() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b) + "_", nil
+}
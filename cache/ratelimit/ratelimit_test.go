@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"github.com/lixy529/gotools/cache"
+	_ "github.com/lixy529/gotools/cache/memory"
+	_ "github.com/lixy529/gotools/cache/redis/redism"
+	"testing"
+	"time"
+)
+
+var gRedisConfig = `{"mAddr":"127.0.0.1:6379","mDbNum":"1","mAuth":"123456","sAddr":"127.0.0.1:6379","sDbNum":"1","sAuth":"123456","prefix":"le_"}`
+
+// newAdapters 返回测试用的适配器，redism使用lua脚本，memory使用进程内的锁
+func newAdapters(t *testing.T) map[string]cache.Cache {
+	adapters := make(map[string]cache.Cache)
+	if c, err := cache.NewCache(cache.AdapterMemory, ""); err == nil {
+		adapters[cache.AdapterMemory] = c
+	} else {
+		t.Errorf("NewCache memory failed. err: %s.", err.Error())
+	}
+	if c, err := cache.NewCache(cache.AdapterRedism, gRedisConfig); err == nil {
+		adapters[cache.AdapterRedism] = c
+	} else {
+		t.Errorf("NewCache redism failed. err: %s.", err.Error())
+	}
+	return adapters
+}
+
+func TestFixedWindow(t *testing.T) {
+	for name, c := range newAdapters(t) {
+		c.Del("rl_fixed")
+		limiter := NewFixedWindow(c, 3, 200*time.Millisecond)
+		for i := int64(1); i <= 3; i++ {
+			res, err := limiter.Allow("rl_fixed")
+			if err != nil || !res.Allowed || res.Remaining != 3-i {
+				t.Errorf("%s FixedWindow Allow failed. Got %v, err: %v.", name, res, err)
+			}
+		}
+
+		res, err := limiter.Allow("rl_fixed")
+		if err != nil || res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > 200*time.Millisecond {
+			t.Errorf("%s FixedWindow Allow failed. Got %v, expected not allowed.", name, res)
+		}
+		c.Del("rl_fixed")
+	}
+}
+
+func TestFixedWindowReset(t *testing.T) {
+	c, err := cache.NewCache(cache.AdapterMemory, "")
+	if err != nil {
+		t.Errorf("NewCache memory failed. err: %s.", err.Error())
+		return
+	}
+
+	limiter := NewFixedWindow(c, 2, 100*time.Millisecond)
+	limiter.AllowN("rl_reset", 2)
+	res, err := limiter.Allow("rl_reset")
+	if err != nil || res.Allowed {
+		t.Errorf("FixedWindow Allow failed. Got %v, expected not allowed.", res)
+		return
+	}
+
+	// 窗口结束后重新计数
+	time.Sleep(res.RetryAfter + 10*time.Millisecond)
+	res, err = limiter.Allow("rl_reset")
+	if err != nil || !res.Allowed || res.Remaining != 1 {
+		t.Errorf("FixedWindow Allow failed. Got %v, err: %v.", res, err)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	for name, c := range newAdapters(t) {
+		c.Del("rl_sliding")
+		limiter := NewSlidingWindow(c, 3, 200*time.Millisecond)
+		res, err := limiter.AllowN("rl_sliding", 2)
+		if err != nil || !res.Allowed || res.Remaining != 1 {
+			t.Errorf("%s SlidingWindow AllowN failed. Got %v, err: %v.", name, res, err)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		res, err = limiter.Allow("rl_sliding")
+		if err != nil || !res.Allowed || res.Remaining != 0 {
+			t.Errorf("%s SlidingWindow Allow failed. Got %v, err: %v.", name, res, err)
+		}
+
+		// 需要等到前两个请求移出窗口
+		res, err = limiter.AllowN("rl_sliding", 2)
+		if err != nil || res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > 100*time.Millisecond {
+			t.Errorf("%s SlidingWindow AllowN failed. Got %v, expected not allowed.", name, res)
+			continue
+		}
+
+		time.Sleep(res.RetryAfter + 10*time.Millisecond)
+		res, err = limiter.AllowN("rl_sliding", 2)
+		if err != nil || !res.Allowed || res.Remaining != 0 {
+			t.Errorf("%s SlidingWindow AllowN failed. Got %v, err: %v.", name, res, err)
+		}
+		c.Del("rl_sliding")
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	for name, c := range newAdapters(t) {
+		c.Del("rl_bucket")
+		limiter := NewTokenBucket(c, 20, 2)
+		for i := int64(1); i <= 2; i++ {
+			res, err := limiter.Allow("rl_bucket")
+			if err != nil || !res.Allowed || res.Remaining != 2-i {
+				t.Errorf("%s TokenBucket Allow failed. Got %v, err: %v.", name, res, err)
+			}
+		}
+
+		res, err := limiter.Allow("rl_bucket")
+		if err != nil || res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > 50*time.Millisecond {
+			t.Errorf("%s TokenBucket Allow failed. Got %v, expected not allowed.", name, res)
+			continue
+		}
+
+		time.Sleep(res.RetryAfter + 10*time.Millisecond)
+		res, err = limiter.Allow("rl_bucket")
+		if err != nil || !res.Allowed {
+			t.Errorf("%s TokenBucket Allow failed. Got %v, err: %v.", name, res, err)
+		}
+		c.Del("rl_bucket")
+	}
+}
@@ -2,16 +2,20 @@
 package redisc
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v7"
 	"github.com/lixy529/gotools/cache"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+// NOT_EXIST key不存在时go-redis的错误信息
+// Deprecated: 使用cache.IsMiss或errors.Is(err, cache.ErrMiss)判断
 const NOT_EXIST = "redis: nil"
 
 // RediscCache Redis cluster 缓存
@@ -29,8 +33,18 @@ type RediscCache struct {
 	poolTimeout  time.Duration // 如果所有连接都忙时的等待时间，默认为readTimeout+1秒
 	idleTimeout  time.Duration // 最大空闲时间，单位秒，默认为5分钟
 
-	prefix    string // key前缀，如果配置里有，则所有key前自动添加此前缀
-	encodeKey []byte // 加解密密钥，使用Aes加密，长度为16的倍数
+	prefix     string           // key前缀，如果配置里有，则所有key前自动添加此前缀
+	encodeKey  [][]byte         // 加解密密钥，第一个用于加密，全部用于解密
+	serializer cache.Serializer // 序列化，默认为json
+
+	compressType      string // 压缩类型，支持zlib、gzip、snappy，为空时不压缩
+	compressThreshold int    // 超过大小就进行压缩，单位字节，默认256
+
+	hooks cache.Hooks // 命令钩子
+}
+
+func init() {
+	cache.Register(cache.AdapterRedisc, NewRediscCache)
 }
 
 // NewRediscCache 新建一个RediscCache适配器.
@@ -54,6 +68,9 @@ func NewRediscCache() cache.Cache {
 //         "idleTimeout":"5",
 //         "prefix":"le_",
 //         "encodeKey":"abcdefghij123456",
+//         "serializer":"json",
+//         "compressType":"snappy",
+//         "compressThreshold":"256",
 //       }
 //       addr:         连接主机和端口，多个主机用逗号分割，如127.0.0.1:1900,127.0.0.2:1900
 //       auth:         授权密码
@@ -66,7 +83,10 @@ func NewRediscCache() cache.Cache {
 //       poolTimeout:  如果所有连接都忙时的等待时间，默认为readTimeout+1秒
 //       idleTimeout:  最大空闲时间，单位秒，默认为5分钟
 //       prefix:       key前缀，如果配置里有，则所有key前自动添加此前缀
-//       encodeKey:    数据如果要加密，传的加密密钥
+//       encodeKey:    数据如果要加密，传的加密密钥，多个用逗号分割，第一个用于加密，全部用于解密
+//       serializer:   序列化，支持json、gob、msgpack、raw，默认为json
+//       compressType: 压缩类型，支持zlib、gzip、snappy，为空时不压缩
+//       compressThreshold: 超过大小就进行压缩，单位字节，默认256
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (c *RediscCache) Init(config string) error {
@@ -159,7 +179,26 @@ func (c *RediscCache) Init(config string) error {
 
 	// 加密密钥
 	if tmp, ok := mapCfg["encodeKey"]; ok && tmp != "" {
-		c.encodeKey = []byte(tmp)
+		c.encodeKey = cache.ParseEncodeKeys(tmp)
+	}
+
+	// 序列化
+	c.serializer, err = cache.GetSerializer(mapCfg["serializer"])
+	if err != nil {
+		return err
+	}
+
+	// 压缩
+	c.compressType = mapCfg["compressType"]
+	err = cache.CheckCompressType(c.compressType)
+	if err != nil {
+		return err
+	}
+	compressThreshold, err := strconv.Atoi(mapCfg["compressThreshold"])
+	if err != nil || compressThreshold < 0 {
+		c.compressThreshold = 256
+	} else {
+		c.compressThreshold = compressThreshold
 	}
 
 	// 连接
@@ -175,10 +214,38 @@ func (c *RediscCache) Init(config string) error {
 		PoolTimeout:  c.poolTimeout * time.Second,
 		IdleTimeout:  c.idleTimeout * time.Second,
 	})
+	c.client.AddHook(cache.NewRedisHook(cache.AdapterRedisc, c.prefix, &c.hooks))
 
 	return nil
 }
 
+// getClient 获取客户端，ctx不是context.Background()时返回绑定了ctx的客户端
+//   参数
+//     ctx: 上下文
+//   返回
+//     客户端
+func (c *RediscCache) getClient(ctx context.Context) *redis.ClusterClient {
+	if ctx == nil || ctx == context.Background() {
+		return c.client
+	}
+
+	return c.client.WithContext(ctx)
+}
+
+// AddHook 添加命令钩子
+//   参数
+//     hook: 钩子
+//   返回
+//
+func (c *RediscCache) AddHook(hook cache.Hook) {
+	c.hooks.AddHook(hook)
+}
+
+// Serializer 返回配置的序列化，实现cache.SerializerGetter
+func (c *RediscCache) Serializer() cache.Serializer {
+	return c.serializer
+}
+
 // Set 向缓存设置一个值
 //   参数
 //     key:    key值
@@ -188,26 +255,23 @@ func (c *RediscCache) Init(config string) error {
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (c *RediscCache) Set(key string, val interface{}, expire int32, encode ...bool) error {
-	// 类型转换
-	data, err := cache.InterToByte(val)
+	return c.SetCtx(context.Background(), key, val, expire, encode...)
+}
+
+// SetCtx 同Set，ctx用于控制超时和取消
+func (c *RediscCache) SetCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	data, err := c.encodeValue(val, encode...)
 	if err != nil {
 		return err
 	}
 
-	// 加密判断
-	encode = append(encode, false)
-	if encode[0] {
-		data, err = cache.Encode(data, c.encodeKey)
-		if err != nil {
-			return err
-		}
-	}
-
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.client.Set(key, data, time.Duration(expire)*time.Second).Err()
+	return c.getClient(ctx).Set(key, data, time.Duration(expire)*time.Second).Err()
 }
 
 // Get 从缓存取一个值
@@ -217,31 +281,96 @@ func (c *RediscCache) Set(key string, val interface{}, expire int32, encode ...b
 //   返回
 //     错误信息，是否存在
 func (c *RediscCache) Get(key string, val interface{}) (error, bool) {
+	return c.GetCtx(context.Background(), key, val)
+}
+
+// GetCtx 同Get，ctx用于控制超时和取消
+func (c *RediscCache) GetCtx(ctx context.Context, key string, val interface{}) (err error, exist bool) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	v, err := c.client.Get(key).Result()
+	v, err := c.getClient(ctx).Get(key).Result()
 	if err != nil {
-		if err.Error() == NOT_EXIST {
+		if cache.IsMiss(err) {
 			return nil, false
 		}
 		return err, false
 	}
 
+	return c.decodeValue(v, val), true
+}
+
+// Lookup 从缓存取一个值，同Get，返回值顺序为(是否存在, 错误信息)
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     是否存在，错误信息，key不存在时返回false和nil
+func (c *RediscCache) Lookup(key string, val interface{}) (bool, error) {
+	return c.LookupCtx(context.Background(), key, val)
+}
+
+// LookupCtx 同Lookup，ctx用于控制超时和取消
+func (c *RediscCache) LookupCtx(ctx context.Context, key string, val interface{}) (bool, error) {
+	err, exist := c.GetCtx(ctx, key, val)
+	return exist, err
+}
+
+// encodeValue 生成要保存的数据，处理序列化、压缩和加密
+//   参数
+//     val:    value值
+//     encode: 是否加密标识
+//   返回
+//     要保存的数据，失败返回错误信息
+func (c *RediscCache) encodeValue(val interface{}, encode ...bool) ([]byte, error) {
+	// 类型转换
+	data, err := cache.InterToByte(val, c.serializer)
+	if err != nil {
+		return nil, err
+	}
+
+	// 压缩判断
+	data, err = cache.Compress(data, c.compressType, c.compressThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	// 加密判断
+	encode = append(encode, false)
+	if encode[0] {
+		data, err = cache.Encode(data, c.encodeKey...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// decodeValue 解析保存的数据，处理解密、解压和类型转换
+//   参数
+//     v:   保存的数据
+//     val: 保存结果地址
+//   返回
+//     失败返回错误信息
+func (c *RediscCache) decodeValue(v string, val interface{}) error {
 	// 解密判断
-	data, err := cache.Decode([]byte(v), c.encodeKey)
+	data, err := cache.Decode([]byte(v), c.encodeKey...)
 	if err != nil {
-		return err, true
+		return err
 	}
 
-	// 类型转换
-	err = cache.ByteToInter(data, val)
+	// 解压
+	data, err = cache.Uncompress(data)
 	if err != nil {
-		return err, true
+		return err
 	}
 
-	return nil, true
+	// 类型转换
+	return cache.ByteToInter(data, val)
 }
 
 // Del 从缓存删除一个值
@@ -250,11 +379,145 @@ func (c *RediscCache) Get(key string, val interface{}) (error, bool) {
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (c *RediscCache) Del(key string) error {
+	return c.DelCtx(context.Background(), key)
+}
+
+// DelCtx 同Del，ctx用于控制超时和取消
+func (c *RediscCache) DelCtx(ctx context.Context, key string) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx).Del(key).Err()
+}
+
+// Add key不存在时设置一个值
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key已存在返回false，失败返回错误信息
+func (c *RediscCache) Add(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return c.AddCtx(context.Background(), key, val, expire, encode...)
+}
+
+// AddCtx 同Add，ctx用于控制超时和取消
+func (c *RediscCache) AddCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	data, err := c.encodeValue(val, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx).SetNX(key, data, time.Duration(expire)*time.Second).Result()
+}
+
+// Replace key存在时设置一个值
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key不存在返回false，失败返回错误信息
+func (c *RediscCache) Replace(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return c.ReplaceCtx(context.Background(), key, val, expire, encode...)
+}
+
+// ReplaceCtx 同Replace，ctx用于控制超时和取消
+func (c *RediscCache) ReplaceCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	data, err := c.encodeValue(val, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx).SetXX(key, data, time.Duration(expire)*time.Second).Result()
+}
+
+// GetWithVersion 从缓存取一个值，同时返回数据的版本，用于CompareAndSwap
+// redis的版本为保存数据的sha1，数据被修改后又改回原值时版本不变
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     数据的版本，key不存在时返回nil，失败返回错误信息
+func (c *RediscCache) GetWithVersion(key string, val interface{}) (*cache.Version, error) {
+	return c.GetWithVersionCtx(context.Background(), key, val)
+}
+
+// GetWithVersionCtx 同GetWithVersion，ctx用于控制超时和取消
+func (c *RediscCache) GetWithVersionCtx(ctx context.Context, key string, val interface{}) (res *cache.Version, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKey := key
+	if c.prefix != "" {
+		pKey = c.prefix + key
+	}
+
+	v, err := c.getClient(ctx).Get(pKey).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	ver := &cache.Version{Key: key, Token: cache.DataSha1(v)}
+	return ver, c.decodeValue(v, val)
+}
+
+// CompareAndSwap 数据的版本与GetWithVersion返回的版本一致时设置新值，在lua脚本里原子执行
+// 只比较数据内容，数据被修改后又改回原值(A→B→A)时检测不到，需要时在值里保存版本号
+//   参数
+//     ver:    GetWithVersion返回的版本
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示保留原来的过期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，数据已被修改或删除返回false，失败返回错误信息
+func (c *RediscCache) CompareAndSwap(ver *cache.Version, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return c.CompareAndSwapCtx(context.Background(), ver, val, expire, encode...)
+}
+
+// CompareAndSwapCtx 同CompareAndSwap，ctx用于控制超时和取消
+func (c *RediscCache) CompareAndSwapCtx(ctx context.Context, ver *cache.Version, val interface{}, expire int32, encode ...bool) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if ver == nil {
+		return false, errors.New("RediscCache: CompareAndSwap invalid version")
+	}
+	sha, ok := ver.Token.(string)
+	if !ok {
+		return false, errors.New("RediscCache: CompareAndSwap invalid version")
+	}
+
+	data, err := c.encodeValue(val, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	key := ver.Key
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.client.Del(key).Err()
+	n, err := cache.CompareAndSwapScript.Run(c.getClient(ctx), []string{key}, sha, data, expire).Int64()
+	return n == 1, err
 }
 
 // MSet 同时设置一个或多个key-value对
@@ -267,8 +530,15 @@ func (c *RediscCache) Del(key string) error {
 //   返回
 //     成功返回查询结果，失败返回错误信息，key不存在时对应的val为nil
 func (c *RediscCache) MSet(mList map[string]interface{}, expire int32, encode ...bool) error {
+	return c.MSetCtx(context.Background(), mList, expire, encode...)
+}
+
+// MSetCtx 同MSet，ctx用于控制超时和取消
+func (c *RediscCache) MSetCtx(ctx context.Context, mList map[string]interface{}, expire int32, encode ...bool) (err error) {
+	defer cache.ConvRedisError(&err)
+
 	for k, v := range mList {
-		err := c.Set(k, v, expire, encode...)
+		err := c.SetCtx(ctx, k, v, expire, encode...)
 		if err != nil {
 			return err
 		}
@@ -285,10 +555,17 @@ func (c *RediscCache) MSet(mList map[string]interface{}, expire int32, encode ..
 //   返回
 //     成功返回查询结果，失败返回错误信息
 func (c *RediscCache) MGet(keys ...string) (map[string]interface{}, error) {
+	return c.MGetCtx(context.Background(), keys...)
+}
+
+// MGetCtx 同MGet，ctx用于控制超时和取消
+func (c *RediscCache) MGetCtx(ctx context.Context, keys ...string) (res map[string]interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
 	mList := make(map[string]interface{})
 	for _, k := range keys {
 		v := ""
-		err, b := c.Get(k, &v)
+		err, b := c.GetCtx(ctx, k, &v)
 		if err != nil {
 			return mList, err
 		} else if !b {
@@ -308,8 +585,15 @@ func (c *RediscCache) MGet(keys ...string) (map[string]interface{}, error) {
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (c *RediscCache) MDel(keys ...string) error {
+	return c.MDelCtx(context.Background(), keys...)
+}
+
+// MDelCtx 同MDel，ctx用于控制超时和取消
+func (c *RediscCache) MDelCtx(ctx context.Context, keys ...string) (err error) {
+	defer cache.ConvRedisError(&err)
+
 	for _, key := range keys {
-		err := c.Del(key)
+		err := c.DelCtx(ctx, key)
 		if err != nil {
 			return err
 		}
@@ -325,11 +609,18 @@ func (c *RediscCache) MDel(keys ...string) error {
 //   返回
 //     递增后的结果，失败返回错误信息
 func (c *RediscCache) Incr(key string, delta ...uint64) (int64, error) {
+	return c.IncrCtx(context.Background(), key, delta...)
+}
+
+// IncrCtx 同Incr，ctx用于控制超时和取消
+func (c *RediscCache) IncrCtx(ctx context.Context, key string, delta ...uint64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	delta = append(delta, 1)
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
-	v, err := c.client.IncrBy(key, int64(delta[0])).Result()
+	v, err := c.getClient(ctx).IncrBy(key, int64(delta[0])).Result()
 	if err != nil {
 		return 0, err
 	}
@@ -345,11 +636,18 @@ func (c *RediscCache) Incr(key string, delta ...uint64) (int64, error) {
 //   返回
 //     递减后的结果，失败返回错误信息
 func (c *RediscCache) Decr(key string, delta ...uint64) (int64, error) {
+	return c.DecrCtx(context.Background(), key, delta...)
+}
+
+// DecrCtx 同Decr，ctx用于控制超时和取消
+func (c *RediscCache) DecrCtx(ctx context.Context, key string, delta ...uint64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	delta = append(delta, 1)
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
-	v, err := c.client.DecrBy(key, int64(delta[0])).Result()
+	v, err := c.getClient(ctx).DecrBy(key, int64(delta[0])).Result()
 	if err != nil {
 		return 0, err
 	}
@@ -357,16 +655,46 @@ func (c *RediscCache) Decr(key string, delta ...uint64) (int64, error) {
 	return v, nil
 }
 
+// IncrEx 缓存里的值自增并设置过期时间，自增和设置过期时间在lua脚本里原子执行
+// key不存在时会新建一个，每次自增都会更新过期时间
+//   参数
+//     key:    递增的key值
+//     delta:  递增的量，负数时为递减
+//     expire: 过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     递增后的结果，失败返回错误信息
+func (c *RediscCache) IncrEx(key string, delta int64, expire int32) (int64, error) {
+	return c.IncrExCtx(context.Background(), key, delta, expire)
+}
+
+// IncrExCtx 同IncrEx，ctx用于控制超时和取消
+func (c *RediscCache) IncrExCtx(ctx context.Context, key string, delta int64, expire int32) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return cache.IncrExScript.Run(c.getClient(ctx), []string{key}, delta, expire).Int64()
+}
+
 // IsExist 判断key值是否存在
 //   参数
 //     key:  要查询的key值
 //   返回
 //     存在返回true，不存在返回false
 func (c *RediscCache) IsExist(key string) (bool, error) {
+	return c.IsExistCtx(context.Background(), key)
+}
+
+// IsExistCtx 同IsExist，ctx用于控制超时和取消
+func (c *RediscCache) IsExistCtx(ctx context.Context, key string) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
-	n, err := c.client.Exists(key).Result()
+	n, err := c.getClient(ctx).Exists(key).Result()
 	if err != nil {
 		return false, err
 	}
@@ -378,108 +706,380 @@ func (c *RediscCache) IsExist(key string) (bool, error) {
 	return false, nil
 }
 
-// ClearAll 清空所有数据
+// ClearAll 清空所有主节点前缀下的数据，使用SCAN分批查找key，UNLINK分批删除，不阻塞服务器
 //   参数
 //
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (c *RediscCache) ClearAll() error {
-	keys, err := c.client.Keys("*").Result()
-	if err != nil {
-		return err
-	}
-
-	return c.client.Del(keys...).Err()
+	return c.ClearAllCtx(context.Background())
 }
 
-// Hset 添加哈希表
-//   参数
-//     key:    哈希表key值
-//     field:  哈希表field值
-//     val:    哈希表value值
-//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
-//   返回
-//     成功时返回添加的个数，失败返回错误信息
-func (c *RediscCache) HSet(key string, field string, val interface{}, expire int32) (int64, error) {
-	// 类型转换
-	data, err := cache.InterToByte(val)
-	if err != nil {
-		return -1, err
-	}
-
-	if c.prefix != "" {
-		key = c.prefix + key
-	}
+// ClearAllCtx 同ClearAll，ctx用于控制超时和取消
+func (c *RediscCache) ClearAllCtx(ctx context.Context) (err error) {
+	defer cache.ConvRedisError(&err)
 
-	err = c.client.HSet(key, field, data).Err()
-	if err != nil {
-		return -1, err
-	}
+	pattern := cache.ScanPattern(c.prefix, "")
+	return c.getClient(ctx).ForEachMaster(func(client *redis.Client) error {
+		client = nodeClient(ctx, client)
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(cursor, pattern, cache.CLEAR_BATCH).Result()
+			if err != nil {
+				return err
+			}
 
-	if expire > 0 {
-		c.client.Expire(key, time.Duration(expire)*time.Second)
-	}
+			// 同一节点的key也可能属于不同的slot，不能用一个UNLINK删除
+			if len(keys) > 0 {
+				pipe := client.Pipeline()
+				for _, key := range keys {
+					pipe.Unlink(key)
+				}
+				_, err = pipe.Exec()
+				if err != nil {
+					return err
+				}
+			}
 
-	return 1, err
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	})
 }
 
-// HGet 查询哈希表数据
+// TTL 查询key的剩余过期时间，精确到毫秒
 //   参数
-//     key:   哈希表key值
-//     field: 哈希表field值
-//     val:   保存结果地址
+//     key: key值
 //   返回
-//     错误信息，是否存在
-func (c *RediscCache) HGet(key string, field string, val interface{}) (error, bool) {
-	if c.prefix != "" {
-		key = c.prefix + key
-	}
+//     剩余过期时间，没有过期时间返回cache.TTL_PERSIST，key不存在返回cache.TTL_NOT_EXIST，失败返回错误信息
+func (c *RediscCache) TTL(key string) (time.Duration, error) {
+	return c.TTLCtx(context.Background(), key)
+}
 
-	v, err := c.client.HGet(key, field).Result()
-	if err != nil {
-		if err.Error() == NOT_EXIST {
-			return nil, false
-		}
-		return err, false
-	}
+// TTLCtx 同TTL，ctx用于控制超时和取消
+func (c *RediscCache) TTLCtx(ctx context.Context, key string) (res time.Duration, err error) {
+	defer cache.ConvRedisError(&err)
 
-	// 类型转换
-	err = cache.ByteToInter([]byte(v), val)
-	if err != nil {
-		return err, true
+	if c.prefix != "" {
+		key = c.prefix + key
 	}
 
-	return nil, true
+	return c.getClient(ctx).PTTL(key).Result()
 }
 
-// HDel 删除哈希表数据
+// Expire 设置key的过期时间，精确到毫秒
 //   参数
-//     key:   哈希表key值
-//     fields: 哈希表field值
+//     key:    key值
+//     expire: 过期时间，小于等于0时删除key
 //   返回
-//     成功返回nil，失败返回错误信息
-func (c *RediscCache) HDel(key string, fields ...string) error {
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (c *RediscCache) Expire(key string, expire time.Duration) (bool, error) {
+	return c.ExpireCtx(context.Background(), key, expire)
+}
+
+// ExpireCtx 同Expire，ctx用于控制超时和取消
+func (c *RediscCache) ExpireCtx(ctx context.Context, key string, expire time.Duration) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.client.HDel(key, fields...).Err()
+	return c.getClient(ctx).PExpire(key, expire).Result()
 }
 
-// HGetAll 返回哈希表 key 中，所有的域和值，struct、map类型需要业务层调用json.Unmarshal
+// ExpireAt 设置key在指定时间过期
+//   参数
+//     key: key值
+//     tm:  过期的时间点，早于当前时间时删除key
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (c *RediscCache) ExpireAt(key string, tm time.Time) (bool, error) {
+	return c.ExpireAtCtx(context.Background(), key, tm)
+}
+
+// ExpireAtCtx 同ExpireAt，ctx用于控制超时和取消
+func (c *RediscCache) ExpireAtCtx(ctx context.Context, key string, tm time.Time) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx).PExpireAt(key, tm).Result()
+}
+
+// Persist 删除key的过期时间，key不再过期
+//   参数
+//     key: key值
+//   返回
+//     删除了过期时间返回true，key不存在或没有过期时间返回false，失败返回错误信息
+func (c *RediscCache) Persist(key string) (bool, error) {
+	return c.PersistCtx(context.Background(), key)
+}
+
+// PersistCtx 同Persist，ctx用于控制超时和取消
+func (c *RediscCache) PersistCtx(ctx context.Context, key string) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx).Persist(key).Result()
+}
+
+// Touch 更新key的过期时间，与Set的expire参数一致
+//   参数
+//     key:    key值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (c *RediscCache) Touch(key string, expire int32) (bool, error) {
+	return c.TouchCtx(context.Background(), key, expire)
+}
+
+// TouchCtx 同Touch，ctx用于控制超时和取消
+func (c *RediscCache) TouchCtx(ctx context.Context, key string, expire int32) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	if expire > 0 {
+		return c.getClient(ctx).Expire(key, time.Duration(expire)*time.Second).Result()
+	}
+
+	// 没有过期时间的key执行persist返回0，需要单独判断key是否存在
+	var exists *redis.IntCmd
+	_, err = c.getClient(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Persist(key)
+		exists = pipe.Exists(key)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return exists.Val() == 1, nil
+}
+
+// GetEx 从缓存取一个值，同时更新过期时间，用于滑动过期，读取和更新过期时间在一次请求里执行
+//   参数
+//     key:    key值
+//     val:    保存结果地址
+//     expire: 新的过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     错误信息，是否存在
+func (c *RediscCache) GetEx(key string, val interface{}, expire int32) (error, bool) {
+	return c.GetExCtx(context.Background(), key, val, expire)
+}
+
+// GetExCtx 同GetEx，ctx用于控制超时和取消
+func (c *RediscCache) GetExCtx(ctx context.Context, key string, val interface{}, expire int32) (err error, exist bool) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	var get *redis.StringCmd
+	_, err = c.getClient(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(key)
+		if expire > 0 {
+			pipe.Expire(key, time.Duration(expire)*time.Second)
+		}
+		return nil
+	})
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, false
+		}
+		return err, false
+	}
+
+	return c.decodeValue(get.Val(), val), true
+}
+
+// Scan 按模式遍历所有主节点前缀下的key
+//   参数
+//     pattern: 匹配模式，不含前缀，为空时匹配所有key
+//     batch:   每次SCAN的key个数，小于等于0时使用redis默认值
+//   返回
+//     迭代器，返回的key已去掉前缀
+func (c *RediscCache) Scan(pattern string, batch int64) cache.Iterator {
+	return c.ScanCtx(context.Background(), pattern, batch)
+}
+
+// ScanCtx 同Scan，ctx用于控制超时和取消
+func (c *RediscCache) ScanCtx(ctx context.Context, pattern string, batch int64) cache.Iterator {
+	var lock sync.Mutex
+	var clients []*redis.Client
+	err := c.getClient(ctx).ForEachMaster(func(client *redis.Client) error {
+		lock.Lock()
+		clients = append(clients, nodeClient(ctx, client))
+		lock.Unlock()
+		return nil
+	})
+	if err != nil {
+		return cache.ErrIterator(err)
+	}
+
+	match := cache.ScanPattern(c.prefix, pattern)
+	scans := make([]cache.ScanFunc, 0, len(clients))
+	for _, client := range clients {
+		client := client
+		scans = append(scans, func(cursor uint64) ([]string, uint64, error) {
+			return client.Scan(cursor, match, batch).Result()
+		})
+	}
+
+	return cache.NewIterator(c.prefix, scans...)
+}
+
+// nodeClient 返回绑定了ctx的节点连接池，ctx为context.Background()时原样返回
+func nodeClient(ctx context.Context, client *redis.Client) *redis.Client {
+	if ctx == nil || ctx == context.Background() {
+		return client
+	}
+
+	return client.WithContext(ctx)
+}
+
+// Hset 添加哈希表
+//   参数
+//     key:    哈希表key值
+//     field:  哈希表field值
+//     val:    哈希表value值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//   返回
+//     成功时返回添加的个数，失败返回错误信息
+func (c *RediscCache) HSet(key string, field string, val interface{}, expire int32) (int64, error) {
+	return c.HSetCtx(context.Background(), key, field, val, expire)
+}
+
+// HSetCtx 同HSet，ctx用于控制超时和取消
+func (c *RediscCache) HSetCtx(ctx context.Context, key string, field string, val interface{}, expire int32) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	// 类型转换
+	data, err := cache.InterToByte(val, c.serializer)
+	if err != nil {
+		return -1, err
+	}
+
+	// 压缩判断
+	data, err = cache.Compress(data, c.compressType, c.compressThreshold)
+	if err != nil {
+		return -1, err
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	// 设置和过期时间在事务里执行
+	_, err = c.getClient(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.HSet(key, field, data)
+		if expire > 0 {
+			pipe.Expire(key, time.Duration(expire)*time.Second)
+		}
+		return nil
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return 1, err
+}
+
+// HGet 查询哈希表数据
+//   参数
+//     key:   哈希表key值
+//     field: 哈希表field值
+//     val:   保存结果地址
+//   返回
+//     错误信息，是否存在
+func (c *RediscCache) HGet(key string, field string, val interface{}) (error, bool) {
+	return c.HGetCtx(context.Background(), key, field, val)
+}
+
+// HGetCtx 同HGet，ctx用于控制超时和取消
+func (c *RediscCache) HGetCtx(ctx context.Context, key string, field string, val interface{}) (err error, exist bool) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	v, err := c.getClient(ctx).HGet(key, field).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, false
+		}
+		return err, false
+	}
+
+	// 解压
+	data, err := cache.Uncompress([]byte(v))
+	if err != nil {
+		return err, true
+	}
+
+	// 类型转换
+	err = cache.ByteToInter(data, val)
+	if err != nil {
+		return err, true
+	}
+
+	return nil, true
+}
+
+// HDel 删除哈希表数据
+//   参数
+//     key:   哈希表key值
+//     fields: 哈希表field值
+//   返回
+//     成功返回nil，失败返回错误信息
+func (c *RediscCache) HDel(key string, fields ...string) error {
+	return c.HDelCtx(context.Background(), key, fields...)
+}
+
+// HDelCtx 同HDel，ctx用于控制超时和取消
+func (c *RediscCache) HDelCtx(ctx context.Context, key string, fields ...string) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx).HDel(key, fields...).Err()
+}
+
+// HGetAll 返回哈希表 key 中，所有的域和值，struct、map类型需要业务层调用json.Unmarshal
 //   参数
 //     key: 有序集合key值
 //   返回
 //     查询的结果数据和错误码
 func (c *RediscCache) HGetAll(key string) (map[string]interface{}, error) {
+	return c.HGetAllCtx(context.Background(), key)
+}
+
+// HGetAllCtx 同HGetAll，ctx用于控制超时和取消
+func (c *RediscCache) HGetAllCtx(ctx context.Context, key string) (res map[string]interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	res := make(map[string]interface{})
-	val, err := c.client.HGetAll(key).Result()
+	res = make(map[string]interface{})
+	val, err := c.getClient(ctx).HGetAll(key).Result()
 	if err != nil {
-		if err.Error() == NOT_EXIST {
+		if cache.IsMiss(err) {
 			return res, nil
 		}
 
@@ -487,7 +1087,11 @@ func (c *RediscCache) HGetAll(key string) (map[string]interface{}, error) {
 	}
 
 	for k, v := range val {
-		res[k] = v
+		// 解压
+		res[k], err = cache.UncompressString(v)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return res, err
@@ -501,17 +1105,24 @@ func (c *RediscCache) HGetAll(key string) (map[string]interface{}, error) {
 //   返回
 //     执行结果
 func (c *RediscCache) HMSet(key string, fields map[string]interface{}, expire int32) error {
+	return c.HMSetCtx(context.Background(), key, fields, expire)
+}
+
+// HMSetCtx 同HMSet，ctx用于控制超时和取消
+func (c *RediscCache) HMSetCtx(ctx context.Context, key string, fields map[string]interface{}, expire int32) (err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	err := c.client.HMSet(key, fields).Err()
+	err = c.getClient(ctx).HMSet(key, fields).Err()
 	if err != nil {
 		return err
 	}
 
 	if expire > 0 {
-		c.client.Expire(key, time.Duration(expire)*time.Second)
+		c.getClient(ctx).Expire(key, time.Duration(expire)*time.Second)
 	}
 
 	return nil
@@ -524,12 +1135,19 @@ func (c *RediscCache) HMSet(key string, fields map[string]interface{}, expire in
 //   返回
 //     查询的结果数据和错误码
 func (c *RediscCache) HMGet(key string, fields ...string) (map[string]interface{}, error) {
+	return c.HMGetCtx(context.Background(), key, fields...)
+}
+
+// HMGetCtx 同HMGet，ctx用于控制超时和取消
+func (c *RediscCache) HMGetCtx(ctx context.Context, key string, fields ...string) (res map[string]interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
-	res := make(map[string]interface{})
+	res = make(map[string]interface{})
 
-	v, err := c.client.HMGet(key, fields...).Result()
+	v, err := c.getClient(ctx).HMGet(key, fields...).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -540,7 +1158,11 @@ func (c *RediscCache) HMGet(key string, fields ...string) (map[string]interface{
 
 	i := 0
 	for _, field := range fields {
-		res[field] = v[i]
+		// 解压
+		res[field], err = cache.UncompressString(v[i])
+		if err != nil {
+			return nil, err
+		}
 		i++
 	}
 
@@ -553,14 +1175,25 @@ func (c *RediscCache) HMGet(key string, fields ...string) (map[string]interface{
 //   返回
 //     查询的结果数据和错误码
 func (c *RediscCache) HVals(key string) ([]interface{}, error) {
-	vals, err := c.client.HVals(key).Result()
+	return c.HValsCtx(context.Background(), key)
+}
+
+// HValsCtx 同HVals，ctx用于控制超时和取消
+func (c *RediscCache) HValsCtx(ctx context.Context, key string) (res []interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	vals, err := c.getClient(ctx).HVals(key).Result()
 	if err != nil {
 		return nil, err
 	}
 
-	res := make([]interface{}, len(vals))
+	res = make([]interface{}, len(vals))
 	for k, v := range vals {
-		res[k] = v
+		// 解压
+		res[k], err = cache.UncompressString(v)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return res, nil
@@ -574,12 +1207,19 @@ func (c *RediscCache) HVals(key string) ([]interface{}, error) {
 //   返回
 //     递增后的结果、失败返回错误信息
 func (c *RediscCache) HIncr(key, fields string, delta ...uint64) (int64, error) {
+	return c.HIncrCtx(context.Background(), key, fields, delta...)
+}
+
+// HIncrCtx 同HIncr，ctx用于控制超时和取消
+func (c *RediscCache) HIncrCtx(ctx context.Context, key, fields string, delta ...uint64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	delta = append(delta, 1)
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.client.HIncrBy(key, fields, int64(delta[0])).Result()
+	return c.getClient(ctx).HIncrBy(key, fields, int64(delta[0])).Result()
 }
 
 // HDecr 哈希表的值自减
@@ -590,12 +1230,19 @@ func (c *RediscCache) HIncr(key, fields string, delta ...uint64) (int64, error)
 //   返回
 //     递减后的结果、失败返回错误信息
 func (c *RediscCache) HDecr(key, fields string, delta ...uint64) (int64, error) {
+	return c.HDecrCtx(context.Background(), key, fields, delta...)
+}
+
+// HDecrCtx 同HDecr，ctx用于控制超时和取消
+func (c *RediscCache) HDecrCtx(ctx context.Context, key, fields string, delta ...uint64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	delta = append(delta, 1)
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.client.HIncrBy(key, fields, 0-int64(delta[0])).Result()
+	return c.getClient(ctx).HIncrBy(key, fields, 0-int64(delta[0])).Result()
 }
 
 // ZSet 添加有序集合
@@ -606,13 +1253,20 @@ func (c *RediscCache) HDecr(key, fields string, delta ...uint64) (int64, error)
 //   返回
 //     成功添加的数据和错误码
 func (c *RediscCache) ZSet(key string, expire int32, val ...interface{}) (int64, error) {
+	return c.ZSetCtx(context.Background(), key, expire, val...)
+}
+
+// ZSetCtx 同ZSet，ctx用于控制超时和取消
+func (c *RediscCache) ZSetCtx(ctx context.Context, key string, expire int32, val ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	valLen := len(val)
 	if valLen < 2 || valLen%2 != 0 {
 		return -1, errors.New("val param error")
 	}
-	vals := []redis.Z{}
+	vals := []*redis.Z{}
 	for i := 0; i < valLen-1; i += 2 {
-		stZ := redis.Z{
+		stZ := &redis.Z{
 			Score:  val[i].(float64),
 			Member: val[i+1],
 		}
@@ -623,16 +1277,19 @@ func (c *RediscCache) ZSet(key string, expire int32, val ...interface{}) (int64,
 		key = c.prefix + key
 	}
 
-	n, err := c.client.ZAdd(key, vals...).Result()
+	var cmd *redis.IntCmd
+	_, err = c.getClient(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		cmd = pipe.ZAdd(key, vals...)
+		if expire > 0 {
+			pipe.Expire(key, time.Duration(expire)*time.Second)
+		}
+		return nil
+	})
 	if err != nil {
 		return -1, err
 	}
 
-	if expire > 0 {
-		c.client.Expire(key, time.Duration(expire)*time.Second)
-	}
-
-	return n, err
+	return cmd.Val(), err
 }
 
 // ZGet 查询有序集合
@@ -645,9 +1302,15 @@ func (c *RediscCache) ZSet(key string, expire int32, val ...interface{}) (int64,
 //   返回
 //     查询的结果数据和错误码
 func (c *RediscCache) ZGet(key string, start, stop int, withScores bool, isRev bool) ([]string, error) {
-	var err error
+	return c.ZGetCtx(context.Background(), key, start, stop, withScores, isRev)
+}
+
+// ZGetCtx 同ZGet，ctx用于控制超时和取消
+func (c *RediscCache) ZGetCtx(ctx context.Context, key string, start, stop int, withScores bool, isRev bool) (res []string, err error) {
+	defer cache.ConvRedisError(&err)
+
 	vals := []redis.Z{}
-	res := []string{}
+	res = []string{}
 
 	if c.prefix != "" {
 		key = c.prefix + key
@@ -656,21 +1319,21 @@ func (c *RediscCache) ZGet(key string, start, stop int, withScores bool, isRev b
 	if isRev {
 
 		if withScores {
-			vals, err = c.client.ZRevRangeWithScores(key, int64(start), int64(stop)).Result()
+			vals, err = c.getClient(ctx).ZRevRangeWithScores(key, int64(start), int64(stop)).Result()
 			if err != nil {
 				return res, err
 			}
 		} else {
-			return c.client.ZRevRange(key, int64(start), int64(stop)).Result()
+			return c.getClient(ctx).ZRevRange(key, int64(start), int64(stop)).Result()
 		}
 	} else {
 		if withScores {
-			vals, err = c.client.ZRangeWithScores(key, int64(start), int64(stop)).Result()
+			vals, err = c.getClient(ctx).ZRangeWithScores(key, int64(start), int64(stop)).Result()
 			if err != nil {
 				return res, err
 			}
 		} else {
-			return c.client.ZRange(key, int64(start), int64(stop)).Result()
+			return c.getClient(ctx).ZRange(key, int64(start), int64(stop)).Result()
 		}
 	}
 
@@ -688,6 +1351,13 @@ func (c *RediscCache) ZGet(key string, start, stop int, withScores bool, isRev b
 //   返回
 //     成功删除的数据个数和错误码
 func (c *RediscCache) ZDel(key string, field ...string) (int64, error) {
+	return c.ZDelCtx(context.Background(), key, field...)
+}
+
+// ZDelCtx 同ZDel，ctx用于控制超时和取消
+func (c *RediscCache) ZDelCtx(ctx context.Context, key string, field ...string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	var args []interface{}
 	for _, f := range field {
 		args = append(args, f)
@@ -696,7 +1366,7 @@ func (c *RediscCache) ZDel(key string, field ...string) (int64, error) {
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
-	return c.client.ZRem(key, args...).Result()
+	return c.getClient(ctx).ZRem(key, args...).Result()
 }
 
 // ZRemRangeByRank 删除指定排名区间内的有序集合数据
@@ -707,11 +1377,18 @@ func (c *RediscCache) ZDel(key string, field ...string) (int64, error) {
 //   返回
 //     成功删除的数据个数和错误码
 func (c *RediscCache) ZRemRangeByRank(key string, start, end int64) (int64, error) {
+	return c.ZRemRangeByRankCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByRankCtx 同ZRemRangeByRank，ctx用于控制超时和取消
+func (c *RediscCache) ZRemRangeByRankCtx(ctx context.Context, key string, start, end int64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.client.ZRemRangeByRank(key, start, end).Result()
+	return c.getClient(ctx).ZRemRangeByRank(key, start, end).Result()
 }
 
 // ZRemRangeByScore 删除指定分值区间内的有序集合数据
@@ -722,11 +1399,18 @@ func (c *RediscCache) ZRemRangeByRank(key string, start, end int64) (int64, erro
 //   返回
 //     成功删除的数据个数和错误码
 func (c *RediscCache) ZRemRangeByScore(key string, start, end string) (int64, error) {
+	return c.ZRemRangeByScoreCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByScoreCtx 同ZRemRangeByScore，ctx用于控制超时和取消
+func (c *RediscCache) ZRemRangeByScoreCtx(ctx context.Context, key string, start, end string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.client.ZRemRangeByScore(key, start, end).Result()
+	return c.getClient(ctx).ZRemRangeByScore(key, start, end).Result()
 }
 
 // ZRemRangeByLex 删除指定变量区间内的有序集合数据
@@ -738,11 +1422,18 @@ func (c *RediscCache) ZRemRangeByScore(key string, start, end string) (int64, er
 //   返回
 //     成功删除的数据个数和错误码
 func (c *RediscCache) ZRemRangeByLex(key string, start, end string) (int64, error) {
+	return c.ZRemRangeByLexCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByLexCtx 同ZRemRangeByLex，ctx用于控制超时和取消
+func (c *RediscCache) ZRemRangeByLexCtx(ctx context.Context, key string, start, end string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.client.ZRemRangeByLex(key, start, end).Result()
+	return c.getClient(ctx).ZRemRangeByLex(key, start, end).Result()
 }
 
 // ZCard 返回有序集 key 的基数
@@ -751,10 +1442,17 @@ func (c *RediscCache) ZRemRangeByLex(key string, start, end string) (int64, erro
 //   返回
 //     有序集 key 的基数和错误码
 func (c *RediscCache) ZCard(key string) (int64, error) {
+	return c.ZCardCtx(context.Background(), key)
+}
+
+// ZCardCtx 同ZCard，ctx用于控制超时和取消
+func (c *RediscCache) ZCardCtx(ctx context.Context, key string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
-	return c.client.ZCard(key).Result()
+	return c.getClient(ctx).ZCard(key).Result()
 }
 
 // SetBit 设置或清除指定偏移量上的位(bit)
@@ -766,20 +1464,27 @@ func (c *RediscCache) ZCard(key string) (int64, error) {
 //   返回
 //     指定偏移量原来储存的位、错误信息
 func (c *RediscCache) SetBit(key string, offset int64, value int, expire int32) (int64, error) {
+	return c.SetBitCtx(context.Background(), key, offset, value, expire)
+}
+
+// SetBitCtx 同SetBit，ctx用于控制超时和取消
+func (c *RediscCache) SetBitCtx(ctx context.Context, key string, offset int64, value int, expire int32) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	res, err := c.client.SetBit(key, offset, value).Result()
-	if err != nil {
-		return res, err
-	}
-
-	if expire > 0 {
-		c.client.Expire(key, time.Duration(expire)*time.Second)
-	}
+	var cmd *redis.IntCmd
+	_, err = c.getClient(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		cmd = pipe.SetBit(key, offset, value)
+		if expire > 0 {
+			pipe.Expire(key, time.Duration(expire)*time.Second)
+		}
+		return nil
+	})
 
-	return res, err
+	return cmd.Val(), err
 }
 
 // GetBit 获取指定偏移量上的位(bit)
@@ -789,11 +1494,18 @@ func (c *RediscCache) SetBit(key string, offset int64, value int, expire int32)
 //   返回
 //     字符串值指定偏移量上的位(bit)、错误信息
 func (c *RediscCache) GetBit(key string, offset int64) (int64, error) {
+	return c.GetBitCtx(context.Background(), key, offset)
+}
+
+// GetBitCtx 同GetBit，ctx用于控制超时和取消
+func (c *RediscCache) GetBitCtx(ctx context.Context, key string, offset int64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.client.GetBit(key, offset).Result()
+	return c.getClient(ctx).GetBit(key, offset).Result()
 }
 
 // BitCount 计算给定字符串中被设置为 1 的比特位的数量
@@ -803,16 +1515,23 @@ func (c *RediscCache) GetBit(key string, offset int64) (int64, error) {
 //   返回
 //     给定字符串中被设置为 1 的比特位的数量、错误信息
 func (c *RediscCache) BitCount(key string, bitCount *cache.BitCount) (int64, error) {
+	return c.BitCountCtx(context.Background(), key, bitCount)
+}
+
+// BitCountCtx 同BitCount，ctx用于控制超时和取消
+func (c *RediscCache) BitCountCtx(ctx context.Context, key string, bitCount *cache.BitCount) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
 	if bitCount != nil {
 		bc := redis.BitCount{Start: bitCount.Start, End: bitCount.End}
-		return c.client.BitCount(key, &bc).Result()
+		return c.getClient(ctx).BitCount(key, &bc).Result()
 	}
 
-	return c.client.BitCount(key, nil).Result()
+	return c.getClient(ctx).BitCount(key, nil).Result()
 }
 
 // PFAdd 添加基数
@@ -824,17 +1543,24 @@ func (c *RediscCache) BitCount(key string, bitCount *cache.BitCount) (int64, err
 //     存在，不做任何事情，返回0；不存在的话就创建，并返回1
 //     错误信息
 func (c *RediscCache) PFAdd(key string, expire int32, vals ...interface{}) (int64, error) {
+	return c.PFAddCtx(context.Background(), key, expire, vals...)
+}
+
+// PFAddCtx 同PFAdd，ctx用于控制超时和取消
+func (c *RediscCache) PFAddCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	res, err := c.client.PFAdd(key, vals...).Result()
+	res, err = c.getClient(ctx).PFAdd(key, vals...).Result()
 	if err != nil {
 		return res, err
 	}
 
 	if expire > 0 {
-		c.client.Expire(key, time.Duration(expire)*time.Second)
+		c.getClient(ctx).Expire(key, time.Duration(expire)*time.Second)
 	}
 
 	return res, err
@@ -846,11 +1572,1077 @@ func (c *RediscCache) PFAdd(key string, expire int32, vals ...interface{}) (int6
 //   返回
 //     基数估算值
 func (c *RediscCache) PFCount(key string) (int64, error) {
+	return c.PFCountCtx(context.Background(), key)
+}
+
+// PFCountCtx 同PFCount，ctx用于控制超时和取消
+func (c *RediscCache) PFCountCtx(ctx context.Context, key string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.client.PFCount(key).Result()
+	return c.getClient(ctx).PFCount(key).Result()
+}
+
+// LPush 从列表头部插入元素
+//   参数
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素，与HSet一致，按序列化和压缩配置转换
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (c *RediscCache) LPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return c.LPushCtx(context.Background(), key, expire, vals...)
+}
+
+// LPushCtx 同LPush，ctx用于控制超时和取消
+func (c *RediscCache) LPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	return c.push(ctx, key, expire, true, vals)
+}
+
+// RPush 从列表尾部插入元素
+//   参数
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素，与HSet一致，按序列化和压缩配置转换
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (c *RediscCache) RPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return c.RPushCtx(context.Background(), key, expire, vals...)
+}
+
+// RPushCtx 同RPush，ctx用于控制超时和取消
+func (c *RediscCache) RPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	return c.push(ctx, key, expire, false, vals)
+}
+
+// push 向列表插入元素
+//   参数
+//     ctx:    上下文
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位
+//     isLeft: 是否从头部插入
+//     vals:   元素
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (c *RediscCache) push(ctx context.Context, key string, expire int32, isLeft bool, vals []interface{}) (int64, error) {
+	args, err := c.toValues(vals)
+	if err != nil {
+		return 0, err
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	var n int64
+	if isLeft {
+		n, err = c.getClient(ctx).LPush(key, args...).Result()
+	} else {
+		n, err = c.getClient(ctx).RPush(key, args...).Result()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if expire > 0 {
+		c.getClient(ctx).Expire(key, time.Duration(expire)*time.Second)
+	}
+
+	return n, nil
+}
+
+// LPop 从列表头部取出一个元素
+//   参数
+//     key: 列表key值
+//     val: 保存结果地址
+//   返回
+//     错误信息，是否存在
+func (c *RediscCache) LPop(key string, val interface{}) (error, bool) {
+	return c.LPopCtx(context.Background(), key, val)
+}
+
+// LPopCtx 同LPop，ctx用于控制超时和取消
+func (c *RediscCache) LPopCtx(ctx context.Context, key string, val interface{}) (err error, exist bool) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	v, err := c.getClient(ctx).LPop(key).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, false
+		}
+		return err, false
+	}
+
+	// 解压
+	data, err := cache.Uncompress([]byte(v))
+	if err != nil {
+		return err, true
+	}
+
+	// 类型转换
+	err = cache.ByteToInter(data, val)
+	if err != nil {
+		return err, true
+	}
+
+	return nil, true
+}
+
+// BRPop 阻塞地从多个列表尾部取出一个元素
+//   参数
+//     timeout: 阻塞时间，以秒为单位，“0”表示一直阻塞
+//     val:     保存结果地址
+//     keys:    列表key值，按顺序检查，必须在同一slot，可以用{}指定hash tag
+//   返回
+//     取到元素的列表key值，超时返回空串，错误信息
+func (c *RediscCache) BRPop(timeout int32, val interface{}, keys ...string) (string, error) {
+	return c.BRPopCtx(context.Background(), timeout, val, keys...)
+}
+
+// BRPopCtx 同BRPop，ctx用于控制超时和取消
+func (c *RediscCache) BRPopCtx(ctx context.Context, timeout int32, val interface{}, keys ...string) (_ string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKeys := make([]string, len(keys))
+	for i, key := range keys {
+		pKeys[i] = c.prefix + key
+	}
+
+	res, err := c.getClient(ctx).BRPop(time.Duration(timeout)*time.Second, pKeys...).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	// 解压
+	data, err := cache.Uncompress([]byte(res[1]))
+	if err != nil {
+		return "", err
+	}
+
+	// 类型转换
+	key := strings.TrimPrefix(res[0], c.prefix)
+	err = cache.ByteToInter(data, val)
+	if err != nil {
+		return key, err
+	}
+
+	return key, nil
+}
+
+// LRange 查询列表指定区间的元素
+//   参数
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
+//   返回
+//     成功时返回元素列表，失败返回错误信息
+func (c *RediscCache) LRange(key string, start, stop int64) ([]interface{}, error) {
+	return c.LRangeCtx(context.Background(), key, start, stop)
+}
+
+// LRangeCtx 同LRange，ctx用于控制超时和取消
+func (c *RediscCache) LRangeCtx(ctx context.Context, key string, start, stop int64) (res []interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	v, err := c.getClient(ctx).LRange(key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	res = make([]interface{}, len(v))
+	for i, item := range v {
+		// 解压
+		res[i], err = cache.UncompressString(item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// LTrim 只保留列表指定区间的元素
+//   参数
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *RediscCache) LTrim(key string, start, stop int64) error {
+	return c.LTrimCtx(context.Background(), key, start, stop)
+}
+
+// LTrimCtx 同LTrim，ctx用于控制超时和取消
+func (c *RediscCache) LTrimCtx(ctx context.Context, key string, start, stop int64) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx).LTrim(key, start, stop).Err()
+}
+
+// SAdd 向集合添加成员
+//   参数
+//     key:     集合key值
+//     expire:  缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     members: 成员，只做序列化，不压缩，保证相同的成员保存的数据相同
+//   返回
+//     成功时返回新添加的个数，失败返回错误信息
+func (c *RediscCache) SAdd(key string, expire int32, members ...interface{}) (int64, error) {
+	return c.SAddCtx(context.Background(), key, expire, members...)
+}
+
+// SAddCtx 同SAdd，ctx用于控制超时和取消
+func (c *RediscCache) SAddCtx(ctx context.Context, key string, expire int32, members ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	args, err := c.toMembers(members)
+	if err != nil {
+		return 0, err
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	n, err := c.getClient(ctx).SAdd(key, args...).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if expire > 0 {
+		c.getClient(ctx).Expire(key, time.Duration(expire)*time.Second)
+	}
+
+	return n, nil
+}
+
+// SRem 删除集合的成员
+//   参数
+//     key:     集合key值
+//     members: 成员
+//   返回
+//     成功时返回删除的个数，失败返回错误信息
+func (c *RediscCache) SRem(key string, members ...interface{}) (int64, error) {
+	return c.SRemCtx(context.Background(), key, members...)
+}
+
+// SRemCtx 同SRem，ctx用于控制超时和取消
+func (c *RediscCache) SRemCtx(ctx context.Context, key string, members ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	args, err := c.toMembers(members)
+	if err != nil {
+		return 0, err
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx).SRem(key, args...).Result()
+}
+
+// SIsMember 判断是否为集合的成员
+//   参数
+//     key:    集合key值
+//     member: 成员
+//   返回
+//     是成员返回true，不是返回false，失败返回错误信息
+func (c *RediscCache) SIsMember(key string, member interface{}) (bool, error) {
+	return c.SIsMemberCtx(context.Background(), key, member)
+}
+
+// SIsMemberCtx 同SIsMember，ctx用于控制超时和取消
+func (c *RediscCache) SIsMemberCtx(ctx context.Context, key string, member interface{}) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	data, err := cache.InterToByte(member, c.serializer)
+	if err != nil {
+		return false, err
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx).SIsMember(key, data).Result()
+}
+
+// SMembers 查询集合的所有成员
+//   参数
+//     key: 集合key值
+//   返回
+//     成功时返回所有成员，失败返回错误信息
+func (c *RediscCache) SMembers(key string) ([]string, error) {
+	return c.SMembersCtx(context.Background(), key)
+}
+
+// SMembersCtx 同SMembers，ctx用于控制超时和取消
+func (c *RediscCache) SMembersCtx(ctx context.Context, key string) (res []string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx).SMembers(key).Result()
+}
+
+// SInter 返回多个集合的交集
+//   参数
+//     keys: 集合key值
+//   返回
+//     成功时返回交集的成员，失败返回错误信息
+func (c *RediscCache) SInter(keys ...string) ([]string, error) {
+	return c.SInterCtx(context.Background(), keys...)
+}
+
+// SInterCtx 同SInter，ctx用于控制超时和取消
+func (c *RediscCache) SInterCtx(ctx context.Context, keys ...string) (res []string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKeys := make([]string, len(keys))
+	for i, key := range keys {
+		pKeys[i] = c.prefix + key
+	}
+
+	res, err = c.getClient(ctx).SInter(pKeys...).Result()
+	if err != nil && strings.HasPrefix(err.Error(), "CROSSSLOT") {
+		// key不在同一slot时在客户端计算
+		return c.setOp(ctx, pKeys, true)
+	}
+
+	return res, err
+}
+
+// SUnion 返回多个集合的并集
+//   参数
+//     keys: 集合key值
+//   返回
+//     成功时返回并集的成员，失败返回错误信息
+func (c *RediscCache) SUnion(keys ...string) ([]string, error) {
+	return c.SUnionCtx(context.Background(), keys...)
+}
+
+// SUnionCtx 同SUnion，ctx用于控制超时和取消
+func (c *RediscCache) SUnionCtx(ctx context.Context, keys ...string) (res []string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKeys := make([]string, len(keys))
+	for i, key := range keys {
+		pKeys[i] = c.prefix + key
+	}
+
+	res, err = c.getClient(ctx).SUnion(pKeys...).Result()
+	if err != nil && strings.HasPrefix(err.Error(), "CROSSSLOT") {
+		// key不在同一slot时在客户端计算
+		return c.setOp(ctx, pKeys, false)
+	}
+
+	return res, err
+}
+
+// XAdd 向流添加消息
+//   参数
+//     key:    流key值
+//     maxLen: 流的最大长度，超过时删除旧消息，为近似值，小于等于0时不限制
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   消息内容，与HSet一致，按序列化和压缩配置转换
+//   返回
+//     成功时返回消息id，失败返回错误信息
+func (c *RediscCache) XAdd(key string, maxLen int64, expire int32, vals map[string]interface{}) (string, error) {
+	return c.XAddCtx(context.Background(), key, maxLen, expire, vals)
+}
+
+// XAddCtx 同XAdd，ctx用于控制超时和取消
+func (c *RediscCache) XAddCtx(ctx context.Context, key string, maxLen int64, expire int32, vals map[string]interface{}) (res string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	values := make(map[string]interface{}, len(vals))
+	for field, val := range vals {
+		args, err := c.toValues([]interface{}{val})
+		if err != nil {
+			return "", err
+		}
+		values[field] = args[0]
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	args := &redis.XAddArgs{Stream: key, Values: values}
+	if maxLen > 0 {
+		args.MaxLenApprox = maxLen
+	}
+	id, err := c.getClient(ctx).XAdd(args).Result()
+	if err != nil {
+		return "", err
+	}
+
+	if expire > 0 {
+		c.getClient(ctx).Expire(key, time.Duration(expire)*time.Second)
+	}
+
+	return id, nil
+}
+
+// XRead 读取流消息
+//   参数
+//     args: 读取参数，key必须在同一slot，Streams前一半为key值，后一半为对应的开始id(不包含)，$表示只读新消息
+//   返回
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (c *RediscCache) XRead(args *cache.XReadArgs) ([]cache.XStream, error) {
+	return c.XReadCtx(context.Background(), args)
+}
+
+// XReadCtx 同XRead，ctx用于控制超时和取消
+func (c *RediscCache) XReadCtx(ctx context.Context, args *cache.XReadArgs) (_ []cache.XStream, err error) {
+	defer cache.ConvRedisError(&err)
+
+	block := args.Block
+	if block <= 0 {
+		block = -1
+	}
+
+	res, err := c.getClient(ctx).XRead(&redis.XReadArgs{
+		Streams: c.prefixStreams(args.Streams),
+		Count:   args.Count,
+		Block:   block,
+	}).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return c.toXStreams(res)
+}
+
+// XGroupCreate 创建消费组，流不存在时自动创建
+//   参数
+//     key:   流key值
+//     group: 消费组
+//     start: 开始读取的消息id，0表示从头开始，为空或$表示只读新消息
+//   返回
+//     成功或消费组已存在时返回nil，失败返回错误信息
+func (c *RediscCache) XGroupCreate(key, group, start string) error {
+	return c.XGroupCreateCtx(context.Background(), key, group, start)
+}
+
+// XGroupCreateCtx 同XGroupCreate，ctx用于控制超时和取消
+func (c *RediscCache) XGroupCreateCtx(ctx context.Context, key, group, start string) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	if start == "" {
+		start = "$"
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	err = c.getClient(ctx).XGroupCreateMkStream(key, group, start).Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+
+	return err
+}
+
+// XReadGroup 按消费组读取流消息
+//   参数
+//     args: 读取参数，key必须在同一slot，Streams前一半为key值，后一半为对应的id，>表示读取未分配给消费者的新消息
+//   返回
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (c *RediscCache) XReadGroup(args *cache.XReadGroupArgs) ([]cache.XStream, error) {
+	return c.XReadGroupCtx(context.Background(), args)
+}
+
+// XReadGroupCtx 同XReadGroup，ctx用于控制超时和取消
+func (c *RediscCache) XReadGroupCtx(ctx context.Context, args *cache.XReadGroupArgs) (_ []cache.XStream, err error) {
+	defer cache.ConvRedisError(&err)
+
+	block := args.Block
+	if block <= 0 {
+		block = -1
+	}
+
+	res, err := c.getClient(ctx).XReadGroup(&redis.XReadGroupArgs{
+		Group:    args.Group,
+		Consumer: args.Consumer,
+		Streams:  c.prefixStreams(args.Streams),
+		Count:    args.Count,
+		Block:    block,
+		NoAck:    args.NoAck,
+	}).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return c.toXStreams(res)
+}
+
+// XAck 确认消费组的消息
+//   参数
+//     key:   流key值
+//     group: 消费组
+//     ids:   消息id
+//   返回
+//     成功时返回确认的个数，失败返回错误信息
+func (c *RediscCache) XAck(key, group string, ids ...string) (int64, error) {
+	return c.XAckCtx(context.Background(), key, group, ids...)
+}
+
+// XAckCtx 同XAck，ctx用于控制超时和取消
+func (c *RediscCache) XAckCtx(ctx context.Context, key, group string, ids ...string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx).XAck(key, group, ids...).Result()
+}
+
+// Eval 执行lua脚本，先使用EVALSHA，脚本未加载时使用EVAL
+// 按第一个key的slot路由到对应的节点，所有key需要在同一个slot，可以使用{hash tag}
+//   参数
+//     script: lua脚本
+//     keys:   脚本的KEYS，会自动添加key前缀，为空时在随机节点执行
+//     args:   脚本的ARGV
+//   返回
+//     脚本的返回值，脚本返回nil时返回nil，失败返回错误信息
+func (c *RediscCache) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.EvalCtx(context.Background(), script, keys, args...)
+}
+
+// EvalCtx 同Eval，ctx用于控制超时和取消
+func (c *RediscCache) EvalCtx(ctx context.Context, script string, keys []string, args ...interface{}) (res interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKeys := c.prefixKeys(keys)
+	if !sameSlot(pKeys) {
+		return nil, errors.New("RediscCache: Eval keys must be in the same slot")
+	}
+
+	return cache.EvalScript(c.getClient(ctx), script, pKeys, args...)
+}
+
+// EvalSha 按sha1执行lua脚本，脚本未加载且执行过Eval或ScriptLoad时使用EVAL重试
+// 按第一个key的slot路由到对应的节点，所有key需要在同一个slot，可以使用{hash tag}
+//   参数
+//     sha1: 脚本的sha1
+//     keys: 脚本的KEYS，会自动添加key前缀，为空时在随机节点执行
+//     args: 脚本的ARGV
+//   返回
+//     脚本的返回值，脚本返回nil时返回nil，失败返回错误信息
+func (c *RediscCache) EvalSha(sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.EvalShaCtx(context.Background(), sha1, keys, args...)
+}
+
+// EvalShaCtx 同EvalSha，ctx用于控制超时和取消
+func (c *RediscCache) EvalShaCtx(ctx context.Context, sha1 string, keys []string, args ...interface{}) (res interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKeys := c.prefixKeys(keys)
+	if !sameSlot(pKeys) {
+		return nil, errors.New("RediscCache: EvalSha keys must be in the same slot")
+	}
+
+	return cache.EvalShaScript(c.getClient(ctx), sha1, pKeys, args...)
+}
+
+// ScriptLoad 在每个主节点上加载lua脚本
+//   参数
+//     script: lua脚本
+//   返回
+//     脚本的sha1，失败返回错误信息
+func (c *RediscCache) ScriptLoad(script string) (string, error) {
+	return c.ScriptLoadCtx(context.Background(), script)
+}
+
+// ScriptLoadCtx 同ScriptLoad，ctx用于控制超时和取消
+func (c *RediscCache) ScriptLoadCtx(ctx context.Context, script string) (res string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	sha := cache.ScriptSha1(script)
+	err = c.getClient(ctx).ForEachMaster(func(client *redis.Client) error {
+		return client.ScriptLoad(script).Err()
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return sha, nil
+}
+
+// prefixKeys 给key添加前缀
+func (c *RediscCache) prefixKeys(keys []string) []string {
+	pKeys := make([]string, len(keys))
+	for i, key := range keys {
+		pKeys[i] = c.prefix + key
+	}
+	return pKeys
+}
+
+// sameSlot 判断key是否都在同一个slot
+func sameSlot(keys []string) bool {
+	for i := 1; i < len(keys); i++ {
+		if keySlot(keys[i]) != keySlot(keys[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// keySlot 计算key所在的slot，与redis集群一致，有{hash tag}时只计算tag部分
+func keySlot(key string) int {
+	if s := strings.IndexByte(key, '{'); s > -1 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			key = key[s+1 : s+e+1]
+		}
+	}
+
+	// CRC16/XMODEM
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % 16384
+}
+
+// setOp 在客户端计算多个集合的交集或并集，用于key不在同一slot的情况
+//   参数
+//     ctx:     上下文
+//     keys:    添加前缀后的集合key值
+//     isInter: true-交集，false-并集
+//   返回
+//     成功时返回成员，失败返回错误信息
+func (c *RediscCache) setOp(ctx context.Context, keys []string, isInter bool) ([]string, error) {
+	count := make(map[string]int)
+	for _, key := range keys {
+		members, err := c.getClient(ctx).SMembers(key).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			count[member]++
+		}
+	}
+
+	res := make([]string, 0, len(count))
+	for member, n := range count {
+		if !isInter || n == len(keys) {
+			res = append(res, member)
+		}
+	}
+
+	return res, nil
+}
+
+// toValues 转换列表和流的元素，与HSet一致，按序列化和压缩配置转换
+func (c *RediscCache) toValues(vals []interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(vals))
+	for i, val := range vals {
+		// 类型转换
+		data, err := cache.InterToByte(val, c.serializer)
+		if err != nil {
+			return nil, err
+		}
+
+		// 压缩判断
+		data, err = cache.Compress(data, c.compressType, c.compressThreshold)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = data
+	}
+
+	return args, nil
+}
+
+// toMembers 转换集合的成员，只做序列化
+func (c *RediscCache) toMembers(members []interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(members))
+	for i, member := range members {
+		data, err := cache.InterToByte(member, c.serializer)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = data
+	}
+
+	return args, nil
+}
+
+// toXStreams 转换读取到的流消息，去掉key前缀并解压消息内容
+func (c *RediscCache) toXStreams(streams []redis.XStream) ([]cache.XStream, error) {
+	res := make([]cache.XStream, len(streams))
+	for i, stream := range streams {
+		res[i].Stream = strings.TrimPrefix(stream.Stream, c.prefix)
+		res[i].Messages = make([]cache.XMessage, len(stream.Messages))
+		for j, msg := range stream.Messages {
+			values := make(map[string]interface{}, len(msg.Values))
+			for field, val := range msg.Values {
+				v, err := cache.UncompressString(val)
+				if err != nil {
+					return nil, err
+				}
+				values[field] = v
+			}
+			res[i].Messages[j] = cache.XMessage{ID: msg.ID, Values: values}
+		}
+	}
+
+	return res, nil
+}
+
+// prefixStreams 给XRead参数中的key添加前缀，前一半为key，后一半为id
+func (c *RediscCache) prefixStreams(streams []string) []string {
+	res := make([]string, len(streams))
+	copy(res, streams)
+	if c.prefix != "" {
+		for i := 0; i < len(res)/2; i++ {
+			res[i] = c.prefix + res[i]
+		}
+	}
+
+	return res
+}
+
+// RunScript 执行lua脚本，优先使用EVALSHA，脚本未加载时使用EVAL
+//   参数
+//     ctx:    上下文
+//     script: lua脚本
+//     keys:   脚本的KEYS，会添加key前缀
+//     args:   脚本的ARGV
+//   返回
+//     脚本的返回值，失败返回错误信息
+func (c *RediscCache) RunScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (res interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	return script.Run(c.getClient(ctx), c.prefixKeys(keys), args...).Result()
+}
+
+// AcquireLock 获取分布式锁，使用SET NX PX，由cache/lock包使用
+//   参数
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 持有者的随机token
+//     ttl:   锁的过期时间
+//   返回
+//     获取到返回true，锁已存在返回false，失败返回错误信息
+func (c *RediscCache) AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx).SetNX(key, token, ttl).Result()
+}
+
+// ReleaseLock 释放分布式锁，token一致时才删除
+//   参数
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 持有者的随机token
+//   返回
+//     删除成功返回true，锁不存在或不是持有者返回false，失败返回错误信息
+func (c *RediscCache) ReleaseLock(ctx context.Context, key, token string) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	n, err := cache.LockReleaseScript.Run(c.getClient(ctx), []string{key}, token).Int64()
+	return n == 1, err
+}
+
+// RefreshLock 更新分布式锁的过期时间，token一致时才更新
+//   参数
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 持有者的随机token
+//     ttl:   新的过期时间
+//   返回
+//     更新成功返回true，锁不存在或不是持有者返回false，失败返回错误信息
+func (c *RediscCache) RefreshLock(ctx context.Context, key, token string, ttl time.Duration) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	n, err := cache.LockRefreshScript.Run(c.getClient(ctx), []string{key}, token, ttl.Milliseconds()).Int64()
+	return n == 1, err
+}
+
+// Publish 向频道发布消息，集群会把消息广播到所有节点
+//   参数
+//     channel: 频道，不添加key前缀
+//     msg:     消息内容
+//   返回
+//     收到消息的订阅者数、错误信息
+func (c *RediscCache) Publish(channel string, msg interface{}) (int64, error) {
+	return c.PublishCtx(context.Background(), channel, msg)
+}
+
+// PublishCtx 同Publish，ctx用于控制超时和取消
+func (c *RediscCache) PublishCtx(ctx context.Context, channel string, msg interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	return c.getClient(ctx).Publish(channel, msg).Result()
+}
+
+// Subscribe 订阅频道，按频道的slot分组，每组连接slot所在的主节点，断线或迁移后自动重连并重新订阅
+//   参数
+//     ctx:      上下文，取消时取消订阅并关闭返回的channel
+//     channels: 频道，不添加key前缀
+//   返回
+//     消息channel、错误信息
+func (c *RediscCache) Subscribe(ctx context.Context, channels ...string) (_ <-chan *cache.Message, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if len(channels) == 0 {
+		return nil, errors.New("RediscCache: Subscribe channels is empty")
+	}
+
+	slots := make(map[int][]string)
+	var order []int
+	for _, channel := range channels {
+		slot := keySlot(channel)
+		if _, ok := slots[slot]; !ok {
+			order = append(order, slot)
+		}
+		slots[slot] = append(slots[slot], channel)
+	}
+
+	pubsubs := make([]*redis.PubSub, 0, len(order))
+	for _, slot := range order {
+		pubsubs = append(pubsubs, c.client.Subscribe(slots[slot]...))
+	}
+
+	return cache.ReceiveMessages(ctx, "", pubsubs...)
+}
+
+// SubscribeExpired 订阅带key前缀的key的过期通知，键空间通知只在本节点发送，所以每个主节点都订阅
+// redis需要配置notify-keyspace-events包含Ex，订阅后新增的主节点不会订阅
+//   参数
+//     ctx: 上下文，取消时取消订阅并关闭返回的channel
+//   返回
+//     消息channel，Payload为不含前缀的key，失败返回错误信息
+func (c *RediscCache) SubscribeExpired(ctx context.Context) (_ <-chan *cache.Message, err error) {
+	defer cache.ConvRedisError(&err)
+
+	var lock sync.Mutex
+	var pubsubs []*redis.PubSub
+	err = c.client.ForEachMaster(func(client *redis.Client) error {
+		ps := client.PSubscribe(cache.KeyEventExpired)
+		lock.Lock()
+		pubsubs = append(pubsubs, ps)
+		lock.Unlock()
+		return nil
+	})
+	if err != nil {
+		for _, ps := range pubsubs {
+			ps.Close()
+		}
+		return nil, err
+	}
+
+	return cache.ReceiveMessages(ctx, c.prefix, pubsubs...)
+}
+
+// Batch 新建批量命令，key前缀、序列化、压缩和加密与Set、Get等方法一致
+// 命令按key所在的节点分组执行，事务模式下只保证同一slot的命令在一个MULTI/EXEC中
+//   参数
+//     isTx: 是否事务模式，为true时使用MULTI/EXEC执行
+//   返回
+//     批量命令
+func (c *RediscCache) Batch(isTx bool) *cache.Batch {
+	return cache.NewBatch(isTx, c.execBatch)
+}
+
+// execBatch 使用pipeline执行批量命令
+func (c *RediscCache) execBatch(ctx context.Context, cmds []*cache.BatchCmd, isTx bool) error {
+	var pipe redis.Pipeliner
+	if isTx {
+		pipe = c.getClient(ctx).TxPipeline()
+	} else {
+		pipe = c.getClient(ctx).Pipeline()
+	}
+
+	res, err := c.queueBatch(pipe, cmds, isTx)
+	if err != nil {
+		return err
+	}
+
+	// 每个命令的错误由batchResult处理
+	pipe.Exec()
+
+	return c.batchResult(cmds, res)
+}
+
+// queueBatch 将批量命令加入pipeline，返回每个命令对应的redis命令
+// 值转换出错时，事务模式直接返回错误，否则只设置该命令的错误
+//   参数
+//     pipe: pipeline
+//     cmds: 批量命令
+//     isTx: 是否事务模式
+//   返回
+//     每个命令对应的redis命令，出错的命令为nil，失败返回错误信息
+func (c *RediscCache) queueBatch(pipe redis.Pipeliner, cmds []*cache.BatchCmd, isTx bool) ([]redis.Cmder, error) {
+	res := make([]redis.Cmder, len(cmds))
+	for i, cmd := range cmds {
+		key := cmd.Key
+		if c.prefix != "" {
+			key = c.prefix + key
+		}
+
+		switch cmd.Op {
+		case cache.BatchSet, cache.BatchHSet:
+			// 类型转换
+			data, err := cache.InterToByte(cmd.Val, c.serializer)
+
+			// 压缩判断
+			if err == nil {
+				data, err = cache.Compress(data, c.compressType, c.compressThreshold)
+			}
+
+			// 加密判断，哈希表不加密
+			if err == nil && cmd.Op == cache.BatchSet && cmd.Encode {
+				data, err = cache.Encode(data, c.encodeKey...)
+			}
+
+			if err != nil {
+				if isTx {
+					return nil, err
+				}
+				cmd.SetResult(nil, 0, false, err)
+				continue
+			}
+
+			if cmd.Op == cache.BatchSet {
+				res[i] = pipe.Set(key, data, time.Duration(cmd.Expire)*time.Second)
+			} else {
+				res[i] = pipe.HSet(key, cmd.Fields[0], data)
+				if cmd.Expire > 0 {
+					pipe.Expire(key, time.Duration(cmd.Expire)*time.Second)
+				}
+			}
+		case cache.BatchGet:
+			res[i] = pipe.Get(key)
+		case cache.BatchDel:
+			res[i] = pipe.Del(key)
+		case cache.BatchIncr:
+			res[i] = pipe.IncrBy(key, cmd.Delta)
+		case cache.BatchExpire:
+			res[i] = pipe.Expire(key, time.Duration(cmd.Expire)*time.Second)
+		case cache.BatchHGet:
+			res[i] = pipe.HGet(key, cmd.Fields[0])
+		case cache.BatchHDel:
+			res[i] = pipe.HDel(key, cmd.Fields...)
+		case cache.BatchHIncr:
+			res[i] = pipe.HIncrBy(key, cmd.Fields[0], cmd.Delta)
+		default:
+			err := cache.NewError(cache.ErrUnsupported, fmt.Sprintf("RediscCache: Batch don't support %s", cmd.Op))
+			if isTx {
+				return nil, err
+			}
+			cmd.SetResult(nil, 0, false, err)
+		}
+	}
+
+	return res, nil
+}
+
+// batchResult 设置批量命令的执行结果，Get的结果解密后解压，HGet的结果只解压
+//   参数
+//     cmds: 批量命令
+//     res:  每个命令对应的redis命令
+//   返回
+//     第一个出错命令的错误信息，key不存在不算错误
+func (c *RediscCache) batchResult(cmds []*cache.BatchCmd, res []redis.Cmder) error {
+	var firstErr error
+	for i, cmd := range cmds {
+		switch r := res[i].(type) {
+		case nil:
+			// 加入pipeline前已出错
+		case *redis.StringCmd:
+			v, err := r.Result()
+			if err != nil {
+				if cache.IsMiss(err) {
+					cmd.SetResult(nil, 0, false, nil)
+				} else {
+					cmd.SetResult(nil, 0, false, err)
+				}
+				break
+			}
+
+			data := []byte(v)
+			if cmd.Op == cache.BatchGet {
+				data, err = cache.Decode(data, c.encodeKey...)
+			}
+			if err == nil {
+				data, err = cache.Uncompress(data)
+			}
+			cmd.SetResult(data, 0, true, err)
+		case *redis.IntCmd:
+			v, err := r.Result()
+			cmd.SetResult(nil, v, err == nil, err)
+		case *redis.BoolCmd:
+			v, err := r.Result()
+			var n int64
+			if v {
+				n = 1
+			}
+			cmd.SetResult(nil, n, v, err)
+		default:
+			err := r.Err()
+			cmd.SetResult(nil, 0, err == nil, err)
+		}
+
+		if err := cmd.Err(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
 }
 
 // Pipeline 执行pipeline命令
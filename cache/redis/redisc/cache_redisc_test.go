@@ -1,8 +1,14 @@
 package redisc
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/lixy529/gotools/cache"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -942,3 +948,812 @@ func TestRediscPipeline(t *testing.T) {
 	}
 	fmt.Println("r1:", r1.Val(), "r2:", r2.Val(), "r3:", r3.Val(), "r4:", r4.Val())
 }
+
+func TestRediscCtx(t *testing.T) {
+	var adapter cache.ContextCache = &RediscCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	k1 := "ctx_k1"
+	v1 := "HelloWorld"
+	err = adapter.SetCtx(ctx, k1, v1, 10)
+	if err != nil {
+		t.Errorf("Redisc SetCtx failed. err: %s.", err.Error())
+		return
+	}
+
+	var v11 string
+	err, _ = adapter.GetCtx(ctx, k1, &v11)
+	if err != nil {
+		t.Errorf("Redisc GetCtx failed. err: %s.", err.Error())
+		return
+	} else if v11 != v1 {
+		t.Errorf("Redisc GetCtx failed. Got %s, expected %s.", v11, v1)
+		return
+	}
+
+	// 已取消的ctx
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	cancel2()
+	err, _ = adapter.GetCtx(ctx2, k1, &v11)
+	if err == nil {
+		t.Errorf("Redisc GetCtx failed. expected context error.")
+		return
+	}
+}
+
+func TestRediscCompress(t *testing.T) {
+	mapCfg := make(map[string]string)
+	json.Unmarshal([]byte(gConfig), &mapCfg)
+	mapCfg["compressType"] = "snappy"
+	mapCfg["compressThreshold"] = "10"
+	config, _ := json.Marshal(mapCfg)
+
+	adapter := &RediscCache{}
+	err := adapter.Init(string(config))
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+	plain := &RediscCache{}
+	plain.Init(gConfig)
+
+	k1 := "cmp_k1"
+	v1 := User{Id: 1001, Name: "HelloWorld HelloWorld HelloWorld"}
+	err = adapter.Set(k1, v1, 60)
+	if err != nil {
+		t.Errorf("Redisc Set failed. err: %s.", err.Error())
+		return
+	}
+
+	// 保存的数据是压缩过的
+	raw, _ := adapter.getClient(nil).Get("le_" + k1).Result()
+	if len(raw) < cache.COMPRESS_LEN || raw[:cache.COMPRESS_LEN] != cache.COMPRESS_FLAG+"s" {
+		t.Errorf("Redisc Set failed. value is not compressed: %q.", raw)
+		return
+	}
+
+	var v11 User
+	err, _ = adapter.Get(k1, &v11)
+	if err != nil {
+		t.Errorf("Redisc Get failed. err: %s.", err.Error())
+		return
+	} else if v11.Id != v1.Id || v11.Name != v1.Name {
+		t.Errorf("Redisc Get failed. Got %d-%s, expected %d-%s.", v11.Id, v11.Name, v1.Id, v1.Name)
+		return
+	}
+
+	// 没配置压缩的也能读
+	err, _ = plain.Get(k1, &v11)
+	if err != nil || v11.Name != v1.Name {
+		t.Errorf("Redisc Get failed. Got %s, err: %v.", v11.Name, err)
+		return
+	}
+
+	mList, err := adapter.MGet(k1)
+	if err != nil {
+		t.Errorf("Redisc MGet failed. err: %s.", err.Error())
+		return
+	} else if s, _ := mList[k1].(string); len(s) == 0 || s[0] != '{' {
+		t.Errorf("Redisc MGet failed. Got %v.", mList[k1])
+		return
+	}
+
+	// 哈希表
+	k2 := "cmp_h1"
+	v2 := "HelloWorld HelloWorld HelloWorld"
+	_, err = adapter.HSet(k2, "f1", v2, 60)
+	if err != nil {
+		t.Errorf("Redisc HSet failed. err: %s.", err.Error())
+		return
+	}
+	var v22 string
+	err, _ = adapter.HGet(k2, "f1", &v22)
+	if err != nil || v22 != v2 {
+		t.Errorf("Redisc HGet failed. Got %s, err: %v.", v22, err)
+		return
+	}
+	hList, err := adapter.HGetAll(k2)
+	if err != nil || hList["f1"] != v2 {
+		t.Errorf("Redisc HGetAll failed. Got %v, err: %v.", hList["f1"], err)
+		return
+	}
+
+	adapter.Del(k1)
+	adapter.Del(k2)
+}
+
+func TestRediscScan(t *testing.T) {
+	adapter := &RediscCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+
+	// 其它前缀的数据
+	mapCfg := make(map[string]string)
+	json.Unmarshal([]byte(gConfig), &mapCfg)
+	mapCfg["prefix"] = "other_"
+	config, _ := json.Marshal(mapCfg)
+	other := &RediscCache{}
+	other.Init(string(config))
+	other.Set("scan_k1", "v1", 60)
+	defer other.Del("scan_k1")
+
+	for i := 0; i < 20; i++ {
+		adapter.Set(fmt.Sprintf("scan_k%d", i), i, 60)
+	}
+	adapter.Set("noscan_k1", "v1", 60)
+
+	// Scan返回的key不含前缀
+	keys := make(map[string]bool)
+	it := adapter.Scan("scan_*", 5)
+	for it.Next() {
+		keys[it.Key()] = true
+	}
+	if it.Err() != nil {
+		t.Errorf("Redisc Scan failed. err: %s.", it.Err().Error())
+		return
+	} else if len(keys) != 20 || !keys["scan_k0"] || !keys["scan_k19"] {
+		t.Errorf("Redisc Scan failed. Got %d keys, expected 20.", len(keys))
+		return
+	}
+
+	// ClearAll只删除本前缀的数据
+	err = adapter.ClearAll()
+	if err != nil {
+		t.Errorf("Redisc ClearAll failed. err: %s.", err.Error())
+		return
+	}
+	if ok, _ := adapter.IsExist("noscan_k1"); ok {
+		t.Error("Redisc ClearAll failed. noscan_k1 is exist.")
+	}
+	if ok, _ := other.IsExist("scan_k1"); !ok {
+		t.Error("Redisc ClearAll failed. other_scan_k1 is deleted.")
+	}
+}
+
+func TestRediscBatch(t *testing.T) {
+	mapCfg := make(map[string]string)
+	json.Unmarshal([]byte(gConfig), &mapCfg)
+	mapCfg["encodeKey"] = "abcdefghij123456"
+	config, _ := json.Marshal(mapCfg)
+
+	adapter := &RediscCache{}
+	err := adapter.Init(string(config))
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.MDel("batch_k1", "batch_k2", "batch_n1", "batch_h1")
+
+	for i, isTx := range []bool{false, true} {
+		b := adapter.Batch(isTx)
+		b.Set("batch_k1", User{Id: 1001, Name: "Diego"}, 60, true)
+		b.HSet("batch_h1", "f1", "hv1", 60)
+		get := b.Get("batch_k1")
+		miss := b.Get("batch_k2")
+		hget := b.HGet("batch_h1", "f1")
+		incr := b.Incr("batch_n1", 2)
+		decr := b.Decr("batch_n1")
+		if b.Len() != 7 {
+			t.Errorf("Redisc Batch failed. Got %d commands, expected 7.", b.Len())
+		}
+		err = b.Exec()
+		if err != nil {
+			t.Errorf("Redisc Batch Exec failed. err: %s.", err.Error())
+			return
+		}
+
+		var u User
+		err, exist := get.Scan(&u)
+		if err != nil || !exist || u.Id != 1001 || u.Name != "Diego" {
+			t.Errorf("Redisc Batch Get failed. Got %v, expected 1001-Diego.", u)
+		}
+		var s string
+		if err, exist = miss.Scan(&s); err != nil || exist {
+			t.Errorf("Redisc Batch Get failed. batch_k2 is exist.")
+		}
+		if err, exist = hget.Scan(&s); err != nil || !exist || s != "hv1" {
+			t.Errorf("Redisc Batch HGet failed. Got %s, expected hv1.", s)
+		}
+		if n, _ := incr.Int(); n != int64(2+i) {
+			t.Errorf("Redisc Batch Incr failed. Got %d, expected %d.", n, 2+i)
+		}
+		if n, _ := decr.Int(); n != int64(1+i) {
+			t.Errorf("Redisc Batch Decr failed. Got %d, expected %d.", n, 1+i)
+		}
+	}
+
+	// 与普通方法使用相同的前缀和加密
+	var u User
+	err, exist := adapter.Get("batch_k1", &u)
+	if err != nil || !exist || u.Id != 1001 {
+		t.Errorf("Redisc Get failed. Got %v, expected 1001.", u)
+	}
+
+	// 队列执行后清空
+	b := adapter.Batch(false)
+	b.Del("batch_k1")
+	b.Exec()
+	if b.Len() != 0 {
+		t.Errorf("Redisc Batch failed. Got %d commands, expected 0.", b.Len())
+	}
+	if ok, _ := adapter.IsExist("batch_k1"); ok {
+		t.Error("Redisc Batch Del failed. batch_k1 is exist.")
+	}
+	adapter.MDel("batch_n1", "batch_h1")
+}
+
+func TestRediscList(t *testing.T) {
+	mapCfg := make(map[string]string)
+	json.Unmarshal([]byte(gConfig), &mapCfg)
+	mapCfg["compressType"] = "snappy"
+	mapCfg["compressThreshold"] = "10"
+	config, _ := json.Marshal(mapCfg)
+
+	adapter := &RediscCache{}
+	err := adapter.Init(string(config))
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.MDel("list_l1", "list_l2")
+
+	n, err := adapter.RPush("list_l1", 60, "v1", "v2")
+	if err != nil || n != 2 {
+		t.Errorf("Redisc RPush failed. Got %d, expected 2.", n)
+	}
+	n, err = adapter.LPush("list_l1", 60, strings.Repeat("a", 100), "v0")
+	if err != nil || n != 4 {
+		t.Errorf("Redisc LPush failed. Got %d, expected 4.", n)
+	}
+
+	vals, err := adapter.LRange("list_l1", 0, -1)
+	if err != nil || len(vals) != 4 || vals[0] != "v0" || vals[1] != strings.Repeat("a", 100) || vals[3] != "v2" {
+		t.Errorf("Redisc LRange failed. Got %v.", vals)
+	}
+
+	err = adapter.LTrim("list_l1", 0, 2)
+	if err != nil {
+		t.Errorf("Redisc LTrim failed. err: %s.", err.Error())
+	}
+
+	var s string
+	err, exist := adapter.LPop("list_l1", &s)
+	if err != nil || !exist || s != "v0" {
+		t.Errorf("Redisc LPop failed. Got %s, expected v0.", s)
+	}
+
+	key, err := adapter.BRPop(1, &s, "list_l2", "list_l1")
+	if err != nil || key != "list_l1" || s != "v1" {
+		t.Errorf("Redisc BRPop failed. Got %s-%s, expected list_l1-v1.", key, s)
+	}
+
+	key, err = adapter.BRPop(1, &s, "list_l2")
+	if err != nil || key != "" {
+		t.Errorf("Redisc BRPop failed. Got %s, expected empty.", key)
+	}
+
+	adapter.MDel("list_l1")
+	err, exist = adapter.LPop("list_l1", &s)
+	if err != nil || exist {
+		t.Errorf("Redisc LPop failed. list_l1 is exist.")
+	}
+}
+
+func TestRediscSAdd(t *testing.T) {
+	adapter := &RediscCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.MDel("set_s1", "set_s2")
+
+	n, err := adapter.SAdd("set_s1", 60, "a", "b", "c", "a")
+	if err != nil || n != 3 {
+		t.Errorf("Redisc SAdd failed. Got %d, expected 3.", n)
+	}
+	n, err = adapter.SAdd("set_s2", 60, "b", "c", "d")
+	if err != nil || n != 3 {
+		t.Errorf("Redisc SAdd failed. Got %d, expected 3.", n)
+	}
+
+	ok, err := adapter.SIsMember("set_s1", "a")
+	if err != nil || !ok {
+		t.Errorf("Redisc SIsMember failed. a is not member.")
+	}
+
+	n, err = adapter.SRem("set_s1", "a", "x")
+	if err != nil || n != 1 {
+		t.Errorf("Redisc SRem failed. Got %d, expected 1.", n)
+	}
+
+	ok, err = adapter.SIsMember("set_s1", "a")
+	if err != nil || ok {
+		t.Errorf("Redisc SIsMember failed. a is member.")
+	}
+
+	members, err := adapter.SMembers("set_s1")
+	sort.Strings(members)
+	if err != nil || strings.Join(members, ",") != "b,c" {
+		t.Errorf("Redisc SMembers failed. Got %v, expected [b c].", members)
+	}
+
+	members, err = adapter.SInter("set_s1", "set_s2")
+	sort.Strings(members)
+	if err != nil || strings.Join(members, ",") != "b,c" {
+		t.Errorf("Redisc SInter failed. Got %v, expected [b c].", members)
+	}
+
+	members, err = adapter.SUnion("set_s1", "set_s2")
+	sort.Strings(members)
+	if err != nil || strings.Join(members, ",") != "b,c,d" {
+		t.Errorf("Redisc SUnion failed. Got %v, expected [b c d].", members)
+	}
+}
+
+func TestRediscStream(t *testing.T) {
+	adapter := &RediscCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.MDel("stream_x1")
+
+	id1, err := adapter.XAdd("stream_x1", 100, 60, map[string]interface{}{"name": "Diego", "age": 20})
+	if err != nil || id1 == "" {
+		t.Errorf("Redisc XAdd failed. err: %v.", err)
+		return
+	}
+	id2, err := adapter.XAdd("stream_x1", 100, 60, map[string]interface{}{"name": "Lily"})
+	if err != nil || id2 == "" {
+		t.Errorf("Redisc XAdd failed. err: %v.", err)
+		return
+	}
+
+	res, err := adapter.XRead(&cache.XReadArgs{Streams: []string{"stream_x1", "0"}})
+	if err != nil || len(res) != 1 || res[0].Stream != "stream_x1" || len(res[0].Messages) != 2 {
+		t.Errorf("Redisc XRead failed. Got %v, err: %v.", res, err)
+		return
+	}
+	msg := res[0].Messages[0]
+	if msg.ID != id1 || msg.Values["name"] != "Diego" || msg.Values["age"] != "20" {
+		t.Errorf("Redisc XRead failed. Got %v.", msg)
+	}
+
+	res, err = adapter.XRead(&cache.XReadArgs{Streams: []string{"stream_x1", id2}})
+	if err != nil || len(res) != 0 {
+		t.Errorf("Redisc XRead failed. Got %v, expected empty.", res)
+	}
+
+	err = adapter.XGroupCreate("stream_x1", "g1", "0")
+	if err != nil {
+		t.Errorf("Redisc XGroupCreate failed. err: %s.", err.Error())
+	}
+	err = adapter.XGroupCreate("stream_x1", "g1", "0")
+	if err != nil {
+		t.Errorf("Redisc XGroupCreate failed. err: %s.", err.Error())
+	}
+
+	res, err = adapter.XReadGroup(&cache.XReadGroupArgs{Group: "g1", Consumer: "c1", Streams: []string{"stream_x1", ">"}, Count: 1})
+	if err != nil || len(res) != 1 || len(res[0].Messages) != 1 || res[0].Messages[0].ID != id1 {
+		t.Errorf("Redisc XReadGroup failed. Got %v, err: %v.", res, err)
+	}
+
+	n, err := adapter.XAck("stream_x1", "g1", id1)
+	if err != nil || n != 1 {
+		t.Errorf("Redisc XAck failed. Got %d, expected 1.", n)
+	}
+
+	res, err = adapter.XReadGroup(&cache.XReadGroupArgs{Group: "g1", Consumer: "c1", Streams: []string{"stream_x1", ">"}})
+	if err != nil || len(res) != 1 || len(res[0].Messages) != 1 || res[0].Messages[0].ID != id2 {
+		t.Errorf("Redisc XReadGroup failed. Got %v, err: %v.", res, err)
+	}
+}
+
+func TestRediscLock(t *testing.T) {
+	adapter := &RediscCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("lock_k1")
+
+	ctx := context.Background()
+	ok, err := adapter.AcquireLock(ctx, "lock_k1", "token1", 500*time.Millisecond)
+	if err != nil || !ok {
+		t.Errorf("Redisc AcquireLock failed. err: %v.", err)
+		return
+	}
+	ok, err = adapter.AcquireLock(ctx, "lock_k1", "token2", time.Second)
+	if err != nil || ok {
+		t.Errorf("Redisc AcquireLock failed. lock_k1 is not locked.")
+	}
+
+	ok, err = adapter.RefreshLock(ctx, "lock_k1", "token2", time.Second)
+	if err != nil || ok {
+		t.Errorf("Redisc RefreshLock failed. token2 is not owner.")
+	}
+	ok, err = adapter.RefreshLock(ctx, "lock_k1", "token1", 2*time.Second)
+	if err != nil || !ok {
+		t.Errorf("Redisc RefreshLock failed. err: %v.", err)
+	}
+
+	ok, err = adapter.ReleaseLock(ctx, "lock_k1", "token2")
+	if err != nil || ok {
+		t.Errorf("Redisc ReleaseLock failed. token2 is not owner.")
+	}
+	ok, err = adapter.ReleaseLock(ctx, "lock_k1", "token1")
+	if err != nil || !ok {
+		t.Errorf("Redisc ReleaseLock failed. err: %v.", err)
+	}
+
+	ok, err = adapter.AcquireLock(ctx, "lock_k1", "token2", time.Second)
+	if err != nil || !ok {
+		t.Errorf("Redisc AcquireLock failed. err: %v.", err)
+	}
+	adapter.Del("lock_k1")
+}
+
+func TestRediscEval(t *testing.T) {
+	adapter := &RediscCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("eval_k1")
+
+	// IncrEx
+	n, err := adapter.IncrEx("eval_k1", 5, 100)
+	if err != nil || n != 5 {
+		t.Errorf("Redisc IncrEx failed. Got %d, expected 5, err: %v.", n, err)
+	}
+	n, err = adapter.IncrEx("eval_k1", -2, 100)
+	if err != nil || n != 3 {
+		t.Errorf("Redisc IncrEx failed. Got %d, expected 3, err: %v.", n, err)
+	}
+	ttl, err := adapter.client.TTL("le_eval_k1").Result()
+	if err != nil || ttl <= 0 || ttl > 100*time.Second {
+		t.Errorf("Redisc IncrEx failed. TTL got %v, err: %v.", ttl, err)
+	}
+
+	// Eval，KEYS添加前缀
+	script := `redis.call("set", KEYS[1], ARGV[1]) return KEYS[1]`
+	v, err := adapter.Eval(script, []string{"eval_k1"}, "v1")
+	if err != nil || v != "le_eval_k1" {
+		t.Errorf("Redisc Eval failed. Got %v, expected le_eval_k1, err: %v.", v, err)
+	}
+	v, err = adapter.Eval(`return redis.call("get", KEYS[1])`, []string{"eval_k1"})
+	if err != nil || v != "v1" {
+		t.Errorf("Redisc Eval failed. Got %v, expected v1, err: %v.", v, err)
+	}
+	v, err = adapter.Eval(`return nil`, []string{"eval_k1"})
+	if err != nil || v != nil {
+		t.Errorf("Redisc Eval failed. Got %v, expected nil, err: %v.", v, err)
+	}
+
+	// EvalSha，脚本被清除后使用EVAL重试
+	sha, err := adapter.ScriptLoad(`return redis.call("incrby", KEYS[1], ARGV[1])`)
+	if err != nil || len(sha) != 40 {
+		t.Errorf("Redisc ScriptLoad failed. Got %s, err: %v.", sha, err)
+		return
+	}
+	adapter.Del("eval_k1")
+	v, err = adapter.EvalSha(sha, []string{"eval_k1"}, 2)
+	if err != nil || v != int64(2) {
+		t.Errorf("Redisc EvalSha failed. Got %v, expected 2, err: %v.", v, err)
+	}
+	adapter.client.ForEachMaster(func(client *redis.Client) error {
+		return client.ScriptFlush().Err()
+	})
+	v, err = adapter.EvalSha(sha, []string{"eval_k1"}, 3)
+	if err != nil || v != int64(5) {
+		t.Errorf("Redisc EvalSha failed. Got %v, expected 5, err: %v.", v, err)
+	}
+	_, err = adapter.EvalSha("0000000000000000000000000000000000000000", []string{"eval_k1"})
+	if !cache.IsNoScript(err) {
+		t.Errorf("Redisc EvalSha failed. Got %v, expected NOSCRIPT.", err)
+	}
+
+	// key不在同一个slot，使用hash tag时在同一个slot
+	_, err = adapter.Eval(script, []string{"eval_k1", "eval_k2"}, "v1")
+	if err == nil {
+		t.Errorf("Redisc Eval failed. keys are not in the same slot.")
+	}
+	_, err = adapter.Eval(script, []string{"{eval}k1", "{eval}k2"}, "v1")
+	if err != nil {
+		t.Errorf("Redisc Eval failed. err: %v.", err)
+	}
+	if keySlot("123456789") != 12739 || keySlot("{user1000}.following") != keySlot("{user1000}.followers") {
+		t.Errorf("Redisc keySlot failed. Got %d, expected 12739.", keySlot("123456789"))
+	}
+	adapter.MDel("{eval}k1", "{eval}k2")
+	adapter.Del("eval_k1")
+}
+
+func TestRediscCas(t *testing.T) {
+	adapter := &RediscCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("cas_k1")
+
+	// Add、Replace
+	ok, err := adapter.Replace("cas_k1", "v0", 60)
+	if err != nil || ok {
+		t.Errorf("Redisc Replace failed. cas_k1 is not exist, err: %v.", err)
+	}
+	ok, err = adapter.Add("cas_k1", "v1", 60)
+	if err != nil || !ok {
+		t.Errorf("Redisc Add failed. err: %v.", err)
+	}
+	ok, err = adapter.Add("cas_k1", "v2", 60)
+	if err != nil || ok {
+		t.Errorf("Redisc Add failed. cas_k1 is exist, err: %v.", err)
+	}
+	ok, err = adapter.Replace("cas_k1", map[string]int{"n": 1}, 60)
+	if err != nil || !ok {
+		t.Errorf("Redisc Replace failed. err: %v.", err)
+	}
+
+	// GetWithVersion、CompareAndSwap
+	doc := map[string]int{}
+	ver, err := adapter.GetWithVersion("cas_k1", &doc)
+	if err != nil || ver == nil || doc["n"] != 1 {
+		t.Errorf("Redisc GetWithVersion failed. Got %v, err: %v.", doc, err)
+		return
+	}
+	doc["n"]++
+	ok, err = adapter.CompareAndSwap(ver, doc, 60)
+	if err != nil || !ok {
+		t.Errorf("Redisc CompareAndSwap failed. err: %v.", err)
+	}
+	ok, err = adapter.CompareAndSwap(ver, doc, 60)
+	if err != nil || ok {
+		t.Errorf("Redisc CompareAndSwap failed. version is changed, err: %v.", err)
+	}
+
+	ver, err = adapter.GetWithVersion("cas_k1", &doc)
+	if err != nil || ver == nil || doc["n"] != 2 {
+		t.Errorf("Redisc GetWithVersion failed. Got %v, err: %v.", doc, err)
+		return
+	}
+	adapter.Set("cas_k1", "v3", 60)
+	ok, err = adapter.CompareAndSwap(ver, "v4", 60)
+	if err != nil || ok {
+		t.Errorf("Redisc CompareAndSwap failed. cas_k1 is modified, err: %v.", err)
+	}
+
+	adapter.Del("cas_k1")
+	ver, err = adapter.GetWithVersion("cas_k1", &doc)
+	if err != nil || ver != nil {
+		t.Errorf("Redisc GetWithVersion failed. cas_k1 is not exist, err: %v.", err)
+	}
+}
+
+func TestRediscTTL(t *testing.T) {
+	adapter := &RediscCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("ttl_k1")
+
+	ttl, err := adapter.TTL("ttl_k1")
+	if err != nil || ttl != cache.TTL_NOT_EXIST {
+		t.Errorf("Redisc TTL failed. Got %v, expected %v, err: %v.", ttl, cache.TTL_NOT_EXIST, err)
+	}
+	adapter.Set("ttl_k1", "v1", 0)
+	ttl, err = adapter.TTL("ttl_k1")
+	if err != nil || ttl != cache.TTL_PERSIST {
+		t.Errorf("Redisc TTL failed. Got %v, expected %v, err: %v.", ttl, cache.TTL_PERSIST, err)
+	}
+
+	// Expire、ExpireAt
+	ok, err := adapter.Expire("ttl_k1", 10*time.Second)
+	if err != nil || !ok {
+		t.Errorf("Redisc Expire failed. err: %v.", err)
+	}
+	ttl, err = adapter.TTL("ttl_k1")
+	if err != nil || ttl <= 9*time.Second || ttl > 10*time.Second {
+		t.Errorf("Redisc TTL failed. Got %v, expected 10s, err: %v.", ttl, err)
+	}
+	ok, err = adapter.ExpireAt("ttl_k1", time.Now().Add(time.Minute))
+	if err != nil || !ok {
+		t.Errorf("Redisc ExpireAt failed. err: %v.", err)
+	}
+	ttl, err = adapter.TTL("ttl_k1")
+	if err != nil || ttl <= 58*time.Second || ttl > time.Minute {
+		t.Errorf("Redisc TTL failed. Got %v, expected 1m, err: %v.", ttl, err)
+	}
+	ok, err = adapter.Expire("ttl_k2", time.Second)
+	if err != nil || ok {
+		t.Errorf("Redisc Expire failed. ttl_k2 is not exist, err: %v.", err)
+	}
+
+	// Persist、Touch
+	ok, err = adapter.Persist("ttl_k1")
+	if err != nil || !ok {
+		t.Errorf("Redisc Persist failed. err: %v.", err)
+	}
+	if ttl, _ = adapter.TTL("ttl_k1"); ttl != cache.TTL_PERSIST {
+		t.Errorf("Redisc Persist failed. TTL got %v.", ttl)
+	}
+	ok, err = adapter.Touch("ttl_k1", 100)
+	if err != nil || !ok {
+		t.Errorf("Redisc Touch failed. err: %v.", err)
+	}
+	if ttl, _ = adapter.TTL("ttl_k1"); ttl <= 99*time.Second || ttl > 100*time.Second {
+		t.Errorf("Redisc Touch failed. TTL got %v.", ttl)
+	}
+	ok, err = adapter.Touch("ttl_k1", 0)
+	if err != nil || !ok {
+		t.Errorf("Redisc Touch failed. err: %v.", err)
+	}
+	if ttl, _ = adapter.TTL("ttl_k1"); ttl != cache.TTL_PERSIST {
+		t.Errorf("Redisc Touch failed. TTL got %v.", ttl)
+	}
+
+	// GetEx
+	v := ""
+	err, exist := adapter.GetEx("ttl_k1", &v, 200)
+	if err != nil || !exist || v != "v1" {
+		t.Errorf("Redisc GetEx failed. Got %s, err: %v.", v, err)
+	}
+	if ttl, _ = adapter.TTL("ttl_k1"); ttl <= 199*time.Second || ttl > 200*time.Second {
+		t.Errorf("Redisc GetEx failed. TTL got %v.", ttl)
+	}
+	err, exist = adapter.GetEx("ttl_k2", &v, 200)
+	if err != nil || exist {
+		t.Errorf("Redisc GetEx failed. ttl_k2 is not exist, err: %v.", err)
+	}
+
+	// 过期时间小于等于0时删除
+	ok, err = adapter.Expire("ttl_k1", 0)
+	if err != nil || !ok {
+		t.Errorf("Redisc Expire failed. err: %v.", err)
+	}
+	if exist, _ = adapter.IsExist("ttl_k1"); exist {
+		t.Errorf("Redisc Expire failed. ttl_k1 is not deleted.")
+	}
+}
+
+func TestRediscHook(t *testing.T) {
+	adapter := &RediscCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+	collector := cache.NewCollector("")
+	adapter.AddHook(collector)
+	adapter.Del("hook_k1")
+	adapter.Del("hook_k2")
+
+	v := ""
+	adapter.Set("hook_k1", "v1", 100)
+	adapter.Get("hook_k1", &v)
+	adapter.Get("hook_k2", &v)
+	adapter.Get("hook_k1", &v)
+	if r := collector.HitRatio(cache.AdapterRedisc); r < 0.66 || r > 0.67 {
+		t.Errorf("Redisc HitRatio failed. Got %f, expected 0.67.", r)
+	}
+
+	body := string(collector.Bytes())
+	for _, line := range []string{
+		`cache_commands_total{adapter="redisc",cmd="set"} 1`,
+		`cache_hits_total{adapter="redisc",cmd="get"} 2`,
+		`cache_misses_total{adapter="redisc",cmd="get"} 1`,
+		`cache_errors_total{adapter="redisc",cmd="get"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Redisc Hook failed. %s not found in:\n%s", line, body)
+		}
+	}
+	adapter.Del("hook_k1")
+}
+
+func TestRediscPubSub(t *testing.T) {
+	adapter := &RediscCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := adapter.Subscribe(ctx, "ps_ch1", "ps_ch2")
+	if err != nil {
+		t.Errorf("Redisc Subscribe failed. err: %s.", err.Error())
+		return
+	}
+	expCh, err := adapter.SubscribeExpired(ctx)
+	if err != nil {
+		t.Errorf("Redisc SubscribeExpired failed. err: %s.", err.Error())
+		return
+	}
+
+	n, err := adapter.Publish("ps_ch2", "hello")
+	if err != nil || n != 1 {
+		t.Errorf("Redisc Publish failed. Got %d, expected 1, err: %v.", n, err)
+	}
+	select {
+	case msg := <-ch:
+		if msg.Channel != "ps_ch2" || msg.Payload != "hello" {
+			t.Errorf("Redisc Subscribe failed. Got %s %s, expected ps_ch2 hello.", msg.Channel, msg.Payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Redisc Subscribe failed. Receive timeout.")
+	}
+
+	// 模拟过期通知，不带前缀的key不转发
+	adapter.Publish("__keyevent@0__:expired", "other_k1")
+	adapter.Publish("__keyevent@0__:expired", "le_ps_k1")
+	select {
+	case msg := <-expCh:
+		if msg.Payload != "ps_k1" || msg.Pattern != cache.KeyEventExpired {
+			t.Errorf("Redisc SubscribeExpired failed. Got %s %s, expected ps_k1.", msg.Pattern, msg.Payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Redisc SubscribeExpired failed. Receive timeout.")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Errorf("Redisc Subscribe failed. Channel is not closed.")
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Redisc Subscribe failed. Close timeout.")
+	}
+
+	if _, err = adapter.Subscribe(context.Background()); err == nil {
+		t.Errorf("Redisc Subscribe failed. Channels is empty.")
+	}
+}
+
+func TestRediscLookup(t *testing.T) {
+	adapter := &RediscCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisc Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("lookup_k1")
+	adapter.Del("lookup_k2")
+
+	v := ""
+	exist, err := adapter.Lookup("lookup_k1", &v)
+	if err != nil || exist {
+		t.Errorf("Redisc Lookup failed. lookup_k1 is not exist, err: %v.", err)
+	}
+	adapter.Set("lookup_k1", "v1", 100)
+	exist, err = adapter.LookupCtx(context.Background(), "lookup_k1", &v)
+	if err != nil || !exist || v != "v1" {
+		t.Errorf("Redisc Lookup failed. Got %s, expected v1, err: %v.", v, err)
+	}
+
+	// 反序列化失败
+	n := 0
+	exist, err = adapter.Lookup("lookup_k1", &n)
+	if !errors.Is(err, cache.ErrDecode) {
+		t.Errorf("Redisc Lookup failed. Got %v, expected cache.ErrDecode.", err)
+	}
+	adapter.Del("lookup_k1")
+}
@@ -2,24 +2,34 @@
 package redisd
 
 import (
+	"context"
 	"github.com/lixy529/gotools/cache"
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v7"
 	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
 	"strings"
 	"errors"
-	"math/rand"
+	"github.com/lixy529/gotools/cache/hashring"
+	"sync"
 )
 
+// NOT_EXIST key不存在时go-redis的错误信息
+// Deprecated: 使用cache.IsMiss或errors.Is(err, cache.ErrMiss)判断
 const NOT_EXIST = "redis: nil"
 
 // RedisdCache缓存
 type RedisdCache struct {
-	connClient []*redis.Client // 每个主机有一个连接池
+	connClient map[string]*redis.Client // 每个主机有一个连接池，key为主机和端口
+	ring       *hashring.Ring           // 一致性哈希环，按key选择主机
+	lock       sync.RWMutex             // 保护connClient，修改ring时也要加锁，保证哈希环上的主机都有连接池
+
+	subs    map[*subscription]struct{} // Subscribe的订阅，AddNode、RemoveNode后按哈希环重新订阅
+	subLock sync.Mutex                 // 保护subs和订阅的频道、主机
 
 	addr         string        // 连接主机和端口，多个主机用逗号分割，如127.0.0.1:1900,127.0.0.2:1900
+	weights      string        // 主机权重，与addr一一对应，多个用逗号分割，默认都为1
 	auth         string        // 授权密码
 	dbNum        int           // db编号，默认为0
 	dialTimeout  time.Duration // 连接超时时间，单位秒，默认5秒
@@ -31,8 +41,18 @@ type RedisdCache struct {
 	poolTimeout  time.Duration // 如果所有连接都忙时的等待时间，默认为readTimeout+1秒
 	idleTimeout  time.Duration // 最大空闲时间，单位秒，默认为5分钟
 
-	prefix    string // key前缀，如果配置里有，则所有key前自动添加此前缀
-	encodeKey []byte // 加解密密钥，使用Aes加密，长度为16的倍数
+	prefix     string           // key前缀，如果配置里有，则所有key前自动添加此前缀
+	encodeKey  [][]byte         // 加解密密钥，第一个用于加密，全部用于解密
+	serializer cache.Serializer // 序列化，默认为json
+
+	compressType      string // 压缩类型，支持zlib、gzip、snappy，为空时不压缩
+	compressThreshold int    // 超过大小就进行压缩，单位字节，默认256
+
+	hooks cache.Hooks // 命令钩子
+}
+
+func init() {
+	cache.Register(cache.AdapterRedisd, NewRedisdCache)
 }
 
 // NewRedisdCache 新建一个RedisdCache适配器.
@@ -45,6 +65,7 @@ func NewRedisdCache() cache.Cache {
 //     config: 配置josn串
 //       {
 //         "addr":"127.0.0.1:19100,127.0.0.2:19100,127.0.0.3:19100",
+//         "weights":"2,1,1",
 //         "auth":"xxxx",
 //         "dbNum":"1",
 //         "dialTimeout":"5",
@@ -57,8 +78,12 @@ func NewRedisdCache() cache.Cache {
 //         "idleTimeout":"5",
 //         "prefix":"le_",
 //         "encodeKey":"abcdefghij123456",
+//         "serializer":"json",
+//         "compressType":"snappy",
+//         "compressThreshold":"256",
 //       }
 //       addr:         连接主机和端口，多个主机用逗号分割，如127.0.0.1:1900,127.0.0.2:1900
+//       weights:      主机权重，与addr一一对应，多个用逗号分割，默认都为1
 //       auth:         授权密码
 //       dbNum:        db编号，默认为0
 //       dialTimeout:  连接超时时间，单位秒，默认5秒
@@ -70,6 +95,9 @@ func NewRedisdCache() cache.Cache {
 //       poolTimeout:  如果所有连接都忙时的等待时间，默认为readTimeout+1秒
 //       idleTimeout:  最大空闲时间，单位秒，默认为5分钟
 //       prefix:       key前缀，如果配置里有，则所有key前自动添加此前缀
+//       serializer:   序列化，支持json、gob、msgpack、raw，默认为json
+//       compressType: 压缩类型，支持zlib、gzip、snappy，为空时不压缩
+//       compressThreshold: 超过大小就进行压缩，单位字节，默认256
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (c *RedisdCache) Init(config string) error {
@@ -83,6 +111,12 @@ func (c *RedisdCache) Init(config string) error {
 
 	// 连接串
 	c.addr = mapCfg["addr"]
+	if c.addr == "" {
+		return errors.New("RedisdCache: Addr is empty")
+	}
+
+	// 权重
+	c.weights = mapCfg["weights"]
 
 	// 授权
 	c.auth = mapCfg["auth"]
@@ -162,7 +196,26 @@ func (c *RedisdCache) Init(config string) error {
 
 	// 加密密钥
 	if tmp, ok := mapCfg["encodeKey"]; ok && tmp != "" {
-		c.encodeKey = []byte(tmp)
+		c.encodeKey = cache.ParseEncodeKeys(tmp)
+	}
+
+	// 序列化
+	c.serializer, err = cache.GetSerializer(mapCfg["serializer"])
+	if err != nil {
+		return err
+	}
+
+	// 压缩
+	c.compressType = mapCfg["compressType"]
+	err = cache.CheckCompressType(c.compressType)
+	if err != nil {
+		return err
+	}
+	compressThreshold, err := strconv.Atoi(mapCfg["compressThreshold"])
+	if err != nil || compressThreshold < 0 {
+		c.compressThreshold = 256
+	} else {
+		c.compressThreshold = compressThreshold
 	}
 
 	// dbNum
@@ -174,17 +227,87 @@ func (c *RedisdCache) Init(config string) error {
 	}
 
 	// 设置连接池
-	for _, v := range strings.Split(c.addr, ",") {
-		rp, err := c.connect(v)
-		if err != nil {
-			continue
+	c.lock.Lock()
+	c.connClient = make(map[string]*redis.Client)
+	c.ring = hashring.New(hashring.DEFAULT_REPLICAS)
+	c.lock.Unlock()
+	weights := strings.Split(c.weights, ",")
+	for i, v := range strings.Split(c.addr, ",") {
+		weight := 1
+		if i < len(weights) {
+			if w, err := strconv.Atoi(strings.TrimSpace(weights[i])); err == nil && w > 0 {
+				weight = w
+			}
 		}
-		c.connClient = append(c.connClient, rp)
+		if err = c.AddNode(strings.TrimSpace(v), weight); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddNode 添加一台主机，只有哈希环上相邻区间的key会移到新主机
+//   参数
+//     host:   主机和端口，如127.0.0.1:1900
+//     weight: 权重，小于等于0时为1
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *RedisdCache) AddNode(host string, weight int) error {
+	if host == "" {
+		return errors.New("RedisdCache: Host is empty")
+	}
+
+	client, err := c.connect(host)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	old, ok := c.connClient[host]
+	c.connClient[host] = client
+	c.ring.Add(host, weight)
+	c.lock.Unlock()
+	if ok {
+		c.moveSubscriptions(host)
+		old.Close()
+	} else {
+		c.moveSubscriptions("")
+	}
+
+	return nil
+}
+
+// RemoveNode 删除一台主机，这台主机上的key会分到其它主机，不能删除最后一台主机
+//   参数
+//     host: 主机和端口，如127.0.0.1:1900
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *RedisdCache) RemoveNode(host string) error {
+	c.lock.Lock()
+	client, ok := c.connClient[host]
+	if !ok {
+		c.lock.Unlock()
+		return nil
 	}
+	if len(c.connClient) == 1 {
+		c.lock.Unlock()
+		return fmt.Errorf("RedisdCache: Can't remove the last host %s", host)
+	}
+	c.ring.Remove(host)
+	delete(c.connClient, host)
+	c.lock.Unlock()
+	c.moveSubscriptions("")
+	client.Close()
 
 	return nil
 }
 
+// Nodes 返回所有主机，按名称排序
+func (c *RedisdCache) Nodes() []string {
+	return c.ring.Nodes()
+}
+
 // connect 建立连接
 //   参数
 //     host: 其中一台主机信息
@@ -205,26 +328,108 @@ func (c *RedisdCache) connect(host string) (*redis.Client, error) {
 		PoolTimeout:  c.poolTimeout * time.Second,
 		IdleTimeout:  c.idleTimeout * time.Second,
 	})
+	client.AddHook(cache.NewRedisHook(cache.AdapterRedisd, c.prefix, &c.hooks))
 
 	return client, nil
 }
 
-// getClient 获取一个连接
-// 外面调用完需要通过c.Close()放回连接池
+// getClient 按key获取所在主机的连接池
 //   参数
-//     host: 其中一台主机信息
+//     ctx: 上下文，不是context.Background()时返回绑定了ctx的连接池
+//     key: 添加前缀后的key值，用一致性哈希选择主机
+//   返回
+//     这台主机的连接池，Init成功后不会为nil
+func (c *RedisdCache) getClient(ctx context.Context, key string) *redis.Client {
+	c.lock.RLock()
+	client := c.connClient[c.ring.Get(key)]
+	c.lock.RUnlock()
+	if client == nil || ctx == nil || ctx == context.Background() {
+		return client
+	}
+
+	return client.WithContext(ctx)
+}
+
+// nodeClient 获取指定主机的连接池
+//   参数
+//     ctx:  上下文，不是context.Background()时返回绑定了ctx的连接池
+//     host: 主机和端口
 //   返回
-//     这台主机的连接池
-func (c *RedisdCache) getClient() *redis.Client {
-	connCnt := len(c.connClient)
-	if connCnt == 0 {
+//     这台主机的连接池，主机不存在时返回nil
+func (c *RedisdCache) nodeClient(ctx context.Context, host string) *redis.Client {
+	c.lock.RLock()
+	client := c.connClient[host]
+	c.lock.RUnlock()
+	if client == nil {
 		return nil
-	} else if connCnt == 1 {
-		return c.connClient[0]
 	}
 
-	k := rand.Intn(connCnt)
-	return c.connClient[k]
+	if ctx == nil || ctx == context.Background() {
+		return client
+	}
+
+	return client.WithContext(ctx)
+}
+
+// hostClient 获取指定主机的连接池，同nodeClient，主机不存在时返回错误信息
+// 主机由groupKeys或keysHost得到时，期间可能被RemoveNode删除
+//   参数
+//     ctx:  上下文，不是context.Background()时返回绑定了ctx的连接池
+//     host: 主机和端口
+//   返回
+//     这台主机的连接池、错误信息
+func (c *RedisdCache) hostClient(ctx context.Context, host string) (*redis.Client, error) {
+	client := c.nodeClient(ctx, host)
+	if client == nil {
+		return nil, fmt.Errorf("RedisdCache: Host %s is not exist", host)
+	}
+
+	return client, nil
+}
+
+// groupKeys 按主机对key分组
+//   参数
+//     keys: 添加前缀后的key值
+//   返回
+//     主机对应的key在keys中的下标
+func (c *RedisdCache) groupKeys(keys []string) map[string][]int {
+	group := make(map[string][]int)
+	for i, key := range keys {
+		host := c.ring.Get(key)
+		group[host] = append(group[host], i)
+	}
+	return group
+}
+
+// keysHost 返回多个key共同所在的主机
+//   参数
+//     keys: 添加前缀后的key值
+//   返回
+//     所有key在同一台主机时返回主机，否则返回空串
+func (c *RedisdCache) keysHost(keys []string) string {
+	host := ""
+	for i, key := range keys {
+		h := c.ring.Get(key)
+		if i > 0 && h != host {
+			return ""
+		}
+		host = h
+	}
+	return host
+}
+
+// AddHook 添加命令钩子，所有主机的命令都会调用
+//   参数
+//     hook: 钩子
+//   返回
+//
+func (c *RedisdCache) AddHook(hook cache.Hook) {
+	c.hooks.AddHook(hook)
+}
+
+// Serializer 返回配置的序列化，实现cache.SerializerGetter
+func (c *RedisdCache) Serializer() cache.Serializer {
+	return c.serializer
 }
 
 // Set 向缓存设置一个值
@@ -236,26 +441,23 @@ func (c *RedisdCache) getClient() *redis.Client {
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (c *RedisdCache) Set(key string, val interface{}, expire int32, encode ...bool) error {
-	// 类型转换
-	data, err := cache.InterToByte(val)
+	return c.SetCtx(context.Background(), key, val, expire, encode...)
+}
+
+// SetCtx 同Set，ctx用于控制超时和取消
+func (c *RedisdCache) SetCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	data, err := c.encodeValue(val, encode...)
 	if err != nil {
 		return err
 	}
 
-	// 加密判断
-	encode = append(encode, false)
-	if encode[0] {
-		data, err = cache.Encode(data, c.encodeKey)
-		if err != nil {
-			return err
-		}
-	}
-
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.getClient().Set(key, data, time.Duration(expire)*time.Second).Err()
+	return c.getClient(ctx, key).Set(key, data, time.Duration(expire)*time.Second).Err()
 }
 
 // Get 从缓存取一个值
@@ -265,31 +467,96 @@ func (c *RedisdCache) Set(key string, val interface{}, expire int32, encode ...b
 //   返回
 //     错误信息，是否存在
 func (c *RedisdCache) Get(key string, val interface{}) (error, bool) {
+	return c.GetCtx(context.Background(), key, val)
+}
+
+// GetCtx 同Get，ctx用于控制超时和取消
+func (c *RedisdCache) GetCtx(ctx context.Context, key string, val interface{}) (err error, exist bool) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	v, err := c.getClient().Get(key).Result()
+	v, err := c.getClient(ctx, key).Get(key).Result()
 	if err != nil {
-		if err.Error() == NOT_EXIST {
+		if cache.IsMiss(err) {
 			return nil, false
 		}
 		return err, false
 	}
 
+	return c.decodeValue(v, val), true
+}
+
+// Lookup 从缓存取一个值，同Get，返回值顺序为(是否存在, 错误信息)
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     是否存在，错误信息，key不存在时返回false和nil
+func (c *RedisdCache) Lookup(key string, val interface{}) (bool, error) {
+	return c.LookupCtx(context.Background(), key, val)
+}
+
+// LookupCtx 同Lookup，ctx用于控制超时和取消
+func (c *RedisdCache) LookupCtx(ctx context.Context, key string, val interface{}) (bool, error) {
+	err, exist := c.GetCtx(ctx, key, val)
+	return exist, err
+}
+
+// encodeValue 生成要保存的数据，处理序列化、压缩和加密
+//   参数
+//     val:    value值
+//     encode: 是否加密标识
+//   返回
+//     要保存的数据，失败返回错误信息
+func (c *RedisdCache) encodeValue(val interface{}, encode ...bool) ([]byte, error) {
+	// 类型转换
+	data, err := cache.InterToByte(val, c.serializer)
+	if err != nil {
+		return nil, err
+	}
+
+	// 压缩判断
+	data, err = cache.Compress(data, c.compressType, c.compressThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	// 加密判断
+	encode = append(encode, false)
+	if encode[0] {
+		data, err = cache.Encode(data, c.encodeKey...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// decodeValue 解析保存的数据，处理解密、解压和类型转换
+//   参数
+//     v:   保存的数据
+//     val: 保存结果地址
+//   返回
+//     失败返回错误信息
+func (c *RedisdCache) decodeValue(v string, val interface{}) error {
 	// 解密判断
-	data, err := cache.Decode([]byte(v), c.encodeKey)
+	data, err := cache.Decode([]byte(v), c.encodeKey...)
 	if err != nil {
-		return err, true
+		return err
 	}
 
-	// 类型转换
-	err = cache.ByteToInter(data, val)
+	// 解压
+	data, err = cache.Uncompress(data)
 	if err != nil {
-		return err, true
+		return err
 	}
 
-	return nil, true
+	// 类型转换
+	return cache.ByteToInter(data, val)
 }
 
 // Del 从缓存删除一个值
@@ -298,11 +565,145 @@ func (c *RedisdCache) Get(key string, val interface{}) (error, bool) {
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (c *RedisdCache) Del(key string) error {
+	return c.DelCtx(context.Background(), key)
+}
+
+// DelCtx 同Del，ctx用于控制超时和取消
+func (c *RedisdCache) DelCtx(ctx context.Context, key string) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx, key).Del(key).Err()
+}
+
+// Add key不存在时设置一个值
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key已存在返回false，失败返回错误信息
+func (c *RedisdCache) Add(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return c.AddCtx(context.Background(), key, val, expire, encode...)
+}
+
+// AddCtx 同Add，ctx用于控制超时和取消
+func (c *RedisdCache) AddCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	data, err := c.encodeValue(val, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx, key).SetNX(key, data, time.Duration(expire)*time.Second).Result()
+}
+
+// Replace key存在时设置一个值
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key不存在返回false，失败返回错误信息
+func (c *RedisdCache) Replace(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return c.ReplaceCtx(context.Background(), key, val, expire, encode...)
+}
+
+// ReplaceCtx 同Replace，ctx用于控制超时和取消
+func (c *RedisdCache) ReplaceCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	data, err := c.encodeValue(val, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx, key).SetXX(key, data, time.Duration(expire)*time.Second).Result()
+}
+
+// GetWithVersion 从缓存取一个值，同时返回数据的版本，用于CompareAndSwap
+// redis的版本为保存数据的sha1，数据被修改后又改回原值时版本不变
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     数据的版本，key不存在时返回nil，失败返回错误信息
+func (c *RedisdCache) GetWithVersion(key string, val interface{}) (*cache.Version, error) {
+	return c.GetWithVersionCtx(context.Background(), key, val)
+}
+
+// GetWithVersionCtx 同GetWithVersion，ctx用于控制超时和取消
+func (c *RedisdCache) GetWithVersionCtx(ctx context.Context, key string, val interface{}) (res *cache.Version, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKey := key
+	if c.prefix != "" {
+		pKey = c.prefix + key
+	}
+
+	v, err := c.getClient(ctx, pKey).Get(pKey).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	ver := &cache.Version{Key: key, Token: cache.DataSha1(v)}
+	return ver, c.decodeValue(v, val)
+}
+
+// CompareAndSwap 数据的版本与GetWithVersion返回的版本一致时设置新值，在lua脚本里原子执行
+// 只比较数据内容，数据被修改后又改回原值(A→B→A)时检测不到，需要时在值里保存版本号
+//   参数
+//     ver:    GetWithVersion返回的版本
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示保留原来的过期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，数据已被修改或删除返回false，失败返回错误信息
+func (c *RedisdCache) CompareAndSwap(ver *cache.Version, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return c.CompareAndSwapCtx(context.Background(), ver, val, expire, encode...)
+}
+
+// CompareAndSwapCtx 同CompareAndSwap，ctx用于控制超时和取消
+func (c *RedisdCache) CompareAndSwapCtx(ctx context.Context, ver *cache.Version, val interface{}, expire int32, encode ...bool) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if ver == nil {
+		return false, errors.New("RedisdCache: CompareAndSwap invalid version")
+	}
+	sha, ok := ver.Token.(string)
+	if !ok {
+		return false, errors.New("RedisdCache: CompareAndSwap invalid version")
+	}
+
+	data, err := c.encodeValue(val, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	key := ver.Key
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.getClient().Del(key).Err()
+	n, err := cache.CompareAndSwapScript.Run(c.getClient(ctx, key), []string{key}, sha, data, expire).Int64()
+	return n == 1, err
 }
 
 // MSet 同时设置一个或多个key-value对
@@ -313,10 +714,24 @@ func (c *RedisdCache) Del(key string) error {
 //   返回
 //     成功返回查询结果，失败返回错误信息，key不存在时对应的val为nil
 func (c *RedisdCache) MSet(mList map[string]interface{}, expire int32, encode ...bool) error {
-	var v []interface{}
+	return c.MSetCtx(context.Background(), mList, expire, encode...)
+}
+
+// MSetCtx 同MSet，ctx用于控制超时和取消
+func (c *RedisdCache) MSetCtx(ctx context.Context, mList map[string]interface{}, expire int32, encode ...bool) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	keys := make([]string, 0, len(mList))
+	vals := make([][]byte, 0, len(mList))
 	for key, val := range mList {
 		// 类型转换
-		data, err := cache.InterToByte(val)
+		data, err := cache.InterToByte(val, c.serializer)
+		if err != nil {
+			return err
+		}
+
+		// 压缩判断
+		data, err = cache.Compress(data, c.compressType, c.compressThreshold)
 		if err != nil {
 			return err
 		}
@@ -324,7 +739,7 @@ func (c *RedisdCache) MSet(mList map[string]interface{}, expire int32, encode ..
 		// 加密判断
 		encode = append(encode, false)
 		if encode[0] {
-			data, err = cache.Encode(data, c.encodeKey)
+			data, err = cache.Encode(data, c.encodeKey...)
 			if err != nil {
 				return err
 			}
@@ -333,25 +748,34 @@ func (c *RedisdCache) MSet(mList map[string]interface{}, expire int32, encode ..
 		if c.prefix != "" {
 			key = c.prefix + key
 		}
-		v = append(v, key, data)
+		keys = append(keys, key)
+		vals = append(vals, data)
 	}
 
-	err := c.getClient().MSet(v...).Err()
-	if err != nil {
-		return err
-	}
+	// 按主机分组写入
+	for host, idx := range c.groupKeys(keys) {
+		client, err := c.hostClient(ctx, host)
+		if err != nil {
+			return err
+		}
+		v := make([]interface{}, 0, len(idx)*2)
+		for _, i := range idx {
+			v = append(v, keys[i], vals[i])
+		}
+		err = client.MSet(v...).Err()
+		if err != nil {
+			return err
+		}
 
-	// 设置失效时间
-	if expire > 0 {
-		for key := range mList {
-			if c.prefix != "" {
-				key = c.prefix + key
+		// 设置失效时间
+		if expire > 0 {
+			for _, i := range idx {
+				client.Expire(keys[i], time.Duration(expire)*time.Second)
 			}
-			c.getClient().Expire(key, time.Duration(expire)*time.Second)
 		}
 	}
 
-	return err
+	return nil
 }
 
 // MGet 同时获取一个或多个key的value
@@ -360,6 +784,13 @@ func (c *RedisdCache) MSet(mList map[string]interface{}, expire int32, encode ..
 //   返回
 //     成功返回查询结果，失败返回错误信息
 func (c *RedisdCache) MGet(keys ...string) (map[string]interface{}, error) {
+	return c.MGetCtx(context.Background(), keys...)
+}
+
+// MGetCtx 同MGet，ctx用于控制超时和取消
+func (c *RedisdCache) MGetCtx(ctx context.Context, keys ...string) (res map[string]interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
 	mList := make(map[string]interface{})
 	args := []string{}
 	for _, k := range keys {
@@ -369,9 +800,24 @@ func (c *RedisdCache) MGet(keys ...string) (map[string]interface{}, error) {
 		args = append(args, k)
 	}
 
-	v, err := c.getClient().MGet(args...).Result()
-	if err != nil {
-		return mList, err
+	// 按主机分组查询
+	v := make([]interface{}, len(args))
+	for host, idx := range c.groupKeys(args) {
+		hostArgs := make([]string, len(idx))
+		for j, i := range idx {
+			hostArgs[j] = args[i]
+		}
+		client, err := c.hostClient(ctx, host)
+		if err != nil {
+			return mList, err
+		}
+		res, err := client.MGet(hostArgs...).Result()
+		if err != nil {
+			return mList, err
+		}
+		for j, i := range idx {
+			v[i] = res[j]
+		}
 	}
 
 	i := 0
@@ -381,7 +827,13 @@ func (c *RedisdCache) MGet(keys ...string) (map[string]interface{}, error) {
 			mList[keys[i]] = nil
 		} else {
 			// 解密判断
-			data, err := cache.Decode([]byte(val.(string)), c.encodeKey)
+			data, err := cache.Decode([]byte(val.(string)), c.encodeKey...)
+			if err != nil {
+				return mList, err
+			}
+
+			// 解压
+			data, err = cache.Uncompress(data)
 			if err != nil {
 				return mList, err
 			}
@@ -401,6 +853,13 @@ func (c *RedisdCache) MGet(keys ...string) (map[string]interface{}, error) {
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (c *RedisdCache) MDel(keys ...string) error {
+	return c.MDelCtx(context.Background(), keys...)
+}
+
+// MDelCtx 同MDel，ctx用于控制超时和取消
+func (c *RedisdCache) MDelCtx(ctx context.Context, keys ...string) (err error) {
+	defer cache.ConvRedisError(&err)
+
 	args := make([]string, len(keys))
 	for k, v := range keys {
 		if c.prefix != "" {
@@ -409,9 +868,21 @@ func (c *RedisdCache) MDel(keys ...string) error {
 		args[k] = v
 	}
 
-	c.getClient().Del(args...)
-	return nil
-}
+	// 按主机分组删除
+	for host, idx := range c.groupKeys(args) {
+		hostArgs := make([]string, len(idx))
+		for j, i := range idx {
+			hostArgs[j] = args[i]
+		}
+		client, err := c.hostClient(ctx, host)
+		if err != nil {
+			return err
+		}
+		client.Del(hostArgs...)
+	}
+
+	return nil
+}
 
 // Incr 缓存里的值自增
 // key不存在时会新建一个，再返回1
@@ -421,11 +892,18 @@ func (c *RedisdCache) MDel(keys ...string) error {
 //   返回
 //     递增后的结果，失败返回错误信息
 func (c *RedisdCache) Incr(key string, delta ...uint64) (int64, error) {
+	return c.IncrCtx(context.Background(), key, delta...)
+}
+
+// IncrCtx 同Incr，ctx用于控制超时和取消
+func (c *RedisdCache) IncrCtx(ctx context.Context, key string, delta ...uint64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	delta = append(delta, 1)
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
-	v, err := c.getClient().IncrBy(key, int64(delta[0])).Result()
+	v, err := c.getClient(ctx, key).IncrBy(key, int64(delta[0])).Result()
 	if err != nil {
 		return 0, err
 	}
@@ -441,11 +919,18 @@ func (c *RedisdCache) Incr(key string, delta ...uint64) (int64, error) {
 //   返回
 //     递减后的结果，失败返回错误信息
 func (c *RedisdCache) Decr(key string, delta ...uint64) (int64, error) {
+	return c.DecrCtx(context.Background(), key, delta...)
+}
+
+// DecrCtx 同Decr，ctx用于控制超时和取消
+func (c *RedisdCache) DecrCtx(ctx context.Context, key string, delta ...uint64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	delta = append(delta, 1)
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
-	v, err := c.getClient().DecrBy(key, int64(delta[0])).Result()
+	v, err := c.getClient(ctx, key).DecrBy(key, int64(delta[0])).Result()
 	if err != nil {
 		return 0, err
 	}
@@ -453,16 +938,46 @@ func (c *RedisdCache) Decr(key string, delta ...uint64) (int64, error) {
 	return v, nil
 }
 
+// IncrEx 缓存里的值自增并设置过期时间，自增和设置过期时间在lua脚本里原子执行
+// key不存在时会新建一个，每次自增都会更新过期时间
+//   参数
+//     key:    递增的key值
+//     delta:  递增的量，负数时为递减
+//     expire: 过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     递增后的结果，失败返回错误信息
+func (c *RedisdCache) IncrEx(key string, delta int64, expire int32) (int64, error) {
+	return c.IncrExCtx(context.Background(), key, delta, expire)
+}
+
+// IncrExCtx 同IncrEx，ctx用于控制超时和取消
+func (c *RedisdCache) IncrExCtx(ctx context.Context, key string, delta int64, expire int32) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return cache.IncrExScript.Run(c.getClient(ctx, key), []string{key}, delta, expire).Int64()
+}
+
 // IsExist 判断key值是否存在
 //   参数
 //     key:  要查询的key值
 //   返回
 //     存在返回true，不存在返回false
 func (c *RedisdCache) IsExist(key string) (bool, error) {
+	return c.IsExistCtx(context.Background(), key)
+}
+
+// IsExistCtx 同IsExist，ctx用于控制超时和取消
+func (c *RedisdCache) IsExistCtx(ctx context.Context, key string) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
-	n, err := c.getClient().Exists(key).Result()
+	n, err := c.getClient(ctx, key).Exists(key).Result()
 	if err != nil {
 		return false, err
 	}
@@ -474,18 +989,231 @@ func (c *RedisdCache) IsExist(key string) (bool, error) {
 	return false, nil
 }
 
-// ClearAll 清空所有数据
+// ClearAll 清空所有主机前缀下的数据，使用SCAN分批查找key，UNLINK分批删除，不阻塞服务器
 //   参数
 //
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (c *RedisdCache) ClearAll() error {
-	keys, err := c.getClient().Keys("*").Result()
+	return c.ClearAllCtx(context.Background())
+}
+
+// ClearAllCtx 同ClearAll，ctx用于控制超时和取消
+func (c *RedisdCache) ClearAllCtx(ctx context.Context) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	// 每台主机都要清空
+	pattern := cache.ScanPattern(c.prefix, "")
+	for _, host := range c.ring.Nodes() {
+		client := c.nodeClient(ctx, host)
+		if client == nil {
+			continue
+		}
+
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(cursor, pattern, cache.CLEAR_BATCH).Result()
+			if err != nil {
+				return err
+			}
+
+			if len(keys) > 0 {
+				err = client.Unlink(keys...).Err()
+				if err != nil {
+					return err
+				}
+			}
+
+			if next == 0 {
+				break
+			}
+			cursor = next
+		}
+	}
+
+	return nil
+}
+
+// TTL 查询key的剩余过期时间，精确到毫秒
+//   参数
+//     key: key值
+//   返回
+//     剩余过期时间，没有过期时间返回cache.TTL_PERSIST，key不存在返回cache.TTL_NOT_EXIST，失败返回错误信息
+func (c *RedisdCache) TTL(key string) (time.Duration, error) {
+	return c.TTLCtx(context.Background(), key)
+}
+
+// TTLCtx 同TTL，ctx用于控制超时和取消
+func (c *RedisdCache) TTLCtx(ctx context.Context, key string) (res time.Duration, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx, key).PTTL(key).Result()
+}
+
+// Expire 设置key的过期时间，精确到毫秒
+//   参数
+//     key:    key值
+//     expire: 过期时间，小于等于0时删除key
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (c *RedisdCache) Expire(key string, expire time.Duration) (bool, error) {
+	return c.ExpireCtx(context.Background(), key, expire)
+}
+
+// ExpireCtx 同Expire，ctx用于控制超时和取消
+func (c *RedisdCache) ExpireCtx(ctx context.Context, key string, expire time.Duration) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx, key).PExpire(key, expire).Result()
+}
+
+// ExpireAt 设置key在指定时间过期
+//   参数
+//     key: key值
+//     tm:  过期的时间点，早于当前时间时删除key
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (c *RedisdCache) ExpireAt(key string, tm time.Time) (bool, error) {
+	return c.ExpireAtCtx(context.Background(), key, tm)
+}
+
+// ExpireAtCtx 同ExpireAt，ctx用于控制超时和取消
+func (c *RedisdCache) ExpireAtCtx(ctx context.Context, key string, tm time.Time) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx, key).PExpireAt(key, tm).Result()
+}
+
+// Persist 删除key的过期时间，key不再过期
+//   参数
+//     key: key值
+//   返回
+//     删除了过期时间返回true，key不存在或没有过期时间返回false，失败返回错误信息
+func (c *RedisdCache) Persist(key string) (bool, error) {
+	return c.PersistCtx(context.Background(), key)
+}
+
+// PersistCtx 同Persist，ctx用于控制超时和取消
+func (c *RedisdCache) PersistCtx(ctx context.Context, key string) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx, key).Persist(key).Result()
+}
+
+// Touch 更新key的过期时间，与Set的expire参数一致
+//   参数
+//     key:    key值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (c *RedisdCache) Touch(key string, expire int32) (bool, error) {
+	return c.TouchCtx(context.Background(), key, expire)
+}
+
+// TouchCtx 同Touch，ctx用于控制超时和取消
+func (c *RedisdCache) TouchCtx(ctx context.Context, key string, expire int32) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	if expire > 0 {
+		return c.getClient(ctx, key).Expire(key, time.Duration(expire)*time.Second).Result()
+	}
+
+	// 没有过期时间的key执行persist返回0，需要单独判断key是否存在
+	var exists *redis.IntCmd
+	_, err = c.getClient(ctx, key).TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Persist(key)
+		exists = pipe.Exists(key)
+		return nil
+	})
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	return exists.Val() == 1, nil
+}
+
+// GetEx 从缓存取一个值，同时更新过期时间，用于滑动过期，读取和更新过期时间在一次请求里执行
+//   参数
+//     key:    key值
+//     val:    保存结果地址
+//     expire: 新的过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     错误信息，是否存在
+func (c *RedisdCache) GetEx(key string, val interface{}, expire int32) (error, bool) {
+	return c.GetExCtx(context.Background(), key, val, expire)
+}
+
+// GetExCtx 同GetEx，ctx用于控制超时和取消
+func (c *RedisdCache) GetExCtx(ctx context.Context, key string, val interface{}, expire int32) (err error, exist bool) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	var get *redis.StringCmd
+	_, err = c.getClient(ctx, key).TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(key)
+		if expire > 0 {
+			pipe.Expire(key, time.Duration(expire)*time.Second)
+		}
+		return nil
+	})
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, false
+		}
+		return err, false
+	}
+
+	return c.decodeValue(get.Val(), val), true
+}
+
+// Scan 按模式遍历所有主机前缀下的key
+//   参数
+//     pattern: 匹配模式，不含前缀，为空时匹配所有key
+//     batch:   每次SCAN的key个数，小于等于0时使用redis默认值
+//   返回
+//     迭代器，返回的key已去掉前缀
+func (c *RedisdCache) Scan(pattern string, batch int64) cache.Iterator {
+	return c.ScanCtx(context.Background(), pattern, batch)
+}
+
+// ScanCtx 同Scan，ctx用于控制超时和取消
+func (c *RedisdCache) ScanCtx(ctx context.Context, pattern string, batch int64) cache.Iterator {
+	match := cache.ScanPattern(c.prefix, pattern)
+	var scans []cache.ScanFunc
+	for _, host := range c.ring.Nodes() {
+		client := c.nodeClient(ctx, host)
+		if client == nil {
+			continue
+		}
+		scans = append(scans, func(cursor uint64) ([]string, uint64, error) {
+			return client.Scan(cursor, match, batch).Result()
+		})
 	}
 
-	return c.getClient().Del(keys...).Err()
+	return cache.NewIterator(c.prefix, scans...)
 }
 
 // Hset 添加哈希表
@@ -497,8 +1225,21 @@ func (c *RedisdCache) ClearAll() error {
 //   返回
 //     成功时返回添加的个数，失败返回错误信息
 func (c *RedisdCache) HSet(key string, field string, val interface{}, expire int32) (int64, error) {
+	return c.HSetCtx(context.Background(), key, field, val, expire)
+}
+
+// HSetCtx 同HSet，ctx用于控制超时和取消
+func (c *RedisdCache) HSetCtx(ctx context.Context, key string, field string, val interface{}, expire int32) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	// 类型转换
-	data, err := cache.InterToByte(val)
+	data, err := cache.InterToByte(val, c.serializer)
+	if err != nil {
+		return -1, err
+	}
+
+	// 压缩判断
+	data, err = cache.Compress(data, c.compressType, c.compressThreshold)
 	if err != nil {
 		return -1, err
 	}
@@ -507,15 +1248,18 @@ func (c *RedisdCache) HSet(key string, field string, val interface{}, expire int
 		key = c.prefix + key
 	}
 
-	err = c.getClient().HSet(key, field, data).Err()
+	// 设置和过期时间在事务里执行
+	_, err = c.getClient(ctx, key).TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.HSet(key, field, data)
+		if expire > 0 {
+			pipe.Expire(key, time.Duration(expire)*time.Second)
+		}
+		return nil
+	})
 	if err != nil {
 		return -1, err
 	}
 
-	if expire > 0 {
-		c.getClient().Expire(key, time.Duration(expire)*time.Second)
-	}
-
 	return 1, err
 }
 
@@ -527,20 +1271,33 @@ func (c *RedisdCache) HSet(key string, field string, val interface{}, expire int
 //   返回
 //     错误信息，是否存在
 func (c *RedisdCache) HGet(key string, field string, val interface{}) (error, bool) {
+	return c.HGetCtx(context.Background(), key, field, val)
+}
+
+// HGetCtx 同HGet，ctx用于控制超时和取消
+func (c *RedisdCache) HGetCtx(ctx context.Context, key string, field string, val interface{}) (err error, exist bool) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	v, err := c.getClient().HGet(key, field).Result()
+	v, err := c.getClient(ctx, key).HGet(key, field).Result()
 	if err != nil {
-		if err.Error() == NOT_EXIST {
+		if cache.IsMiss(err) {
 			return nil, false
 		}
 		return err, false
 	}
 
+	// 解压
+	data, err := cache.Uncompress([]byte(v))
+	if err != nil {
+		return err, true
+	}
+
 	// 类型转换
-	err = cache.ByteToInter([]byte(v), val)
+	err = cache.ByteToInter(data, val)
 	if err != nil {
 		return err, true
 	}
@@ -555,11 +1312,18 @@ func (c *RedisdCache) HGet(key string, field string, val interface{}) (error, bo
 //   返回
 //     成功返回nil，失败返回错误信息
 func (c *RedisdCache) HDel(key string, fields ...string) error {
+	return c.HDelCtx(context.Background(), key, fields...)
+}
+
+// HDelCtx 同HDel，ctx用于控制超时和取消
+func (c *RedisdCache) HDelCtx(ctx context.Context, key string, fields ...string) (err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.getClient().HDel(key, fields...).Err()
+	return c.getClient(ctx, key).HDel(key, fields...).Err()
 }
 
 // HGetAll 返回哈希表 key 中，所有的域和值，struct、map类型需要业务层调用json.Unmarshal
@@ -568,14 +1332,21 @@ func (c *RedisdCache) HDel(key string, fields ...string) error {
 //   返回
 //     查询的结果数据和错误码
 func (c *RedisdCache) HGetAll(key string) (map[string]interface{}, error) {
+	return c.HGetAllCtx(context.Background(), key)
+}
+
+// HGetAllCtx 同HGetAll，ctx用于控制超时和取消
+func (c *RedisdCache) HGetAllCtx(ctx context.Context, key string) (res map[string]interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	res := make(map[string]interface{})
-	val, err := c.getClient().HGetAll(key).Result()
+	res = make(map[string]interface{})
+	val, err := c.getClient(ctx, key).HGetAll(key).Result()
 	if err != nil {
-		if err.Error() == NOT_EXIST {
+		if cache.IsMiss(err) {
 			return res, nil
 		}
 
@@ -583,7 +1354,11 @@ func (c *RedisdCache) HGetAll(key string) (map[string]interface{}, error) {
 	}
 
 	for k, v := range val {
-		res[k] = v
+		// 解压
+		res[k], err = cache.UncompressString(v)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return res, err
@@ -597,17 +1372,24 @@ func (c *RedisdCache) HGetAll(key string) (map[string]interface{}, error) {
 //   返回
 //     执行结果
 func (c *RedisdCache) HMSet(key string, fields map[string]interface{}, expire int32) error {
+	return c.HMSetCtx(context.Background(), key, fields, expire)
+}
+
+// HMSetCtx 同HMSet，ctx用于控制超时和取消
+func (c *RedisdCache) HMSetCtx(ctx context.Context, key string, fields map[string]interface{}, expire int32) (err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	err := c.getClient().HMSet(key, fields).Err()
+	err = c.getClient(ctx, key).HMSet(key, fields).Err()
 	if err != nil {
 		return err
 	}
 
 	if expire > 0 {
-		c.getClient().Expire(key, time.Duration(expire)*time.Second)
+		c.getClient(ctx, key).Expire(key, time.Duration(expire)*time.Second)
 	}
 
 	return nil
@@ -620,12 +1402,19 @@ func (c *RedisdCache) HMSet(key string, fields map[string]interface{}, expire in
 //   返回
 //     查询的结果数据和错误码
 func (c *RedisdCache) HMGet(key string, fields ...string) (map[string]interface{}, error) {
+	return c.HMGetCtx(context.Background(), key, fields...)
+}
+
+// HMGetCtx 同HMGet，ctx用于控制超时和取消
+func (c *RedisdCache) HMGetCtx(ctx context.Context, key string, fields ...string) (res map[string]interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
-	res := make(map[string]interface{})
+	res = make(map[string]interface{})
 
-	v, err := c.getClient().HMGet(key, fields...).Result()
+	v, err := c.getClient(ctx, key).HMGet(key, fields...).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -636,7 +1425,11 @@ func (c *RedisdCache) HMGet(key string, fields ...string) (map[string]interface{
 
 	i := 0
 	for _, field := range fields {
-		res[field] = v[i]
+		// 解压
+		res[field], err = cache.UncompressString(v[i])
+		if err != nil {
+			return nil, err
+		}
 		i++
 	}
 
@@ -649,14 +1442,25 @@ func (c *RedisdCache) HMGet(key string, fields ...string) (map[string]interface{
 //   返回
 //     查询的结果数据和错误码
 func (c *RedisdCache) HVals(key string) ([]interface{}, error) {
-	vals, err := c.getClient().HVals(key).Result()
+	return c.HValsCtx(context.Background(), key)
+}
+
+// HValsCtx 同HVals，ctx用于控制超时和取消
+func (c *RedisdCache) HValsCtx(ctx context.Context, key string) (res []interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	vals, err := c.getClient(ctx, key).HVals(key).Result()
 	if err != nil {
 		return nil, err
 	}
 
-	res := make([]interface{}, len(vals))
+	res = make([]interface{}, len(vals))
 	for k, v := range vals {
-		res[k] = v
+		// 解压
+		res[k], err = cache.UncompressString(v)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return res, nil
@@ -670,12 +1474,19 @@ func (c *RedisdCache) HVals(key string) ([]interface{}, error) {
 //   返回
 //     递增后的结果、失败返回错误信息
 func (c *RedisdCache) HIncr(key, fields string, delta ...uint64) (int64, error) {
+	return c.HIncrCtx(context.Background(), key, fields, delta...)
+}
+
+// HIncrCtx 同HIncr，ctx用于控制超时和取消
+func (c *RedisdCache) HIncrCtx(ctx context.Context, key, fields string, delta ...uint64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	delta = append(delta, 1)
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.getClient().HIncrBy(key, fields, int64(delta[0])).Result()
+	return c.getClient(ctx, key).HIncrBy(key, fields, int64(delta[0])).Result()
 }
 
 // HDecr 哈希表的值自减
@@ -686,12 +1497,19 @@ func (c *RedisdCache) HIncr(key, fields string, delta ...uint64) (int64, error)
 //   返回
 //     递减后的结果、失败返回错误信息
 func (c *RedisdCache) HDecr(key, fields string, delta ...uint64) (int64, error) {
+	return c.HDecrCtx(context.Background(), key, fields, delta...)
+}
+
+// HDecrCtx 同HDecr，ctx用于控制超时和取消
+func (c *RedisdCache) HDecrCtx(ctx context.Context, key, fields string, delta ...uint64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	delta = append(delta, 1)
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.getClient().HIncrBy(key, fields, 0-int64(delta[0])).Result()
+	return c.getClient(ctx, key).HIncrBy(key, fields, 0-int64(delta[0])).Result()
 }
 
 // ZSet 添加有序集合
@@ -702,13 +1520,20 @@ func (c *RedisdCache) HDecr(key, fields string, delta ...uint64) (int64, error)
 //   返回
 //     成功添加的数据和错误码
 func (c *RedisdCache) ZSet(key string, expire int32, val ...interface{}) (int64, error) {
+	return c.ZSetCtx(context.Background(), key, expire, val...)
+}
+
+// ZSetCtx 同ZSet，ctx用于控制超时和取消
+func (c *RedisdCache) ZSetCtx(ctx context.Context, key string, expire int32, val ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	valLen := len(val)
 	if valLen < 2 || valLen%2 != 0 {
 		return -1, errors.New("val param error")
 	}
-	vals := []redis.Z{}
+	vals := []*redis.Z{}
 	for i := 0; i < valLen-1; i += 2 {
-		stZ := redis.Z{
+		stZ := &redis.Z{
 			Score:  val[i].(float64),
 			Member: val[i+1],
 		}
@@ -719,16 +1544,19 @@ func (c *RedisdCache) ZSet(key string, expire int32, val ...interface{}) (int64,
 		key = c.prefix + key
 	}
 
-	n, err := c.getClient().ZAdd(key, vals...).Result()
+	var cmd *redis.IntCmd
+	_, err = c.getClient(ctx, key).TxPipelined(func(pipe redis.Pipeliner) error {
+		cmd = pipe.ZAdd(key, vals...)
+		if expire > 0 {
+			pipe.Expire(key, time.Duration(expire)*time.Second)
+		}
+		return nil
+	})
 	if err != nil {
 		return -1, err
 	}
 
-	if expire > 0 {
-		c.getClient().Expire(key, time.Duration(expire)*time.Second)
-	}
-
-	return n, err
+	return cmd.Val(), err
 }
 
 // ZGet 查询有序集合
@@ -741,9 +1569,15 @@ func (c *RedisdCache) ZSet(key string, expire int32, val ...interface{}) (int64,
 //   返回
 //     查询的结果数据和错误码
 func (c *RedisdCache) ZGet(key string, start, stop int, withScores bool, isRev bool) ([]string, error) {
-	var err error
+	return c.ZGetCtx(context.Background(), key, start, stop, withScores, isRev)
+}
+
+// ZGetCtx 同ZGet，ctx用于控制超时和取消
+func (c *RedisdCache) ZGetCtx(ctx context.Context, key string, start, stop int, withScores bool, isRev bool) (res []string, err error) {
+	defer cache.ConvRedisError(&err)
+
 	vals := []redis.Z{}
-	res := []string{}
+	res = []string{}
 
 	if c.prefix != "" {
 		key = c.prefix + key
@@ -752,21 +1586,21 @@ func (c *RedisdCache) ZGet(key string, start, stop int, withScores bool, isRev b
 	if isRev {
 
 		if withScores {
-			vals, err = c.getClient().ZRevRangeWithScores(key, int64(start), int64(stop)).Result()
+			vals, err = c.getClient(ctx, key).ZRevRangeWithScores(key, int64(start), int64(stop)).Result()
 			if err != nil {
 				return res, err
 			}
 		} else {
-			return c.getClient().ZRevRange(key, int64(start), int64(stop)).Result()
+			return c.getClient(ctx, key).ZRevRange(key, int64(start), int64(stop)).Result()
 		}
 	} else {
 		if withScores {
-			vals, err = c.getClient().ZRangeWithScores(key, int64(start), int64(stop)).Result()
+			vals, err = c.getClient(ctx, key).ZRangeWithScores(key, int64(start), int64(stop)).Result()
 			if err != nil {
 				return res, err
 			}
 		} else {
-			return c.getClient().ZRange(key, int64(start), int64(stop)).Result()
+			return c.getClient(ctx, key).ZRange(key, int64(start), int64(stop)).Result()
 		}
 	}
 
@@ -784,6 +1618,13 @@ func (c *RedisdCache) ZGet(key string, start, stop int, withScores bool, isRev b
 //   返回
 //     成功删除的数据个数和错误码
 func (c *RedisdCache) ZDel(key string, field ...string) (int64, error) {
+	return c.ZDelCtx(context.Background(), key, field...)
+}
+
+// ZDelCtx 同ZDel，ctx用于控制超时和取消
+func (c *RedisdCache) ZDelCtx(ctx context.Context, key string, field ...string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	var args []interface{}
 	for _, f := range field {
 		args = append(args, f)
@@ -792,7 +1633,7 @@ func (c *RedisdCache) ZDel(key string, field ...string) (int64, error) {
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
-	return c.getClient().ZRem(key, args...).Result()
+	return c.getClient(ctx, key).ZRem(key, args...).Result()
 }
 
 // ZRemRangeByRank 删除指定排名区间内的有序集合数据
@@ -803,11 +1644,18 @@ func (c *RedisdCache) ZDel(key string, field ...string) (int64, error) {
 //   返回
 //     成功删除的数据个数和错误码
 func (c *RedisdCache) ZRemRangeByRank(key string, start, end int64) (int64, error) {
+	return c.ZRemRangeByRankCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByRankCtx 同ZRemRangeByRank，ctx用于控制超时和取消
+func (c *RedisdCache) ZRemRangeByRankCtx(ctx context.Context, key string, start, end int64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.getClient().ZRemRangeByRank(key, start, end).Result()
+	return c.getClient(ctx, key).ZRemRangeByRank(key, start, end).Result()
 }
 
 // ZRemRangeByScore 删除指定分值区间内的有序集合数据
@@ -818,11 +1666,18 @@ func (c *RedisdCache) ZRemRangeByRank(key string, start, end int64) (int64, erro
 //   返回
 //     成功删除的数据个数和错误码
 func (c *RedisdCache) ZRemRangeByScore(key string, start, end string) (int64, error) {
+	return c.ZRemRangeByScoreCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByScoreCtx 同ZRemRangeByScore，ctx用于控制超时和取消
+func (c *RedisdCache) ZRemRangeByScoreCtx(ctx context.Context, key string, start, end string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.getClient().ZRemRangeByScore(key, start, end).Result()
+	return c.getClient(ctx, key).ZRemRangeByScore(key, start, end).Result()
 }
 
 // ZRemRangeByLex 删除指定变量区间内的有序集合数据
@@ -834,11 +1689,18 @@ func (c *RedisdCache) ZRemRangeByScore(key string, start, end string) (int64, er
 //   返回
 //     成功删除的数据个数和错误码
 func (c *RedisdCache) ZRemRangeByLex(key string, start, end string) (int64, error) {
+	return c.ZRemRangeByLexCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByLexCtx 同ZRemRangeByLex，ctx用于控制超时和取消
+func (c *RedisdCache) ZRemRangeByLexCtx(ctx context.Context, key string, start, end string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.getClient().ZRemRangeByLex(key, start, end).Result()
+	return c.getClient(ctx, key).ZRemRangeByLex(key, start, end).Result()
 }
 
 // ZCard 返回有序集 key 的基数
@@ -847,10 +1709,17 @@ func (c *RedisdCache) ZRemRangeByLex(key string, start, end string) (int64, erro
 //   返回
 //     有序集 key 的基数和错误码
 func (c *RedisdCache) ZCard(key string) (int64, error) {
+	return c.ZCardCtx(context.Background(), key)
+}
+
+// ZCardCtx 同ZCard，ctx用于控制超时和取消
+func (c *RedisdCache) ZCardCtx(ctx context.Context, key string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
-	return c.getClient().ZCard(key).Result()
+	return c.getClient(ctx, key).ZCard(key).Result()
 }
 
 // SetBit 设置或清除指定偏移量上的位(bit)
@@ -862,20 +1731,27 @@ func (c *RedisdCache) ZCard(key string) (int64, error) {
 //   返回
 //     指定偏移量原来储存的位、错误信息
 func (c *RedisdCache) SetBit(key string, offset int64, value int, expire int32) (int64, error) {
+	return c.SetBitCtx(context.Background(), key, offset, value, expire)
+}
+
+// SetBitCtx 同SetBit，ctx用于控制超时和取消
+func (c *RedisdCache) SetBitCtx(ctx context.Context, key string, offset int64, value int, expire int32) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	res, err := c.getClient().SetBit(key, offset, value).Result()
-	if err != nil {
-		return res, err
-	}
-
-	if expire > 0 {
-		c.getClient().Expire(key, time.Duration(expire)*time.Second)
-	}
+	var cmd *redis.IntCmd
+	_, err = c.getClient(ctx, key).TxPipelined(func(pipe redis.Pipeliner) error {
+		cmd = pipe.SetBit(key, offset, value)
+		if expire > 0 {
+			pipe.Expire(key, time.Duration(expire)*time.Second)
+		}
+		return nil
+	})
 
-	return res, err
+	return cmd.Val(), err
 }
 
 // GetBit 获取指定偏移量上的位(bit)
@@ -885,11 +1761,18 @@ func (c *RedisdCache) SetBit(key string, offset int64, value int, expire int32)
 //   返回
 //     字符串值指定偏移量上的位(bit)、错误信息
 func (c *RedisdCache) GetBit(key string, offset int64) (int64, error) {
+	return c.GetBitCtx(context.Background(), key, offset)
+}
+
+// GetBitCtx 同GetBit，ctx用于控制超时和取消
+func (c *RedisdCache) GetBitCtx(ctx context.Context, key string, offset int64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.getClient().GetBit(key, offset).Result()
+	return c.getClient(ctx, key).GetBit(key, offset).Result()
 }
 
 // BitCount 计算给定字符串中被设置为 1 的比特位的数量
@@ -899,16 +1782,23 @@ func (c *RedisdCache) GetBit(key string, offset int64) (int64, error) {
 //   返回
 //     给定字符串中被设置为 1 的比特位的数量、错误信息
 func (c *RedisdCache) BitCount(key string, bitCount *cache.BitCount) (int64, error) {
+	return c.BitCountCtx(context.Background(), key, bitCount)
+}
+
+// BitCountCtx 同BitCount，ctx用于控制超时和取消
+func (c *RedisdCache) BitCountCtx(ctx context.Context, key string, bitCount *cache.BitCount) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
 	if bitCount != nil {
 		bc := redis.BitCount{Start: bitCount.Start, End: bitCount.End}
-		return c.getClient().BitCount(key, &bc).Result()
+		return c.getClient(ctx, key).BitCount(key, &bc).Result()
 	}
 
-	return c.getClient().BitCount(key, nil).Result()
+	return c.getClient(ctx, key).BitCount(key, nil).Result()
 }
 
 // PFAdd 添加基数
@@ -920,17 +1810,24 @@ func (c *RedisdCache) BitCount(key string, bitCount *cache.BitCount) (int64, err
 //     存在，不做任何事情，返回0；不存在的话就创建，并返回1
 //     错误信息
 func (c *RedisdCache) PFAdd(key string, expire int32, vals ...interface{}) (int64, error) {
+	return c.PFAddCtx(context.Background(), key, expire, vals...)
+}
+
+// PFAddCtx 同PFAdd，ctx用于控制超时和取消
+func (c *RedisdCache) PFAddCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	res, err := c.getClient().PFAdd(key, vals...).Result()
+	res, err = c.getClient(ctx, key).PFAdd(key, vals...).Result()
 	if err != nil {
 		return res, err
 	}
 
 	if expire > 0 {
-		c.getClient().Expire(key, time.Duration(expire)*time.Second)
+		c.getClient(ctx, key).Expire(key, time.Duration(expire)*time.Second)
 	}
 
 	return res, err
@@ -942,14 +1839,1327 @@ func (c *RedisdCache) PFAdd(key string, expire int32, vals ...interface{}) (int6
 //   返回
 //     基数估算值
 func (c *RedisdCache) PFCount(key string) (int64, error) {
+	return c.PFCountCtx(context.Background(), key)
+}
+
+// PFCountCtx 同PFCount，ctx用于控制超时和取消
+func (c *RedisdCache) PFCountCtx(ctx context.Context, key string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if c.prefix != "" {
 		key = c.prefix + key
 	}
 
-	return c.getClient().PFCount(key).Result()
+	return c.getClient(ctx, key).PFCount(key).Result()
+}
+
+// LPush 从列表头部插入元素
+//   参数
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素，与HSet一致，按序列化和压缩配置转换
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (c *RedisdCache) LPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return c.LPushCtx(context.Background(), key, expire, vals...)
+}
+
+// LPushCtx 同LPush，ctx用于控制超时和取消
+func (c *RedisdCache) LPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	return c.push(ctx, key, expire, true, vals)
+}
+
+// RPush 从列表尾部插入元素
+//   参数
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素，与HSet一致，按序列化和压缩配置转换
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (c *RedisdCache) RPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return c.RPushCtx(context.Background(), key, expire, vals...)
+}
+
+// RPushCtx 同RPush，ctx用于控制超时和取消
+func (c *RedisdCache) RPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	return c.push(ctx, key, expire, false, vals)
+}
+
+// push 向列表插入元素
+//   参数
+//     ctx:    上下文
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位
+//     isLeft: 是否从头部插入
+//     vals:   元素
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (c *RedisdCache) push(ctx context.Context, key string, expire int32, isLeft bool, vals []interface{}) (int64, error) {
+	args, err := c.toValues(vals)
+	if err != nil {
+		return 0, err
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	var n int64
+	if isLeft {
+		n, err = c.getClient(ctx, key).LPush(key, args...).Result()
+	} else {
+		n, err = c.getClient(ctx, key).RPush(key, args...).Result()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if expire > 0 {
+		c.getClient(ctx, key).Expire(key, time.Duration(expire)*time.Second)
+	}
+
+	return n, nil
+}
+
+// LPop 从列表头部取出一个元素
+//   参数
+//     key: 列表key值
+//     val: 保存结果地址
+//   返回
+//     错误信息，是否存在
+func (c *RedisdCache) LPop(key string, val interface{}) (error, bool) {
+	return c.LPopCtx(context.Background(), key, val)
+}
+
+// LPopCtx 同LPop，ctx用于控制超时和取消
+func (c *RedisdCache) LPopCtx(ctx context.Context, key string, val interface{}) (err error, exist bool) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	v, err := c.getClient(ctx, key).LPop(key).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, false
+		}
+		return err, false
+	}
+
+	// 解压
+	data, err := cache.Uncompress([]byte(v))
+	if err != nil {
+		return err, true
+	}
+
+	// 类型转换
+	err = cache.ByteToInter(data, val)
+	if err != nil {
+		return err, true
+	}
+
+	return nil, true
+}
+
+// BRPop 阻塞地从多个列表尾部取出一个元素
+//   参数
+//     timeout: 阻塞时间，以秒为单位，“0”表示一直阻塞
+//     val:     保存结果地址
+//     keys:    列表key值，按顺序检查，必须在同一台主机
+//   返回
+//     取到元素的列表key值，超时返回空串，错误信息
+func (c *RedisdCache) BRPop(timeout int32, val interface{}, keys ...string) (string, error) {
+	return c.BRPopCtx(context.Background(), timeout, val, keys...)
+}
+
+// BRPopCtx 同BRPop，ctx用于控制超时和取消
+func (c *RedisdCache) BRPopCtx(ctx context.Context, timeout int32, val interface{}, keys ...string) (_ string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKeys := make([]string, len(keys))
+	for i, key := range keys {
+		pKeys[i] = c.prefix + key
+	}
+
+	host := c.keysHost(pKeys)
+	if host == "" {
+		return "", errors.New("RedisdCache: BRPop keys must be on the same host")
+	}
+
+	client, err := c.hostClient(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	res, err := client.BRPop(time.Duration(timeout)*time.Second, pKeys...).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	// 解压
+	data, err := cache.Uncompress([]byte(res[1]))
+	if err != nil {
+		return "", err
+	}
+
+	// 类型转换
+	key := strings.TrimPrefix(res[0], c.prefix)
+	err = cache.ByteToInter(data, val)
+	if err != nil {
+		return key, err
+	}
+
+	return key, nil
+}
+
+// LRange 查询列表指定区间的元素
+//   参数
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
+//   返回
+//     成功时返回元素列表，失败返回错误信息
+func (c *RedisdCache) LRange(key string, start, stop int64) ([]interface{}, error) {
+	return c.LRangeCtx(context.Background(), key, start, stop)
+}
+
+// LRangeCtx 同LRange，ctx用于控制超时和取消
+func (c *RedisdCache) LRangeCtx(ctx context.Context, key string, start, stop int64) (res []interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	v, err := c.getClient(ctx, key).LRange(key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	res = make([]interface{}, len(v))
+	for i, item := range v {
+		// 解压
+		res[i], err = cache.UncompressString(item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// LTrim 只保留列表指定区间的元素
+//   参数
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *RedisdCache) LTrim(key string, start, stop int64) error {
+	return c.LTrimCtx(context.Background(), key, start, stop)
+}
+
+// LTrimCtx 同LTrim，ctx用于控制超时和取消
+func (c *RedisdCache) LTrimCtx(ctx context.Context, key string, start, stop int64) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx, key).LTrim(key, start, stop).Err()
+}
+
+// SAdd 向集合添加成员
+//   参数
+//     key:     集合key值
+//     expire:  缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     members: 成员，只做序列化，不压缩，保证相同的成员保存的数据相同
+//   返回
+//     成功时返回新添加的个数，失败返回错误信息
+func (c *RedisdCache) SAdd(key string, expire int32, members ...interface{}) (int64, error) {
+	return c.SAddCtx(context.Background(), key, expire, members...)
+}
+
+// SAddCtx 同SAdd，ctx用于控制超时和取消
+func (c *RedisdCache) SAddCtx(ctx context.Context, key string, expire int32, members ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	args, err := c.toMembers(members)
+	if err != nil {
+		return 0, err
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	n, err := c.getClient(ctx, key).SAdd(key, args...).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if expire > 0 {
+		c.getClient(ctx, key).Expire(key, time.Duration(expire)*time.Second)
+	}
+
+	return n, nil
+}
+
+// SRem 删除集合的成员
+//   参数
+//     key:     集合key值
+//     members: 成员
+//   返回
+//     成功时返回删除的个数，失败返回错误信息
+func (c *RedisdCache) SRem(key string, members ...interface{}) (int64, error) {
+	return c.SRemCtx(context.Background(), key, members...)
+}
+
+// SRemCtx 同SRem，ctx用于控制超时和取消
+func (c *RedisdCache) SRemCtx(ctx context.Context, key string, members ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	args, err := c.toMembers(members)
+	if err != nil {
+		return 0, err
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx, key).SRem(key, args...).Result()
+}
+
+// SIsMember 判断是否为集合的成员
+//   参数
+//     key:    集合key值
+//     member: 成员
+//   返回
+//     是成员返回true，不是返回false，失败返回错误信息
+func (c *RedisdCache) SIsMember(key string, member interface{}) (bool, error) {
+	return c.SIsMemberCtx(context.Background(), key, member)
+}
+
+// SIsMemberCtx 同SIsMember，ctx用于控制超时和取消
+func (c *RedisdCache) SIsMemberCtx(ctx context.Context, key string, member interface{}) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	data, err := cache.InterToByte(member, c.serializer)
+	if err != nil {
+		return false, err
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx, key).SIsMember(key, data).Result()
+}
+
+// SMembers 查询集合的所有成员
+//   参数
+//     key: 集合key值
+//   返回
+//     成功时返回所有成员，失败返回错误信息
+func (c *RedisdCache) SMembers(key string) ([]string, error) {
+	return c.SMembersCtx(context.Background(), key)
+}
+
+// SMembersCtx 同SMembers，ctx用于控制超时和取消
+func (c *RedisdCache) SMembersCtx(ctx context.Context, key string) (res []string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx, key).SMembers(key).Result()
+}
+
+// SInter 返回多个集合的交集
+//   参数
+//     keys: 集合key值
+//   返回
+//     成功时返回交集的成员，失败返回错误信息
+func (c *RedisdCache) SInter(keys ...string) ([]string, error) {
+	return c.SInterCtx(context.Background(), keys...)
+}
+
+// SInterCtx 同SInter，ctx用于控制超时和取消
+func (c *RedisdCache) SInterCtx(ctx context.Context, keys ...string) (res []string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKeys := make([]string, len(keys))
+	for i, key := range keys {
+		pKeys[i] = c.prefix + key
+	}
+
+	// key不在同一台主机时在客户端计算
+	host := c.keysHost(pKeys)
+	if host == "" {
+		return c.setOp(ctx, pKeys, true)
+	}
+
+	client, err := c.hostClient(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return client.SInter(pKeys...).Result()
+}
+
+// SUnion 返回多个集合的并集
+//   参数
+//     keys: 集合key值
+//   返回
+//     成功时返回并集的成员，失败返回错误信息
+func (c *RedisdCache) SUnion(keys ...string) ([]string, error) {
+	return c.SUnionCtx(context.Background(), keys...)
+}
+
+// SUnionCtx 同SUnion，ctx用于控制超时和取消
+func (c *RedisdCache) SUnionCtx(ctx context.Context, keys ...string) (res []string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKeys := make([]string, len(keys))
+	for i, key := range keys {
+		pKeys[i] = c.prefix + key
+	}
+
+	// key不在同一台主机时在客户端计算
+	host := c.keysHost(pKeys)
+	if host == "" {
+		return c.setOp(ctx, pKeys, false)
+	}
+
+	client, err := c.hostClient(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return client.SUnion(pKeys...).Result()
+}
+
+// XAdd 向流添加消息
+//   参数
+//     key:    流key值
+//     maxLen: 流的最大长度，超过时删除旧消息，为近似值，小于等于0时不限制
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   消息内容，与HSet一致，按序列化和压缩配置转换
+//   返回
+//     成功时返回消息id，失败返回错误信息
+func (c *RedisdCache) XAdd(key string, maxLen int64, expire int32, vals map[string]interface{}) (string, error) {
+	return c.XAddCtx(context.Background(), key, maxLen, expire, vals)
+}
+
+// XAddCtx 同XAdd，ctx用于控制超时和取消
+func (c *RedisdCache) XAddCtx(ctx context.Context, key string, maxLen int64, expire int32, vals map[string]interface{}) (res string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	values := make(map[string]interface{}, len(vals))
+	for field, val := range vals {
+		args, err := c.toValues([]interface{}{val})
+		if err != nil {
+			return "", err
+		}
+		values[field] = args[0]
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	args := &redis.XAddArgs{Stream: key, Values: values}
+	if maxLen > 0 {
+		args.MaxLenApprox = maxLen
+	}
+	id, err := c.getClient(ctx, key).XAdd(args).Result()
+	if err != nil {
+		return "", err
+	}
+
+	if expire > 0 {
+		c.getClient(ctx, key).Expire(key, time.Duration(expire)*time.Second)
+	}
+
+	return id, nil
+}
+
+// XRead 读取流消息
+//   参数
+//     args: 读取参数，key必须在同一台主机，Streams前一半为key值，后一半为对应的开始id(不包含)，$表示只读新消息
+//   返回
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (c *RedisdCache) XRead(args *cache.XReadArgs) ([]cache.XStream, error) {
+	return c.XReadCtx(context.Background(), args)
+}
+
+// XReadCtx 同XRead，ctx用于控制超时和取消
+func (c *RedisdCache) XReadCtx(ctx context.Context, args *cache.XReadArgs) (_ []cache.XStream, err error) {
+	defer cache.ConvRedisError(&err)
+
+	streams := c.prefixStreams(args.Streams)
+	host := c.keysHost(streams[:len(streams)/2])
+	if host == "" {
+		return nil, errors.New("RedisdCache: XRead streams must be on the same host")
+	}
+
+	block := args.Block
+	if block <= 0 {
+		block = -1
+	}
+
+	client, err := c.hostClient(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.XRead(&redis.XReadArgs{
+		Streams: streams,
+		Count:   args.Count,
+		Block:   block,
+	}).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return c.toXStreams(res)
+}
+
+// XGroupCreate 创建消费组，流不存在时自动创建
+//   参数
+//     key:   流key值
+//     group: 消费组
+//     start: 开始读取的消息id，0表示从头开始，为空或$表示只读新消息
+//   返回
+//     成功或消费组已存在时返回nil，失败返回错误信息
+func (c *RedisdCache) XGroupCreate(key, group, start string) error {
+	return c.XGroupCreateCtx(context.Background(), key, group, start)
+}
+
+// XGroupCreateCtx 同XGroupCreate，ctx用于控制超时和取消
+func (c *RedisdCache) XGroupCreateCtx(ctx context.Context, key, group, start string) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	if start == "" {
+		start = "$"
+	}
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	err = c.getClient(ctx, key).XGroupCreateMkStream(key, group, start).Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+
+	return err
+}
+
+// XReadGroup 按消费组读取流消息
+//   参数
+//     args: 读取参数，key必须在同一台主机，Streams前一半为key值，后一半为对应的id，>表示读取未分配给消费者的新消息
+//   返回
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (c *RedisdCache) XReadGroup(args *cache.XReadGroupArgs) ([]cache.XStream, error) {
+	return c.XReadGroupCtx(context.Background(), args)
+}
+
+// XReadGroupCtx 同XReadGroup，ctx用于控制超时和取消
+func (c *RedisdCache) XReadGroupCtx(ctx context.Context, args *cache.XReadGroupArgs) (_ []cache.XStream, err error) {
+	defer cache.ConvRedisError(&err)
+
+	streams := c.prefixStreams(args.Streams)
+	host := c.keysHost(streams[:len(streams)/2])
+	if host == "" {
+		return nil, errors.New("RedisdCache: XReadGroup streams must be on the same host")
+	}
+
+	block := args.Block
+	if block <= 0 {
+		block = -1
+	}
+
+	client, err := c.hostClient(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.XReadGroup(&redis.XReadGroupArgs{
+		Group:    args.Group,
+		Consumer: args.Consumer,
+		Streams:  streams,
+		Count:    args.Count,
+		Block:    block,
+		NoAck:    args.NoAck,
+	}).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return c.toXStreams(res)
+}
+
+// XAck 确认消费组的消息
+//   参数
+//     key:   流key值
+//     group: 消费组
+//     ids:   消息id
+//   返回
+//     成功时返回确认的个数，失败返回错误信息
+func (c *RedisdCache) XAck(key, group string, ids ...string) (int64, error) {
+	return c.XAckCtx(context.Background(), key, group, ids...)
+}
+
+// XAckCtx 同XAck，ctx用于控制超时和取消
+func (c *RedisdCache) XAckCtx(ctx context.Context, key, group string, ids ...string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx, key).XAck(key, group, ids...).Result()
+}
+
+// Eval 执行lua脚本，先使用EVALSHA，脚本未加载时使用EVAL
+// 在key所在的主机上执行，所有key需要在同一台主机
+//   参数
+//     script: lua脚本
+//     keys:   脚本的KEYS，会自动添加key前缀，至少要有一个
+//     args:   脚本的ARGV
+//   返回
+//     脚本的返回值，脚本返回nil时返回nil，失败返回错误信息
+func (c *RedisdCache) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.EvalCtx(context.Background(), script, keys, args...)
+}
+
+// EvalCtx 同Eval，ctx用于控制超时和取消
+func (c *RedisdCache) EvalCtx(ctx context.Context, script string, keys []string, args ...interface{}) (res interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKeys := c.prefixKeys(keys)
+	host := c.keysHost(pKeys)
+	if host == "" {
+		return nil, errors.New("RedisdCache: Eval keys must be on the same host")
+	}
+
+	client, err := c.hostClient(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return cache.EvalScript(client, script, pKeys, args...)
+}
+
+// EvalSha 按sha1执行lua脚本，脚本未加载且执行过Eval或ScriptLoad时使用EVAL重试
+// 在key所在的主机上执行，所有key需要在同一台主机
+//   参数
+//     sha1: 脚本的sha1
+//     keys: 脚本的KEYS，会自动添加key前缀，至少要有一个
+//     args: 脚本的ARGV
+//   返回
+//     脚本的返回值，脚本返回nil时返回nil，失败返回错误信息
+func (c *RedisdCache) EvalSha(sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.EvalShaCtx(context.Background(), sha1, keys, args...)
+}
+
+// EvalShaCtx 同EvalSha，ctx用于控制超时和取消
+func (c *RedisdCache) EvalShaCtx(ctx context.Context, sha1 string, keys []string, args ...interface{}) (res interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKeys := c.prefixKeys(keys)
+	host := c.keysHost(pKeys)
+	if host == "" {
+		return nil, errors.New("RedisdCache: EvalSha keys must be on the same host")
+	}
+
+	client, err := c.hostClient(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return cache.EvalShaScript(client, sha1, pKeys, args...)
+}
+
+// ScriptLoad 在每台主机上加载lua脚本
+//   参数
+//     script: lua脚本
+//   返回
+//     脚本的sha1，失败返回错误信息
+func (c *RedisdCache) ScriptLoad(script string) (string, error) {
+	return c.ScriptLoadCtx(context.Background(), script)
+}
+
+// ScriptLoadCtx 同ScriptLoad，ctx用于控制超时和取消
+func (c *RedisdCache) ScriptLoadCtx(ctx context.Context, script string) (res string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	sha := cache.ScriptSha1(script)
+	for _, host := range c.ring.Nodes() {
+		client := c.nodeClient(ctx, host)
+		if client == nil {
+			continue
+		}
+
+		if err := client.ScriptLoad(script).Err(); err != nil {
+			return "", err
+		}
+	}
+
+	return sha, nil
+}
+
+// prefixKeys 给key添加前缀
+func (c *RedisdCache) prefixKeys(keys []string) []string {
+	pKeys := make([]string, len(keys))
+	for i, key := range keys {
+		pKeys[i] = c.prefix + key
+	}
+	return pKeys
+}
+
+// setOp 在客户端计算多个集合的交集或并集，用于key不在同一台主机的情况
+//   参数
+//     ctx:     上下文
+//     keys:    添加前缀后的集合key值
+//     isInter: true-交集，false-并集
+//   返回
+//     成功时返回成员，失败返回错误信息
+func (c *RedisdCache) setOp(ctx context.Context, keys []string, isInter bool) ([]string, error) {
+	count := make(map[string]int)
+	for _, key := range keys {
+		members, err := c.getClient(ctx, key).SMembers(key).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			count[member]++
+		}
+	}
+
+	res := make([]string, 0, len(count))
+	for member, n := range count {
+		if !isInter || n == len(keys) {
+			res = append(res, member)
+		}
+	}
+
+	return res, nil
+}
+
+// toValues 转换列表和流的元素，与HSet一致，按序列化和压缩配置转换
+func (c *RedisdCache) toValues(vals []interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(vals))
+	for i, val := range vals {
+		// 类型转换
+		data, err := cache.InterToByte(val, c.serializer)
+		if err != nil {
+			return nil, err
+		}
+
+		// 压缩判断
+		data, err = cache.Compress(data, c.compressType, c.compressThreshold)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = data
+	}
+
+	return args, nil
+}
+
+// toMembers 转换集合的成员，只做序列化
+func (c *RedisdCache) toMembers(members []interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(members))
+	for i, member := range members {
+		data, err := cache.InterToByte(member, c.serializer)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = data
+	}
+
+	return args, nil
+}
+
+// toXStreams 转换读取到的流消息，去掉key前缀并解压消息内容
+func (c *RedisdCache) toXStreams(streams []redis.XStream) ([]cache.XStream, error) {
+	res := make([]cache.XStream, len(streams))
+	for i, stream := range streams {
+		res[i].Stream = strings.TrimPrefix(stream.Stream, c.prefix)
+		res[i].Messages = make([]cache.XMessage, len(stream.Messages))
+		for j, msg := range stream.Messages {
+			values := make(map[string]interface{}, len(msg.Values))
+			for field, val := range msg.Values {
+				v, err := cache.UncompressString(val)
+				if err != nil {
+					return nil, err
+				}
+				values[field] = v
+			}
+			res[i].Messages[j] = cache.XMessage{ID: msg.ID, Values: values}
+		}
+	}
+
+	return res, nil
+}
+
+// prefixStreams 给XRead参数中的key添加前缀，前一半为key，后一半为id
+func (c *RedisdCache) prefixStreams(streams []string) []string {
+	res := make([]string, len(streams))
+	copy(res, streams)
+	if c.prefix != "" {
+		for i := 0; i < len(res)/2; i++ {
+			res[i] = c.prefix + res[i]
+		}
+	}
+
+	return res
+}
+
+// RunScript 执行lua脚本，优先使用EVALSHA，脚本未加载时使用EVAL
+//   参数
+//     ctx:    上下文
+//     script: lua脚本
+//     keys:   脚本的KEYS，会添加key前缀
+//     args:   脚本的ARGV
+//   返回
+//     脚本的返回值，失败返回错误信息
+func (c *RedisdCache) RunScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (res interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	// 所有key需要在同一台主机
+	pKeys := c.prefixKeys(keys)
+	host := c.keysHost(pKeys)
+	if host == "" {
+		return nil, errors.New("RedisdCache: RunScript keys must be on the same host")
+	}
+
+	client, err := c.hostClient(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return script.Run(client, pKeys, args...).Result()
+}
+
+// AcquireLock 获取分布式锁，使用SET NX PX，由cache/lock包使用
+//   参数
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 持有者的随机token
+//     ttl:   锁的过期时间
+//   返回
+//     获取到返回true，锁已存在返回false，失败返回错误信息
+func (c *RedisdCache) AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	return c.getClient(ctx, key).SetNX(key, token, ttl).Result()
+}
+
+// ReleaseLock 释放分布式锁，token一致时才删除
+//   参数
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 持有者的随机token
+//   返回
+//     删除成功返回true，锁不存在或不是持有者返回false，失败返回错误信息
+func (c *RedisdCache) ReleaseLock(ctx context.Context, key, token string) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	n, err := cache.LockReleaseScript.Run(c.getClient(ctx, key), []string{key}, token).Int64()
+	return n == 1, err
+}
+
+// RefreshLock 更新分布式锁的过期时间，token一致时才更新
+//   参数
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 持有者的随机token
+//     ttl:   新的过期时间
+//   返回
+//     更新成功返回true，锁不存在或不是持有者返回false，失败返回错误信息
+func (c *RedisdCache) RefreshLock(ctx context.Context, key, token string, ttl time.Duration) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+
+	n, err := cache.LockRefreshScript.Run(c.getClient(ctx, key), []string{key}, token, ttl.Milliseconds()).Int64()
+	return n == 1, err
+}
+
+// LockNodes 返回每台主机的锁，用于Redlock，由cache/lock包使用
+//   参数
+//
+//   返回
+//     每台主机对应的Locker，按主机排序
+func (c *RedisdCache) LockNodes() []cache.Locker {
+	hosts := c.ring.Nodes()
+	nodes := make([]cache.Locker, len(hosts))
+	for i, host := range hosts {
+		nodes[i] = &nodeLocker{c: c, host: host}
+	}
+	return nodes
+}
+
+// nodeLocker 一台主机上的分布式锁
+type nodeLocker struct {
+	c    *RedisdCache
+	host string
+}
+
+// AcquireLock 在这台主机上获取锁
+func (l *nodeLocker) AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	if l.c.prefix != "" {
+		key = l.c.prefix + key
+	}
+
+	client, err := l.c.hostClient(ctx, l.host)
+	if err != nil {
+		return false, err
+	}
+	return client.SetNX(key, token, ttl).Result()
+}
+
+// ReleaseLock 在这台主机上释放锁
+func (l *nodeLocker) ReleaseLock(ctx context.Context, key, token string) (bool, error) {
+	if l.c.prefix != "" {
+		key = l.c.prefix + key
+	}
+
+	client, err := l.c.hostClient(ctx, l.host)
+	if err != nil {
+		return false, err
+	}
+	n, err := cache.LockReleaseScript.Run(client, []string{key}, token).Int64()
+	return n == 1, err
+}
+
+// RefreshLock 在这台主机上更新锁的过期时间
+func (l *nodeLocker) RefreshLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	if l.c.prefix != "" {
+		key = l.c.prefix + key
+	}
+
+	client, err := l.c.hostClient(ctx, l.host)
+	if err != nil {
+		return false, err
+	}
+	n, err := cache.LockRefreshScript.Run(client, []string{key}, token, ttl.Milliseconds()).Int64()
+	return n == 1, err
+}
+
+// Publish 向频道发布消息，按频道名用一致性哈希选择主机，与Subscribe选择的主机一致
+//   参数
+//     channel: 频道，不添加key前缀
+//     msg:     消息内容
+//   返回
+//     收到消息的订阅者数、错误信息
+func (c *RedisdCache) Publish(channel string, msg interface{}) (int64, error) {
+	return c.PublishCtx(context.Background(), channel, msg)
+}
+
+// PublishCtx 同Publish，ctx用于控制超时和取消
+func (c *RedisdCache) PublishCtx(ctx context.Context, channel string, msg interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	client := c.getClient(ctx, channel)
+	if client == nil {
+		return 0, fmt.Errorf("RedisdCache: Channel %s has no host", channel)
+	}
+
+	return client.Publish(channel, msg).Result()
+}
+
+// subscription Subscribe的一个订阅，记录每个频道订阅在哪台主机上
+type subscription struct {
+	ctx      context.Context
+	out      chan *cache.Message
+	wg       sync.WaitGroup           // 转发消息的goroutine
+	channels map[string]string        // 频道对应的主机
+	hosts    map[string]*redis.PubSub // 主机对应的go-redis订阅
+}
+
+// Subscribe 订阅频道，按频道名用一致性哈希选择主机，断线后自动重连并重新订阅
+// AddNode、RemoveNode后频道所在的主机变化时，先在新主机订阅再取消原来主机的订阅，与Publish选择的主机保持一致
+// 哈希环变化到重新订阅完成之间发布的消息可能收不到
+//   参数
+//     ctx:      上下文，取消时取消订阅并关闭返回的channel
+//     channels: 频道，不添加key前缀
+//   返回
+//     消息channel、错误信息
+func (c *RedisdCache) Subscribe(ctx context.Context, channels ...string) (_ <-chan *cache.Message, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if len(channels) == 0 {
+		return nil, errors.New("RedisdCache: Subscribe channels is empty")
+	}
+
+	s := &subscription{
+		ctx:      ctx,
+		out:      make(chan *cache.Message, 100),
+		channels: make(map[string]string),
+		hosts:    make(map[string]*redis.PubSub),
+	}
+
+	c.subLock.Lock()
+	for host, idxs := range c.groupKeys(channels) {
+		client := c.nodeClient(context.Background(), host)
+		if client == nil {
+			err = fmt.Errorf("RedisdCache: Host %s not found", host)
+			break
+		}
+
+		group := make([]string, len(idxs))
+		for i, idx := range idxs {
+			group[i] = channels[idx]
+			s.channels[channels[idx]] = host
+		}
+		ps := client.Subscribe(group...)
+		s.hosts[host] = ps
+		if _, err = ps.Receive(); err != nil {
+			break
+		}
+	}
+	if err != nil {
+		c.subLock.Unlock()
+		for _, ps := range s.hosts {
+			ps.Close()
+		}
+		return nil, err
+	}
+
+	for _, ps := range s.hosts {
+		s.forward(ps)
+	}
+	if c.subs == nil {
+		c.subs = make(map[*subscription]struct{})
+	}
+	c.subs[s] = struct{}{}
+	c.subLock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		c.subLock.Lock()
+		delete(c.subs, s)
+		c.subLock.Unlock()
+		s.wg.Wait()
+		close(s.out)
+	}()
+
+	return s.out, nil
+}
+
+// forward 启动goroutine把ps收到的消息转发到订阅的channel，调用时要持有subLock
+func (s *subscription) forward(ps *redis.PubSub) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		cache.ForwardMessages(s.ctx, "", ps, s.out)
+	}()
+}
+
+// moveSubscriptions 哈希环变化后，把所在主机变化的频道移到新主机订阅
+// go-redis的订阅出错时会自动重连并重新订阅，所以订阅和取消订阅的错误不处理
+//   参数
+//     reset: 连接池被替换的主机，这台主机上的频道全部重新订阅，为空时没有
+//   返回
+//
+func (c *RedisdCache) moveSubscriptions(reset string) {
+	c.subLock.Lock()
+	defer c.subLock.Unlock()
+
+	for s := range c.subs {
+		// 主机已删除或连接池被替换时，原来的订阅不能再用
+		for host, ps := range s.hosts {
+			if host == reset || c.nodeClient(context.Background(), host) == nil {
+				ps.Close()
+				delete(s.hosts, host)
+			}
+		}
+
+		added := make(map[string][]string) // 新主机要订阅的频道
+		from := make(map[string]string)    // 移动的频道原来的主机
+		for channel, host := range s.channels {
+			newHost := c.ring.Get(channel)
+			if _, ok := s.hosts[host]; ok && newHost == host {
+				continue
+			}
+			added[newHost] = append(added[newHost], channel)
+			from[channel] = host
+		}
+
+		for host, channels := range added {
+			if ps, ok := s.hosts[host]; ok {
+				ps.Subscribe(channels...)
+			} else if client := c.nodeClient(context.Background(), host); client != nil {
+				ps = client.Subscribe(channels...)
+				s.hosts[host] = ps
+				s.forward(ps)
+			} else {
+				continue
+			}
+			for _, channel := range channels {
+				s.channels[channel] = host
+			}
+		}
+
+		// 新主机订阅后再取消原来主机的订阅，没有频道的订阅直接关闭
+		counts := make(map[string]int)
+		for _, host := range s.channels {
+			counts[host]++
+		}
+		removed := make(map[string][]string)
+		for channel, host := range from {
+			if s.channels[channel] != host {
+				removed[host] = append(removed[host], channel)
+			}
+		}
+		for host, channels := range removed {
+			ps, ok := s.hosts[host]
+			if !ok {
+				continue
+			}
+			if counts[host] == 0 {
+				ps.Close()
+				delete(s.hosts, host)
+			} else {
+				ps.Unsubscribe(channels...)
+			}
+		}
+	}
+}
+
+// SubscribeExpired 订阅带key前缀的key的过期通知，每台主机都订阅，redis需要配置notify-keyspace-events包含Ex
+// 订阅后AddNode新增的主机不会订阅
+//   参数
+//     ctx: 上下文，取消时取消订阅并关闭返回的channel
+//   返回
+//     消息channel，Payload为不含前缀的key，失败返回错误信息
+func (c *RedisdCache) SubscribeExpired(ctx context.Context) (_ <-chan *cache.Message, err error) {
+	defer cache.ConvRedisError(&err)
+
+	var pubsubs []*redis.PubSub
+	for _, host := range c.ring.Nodes() {
+		if client := c.nodeClient(context.Background(), host); client != nil {
+			pubsubs = append(pubsubs, client.PSubscribe(cache.KeyEventExpired))
+		}
+	}
+
+	return cache.ReceiveMessages(ctx, c.prefix, pubsubs...)
+}
+
+// Batch 新建批量命令，key前缀、序列化、压缩和加密与Set、Get等方法一致
+// 命令按key所在的主机分组，每台主机一个pipeline，事务模式下只保证同一主机的命令在一个MULTI/EXEC中
+//   参数
+//     isTx: 是否事务模式，为true时使用MULTI/EXEC执行
+//   返回
+//     批量命令
+func (c *RedisdCache) Batch(isTx bool) *cache.Batch {
+	return cache.NewBatch(isTx, c.execBatch)
+}
+
+// execBatch 按主机分组，使用pipeline执行批量命令
+// 所有主机的命令都加入pipeline后再执行，事务模式下有命令出错时都不执行
+func (c *RedisdCache) execBatch(ctx context.Context, cmds []*cache.BatchCmd, isTx bool) error {
+	keys := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		keys[i] = c.prefix + cmd.Key
+	}
+
+	type hostBatch struct {
+		pipe redis.Pipeliner
+		cmds []*cache.BatchCmd
+		res  []redis.Cmder
+	}
+	var batches []hostBatch
+	for host, idxs := range c.groupKeys(keys) {
+		hostCmds := make([]*cache.BatchCmd, len(idxs))
+		for i, idx := range idxs {
+			hostCmds[i] = cmds[idx]
+		}
+
+		client, err := c.hostClient(ctx, host)
+		if err != nil {
+			return err
+		}
+
+		var pipe redis.Pipeliner
+		if isTx {
+			pipe = client.TxPipeline()
+		} else {
+			pipe = client.Pipeline()
+		}
+
+		res, err := c.queueBatch(pipe, hostCmds, isTx)
+		if err != nil {
+			return err
+		}
+		batches = append(batches, hostBatch{pipe: pipe, cmds: hostCmds, res: res})
+	}
+
+	var firstErr error
+	for _, b := range batches {
+		// 每个命令的错误由batchResult处理
+		b.pipe.Exec()
+
+		err := c.batchResult(b.cmds, b.res)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// queueBatch 将批量命令加入pipeline，返回每个命令对应的redis命令
+// 值转换出错时，事务模式直接返回错误，否则只设置该命令的错误
+//   参数
+//     pipe: pipeline
+//     cmds: 批量命令
+//     isTx: 是否事务模式
+//   返回
+//     每个命令对应的redis命令，出错的命令为nil，失败返回错误信息
+func (c *RedisdCache) queueBatch(pipe redis.Pipeliner, cmds []*cache.BatchCmd, isTx bool) ([]redis.Cmder, error) {
+	res := make([]redis.Cmder, len(cmds))
+	for i, cmd := range cmds {
+		key := cmd.Key
+		if c.prefix != "" {
+			key = c.prefix + key
+		}
+
+		switch cmd.Op {
+		case cache.BatchSet, cache.BatchHSet:
+			// 类型转换
+			data, err := cache.InterToByte(cmd.Val, c.serializer)
+
+			// 压缩判断
+			if err == nil {
+				data, err = cache.Compress(data, c.compressType, c.compressThreshold)
+			}
+
+			// 加密判断，哈希表不加密
+			if err == nil && cmd.Op == cache.BatchSet && cmd.Encode {
+				data, err = cache.Encode(data, c.encodeKey...)
+			}
+
+			if err != nil {
+				if isTx {
+					return nil, err
+				}
+				cmd.SetResult(nil, 0, false, err)
+				continue
+			}
+
+			if cmd.Op == cache.BatchSet {
+				res[i] = pipe.Set(key, data, time.Duration(cmd.Expire)*time.Second)
+			} else {
+				res[i] = pipe.HSet(key, cmd.Fields[0], data)
+				if cmd.Expire > 0 {
+					pipe.Expire(key, time.Duration(cmd.Expire)*time.Second)
+				}
+			}
+		case cache.BatchGet:
+			res[i] = pipe.Get(key)
+		case cache.BatchDel:
+			res[i] = pipe.Del(key)
+		case cache.BatchIncr:
+			res[i] = pipe.IncrBy(key, cmd.Delta)
+		case cache.BatchExpire:
+			res[i] = pipe.Expire(key, time.Duration(cmd.Expire)*time.Second)
+		case cache.BatchHGet:
+			res[i] = pipe.HGet(key, cmd.Fields[0])
+		case cache.BatchHDel:
+			res[i] = pipe.HDel(key, cmd.Fields...)
+		case cache.BatchHIncr:
+			res[i] = pipe.HIncrBy(key, cmd.Fields[0], cmd.Delta)
+		default:
+			err := cache.NewError(cache.ErrUnsupported, fmt.Sprintf("RedisdCache: Batch don't support %s", cmd.Op))
+			if isTx {
+				return nil, err
+			}
+			cmd.SetResult(nil, 0, false, err)
+		}
+	}
+
+	return res, nil
+}
+
+// batchResult 设置批量命令的执行结果，Get的结果解密后解压，HGet的结果只解压
+//   参数
+//     cmds: 批量命令
+//     res:  每个命令对应的redis命令
+//   返回
+//     第一个出错命令的错误信息，key不存在不算错误
+func (c *RedisdCache) batchResult(cmds []*cache.BatchCmd, res []redis.Cmder) error {
+	var firstErr error
+	for i, cmd := range cmds {
+		switch r := res[i].(type) {
+		case nil:
+			// 加入pipeline前已出错
+		case *redis.StringCmd:
+			v, err := r.Result()
+			if err != nil {
+				if cache.IsMiss(err) {
+					cmd.SetResult(nil, 0, false, nil)
+				} else {
+					cmd.SetResult(nil, 0, false, err)
+				}
+				break
+			}
+
+			data := []byte(v)
+			if cmd.Op == cache.BatchGet {
+				data, err = cache.Decode(data, c.encodeKey...)
+			}
+			if err == nil {
+				data, err = cache.Uncompress(data)
+			}
+			cmd.SetResult(data, 0, true, err)
+		case *redis.IntCmd:
+			v, err := r.Result()
+			cmd.SetResult(nil, v, err == nil, err)
+		case *redis.BoolCmd:
+			v, err := r.Result()
+			var n int64
+			if v {
+				n = 1
+			}
+			cmd.SetResult(nil, n, v, err)
+		default:
+			err := r.Err()
+			cmd.SetResult(nil, 0, err == nil, err)
+		}
+
+		if err := cmd.Err(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
 }
 
-// Pipeline 执行pipeline命令
+// Pipeline 执行pipeline命令，所有命令都发送到同一台主机，不按key分片
 //   实例：
 //     pipe := rc.Pipeline(false).Pipe
 //     incr := pipe.Incr("pipeline_counter")
@@ -963,9 +3173,9 @@ func (c *RedisdCache) PFCount(key string) (int64, error) {
 func (c *RedisdCache) Pipeline(isTx bool) cache.Pipeliner {
 	p := cache.Pipeliner{}
 	if isTx {
-		p.Pipe = c.getClient().TxPipeline()
+		p.Pipe = c.getClient(nil, "").TxPipeline()
 	} else {
-		p.Pipe = c.getClient().Pipeline()
+		p.Pipe = c.getClient(nil, "").Pipeline()
 	}
 
 	return p
@@ -1,8 +1,13 @@
 package redisd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/lixy529/gotools/cache"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -928,3 +933,989 @@ func TestRedisdPipeline(t *testing.T) {
 	}
 	fmt.Println("r1:", r1.Val(), "r2:", r2.Val(), "r3:", r3.Val(), "r4:", r4.Val())
 }
+
+func TestRedisdCtx(t *testing.T) {
+	var adapter cache.ContextCache = &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	k1 := "ctx_k1"
+	v1 := "HelloWorld"
+	err = adapter.SetCtx(ctx, k1, v1, 10)
+	if err != nil {
+		t.Errorf("Redisd SetCtx failed. err: %s.", err.Error())
+		return
+	}
+
+	var v11 string
+	err, _ = adapter.GetCtx(ctx, k1, &v11)
+	if err != nil {
+		t.Errorf("Redisd GetCtx failed. err: %s.", err.Error())
+		return
+	} else if v11 != v1 {
+		t.Errorf("Redisd GetCtx failed. Got %s, expected %s.", v11, v1)
+		return
+	}
+
+	// 已取消的ctx
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	cancel2()
+	err, _ = adapter.GetCtx(ctx2, k1, &v11)
+	if err == nil {
+		t.Errorf("Redisd GetCtx failed. expected context error.")
+		return
+	}
+}
+
+func TestRedisdCompress(t *testing.T) {
+	mapCfg := make(map[string]string)
+	json.Unmarshal([]byte(gConfig), &mapCfg)
+	mapCfg["compressType"] = "snappy"
+	mapCfg["compressThreshold"] = "10"
+	config, _ := json.Marshal(mapCfg)
+
+	adapter := &RedisdCache{}
+	err := adapter.Init(string(config))
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+	plain := &RedisdCache{}
+	plain.Init(gConfig)
+
+	k1 := "cmp_k1"
+	v1 := User{Id: 1001, Name: "HelloWorld HelloWorld HelloWorld"}
+	err = adapter.Set(k1, v1, 60)
+	if err != nil {
+		t.Errorf("Redisd Set failed. err: %s.", err.Error())
+		return
+	}
+
+	// 保存的数据是压缩过的
+	raw, _ := adapter.getClient(context.Background(), "le_"+k1).Get("le_" + k1).Result()
+	if len(raw) < cache.COMPRESS_LEN || raw[:cache.COMPRESS_LEN] != cache.COMPRESS_FLAG+"s" {
+		t.Errorf("Redisd Set failed. value is not compressed: %q.", raw)
+		return
+	}
+
+	var v11 User
+	err, _ = adapter.Get(k1, &v11)
+	if err != nil {
+		t.Errorf("Redisd Get failed. err: %s.", err.Error())
+		return
+	} else if v11.Id != v1.Id || v11.Name != v1.Name {
+		t.Errorf("Redisd Get failed. Got %d-%s, expected %d-%s.", v11.Id, v11.Name, v1.Id, v1.Name)
+		return
+	}
+
+	// 没配置压缩的也能读
+	err, _ = plain.Get(k1, &v11)
+	if err != nil || v11.Name != v1.Name {
+		t.Errorf("Redisd Get failed. Got %s, err: %v.", v11.Name, err)
+		return
+	}
+
+	mList, err := adapter.MGet(k1)
+	if err != nil {
+		t.Errorf("Redisd MGet failed. err: %s.", err.Error())
+		return
+	} else if s, _ := mList[k1].(string); len(s) == 0 || s[0] != '{' {
+		t.Errorf("Redisd MGet failed. Got %v.", mList[k1])
+		return
+	}
+
+	// 哈希表
+	k2 := "cmp_h1"
+	v2 := "HelloWorld HelloWorld HelloWorld"
+	_, err = adapter.HSet(k2, "f1", v2, 60)
+	if err != nil {
+		t.Errorf("Redisd HSet failed. err: %s.", err.Error())
+		return
+	}
+	var v22 string
+	err, _ = adapter.HGet(k2, "f1", &v22)
+	if err != nil || v22 != v2 {
+		t.Errorf("Redisd HGet failed. Got %s, err: %v.", v22, err)
+		return
+	}
+	hList, err := adapter.HGetAll(k2)
+	if err != nil || hList["f1"] != v2 {
+		t.Errorf("Redisd HGetAll failed. Got %v, err: %v.", hList["f1"], err)
+		return
+	}
+
+	adapter.Del(k1)
+	adapter.Del(k2)
+}
+
+func TestRedisdShard(t *testing.T) {
+	adapter := &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+
+	// 同一个key总是路由到同一台主机
+	mList := make(map[string]interface{})
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("shard_k%d", i)
+		mList[key] = fmt.Sprintf("v%d", i)
+		if adapter.ring.Get("le_"+key) != adapter.ring.Get("le_"+key) {
+			t.Errorf("Redisd ring failed. %s is not stable.", key)
+			return
+		}
+	}
+
+	err = adapter.MSet(mList, 60)
+	if err != nil {
+		t.Errorf("Redisd MSet failed. err: %s.", err.Error())
+		return
+	}
+
+	// 数据在key所在的主机上
+	for key := range mList {
+		host := adapter.ring.Get("le_" + key)
+		n, err := adapter.nodeClient(context.Background(), host).Exists("le_" + key).Result()
+		if err != nil || n != 1 {
+			t.Errorf("Redisd MSet failed. %s is not on %s.", key, host)
+			return
+		}
+	}
+
+	keys := make([]string, 0, len(mList))
+	for key := range mList {
+		keys = append(keys, key)
+	}
+	res, err := adapter.MGet(keys...)
+	if err != nil {
+		t.Errorf("Redisd MGet failed. err: %s.", err.Error())
+		return
+	}
+	for key, val := range mList {
+		if res[key] != val {
+			t.Errorf("Redisd MGet failed. Got %v, expected %v.", res[key], val)
+			return
+		}
+	}
+
+	err = adapter.MDel(keys...)
+	if err != nil {
+		t.Errorf("Redisd MDel failed. err: %s.", err.Error())
+		return
+	}
+	res, _ = adapter.MGet(keys...)
+	for key := range mList {
+		if res[key] != nil {
+			t.Errorf("Redisd MDel failed. %s is exist.", key)
+			return
+		}
+	}
+
+	// 删除主机
+	nodes := adapter.Nodes()
+	if err = adapter.RemoveNode(nodes[0]); err != nil {
+		t.Errorf("Redisd RemoveNode failed. err: %s.", err.Error())
+		return
+	}
+	if len(adapter.Nodes()) != len(nodes)-1 {
+		t.Errorf("Redisd RemoveNode failed. Got %v.", adapter.Nodes())
+		return
+	}
+	for i := 0; i < 20; i++ {
+		if adapter.ring.Get(fmt.Sprintf("le_shard_k%d", i)) == nodes[0] {
+			t.Errorf("Redisd RemoveNode failed. key is still on %s.", nodes[0])
+			return
+		}
+	}
+
+	// 不能删除最后一台主机
+	nodes = adapter.Nodes()
+	for _, host := range nodes[1:] {
+		adapter.RemoveNode(host)
+	}
+	if err = adapter.RemoveNode(nodes[0]); err == nil {
+		t.Error("Redisd RemoveNode failed. Remove the last host expected error.")
+		return
+	}
+	if _, err = adapter.IsExist("shard_k1"); err != nil {
+		t.Errorf("Redisd IsExist failed. err: %s.", err.Error())
+		return
+	}
+
+	// 主机为空时Init返回错误
+	if err = (&RedisdCache{}).Init(`{"addr":"127.0.0.1:6379,"}`); err == nil {
+		t.Error("Redisd Init failed. Empty host expected error.")
+	}
+}
+
+func TestRedisdScan(t *testing.T) {
+	adapter := &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+
+	// 其它前缀的数据
+	mapCfg := make(map[string]string)
+	json.Unmarshal([]byte(gConfig), &mapCfg)
+	mapCfg["prefix"] = "other_"
+	config, _ := json.Marshal(mapCfg)
+	other := &RedisdCache{}
+	other.Init(string(config))
+	other.Set("scan_k1", "v1", 60)
+	defer other.Del("scan_k1")
+
+	for i := 0; i < 20; i++ {
+		adapter.Set(fmt.Sprintf("scan_k%d", i), i, 60)
+	}
+	adapter.Set("noscan_k1", "v1", 60)
+
+	// Scan返回的key不含前缀
+	keys := make(map[string]bool)
+	it := adapter.Scan("scan_*", 5)
+	for it.Next() {
+		keys[it.Key()] = true
+	}
+	if it.Err() != nil {
+		t.Errorf("Redisd Scan failed. err: %s.", it.Err().Error())
+		return
+	} else if len(keys) != 20 || !keys["scan_k0"] || !keys["scan_k19"] {
+		t.Errorf("Redisd Scan failed. Got %d keys, expected 20.", len(keys))
+		return
+	}
+
+	// ClearAll只删除本前缀的数据
+	err = adapter.ClearAll()
+	if err != nil {
+		t.Errorf("Redisd ClearAll failed. err: %s.", err.Error())
+		return
+	}
+	if ok, _ := adapter.IsExist("noscan_k1"); ok {
+		t.Error("Redisd ClearAll failed. noscan_k1 is exist.")
+	}
+	if ok, _ := other.IsExist("scan_k1"); !ok {
+		t.Error("Redisd ClearAll failed. other_scan_k1 is deleted.")
+	}
+}
+
+func TestRedisdBatch(t *testing.T) {
+	mapCfg := make(map[string]string)
+	json.Unmarshal([]byte(gConfig), &mapCfg)
+	mapCfg["encodeKey"] = "abcdefghij123456"
+	config, _ := json.Marshal(mapCfg)
+
+	adapter := &RedisdCache{}
+	err := adapter.Init(string(config))
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.MDel("batch_k1", "batch_k2", "batch_n1", "batch_h1")
+
+	for i, isTx := range []bool{false, true} {
+		b := adapter.Batch(isTx)
+		b.Set("batch_k1", User{Id: 1001, Name: "Diego"}, 60, true)
+		b.HSet("batch_h1", "f1", "hv1", 60)
+		get := b.Get("batch_k1")
+		miss := b.Get("batch_k2")
+		hget := b.HGet("batch_h1", "f1")
+		incr := b.Incr("batch_n1", 2)
+		decr := b.Decr("batch_n1")
+		if b.Len() != 7 {
+			t.Errorf("Redisd Batch failed. Got %d commands, expected 7.", b.Len())
+		}
+		err = b.Exec()
+		if err != nil {
+			t.Errorf("Redisd Batch Exec failed. err: %s.", err.Error())
+			return
+		}
+
+		var u User
+		err, exist := get.Scan(&u)
+		if err != nil || !exist || u.Id != 1001 || u.Name != "Diego" {
+			t.Errorf("Redisd Batch Get failed. Got %v, expected 1001-Diego.", u)
+		}
+		var s string
+		if err, exist = miss.Scan(&s); err != nil || exist {
+			t.Errorf("Redisd Batch Get failed. batch_k2 is exist.")
+		}
+		if err, exist = hget.Scan(&s); err != nil || !exist || s != "hv1" {
+			t.Errorf("Redisd Batch HGet failed. Got %s, expected hv1.", s)
+		}
+		if n, _ := incr.Int(); n != int64(2+i) {
+			t.Errorf("Redisd Batch Incr failed. Got %d, expected %d.", n, 2+i)
+		}
+		if n, _ := decr.Int(); n != int64(1+i) {
+			t.Errorf("Redisd Batch Decr failed. Got %d, expected %d.", n, 1+i)
+		}
+	}
+
+	// 与普通方法使用相同的前缀和加密
+	var u User
+	err, exist := adapter.Get("batch_k1", &u)
+	if err != nil || !exist || u.Id != 1001 {
+		t.Errorf("Redisd Get failed. Got %v, expected 1001.", u)
+	}
+
+	// 队列执行后清空
+	b := adapter.Batch(false)
+	b.Del("batch_k1")
+	b.Exec()
+	if b.Len() != 0 {
+		t.Errorf("Redisd Batch failed. Got %d commands, expected 0.", b.Len())
+	}
+	if ok, _ := adapter.IsExist("batch_k1"); ok {
+		t.Error("Redisd Batch Del failed. batch_k1 is exist.")
+	}
+	adapter.MDel("batch_n1", "batch_h1")
+}
+
+func TestRedisdList(t *testing.T) {
+	mapCfg := make(map[string]string)
+	json.Unmarshal([]byte(gConfig), &mapCfg)
+	mapCfg["compressType"] = "snappy"
+	mapCfg["compressThreshold"] = "10"
+	config, _ := json.Marshal(mapCfg)
+
+	adapter := &RedisdCache{}
+	err := adapter.Init(string(config))
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.MDel("list_l1", "list_l2")
+
+	n, err := adapter.RPush("list_l1", 60, "v1", "v2")
+	if err != nil || n != 2 {
+		t.Errorf("Redisd RPush failed. Got %d, expected 2.", n)
+	}
+	n, err = adapter.LPush("list_l1", 60, strings.Repeat("a", 100), "v0")
+	if err != nil || n != 4 {
+		t.Errorf("Redisd LPush failed. Got %d, expected 4.", n)
+	}
+
+	vals, err := adapter.LRange("list_l1", 0, -1)
+	if err != nil || len(vals) != 4 || vals[0] != "v0" || vals[1] != strings.Repeat("a", 100) || vals[3] != "v2" {
+		t.Errorf("Redisd LRange failed. Got %v.", vals)
+	}
+
+	err = adapter.LTrim("list_l1", 0, 2)
+	if err != nil {
+		t.Errorf("Redisd LTrim failed. err: %s.", err.Error())
+	}
+
+	var s string
+	err, exist := adapter.LPop("list_l1", &s)
+	if err != nil || !exist || s != "v0" {
+		t.Errorf("Redisd LPop failed. Got %s, expected v0.", s)
+	}
+
+	key, err := adapter.BRPop(1, &s, "list_l2", "list_l1")
+	if err != nil || key != "list_l1" || s != "v1" {
+		t.Errorf("Redisd BRPop failed. Got %s-%s, expected list_l1-v1.", key, s)
+	}
+
+	key, err = adapter.BRPop(1, &s, "list_l2")
+	if err != nil || key != "" {
+		t.Errorf("Redisd BRPop failed. Got %s, expected empty.", key)
+	}
+
+	adapter.MDel("list_l1")
+	err, exist = adapter.LPop("list_l1", &s)
+	if err != nil || exist {
+		t.Errorf("Redisd LPop failed. list_l1 is exist.")
+	}
+}
+
+func TestRedisdSAdd(t *testing.T) {
+	adapter := &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.MDel("set_s1", "set_s2")
+
+	n, err := adapter.SAdd("set_s1", 60, "a", "b", "c", "a")
+	if err != nil || n != 3 {
+		t.Errorf("Redisd SAdd failed. Got %d, expected 3.", n)
+	}
+	n, err = adapter.SAdd("set_s2", 60, "b", "c", "d")
+	if err != nil || n != 3 {
+		t.Errorf("Redisd SAdd failed. Got %d, expected 3.", n)
+	}
+
+	ok, err := adapter.SIsMember("set_s1", "a")
+	if err != nil || !ok {
+		t.Errorf("Redisd SIsMember failed. a is not member.")
+	}
+
+	n, err = adapter.SRem("set_s1", "a", "x")
+	if err != nil || n != 1 {
+		t.Errorf("Redisd SRem failed. Got %d, expected 1.", n)
+	}
+
+	ok, err = adapter.SIsMember("set_s1", "a")
+	if err != nil || ok {
+		t.Errorf("Redisd SIsMember failed. a is member.")
+	}
+
+	members, err := adapter.SMembers("set_s1")
+	sort.Strings(members)
+	if err != nil || strings.Join(members, ",") != "b,c" {
+		t.Errorf("Redisd SMembers failed. Got %v, expected [b c].", members)
+	}
+
+	members, err = adapter.SInter("set_s1", "set_s2")
+	sort.Strings(members)
+	if err != nil || strings.Join(members, ",") != "b,c" {
+		t.Errorf("Redisd SInter failed. Got %v, expected [b c].", members)
+	}
+
+	members, err = adapter.SUnion("set_s1", "set_s2")
+	sort.Strings(members)
+	if err != nil || strings.Join(members, ",") != "b,c,d" {
+		t.Errorf("Redisd SUnion failed. Got %v, expected [b c d].", members)
+	}
+}
+
+func TestRedisdStream(t *testing.T) {
+	adapter := &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.MDel("stream_x1")
+
+	id1, err := adapter.XAdd("stream_x1", 100, 60, map[string]interface{}{"name": "Diego", "age": 20})
+	if err != nil || id1 == "" {
+		t.Errorf("Redisd XAdd failed. err: %v.", err)
+		return
+	}
+	id2, err := adapter.XAdd("stream_x1", 100, 60, map[string]interface{}{"name": "Lily"})
+	if err != nil || id2 == "" {
+		t.Errorf("Redisd XAdd failed. err: %v.", err)
+		return
+	}
+
+	res, err := adapter.XRead(&cache.XReadArgs{Streams: []string{"stream_x1", "0"}})
+	if err != nil || len(res) != 1 || res[0].Stream != "stream_x1" || len(res[0].Messages) != 2 {
+		t.Errorf("Redisd XRead failed. Got %v, err: %v.", res, err)
+		return
+	}
+	msg := res[0].Messages[0]
+	if msg.ID != id1 || msg.Values["name"] != "Diego" || msg.Values["age"] != "20" {
+		t.Errorf("Redisd XRead failed. Got %v.", msg)
+	}
+
+	res, err = adapter.XRead(&cache.XReadArgs{Streams: []string{"stream_x1", id2}})
+	if err != nil || len(res) != 0 {
+		t.Errorf("Redisd XRead failed. Got %v, expected empty.", res)
+	}
+
+	err = adapter.XGroupCreate("stream_x1", "g1", "0")
+	if err != nil {
+		t.Errorf("Redisd XGroupCreate failed. err: %s.", err.Error())
+	}
+	err = adapter.XGroupCreate("stream_x1", "g1", "0")
+	if err != nil {
+		t.Errorf("Redisd XGroupCreate failed. err: %s.", err.Error())
+	}
+
+	res, err = adapter.XReadGroup(&cache.XReadGroupArgs{Group: "g1", Consumer: "c1", Streams: []string{"stream_x1", ">"}, Count: 1})
+	if err != nil || len(res) != 1 || len(res[0].Messages) != 1 || res[0].Messages[0].ID != id1 {
+		t.Errorf("Redisd XReadGroup failed. Got %v, err: %v.", res, err)
+	}
+
+	n, err := adapter.XAck("stream_x1", "g1", id1)
+	if err != nil || n != 1 {
+		t.Errorf("Redisd XAck failed. Got %d, expected 1.", n)
+	}
+
+	res, err = adapter.XReadGroup(&cache.XReadGroupArgs{Group: "g1", Consumer: "c1", Streams: []string{"stream_x1", ">"}})
+	if err != nil || len(res) != 1 || len(res[0].Messages) != 1 || res[0].Messages[0].ID != id2 {
+		t.Errorf("Redisd XReadGroup failed. Got %v, err: %v.", res, err)
+	}
+}
+
+func TestRedisdLock(t *testing.T) {
+	adapter := &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("lock_k1")
+
+	ctx := context.Background()
+	ok, err := adapter.AcquireLock(ctx, "lock_k1", "token1", 500*time.Millisecond)
+	if err != nil || !ok {
+		t.Errorf("Redisd AcquireLock failed. err: %v.", err)
+		return
+	}
+	ok, err = adapter.AcquireLock(ctx, "lock_k1", "token2", time.Second)
+	if err != nil || ok {
+		t.Errorf("Redisd AcquireLock failed. lock_k1 is not locked.")
+	}
+
+	ok, err = adapter.RefreshLock(ctx, "lock_k1", "token2", time.Second)
+	if err != nil || ok {
+		t.Errorf("Redisd RefreshLock failed. token2 is not owner.")
+	}
+	ok, err = adapter.RefreshLock(ctx, "lock_k1", "token1", 2*time.Second)
+	if err != nil || !ok {
+		t.Errorf("Redisd RefreshLock failed. err: %v.", err)
+	}
+
+	ok, err = adapter.ReleaseLock(ctx, "lock_k1", "token2")
+	if err != nil || ok {
+		t.Errorf("Redisd ReleaseLock failed. token2 is not owner.")
+	}
+	ok, err = adapter.ReleaseLock(ctx, "lock_k1", "token1")
+	if err != nil || !ok {
+		t.Errorf("Redisd ReleaseLock failed. err: %v.", err)
+	}
+
+	ok, err = adapter.AcquireLock(ctx, "lock_k1", "token2", time.Second)
+	if err != nil || !ok {
+		t.Errorf("Redisd AcquireLock failed. err: %v.", err)
+	}
+	adapter.Del("lock_k1")
+}
+
+func TestRedisdLockNodes(t *testing.T) {
+	adapter := &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+
+	nodes := adapter.LockNodes()
+	if len(nodes) != 2 {
+		t.Errorf("Redisd LockNodes failed. Got %d, expected 2.", len(nodes))
+	}
+}
+
+func TestRedisdEval(t *testing.T) {
+	adapter := &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("eval_k1")
+
+	// IncrEx
+	n, err := adapter.IncrEx("eval_k1", 5, 100)
+	if err != nil || n != 5 {
+		t.Errorf("Redisd IncrEx failed. Got %d, expected 5, err: %v.", n, err)
+	}
+	n, err = adapter.IncrEx("eval_k1", -2, 100)
+	if err != nil || n != 3 {
+		t.Errorf("Redisd IncrEx failed. Got %d, expected 3, err: %v.", n, err)
+	}
+	ttl, err := adapter.getClient(context.Background(), "le_eval_k1").TTL("le_eval_k1").Result()
+	if err != nil || ttl <= 0 || ttl > 100*time.Second {
+		t.Errorf("Redisd IncrEx failed. TTL got %v, err: %v.", ttl, err)
+	}
+
+	// Eval，KEYS添加前缀
+	script := `redis.call("set", KEYS[1], ARGV[1]) return KEYS[1]`
+	v, err := adapter.Eval(script, []string{"eval_k1"}, "v1")
+	if err != nil || v != "le_eval_k1" {
+		t.Errorf("Redisd Eval failed. Got %v, expected le_eval_k1, err: %v.", v, err)
+	}
+	v, err = adapter.Eval(`return redis.call("get", KEYS[1])`, []string{"eval_k1"})
+	if err != nil || v != "v1" {
+		t.Errorf("Redisd Eval failed. Got %v, expected v1, err: %v.", v, err)
+	}
+	v, err = adapter.Eval(`return nil`, []string{"eval_k1"})
+	if err != nil || v != nil {
+		t.Errorf("Redisd Eval failed. Got %v, expected nil, err: %v.", v, err)
+	}
+
+	// EvalSha，脚本被清除后使用EVAL重试
+	sha, err := adapter.ScriptLoad(`return redis.call("incrby", KEYS[1], ARGV[1])`)
+	if err != nil || len(sha) != 40 {
+		t.Errorf("Redisd ScriptLoad failed. Got %s, err: %v.", sha, err)
+		return
+	}
+	adapter.Del("eval_k1")
+	v, err = adapter.EvalSha(sha, []string{"eval_k1"}, 2)
+	if err != nil || v != int64(2) {
+		t.Errorf("Redisd EvalSha failed. Got %v, expected 2, err: %v.", v, err)
+	}
+	for _, host := range adapter.ring.Nodes() {
+		adapter.nodeClient(context.Background(), host).ScriptFlush()
+	}
+	v, err = adapter.EvalSha(sha, []string{"eval_k1"}, 3)
+	if err != nil || v != int64(5) {
+		t.Errorf("Redisd EvalSha failed. Got %v, expected 5, err: %v.", v, err)
+	}
+	_, err = adapter.EvalSha("0000000000000000000000000000000000000000", []string{"eval_k1"})
+	if !cache.IsNoScript(err) {
+		t.Errorf("Redisd EvalSha failed. Got %v, expected NOSCRIPT.", err)
+	}
+
+	// key不在同一台主机
+	keys := []string{"eval_k1"}
+	for i := 0; len(keys) < 2; i++ {
+		k := fmt.Sprintf("eval_k%d", i)
+		if adapter.ring.Get("le_"+k) != adapter.ring.Get("le_eval_k1") {
+			keys = append(keys, k)
+		}
+	}
+	_, err = adapter.Eval(script, keys, "v1")
+	if err == nil {
+		t.Errorf("Redisd Eval failed. keys %v are not on the same host.", keys)
+	}
+	adapter.Del("eval_k1")
+}
+
+func TestRedisdCas(t *testing.T) {
+	adapter := &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("cas_k1")
+
+	// Add、Replace
+	ok, err := adapter.Replace("cas_k1", "v0", 60)
+	if err != nil || ok {
+		t.Errorf("Redisd Replace failed. cas_k1 is not exist, err: %v.", err)
+	}
+	ok, err = adapter.Add("cas_k1", "v1", 60)
+	if err != nil || !ok {
+		t.Errorf("Redisd Add failed. err: %v.", err)
+	}
+	ok, err = adapter.Add("cas_k1", "v2", 60)
+	if err != nil || ok {
+		t.Errorf("Redisd Add failed. cas_k1 is exist, err: %v.", err)
+	}
+	ok, err = adapter.Replace("cas_k1", map[string]int{"n": 1}, 60)
+	if err != nil || !ok {
+		t.Errorf("Redisd Replace failed. err: %v.", err)
+	}
+
+	// GetWithVersion、CompareAndSwap
+	doc := map[string]int{}
+	ver, err := adapter.GetWithVersion("cas_k1", &doc)
+	if err != nil || ver == nil || doc["n"] != 1 {
+		t.Errorf("Redisd GetWithVersion failed. Got %v, err: %v.", doc, err)
+		return
+	}
+	doc["n"]++
+	ok, err = adapter.CompareAndSwap(ver, doc, 60)
+	if err != nil || !ok {
+		t.Errorf("Redisd CompareAndSwap failed. err: %v.", err)
+	}
+	ok, err = adapter.CompareAndSwap(ver, doc, 60)
+	if err != nil || ok {
+		t.Errorf("Redisd CompareAndSwap failed. version is changed, err: %v.", err)
+	}
+
+	ver, err = adapter.GetWithVersion("cas_k1", &doc)
+	if err != nil || ver == nil || doc["n"] != 2 {
+		t.Errorf("Redisd GetWithVersion failed. Got %v, err: %v.", doc, err)
+		return
+	}
+	adapter.Set("cas_k1", "v3", 60)
+	ok, err = adapter.CompareAndSwap(ver, "v4", 60)
+	if err != nil || ok {
+		t.Errorf("Redisd CompareAndSwap failed. cas_k1 is modified, err: %v.", err)
+	}
+
+	adapter.Del("cas_k1")
+	ver, err = adapter.GetWithVersion("cas_k1", &doc)
+	if err != nil || ver != nil {
+		t.Errorf("Redisd GetWithVersion failed. cas_k1 is not exist, err: %v.", err)
+	}
+}
+
+func TestRedisdTTL(t *testing.T) {
+	adapter := &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("ttl_k1")
+
+	ttl, err := adapter.TTL("ttl_k1")
+	if err != nil || ttl != cache.TTL_NOT_EXIST {
+		t.Errorf("Redisd TTL failed. Got %v, expected %v, err: %v.", ttl, cache.TTL_NOT_EXIST, err)
+	}
+	adapter.Set("ttl_k1", "v1", 0)
+	ttl, err = adapter.TTL("ttl_k1")
+	if err != nil || ttl != cache.TTL_PERSIST {
+		t.Errorf("Redisd TTL failed. Got %v, expected %v, err: %v.", ttl, cache.TTL_PERSIST, err)
+	}
+
+	// Expire、ExpireAt
+	ok, err := adapter.Expire("ttl_k1", 10*time.Second)
+	if err != nil || !ok {
+		t.Errorf("Redisd Expire failed. err: %v.", err)
+	}
+	ttl, err = adapter.TTL("ttl_k1")
+	if err != nil || ttl <= 9*time.Second || ttl > 10*time.Second {
+		t.Errorf("Redisd TTL failed. Got %v, expected 10s, err: %v.", ttl, err)
+	}
+	ok, err = adapter.ExpireAt("ttl_k1", time.Now().Add(time.Minute))
+	if err != nil || !ok {
+		t.Errorf("Redisd ExpireAt failed. err: %v.", err)
+	}
+	ttl, err = adapter.TTL("ttl_k1")
+	if err != nil || ttl <= 58*time.Second || ttl > time.Minute {
+		t.Errorf("Redisd TTL failed. Got %v, expected 1m, err: %v.", ttl, err)
+	}
+	ok, err = adapter.Expire("ttl_k2", time.Second)
+	if err != nil || ok {
+		t.Errorf("Redisd Expire failed. ttl_k2 is not exist, err: %v.", err)
+	}
+
+	// Persist、Touch
+	ok, err = adapter.Persist("ttl_k1")
+	if err != nil || !ok {
+		t.Errorf("Redisd Persist failed. err: %v.", err)
+	}
+	if ttl, _ = adapter.TTL("ttl_k1"); ttl != cache.TTL_PERSIST {
+		t.Errorf("Redisd Persist failed. TTL got %v.", ttl)
+	}
+	ok, err = adapter.Touch("ttl_k1", 100)
+	if err != nil || !ok {
+		t.Errorf("Redisd Touch failed. err: %v.", err)
+	}
+	if ttl, _ = adapter.TTL("ttl_k1"); ttl <= 99*time.Second || ttl > 100*time.Second {
+		t.Errorf("Redisd Touch failed. TTL got %v.", ttl)
+	}
+	ok, err = adapter.Touch("ttl_k1", 0)
+	if err != nil || !ok {
+		t.Errorf("Redisd Touch failed. err: %v.", err)
+	}
+	if ttl, _ = adapter.TTL("ttl_k1"); ttl != cache.TTL_PERSIST {
+		t.Errorf("Redisd Touch failed. TTL got %v.", ttl)
+	}
+
+	// GetEx
+	v := ""
+	err, exist := adapter.GetEx("ttl_k1", &v, 200)
+	if err != nil || !exist || v != "v1" {
+		t.Errorf("Redisd GetEx failed. Got %s, err: %v.", v, err)
+	}
+	if ttl, _ = adapter.TTL("ttl_k1"); ttl <= 199*time.Second || ttl > 200*time.Second {
+		t.Errorf("Redisd GetEx failed. TTL got %v.", ttl)
+	}
+	err, exist = adapter.GetEx("ttl_k2", &v, 200)
+	if err != nil || exist {
+		t.Errorf("Redisd GetEx failed. ttl_k2 is not exist, err: %v.", err)
+	}
+
+	// 过期时间小于等于0时删除
+	ok, err = adapter.Expire("ttl_k1", 0)
+	if err != nil || !ok {
+		t.Errorf("Redisd Expire failed. err: %v.", err)
+	}
+	if exist, _ = adapter.IsExist("ttl_k1"); exist {
+		t.Errorf("Redisd Expire failed. ttl_k1 is not deleted.")
+	}
+}
+
+func TestRedisdHook(t *testing.T) {
+	adapter := &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+	collector := cache.NewCollector("")
+	adapter.AddHook(collector)
+	adapter.Del("hook_k1")
+	adapter.Del("hook_k2")
+
+	v := ""
+	adapter.Set("hook_k1", "v1", 100)
+	adapter.Get("hook_k1", &v)
+	adapter.Get("hook_k2", &v)
+	adapter.Get("hook_k1", &v)
+	if r := collector.HitRatio(cache.AdapterRedisd); r < 0.66 || r > 0.67 {
+		t.Errorf("Redisd HitRatio failed. Got %f, expected 0.67.", r)
+	}
+
+	body := string(collector.Bytes())
+	for _, line := range []string{
+		`cache_commands_total{adapter="redisd",cmd="set"} 1`,
+		`cache_hits_total{adapter="redisd",cmd="get"} 2`,
+		`cache_misses_total{adapter="redisd",cmd="get"} 1`,
+		`cache_errors_total{adapter="redisd",cmd="get"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Redisd Hook failed. %s not found in:\n%s", line, body)
+		}
+	}
+	adapter.Del("hook_k1")
+}
+
+func TestRedisdPubSub(t *testing.T) {
+	adapter := &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := adapter.Subscribe(ctx, "ps_ch1", "ps_ch2")
+	if err != nil {
+		t.Errorf("Redisd Subscribe failed. err: %s.", err.Error())
+		return
+	}
+	expCh, err := adapter.SubscribeExpired(ctx)
+	if err != nil {
+		t.Errorf("Redisd SubscribeExpired failed. err: %s.", err.Error())
+		return
+	}
+
+	n, err := adapter.Publish("ps_ch2", "hello")
+	if err != nil || n != 1 {
+		t.Errorf("Redisd Publish failed. Got %d, expected 1, err: %v.", n, err)
+	}
+	select {
+	case msg := <-ch:
+		if msg.Channel != "ps_ch2" || msg.Payload != "hello" {
+			t.Errorf("Redisd Subscribe failed. Got %s %s, expected ps_ch2 hello.", msg.Channel, msg.Payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Redisd Subscribe failed. Receive timeout.")
+	}
+
+	// 模拟过期通知，不带前缀的key不转发
+	adapter.Publish("__keyevent@0__:expired", "other_k1")
+	adapter.Publish("__keyevent@0__:expired", "le_ps_k1")
+	select {
+	case msg := <-expCh:
+		if msg.Payload != "ps_k1" || msg.Pattern != cache.KeyEventExpired {
+			t.Errorf("Redisd SubscribeExpired failed. Got %s %s, expected ps_k1.", msg.Pattern, msg.Payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Redisd SubscribeExpired failed. Receive timeout.")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Errorf("Redisd Subscribe failed. Channel is not closed.")
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Redisd Subscribe failed. Close timeout.")
+	}
+
+	if _, err = adapter.Subscribe(context.Background()); err == nil {
+		t.Errorf("Redisd Subscribe failed. Channels is empty.")
+	}
+}
+
+func TestRedisdPubSubAddNode(t *testing.T) {
+	adapter := &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	channels := make([]string, 50)
+	for i := range channels {
+		channels[i] = fmt.Sprintf("ps_move_ch%d", i)
+	}
+	ch, err := adapter.Subscribe(ctx, channels...)
+	if err != nil {
+		t.Errorf("Redisd Subscribe failed. err: %s.", err.Error())
+		return
+	}
+
+	// 新主机是另一个redis，移到新主机的频道要在新主机重新订阅
+	host := "127.0.0.1:6379"
+	if err = adapter.AddNode(host, 1); err != nil {
+		t.Errorf("Redisd AddNode failed. err: %s.", err.Error())
+		return
+	}
+	channel := ""
+	for _, c := range channels {
+		if adapter.ring.Get(c) == host {
+			channel = c
+			break
+		}
+	}
+	if channel == "" {
+		t.Errorf("Redisd AddNode failed. No channel is moved to %s.", host)
+		return
+	}
+
+	receive := func(step string) {
+		n, err := adapter.Publish(channel, step)
+		if err != nil || n != 1 {
+			t.Errorf("Redisd Publish after %s failed. Got %d, expected 1, err: %v.", step, n, err)
+			return
+		}
+		select {
+		case msg := <-ch:
+			if msg.Channel != channel || msg.Payload != step {
+				t.Errorf("Redisd Subscribe after %s failed. Got %s %s, expected %s %s.", step, msg.Channel, msg.Payload, channel, step)
+			}
+		case <-time.After(2 * time.Second):
+			t.Errorf("Redisd Subscribe after %s failed. Receive timeout.", step)
+		}
+	}
+	receive("AddNode")
+
+	// 删除主机后频道移回原来的主机
+	if err = adapter.RemoveNode(host); err != nil {
+		t.Errorf("Redisd RemoveNode failed. err: %s.", err.Error())
+		return
+	}
+	receive("RemoveNode")
+}
+
+func TestRedisdLookup(t *testing.T) {
+	adapter := &RedisdCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Redisd Init failed. err: %s.", err.Error())
+		return
+	}
+	adapter.Del("lookup_k1")
+	adapter.Del("lookup_k2")
+
+	v := ""
+	exist, err := adapter.Lookup("lookup_k1", &v)
+	if err != nil || exist {
+		t.Errorf("Redisd Lookup failed. lookup_k1 is not exist, err: %v.", err)
+	}
+	adapter.Set("lookup_k1", "v1", 100)
+	exist, err = adapter.LookupCtx(context.Background(), "lookup_k1", &v)
+	if err != nil || !exist || v != "v1" {
+		t.Errorf("Redisd Lookup failed. Got %s, expected v1, err: %v.", v, err)
+	}
+
+	// 反序列化失败
+	n := 0
+	exist, err = adapter.Lookup("lookup_k1", &n)
+	if !errors.Is(err, cache.ErrDecode) {
+		t.Errorf("Redisd Lookup failed. Got %v, expected cache.ErrDecode.", err)
+	}
+	adapter.Del("lookup_k1")
+}
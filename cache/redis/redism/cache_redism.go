@@ -2,15 +2,19 @@
 package redism
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v7"
 	"github.com/lixy529/gotools/cache"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// NOT_EXIST key不存在时go-redis的错误信息
+// Deprecated: 使用cache.IsMiss或errors.Is(err, cache.ErrMiss)判断
 const NOT_EXIST = "redis: nil"
 
 // RedismCache Redis缓存
@@ -35,8 +39,18 @@ type RedismCache struct {
 	poolTimeout  time.Duration // 如果所有连接都忙时的等待时间，默认为readTimeout+1秒
 	idleTimeout  time.Duration // 最大空闲时间，单位秒，默认为5分钟
 
-	prefix    string // key前缀，如果配置里有，则所有key前自动添加此前缀
-	encodeKey []byte // 加解密密钥，使用Aes加密，长度为16的倍数
+	prefix     string           // key前缀，如果配置里有，则所有key前自动添加此前缀
+	encodeKey  [][]byte         // 加解密密钥，第一个用于加密，全部用于解密
+	serializer cache.Serializer // 序列化，默认为json
+
+	compressType      string // 压缩类型，支持zlib、gzip、snappy，为空时不压缩
+	compressThreshold int    // 超过大小就进行压缩，单位字节，默认256
+
+	hooks cache.Hooks // 命令钩子
+}
+
+func init() {
+	cache.Register(cache.AdapterRedism, NewRedismCache)
 }
 
 // NewRedismCache 新建一个RedismCache适配器.
@@ -60,6 +74,9 @@ func NewRedismCache() cache.Cache {
 //         "idleTimeout":"5",
 //         "prefix":"le_",
 //         "encodeKey":"abcdefghij123456",
+//         "serializer":"json",
+//         "compressType":"snappy",
+//         "compressThreshold":"256",
 //       }
 //       addr:         连接主机和端口，如127.0.0.1:1900
 //       auth:         授权密码
@@ -73,6 +90,9 @@ func NewRedismCache() cache.Cache {
 //       poolTimeout:  如果所有连接都忙时的等待时间，默认为readTimeout+1秒
 //       idleTimeout:  最大空闲时间，单位秒，默认为5分钟
 //       prefix:       key前缀，如果配置里有，则所有key前自动添加此前缀
+//       serializer:   序列化，支持json、gob、msgpack、raw，默认为json
+//       compressType: 压缩类型，支持zlib、gzip、snappy，为空时不压缩
+//       compressThreshold: 超过大小就进行压缩，单位字节，默认256
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (rc *RedismCache) Init(config string) error {
@@ -159,7 +179,26 @@ func (rc *RedismCache) Init(config string) error {
 
 	// 加密密钥
 	if tmp, ok := mapCfg["encodeKey"]; ok && tmp != "" {
-		rc.encodeKey = []byte(tmp)
+		rc.encodeKey = cache.ParseEncodeKeys(tmp)
+	}
+
+	// 序列化
+	rc.serializer, err = cache.GetSerializer(mapCfg["serializer"])
+	if err != nil {
+		return err
+	}
+
+	// 压缩
+	rc.compressType = mapCfg["compressType"]
+	err = cache.CheckCompressType(rc.compressType)
+	if err != nil {
+		return err
+	}
+	compressThreshold, err := strconv.Atoi(mapCfg["compressThreshold"])
+	if err != nil || compressThreshold < 0 {
+		rc.compressThreshold = 256
+	} else {
+		rc.compressThreshold = compressThreshold
 	}
 
 	// 主库配置
@@ -178,7 +217,8 @@ func (rc *RedismCache) Init(config string) error {
 	rc.mAuth = mapCfg["mAuth"]
 
 	// 实例化主库
-	rc.master = NewRedisPool(rc.mAddr, rc.mAuth, rc.mDbNum, rc.dialTimeout, rc.readTimeout, rc.writeTimeout, rc.poolSize, rc.minIdleConns, rc.maxConnAge, rc.poolTimeout, rc.idleTimeout, rc.prefix, rc.encodeKey)
+	rc.master = NewRedisPool(rc.mAddr, rc.mAuth, rc.mDbNum, rc.dialTimeout, rc.readTimeout, rc.writeTimeout, rc.poolSize, rc.minIdleConns, rc.maxConnAge, rc.poolTimeout, rc.idleTimeout, rc.prefix, rc.encodeKey, rc.serializer, rc.compressType, rc.compressThreshold)
+	rc.master.AddRedisHook(cache.NewRedisHook(cache.AdapterRedism, rc.prefix, &rc.hooks))
 
 	// 从库配置
 	rc.sAddr = mapCfg["sAddr"]
@@ -193,12 +233,27 @@ func (rc *RedismCache) Init(config string) error {
 		}
 
 		rc.sAuth = mapCfg["sAuth"]
-		rc.slave = NewRedisPool(rc.sAddr, rc.sAuth, rc.sDbNum, rc.dialTimeout, rc.readTimeout, rc.writeTimeout, rc.poolSize, rc.minIdleConns, rc.maxConnAge, rc.poolTimeout, rc.idleTimeout, rc.prefix, rc.encodeKey)
+		rc.slave = NewRedisPool(rc.sAddr, rc.sAuth, rc.sDbNum, rc.dialTimeout, rc.readTimeout, rc.writeTimeout, rc.poolSize, rc.minIdleConns, rc.maxConnAge, rc.poolTimeout, rc.idleTimeout, rc.prefix, rc.encodeKey, rc.serializer, rc.compressType, rc.compressThreshold)
+		rc.slave.AddRedisHook(cache.NewRedisHook(cache.AdapterRedism, rc.prefix, &rc.hooks))
 	}
 
 	return nil
 }
 
+// AddHook 添加命令钩子，主库和从库的命令都会调用
+//   参数
+//     hook: 钩子
+//   返回
+//
+func (rc *RedismCache) AddHook(hook cache.Hook) {
+	rc.hooks.AddHook(hook)
+}
+
+// Serializer 返回配置的序列化，实现cache.SerializerGetter
+func (rc *RedismCache) Serializer() cache.Serializer {
+	return rc.serializer
+}
+
 // Set 向缓存设置一个值，访问主库
 //   参数
 //     key:    key值
@@ -208,7 +263,12 @@ func (rc *RedismCache) Init(config string) error {
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (rc *RedismCache) Set(key string, val interface{}, expire int32, encode ...bool) error {
-	return rc.master.Set(key, val, expire, encode...)
+	return rc.SetCtx(context.Background(), key, val, expire, encode...)
+}
+
+// SetCtx 同Set，ctx用于控制超时和取消
+func (rc *RedismCache) SetCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) error {
+	return rc.master.SetCtx(ctx, key, val, expire, encode...)
 }
 
 // Get 从缓存取一个值，访问从库
@@ -218,7 +278,28 @@ func (rc *RedismCache) Set(key string, val interface{}, expire int32, encode ...
 //   返回
 //     错误信息，是否存在
 func (rc *RedismCache) Get(key string, val interface{}) (error, bool) {
-	return rc.slave.Get(key, val)
+	return rc.GetCtx(context.Background(), key, val)
+}
+
+// GetCtx 同Get，ctx用于控制超时和取消
+func (rc *RedismCache) GetCtx(ctx context.Context, key string, val interface{}) (error, bool) {
+	return rc.slave.GetCtx(ctx, key, val)
+}
+
+// Lookup 从缓存取一个值，同Get，返回值顺序为(是否存在, 错误信息)，访问从库
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     是否存在，错误信息，key不存在时返回false和nil
+func (rc *RedismCache) Lookup(key string, val interface{}) (bool, error) {
+	return rc.LookupCtx(context.Background(), key, val)
+}
+
+// LookupCtx 同Lookup，ctx用于控制超时和取消
+func (rc *RedismCache) LookupCtx(ctx context.Context, key string, val interface{}) (bool, error) {
+	err, exist := rc.GetCtx(ctx, key, val)
+	return exist, err
 }
 
 // Del 从缓存删除一个值，访问主库
@@ -227,7 +308,79 @@ func (rc *RedismCache) Get(key string, val interface{}) (error, bool) {
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (rc *RedismCache) Del(key string) error {
-	return rc.master.Del(key)
+	return rc.DelCtx(context.Background(), key)
+}
+
+// DelCtx 同Del，ctx用于控制超时和取消
+func (rc *RedismCache) DelCtx(ctx context.Context, key string) error {
+	return rc.master.DelCtx(ctx, key)
+}
+
+// Add key不存在时设置一个值，访问主库
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key已存在返回false，失败返回错误信息
+func (rc *RedismCache) Add(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rc.AddCtx(context.Background(), key, val, expire, encode...)
+}
+
+// AddCtx 同Add，ctx用于控制超时和取消
+func (rc *RedismCache) AddCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rc.master.AddCtx(ctx, key, val, expire, encode...)
+}
+
+// Replace key存在时设置一个值，访问主库
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key不存在返回false，失败返回错误信息
+func (rc *RedismCache) Replace(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rc.ReplaceCtx(context.Background(), key, val, expire, encode...)
+}
+
+// ReplaceCtx 同Replace，ctx用于控制超时和取消
+func (rc *RedismCache) ReplaceCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rc.master.ReplaceCtx(ctx, key, val, expire, encode...)
+}
+
+// GetWithVersion 从缓存取一个值，同时返回数据的版本，用于CompareAndSwap，访问主库
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     数据的版本，key不存在时返回nil，失败返回错误信息
+func (rc *RedismCache) GetWithVersion(key string, val interface{}) (*cache.Version, error) {
+	return rc.GetWithVersionCtx(context.Background(), key, val)
+}
+
+// GetWithVersionCtx 同GetWithVersion，ctx用于控制超时和取消
+func (rc *RedismCache) GetWithVersionCtx(ctx context.Context, key string, val interface{}) (*cache.Version, error) {
+	return rc.master.GetWithVersionCtx(ctx, key, val)
+}
+
+// CompareAndSwap 数据的版本与GetWithVersion返回的版本一致时设置新值，访问主库
+// 只比较数据内容，数据被修改后又改回原值(A→B→A)时检测不到，需要时在值里保存版本号
+//   参数
+//     ver:    GetWithVersion返回的版本
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示保留原来的过期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，数据已被修改或删除返回false，失败返回错误信息
+func (rc *RedismCache) CompareAndSwap(ver *cache.Version, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rc.CompareAndSwapCtx(context.Background(), ver, val, expire, encode...)
+}
+
+// CompareAndSwapCtx 同CompareAndSwap，ctx用于控制超时和取消
+func (rc *RedismCache) CompareAndSwapCtx(ctx context.Context, ver *cache.Version, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rc.master.CompareAndSwapCtx(ctx, ver, val, expire, encode...)
 }
 
 // MSet 同时设置一个或多个key-value对，访问主库
@@ -238,7 +391,12 @@ func (rc *RedismCache) Del(key string) error {
 //   返回
 //     成功返回查询结果，失败返回错误信息，key不存在时对应的val为nil
 func (rc *RedismCache) MSet(mList map[string]interface{}, expire int32, encode ...bool) error {
-	return rc.master.MSet(mList, expire, encode...)
+	return rc.MSetCtx(context.Background(), mList, expire, encode...)
+}
+
+// MSetCtx 同MSet，ctx用于控制超时和取消
+func (rc *RedismCache) MSetCtx(ctx context.Context, mList map[string]interface{}, expire int32, encode ...bool) error {
+	return rc.master.MSetCtx(ctx, mList, expire, encode...)
 }
 
 // MGet 同时获取一个或多个key的value，访问从库
@@ -247,7 +405,12 @@ func (rc *RedismCache) MSet(mList map[string]interface{}, expire int32, encode .
 //   返回
 //     成功返回查询结果，失败返回错误信息
 func (rc *RedismCache) MGet(keys ...string) (map[string]interface{}, error) {
-	return rc.slave.MGet(keys...)
+	return rc.MGetCtx(context.Background(), keys...)
+}
+
+// MGetCtx 同MGet，ctx用于控制超时和取消
+func (rc *RedismCache) MGetCtx(ctx context.Context, keys ...string) (map[string]interface{}, error) {
+	return rc.slave.MGetCtx(ctx, keys...)
 }
 
 // MDel 同时删除一个或多个key，访问主库
@@ -256,7 +419,12 @@ func (rc *RedismCache) MGet(keys ...string) (map[string]interface{}, error) {
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (rc *RedismCache) MDel(keys ...string) error {
-	return rc.master.MDel(keys...)
+	return rc.MDelCtx(context.Background(), keys...)
+}
+
+// MDelCtx 同MDel，ctx用于控制超时和取消
+func (rc *RedismCache) MDelCtx(ctx context.Context, keys ...string) error {
+	return rc.master.MDelCtx(ctx, keys...)
 }
 
 // Incr 缓存里的值自增，访问主库
@@ -267,7 +435,12 @@ func (rc *RedismCache) MDel(keys ...string) error {
 //   返回
 //     递增后的结果，失败返回错误信息
 func (rc *RedismCache) Incr(key string, delta ...uint64) (int64, error) {
-	return rc.master.Incr(key, delta...)
+	return rc.IncrCtx(context.Background(), key, delta...)
+}
+
+// IncrCtx 同Incr，ctx用于控制超时和取消
+func (rc *RedismCache) IncrCtx(ctx context.Context, key string, delta ...uint64) (int64, error) {
+	return rc.master.IncrCtx(ctx, key, delta...)
 }
 
 // Decr 缓存里的值自减，访问主库
@@ -278,7 +451,28 @@ func (rc *RedismCache) Incr(key string, delta ...uint64) (int64, error) {
 //   返回
 //     递减后的结果，失败返回错误信息
 func (rc *RedismCache) Decr(key string, delta ...uint64) (int64, error) {
-	return rc.master.Decr(key, delta...)
+	return rc.DecrCtx(context.Background(), key, delta...)
+}
+
+// DecrCtx 同Decr，ctx用于控制超时和取消
+func (rc *RedismCache) DecrCtx(ctx context.Context, key string, delta ...uint64) (int64, error) {
+	return rc.master.DecrCtx(ctx, key, delta...)
+}
+
+// IncrEx 缓存里的值自增并设置过期时间，自增和设置过期时间原子执行，访问主库
+//   参数
+//     key:    递增的key值
+//     delta:  递增的量，负数时为递减
+//     expire: 过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     递增后的结果，失败返回错误信息
+func (rc *RedismCache) IncrEx(key string, delta int64, expire int32) (int64, error) {
+	return rc.IncrExCtx(context.Background(), key, delta, expire)
+}
+
+// IncrExCtx 同IncrEx，ctx用于控制超时和取消
+func (rc *RedismCache) IncrExCtx(ctx context.Context, key string, delta int64, expire int32) (int64, error) {
+	return rc.master.IncrExCtx(ctx, key, delta, expire)
 }
 
 // IsExist 判断key值是否存在，访问从库
@@ -287,16 +481,130 @@ func (rc *RedismCache) Decr(key string, delta ...uint64) (int64, error) {
 //   返回
 //     存在返回true，不存在返回false
 func (rc *RedismCache) IsExist(key string) (bool, error) {
-	return rc.slave.IsExist(key)
+	return rc.IsExistCtx(context.Background(), key)
+}
+
+// IsExistCtx 同IsExist，ctx用于控制超时和取消
+func (rc *RedismCache) IsExistCtx(ctx context.Context, key string) (bool, error) {
+	return rc.slave.IsExistCtx(ctx, key)
 }
 
-// ClearAll 清空所有数据，访问主库
+// ClearAll 清空前缀下的所有数据，访问主库
 //   参数
 //
 //   返回
 //     成功时返回nil，失败返回错误信息
 func (rc *RedismCache) ClearAll() error {
-	return rc.master.ClearAll()
+	return rc.ClearAllCtx(context.Background())
+}
+
+// ClearAllCtx 同ClearAll，ctx用于控制超时和取消
+func (rc *RedismCache) ClearAllCtx(ctx context.Context) error {
+	return rc.master.ClearAllCtx(ctx)
+}
+
+// TTL 查询key的剩余过期时间，精确到毫秒，访问从库
+//   参数
+//     key: key值
+//   返回
+//     剩余过期时间，没有过期时间返回cache.TTL_PERSIST，key不存在返回cache.TTL_NOT_EXIST，失败返回错误信息
+func (rc *RedismCache) TTL(key string) (time.Duration, error) {
+	return rc.TTLCtx(context.Background(), key)
+}
+
+// TTLCtx 同TTL，ctx用于控制超时和取消
+func (rc *RedismCache) TTLCtx(ctx context.Context, key string) (time.Duration, error) {
+	return rc.slave.TTLCtx(ctx, key)
+}
+
+// Expire 设置key的过期时间，精确到毫秒，访问主库
+//   参数
+//     key:    key值
+//     expire: 过期时间，小于等于0时删除key
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (rc *RedismCache) Expire(key string, expire time.Duration) (bool, error) {
+	return rc.ExpireCtx(context.Background(), key, expire)
+}
+
+// ExpireCtx 同Expire，ctx用于控制超时和取消
+func (rc *RedismCache) ExpireCtx(ctx context.Context, key string, expire time.Duration) (bool, error) {
+	return rc.master.ExpireCtx(ctx, key, expire)
+}
+
+// ExpireAt 设置key在指定时间过期，访问主库
+//   参数
+//     key: key值
+//     tm:  过期的时间点，早于当前时间时删除key
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (rc *RedismCache) ExpireAt(key string, tm time.Time) (bool, error) {
+	return rc.ExpireAtCtx(context.Background(), key, tm)
+}
+
+// ExpireAtCtx 同ExpireAt，ctx用于控制超时和取消
+func (rc *RedismCache) ExpireAtCtx(ctx context.Context, key string, tm time.Time) (bool, error) {
+	return rc.master.ExpireAtCtx(ctx, key, tm)
+}
+
+// Persist 删除key的过期时间，访问主库
+//   参数
+//     key: key值
+//   返回
+//     删除了过期时间返回true，key不存在或没有过期时间返回false，失败返回错误信息
+func (rc *RedismCache) Persist(key string) (bool, error) {
+	return rc.PersistCtx(context.Background(), key)
+}
+
+// PersistCtx 同Persist，ctx用于控制超时和取消
+func (rc *RedismCache) PersistCtx(ctx context.Context, key string) (bool, error) {
+	return rc.master.PersistCtx(ctx, key)
+}
+
+// Touch 更新key的过期时间，与Set的expire参数一致，访问主库
+//   参数
+//     key:    key值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (rc *RedismCache) Touch(key string, expire int32) (bool, error) {
+	return rc.TouchCtx(context.Background(), key, expire)
+}
+
+// TouchCtx 同Touch，ctx用于控制超时和取消
+func (rc *RedismCache) TouchCtx(ctx context.Context, key string, expire int32) (bool, error) {
+	return rc.master.TouchCtx(ctx, key, expire)
+}
+
+// GetEx 从缓存取一个值，同时更新过期时间，访问主库
+//   参数
+//     key:    key值
+//     val:    保存结果地址
+//     expire: 新的过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     错误信息，是否存在
+func (rc *RedismCache) GetEx(key string, val interface{}, expire int32) (error, bool) {
+	return rc.GetExCtx(context.Background(), key, val, expire)
+}
+
+// GetExCtx 同GetEx，ctx用于控制超时和取消
+func (rc *RedismCache) GetExCtx(ctx context.Context, key string, val interface{}, expire int32) (error, bool) {
+	return rc.master.GetExCtx(ctx, key, val, expire)
+}
+
+// Scan 按模式遍历前缀下的key，访问主库
+//   参数
+//     pattern: 匹配模式，不含前缀，为空时匹配所有key
+//     batch:   每次SCAN的key个数，小于等于0时使用redis默认值
+//   返回
+//     迭代器，返回的key已去掉前缀
+func (rc *RedismCache) Scan(pattern string, batch int64) cache.Iterator {
+	return rc.ScanCtx(context.Background(), pattern, batch)
+}
+
+// ScanCtx 同Scan，ctx用于控制超时和取消
+func (rc *RedismCache) ScanCtx(ctx context.Context, pattern string, batch int64) cache.Iterator {
+	return rc.master.ScanCtx(ctx, pattern, batch)
 }
 
 // Hset 添加哈希表，访问主库
@@ -308,7 +616,12 @@ func (rc *RedismCache) ClearAll() error {
 //   返回
 //     成功时返回添加的个数，失败返回错误信息
 func (rc *RedismCache) HSet(key string, field string, val interface{}, expire int32) (int64, error) {
-	return rc.master.HSet(key, field, val, expire)
+	return rc.HSetCtx(context.Background(), key, field, val, expire)
+}
+
+// HSetCtx 同HSet，ctx用于控制超时和取消
+func (rc *RedismCache) HSetCtx(ctx context.Context, key string, field string, val interface{}, expire int32) (int64, error) {
+	return rc.master.HSetCtx(ctx, key, field, val, expire)
 }
 
 // HGet 查询哈希表数据，访问从库
@@ -319,7 +632,12 @@ func (rc *RedismCache) HSet(key string, field string, val interface{}, expire in
 //   返回
 //     错误信息，是否存在
 func (rc *RedismCache) HGet(key string, field string, val interface{}) (error, bool) {
-	return rc.slave.HGet(key, field, val)
+	return rc.HGetCtx(context.Background(), key, field, val)
+}
+
+// HGetCtx 同HGet，ctx用于控制超时和取消
+func (rc *RedismCache) HGetCtx(ctx context.Context, key string, field string, val interface{}) (error, bool) {
+	return rc.slave.HGetCtx(ctx, key, field, val)
 }
 
 // HDel 删除哈希表数据，访问主库
@@ -329,7 +647,12 @@ func (rc *RedismCache) HGet(key string, field string, val interface{}) (error, b
 //   返回
 //     成功返回nil，失败返回错误信息
 func (rc *RedismCache) HDel(key string, fields ...string) error {
-	return rc.master.HDel(key, fields...)
+	return rc.HDelCtx(context.Background(), key, fields...)
+}
+
+// HDelCtx 同HDel，ctx用于控制超时和取消
+func (rc *RedismCache) HDelCtx(ctx context.Context, key string, fields ...string) error {
+	return rc.master.HDelCtx(ctx, key, fields...)
 }
 
 // HGetAll 返回哈希表 key 中，所有的域和值，struct、map类型需要业务层调用json.Unmarshal
@@ -338,7 +661,12 @@ func (rc *RedismCache) HDel(key string, fields ...string) error {
 //   返回
 //     查询的结果数据和错误码
 func (rc *RedismCache) HGetAll(key string) (map[string]interface{}, error) {
-	return rc.slave.HGetAll(key)
+	return rc.HGetAllCtx(context.Background(), key)
+}
+
+// HGetAllCtx 同HGetAll，ctx用于控制超时和取消
+func (rc *RedismCache) HGetAllCtx(ctx context.Context, key string) (map[string]interface{}, error) {
+	return rc.slave.HGetAllCtx(ctx, key)
 }
 
 // HMSet 同时将多个 field-value (域-值)对设置到哈希表 key 中
@@ -349,7 +677,12 @@ func (rc *RedismCache) HGetAll(key string) (map[string]interface{}, error) {
 //   返回
 //     执行结果
 func (rc *RedismCache) HMSet(key string, fields map[string]interface{}, expire int32) error {
-	return rc.slave.HMSet(key, fields, expire)
+	return rc.HMSetCtx(context.Background(), key, fields, expire)
+}
+
+// HMSetCtx 同HMSet，ctx用于控制超时和取消
+func (rc *RedismCache) HMSetCtx(ctx context.Context, key string, fields map[string]interface{}, expire int32) error {
+	return rc.slave.HMSetCtx(ctx, key, fields, expire)
 }
 
 // HMGet 返回哈希表 key 中，一个或多个给定域的值，struct、map类型需要业务层调用json.Unmarshal
@@ -359,7 +692,12 @@ func (rc *RedismCache) HMSet(key string, fields map[string]interface{}, expire i
 //   返回
 //     查询的结果数据和错误码
 func (rc *RedismCache) HMGet(key string, fields ...string) (map[string]interface{}, error) {
-	return rc.slave.HMGet(key, fields...)
+	return rc.HMGetCtx(context.Background(), key, fields...)
+}
+
+// HMGetCtx 同HMGet，ctx用于控制超时和取消
+func (rc *RedismCache) HMGetCtx(ctx context.Context, key string, fields ...string) (map[string]interface{}, error) {
+	return rc.slave.HMGetCtx(ctx, key, fields...)
 }
 
 // HVals 返回哈希表 key 中，所有的域和值
@@ -368,7 +706,12 @@ func (rc *RedismCache) HMGet(key string, fields ...string) (map[string]interface
 //   返回
 //     查询的结果数据和错误码
 func (rc *RedismCache) HVals(key string) ([]interface{}, error) {
-	return rc.slave.HVals(key)
+	return rc.HValsCtx(context.Background(), key)
+}
+
+// HValsCtx 同HVals，ctx用于控制超时和取消
+func (rc *RedismCache) HValsCtx(ctx context.Context, key string) ([]interface{}, error) {
+	return rc.slave.HValsCtx(ctx, key)
 }
 
 // HIncr 哈希表的值自增
@@ -379,7 +722,12 @@ func (rc *RedismCache) HVals(key string) ([]interface{}, error) {
 //   返回
 //     递增后的结果、失败返回错误信息
 func (rc *RedismCache) HIncr(key, fields string, delta ...uint64) (int64, error) {
-	return rc.master.HIncr(key, fields, delta...)
+	return rc.HIncrCtx(context.Background(), key, fields, delta...)
+}
+
+// HIncrCtx 同HIncr，ctx用于控制超时和取消
+func (rc *RedismCache) HIncrCtx(ctx context.Context, key, fields string, delta ...uint64) (int64, error) {
+	return rc.master.HIncrCtx(ctx, key, fields, delta...)
 }
 
 // HDecr 哈希表的值自减
@@ -390,7 +738,12 @@ func (rc *RedismCache) HIncr(key, fields string, delta ...uint64) (int64, error)
 //   返回
 //     递减后的结果、失败返回错误信息
 func (rc *RedismCache) HDecr(key, fields string, delta ...uint64) (int64, error) {
-	return rc.master.HDecr(key, fields, delta...)
+	return rc.HDecrCtx(context.Background(), key, fields, delta...)
+}
+
+// HDecrCtx 同HDecr，ctx用于控制超时和取消
+func (rc *RedismCache) HDecrCtx(ctx context.Context, key, fields string, delta ...uint64) (int64, error) {
+	return rc.master.HDecrCtx(ctx, key, fields, delta...)
 }
 
 // ZSet 添加有序集合
@@ -401,7 +754,12 @@ func (rc *RedismCache) HDecr(key, fields string, delta ...uint64) (int64, error)
 //   返回
 //     成功添加的数据和错误码
 func (rc *RedismCache) ZSet(key string, expire int32, val ...interface{}) (int64, error) {
-	return rc.master.ZSet(key, expire, val...)
+	return rc.ZSetCtx(context.Background(), key, expire, val...)
+}
+
+// ZSetCtx 同ZSet，ctx用于控制超时和取消
+func (rc *RedismCache) ZSetCtx(ctx context.Context, key string, expire int32, val ...interface{}) (int64, error) {
+	return rc.master.ZSetCtx(ctx, key, expire, val...)
 }
 
 // ZGet 查询有序集合
@@ -414,7 +772,12 @@ func (rc *RedismCache) ZSet(key string, expire int32, val ...interface{}) (int64
 //   返回
 //     查询的结果数据和错误码
 func (rc *RedismCache) ZGet(key string, start, stop int, withScores bool, isRev bool) ([]string, error) {
-	return rc.slave.ZGet(key, start, stop, withScores, isRev)
+	return rc.ZGetCtx(context.Background(), key, start, stop, withScores, isRev)
+}
+
+// ZGetCtx 同ZGet，ctx用于控制超时和取消
+func (rc *RedismCache) ZGetCtx(ctx context.Context, key string, start, stop int, withScores bool, isRev bool) ([]string, error) {
+	return rc.slave.ZGetCtx(ctx, key, start, stop, withScores, isRev)
 }
 
 // ZDel 删除有序集合数据
@@ -424,7 +787,12 @@ func (rc *RedismCache) ZGet(key string, start, stop int, withScores bool, isRev
 //   返回
 //     成功删除的数据个数和错误码
 func (rc *RedismCache) ZDel(key string, field ...string) (int64, error) {
-	return rc.master.ZDel(key, field...)
+	return rc.ZDelCtx(context.Background(), key, field...)
+}
+
+// ZDelCtx 同ZDel，ctx用于控制超时和取消
+func (rc *RedismCache) ZDelCtx(ctx context.Context, key string, field ...string) (int64, error) {
+	return rc.master.ZDelCtx(ctx, key, field...)
 }
 
 // ZRemRangeByRank 删除指定排名区间内的有序集合数据
@@ -435,7 +803,12 @@ func (rc *RedismCache) ZDel(key string, field ...string) (int64, error) {
 //   返回
 //     成功删除的数据个数和错误码
 func (rc *RedismCache) ZRemRangeByRank(key string, start, end int64) (int64, error) {
-	return rc.master.ZRemRangeByRank(key, start, end)
+	return rc.ZRemRangeByRankCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByRankCtx 同ZRemRangeByRank，ctx用于控制超时和取消
+func (rc *RedismCache) ZRemRangeByRankCtx(ctx context.Context, key string, start, end int64) (int64, error) {
+	return rc.master.ZRemRangeByRankCtx(ctx, key, start, end)
 }
 
 // ZRemRangeByScore 删除指定分值区间内的有序集合数据
@@ -446,7 +819,12 @@ func (rc *RedismCache) ZRemRangeByRank(key string, start, end int64) (int64, err
 //   返回
 //     成功删除的数据个数和错误码
 func (rc *RedismCache) ZRemRangeByScore(key string, start, end string) (int64, error) {
-	return rc.master.ZRemRangeByScore(key, start, end)
+	return rc.ZRemRangeByScoreCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByScoreCtx 同ZRemRangeByScore，ctx用于控制超时和取消
+func (rc *RedismCache) ZRemRangeByScoreCtx(ctx context.Context, key string, start, end string) (int64, error) {
+	return rc.master.ZRemRangeByScoreCtx(ctx, key, start, end)
 }
 
 // ZRemRangeByLex 删除指定变量区间内的有序集合数据
@@ -458,7 +836,12 @@ func (rc *RedismCache) ZRemRangeByScore(key string, start, end string) (int64, e
 //   返回
 //     成功删除的数据个数和错误码
 func (rc *RedismCache) ZRemRangeByLex(key string, start, end string) (int64, error) {
-	return rc.master.ZRemRangeByLex(key, start, end)
+	return rc.ZRemRangeByLexCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByLexCtx 同ZRemRangeByLex，ctx用于控制超时和取消
+func (rc *RedismCache) ZRemRangeByLexCtx(ctx context.Context, key string, start, end string) (int64, error) {
+	return rc.master.ZRemRangeByLexCtx(ctx, key, start, end)
 }
 
 // ZCard 返回有序集 key 的基数
@@ -467,7 +850,12 @@ func (rc *RedismCache) ZRemRangeByLex(key string, start, end string) (int64, err
 //   返回
 //     有序集 key 的基数和错误码
 func (rc *RedismCache) ZCard(key string) (int64, error) {
-	return rc.slave.ZCard(key)
+	return rc.ZCardCtx(context.Background(), key)
+}
+
+// ZCardCtx 同ZCard，ctx用于控制超时和取消
+func (rc *RedismCache) ZCardCtx(ctx context.Context, key string) (int64, error) {
+	return rc.slave.ZCardCtx(ctx, key)
 }
 
 // SetBit 设置或清除指定偏移量上的位(bit)
@@ -479,7 +867,12 @@ func (rc *RedismCache) ZCard(key string) (int64, error) {
 //   返回
 //     指定偏移量原来储存的位、错误信息
 func (rc *RedismCache) SetBit(key string, offset int64, value int, expire int32) (int64, error) {
-	return rc.master.SetBit(key, offset, value, expire)
+	return rc.SetBitCtx(context.Background(), key, offset, value, expire)
+}
+
+// SetBitCtx 同SetBit，ctx用于控制超时和取消
+func (rc *RedismCache) SetBitCtx(ctx context.Context, key string, offset int64, value int, expire int32) (int64, error) {
+	return rc.master.SetBitCtx(ctx, key, offset, value, expire)
 }
 
 // GetBit 获取指定偏移量上的位(bit)
@@ -489,7 +882,12 @@ func (rc *RedismCache) SetBit(key string, offset int64, value int, expire int32)
 //   返回
 //     字符串值指定偏移量上的位(bit)、错误信息
 func (rc *RedismCache) GetBit(key string, offset int64) (int64, error) {
-	return rc.slave.GetBit(key, offset)
+	return rc.GetBitCtx(context.Background(), key, offset)
+}
+
+// GetBitCtx 同GetBit，ctx用于控制超时和取消
+func (rc *RedismCache) GetBitCtx(ctx context.Context, key string, offset int64) (int64, error) {
+	return rc.slave.GetBitCtx(ctx, key, offset)
 }
 
 // BitCount 计算给定字符串中被设置为 1 的比特位的数量
@@ -499,7 +897,12 @@ func (rc *RedismCache) GetBit(key string, offset int64) (int64, error) {
 //   返回
 //     给定字符串中被设置为 1 的比特位的数量、错误信息
 func (rc *RedismCache) BitCount(key string, bitCount *cache.BitCount) (int64, error) {
-	return rc.slave.BitCount(key, bitCount)
+	return rc.BitCountCtx(context.Background(), key, bitCount)
+}
+
+// BitCountCtx 同BitCount，ctx用于控制超时和取消
+func (rc *RedismCache) BitCountCtx(ctx context.Context, key string, bitCount *cache.BitCount) (int64, error) {
+	return rc.slave.BitCountCtx(ctx, key, bitCount)
 }
 
 // PFAdd 添加基数
@@ -511,7 +914,12 @@ func (rc *RedismCache) BitCount(key string, bitCount *cache.BitCount) (int64, er
 //     存在，不做任何事情，返回0；不存在的话就创建，并返回1
 //     错误信息
 func (rc *RedismCache) PFAdd(key string, expire int32, vals ...interface{}) (int64, error) {
-	return rc.master.PFAdd(key, expire, vals...)
+	return rc.PFAddCtx(context.Background(), key, expire, vals...)
+}
+
+// PFAddCtx 同PFAdd，ctx用于控制超时和取消
+func (rc *RedismCache) PFAddCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error) {
+	return rc.master.PFAddCtx(ctx, key, expire, vals...)
 }
 
 // PFCount 返回基数估算值
@@ -520,90 +928,493 @@ func (rc *RedismCache) PFAdd(key string, expire int32, vals ...interface{}) (int
 //   返回
 //     基数估算值
 func (rc *RedismCache) PFCount(key string) (int64, error) {
-	return rc.slave.PFCount(key)
+	return rc.PFCountCtx(context.Background(), key)
 }
 
-// Pipeline 执行pipeline命令
-//   实例：
-//     pipe := rc.Pipeline(false).Pipe
-//     incr := pipe.Incr("pipeline_counter")
-//     pipe.Expire("pipeline_counter", time.Hour)
-//     _, err := pipe.Exec()
-//     fmt.Println(incr.Val(), err)
+// PFCountCtx 同PFCount，ctx用于控制超时和取消
+func (rc *RedismCache) PFCountCtx(ctx context.Context, key string) (int64, error) {
+	return rc.slave.PFCountCtx(ctx, key)
+}
+
+// LPush 从列表头部插入元素
 //   参数
-//     isTx: 是否事务模式
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素，与HSet一致，按序列化和压缩配置转换
 //   返回
-//     成功刊返回命令执行的结果
-func (rc *RedismCache) Pipeline(isTx bool) cache.Pipeliner {
-	return rc.master.Pipeline(isTx)
+//     成功时返回列表长度，失败返回错误信息
+func (rc *RedismCache) LPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return rc.LPushCtx(context.Background(), key, expire, vals...)
 }
 
-// RedisPool Redis缓存
-type RedisPool struct {
-	client *redis.Client // 连接池
+// LPushCtx 同LPush，ctx用于控制超时和取消
+func (rc *RedismCache) LPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error) {
+	return rc.master.LPushCtx(ctx, key, expire, vals...)
+}
 
-	addr         string        // 连接主机和端口，多个主机用逗号分割，如127.0.0.1:1900,127.0.0.2:1900
-	auth         string        // 授权密码
-	dbNum        int           // db编号，默认为0
-	dialTimeout  time.Duration // 连接超时时间，单位秒，默认5秒
-	readTimeout  time.Duration // 读超时时间，单位秒，-1-不超时，0-使用默认3秒
-	writeTimeout time.Duration // 写超时时间，单位秒，默认为readTimeout
-	poolSize     int           // 每个节点连接池的连接数，默认为cpu个数的10倍
-	minIdleConns int           // 最少空闲连接数，默认为0
-	maxConnAge   time.Duration // 最大连接时间，单位秒，超时时间自动关闭，默认为0
-	poolTimeout  time.Duration // 如果所有连接都忙时的等待时间，默认为readTimeout+1秒
-	idleTimeout  time.Duration // 最大空闲时间，单位秒，默认为5分钟
+// RPush 从列表尾部插入元素
+//   参数
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素，与HSet一致，按序列化和压缩配置转换
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (rc *RedismCache) RPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return rc.RPushCtx(context.Background(), key, expire, vals...)
+}
 
-	prefix    string // key前缀，如果配置里有，则所有key前自动添加此前缀
-	encodeKey []byte // 加解密密钥，使用Aes加密，长度为16的倍数
+// RPushCtx 同RPush，ctx用于控制超时和取消
+func (rc *RedismCache) RPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error) {
+	return rc.master.RPushCtx(ctx, key, expire, vals...)
 }
 
-// NewRedisPool 实例化RedisPool对象
+// LPop 从列表头部取出一个元素
 //   参数
-//     addr:         连接主机和端口
-//     auth:         授权码
-//     dbNum:        db号
-//     dialTimeout:  连接超时时间，单位秒，默认5秒
-//     readTimeout:  读超时时间，单位秒，-1-不超时，0-使用默认3秒
-//     writeTimeout: 写超时时间，单位秒，默认为readTimeout
-//     poolSize:     每个节点连接池的连接数，默认为cpu个数的10倍
-//     minIdleConns: 最少空闲连接数，默认为0
-//     maxConnAge:   最大连接时间，单位秒，超时时间自动关闭，默认为0
-//     poolTimeout:  如果所有连接都忙时的等待时间，默认为readTimeout+1秒
-//     idleTimeout:  最大空闲时间，单位秒，默认为5分钟
-//     prefix:       key前缀，如果配置里有，则所有key前自动添加此前缀
-//     encodeKey:    加密key
+//     key: 列表key值
+//     val: 保存结果地址
 //   返回
-//     成功时Redis连接池
-func NewRedisPool(addr, auth string, dbNum int, dialTimeout, readTimeout, writeTimeout time.Duration, poolSize, minIdleConns int, maxConnAge, poolTimeout, idleTimeout time.Duration, prefix string, encodeKey []byte) *RedisPool {
-	rp := &RedisPool{
-		addr:         addr,
-		auth:         auth,
-		dbNum:        dbNum,
-		dialTimeout:  dialTimeout,
-		readTimeout:  readTimeout,
-		writeTimeout: writeTimeout,
-		poolSize:     poolSize,
-		minIdleConns: minIdleConns,
-		maxConnAge:   maxConnAge,
-		poolTimeout:  poolTimeout,
-		idleTimeout:  idleTimeout,
-		prefix:       prefix,
-		encodeKey:    encodeKey,
-	}
+//     错误信息，是否存在
+func (rc *RedismCache) LPop(key string, val interface{}) (error, bool) {
+	return rc.LPopCtx(context.Background(), key, val)
+}
 
-	rp.connect()
+// LPopCtx 同LPop，ctx用于控制超时和取消
+func (rc *RedismCache) LPopCtx(ctx context.Context, key string, val interface{}) (error, bool) {
+	return rc.master.LPopCtx(ctx, key, val)
+}
 
-	return rp
+// BRPop 阻塞地从多个列表尾部取出一个元素
+//   参数
+//     timeout: 阻塞时间，以秒为单位，“0”表示一直阻塞
+//     val:     保存结果地址
+//     keys:    列表key值，按顺序检查
+//   返回
+//     取到元素的列表key值，超时返回空串，错误信息
+func (rc *RedismCache) BRPop(timeout int32, val interface{}, keys ...string) (string, error) {
+	return rc.BRPopCtx(context.Background(), timeout, val, keys...)
 }
 
-// connect 连接redis
+// BRPopCtx 同BRPop，ctx用于控制超时和取消
+func (rc *RedismCache) BRPopCtx(ctx context.Context, timeout int32, val interface{}, keys ...string) (string, error) {
+	return rc.master.BRPopCtx(ctx, timeout, val, keys...)
+}
+
+// LRange 查询列表指定区间的元素
 //   参数
-//
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
 //   返回
-//     成功时返回nil，失败时返回错误信息
-func (rp *RedisPool) connect() {
-	rp.client = redis.NewClient(&redis.Options{
+//     成功时返回元素列表，失败返回错误信息
+func (rc *RedismCache) LRange(key string, start, stop int64) ([]interface{}, error) {
+	return rc.LRangeCtx(context.Background(), key, start, stop)
+}
+
+// LRangeCtx 同LRange，ctx用于控制超时和取消
+func (rc *RedismCache) LRangeCtx(ctx context.Context, key string, start, stop int64) ([]interface{}, error) {
+	return rc.slave.LRangeCtx(ctx, key, start, stop)
+}
+
+// LTrim 只保留列表指定区间的元素
+//   参数
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rc *RedismCache) LTrim(key string, start, stop int64) error {
+	return rc.LTrimCtx(context.Background(), key, start, stop)
+}
+
+// LTrimCtx 同LTrim，ctx用于控制超时和取消
+func (rc *RedismCache) LTrimCtx(ctx context.Context, key string, start, stop int64) error {
+	return rc.master.LTrimCtx(ctx, key, start, stop)
+}
+
+// SAdd 向集合添加成员
+//   参数
+//     key:     集合key值
+//     expire:  缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     members: 成员，只做序列化，不压缩，保证相同的成员保存的数据相同
+//   返回
+//     成功时返回新添加的个数，失败返回错误信息
+func (rc *RedismCache) SAdd(key string, expire int32, members ...interface{}) (int64, error) {
+	return rc.SAddCtx(context.Background(), key, expire, members...)
+}
+
+// SAddCtx 同SAdd，ctx用于控制超时和取消
+func (rc *RedismCache) SAddCtx(ctx context.Context, key string, expire int32, members ...interface{}) (int64, error) {
+	return rc.master.SAddCtx(ctx, key, expire, members...)
+}
+
+// SRem 删除集合的成员
+//   参数
+//     key:     集合key值
+//     members: 成员
+//   返回
+//     成功时返回删除的个数，失败返回错误信息
+func (rc *RedismCache) SRem(key string, members ...interface{}) (int64, error) {
+	return rc.SRemCtx(context.Background(), key, members...)
+}
+
+// SRemCtx 同SRem，ctx用于控制超时和取消
+func (rc *RedismCache) SRemCtx(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	return rc.master.SRemCtx(ctx, key, members...)
+}
+
+// SIsMember 判断是否为集合的成员
+//   参数
+//     key:    集合key值
+//     member: 成员
+//   返回
+//     是成员返回true，不是返回false，失败返回错误信息
+func (rc *RedismCache) SIsMember(key string, member interface{}) (bool, error) {
+	return rc.SIsMemberCtx(context.Background(), key, member)
+}
+
+// SIsMemberCtx 同SIsMember，ctx用于控制超时和取消
+func (rc *RedismCache) SIsMemberCtx(ctx context.Context, key string, member interface{}) (bool, error) {
+	return rc.slave.SIsMemberCtx(ctx, key, member)
+}
+
+// SMembers 查询集合的所有成员
+//   参数
+//     key: 集合key值
+//   返回
+//     成功时返回所有成员，失败返回错误信息
+func (rc *RedismCache) SMembers(key string) ([]string, error) {
+	return rc.SMembersCtx(context.Background(), key)
+}
+
+// SMembersCtx 同SMembers，ctx用于控制超时和取消
+func (rc *RedismCache) SMembersCtx(ctx context.Context, key string) ([]string, error) {
+	return rc.slave.SMembersCtx(ctx, key)
+}
+
+// SInter 返回多个集合的交集
+//   参数
+//     keys: 集合key值
+//   返回
+//     成功时返回交集的成员，失败返回错误信息
+func (rc *RedismCache) SInter(keys ...string) ([]string, error) {
+	return rc.SInterCtx(context.Background(), keys...)
+}
+
+// SInterCtx 同SInter，ctx用于控制超时和取消
+func (rc *RedismCache) SInterCtx(ctx context.Context, keys ...string) ([]string, error) {
+	return rc.slave.SInterCtx(ctx, keys...)
+}
+
+// SUnion 返回多个集合的并集
+//   参数
+//     keys: 集合key值
+//   返回
+//     成功时返回并集的成员，失败返回错误信息
+func (rc *RedismCache) SUnion(keys ...string) ([]string, error) {
+	return rc.SUnionCtx(context.Background(), keys...)
+}
+
+// SUnionCtx 同SUnion，ctx用于控制超时和取消
+func (rc *RedismCache) SUnionCtx(ctx context.Context, keys ...string) ([]string, error) {
+	return rc.slave.SUnionCtx(ctx, keys...)
+}
+
+// XAdd 向流添加消息
+//   参数
+//     key:    流key值
+//     maxLen: 流的最大长度，超过时删除旧消息，为近似值，小于等于0时不限制
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   消息内容，与HSet一致，按序列化和压缩配置转换
+//   返回
+//     成功时返回消息id，失败返回错误信息
+func (rc *RedismCache) XAdd(key string, maxLen int64, expire int32, vals map[string]interface{}) (string, error) {
+	return rc.XAddCtx(context.Background(), key, maxLen, expire, vals)
+}
+
+// XAddCtx 同XAdd，ctx用于控制超时和取消
+func (rc *RedismCache) XAddCtx(ctx context.Context, key string, maxLen int64, expire int32, vals map[string]interface{}) (string, error) {
+	return rc.master.XAddCtx(ctx, key, maxLen, expire, vals)
+}
+
+// XRead 读取流消息
+//   参数
+//     args: 读取参数，Streams前一半为key值，后一半为对应的开始id(不包含)，$表示只读新消息
+//   返回
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (rc *RedismCache) XRead(args *cache.XReadArgs) ([]cache.XStream, error) {
+	return rc.XReadCtx(context.Background(), args)
+}
+
+// XReadCtx 同XRead，ctx用于控制超时和取消
+func (rc *RedismCache) XReadCtx(ctx context.Context, args *cache.XReadArgs) ([]cache.XStream, error) {
+	return rc.slave.XReadCtx(ctx, args)
+}
+
+// XGroupCreate 创建消费组，流不存在时自动创建
+//   参数
+//     key:   流key值
+//     group: 消费组
+//     start: 开始读取的消息id，0表示从头开始，为空或$表示只读新消息
+//   返回
+//     成功或消费组已存在时返回nil，失败返回错误信息
+func (rc *RedismCache) XGroupCreate(key, group, start string) error {
+	return rc.XGroupCreateCtx(context.Background(), key, group, start)
+}
+
+// XGroupCreateCtx 同XGroupCreate，ctx用于控制超时和取消
+func (rc *RedismCache) XGroupCreateCtx(ctx context.Context, key, group, start string) error {
+	return rc.master.XGroupCreateCtx(ctx, key, group, start)
+}
+
+// XReadGroup 按消费组读取流消息
+//   参数
+//     args: 读取参数，Streams前一半为key值，后一半为对应的id，>表示读取未分配给消费者的新消息
+//   返回
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (rc *RedismCache) XReadGroup(args *cache.XReadGroupArgs) ([]cache.XStream, error) {
+	return rc.XReadGroupCtx(context.Background(), args)
+}
+
+// XReadGroupCtx 同XReadGroup，ctx用于控制超时和取消
+func (rc *RedismCache) XReadGroupCtx(ctx context.Context, args *cache.XReadGroupArgs) ([]cache.XStream, error) {
+	return rc.master.XReadGroupCtx(ctx, args)
+}
+
+// XAck 确认消费组的消息
+//   参数
+//     key:   流key值
+//     group: 消费组
+//     ids:   消息id
+//   返回
+//     成功时返回确认的个数，失败返回错误信息
+func (rc *RedismCache) XAck(key, group string, ids ...string) (int64, error) {
+	return rc.XAckCtx(context.Background(), key, group, ids...)
+}
+
+// XAckCtx 同XAck，ctx用于控制超时和取消
+func (rc *RedismCache) XAckCtx(ctx context.Context, key, group string, ids ...string) (int64, error) {
+	return rc.master.XAckCtx(ctx, key, group, ids...)
+}
+
+// Eval 执行lua脚本，访问主库
+//   参数
+//     script: lua脚本
+//     keys:   脚本的KEYS，会自动添加key前缀
+//     args:   脚本的ARGV
+//   返回
+//     脚本的返回值，脚本返回nil时返回nil，失败返回错误信息
+func (rc *RedismCache) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return rc.EvalCtx(context.Background(), script, keys, args...)
+}
+
+// EvalCtx 同Eval，ctx用于控制超时和取消
+func (rc *RedismCache) EvalCtx(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return rc.master.EvalCtx(ctx, script, keys, args...)
+}
+
+// EvalSha 按sha1执行lua脚本，访问主库
+//   参数
+//     sha1: 脚本的sha1
+//     keys: 脚本的KEYS，会自动添加key前缀
+//     args: 脚本的ARGV
+//   返回
+//     脚本的返回值，脚本返回nil时返回nil，失败返回错误信息
+func (rc *RedismCache) EvalSha(sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return rc.EvalShaCtx(context.Background(), sha1, keys, args...)
+}
+
+// EvalShaCtx 同EvalSha，ctx用于控制超时和取消
+func (rc *RedismCache) EvalShaCtx(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return rc.master.EvalShaCtx(ctx, sha1, keys, args...)
+}
+
+// ScriptLoad 加载lua脚本，访问主库
+//   参数
+//     script: lua脚本
+//   返回
+//     脚本的sha1，失败返回错误信息
+func (rc *RedismCache) ScriptLoad(script string) (string, error) {
+	return rc.ScriptLoadCtx(context.Background(), script)
+}
+
+// ScriptLoadCtx 同ScriptLoad，ctx用于控制超时和取消
+func (rc *RedismCache) ScriptLoadCtx(ctx context.Context, script string) (string, error) {
+	return rc.master.ScriptLoadCtx(ctx, script)
+}
+
+// RunScript 执行lua脚本，访问主库
+func (rc *RedismCache) RunScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	return rc.master.RunScript(ctx, script, keys, args...)
+}
+
+// AcquireLock 获取分布式锁，访问主库，由cache/lock包使用
+func (rc *RedismCache) AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	return rc.master.AcquireLock(ctx, key, token, ttl)
+}
+
+// ReleaseLock 释放分布式锁，token一致时才删除
+func (rc *RedismCache) ReleaseLock(ctx context.Context, key, token string) (bool, error) {
+	return rc.master.ReleaseLock(ctx, key, token)
+}
+
+// RefreshLock 更新分布式锁的过期时间，token一致时才更新
+func (rc *RedismCache) RefreshLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	return rc.master.RefreshLock(ctx, key, token, ttl)
+}
+
+// Publish 向频道发布消息，访问主库
+//   参数
+//     channel: 频道，不添加key前缀
+//     msg:     消息内容
+//   返回
+//     收到消息的订阅者数、错误信息
+func (rc *RedismCache) Publish(channel string, msg interface{}) (int64, error) {
+	return rc.master.PublishCtx(context.Background(), channel, msg)
+}
+
+// PublishCtx 同Publish，ctx用于控制超时和取消
+func (rc *RedismCache) PublishCtx(ctx context.Context, channel string, msg interface{}) (int64, error) {
+	return rc.master.PublishCtx(ctx, channel, msg)
+}
+
+// Subscribe 订阅频道，访问主库
+//   参数
+//     ctx:      上下文，取消时取消订阅并关闭返回的channel
+//     channels: 频道，不添加key前缀
+//   返回
+//     消息channel、错误信息
+func (rc *RedismCache) Subscribe(ctx context.Context, channels ...string) (<-chan *cache.Message, error) {
+	return rc.master.Subscribe(ctx, channels...)
+}
+
+// SubscribeExpired 订阅带key前缀的key的过期通知，访问主库
+//   参数
+//     ctx: 上下文，取消时取消订阅并关闭返回的channel
+//   返回
+//     消息channel、错误信息
+func (rc *RedismCache) SubscribeExpired(ctx context.Context) (<-chan *cache.Message, error) {
+	return rc.master.SubscribeExpired(ctx)
+}
+
+// Batch 新建批量命令，访问主库
+//   参数
+//     isTx: 是否事务模式，为true时使用MULTI/EXEC执行
+//   返回
+//     批量命令
+func (rc *RedismCache) Batch(isTx bool) *cache.Batch {
+	return rc.master.Batch(isTx)
+}
+
+// Pipeline 执行pipeline命令
+//   实例：
+//     pipe := rc.Pipeline(false).Pipe
+//     incr := pipe.Incr("pipeline_counter")
+//     pipe.Expire("pipeline_counter", time.Hour)
+//     _, err := pipe.Exec()
+//     fmt.Println(incr.Val(), err)
+//   参数
+//     isTx: 是否事务模式
+//   返回
+//     成功刊返回命令执行的结果
+func (rc *RedismCache) Pipeline(isTx bool) cache.Pipeliner {
+	return rc.master.Pipeline(isTx)
+}
+
+// RedisPool Redis缓存
+type RedisPool struct {
+	client *redis.Client // 连接池
+
+	addr         string        // 连接主机和端口，多个主机用逗号分割，如127.0.0.1:1900,127.0.0.2:1900
+	auth         string        // 授权密码
+	dbNum        int           // db编号，默认为0
+	dialTimeout  time.Duration // 连接超时时间，单位秒，默认5秒
+	readTimeout  time.Duration // 读超时时间，单位秒，-1-不超时，0-使用默认3秒
+	writeTimeout time.Duration // 写超时时间，单位秒，默认为readTimeout
+	poolSize     int           // 每个节点连接池的连接数，默认为cpu个数的10倍
+	minIdleConns int           // 最少空闲连接数，默认为0
+	maxConnAge   time.Duration // 最大连接时间，单位秒，超时时间自动关闭，默认为0
+	poolTimeout  time.Duration // 如果所有连接都忙时的等待时间，默认为readTimeout+1秒
+	idleTimeout  time.Duration // 最大空闲时间，单位秒，默认为5分钟
+
+	prefix     string           // key前缀，如果配置里有，则所有key前自动添加此前缀
+	encodeKey  [][]byte         // 加解密密钥，第一个用于加密，全部用于解密
+	serializer cache.Serializer // 序列化，默认为json
+
+	compressType      string // 压缩类型，支持zlib、gzip、snappy，为空时不压缩
+	compressThreshold int    // 超过大小就进行压缩，单位字节，默认256
+}
+
+// NewRedisPool 实例化RedisPool对象
+//   参数
+//     addr:         连接主机和端口
+//     auth:         授权码
+//     dbNum:        db号
+//     dialTimeout:  连接超时时间，单位秒，默认5秒
+//     readTimeout:  读超时时间，单位秒，-1-不超时，0-使用默认3秒
+//     writeTimeout: 写超时时间，单位秒，默认为readTimeout
+//     poolSize:     每个节点连接池的连接数，默认为cpu个数的10倍
+//     minIdleConns: 最少空闲连接数，默认为0
+//     maxConnAge:   最大连接时间，单位秒，超时时间自动关闭，默认为0
+//     poolTimeout:  如果所有连接都忙时的等待时间，默认为readTimeout+1秒
+//     idleTimeout:  最大空闲时间，单位秒，默认为5分钟
+//     prefix:       key前缀，如果配置里有，则所有key前自动添加此前缀
+//     encodeKey:    加解密密钥，第一个用于加密，全部用于解密
+//     serializer:   序列化，为nil时使用json
+//     compressType: 压缩类型，为空时不压缩
+//     compressThreshold: 超过大小就进行压缩，单位字节
+//   返回
+//     成功时Redis连接池
+func NewRedisPool(addr, auth string, dbNum int, dialTimeout, readTimeout, writeTimeout time.Duration, poolSize, minIdleConns int, maxConnAge, poolTimeout, idleTimeout time.Duration, prefix string, encodeKey [][]byte, serializer cache.Serializer, compressType string, compressThreshold int) *RedisPool {
+	rp := &RedisPool{
+		addr:         addr,
+		auth:         auth,
+		dbNum:        dbNum,
+		dialTimeout:  dialTimeout,
+		readTimeout:  readTimeout,
+		writeTimeout: writeTimeout,
+		poolSize:     poolSize,
+		minIdleConns: minIdleConns,
+		maxConnAge:   maxConnAge,
+		poolTimeout:  poolTimeout,
+		idleTimeout:  idleTimeout,
+		prefix:       prefix,
+		encodeKey:    encodeKey,
+		serializer:   serializer,
+
+		compressType:      compressType,
+		compressThreshold: compressThreshold,
+	}
+
+	rp.connect()
+
+	return rp
+}
+
+// Close 关闭连接池
+//   参数
+//
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rp *RedisPool) Close() error {
+	return rp.client.Close()
+}
+
+// AddRedisHook 给连接池添加go-redis钩子
+//   参数
+//     hook: go-redis钩子
+//   返回
+//
+func (rp *RedisPool) AddRedisHook(hook redis.Hook) {
+	rp.client.AddHook(hook)
+}
+
+// connect 连接redis
+//   参数
+//
+//   返回
+//     成功时返回nil，失败时返回错误信息
+func (rp *RedisPool) connect() {
+	rp.client = redis.NewClient(&redis.Options{
 		Addr:         rp.addr,
 		Password:     rp.auth,
 		DB:           rp.dbNum,
@@ -617,324 +1428,1524 @@ func (rp *RedisPool) connect() {
 		IdleTimeout:  rp.idleTimeout * time.Second,
 	})
 
-	return
+	return
+}
+
+// getClient 获取连接池，ctx不是context.Background()时返回绑定了ctx的连接池
+//   参数
+//     ctx: 上下文
+//   返回
+//     连接池
+func (rp *RedisPool) getClient(ctx context.Context) *redis.Client {
+	if ctx == nil || ctx == context.Background() {
+		return rp.client
+	}
+
+	return rp.client.WithContext(ctx)
+}
+
+// Set 向缓存设置一个值
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 到期是缓存过期时间，以秒为单位：从现在开始的相对时间。“0”表示项目没有到期时间。
+//     encode: 是否加密标识
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rp *RedisPool) Set(key string, val interface{}, expire int32, encode ...bool) error {
+	return rp.SetCtx(context.Background(), key, val, expire, encode...)
+}
+
+// SetCtx 同Set，ctx用于控制超时和取消
+func (rp *RedisPool) SetCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	data, err := rp.encodeValue(val, encode...)
+	if err != nil {
+		return err
+	}
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).Set(key, data, time.Duration(expire)*time.Second).Err()
+}
+
+// Get 从缓存取一个值
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     错误信息，是否存在
+func (rp *RedisPool) Get(key string, val interface{}) (error, bool) {
+	return rp.GetCtx(context.Background(), key, val)
+}
+
+// GetCtx 同Get，ctx用于控制超时和取消
+func (rp *RedisPool) GetCtx(ctx context.Context, key string, val interface{}) (err error, exist bool) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	v, err := rp.getClient(ctx).Get(key).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, false
+		}
+		return err, false
+	}
+
+	return rp.decodeValue(v, val), true
+}
+
+// Lookup 从缓存取一个值，同Get，返回值顺序为(是否存在, 错误信息)
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     是否存在，错误信息，key不存在时返回false和nil
+func (rp *RedisPool) Lookup(key string, val interface{}) (bool, error) {
+	return rp.LookupCtx(context.Background(), key, val)
+}
+
+// LookupCtx 同Lookup，ctx用于控制超时和取消
+func (rp *RedisPool) LookupCtx(ctx context.Context, key string, val interface{}) (bool, error) {
+	err, exist := rp.GetCtx(ctx, key, val)
+	return exist, err
+}
+
+// encodeValue 生成要保存的数据，处理序列化、压缩和加密
+//   参数
+//     val:    value值
+//     encode: 是否加密标识
+//   返回
+//     要保存的数据，失败返回错误信息
+func (rp *RedisPool) encodeValue(val interface{}, encode ...bool) ([]byte, error) {
+	// 类型转换
+	data, err := cache.InterToByte(val, rp.serializer)
+	if err != nil {
+		return nil, err
+	}
+
+	// 压缩判断
+	data, err = cache.Compress(data, rp.compressType, rp.compressThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	// 加密判断
+	encode = append(encode, false)
+	if encode[0] {
+		data, err = cache.Encode(data, rp.encodeKey...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// decodeValue 解析保存的数据，处理解密、解压和类型转换
+//   参数
+//     v:   保存的数据
+//     val: 保存结果地址
+//   返回
+//     失败返回错误信息
+func (rp *RedisPool) decodeValue(v string, val interface{}) error {
+	// 解密判断
+	data, err := cache.Decode([]byte(v), rp.encodeKey...)
+	if err != nil {
+		return err
+	}
+
+	// 解压
+	data, err = cache.Uncompress(data)
+	if err != nil {
+		return err
+	}
+
+	// 类型转换
+	return cache.ByteToInter(data, val)
+}
+
+// Del 从缓存删除一个值
+//   参数
+//     key:    key值
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rp *RedisPool) Del(key string) error {
+	return rp.DelCtx(context.Background(), key)
+}
+
+// DelCtx 同Del，ctx用于控制超时和取消
+func (rp *RedisPool) DelCtx(ctx context.Context, key string) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).Del(key).Err()
+}
+
+// Add key不存在时设置一个值
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key已存在返回false，失败返回错误信息
+func (rp *RedisPool) Add(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rp.AddCtx(context.Background(), key, val, expire, encode...)
+}
+
+// AddCtx 同Add，ctx用于控制超时和取消
+func (rp *RedisPool) AddCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	data, err := rp.encodeValue(val, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).SetNX(key, data, time.Duration(expire)*time.Second).Result()
+}
+
+// Replace key存在时设置一个值
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key不存在返回false，失败返回错误信息
+func (rp *RedisPool) Replace(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rp.ReplaceCtx(context.Background(), key, val, expire, encode...)
+}
+
+// ReplaceCtx 同Replace，ctx用于控制超时和取消
+func (rp *RedisPool) ReplaceCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	data, err := rp.encodeValue(val, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).SetXX(key, data, time.Duration(expire)*time.Second).Result()
+}
+
+// GetWithVersion 从缓存取一个值，同时返回数据的版本，用于CompareAndSwap
+// redis的版本为保存数据的sha1，数据被修改后又改回原值时版本不变
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     数据的版本，key不存在时返回nil，失败返回错误信息
+func (rp *RedisPool) GetWithVersion(key string, val interface{}) (*cache.Version, error) {
+	return rp.GetWithVersionCtx(context.Background(), key, val)
+}
+
+// GetWithVersionCtx 同GetWithVersion，ctx用于控制超时和取消
+func (rp *RedisPool) GetWithVersionCtx(ctx context.Context, key string, val interface{}) (res *cache.Version, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKey := key
+	if rp.prefix != "" {
+		pKey = rp.prefix + key
+	}
+
+	v, err := rp.getClient(ctx).Get(pKey).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	ver := &cache.Version{Key: key, Token: cache.DataSha1(v)}
+	return ver, rp.decodeValue(v, val)
+}
+
+// CompareAndSwap 数据的版本与GetWithVersion返回的版本一致时设置新值，在lua脚本里原子执行
+// 只比较数据内容，数据被修改后又改回原值(A→B→A)时检测不到，需要时在值里保存版本号
+//   参数
+//     ver:    GetWithVersion返回的版本
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示保留原来的过期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，数据已被修改或删除返回false，失败返回错误信息
+func (rp *RedisPool) CompareAndSwap(ver *cache.Version, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rp.CompareAndSwapCtx(context.Background(), ver, val, expire, encode...)
+}
+
+// CompareAndSwapCtx 同CompareAndSwap，ctx用于控制超时和取消
+func (rp *RedisPool) CompareAndSwapCtx(ctx context.Context, ver *cache.Version, val interface{}, expire int32, encode ...bool) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if ver == nil {
+		return false, errors.New("RedismCache: CompareAndSwap invalid version")
+	}
+	sha, ok := ver.Token.(string)
+	if !ok {
+		return false, errors.New("RedismCache: CompareAndSwap invalid version")
+	}
+
+	data, err := rp.encodeValue(val, encode...)
+	if err != nil {
+		return false, err
+	}
+
+	key := ver.Key
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	n, err := cache.CompareAndSwapScript.Run(rp.getClient(ctx), []string{key}, sha, data, expire).Int64()
+	return n == 1, err
+}
+
+// MSet 同时设置一个或多个key-value对
+//   参数
+//     mList:  key-value对
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间。“0”表示项目没有到期时间。
+//     encode: 是否加密标识
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rp *RedisPool) MSet(mList map[string]interface{}, expire int32, encode ...bool) error {
+	return rp.MSetCtx(context.Background(), mList, expire, encode...)
+}
+
+// MSetCtx 同MSet，ctx用于控制超时和取消
+func (rp *RedisPool) MSetCtx(ctx context.Context, mList map[string]interface{}, expire int32, encode ...bool) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	var v []interface{}
+	for key, val := range mList {
+		// 类型转换
+		data, err := cache.InterToByte(val, rp.serializer)
+		if err != nil {
+			return err
+		}
+
+		// 压缩判断
+		data, err = cache.Compress(data, rp.compressType, rp.compressThreshold)
+		if err != nil {
+			return err
+		}
+
+		// 加密判断
+		encode = append(encode, false)
+		if encode[0] {
+			data, err = cache.Encode(data, rp.encodeKey...)
+			if err != nil {
+				return err
+			}
+		}
+
+		if rp.prefix != "" {
+			key = rp.prefix + key
+		}
+		v = append(v, key, data)
+	}
+
+	err = rp.getClient(ctx).MSet(v...).Err()
+	if err != nil {
+		return err
+	}
+
+	// 设置失效时间
+	if expire > 0 {
+		for key := range mList {
+			if rp.prefix != "" {
+				key = rp.prefix + key
+			}
+			rp.getClient(ctx).Expire(key, time.Duration(expire)*time.Second)
+		}
+	}
+
+	return err
+}
+
+// MGet 同时获取一个或多个key的value，
+// struct、map类型返回的结果需要调用方做一下json.Unmarshal处理
+//   参数
+//     keys:  要查询的key值
+//   返回
+//     成功返回查询结果，失败返回错误信息，key不存在时对应的val为nil
+func (rp *RedisPool) MGet(keys ...string) (map[string]interface{}, error) {
+	return rp.MGetCtx(context.Background(), keys...)
+}
+
+// MGetCtx 同MGet，ctx用于控制超时和取消
+func (rp *RedisPool) MGetCtx(ctx context.Context, keys ...string) (res map[string]interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	mList := make(map[string]interface{})
+	args := []string{}
+	for _, k := range keys {
+		if rp.prefix != "" {
+			k = rp.prefix + k
+		}
+		args = append(args, k)
+	}
+
+	v, err := rp.getClient(ctx).MGet(args...).Result()
+	if err != nil {
+		return mList, err
+	}
+
+	i := 0
+	for _, val := range v {
+		if val == nil {
+			// 不存在
+			mList[keys[i]] = nil
+		} else {
+			// 解密判断
+			data, err := cache.Decode([]byte(val.(string)), rp.encodeKey...)
+			if err != nil {
+				return mList, err
+			}
+
+			// 解压
+			data, err = cache.Uncompress(data)
+			if err != nil {
+				return mList, err
+			}
+
+			mList[keys[i]] = string(data)
+		}
+
+		i++
+	}
+
+	return mList, nil
+}
+
+// MDel 同时删除一个或多个key
+//   参数
+//     keys:  要查询的key值
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rp *RedisPool) MDel(keys ...string) error {
+	return rp.MDelCtx(context.Background(), keys...)
+}
+
+// MDelCtx 同MDel，ctx用于控制超时和取消
+func (rp *RedisPool) MDelCtx(ctx context.Context, keys ...string) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	args := make([]string, len(keys))
+	for k, v := range keys {
+		if rp.prefix != "" {
+			v = rp.prefix + v
+		}
+		args[k] = v
+	}
+
+	rp.getClient(ctx).Del(args...)
+	return nil
+}
+
+// Incr 缓存里的值自增
+// key不存在时会新建一个，再返回1
+//   参数
+//     key:   递增的key值
+//     delta: 递增的量
+//   返回
+//     递增后的结果，失败返回错误信息
+func (rp *RedisPool) Incr(key string, delta ...uint64) (int64, error) {
+	return rp.IncrCtx(context.Background(), key, delta...)
+}
+
+// IncrCtx 同Incr，ctx用于控制超时和取消
+func (rp *RedisPool) IncrCtx(ctx context.Context, key string, delta ...uint64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	delta = append(delta, 1)
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+	v, err := rp.getClient(ctx).IncrBy(key, int64(delta[0])).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	return v, nil
+}
+
+// Decr 缓存里的值自减
+// key不存在时会新建一个，再返回-1
+//   参数
+//     key:   要递减的key值
+//     delta: 递减的量
+//   返回
+//     递减后的结果，失败返回错误信息
+func (rp *RedisPool) Decr(key string, delta ...uint64) (int64, error) {
+	return rp.DecrCtx(context.Background(), key, delta...)
+}
+
+// DecrCtx 同Decr，ctx用于控制超时和取消
+func (rp *RedisPool) DecrCtx(ctx context.Context, key string, delta ...uint64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	delta = append(delta, 1)
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+	v, err := rp.getClient(ctx).DecrBy(key, int64(delta[0])).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	return v, nil
+}
+
+// IncrEx 缓存里的值自增并设置过期时间，自增和设置过期时间在lua脚本里原子执行
+// key不存在时会新建一个，每次自增都会更新过期时间
+//   参数
+//     key:    递增的key值
+//     delta:  递增的量，负数时为递减
+//     expire: 过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     递增后的结果，失败返回错误信息
+func (rp *RedisPool) IncrEx(key string, delta int64, expire int32) (int64, error) {
+	return rp.IncrExCtx(context.Background(), key, delta, expire)
+}
+
+// IncrExCtx 同IncrEx，ctx用于控制超时和取消
+func (rp *RedisPool) IncrExCtx(ctx context.Context, key string, delta int64, expire int32) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	return cache.IncrExScript.Run(rp.getClient(ctx), []string{key}, delta, expire).Int64()
+}
+
+// IsExist 判断key值是否存在
+//   参数
+//     key:  要查询的key值
+//   返回
+//     存在返回true，不存在返回false，出错时返回错误信息
+func (rp *RedisPool) IsExist(key string) (bool, error) {
+	return rp.IsExistCtx(context.Background(), key)
+}
+
+// IsExistCtx 同IsExist，ctx用于控制超时和取消
+func (rp *RedisPool) IsExistCtx(ctx context.Context, key string) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+	n, err := rp.getClient(ctx).Exists(key).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if n == 1 {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// ClearAll 清空前缀下的所有数据，使用SCAN分批查找key，UNLINK分批删除，不阻塞服务器
+//   参数
+//
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rp *RedisPool) ClearAll() error {
+	return rp.ClearAllCtx(context.Background())
+}
+
+// ClearAllCtx 同ClearAll，ctx用于控制超时和取消
+func (rp *RedisPool) ClearAllCtx(ctx context.Context) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	client := rp.getClient(ctx)
+	pattern := cache.ScanPattern(rp.prefix, "")
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(cursor, pattern, cache.CLEAR_BATCH).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			err = client.Unlink(keys...).Err()
+			if err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// TTL 查询key的剩余过期时间，精确到毫秒
+//   参数
+//     key: key值
+//   返回
+//     剩余过期时间，没有过期时间返回cache.TTL_PERSIST，key不存在返回cache.TTL_NOT_EXIST，失败返回错误信息
+func (rp *RedisPool) TTL(key string) (time.Duration, error) {
+	return rp.TTLCtx(context.Background(), key)
+}
+
+// TTLCtx 同TTL，ctx用于控制超时和取消
+func (rp *RedisPool) TTLCtx(ctx context.Context, key string) (res time.Duration, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).PTTL(key).Result()
+}
+
+// Expire 设置key的过期时间，精确到毫秒
+//   参数
+//     key:    key值
+//     expire: 过期时间，小于等于0时删除key
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (rp *RedisPool) Expire(key string, expire time.Duration) (bool, error) {
+	return rp.ExpireCtx(context.Background(), key, expire)
+}
+
+// ExpireCtx 同Expire，ctx用于控制超时和取消
+func (rp *RedisPool) ExpireCtx(ctx context.Context, key string, expire time.Duration) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).PExpire(key, expire).Result()
+}
+
+// ExpireAt 设置key在指定时间过期
+//   参数
+//     key: key值
+//     tm:  过期的时间点，早于当前时间时删除key
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (rp *RedisPool) ExpireAt(key string, tm time.Time) (bool, error) {
+	return rp.ExpireAtCtx(context.Background(), key, tm)
+}
+
+// ExpireAtCtx 同ExpireAt，ctx用于控制超时和取消
+func (rp *RedisPool) ExpireAtCtx(ctx context.Context, key string, tm time.Time) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).PExpireAt(key, tm).Result()
+}
+
+// Persist 删除key的过期时间，key不再过期
+//   参数
+//     key: key值
+//   返回
+//     删除了过期时间返回true，key不存在或没有过期时间返回false，失败返回错误信息
+func (rp *RedisPool) Persist(key string) (bool, error) {
+	return rp.PersistCtx(context.Background(), key)
+}
+
+// PersistCtx 同Persist，ctx用于控制超时和取消
+func (rp *RedisPool) PersistCtx(ctx context.Context, key string) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).Persist(key).Result()
+}
+
+// Touch 更新key的过期时间，与Set的expire参数一致
+//   参数
+//     key:    key值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (rp *RedisPool) Touch(key string, expire int32) (bool, error) {
+	return rp.TouchCtx(context.Background(), key, expire)
+}
+
+// TouchCtx 同Touch，ctx用于控制超时和取消
+func (rp *RedisPool) TouchCtx(ctx context.Context, key string, expire int32) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	if expire > 0 {
+		return rp.getClient(ctx).Expire(key, time.Duration(expire)*time.Second).Result()
+	}
+
+	// 没有过期时间的key执行persist返回0，需要单独判断key是否存在
+	var exists *redis.IntCmd
+	_, err = rp.getClient(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Persist(key)
+		exists = pipe.Exists(key)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return exists.Val() == 1, nil
+}
+
+// GetEx 从缓存取一个值，同时更新过期时间，用于滑动过期，读取和更新过期时间在一次请求里执行
+//   参数
+//     key:    key值
+//     val:    保存结果地址
+//     expire: 新的过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     错误信息，是否存在
+func (rp *RedisPool) GetEx(key string, val interface{}, expire int32) (error, bool) {
+	return rp.GetExCtx(context.Background(), key, val, expire)
+}
+
+// GetExCtx 同GetEx，ctx用于控制超时和取消
+func (rp *RedisPool) GetExCtx(ctx context.Context, key string, val interface{}, expire int32) (err error, exist bool) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	var get *redis.StringCmd
+	_, err = rp.getClient(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(key)
+		if expire > 0 {
+			pipe.Expire(key, time.Duration(expire)*time.Second)
+		}
+		return nil
+	})
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, false
+		}
+		return err, false
+	}
+
+	return rp.decodeValue(get.Val(), val), true
+}
+
+// Scan 按模式遍历前缀下的key
+//   参数
+//     pattern: 匹配模式，不含前缀，为空时匹配所有key
+//     batch:   每次SCAN的key个数，小于等于0时使用redis默认值
+//   返回
+//     迭代器，返回的key已去掉前缀
+func (rp *RedisPool) Scan(pattern string, batch int64) cache.Iterator {
+	return rp.ScanCtx(context.Background(), pattern, batch)
+}
+
+// ScanCtx 同Scan，ctx用于控制超时和取消
+func (rp *RedisPool) ScanCtx(ctx context.Context, pattern string, batch int64) cache.Iterator {
+	client := rp.getClient(ctx)
+	match := cache.ScanPattern(rp.prefix, pattern)
+	return cache.NewIterator(rp.prefix, func(cursor uint64) ([]string, uint64, error) {
+		return client.Scan(cursor, match, batch).Result()
+	})
+}
+
+// Hset 添加哈希表
+//   参数
+//     key:    哈希表key值
+//     field:  哈希表field值
+//     val:    哈希表value值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//   返回
+//     成功时返回添加的个数，失败返回错误信息
+func (rp *RedisPool) HSet(key string, field string, val interface{}, expire int32) (int64, error) {
+	return rp.HSetCtx(context.Background(), key, field, val, expire)
+}
+
+// HSetCtx 同HSet，ctx用于控制超时和取消
+func (rp *RedisPool) HSetCtx(ctx context.Context, key string, field string, val interface{}, expire int32) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	// 类型转换
+	data, err := cache.InterToByte(val, rp.serializer)
+	if err != nil {
+		return -1, err
+	}
+
+	// 压缩判断
+	data, err = cache.Compress(data, rp.compressType, rp.compressThreshold)
+	if err != nil {
+		return -1, err
+	}
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	// 设置和过期时间在事务里执行
+	_, err = rp.getClient(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.HSet(key, field, data)
+		if expire > 0 {
+			pipe.Expire(key, time.Duration(expire)*time.Second)
+		}
+		return nil
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return 1, err
+}
+
+// HGet 查询哈希表数据
+//   参数
+//     key:   哈希表key值
+//     field: 哈希表field值
+//     val:   保存结果地址
+//   返回
+//     错误信息，是否存在
+func (rp *RedisPool) HGet(key string, field string, val interface{}) (error, bool) {
+	return rp.HGetCtx(context.Background(), key, field, val)
+}
+
+// HGetCtx 同HGet，ctx用于控制超时和取消
+func (rp *RedisPool) HGetCtx(ctx context.Context, key string, field string, val interface{}) (err error, exist bool) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	v, err := rp.getClient(ctx).HGet(key, field).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, false
+		}
+		return err, false
+	}
+
+	// 解压
+	data, err := cache.Uncompress([]byte(v))
+	if err != nil {
+		return err, true
+	}
+
+	// 类型转换
+	err = cache.ByteToInter(data, val)
+	if err != nil {
+		return err, true
+	}
+
+	return nil, true
+}
+
+// HDel 删除哈希表数据
+//   参数
+//     key:    哈希表key值
+//     fields: 哈希表field值
+//   返回
+//     成功返回nil，失败返回错误信息
+func (rp *RedisPool) HDel(key string, fields ...string) error {
+	return rp.HDelCtx(context.Background(), key, fields...)
+}
+
+// HDelCtx 同HDel，ctx用于控制超时和取消
+func (rp *RedisPool) HDelCtx(ctx context.Context, key string, fields ...string) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).HDel(key, fields...).Err()
+}
+
+// HGetAll 返回哈希表 key 中，所有的域和值，struct、map类型需要业务层调用json.Unmarshal
+//   参数
+//     key: 有序集合key值
+//   返回
+//     查询的结果数据和错误码
+//   示例
+//     key := "addr"
+//     val, err := adapter.HGetAll(key)
+//     if err != nil {
+//         fmt.Printf("Redis HGetAll failed. err: %s.", err.Error())
+//         return
+//     }
+//     for k, v := range val {
+//         var data StUser
+//         if v != nil {
+//             json.Unmarshal(v.([]byte), &data)
+//         }
+//         fmt.Println(k, data)
+//     }
+func (rp *RedisPool) HGetAll(key string) (map[string]interface{}, error) {
+	return rp.HGetAllCtx(context.Background(), key)
+}
+
+// HGetAllCtx 同HGetAll，ctx用于控制超时和取消
+func (rp *RedisPool) HGetAllCtx(ctx context.Context, key string) (res map[string]interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	res = make(map[string]interface{})
+	val, err := rp.getClient(ctx).HGetAll(key).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return res, nil
+		}
+
+		return nil, err
+	}
+
+	for k, v := range val {
+		// 解压
+		res[k], err = cache.UncompressString(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, err
+}
+
+// HMSet 同时将多个 field-value (域-值)对设置到哈希表 key 中
+//   参数
+//     key:    有序集合key值
+//     fields: field-value 对
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//   返回
+//     执行结果
+func (rp *RedisPool) HMSet(key string, fields map[string]interface{}, expire int32) error {
+	return rp.HMSetCtx(context.Background(), key, fields, expire)
+}
+
+// HMSetCtx 同HMSet，ctx用于控制超时和取消
+func (rp *RedisPool) HMSetCtx(ctx context.Context, key string, fields map[string]interface{}, expire int32) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	err = rp.getClient(ctx).HMSet(key, fields).Err()
+	if err != nil {
+		return err
+	}
+
+	if expire > 0 {
+		rp.getClient(ctx).Expire(key, time.Duration(expire)*time.Second)
+	}
+
+	return nil
+}
+
+// HMGet 返回哈希表 key 中，一个或多个给定域的值，struct、map类型需要业务层调用json.Unmarshal
+//   参数
+//     key:    有序集合key值
+//     fields: 给定域的集合
+//   返回
+//     查询的结果数据和错误码
+func (rp *RedisPool) HMGet(key string, fields ...string) (map[string]interface{}, error) {
+	return rp.HMGetCtx(context.Background(), key, fields...)
+}
+
+// HMGetCtx 同HMGet，ctx用于控制超时和取消
+func (rp *RedisPool) HMGetCtx(ctx context.Context, key string, fields ...string) (res map[string]interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+	res = make(map[string]interface{})
+
+	v, err := rp.getClient(ctx).HMGet(key, fields...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if v == nil {
+		return res, nil
+	}
+
+	i := 0
+	for _, field := range fields {
+		// 解压
+		res[field], err = cache.UncompressString(v[i])
+		if err != nil {
+			return nil, err
+		}
+		i++
+	}
+
+	return res, err
+}
+
+// HVals 返回哈希表 key 中，所有的域和值，struct、map类型需要业务层调用json.Unmarshal
+//   参数
+//     key:        有序集合key值
+//   返回
+//     查询的结果数据和错误码
+//   示例
+//     key := "addr"
+//     val, err := adapter.HVals(key)
+//     if err != nil {
+//         fmt.Printf("Redis HVals failed. err: %s.", err.Error())
+//         return
+//     }
+//     for _, v := range val {
+//         var data StUser
+//         if v != nil {
+//             json.Unmarshal(v.([]byte), &data)
+//         }
+//         fmt.Println(data)
+//     }
+func (rp *RedisPool) HVals(key string) ([]interface{}, error) {
+	return rp.HValsCtx(context.Background(), key)
+}
+
+// HValsCtx 同HVals，ctx用于控制超时和取消
+func (rp *RedisPool) HValsCtx(ctx context.Context, key string) (res []interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
+	vals, err := rp.getClient(ctx).HVals(key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	res = make([]interface{}, len(vals))
+	for k, v := range vals {
+		// 解压
+		res[k], err = cache.UncompressString(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// HIncr 哈希表的值自增
+//   参数
+//     key:    有序集合key值
+//     fields: 给定域的集合
+//     delta:  递增的量，默认为1
+//   返回
+//     递增后的结果、失败返回错误信息
+func (rp *RedisPool) HIncr(key, fields string, delta ...uint64) (int64, error) {
+	return rp.HIncrCtx(context.Background(), key, fields, delta...)
+}
+
+// HIncrCtx 同HIncr，ctx用于控制超时和取消
+func (rp *RedisPool) HIncrCtx(ctx context.Context, key, fields string, delta ...uint64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	delta = append(delta, 1)
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).HIncrBy(key, fields, int64(delta[0])).Result()
+}
+
+// HDecr 哈希表的值自减
+//   参数
+//     key:    有序集合key值
+//     fields: 给定域的集合
+//     delta:  递增的量，默认为1
+//   返回
+//     递减后的结果、失败返回错误信息
+func (rp *RedisPool) HDecr(key, fields string, delta ...uint64) (int64, error) {
+	return rp.HDecrCtx(context.Background(), key, fields, delta...)
+}
+
+// HDecrCtx 同HDecr，ctx用于控制超时和取消
+func (rp *RedisPool) HDecrCtx(ctx context.Context, key, fields string, delta ...uint64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	delta = append(delta, 1)
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).HIncrBy(key, fields, 0-int64(delta[0])).Result()
+}
+
+// ZSet 添加有序集合
+//   参数
+//     key:    有序集合key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     val:    有序集合值，数据为成对出来，前面为score(整数值或双精度浮点数), 后面为变量
+//   返回
+//     成功添加的数据个数和错误码
+func (rp *RedisPool) ZSet(key string, expire int32, val ...interface{}) (int64, error) {
+	return rp.ZSetCtx(context.Background(), key, expire, val...)
+}
+
+// ZSetCtx 同ZSet，ctx用于控制超时和取消
+func (rp *RedisPool) ZSetCtx(ctx context.Context, key string, expire int32, val ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	valLen := len(val)
+	if valLen < 2 || valLen%2 != 0 {
+		return -1, errors.New("val param error")
+	}
+	vals := []*redis.Z{}
+	for i := 0; i < valLen-1; i += 2 {
+		stZ := &redis.Z{
+			Score:  val[i].(float64),
+			Member: val[i+1],
+		}
+		vals = append(vals, stZ)
+	}
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	var cmd *redis.IntCmd
+	_, err = rp.getClient(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		cmd = pipe.ZAdd(key, vals...)
+		if expire > 0 {
+			pipe.Expire(key, time.Duration(expire)*time.Second)
+		}
+		return nil
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return cmd.Val(), err
 }
 
-// Set 向缓存设置一个值
+// ZGet 查询有序集合
 //   参数
-//     key:    key值
-//     val:    value值
-//     expire: 到期是缓存过期时间，以秒为单位：从现在开始的相对时间。“0”表示项目没有到期时间。
-//     encode: 是否加密标识
+//     key:        有序集合key值
+//     start:      要查询有序集开始下标，0表示第一个，-1表示最后一个，-2表示倒数第二个
+//     stop:       要查询有序集结束下标，0表示第一个，-1表示最后一个，-2表示倒数第二个
+//     withScores: 是否带上score
+//     isRev:      true-递减排列，使用ZREVRANGE命令 false-递增排列，使用ZRANGE命令
 //   返回
-//     成功时返回nil，失败返回错误信息
-func (rp *RedisPool) Set(key string, val interface{}, expire int32, encode ...bool) error {
-	// 类型转换
-	data, err := cache.InterToByte(val)
-	if err != nil {
-		return err
+//     查询的结果数据和错误码
+func (rp *RedisPool) ZGet(key string, start, stop int, withScores bool, isRev bool) ([]string, error) {
+	return rp.ZGetCtx(context.Background(), key, start, stop, withScores, isRev)
+}
+
+// ZGetCtx 同ZGet，ctx用于控制超时和取消
+func (rp *RedisPool) ZGetCtx(ctx context.Context, key string, start, stop int, withScores bool, isRev bool) (res []string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	vals := []redis.Z{}
+	res = []string{}
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
 	}
 
-	// 加密判断
-	encode = append(encode, false)
-	if encode[0] {
-		data, err = cache.Encode(data, rp.encodeKey)
-		if err != nil {
-			return err
+	if isRev {
+
+		if withScores {
+			vals, err = rp.getClient(ctx).ZRevRangeWithScores(key, int64(start), int64(stop)).Result()
+			if err != nil {
+				return res, err
+			}
+		} else {
+			return rp.getClient(ctx).ZRevRange(key, int64(start), int64(stop)).Result()
+		}
+	} else {
+		if withScores {
+			vals, err = rp.getClient(ctx).ZRangeWithScores(key, int64(start), int64(stop)).Result()
+			if err != nil {
+				return res, err
+			}
+		} else {
+			return rp.getClient(ctx).ZRange(key, int64(start), int64(stop)).Result()
 		}
 	}
 
-	if rp.prefix != "" {
-		key = rp.prefix + key
+	for _, val := range vals {
+		res = append(res, fmt.Sprintf("%f", val.Score), fmt.Sprintf("%v", val.Member))
 	}
 
-	return rp.client.Set(key, data, time.Duration(expire)*time.Second).Err()
+	return res, err
 }
 
-// Get 从缓存取一个值
+// ZDel 删除有序集合数据
 //   参数
-//     key: key值
-//     val: 保存结果地址
+//     key:   有序集合key值
+//     field: 要删除的数据
 //   返回
-//     错误信息，是否存在
-func (rp *RedisPool) Get(key string, val interface{}) (error, bool) {
+//     成功删除的数据个数和错误码
+func (rp *RedisPool) ZDel(key string, field ...string) (int64, error) {
+	return rp.ZDelCtx(context.Background(), key, field...)
+}
+
+// ZDelCtx 同ZDel，ctx用于控制超时和取消
+func (rp *RedisPool) ZDelCtx(ctx context.Context, key string, field ...string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	var args []interface{}
+	for _, f := range field {
+		args = append(args, f)
+	}
+
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
+	return rp.getClient(ctx).ZRem(key, args...).Result()
+}
 
-	v, err := rp.client.Get(key).Result()
-	if err != nil {
-		if err.Error() == NOT_EXIST {
-			return nil, false
-		}
-		return err, false
-	}
+// ZRemRangeByRank 删除指定排名区间内的有序集合数据
+//   参数
+//     key:   有序集合key值
+//     start: 开始值
+//     end:   结束值
+//   返回
+//     成功删除的数据个数和错误码
+func (rp *RedisPool) ZRemRangeByRank(key string, start, end int64) (int64, error) {
+	return rp.ZRemRangeByRankCtx(context.Background(), key, start, end)
+}
 
-	// 解密判断
-	data, err := cache.Decode([]byte(v), rp.encodeKey)
-	if err != nil {
-		return err, true
+// ZRemRangeByRankCtx 同ZRemRangeByRank，ctx用于控制超时和取消
+func (rp *RedisPool) ZRemRangeByRankCtx(ctx context.Context, key string, start, end int64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
 	}
 
-	// 类型转换
-	err = cache.ByteToInter(data, val)
-	if err != nil {
-		return err, true
+	return rp.getClient(ctx).ZRemRangeByRank(key, start, end).Result()
+}
+
+// ZRemRangeByScore 删除指定分值区间内的有序集合数据
+//   参数
+//     key:   有序集合key值
+//     start: 开始值
+//     end:   结束值
+//   返回
+//     成功删除的数据个数和错误码
+func (rp *RedisPool) ZRemRangeByScore(key string, start, end string) (int64, error) {
+	return rp.ZRemRangeByScoreCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByScoreCtx 同ZRemRangeByScore，ctx用于控制超时和取消
+func (rp *RedisPool) ZRemRangeByScoreCtx(ctx context.Context, key string, start, end string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
 	}
 
-	return nil, true
+	return rp.getClient(ctx).ZRemRangeByScore(key, start, end).Result()
 }
 
-// Del 从缓存删除一个值
+// ZRemRangeByLex 删除指定变量区间内的有序集合数据
+// 对于一个所有成员的分值都相同的有序集合键 key 来说， 这个命令会移除该集合中， 成员介于 min 和 max 范围内的所有元素。
 //   参数
-//     key:    key值
+//     key:   有序集合key值
+//     start: 开始值
+//     end:   结束值
 //   返回
-//     成功时返回nil，失败返回错误信息
-func (rp *RedisPool) Del(key string) error {
+//     成功删除的数据个数和错误码
+func (rp *RedisPool) ZRemRangeByLex(key string, start, end string) (int64, error) {
+	return rp.ZRemRangeByLexCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByLexCtx 同ZRemRangeByLex，ctx用于控制超时和取消
+func (rp *RedisPool) ZRemRangeByLexCtx(ctx context.Context, key string, start, end string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
 
-	return rp.client.Del(key).Err()
+	return rp.getClient(ctx).ZRemRangeByLex(key, start, end).Result()
 }
 
-// MSet 同时设置一个或多个key-value对
+// ZCard 返回有序集 key 的基数
 //   参数
-//     mList:  key-value对
-//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间。“0”表示项目没有到期时间。
-//     encode: 是否加密标识
+//     key: 有序集合key值
 //   返回
-//     成功时返回nil，失败返回错误信息
-func (rp *RedisPool) MSet(mList map[string]interface{}, expire int32, encode ...bool) error {
-	var v []interface{}
-	for key, val := range mList {
-		// 类型转换
-		data, err := cache.InterToByte(val)
-		if err != nil {
-			return err
-		}
+//     有序集 key 的基数和错误码
+func (rp *RedisPool) ZCard(key string) (int64, error) {
+	return rp.ZCardCtx(context.Background(), key)
+}
 
-		// 加密判断
-		encode = append(encode, false)
-		if encode[0] {
-			data, err = cache.Encode(data, rp.encodeKey)
-			if err != nil {
-				return err
-			}
-		}
+// ZCardCtx 同ZCard，ctx用于控制超时和取消
+func (rp *RedisPool) ZCardCtx(ctx context.Context, key string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
 
-		if rp.prefix != "" {
-			key = rp.prefix + key
-		}
-		v = append(v, key, data)
+	if rp.prefix != "" {
+		key = rp.prefix + key
 	}
+	return rp.getClient(ctx).ZCard(key).Result()
+}
 
-	err := rp.client.MSet(v...).Err()
-	if err != nil {
-		return err
+// SetBit 设置或清除指定偏移量上的位(bit)
+//   参数
+//     key:    位图key值
+//     offset: 位图偏移量
+//     value:  位图值，取值：0或1
+//     expire: 失效时长，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//   返回
+//     指定偏移量原来储存的位、错误信息
+func (rp *RedisPool) SetBit(key string, offset int64, value int, expire int32) (int64, error) {
+	return rp.SetBitCtx(context.Background(), key, offset, value, expire)
+}
+
+// SetBitCtx 同SetBit，ctx用于控制超时和取消
+func (rp *RedisPool) SetBitCtx(ctx context.Context, key string, offset int64, value int, expire int32) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
 	}
 
-	// 设置失效时间
-	if expire > 0 {
-		for key := range mList {
-			if rp.prefix != "" {
-				key = rp.prefix + key
-			}
-			rp.client.Expire(key, time.Duration(expire)*time.Second)
+	var cmd *redis.IntCmd
+	_, err = rp.getClient(ctx).TxPipelined(func(pipe redis.Pipeliner) error {
+		cmd = pipe.SetBit(key, offset, value)
+		if expire > 0 {
+			pipe.Expire(key, time.Duration(expire)*time.Second)
 		}
-	}
+		return nil
+	})
 
-	return err
+	return cmd.Val(), err
 }
 
-// MGet 同时获取一个或多个key的value，
-// struct、map类型返回的结果需要调用方做一下json.Unmarshal处理
+// GetBit 获取指定偏移量上的位(bit)
 //   参数
-//     keys:  要查询的key值
+//     key:    位图key值
+//     offset: 位图偏移量
 //   返回
-//     成功返回查询结果，失败返回错误信息，key不存在时对应的val为nil
-func (rp *RedisPool) MGet(keys ...string) (map[string]interface{}, error) {
-	mList := make(map[string]interface{})
-	args := []string{}
-	for _, k := range keys {
-		if rp.prefix != "" {
-			k = rp.prefix + k
-		}
-		args = append(args, k)
-	}
+//     字符串值指定偏移量上的位(bit)、错误信息
+func (rp *RedisPool) GetBit(key string, offset int64) (int64, error) {
+	return rp.GetBitCtx(context.Background(), key, offset)
+}
 
-	v, err := rp.client.MGet(args...).Result()
-	if err != nil {
-		return mList, err
+// GetBitCtx 同GetBit，ctx用于控制超时和取消
+func (rp *RedisPool) GetBitCtx(ctx context.Context, key string, offset int64) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
 	}
 
-	i := 0
-	for _, val := range v {
-		if val == nil {
-			// 不存在
-			mList[keys[i]] = nil
-		} else {
-			// 解密判断
-			data, err := cache.Decode([]byte(val.(string)), rp.encodeKey)
-			if err != nil {
-				return mList, err
-			}
+	return rp.getClient(ctx).GetBit(key, offset).Result()
+}
 
-			mList[keys[i]] = string(data)
-		}
+// BitCount 计算给定字符串中被设置为 1 的比特位的数量
+//   参数
+//     key:      位图key值
+//     bitCount: 指定额外的 start 或 end 参数，统计只在特定的位上进行，为nil时统计所有的
+//   返回
+//     给定字符串中被设置为 1 的比特位的数量、错误信息
+func (rp *RedisPool) BitCount(key string, bitCount *cache.BitCount) (int64, error) {
+	return rp.BitCountCtx(context.Background(), key, bitCount)
+}
 
-		i++
+// BitCountCtx 同BitCount，ctx用于控制超时和取消
+func (rp *RedisPool) BitCountCtx(ctx context.Context, key string, bitCount *cache.BitCount) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
 	}
 
-	return mList, nil
+	if bitCount != nil {
+		bc := redis.BitCount{Start: bitCount.Start, End: bitCount.End}
+		return rp.getClient(ctx).BitCount(key, &bc).Result()
+	}
+
+	return rp.getClient(ctx).BitCount(key, nil).Result()
 }
 
-// MDel 同时删除一个或多个key
+// PFAdd 添加基数
 //   参数
-//     keys:  要查询的key值
+//     key:    HyperLogLog的key值
+//     expire: 失效时长，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   HyperLogLog的数据
 //   返回
-//     成功时返回nil，失败返回错误信息
-func (rp *RedisPool) MDel(keys ...string) error {
-	args := make([]string, len(keys))
-	for k, v := range keys {
-		if rp.prefix != "" {
-			v = rp.prefix + v
-		}
-		args[k] = v
-	}
-
-	rp.client.Del(args...)
-	return nil
+//     存在，不做任何事情，返回0；不存在的话就创建，并返回1
+//     错误信息
+func (rp *RedisPool) PFAdd(key string, expire int32, vals ...interface{}) (int64, error) {
+	return rp.PFAddCtx(context.Background(), key, expire, vals...)
 }
 
-// Incr 缓存里的值自增
-// key不存在时会新建一个，再返回1
-//   参数
-//     key:   递增的key值
-//     delta: 递增的量
-//   返回
-//     递增后的结果，失败返回错误信息
-func (rp *RedisPool) Incr(key string, delta ...uint64) (int64, error) {
-	delta = append(delta, 1)
+// PFAddCtx 同PFAdd，ctx用于控制超时和取消
+func (rp *RedisPool) PFAddCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
-	v, err := rp.client.IncrBy(key, int64(delta[0])).Result()
+
+	res, err = rp.getClient(ctx).PFAdd(key, vals...).Result()
 	if err != nil {
-		return 0, err
+		return res, err
 	}
 
-	return v, nil
+	if expire > 0 {
+		rp.getClient(ctx).Expire(key, time.Duration(expire)*time.Second)
+	}
+
+	return res, err
 }
 
-// Decr 缓存里的值自减
-// key不存在时会新建一个，再返回-1
+// PFCount 返回基数估算值
 //   参数
-//     key:   要递减的key值
-//     delta: 递减的量
+//     key: 位图key值，PFCount命令可以传多个key，但reddis-cluster会报错(CROSSSLOT Keys in request don't hash to the same slot.)
 //   返回
-//     递减后的结果，失败返回错误信息
-func (rp *RedisPool) Decr(key string, delta ...uint64) (int64, error) {
-	delta = append(delta, 1)
+//     基数估算值
+func (rp *RedisPool) PFCount(key string) (int64, error) {
+	return rp.PFCountCtx(context.Background(), key)
+}
+
+// PFCountCtx 同PFCount，ctx用于控制超时和取消
+func (rp *RedisPool) PFCountCtx(ctx context.Context, key string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
-	v, err := rp.client.DecrBy(key, int64(delta[0])).Result()
-	if err != nil {
-		return 0, err
-	}
 
-	return v, nil
+	return rp.getClient(ctx).PFCount(key).Result()
 }
 
-// IsExist 判断key值是否存在
+// LPush 从列表头部插入元素
 //   参数
-//     key:  要查询的key值
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素，与HSet一致，按序列化和压缩配置转换
 //   返回
-//     存在返回true，不存在返回false，出错时返回错误信息
-func (rp *RedisPool) IsExist(key string) (bool, error) {
-	if rp.prefix != "" {
-		key = rp.prefix + key
-	}
-	n, err := rp.client.Exists(key).Result()
-	if err != nil {
-		return false, err
-	}
+//     成功时返回列表长度，失败返回错误信息
+func (rp *RedisPool) LPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return rp.LPushCtx(context.Background(), key, expire, vals...)
+}
 
-	if n == 1 {
-		return true, nil
-	}
+// LPushCtx 同LPush，ctx用于控制超时和取消
+func (rp *RedisPool) LPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
 
-	return false, nil
+	return rp.push(ctx, key, expire, true, vals)
 }
 
-// ClearAll 清空所有数据
+// RPush 从列表尾部插入元素
 //   参数
-//
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素，与HSet一致，按序列化和压缩配置转换
 //   返回
-//     成功时返回nil，失败返回错误信息
-func (rp *RedisPool) ClearAll() error {
-	keys, err := rp.client.Keys("*").Result()
-	if err != nil {
-		return err
-	}
+//     成功时返回列表长度，失败返回错误信息
+func (rp *RedisPool) RPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return rp.RPushCtx(context.Background(), key, expire, vals...)
+}
 
-	return rp.client.Del(keys...).Err()
+// RPushCtx 同RPush，ctx用于控制超时和取消
+func (rp *RedisPool) RPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	return rp.push(ctx, key, expire, false, vals)
 }
 
-// Hset 添加哈希表
+// push 向列表插入元素
 //   参数
-//     key:    哈希表key值
-//     field:  哈希表field值
-//     val:    哈希表value值
-//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     ctx:    上下文
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位
+//     isLeft: 是否从头部插入
+//     vals:   元素
 //   返回
-//     成功时返回添加的个数，失败返回错误信息
-func (rp *RedisPool) HSet(key string, field string, val interface{}, expire int32) (int64, error) {
-	// 类型转换
-	data, err := cache.InterToByte(val)
+//     成功时返回列表长度，失败返回错误信息
+func (rp *RedisPool) push(ctx context.Context, key string, expire int32, isLeft bool, vals []interface{}) (int64, error) {
+	args, err := rp.toValues(vals)
 	if err != nil {
-		return -1, err
+		return 0, err
 	}
 
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
 
-	err = rp.client.HSet(key, field, data).Err()
+	var n int64
+	if isLeft {
+		n, err = rp.getClient(ctx).LPush(key, args...).Result()
+	} else {
+		n, err = rp.getClient(ctx).RPush(key, args...).Result()
+	}
 	if err != nil {
-		return -1, err
+		return 0, err
 	}
 
 	if expire > 0 {
-		rp.client.Expire(key, time.Duration(expire)*time.Second)
+		rp.getClient(ctx).Expire(key, time.Duration(expire)*time.Second)
 	}
 
-	return 1, err
+	return n, nil
 }
 
-// HGet 查询哈希表数据
+// LPop 从列表头部取出一个元素
 //   参数
-//     key:   哈希表key值
-//     field: 哈希表field值
-//     val:   保存结果地址
+//     key: 列表key值
+//     val: 保存结果地址
 //   返回
 //     错误信息，是否存在
-func (rp *RedisPool) HGet(key string, field string, val interface{}) (error, bool) {
+func (rp *RedisPool) LPop(key string, val interface{}) (error, bool) {
+	return rp.LPopCtx(context.Background(), key, val)
+}
+
+// LPopCtx 同LPop，ctx用于控制超时和取消
+func (rp *RedisPool) LPopCtx(ctx context.Context, key string, val interface{}) (err error, exist bool) {
+	defer cache.ConvRedisError(&err)
+
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
 
-	v, err := rp.client.HGet(key, field).Result()
+	v, err := rp.getClient(ctx).LPop(key).Result()
 	if err != nil {
-		if err.Error() == NOT_EXIST {
+		if cache.IsMiss(err) {
 			return nil, false
 		}
 		return err, false
 	}
 
+	// 解压
+	data, err := cache.Uncompress([]byte(v))
+	if err != nil {
+		return err, true
+	}
+
 	// 类型转换
-	err = cache.ByteToInter([]byte(v), val)
+	err = cache.ByteToInter(data, val)
 	if err != nil {
 		return err, true
 	}
@@ -942,433 +2953,818 @@ func (rp *RedisPool) HGet(key string, field string, val interface{}) (error, boo
 	return nil, true
 }
 
-// HDel 删除哈希表数据
+// BRPop 阻塞地从多个列表尾部取出一个元素
 //   参数
-//     key:    哈希表key值
-//     fields: 哈希表field值
+//     timeout: 阻塞时间，以秒为单位，“0”表示一直阻塞
+//     val:     保存结果地址
+//     keys:    列表key值，按顺序检查
 //   返回
-//     成功返回nil，失败返回错误信息
-func (rp *RedisPool) HDel(key string, fields ...string) error {
-	if rp.prefix != "" {
-		key = rp.prefix + key
+//     取到元素的列表key值，超时返回空串，错误信息
+func (rp *RedisPool) BRPop(timeout int32, val interface{}, keys ...string) (string, error) {
+	return rp.BRPopCtx(context.Background(), timeout, val, keys...)
+}
+
+// BRPopCtx 同BRPop，ctx用于控制超时和取消
+func (rp *RedisPool) BRPopCtx(ctx context.Context, timeout int32, val interface{}, keys ...string) (_ string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKeys := make([]string, len(keys))
+	for i, key := range keys {
+		pKeys[i] = rp.prefix + key
+	}
+
+	res, err := rp.getClient(ctx).BRPop(time.Duration(timeout)*time.Second, pKeys...).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	// 解压
+	data, err := cache.Uncompress([]byte(res[1]))
+	if err != nil {
+		return "", err
+	}
+
+	// 类型转换
+	key := strings.TrimPrefix(res[0], rp.prefix)
+	err = cache.ByteToInter(data, val)
+	if err != nil {
+		return key, err
 	}
 
-	return rp.client.HDel(key, fields...).Err()
+	return key, nil
 }
 
-// HGetAll 返回哈希表 key 中，所有的域和值，struct、map类型需要业务层调用json.Unmarshal
+// LRange 查询列表指定区间的元素
 //   参数
-//     key: 有序集合key值
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
 //   返回
-//     查询的结果数据和错误码
-//   示例
-//     key := "addr"
-//     val, err := adapter.HGetAll(key)
-//     if err != nil {
-//         fmt.Printf("Redis HGetAll failed. err: %s.", err.Error())
-//         return
-//     }
-//     for k, v := range val {
-//         var data StUser
-//         if v != nil {
-//             json.Unmarshal(v.([]byte), &data)
-//         }
-//         fmt.Println(k, data)
-//     }
-func (rp *RedisPool) HGetAll(key string) (map[string]interface{}, error) {
+//     成功时返回元素列表，失败返回错误信息
+func (rp *RedisPool) LRange(key string, start, stop int64) ([]interface{}, error) {
+	return rp.LRangeCtx(context.Background(), key, start, stop)
+}
+
+// LRangeCtx 同LRange，ctx用于控制超时和取消
+func (rp *RedisPool) LRangeCtx(ctx context.Context, key string, start, stop int64) (res []interface{}, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
 
-	res := make(map[string]interface{})
-	val, err := rp.client.HGetAll(key).Result()
+	v, err := rp.getClient(ctx).LRange(key, start, stop).Result()
 	if err != nil {
-		if err.Error() == NOT_EXIST {
-			return res, nil
-		}
-
 		return nil, err
 	}
 
-	for k, v := range val {
-		res[k] = v
+	res = make([]interface{}, len(v))
+	for i, item := range v {
+		// 解压
+		res[i], err = cache.UncompressString(item)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return res, err
+	return res, nil
 }
 
-// HMSet 同时将多个 field-value (域-值)对设置到哈希表 key 中
+// LTrim 只保留列表指定区间的元素
 //   参数
-//     key:    有序集合key值
-//     fields: field-value 对
-//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
 //   返回
-//     执行结果
-func (rp *RedisPool) HMSet(key string, fields map[string]interface{}, expire int32) error {
+//     成功时返回nil，失败返回错误信息
+func (rp *RedisPool) LTrim(key string, start, stop int64) error {
+	return rp.LTrimCtx(context.Background(), key, start, stop)
+}
+
+// LTrimCtx 同LTrim，ctx用于控制超时和取消
+func (rp *RedisPool) LTrimCtx(ctx context.Context, key string, start, stop int64) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).LTrim(key, start, stop).Err()
+}
+
+// SAdd 向集合添加成员
+//   参数
+//     key:     集合key值
+//     expire:  缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     members: 成员，只做序列化，不压缩，保证相同的成员保存的数据相同
+//   返回
+//     成功时返回新添加的个数，失败返回错误信息
+func (rp *RedisPool) SAdd(key string, expire int32, members ...interface{}) (int64, error) {
+	return rp.SAddCtx(context.Background(), key, expire, members...)
+}
+
+// SAddCtx 同SAdd，ctx用于控制超时和取消
+func (rp *RedisPool) SAddCtx(ctx context.Context, key string, expire int32, members ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	args, err := rp.toMembers(members)
+	if err != nil {
+		return 0, err
+	}
+
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
 
-	err := rp.client.HMSet(key, fields).Err()
+	n, err := rp.getClient(ctx).SAdd(key, args...).Result()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if expire > 0 {
-		rp.client.Expire(key, time.Duration(expire)*time.Second)
+		rp.getClient(ctx).Expire(key, time.Duration(expire)*time.Second)
 	}
 
-	return nil
+	return n, nil
 }
 
-// HMGet 返回哈希表 key 中，一个或多个给定域的值，struct、map类型需要业务层调用json.Unmarshal
+// SRem 删除集合的成员
 //   参数
-//     key:    有序集合key值
-//     fields: 给定域的集合
+//     key:     集合key值
+//     members: 成员
 //   返回
-//     查询的结果数据和错误码
-func (rp *RedisPool) HMGet(key string, fields ...string) (map[string]interface{}, error) {
+//     成功时返回删除的个数，失败返回错误信息
+func (rp *RedisPool) SRem(key string, members ...interface{}) (int64, error) {
+	return rp.SRemCtx(context.Background(), key, members...)
+}
+
+// SRemCtx 同SRem，ctx用于控制超时和取消
+func (rp *RedisPool) SRemCtx(ctx context.Context, key string, members ...interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	args, err := rp.toMembers(members)
+	if err != nil {
+		return 0, err
+	}
+
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
-	res := make(map[string]interface{})
 
-	v, err := rp.client.HMGet(key, fields...).Result()
+	return rp.getClient(ctx).SRem(key, args...).Result()
+}
+
+// SIsMember 判断是否为集合的成员
+//   参数
+//     key:    集合key值
+//     member: 成员
+//   返回
+//     是成员返回true，不是返回false，失败返回错误信息
+func (rp *RedisPool) SIsMember(key string, member interface{}) (bool, error) {
+	return rp.SIsMemberCtx(context.Background(), key, member)
+}
+
+// SIsMemberCtx 同SIsMember，ctx用于控制超时和取消
+func (rp *RedisPool) SIsMemberCtx(ctx context.Context, key string, member interface{}) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
+	data, err := cache.InterToByte(member, rp.serializer)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
 
-	if v == nil {
-		return res, nil
+	if rp.prefix != "" {
+		key = rp.prefix + key
 	}
 
-	i := 0
-	for _, field := range fields {
-		res[field] = v[i]
-		i++
+	return rp.getClient(ctx).SIsMember(key, data).Result()
+}
+
+// SMembers 查询集合的所有成员
+//   参数
+//     key: 集合key值
+//   返回
+//     成功时返回所有成员，失败返回错误信息
+func (rp *RedisPool) SMembers(key string) ([]string, error) {
+	return rp.SMembersCtx(context.Background(), key)
+}
+
+// SMembersCtx 同SMembers，ctx用于控制超时和取消
+func (rp *RedisPool) SMembersCtx(ctx context.Context, key string) (res []string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
 	}
 
-	return res, err
+	return rp.getClient(ctx).SMembers(key).Result()
 }
 
-// HVals 返回哈希表 key 中，所有的域和值，struct、map类型需要业务层调用json.Unmarshal
+// SInter 返回多个集合的交集
 //   参数
-//     key:        有序集合key值
+//     keys: 集合key值
 //   返回
-//     查询的结果数据和错误码
-//   示例
-//     key := "addr"
-//     val, err := adapter.HVals(key)
-//     if err != nil {
-//         fmt.Printf("Redis HVals failed. err: %s.", err.Error())
-//         return
-//     }
-//     for _, v := range val {
-//         var data StUser
-//         if v != nil {
-//             json.Unmarshal(v.([]byte), &data)
-//         }
-//         fmt.Println(data)
-//     }
-func (rp *RedisPool) HVals(key string) ([]interface{}, error) {
-	vals, err := rp.client.HVals(key).Result()
+//     成功时返回交集的成员，失败返回错误信息
+func (rp *RedisPool) SInter(keys ...string) ([]string, error) {
+	return rp.SInterCtx(context.Background(), keys...)
+}
+
+// SInterCtx 同SInter，ctx用于控制超时和取消
+func (rp *RedisPool) SInterCtx(ctx context.Context, keys ...string) (res []string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKeys := make([]string, len(keys))
+	for i, key := range keys {
+		pKeys[i] = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).SInter(pKeys...).Result()
+}
+
+// SUnion 返回多个集合的并集
+//   参数
+//     keys: 集合key值
+//   返回
+//     成功时返回并集的成员，失败返回错误信息
+func (rp *RedisPool) SUnion(keys ...string) ([]string, error) {
+	return rp.SUnionCtx(context.Background(), keys...)
+}
+
+// SUnionCtx 同SUnion，ctx用于控制超时和取消
+func (rp *RedisPool) SUnionCtx(ctx context.Context, keys ...string) (res []string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	pKeys := make([]string, len(keys))
+	for i, key := range keys {
+		pKeys[i] = rp.prefix + key
+	}
+
+	return rp.getClient(ctx).SUnion(pKeys...).Result()
+}
+
+// XAdd 向流添加消息
+//   参数
+//     key:    流key值
+//     maxLen: 流的最大长度，超过时删除旧消息，为近似值，小于等于0时不限制
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   消息内容，与HSet一致，按序列化和压缩配置转换
+//   返回
+//     成功时返回消息id，失败返回错误信息
+func (rp *RedisPool) XAdd(key string, maxLen int64, expire int32, vals map[string]interface{}) (string, error) {
+	return rp.XAddCtx(context.Background(), key, maxLen, expire, vals)
+}
+
+// XAddCtx 同XAdd，ctx用于控制超时和取消
+func (rp *RedisPool) XAddCtx(ctx context.Context, key string, maxLen int64, expire int32, vals map[string]interface{}) (res string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	values := make(map[string]interface{}, len(vals))
+	for field, val := range vals {
+		args, err := rp.toValues([]interface{}{val})
+		if err != nil {
+			return "", err
+		}
+		values[field] = args[0]
+	}
+
+	if rp.prefix != "" {
+		key = rp.prefix + key
+	}
+
+	args := &redis.XAddArgs{Stream: key, Values: values}
+	if maxLen > 0 {
+		args.MaxLenApprox = maxLen
+	}
+	id, err := rp.getClient(ctx).XAdd(args).Result()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	res := make([]interface{}, len(vals))
-	for k, v := range vals {
-		res[k] = v
+	if expire > 0 {
+		rp.getClient(ctx).Expire(key, time.Duration(expire)*time.Second)
 	}
 
-	return res, nil
+	return id, nil
 }
 
-// HIncr 哈希表的值自增
+// XRead 读取流消息
 //   参数
-//     key:    有序集合key值
-//     fields: 给定域的集合
-//     delta:  递增的量，默认为1
+//     args: 读取参数，Streams前一半为key值，后一半为对应的开始id(不包含)，$表示只读新消息
 //   返回
-//     递增后的结果、失败返回错误信息
-func (rp *RedisPool) HIncr(key, fields string, delta ...uint64) (int64, error) {
-	delta = append(delta, 1)
-	if rp.prefix != "" {
-		key = rp.prefix + key
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (rp *RedisPool) XRead(args *cache.XReadArgs) ([]cache.XStream, error) {
+	return rp.XReadCtx(context.Background(), args)
+}
+
+// XReadCtx 同XRead，ctx用于控制超时和取消
+func (rp *RedisPool) XReadCtx(ctx context.Context, args *cache.XReadArgs) (_ []cache.XStream, err error) {
+	defer cache.ConvRedisError(&err)
+
+	block := args.Block
+	if block <= 0 {
+		block = -1
+	}
+
+	res, err := rp.getClient(ctx).XRead(&redis.XReadArgs{
+		Streams: rp.prefixStreams(args.Streams),
+		Count:   args.Count,
+		Block:   block,
+	}).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, nil
+		}
+		return nil, err
 	}
 
-	return rp.client.HIncrBy(key, fields, int64(delta[0])).Result()
+	return rp.toXStreams(res)
 }
 
-// HDecr 哈希表的值自减
+// XGroupCreate 创建消费组，流不存在时自动创建
 //   参数
-//     key:    有序集合key值
-//     fields: 给定域的集合
-//     delta:  递增的量，默认为1
+//     key:   流key值
+//     group: 消费组
+//     start: 开始读取的消息id，0表示从头开始，为空或$表示只读新消息
 //   返回
-//     递减后的结果、失败返回错误信息
-func (rp *RedisPool) HDecr(key, fields string, delta ...uint64) (int64, error) {
-	delta = append(delta, 1)
+//     成功或消费组已存在时返回nil，失败返回错误信息
+func (rp *RedisPool) XGroupCreate(key, group, start string) error {
+	return rp.XGroupCreateCtx(context.Background(), key, group, start)
+}
+
+// XGroupCreateCtx 同XGroupCreate，ctx用于控制超时和取消
+func (rp *RedisPool) XGroupCreateCtx(ctx context.Context, key, group, start string) (err error) {
+	defer cache.ConvRedisError(&err)
+
+	if start == "" {
+		start = "$"
+	}
+
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
 
-	return rp.client.HIncrBy(key, fields, 0-int64(delta[0])).Result()
+	err = rp.getClient(ctx).XGroupCreateMkStream(key, group, start).Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+
+	return err
 }
 
-// ZSet 添加有序集合
+// XReadGroup 按消费组读取流消息
 //   参数
-//     key:    有序集合key值
-//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
-//     val:    有序集合值，数据为成对出来，前面为score(整数值或双精度浮点数), 后面为变量
+//     args: 读取参数，Streams前一半为key值，后一半为对应的id，>表示读取未分配给消费者的新消息
 //   返回
-//     成功添加的数据个数和错误码
-func (rp *RedisPool) ZSet(key string, expire int32, val ...interface{}) (int64, error) {
-	valLen := len(val)
-	if valLen < 2 || valLen%2 != 0 {
-		return -1, errors.New("val param error")
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (rp *RedisPool) XReadGroup(args *cache.XReadGroupArgs) ([]cache.XStream, error) {
+	return rp.XReadGroupCtx(context.Background(), args)
+}
+
+// XReadGroupCtx 同XReadGroup，ctx用于控制超时和取消
+func (rp *RedisPool) XReadGroupCtx(ctx context.Context, args *cache.XReadGroupArgs) (_ []cache.XStream, err error) {
+	defer cache.ConvRedisError(&err)
+
+	block := args.Block
+	if block <= 0 {
+		block = -1
 	}
-	vals := []redis.Z{}
-	for i := 0; i < valLen-1; i += 2 {
-		stZ := redis.Z{
-			Score:  val[i].(float64),
-			Member: val[i+1],
+
+	res, err := rp.getClient(ctx).XReadGroup(&redis.XReadGroupArgs{
+		Group:    args.Group,
+		Consumer: args.Consumer,
+		Streams:  rp.prefixStreams(args.Streams),
+		Count:    args.Count,
+		Block:    block,
+		NoAck:    args.NoAck,
+	}).Result()
+	if err != nil {
+		if cache.IsMiss(err) {
+			return nil, nil
 		}
-		vals = append(vals, stZ)
+		return nil, err
 	}
 
+	return rp.toXStreams(res)
+}
+
+// XAck 确认消费组的消息
+//   参数
+//     key:   流key值
+//     group: 消费组
+//     ids:   消息id
+//   返回
+//     成功时返回确认的个数，失败返回错误信息
+func (rp *RedisPool) XAck(key, group string, ids ...string) (int64, error) {
+	return rp.XAckCtx(context.Background(), key, group, ids...)
+}
+
+// XAckCtx 同XAck，ctx用于控制超时和取消
+func (rp *RedisPool) XAckCtx(ctx context.Context, key, group string, ids ...string) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
 
-	n, err := rp.client.ZAdd(key, vals...).Result()
-	if err != nil {
-		return -1, err
-	}
+	return rp.getClient(ctx).XAck(key, group, ids...).Result()
+}
 
-	if expire > 0 {
-		rp.client.Expire(key, time.Duration(expire)*time.Second)
-	}
+// Eval 执行lua脚本，先使用EVALSHA，脚本未加载时使用EVAL
+//   参数
+//     script: lua脚本
+//     keys:   脚本的KEYS，会自动添加key前缀
+//     args:   脚本的ARGV
+//   返回
+//     脚本的返回值，脚本返回nil时返回nil，失败返回错误信息
+func (rp *RedisPool) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return rp.EvalCtx(context.Background(), script, keys, args...)
+}
 
-	return n, err
+// EvalCtx 同Eval，ctx用于控制超时和取消
+func (rp *RedisPool) EvalCtx(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return cache.EvalScript(rp.getClient(ctx), script, rp.prefixKeys(keys), args...)
 }
 
-// ZGet 查询有序集合
+// EvalSha 按sha1执行lua脚本，脚本未加载且执行过Eval或ScriptLoad时使用EVAL重试
 //   参数
-//     key:        有序集合key值
-//     start:      要查询有序集开始下标，0表示第一个，-1表示最后一个，-2表示倒数第二个
-//     stop:       要查询有序集结束下标，0表示第一个，-1表示最后一个，-2表示倒数第二个
-//     withScores: 是否带上score
-//     isRev:      true-递减排列，使用ZREVRANGE命令 false-递增排列，使用ZRANGE命令
+//     sha1: 脚本的sha1
+//     keys: 脚本的KEYS，会自动添加key前缀
+//     args: 脚本的ARGV
 //   返回
-//     查询的结果数据和错误码
-func (rp *RedisPool) ZGet(key string, start, stop int, withScores bool, isRev bool) ([]string, error) {
-	var err error
-	vals := []redis.Z{}
-	res := []string{}
+//     脚本的返回值，脚本返回nil时返回nil，失败返回错误信息
+func (rp *RedisPool) EvalSha(sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return rp.EvalShaCtx(context.Background(), sha1, keys, args...)
+}
 
-	if rp.prefix != "" {
-		key = rp.prefix + key
+// EvalShaCtx 同EvalSha，ctx用于控制超时和取消
+func (rp *RedisPool) EvalShaCtx(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return cache.EvalShaScript(rp.getClient(ctx), sha1, rp.prefixKeys(keys), args...)
+}
+
+// ScriptLoad 加载lua脚本
+//   参数
+//     script: lua脚本
+//   返回
+//     脚本的sha1，失败返回错误信息
+func (rp *RedisPool) ScriptLoad(script string) (string, error) {
+	return rp.ScriptLoadCtx(context.Background(), script)
+}
+
+// ScriptLoadCtx 同ScriptLoad，ctx用于控制超时和取消
+func (rp *RedisPool) ScriptLoadCtx(ctx context.Context, script string) (res string, err error) {
+	defer cache.ConvRedisError(&err)
+
+	cache.ScriptSha1(script)
+	return rp.getClient(ctx).ScriptLoad(script).Result()
+}
+
+// prefixKeys 给key添加前缀
+func (rp *RedisPool) prefixKeys(keys []string) []string {
+	pKeys := make([]string, len(keys))
+	for i, key := range keys {
+		pKeys[i] = rp.prefix + key
 	}
+	return pKeys
+}
 
-	if isRev {
+// toValues 转换列表和流的元素，与HSet一致，按序列化和压缩配置转换
+func (rp *RedisPool) toValues(vals []interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(vals))
+	for i, val := range vals {
+		// 类型转换
+		data, err := cache.InterToByte(val, rp.serializer)
+		if err != nil {
+			return nil, err
+		}
 
-		if withScores {
-			vals, err = rp.client.ZRevRangeWithScores(key, int64(start), int64(stop)).Result()
-			if err != nil {
-				return res, err
-			}
-		} else {
-			return rp.client.ZRevRange(key, int64(start), int64(stop)).Result()
+		// 压缩判断
+		data, err = cache.Compress(data, rp.compressType, rp.compressThreshold)
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		if withScores {
-			vals, err = rp.client.ZRangeWithScores(key, int64(start), int64(stop)).Result()
-			if err != nil {
-				return res, err
+		args[i] = data
+	}
+
+	return args, nil
+}
+
+// toMembers 转换集合的成员，只做序列化
+func (rp *RedisPool) toMembers(members []interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(members))
+	for i, member := range members {
+		data, err := cache.InterToByte(member, rp.serializer)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = data
+	}
+
+	return args, nil
+}
+
+// toXStreams 转换读取到的流消息，去掉key前缀并解压消息内容
+func (rp *RedisPool) toXStreams(streams []redis.XStream) ([]cache.XStream, error) {
+	res := make([]cache.XStream, len(streams))
+	for i, stream := range streams {
+		res[i].Stream = strings.TrimPrefix(stream.Stream, rp.prefix)
+		res[i].Messages = make([]cache.XMessage, len(stream.Messages))
+		for j, msg := range stream.Messages {
+			values := make(map[string]interface{}, len(msg.Values))
+			for field, val := range msg.Values {
+				v, err := cache.UncompressString(val)
+				if err != nil {
+					return nil, err
+				}
+				values[field] = v
 			}
-		} else {
-			return rp.client.ZRange(key, int64(start), int64(stop)).Result()
+			res[i].Messages[j] = cache.XMessage{ID: msg.ID, Values: values}
 		}
 	}
 
-	for _, val := range vals {
-		res = append(res, fmt.Sprintf("%f", val.Score), fmt.Sprintf("%v", val.Member))
+	return res, nil
+}
+
+// prefixStreams 给XRead参数中的key添加前缀，前一半为key，后一半为id
+func (rp *RedisPool) prefixStreams(streams []string) []string {
+	res := make([]string, len(streams))
+	copy(res, streams)
+	if rp.prefix != "" {
+		for i := 0; i < len(res)/2; i++ {
+			res[i] = rp.prefix + res[i]
+		}
 	}
 
-	return res, err
+	return res
 }
 
-// ZDel 删除有序集合数据
+// RunScript 执行lua脚本，优先使用EVALSHA，脚本未加载时使用EVAL
 //   参数
-//     key:   有序集合key值
-//     field: 要删除的数据
+//     ctx:    上下文
+//     script: lua脚本
+//     keys:   脚本的KEYS，会添加key前缀
+//     args:   脚本的ARGV
 //   返回
-//     成功删除的数据个数和错误码
-func (rp *RedisPool) ZDel(key string, field ...string) (int64, error) {
-	var args []interface{}
-	for _, f := range field {
-		args = append(args, f)
-	}
+//     脚本的返回值，失败返回错误信息
+func (rp *RedisPool) RunScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (res interface{}, err error) {
+	defer cache.ConvRedisError(&err)
 
-	if rp.prefix != "" {
-		key = rp.prefix + key
-	}
-	return rp.client.ZRem(key, args...).Result()
+	return script.Run(rp.getClient(ctx), rp.prefixKeys(keys), args...).Result()
 }
 
-// ZRemRangeByRank 删除指定排名区间内的有序集合数据
+// AcquireLock 获取分布式锁，使用SET NX PX，由cache/lock包使用
 //   参数
-//     key:   有序集合key值
-//     start: 开始值
-//     end:   结束值
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 持有者的随机token
+//     ttl:   锁的过期时间
 //   返回
-//     成功删除的数据个数和错误码
-func (rp *RedisPool) ZRemRangeByRank(key string, start, end int64) (int64, error) {
+//     获取到返回true，锁已存在返回false，失败返回错误信息
+func (rp *RedisPool) AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
 
-	return rp.client.ZRemRangeByRank(key, start, end).Result()
+	return rp.getClient(ctx).SetNX(key, token, ttl).Result()
 }
 
-// ZRemRangeByScore 删除指定分值区间内的有序集合数据
+// ReleaseLock 释放分布式锁，token一致时才删除
 //   参数
-//     key:   有序集合key值
-//     start: 开始值
-//     end:   结束值
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 持有者的随机token
 //   返回
-//     成功删除的数据个数和错误码
-func (rp *RedisPool) ZRemRangeByScore(key string, start, end string) (int64, error) {
+//     删除成功返回true，锁不存在或不是持有者返回false，失败返回错误信息
+func (rp *RedisPool) ReleaseLock(ctx context.Context, key, token string) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
 
-	return rp.client.ZRemRangeByScore(key, start, end).Result()
+	n, err := cache.LockReleaseScript.Run(rp.getClient(ctx), []string{key}, token).Int64()
+	return n == 1, err
 }
 
-// ZRemRangeByLex 删除指定变量区间内的有序集合数据
-// 对于一个所有成员的分值都相同的有序集合键 key 来说， 这个命令会移除该集合中， 成员介于 min 和 max 范围内的所有元素。
+// RefreshLock 更新分布式锁的过期时间，token一致时才更新
 //   参数
-//     key:   有序集合key值
-//     start: 开始值
-//     end:   结束值
+//     ctx:   上下文
+//     key:   锁的key值
+//     token: 持有者的随机token
+//     ttl:   新的过期时间
 //   返回
-//     成功删除的数据个数和错误码
-func (rp *RedisPool) ZRemRangeByLex(key string, start, end string) (int64, error) {
+//     更新成功返回true，锁不存在或不是持有者返回false，失败返回错误信息
+func (rp *RedisPool) RefreshLock(ctx context.Context, key, token string, ttl time.Duration) (ok bool, err error) {
+	defer cache.ConvRedisError(&err)
+
 	if rp.prefix != "" {
 		key = rp.prefix + key
 	}
 
-	return rp.client.ZRemRangeByLex(key, start, end).Result()
+	n, err := cache.LockRefreshScript.Run(rp.getClient(ctx), []string{key}, token, ttl.Milliseconds()).Int64()
+	return n == 1, err
 }
 
-// ZCard 返回有序集 key 的基数
+// Publish 向频道发布消息
 //   参数
-//     key: 有序集合key值
+//     channel: 频道，不添加key前缀
+//     msg:     消息内容
 //   返回
-//     有序集 key 的基数和错误码
-func (rp *RedisPool) ZCard(key string) (int64, error) {
-	if rp.prefix != "" {
-		key = rp.prefix + key
-	}
-	return rp.client.ZCard(key).Result()
+//     收到消息的订阅者数、错误信息
+func (rp *RedisPool) Publish(channel string, msg interface{}) (int64, error) {
+	return rp.PublishCtx(context.Background(), channel, msg)
 }
 
-// SetBit 设置或清除指定偏移量上的位(bit)
+// PublishCtx 同Publish，ctx用于控制超时和取消
+func (rp *RedisPool) PublishCtx(ctx context.Context, channel string, msg interface{}) (res int64, err error) {
+	defer cache.ConvRedisError(&err)
+
+	return rp.getClient(ctx).Publish(channel, msg).Result()
+}
+
+// Subscribe 订阅频道，断线后自动重连并重新订阅
 //   参数
-//     key:    位图key值
-//     offset: 位图偏移量
-//     value:  位图值，取值：0或1
-//     expire: 失效时长，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     ctx:      上下文，取消时取消订阅并关闭返回的channel
+//     channels: 频道，不添加key前缀
 //   返回
-//     指定偏移量原来储存的位、错误信息
-func (rp *RedisPool) SetBit(key string, offset int64, value int, expire int32) (int64, error) {
-	if rp.prefix != "" {
-		key = rp.prefix + key
-	}
-
-	res, err := rp.client.SetBit(key, offset, value).Result()
-	if err != nil {
-		return res, err
-	}
+//     消息channel、错误信息
+func (rp *RedisPool) Subscribe(ctx context.Context, channels ...string) (_ <-chan *cache.Message, err error) {
+	defer cache.ConvRedisError(&err)
 
-	if expire > 0 {
-		rp.client.Expire(key, time.Duration(expire)*time.Second)
+	if len(channels) == 0 {
+		return nil, errors.New("RedisPool: Subscribe channels is empty")
 	}
 
-	return res, err
+	return cache.ReceiveMessages(ctx, "", rp.client.Subscribe(channels...))
 }
 
-// GetBit 获取指定偏移量上的位(bit)
+// SubscribeExpired 订阅带key前缀的key的过期通知，redis需要配置notify-keyspace-events包含Ex
 //   参数
-//     key:    位图key值
-//     offset: 位图偏移量
+//     ctx: 上下文，取消时取消订阅并关闭返回的channel
 //   返回
-//     字符串值指定偏移量上的位(bit)、错误信息
-func (rp *RedisPool) GetBit(key string, offset int64) (int64, error) {
-	if rp.prefix != "" {
-		key = rp.prefix + key
-	}
-
-	return rp.client.GetBit(key, offset).Result()
+//     消息channel，Payload为不含前缀的key，失败返回错误信息
+func (rp *RedisPool) SubscribeExpired(ctx context.Context) (<-chan *cache.Message, error) {
+	return cache.ReceiveMessages(ctx, rp.prefix, rp.client.PSubscribe(cache.KeyEventExpired))
 }
 
-// BitCount 计算给定字符串中被设置为 1 的比特位的数量
+// Batch 新建批量命令，key前缀、序列化、压缩和加密与Set、Get等方法一致
 //   参数
-//     key:      位图key值
-//     bitCount: 指定额外的 start 或 end 参数，统计只在特定的位上进行，为nil时统计所有的
+//     isTx: 是否事务模式，为true时使用MULTI/EXEC执行
 //   返回
-//     给定字符串中被设置为 1 的比特位的数量、错误信息
-func (rp *RedisPool) BitCount(key string, bitCount *cache.BitCount) (int64, error) {
-	if rp.prefix != "" {
-		key = rp.prefix + key
+//     批量命令
+func (rp *RedisPool) Batch(isTx bool) *cache.Batch {
+	return cache.NewBatch(isTx, rp.execBatch)
+}
+
+// execBatch 使用pipeline执行批量命令
+func (rp *RedisPool) execBatch(ctx context.Context, cmds []*cache.BatchCmd, isTx bool) error {
+	var pipe redis.Pipeliner
+	if isTx {
+		pipe = rp.getClient(ctx).TxPipeline()
+	} else {
+		pipe = rp.getClient(ctx).Pipeline()
 	}
 
-	if bitCount != nil {
-		bc := redis.BitCount{Start: bitCount.Start, End: bitCount.End}
-		return rp.client.BitCount(key, &bc).Result()
+	res, err := rp.queueBatch(pipe, cmds, isTx)
+	if err != nil {
+		return err
 	}
 
-	return rp.client.BitCount(key, nil).Result()
+	// 每个命令的错误由batchResult处理
+	pipe.Exec()
+
+	return rp.batchResult(cmds, res)
 }
 
-// PFAdd 添加基数
+// queueBatch 将批量命令加入pipeline，返回每个命令对应的redis命令
+// 值转换出错时，事务模式直接返回错误，否则只设置该命令的错误
 //   参数
-//     key:    HyperLogLog的key值
-//     expire: 失效时长，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
-//     vals:   HyperLogLog的数据
+//     pipe: pipeline
+//     cmds: 批量命令
+//     isTx: 是否事务模式
 //   返回
-//     存在，不做任何事情，返回0；不存在的话就创建，并返回1
-//     错误信息
-func (rp *RedisPool) PFAdd(key string, expire int32, vals ...interface{}) (int64, error) {
-	if rp.prefix != "" {
-		key = rp.prefix + key
-	}
+//     每个命令对应的redis命令，出错的命令为nil，失败返回错误信息
+func (rp *RedisPool) queueBatch(pipe redis.Pipeliner, cmds []*cache.BatchCmd, isTx bool) ([]redis.Cmder, error) {
+	res := make([]redis.Cmder, len(cmds))
+	for i, cmd := range cmds {
+		key := cmd.Key
+		if rp.prefix != "" {
+			key = rp.prefix + key
+		}
 
-	res, err := rp.client.PFAdd(key, vals...).Result()
-	if err != nil {
-		return res, err
-	}
+		switch cmd.Op {
+		case cache.BatchSet, cache.BatchHSet:
+			// 类型转换
+			data, err := cache.InterToByte(cmd.Val, rp.serializer)
 
-	if expire > 0 {
-		rp.client.Expire(key, time.Duration(expire)*time.Second)
+			// 压缩判断
+			if err == nil {
+				data, err = cache.Compress(data, rp.compressType, rp.compressThreshold)
+			}
+
+			// 加密判断，哈希表不加密
+			if err == nil && cmd.Op == cache.BatchSet && cmd.Encode {
+				data, err = cache.Encode(data, rp.encodeKey...)
+			}
+
+			if err != nil {
+				if isTx {
+					return nil, err
+				}
+				cmd.SetResult(nil, 0, false, err)
+				continue
+			}
+
+			if cmd.Op == cache.BatchSet {
+				res[i] = pipe.Set(key, data, time.Duration(cmd.Expire)*time.Second)
+			} else {
+				res[i] = pipe.HSet(key, cmd.Fields[0], data)
+				if cmd.Expire > 0 {
+					pipe.Expire(key, time.Duration(cmd.Expire)*time.Second)
+				}
+			}
+		case cache.BatchGet:
+			res[i] = pipe.Get(key)
+		case cache.BatchDel:
+			res[i] = pipe.Del(key)
+		case cache.BatchIncr:
+			res[i] = pipe.IncrBy(key, cmd.Delta)
+		case cache.BatchExpire:
+			res[i] = pipe.Expire(key, time.Duration(cmd.Expire)*time.Second)
+		case cache.BatchHGet:
+			res[i] = pipe.HGet(key, cmd.Fields[0])
+		case cache.BatchHDel:
+			res[i] = pipe.HDel(key, cmd.Fields...)
+		case cache.BatchHIncr:
+			res[i] = pipe.HIncrBy(key, cmd.Fields[0], cmd.Delta)
+		default:
+			err := cache.NewError(cache.ErrUnsupported, fmt.Sprintf("RedismCache: Batch don't support %s", cmd.Op))
+			if isTx {
+				return nil, err
+			}
+			cmd.SetResult(nil, 0, false, err)
+		}
 	}
 
-	return res, err
+	return res, nil
 }
 
-// PFCount 返回基数估算值
+// batchResult 设置批量命令的执行结果，Get的结果解密后解压，HGet的结果只解压
 //   参数
-//     key: 位图key值，PFCount命令可以传多个key，但reddis-cluster会报错(CROSSSLOT Keys in request don't hash to the same slot.)
+//     cmds: 批量命令
+//     res:  每个命令对应的redis命令
 //   返回
-//     基数估算值
-func (rp *RedisPool) PFCount(key string) (int64, error) {
-	if rp.prefix != "" {
-		key = rp.prefix + key
+//     第一个出错命令的错误信息，key不存在不算错误
+func (rp *RedisPool) batchResult(cmds []*cache.BatchCmd, res []redis.Cmder) error {
+	var firstErr error
+	for i, cmd := range cmds {
+		switch r := res[i].(type) {
+		case nil:
+			// 加入pipeline前已出错
+		case *redis.StringCmd:
+			v, err := r.Result()
+			if err != nil {
+				if cache.IsMiss(err) {
+					cmd.SetResult(nil, 0, false, nil)
+				} else {
+					cmd.SetResult(nil, 0, false, err)
+				}
+				break
+			}
+
+			data := []byte(v)
+			if cmd.Op == cache.BatchGet {
+				data, err = cache.Decode(data, rp.encodeKey...)
+			}
+			if err == nil {
+				data, err = cache.Uncompress(data)
+			}
+			cmd.SetResult(data, 0, true, err)
+		case *redis.IntCmd:
+			v, err := r.Result()
+			cmd.SetResult(nil, v, err == nil, err)
+		case *redis.BoolCmd:
+			v, err := r.Result()
+			var n int64
+			if v {
+				n = 1
+			}
+			cmd.SetResult(nil, n, v, err)
+		default:
+			err := r.Err()
+			cmd.SetResult(nil, 0, err == nil, err)
+		}
+
+		if err := cmd.Err(); err != nil && firstErr == nil {
+			firstErr = err
+		}
 	}
 
-	return rp.client.PFCount(key).Result()
+	return firstErr
 }
 
 // Pipeline 执行pipeline命令
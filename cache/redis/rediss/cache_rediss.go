@@ -0,0 +1,1537 @@
+// Sentinel mode
+package rediss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/lixy529/gotools/cache"
+	"github.com/lixy529/gotools/cache/redis/redism"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 哨兵事件，收到后重新获取主从库
+var sentinelEvents = []string{"+switch-master", "+slave", "+sdown", "-sdown", "+odown", "-odown"}
+
+// RedissCache Redis哨兵模式缓存
+// 通过哨兵发现主库和从库，写操作访问主库，读操作访问从库，没有可用从库时访问主库
+// 收到+switch-master等哨兵事件后重新获取主从库，地址变化时重新连接
+type RedissCache struct {
+	master     *redism.RedisPool // 主库
+	slave      *redism.RedisPool // 从库，为nil时访问主库
+	masterAddr string            // 当前主库连接串
+	slaveAddr  string            // 当前从库连接串
+	lock       sync.RWMutex
+
+	sentinels []*redis.SentinelClient // 哨兵连接
+	pubsubs   []*redis.PubSub         // 哨兵事件订阅
+	wg        sync.WaitGroup
+
+	sentinelAddr []string // 哨兵连接串
+	sentinelAuth string   // 哨兵授权码
+	masterName   string   // 主库名称
+	dbNum        int      // DbNum
+	auth         string   // 授权码
+
+	dialTimeout  time.Duration // 连接超时时间，单位秒，默认5秒
+	readTimeout  time.Duration // 读超时时间，单位秒，-1-不超时，0-使用默认3秒
+	writeTimeout time.Duration // 写超时时间，单位秒，默认为readTimeout
+	poolSize     int           // 每个节点连接池的连接数，默认为cpu个数的10倍
+	minIdleConns int           // 最少空闲连接数，默认为0
+	maxConnAge   time.Duration // 最大连接时间，单位秒，超时时间自动关闭，默认为0
+	poolTimeout  time.Duration // 如果所有连接都忙时的等待时间，默认为readTimeout+1秒
+	idleTimeout  time.Duration // 最大空闲时间，单位秒，默认为5分钟
+
+	prefix     string           // key前缀，如果配置里有，则所有key前自动添加此前缀
+	encodeKey  [][]byte         // 加解密密钥，第一个用于加密，全部用于解密
+	serializer cache.Serializer // 序列化，默认为json
+
+	compressType      string // 压缩类型，支持zlib、gzip、snappy，为空时不压缩
+	compressThreshold int    // 超过大小就进行压缩，单位字节，默认256
+
+	hooks cache.Hooks // 命令钩子
+}
+
+func init() {
+	cache.Register(cache.AdapterRediss, NewRedissCache)
+}
+
+// NewRedissCache 新建一个RedissCache适配器.
+func NewRedissCache() cache.Cache {
+	return &RedissCache{}
+}
+
+// Init 初始化
+//   参数
+//     config: 配置josn串
+//       {
+//         "sentinelAddr":"127.0.0.1:26379,127.0.0.2:26379,127.0.0.3:26379",
+//         "sentinelAuth":"xxxxx",
+//         "masterName":"mymaster",
+//         "auth":"xxxxx",
+//         "dbNum":"0",
+//         "dialTimeout":"5",
+//         "readTimeout":"5",
+//         "writeTimeout":"5",
+//         "poolSize":"5",
+//         "minIdleConns":"5",
+//         "maxConnAge":"5",
+//         "poolTimeout":"5",
+//         "idleTimeout":"5",
+//         "prefix":"le_",
+//         "encodeKey":"abcdefghij123456",
+//         "serializer":"json",
+//         "compressType":"snappy",
+//         "compressThreshold":"256",
+//       }
+//       sentinelAddr: 哨兵主机和端口，多个哨兵用逗号分割
+//       sentinelAuth: 哨兵授权密码
+//       masterName:   主库名称，与哨兵配置的sentinel monitor一致
+//       auth:         主从库授权密码
+//       dbNum:        db编号，默认为0
+//       dialTimeout:  连接超时时间，单位秒，默认5秒
+//       readTimeout:  读超时时间，单位秒，-1-不超时，0-使用默认3秒
+//       writeTimeout: 写超时时间，单位秒，默认为readTimeout
+//       poolSize:     每个节点连接池的连接数，默认为cpu个数的10倍
+//       minIdleConns: 最少空闲连接数，默认为0
+//       maxConnAge:   最大连接时间，单位秒，超时时间自动关闭，默认为0
+//       poolTimeout:  如果所有连接都忙时的等待时间，默认为readTimeout+1秒
+//       idleTimeout:  最大空闲时间，单位秒，默认为5分钟
+//       prefix:       key前缀，如果配置里有，则所有key前自动添加此前缀
+//       serializer:   序列化，支持json、gob、msgpack、raw，默认为json
+//       compressType: 压缩类型，支持zlib、gzip、snappy，为空时不压缩
+//       compressThreshold: 超过大小就进行压缩，单位字节，默认256
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rc *RedissCache) Init(config string) error {
+	var mapCfg map[string]string
+	var err error
+
+	err = json.Unmarshal([]byte(config), &mapCfg)
+	if err != nil {
+		return fmt.Errorf("RedissCache: Unmarshal json[%s] error, %s", config, err.Error())
+	}
+
+	// 连接超时时间
+	dialTimeout, err := strconv.Atoi(mapCfg["dialTimeout"])
+	if err != nil || dialTimeout < 0 {
+		rc.dialTimeout = 5
+	} else {
+		rc.dialTimeout = time.Duration(dialTimeout)
+	}
+
+	// 读超时时间
+	readTimeout, err := strconv.Atoi(mapCfg["readTimeout"])
+	if err != nil {
+		rc.readTimeout = 3
+	} else if readTimeout < 0 {
+		rc.readTimeout = -1
+	} else {
+		rc.readTimeout = time.Duration(readTimeout)
+	}
+
+	// 写超时时间
+	writeTimeout, err := strconv.Atoi(mapCfg["writeTimeout"])
+	if err != nil {
+		rc.writeTimeout = rc.readTimeout
+	} else if writeTimeout < 0 {
+		rc.writeTimeout = -1
+	} else {
+		rc.writeTimeout = time.Duration(writeTimeout)
+	}
+
+	// 每个节点连接池的连接数
+	poolSize, err := strconv.Atoi(mapCfg["poolSize"])
+	if err != nil || poolSize < 0 {
+		rc.poolSize = 0
+	} else {
+		rc.poolSize = poolSize
+	}
+
+	// 最少空闲连接数
+	minIdleConns, err := strconv.Atoi(mapCfg["minIdleConns"])
+	if err != nil || minIdleConns < 0 {
+		rc.minIdleConns = 0
+	} else {
+		rc.minIdleConns = minIdleConns
+	}
+
+	// 最大连接时间
+	maxConnAge, err := strconv.Atoi(mapCfg["maxConnAge"])
+	if err != nil || maxConnAge < 0 {
+		rc.maxConnAge = 0
+	} else {
+		rc.maxConnAge = time.Duration(maxConnAge)
+	}
+
+	// 如果所有连接都忙时的等待时间
+	poolTimeout, err := strconv.Atoi(mapCfg["poolTimeout"])
+	if err != nil || poolTimeout < 0 {
+		rc.poolTimeout = rc.readTimeout + 1
+	} else {
+		rc.poolTimeout = time.Duration(poolTimeout)
+	}
+
+	// 最大空闲时间
+	idleTimeout, err := strconv.Atoi(mapCfg["idleTimeout"])
+	if err != nil || idleTimeout < 0 {
+		rc.idleTimeout = 300
+	} else {
+		rc.idleTimeout = time.Duration(idleTimeout)
+	}
+
+	// 前缀
+	if prefix, ok := mapCfg["prefix"]; ok {
+		rc.prefix = prefix
+	}
+
+	// 加密密钥
+	if tmp, ok := mapCfg["encodeKey"]; ok && tmp != "" {
+		rc.encodeKey = cache.ParseEncodeKeys(tmp)
+	}
+
+	// 序列化
+	rc.serializer, err = cache.GetSerializer(mapCfg["serializer"])
+	if err != nil {
+		return err
+	}
+
+	// 压缩
+	rc.compressType = mapCfg["compressType"]
+	err = cache.CheckCompressType(rc.compressType)
+	if err != nil {
+		return err
+	}
+	compressThreshold, err := strconv.Atoi(mapCfg["compressThreshold"])
+	if err != nil || compressThreshold < 0 {
+		rc.compressThreshold = 256
+	} else {
+		rc.compressThreshold = compressThreshold
+	}
+
+	// 哨兵配置
+	sentinelAddr := mapCfg["sentinelAddr"]
+	if sentinelAddr == "" {
+		return errors.New("RedissCache: Sentinel addr is empty")
+	}
+	rc.sentinelAddr = strings.Split(sentinelAddr, ",")
+	rc.sentinelAuth = mapCfg["sentinelAuth"]
+
+	rc.masterName = mapCfg["masterName"]
+	if rc.masterName == "" {
+		return errors.New("RedissCache: Master name is empty")
+	}
+
+	dbNum, err := strconv.Atoi(mapCfg["dbNum"])
+	if err != nil {
+		rc.dbNum = 0
+	} else {
+		rc.dbNum = dbNum
+	}
+
+	rc.auth = mapCfg["auth"]
+
+	// 哨兵连接
+	for _, addr := range rc.sentinelAddr {
+		rc.sentinels = append(rc.sentinels, redis.NewSentinelClient(&redis.Options{
+			Addr:         addr,
+			Password:     rc.sentinelAuth,
+			DialTimeout:  rc.dialTimeout * time.Second,
+			ReadTimeout:  rc.readTimeout * time.Second,
+			WriteTimeout: rc.writeTimeout * time.Second,
+		}))
+	}
+
+	// 实例化主从库
+	err = rc.refresh()
+	if err != nil {
+		rc.Close()
+		return err
+	}
+
+	// 订阅哨兵事件
+	rc.watch()
+
+	return nil
+}
+
+// Close 关闭主从库连接和哨兵事件订阅
+//   参数
+//
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rc *RedissCache) Close() error {
+	for _, pubsub := range rc.pubsubs {
+		pubsub.Close()
+	}
+	rc.wg.Wait()
+	rc.pubsubs = nil
+
+	for _, sentinel := range rc.sentinels {
+		sentinel.Close()
+	}
+	rc.sentinels = nil
+
+	rc.lock.Lock()
+	master, slave := rc.master, rc.slave
+	rc.master, rc.slave = nil, nil
+	rc.masterAddr, rc.slaveAddr = "", ""
+	rc.lock.Unlock()
+
+	if slave != nil {
+		slave.Close()
+	}
+	if master != nil {
+		return master.Close()
+	}
+
+	return nil
+}
+
+// getMaster 获取主库
+func (rc *RedissCache) getMaster() *redism.RedisPool {
+	rc.lock.RLock()
+	defer rc.lock.RUnlock()
+	return rc.master
+}
+
+// getSlave 获取从库，没有可用从库时返回主库
+func (rc *RedissCache) getSlave() *redism.RedisPool {
+	rc.lock.RLock()
+	defer rc.lock.RUnlock()
+	if rc.slave == nil {
+		return rc.master
+	}
+
+	return rc.slave
+}
+
+// refresh 从哨兵获取主库和可用从库，地址变化时重新连接，当前从库仍可用时不切换
+//   参数
+//
+//   返回
+//     成功时返回nil，所有哨兵都不可用时返回错误信息
+func (rc *RedissCache) refresh() error {
+	masterAddr, slaveAddrs, err := rc.discover()
+	if err != nil {
+		return err
+	}
+
+	var olds []*redism.RedisPool
+	rc.lock.Lock()
+	if masterAddr != rc.masterAddr {
+		if rc.master != nil {
+			olds = append(olds, rc.master)
+		}
+		rc.masterAddr = masterAddr
+		rc.master = rc.newPool(masterAddr)
+	}
+
+	keep := false
+	for _, addr := range slaveAddrs {
+		if addr == rc.slaveAddr {
+			keep = true
+			break
+		}
+	}
+	if !keep {
+		if rc.slave != nil {
+			olds = append(olds, rc.slave)
+		}
+		if len(slaveAddrs) == 0 {
+			rc.slave = nil
+			rc.slaveAddr = ""
+		} else {
+			rc.slaveAddr = slaveAddrs[rand.Intn(len(slaveAddrs))]
+			rc.slave = rc.newPool(rc.slaveAddr)
+		}
+	}
+	rc.lock.Unlock()
+
+	for _, old := range olds {
+		old.Close()
+	}
+
+	return nil
+}
+
+// newPool 实例化指定地址的连接池
+func (rc *RedissCache) newPool(addr string) *redism.RedisPool {
+	pool := redism.NewRedisPool(addr, rc.auth, rc.dbNum, rc.dialTimeout, rc.readTimeout, rc.writeTimeout, rc.poolSize, rc.minIdleConns, rc.maxConnAge, rc.poolTimeout, rc.idleTimeout, rc.prefix, rc.encodeKey, rc.serializer, rc.compressType, rc.compressThreshold)
+	pool.AddRedisHook(cache.NewRedisHook(cache.AdapterRediss, rc.prefix, &rc.hooks))
+	return pool
+}
+
+// discover 依次询问哨兵，返回主库地址和可用的从库地址
+//   参数
+//
+//   返回
+//     主库地址，从库地址列表，所有哨兵都不可用时返回错误信息
+func (rc *RedissCache) discover() (string, []string, error) {
+	var lastErr error
+	for _, sentinel := range rc.sentinels {
+		master, err := sentinel.GetMasterAddrByName(rc.masterName).Result()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(master) != 2 {
+			lastErr = fmt.Errorf("invalid master addr %v", master)
+			continue
+		}
+
+		res, err := sentinel.Slaves(rc.masterName).Result()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		slaves := make([]string, 0, len(res))
+		for _, item := range res {
+			info := parseSlaveInfo(item)
+			if isSlaveDown(info) {
+				continue
+			}
+			slaves = append(slaves, net.JoinHostPort(info["ip"], info["port"]))
+		}
+
+		return net.JoinHostPort(master[0], master[1]), slaves, nil
+	}
+
+	return "", nil, fmt.Errorf("RedissCache: Get master %s from sentinel error, %v", rc.masterName, lastErr)
+}
+
+// parseSlaveInfo 解析SENTINEL SLAVES返回的从库信息，返回的是字段和值交替的列表
+func parseSlaveInfo(item interface{}) map[string]string {
+	info := make(map[string]string)
+	fields, ok := item.([]interface{})
+	if !ok {
+		return info
+	}
+
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, _ := fields[i].(string)
+		val, _ := fields[i+1].(string)
+		info[key] = val
+	}
+
+	return info
+}
+
+// isSlaveDown 判断从库是否不可用，下线、断开或与主库的同步断开时不可用
+func isSlaveDown(info map[string]string) bool {
+	if info["ip"] == "" || info["port"] == "" {
+		return true
+	}
+	if link, ok := info["master-link-status"]; ok && link != "ok" {
+		return true
+	}
+	for _, flag := range strings.Split(info["flags"], ",") {
+		switch flag {
+		case "s_down", "o_down", "disconnected":
+			return true
+		}
+	}
+
+	return false
+}
+
+// watch 订阅所有哨兵的事件，主库切换或从库上下线时重新获取主从库
+// 每个哨兵都会发出相同的事件，重复获取时地址不变不会重新连接，订阅连接断开时由redis.PubSub自动重连
+func (rc *RedissCache) watch() {
+	for _, sentinel := range rc.sentinels {
+		pubsub := sentinel.Subscribe(sentinelEvents...)
+		rc.pubsubs = append(rc.pubsubs, pubsub)
+
+		ch := pubsub.Channel()
+		rc.wg.Add(1)
+		go func() {
+			defer rc.wg.Done()
+			for msg := range ch {
+				if eventMaster(msg.Channel, msg.Payload) != rc.masterName {
+					continue
+				}
+				rc.refresh()
+			}
+		}()
+	}
+}
+
+// eventMaster 返回哨兵事件对应的主库名称
+//   +switch-master事件格式为：<master name> <oldip> <oldport> <newip> <newport>
+//   其它事件格式为：<instance type> <name> <ip> <port> @ <master name> <master ip> <master port>
+//   实例类型为master时没有@部分，name即为主库名称
+func eventMaster(channel, payload string) string {
+	fields := strings.Fields(payload)
+	if channel == "+switch-master" {
+		if len(fields) > 0 {
+			return fields[0]
+		}
+		return ""
+	}
+
+	if len(fields) > 1 && fields[0] == "master" {
+		return fields[1]
+	}
+	for i, field := range fields {
+		if field == "@" && i+1 < len(fields) {
+			return fields[i+1]
+		}
+	}
+
+	return ""
+}
+
+// AddHook 添加命令钩子，主从切换后新的连接也会调用
+//   参数
+//     hook: 钩子
+//   返回
+//
+func (rc *RedissCache) AddHook(hook cache.Hook) {
+	rc.hooks.AddHook(hook)
+}
+
+// Serializer 返回配置的序列化，实现cache.SerializerGetter
+func (rc *RedissCache) Serializer() cache.Serializer {
+	return rc.serializer
+}
+
+// Set 向缓存设置一个值，访问主库
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 到期是缓存过期时间，以秒为单位：从现在开始的相对时间。“0”表示项目没有到期时间。
+//     encode: 是否加密标识
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rc *RedissCache) Set(key string, val interface{}, expire int32, encode ...bool) error {
+	return rc.SetCtx(context.Background(), key, val, expire, encode...)
+}
+
+// SetCtx 同Set，ctx用于控制超时和取消
+func (rc *RedissCache) SetCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) error {
+	return rc.getMaster().SetCtx(ctx, key, val, expire, encode...)
+}
+
+// Get 从缓存取一个值，访问从库
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     错误信息，是否存在
+func (rc *RedissCache) Get(key string, val interface{}) (error, bool) {
+	return rc.GetCtx(context.Background(), key, val)
+}
+
+// GetCtx 同Get，ctx用于控制超时和取消
+func (rc *RedissCache) GetCtx(ctx context.Context, key string, val interface{}) (error, bool) {
+	return rc.getSlave().GetCtx(ctx, key, val)
+}
+
+// Lookup 从缓存取一个值，同Get，返回值顺序为(是否存在, 错误信息)，访问从库
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     是否存在，错误信息，key不存在时返回false和nil
+func (rc *RedissCache) Lookup(key string, val interface{}) (bool, error) {
+	return rc.LookupCtx(context.Background(), key, val)
+}
+
+// LookupCtx 同Lookup，ctx用于控制超时和取消
+func (rc *RedissCache) LookupCtx(ctx context.Context, key string, val interface{}) (bool, error) {
+	err, exist := rc.GetCtx(ctx, key, val)
+	return exist, err
+}
+
+// Del 从缓存删除一个值，访问主库
+//   参数
+//     key:    key值
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rc *RedissCache) Del(key string) error {
+	return rc.DelCtx(context.Background(), key)
+}
+
+// DelCtx 同Del，ctx用于控制超时和取消
+func (rc *RedissCache) DelCtx(ctx context.Context, key string) error {
+	return rc.getMaster().DelCtx(ctx, key)
+}
+
+// Add key不存在时设置一个值，访问主库
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key已存在返回false，失败返回错误信息
+func (rc *RedissCache) Add(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rc.AddCtx(context.Background(), key, val, expire, encode...)
+}
+
+// AddCtx 同Add，ctx用于控制超时和取消
+func (rc *RedissCache) AddCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rc.getMaster().AddCtx(ctx, key, val, expire, encode...)
+}
+
+// Replace key存在时设置一个值，访问主库
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key不存在返回false，失败返回错误信息
+func (rc *RedissCache) Replace(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rc.ReplaceCtx(context.Background(), key, val, expire, encode...)
+}
+
+// ReplaceCtx 同Replace，ctx用于控制超时和取消
+func (rc *RedissCache) ReplaceCtx(ctx context.Context, key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rc.getMaster().ReplaceCtx(ctx, key, val, expire, encode...)
+}
+
+// GetWithVersion 从缓存取一个值，同时返回数据的版本，用于CompareAndSwap，访问主库
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     数据的版本，key不存在时返回nil，失败返回错误信息
+func (rc *RedissCache) GetWithVersion(key string, val interface{}) (*cache.Version, error) {
+	return rc.GetWithVersionCtx(context.Background(), key, val)
+}
+
+// GetWithVersionCtx 同GetWithVersion，ctx用于控制超时和取消
+func (rc *RedissCache) GetWithVersionCtx(ctx context.Context, key string, val interface{}) (*cache.Version, error) {
+	return rc.getMaster().GetWithVersionCtx(ctx, key, val)
+}
+
+// CompareAndSwap 数据的版本与GetWithVersion返回的版本一致时设置新值，访问主库
+// 只比较数据内容，数据被修改后又改回原值(A→B→A)时检测不到，需要时在值里保存版本号
+//   参数
+//     ver:    GetWithVersion返回的版本
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示保留原来的过期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，数据已被修改或删除返回false，失败返回错误信息
+func (rc *RedissCache) CompareAndSwap(ver *cache.Version, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rc.CompareAndSwapCtx(context.Background(), ver, val, expire, encode...)
+}
+
+// CompareAndSwapCtx 同CompareAndSwap，ctx用于控制超时和取消
+func (rc *RedissCache) CompareAndSwapCtx(ctx context.Context, ver *cache.Version, val interface{}, expire int32, encode ...bool) (bool, error) {
+	return rc.getMaster().CompareAndSwapCtx(ctx, ver, val, expire, encode...)
+}
+
+// MSet 同时设置一个或多个key-value对，访问主库
+//   参数
+//     mList:  key-value对
+//     expire: 到期是缓存过期时间，以秒为单位：从现在开始的相对时间。“0”表示项目没有到期时间。
+//     encode: 是否加密标识
+//   返回
+//     成功返回查询结果，失败返回错误信息，key不存在时对应的val为nil
+func (rc *RedissCache) MSet(mList map[string]interface{}, expire int32, encode ...bool) error {
+	return rc.MSetCtx(context.Background(), mList, expire, encode...)
+}
+
+// MSetCtx 同MSet，ctx用于控制超时和取消
+func (rc *RedissCache) MSetCtx(ctx context.Context, mList map[string]interface{}, expire int32, encode ...bool) error {
+	return rc.getMaster().MSetCtx(ctx, mList, expire, encode...)
+}
+
+// MGet 同时获取一个或多个key的value，访问从库
+//   参数
+//     keys:  要查询的key值
+//   返回
+//     成功返回查询结果，失败返回错误信息
+func (rc *RedissCache) MGet(keys ...string) (map[string]interface{}, error) {
+	return rc.MGetCtx(context.Background(), keys...)
+}
+
+// MGetCtx 同MGet，ctx用于控制超时和取消
+func (rc *RedissCache) MGetCtx(ctx context.Context, keys ...string) (map[string]interface{}, error) {
+	return rc.getSlave().MGetCtx(ctx, keys...)
+}
+
+// MDel 同时删除一个或多个key，访问主库
+//   参数
+//     keys:  要查询的key值
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rc *RedissCache) MDel(keys ...string) error {
+	return rc.MDelCtx(context.Background(), keys...)
+}
+
+// MDelCtx 同MDel，ctx用于控制超时和取消
+func (rc *RedissCache) MDelCtx(ctx context.Context, keys ...string) error {
+	return rc.getMaster().MDelCtx(ctx, keys...)
+}
+
+// Incr 缓存里的值自增，访问主库
+// key不存在时会新建一个，再返回1
+//   参数
+//     key:   递增的key值
+//     delta: 递增的量
+//   返回
+//     递增后的结果，失败返回错误信息
+func (rc *RedissCache) Incr(key string, delta ...uint64) (int64, error) {
+	return rc.IncrCtx(context.Background(), key, delta...)
+}
+
+// IncrCtx 同Incr，ctx用于控制超时和取消
+func (rc *RedissCache) IncrCtx(ctx context.Context, key string, delta ...uint64) (int64, error) {
+	return rc.getMaster().IncrCtx(ctx, key, delta...)
+}
+
+// Decr 缓存里的值自减，访问主库
+// key不存在时会新建一个，再返回-1
+//   参数
+//     key:   递减的key值
+//     delta: 递减的量
+//   返回
+//     递减后的结果，失败返回错误信息
+func (rc *RedissCache) Decr(key string, delta ...uint64) (int64, error) {
+	return rc.DecrCtx(context.Background(), key, delta...)
+}
+
+// DecrCtx 同Decr，ctx用于控制超时和取消
+func (rc *RedissCache) DecrCtx(ctx context.Context, key string, delta ...uint64) (int64, error) {
+	return rc.getMaster().DecrCtx(ctx, key, delta...)
+}
+
+// IncrEx 缓存里的值自增并设置过期时间，自增和设置过期时间原子执行，访问主库
+//   参数
+//     key:    递增的key值
+//     delta:  递增的量，负数时为递减
+//     expire: 过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     递增后的结果，失败返回错误信息
+func (rc *RedissCache) IncrEx(key string, delta int64, expire int32) (int64, error) {
+	return rc.IncrExCtx(context.Background(), key, delta, expire)
+}
+
+// IncrExCtx 同IncrEx，ctx用于控制超时和取消
+func (rc *RedissCache) IncrExCtx(ctx context.Context, key string, delta int64, expire int32) (int64, error) {
+	return rc.getMaster().IncrExCtx(ctx, key, delta, expire)
+}
+
+// IsExist 判断key值是否存在，访问从库
+//   参数
+//     key:  要查询的key值
+//   返回
+//     存在返回true，不存在返回false
+func (rc *RedissCache) IsExist(key string) (bool, error) {
+	return rc.IsExistCtx(context.Background(), key)
+}
+
+// IsExistCtx 同IsExist，ctx用于控制超时和取消
+func (rc *RedissCache) IsExistCtx(ctx context.Context, key string) (bool, error) {
+	return rc.getSlave().IsExistCtx(ctx, key)
+}
+
+// ClearAll 清空前缀下的所有数据，访问主库
+//   参数
+//
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rc *RedissCache) ClearAll() error {
+	return rc.ClearAllCtx(context.Background())
+}
+
+// ClearAllCtx 同ClearAll，ctx用于控制超时和取消
+func (rc *RedissCache) ClearAllCtx(ctx context.Context) error {
+	return rc.getMaster().ClearAllCtx(ctx)
+}
+
+// TTL 查询key的剩余过期时间，精确到毫秒，访问从库
+//   参数
+//     key: key值
+//   返回
+//     剩余过期时间，没有过期时间返回cache.TTL_PERSIST，key不存在返回cache.TTL_NOT_EXIST，失败返回错误信息
+func (rc *RedissCache) TTL(key string) (time.Duration, error) {
+	return rc.TTLCtx(context.Background(), key)
+}
+
+// TTLCtx 同TTL，ctx用于控制超时和取消
+func (rc *RedissCache) TTLCtx(ctx context.Context, key string) (time.Duration, error) {
+	return rc.getSlave().TTLCtx(ctx, key)
+}
+
+// Expire 设置key的过期时间，精确到毫秒，访问主库
+//   参数
+//     key:    key值
+//     expire: 过期时间，小于等于0时删除key
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (rc *RedissCache) Expire(key string, expire time.Duration) (bool, error) {
+	return rc.ExpireCtx(context.Background(), key, expire)
+}
+
+// ExpireCtx 同Expire，ctx用于控制超时和取消
+func (rc *RedissCache) ExpireCtx(ctx context.Context, key string, expire time.Duration) (bool, error) {
+	return rc.getMaster().ExpireCtx(ctx, key, expire)
+}
+
+// ExpireAt 设置key在指定时间过期，访问主库
+//   参数
+//     key: key值
+//     tm:  过期的时间点，早于当前时间时删除key
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (rc *RedissCache) ExpireAt(key string, tm time.Time) (bool, error) {
+	return rc.ExpireAtCtx(context.Background(), key, tm)
+}
+
+// ExpireAtCtx 同ExpireAt，ctx用于控制超时和取消
+func (rc *RedissCache) ExpireAtCtx(ctx context.Context, key string, tm time.Time) (bool, error) {
+	return rc.getMaster().ExpireAtCtx(ctx, key, tm)
+}
+
+// Persist 删除key的过期时间，访问主库
+//   参数
+//     key: key值
+//   返回
+//     删除了过期时间返回true，key不存在或没有过期时间返回false，失败返回错误信息
+func (rc *RedissCache) Persist(key string) (bool, error) {
+	return rc.PersistCtx(context.Background(), key)
+}
+
+// PersistCtx 同Persist，ctx用于控制超时和取消
+func (rc *RedissCache) PersistCtx(ctx context.Context, key string) (bool, error) {
+	return rc.getMaster().PersistCtx(ctx, key)
+}
+
+// Touch 更新key的过期时间，与Set的expire参数一致，访问主库
+//   参数
+//     key:    key值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (rc *RedissCache) Touch(key string, expire int32) (bool, error) {
+	return rc.TouchCtx(context.Background(), key, expire)
+}
+
+// TouchCtx 同Touch，ctx用于控制超时和取消
+func (rc *RedissCache) TouchCtx(ctx context.Context, key string, expire int32) (bool, error) {
+	return rc.getMaster().TouchCtx(ctx, key, expire)
+}
+
+// GetEx 从缓存取一个值，同时更新过期时间，访问主库
+//   参数
+//     key:    key值
+//     val:    保存结果地址
+//     expire: 新的过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     错误信息，是否存在
+func (rc *RedissCache) GetEx(key string, val interface{}, expire int32) (error, bool) {
+	return rc.GetExCtx(context.Background(), key, val, expire)
+}
+
+// GetExCtx 同GetEx，ctx用于控制超时和取消
+func (rc *RedissCache) GetExCtx(ctx context.Context, key string, val interface{}, expire int32) (error, bool) {
+	return rc.getMaster().GetExCtx(ctx, key, val, expire)
+}
+
+// Scan 按模式遍历前缀下的key，访问主库
+//   参数
+//     pattern: 匹配模式，不含前缀，为空时匹配所有key
+//     batch:   每次SCAN的key个数，小于等于0时使用redis默认值
+//   返回
+//     迭代器，返回的key已去掉前缀
+func (rc *RedissCache) Scan(pattern string, batch int64) cache.Iterator {
+	return rc.ScanCtx(context.Background(), pattern, batch)
+}
+
+// ScanCtx 同Scan，ctx用于控制超时和取消
+func (rc *RedissCache) ScanCtx(ctx context.Context, pattern string, batch int64) cache.Iterator {
+	return rc.getMaster().ScanCtx(ctx, pattern, batch)
+}
+
+// Hset 添加哈希表，访问主库
+//   参数
+//     key:    哈希表key值
+//     field:  哈希表field值
+//     val:    哈希表value值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//   返回
+//     成功时返回添加的个数，失败返回错误信息
+func (rc *RedissCache) HSet(key string, field string, val interface{}, expire int32) (int64, error) {
+	return rc.HSetCtx(context.Background(), key, field, val, expire)
+}
+
+// HSetCtx 同HSet，ctx用于控制超时和取消
+func (rc *RedissCache) HSetCtx(ctx context.Context, key string, field string, val interface{}, expire int32) (int64, error) {
+	return rc.getMaster().HSetCtx(ctx, key, field, val, expire)
+}
+
+// HGet 查询哈希表数据，访问从库
+//   参数
+//     key:   哈希表key值
+//     field: 哈希表field值
+//     val:   保存结果地址
+//   返回
+//     错误信息，是否存在
+func (rc *RedissCache) HGet(key string, field string, val interface{}) (error, bool) {
+	return rc.HGetCtx(context.Background(), key, field, val)
+}
+
+// HGetCtx 同HGet，ctx用于控制超时和取消
+func (rc *RedissCache) HGetCtx(ctx context.Context, key string, field string, val interface{}) (error, bool) {
+	return rc.getSlave().HGetCtx(ctx, key, field, val)
+}
+
+// HDel 删除哈希表数据，访问主库
+//   参数
+//     key:   哈希表key值
+//     fields: 哈希表field值
+//   返回
+//     成功返回nil，失败返回错误信息
+func (rc *RedissCache) HDel(key string, fields ...string) error {
+	return rc.HDelCtx(context.Background(), key, fields...)
+}
+
+// HDelCtx 同HDel，ctx用于控制超时和取消
+func (rc *RedissCache) HDelCtx(ctx context.Context, key string, fields ...string) error {
+	return rc.getMaster().HDelCtx(ctx, key, fields...)
+}
+
+// HGetAll 返回哈希表 key 中，所有的域和值，struct、map类型需要业务层调用json.Unmarshal
+//   参数
+//     key: 有序集合key值
+//   返回
+//     查询的结果数据和错误码
+func (rc *RedissCache) HGetAll(key string) (map[string]interface{}, error) {
+	return rc.HGetAllCtx(context.Background(), key)
+}
+
+// HGetAllCtx 同HGetAll，ctx用于控制超时和取消
+func (rc *RedissCache) HGetAllCtx(ctx context.Context, key string) (map[string]interface{}, error) {
+	return rc.getSlave().HGetAllCtx(ctx, key)
+}
+
+// HMSet 同时将多个 field-value (域-值)对设置到哈希表 key 中
+//   参数
+//     key:    有序集合key值
+//     fields: field-value 对
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//   返回
+//     执行结果
+func (rc *RedissCache) HMSet(key string, fields map[string]interface{}, expire int32) error {
+	return rc.HMSetCtx(context.Background(), key, fields, expire)
+}
+
+// HMSetCtx 同HMSet，ctx用于控制超时和取消
+func (rc *RedissCache) HMSetCtx(ctx context.Context, key string, fields map[string]interface{}, expire int32) error {
+	return rc.getSlave().HMSetCtx(ctx, key, fields, expire)
+}
+
+// HMGet 返回哈希表 key 中，一个或多个给定域的值，struct、map类型需要业务层调用json.Unmarshal
+//   参数
+//     key:    有序集合key值
+//     fields: 给定域的集合
+//   返回
+//     查询的结果数据和错误码
+func (rc *RedissCache) HMGet(key string, fields ...string) (map[string]interface{}, error) {
+	return rc.HMGetCtx(context.Background(), key, fields...)
+}
+
+// HMGetCtx 同HMGet，ctx用于控制超时和取消
+func (rc *RedissCache) HMGetCtx(ctx context.Context, key string, fields ...string) (map[string]interface{}, error) {
+	return rc.getSlave().HMGetCtx(ctx, key, fields...)
+}
+
+// HVals 返回哈希表 key 中，所有的域和值
+//   参数
+//     key: 有序集合key值
+//   返回
+//     查询的结果数据和错误码
+func (rc *RedissCache) HVals(key string) ([]interface{}, error) {
+	return rc.HValsCtx(context.Background(), key)
+}
+
+// HValsCtx 同HVals，ctx用于控制超时和取消
+func (rc *RedissCache) HValsCtx(ctx context.Context, key string) ([]interface{}, error) {
+	return rc.getSlave().HValsCtx(ctx, key)
+}
+
+// HIncr 哈希表的值自增
+//   参数
+//     key:    有序集合key值
+//     fields: 给定域的集合
+//     delta:  递增的量，默认为1
+//   返回
+//     递增后的结果、失败返回错误信息
+func (rc *RedissCache) HIncr(key, fields string, delta ...uint64) (int64, error) {
+	return rc.HIncrCtx(context.Background(), key, fields, delta...)
+}
+
+// HIncrCtx 同HIncr，ctx用于控制超时和取消
+func (rc *RedissCache) HIncrCtx(ctx context.Context, key, fields string, delta ...uint64) (int64, error) {
+	return rc.getMaster().HIncrCtx(ctx, key, fields, delta...)
+}
+
+// HDecr 哈希表的值自减
+//   参数
+//     key:    有序集合key值
+//     fields: 给定域的集合
+//     delta:  递增的量，默认为1
+//   返回
+//     递减后的结果、失败返回错误信息
+func (rc *RedissCache) HDecr(key, fields string, delta ...uint64) (int64, error) {
+	return rc.HDecrCtx(context.Background(), key, fields, delta...)
+}
+
+// HDecrCtx 同HDecr，ctx用于控制超时和取消
+func (rc *RedissCache) HDecrCtx(ctx context.Context, key, fields string, delta ...uint64) (int64, error) {
+	return rc.getMaster().HDecrCtx(ctx, key, fields, delta...)
+}
+
+// ZSet 添加有序集合
+//   参数
+//     key:    有序集合key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     val:    有序集合值，数据为成对出来，前面为score(整数值或双精度浮点数), 后面为变量
+//   返回
+//     成功添加的数据和错误码
+func (rc *RedissCache) ZSet(key string, expire int32, val ...interface{}) (int64, error) {
+	return rc.ZSetCtx(context.Background(), key, expire, val...)
+}
+
+// ZSetCtx 同ZSet，ctx用于控制超时和取消
+func (rc *RedissCache) ZSetCtx(ctx context.Context, key string, expire int32, val ...interface{}) (int64, error) {
+	return rc.getMaster().ZSetCtx(ctx, key, expire, val...)
+}
+
+// ZGet 查询有序集合
+//   参数
+//     key:        有序集合key值
+//     start:      要查询有序集开始下标，0表示第一个，-1表示最后一个，-2表示倒数第二个
+//     stop:       要查询有序集结束下标，0表示第一个，-1表示最后一个，-2表示倒数第二个
+//     withScores: 是否带上score
+//     isRev:      true-递减排列，使用ZREVRANGE命令 false-递增排列，使用ZRANGE命令
+//   返回
+//     查询的结果数据和错误码
+func (rc *RedissCache) ZGet(key string, start, stop int, withScores bool, isRev bool) ([]string, error) {
+	return rc.ZGetCtx(context.Background(), key, start, stop, withScores, isRev)
+}
+
+// ZGetCtx 同ZGet，ctx用于控制超时和取消
+func (rc *RedissCache) ZGetCtx(ctx context.Context, key string, start, stop int, withScores bool, isRev bool) ([]string, error) {
+	return rc.getSlave().ZGetCtx(ctx, key, start, stop, withScores, isRev)
+}
+
+// ZDel 删除有序集合数据
+//   参数
+//     key:   有序集合key值
+//     field: 要删除的数据
+//   返回
+//     成功删除的数据个数和错误码
+func (rc *RedissCache) ZDel(key string, field ...string) (int64, error) {
+	return rc.ZDelCtx(context.Background(), key, field...)
+}
+
+// ZDelCtx 同ZDel，ctx用于控制超时和取消
+func (rc *RedissCache) ZDelCtx(ctx context.Context, key string, field ...string) (int64, error) {
+	return rc.getMaster().ZDelCtx(ctx, key, field...)
+}
+
+// ZRemRangeByRank 删除指定排名区间内的有序集合数据
+//   参数
+//     key:   有序集合key值
+//     start: 开始值
+//     end:   结束值
+//   返回
+//     成功删除的数据个数和错误码
+func (rc *RedissCache) ZRemRangeByRank(key string, start, end int64) (int64, error) {
+	return rc.ZRemRangeByRankCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByRankCtx 同ZRemRangeByRank，ctx用于控制超时和取消
+func (rc *RedissCache) ZRemRangeByRankCtx(ctx context.Context, key string, start, end int64) (int64, error) {
+	return rc.getMaster().ZRemRangeByRankCtx(ctx, key, start, end)
+}
+
+// ZRemRangeByScore 删除指定分值区间内的有序集合数据
+//   参数
+//     key:   有序集合key值
+//     start: 开始值
+//     end:   结束值
+//   返回
+//     成功删除的数据个数和错误码
+func (rc *RedissCache) ZRemRangeByScore(key string, start, end string) (int64, error) {
+	return rc.ZRemRangeByScoreCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByScoreCtx 同ZRemRangeByScore，ctx用于控制超时和取消
+func (rc *RedissCache) ZRemRangeByScoreCtx(ctx context.Context, key string, start, end string) (int64, error) {
+	return rc.getMaster().ZRemRangeByScoreCtx(ctx, key, start, end)
+}
+
+// ZRemRangeByLex 删除指定变量区间内的有序集合数据
+// 对于一个所有成员的分值都相同的有序集合键 key 来说， 这个命令会移除该集合中， 成员介于 min 和 max 范围内的所有元素。
+//   参数
+//     key:   有序集合key值
+//     start: 开始值
+//     end:   结束值
+//   返回
+//     成功删除的数据个数和错误码
+func (rc *RedissCache) ZRemRangeByLex(key string, start, end string) (int64, error) {
+	return rc.ZRemRangeByLexCtx(context.Background(), key, start, end)
+}
+
+// ZRemRangeByLexCtx 同ZRemRangeByLex，ctx用于控制超时和取消
+func (rc *RedissCache) ZRemRangeByLexCtx(ctx context.Context, key string, start, end string) (int64, error) {
+	return rc.getMaster().ZRemRangeByLexCtx(ctx, key, start, end)
+}
+
+// ZCard 返回有序集 key 的基数
+//   参数
+//     key: 有序集合key值
+//   返回
+//     有序集 key 的基数和错误码
+func (rc *RedissCache) ZCard(key string) (int64, error) {
+	return rc.ZCardCtx(context.Background(), key)
+}
+
+// ZCardCtx 同ZCard，ctx用于控制超时和取消
+func (rc *RedissCache) ZCardCtx(ctx context.Context, key string) (int64, error) {
+	return rc.getSlave().ZCardCtx(ctx, key)
+}
+
+// SetBit 设置或清除指定偏移量上的位(bit)
+//   参数
+//     key:    位图key值
+//     offset: 位图偏移量
+//     value:  位图值，取值：0或1
+//     expire: 失效时长，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//   返回
+//     指定偏移量原来储存的位、错误信息
+func (rc *RedissCache) SetBit(key string, offset int64, value int, expire int32) (int64, error) {
+	return rc.SetBitCtx(context.Background(), key, offset, value, expire)
+}
+
+// SetBitCtx 同SetBit，ctx用于控制超时和取消
+func (rc *RedissCache) SetBitCtx(ctx context.Context, key string, offset int64, value int, expire int32) (int64, error) {
+	return rc.getMaster().SetBitCtx(ctx, key, offset, value, expire)
+}
+
+// GetBit 获取指定偏移量上的位(bit)
+//   参数
+//     key:    位图key值
+//     offset: 位图偏移量
+//   返回
+//     字符串值指定偏移量上的位(bit)、错误信息
+func (rc *RedissCache) GetBit(key string, offset int64) (int64, error) {
+	return rc.GetBitCtx(context.Background(), key, offset)
+}
+
+// GetBitCtx 同GetBit，ctx用于控制超时和取消
+func (rc *RedissCache) GetBitCtx(ctx context.Context, key string, offset int64) (int64, error) {
+	return rc.getSlave().GetBitCtx(ctx, key, offset)
+}
+
+// BitCount 计算给定字符串中被设置为 1 的比特位的数量
+//   参数
+//     key:      位图key值
+//     bitCount: 指定额外的 start 或 end 参数，统计只在特定的位上进行，为nil时统计所有的
+//   返回
+//     给定字符串中被设置为 1 的比特位的数量、错误信息
+func (rc *RedissCache) BitCount(key string, bitCount *cache.BitCount) (int64, error) {
+	return rc.BitCountCtx(context.Background(), key, bitCount)
+}
+
+// BitCountCtx 同BitCount，ctx用于控制超时和取消
+func (rc *RedissCache) BitCountCtx(ctx context.Context, key string, bitCount *cache.BitCount) (int64, error) {
+	return rc.getSlave().BitCountCtx(ctx, key, bitCount)
+}
+
+// PFAdd 添加基数
+//   参数
+//     key:    HyperLogLog的key值
+//     expire: 失效时长，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   HyperLogLog的数据
+//   返回
+//     存在，不做任何事情，返回0；不存在的话就创建，并返回1
+//     错误信息
+func (rc *RedissCache) PFAdd(key string, expire int32, vals ...interface{}) (int64, error) {
+	return rc.PFAddCtx(context.Background(), key, expire, vals...)
+}
+
+// PFAddCtx 同PFAdd，ctx用于控制超时和取消
+func (rc *RedissCache) PFAddCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error) {
+	return rc.getMaster().PFAddCtx(ctx, key, expire, vals...)
+}
+
+// PFCount 返回基数估算值
+//   参数
+//     key: 位图key值，PFCount命令可以传多个key，但reddis-cluster会报错(CROSSSLOT Keys in request don't hash to the same slot.)
+//   返回
+//     基数估算值
+func (rc *RedissCache) PFCount(key string) (int64, error) {
+	return rc.PFCountCtx(context.Background(), key)
+}
+
+// PFCountCtx 同PFCount，ctx用于控制超时和取消
+func (rc *RedissCache) PFCountCtx(ctx context.Context, key string) (int64, error) {
+	return rc.getSlave().PFCountCtx(ctx, key)
+}
+
+// LPush 从列表头部插入元素
+//   参数
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素，与HSet一致，按序列化和压缩配置转换
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (rc *RedissCache) LPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return rc.LPushCtx(context.Background(), key, expire, vals...)
+}
+
+// LPushCtx 同LPush，ctx用于控制超时和取消
+func (rc *RedissCache) LPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error) {
+	return rc.getMaster().LPushCtx(ctx, key, expire, vals...)
+}
+
+// RPush 从列表尾部插入元素
+//   参数
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素，与HSet一致，按序列化和压缩配置转换
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (rc *RedissCache) RPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return rc.RPushCtx(context.Background(), key, expire, vals...)
+}
+
+// RPushCtx 同RPush，ctx用于控制超时和取消
+func (rc *RedissCache) RPushCtx(ctx context.Context, key string, expire int32, vals ...interface{}) (int64, error) {
+	return rc.getMaster().RPushCtx(ctx, key, expire, vals...)
+}
+
+// LPop 从列表头部取出一个元素
+//   参数
+//     key: 列表key值
+//     val: 保存结果地址
+//   返回
+//     错误信息，是否存在
+func (rc *RedissCache) LPop(key string, val interface{}) (error, bool) {
+	return rc.LPopCtx(context.Background(), key, val)
+}
+
+// LPopCtx 同LPop，ctx用于控制超时和取消
+func (rc *RedissCache) LPopCtx(ctx context.Context, key string, val interface{}) (error, bool) {
+	return rc.getMaster().LPopCtx(ctx, key, val)
+}
+
+// BRPop 阻塞地从多个列表尾部取出一个元素
+//   参数
+//     timeout: 阻塞时间，以秒为单位，“0”表示一直阻塞
+//     val:     保存结果地址
+//     keys:    列表key值，按顺序检查
+//   返回
+//     取到元素的列表key值，超时返回空串，错误信息
+func (rc *RedissCache) BRPop(timeout int32, val interface{}, keys ...string) (string, error) {
+	return rc.BRPopCtx(context.Background(), timeout, val, keys...)
+}
+
+// BRPopCtx 同BRPop，ctx用于控制超时和取消
+func (rc *RedissCache) BRPopCtx(ctx context.Context, timeout int32, val interface{}, keys ...string) (string, error) {
+	return rc.getMaster().BRPopCtx(ctx, timeout, val, keys...)
+}
+
+// LRange 查询列表指定区间的元素
+//   参数
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
+//   返回
+//     成功时返回元素列表，失败返回错误信息
+func (rc *RedissCache) LRange(key string, start, stop int64) ([]interface{}, error) {
+	return rc.LRangeCtx(context.Background(), key, start, stop)
+}
+
+// LRangeCtx 同LRange，ctx用于控制超时和取消
+func (rc *RedissCache) LRangeCtx(ctx context.Context, key string, start, stop int64) ([]interface{}, error) {
+	return rc.getSlave().LRangeCtx(ctx, key, start, stop)
+}
+
+// LTrim 只保留列表指定区间的元素
+//   参数
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (rc *RedissCache) LTrim(key string, start, stop int64) error {
+	return rc.LTrimCtx(context.Background(), key, start, stop)
+}
+
+// LTrimCtx 同LTrim，ctx用于控制超时和取消
+func (rc *RedissCache) LTrimCtx(ctx context.Context, key string, start, stop int64) error {
+	return rc.getMaster().LTrimCtx(ctx, key, start, stop)
+}
+
+// SAdd 向集合添加成员
+//   参数
+//     key:     集合key值
+//     expire:  缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     members: 成员，只做序列化，不压缩，保证相同的成员保存的数据相同
+//   返回
+//     成功时返回新添加的个数，失败返回错误信息
+func (rc *RedissCache) SAdd(key string, expire int32, members ...interface{}) (int64, error) {
+	return rc.SAddCtx(context.Background(), key, expire, members...)
+}
+
+// SAddCtx 同SAdd，ctx用于控制超时和取消
+func (rc *RedissCache) SAddCtx(ctx context.Context, key string, expire int32, members ...interface{}) (int64, error) {
+	return rc.getMaster().SAddCtx(ctx, key, expire, members...)
+}
+
+// SRem 删除集合的成员
+//   参数
+//     key:     集合key值
+//     members: 成员
+//   返回
+//     成功时返回删除的个数，失败返回错误信息
+func (rc *RedissCache) SRem(key string, members ...interface{}) (int64, error) {
+	return rc.SRemCtx(context.Background(), key, members...)
+}
+
+// SRemCtx 同SRem，ctx用于控制超时和取消
+func (rc *RedissCache) SRemCtx(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	return rc.getMaster().SRemCtx(ctx, key, members...)
+}
+
+// SIsMember 判断是否为集合的成员
+//   参数
+//     key:    集合key值
+//     member: 成员
+//   返回
+//     是成员返回true，不是返回false，失败返回错误信息
+func (rc *RedissCache) SIsMember(key string, member interface{}) (bool, error) {
+	return rc.SIsMemberCtx(context.Background(), key, member)
+}
+
+// SIsMemberCtx 同SIsMember，ctx用于控制超时和取消
+func (rc *RedissCache) SIsMemberCtx(ctx context.Context, key string, member interface{}) (bool, error) {
+	return rc.getSlave().SIsMemberCtx(ctx, key, member)
+}
+
+// SMembers 查询集合的所有成员
+//   参数
+//     key: 集合key值
+//   返回
+//     成功时返回所有成员，失败返回错误信息
+func (rc *RedissCache) SMembers(key string) ([]string, error) {
+	return rc.SMembersCtx(context.Background(), key)
+}
+
+// SMembersCtx 同SMembers，ctx用于控制超时和取消
+func (rc *RedissCache) SMembersCtx(ctx context.Context, key string) ([]string, error) {
+	return rc.getSlave().SMembersCtx(ctx, key)
+}
+
+// SInter 返回多个集合的交集
+//   参数
+//     keys: 集合key值
+//   返回
+//     成功时返回交集的成员，失败返回错误信息
+func (rc *RedissCache) SInter(keys ...string) ([]string, error) {
+	return rc.SInterCtx(context.Background(), keys...)
+}
+
+// SInterCtx 同SInter，ctx用于控制超时和取消
+func (rc *RedissCache) SInterCtx(ctx context.Context, keys ...string) ([]string, error) {
+	return rc.getSlave().SInterCtx(ctx, keys...)
+}
+
+// SUnion 返回多个集合的并集
+//   参数
+//     keys: 集合key值
+//   返回
+//     成功时返回并集的成员，失败返回错误信息
+func (rc *RedissCache) SUnion(keys ...string) ([]string, error) {
+	return rc.SUnionCtx(context.Background(), keys...)
+}
+
+// SUnionCtx 同SUnion，ctx用于控制超时和取消
+func (rc *RedissCache) SUnionCtx(ctx context.Context, keys ...string) ([]string, error) {
+	return rc.getSlave().SUnionCtx(ctx, keys...)
+}
+
+// XAdd 向流添加消息
+//   参数
+//     key:    流key值
+//     maxLen: 流的最大长度，超过时删除旧消息，为近似值，小于等于0时不限制
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   消息内容，与HSet一致，按序列化和压缩配置转换
+//   返回
+//     成功时返回消息id，失败返回错误信息
+func (rc *RedissCache) XAdd(key string, maxLen int64, expire int32, vals map[string]interface{}) (string, error) {
+	return rc.XAddCtx(context.Background(), key, maxLen, expire, vals)
+}
+
+// XAddCtx 同XAdd，ctx用于控制超时和取消
+func (rc *RedissCache) XAddCtx(ctx context.Context, key string, maxLen int64, expire int32, vals map[string]interface{}) (string, error) {
+	return rc.getMaster().XAddCtx(ctx, key, maxLen, expire, vals)
+}
+
+// XRead 读取流消息
+//   参数
+//     args: 读取参数，Streams前一半为key值，后一半为对应的开始id(不包含)，$表示只读新消息
+//   返回
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (rc *RedissCache) XRead(args *cache.XReadArgs) ([]cache.XStream, error) {
+	return rc.XReadCtx(context.Background(), args)
+}
+
+// XReadCtx 同XRead，ctx用于控制超时和取消
+func (rc *RedissCache) XReadCtx(ctx context.Context, args *cache.XReadArgs) ([]cache.XStream, error) {
+	return rc.getSlave().XReadCtx(ctx, args)
+}
+
+// XGroupCreate 创建消费组，流不存在时自动创建
+//   参数
+//     key:   流key值
+//     group: 消费组
+//     start: 开始读取的消息id，0表示从头开始，为空或$表示只读新消息
+//   返回
+//     成功或消费组已存在时返回nil，失败返回错误信息
+func (rc *RedissCache) XGroupCreate(key, group, start string) error {
+	return rc.XGroupCreateCtx(context.Background(), key, group, start)
+}
+
+// XGroupCreateCtx 同XGroupCreate，ctx用于控制超时和取消
+func (rc *RedissCache) XGroupCreateCtx(ctx context.Context, key, group, start string) error {
+	return rc.getMaster().XGroupCreateCtx(ctx, key, group, start)
+}
+
+// XReadGroup 按消费组读取流消息
+//   参数
+//     args: 读取参数，Streams前一半为key值，后一半为对应的id，>表示读取未分配给消费者的新消息
+//   返回
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (rc *RedissCache) XReadGroup(args *cache.XReadGroupArgs) ([]cache.XStream, error) {
+	return rc.XReadGroupCtx(context.Background(), args)
+}
+
+// XReadGroupCtx 同XReadGroup，ctx用于控制超时和取消
+func (rc *RedissCache) XReadGroupCtx(ctx context.Context, args *cache.XReadGroupArgs) ([]cache.XStream, error) {
+	return rc.getMaster().XReadGroupCtx(ctx, args)
+}
+
+// XAck 确认消费组的消息
+//   参数
+//     key:   流key值
+//     group: 消费组
+//     ids:   消息id
+//   返回
+//     成功时返回确认的个数，失败返回错误信息
+func (rc *RedissCache) XAck(key, group string, ids ...string) (int64, error) {
+	return rc.XAckCtx(context.Background(), key, group, ids...)
+}
+
+// XAckCtx 同XAck，ctx用于控制超时和取消
+func (rc *RedissCache) XAckCtx(ctx context.Context, key, group string, ids ...string) (int64, error) {
+	return rc.getMaster().XAckCtx(ctx, key, group, ids...)
+}
+
+// Eval 执行lua脚本，访问主库
+//   参数
+//     script: lua脚本
+//     keys:   脚本的KEYS，会自动添加key前缀
+//     args:   脚本的ARGV
+//   返回
+//     脚本的返回值，脚本返回nil时返回nil，失败返回错误信息
+func (rc *RedissCache) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return rc.EvalCtx(context.Background(), script, keys, args...)
+}
+
+// EvalCtx 同Eval，ctx用于控制超时和取消
+func (rc *RedissCache) EvalCtx(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return rc.getMaster().EvalCtx(ctx, script, keys, args...)
+}
+
+// EvalSha 按sha1执行lua脚本，访问主库
+//   参数
+//     sha1: 脚本的sha1
+//     keys: 脚本的KEYS，会自动添加key前缀
+//     args: 脚本的ARGV
+//   返回
+//     脚本的返回值，脚本返回nil时返回nil，失败返回错误信息
+func (rc *RedissCache) EvalSha(sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return rc.EvalShaCtx(context.Background(), sha1, keys, args...)
+}
+
+// EvalShaCtx 同EvalSha，ctx用于控制超时和取消
+func (rc *RedissCache) EvalShaCtx(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return rc.getMaster().EvalShaCtx(ctx, sha1, keys, args...)
+}
+
+// ScriptLoad 加载lua脚本，访问主库
+//   参数
+//     script: lua脚本
+//   返回
+//     脚本的sha1，失败返回错误信息
+func (rc *RedissCache) ScriptLoad(script string) (string, error) {
+	return rc.ScriptLoadCtx(context.Background(), script)
+}
+
+// ScriptLoadCtx 同ScriptLoad，ctx用于控制超时和取消
+func (rc *RedissCache) ScriptLoadCtx(ctx context.Context, script string) (string, error) {
+	return rc.getMaster().ScriptLoadCtx(ctx, script)
+}
+
+// RunScript 执行lua脚本，访问主库
+func (rc *RedissCache) RunScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	return rc.getMaster().RunScript(ctx, script, keys, args...)
+}
+
+// AcquireLock 获取分布式锁，访问主库，由cache/lock包使用
+func (rc *RedissCache) AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	return rc.getMaster().AcquireLock(ctx, key, token, ttl)
+}
+
+// ReleaseLock 释放分布式锁，token一致时才删除
+func (rc *RedissCache) ReleaseLock(ctx context.Context, key, token string) (bool, error) {
+	return rc.getMaster().ReleaseLock(ctx, key, token)
+}
+
+// RefreshLock 更新分布式锁的过期时间，token一致时才更新
+func (rc *RedissCache) RefreshLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	return rc.getMaster().RefreshLock(ctx, key, token, ttl)
+}
+
+// Batch 新建批量命令，访问主库
+//   参数
+//     isTx: 是否事务模式，为true时使用MULTI/EXEC执行
+//   返回
+//     批量命令
+func (rc *RedissCache) Batch(isTx bool) *cache.Batch {
+	return rc.getMaster().Batch(isTx)
+}
+
+// Pipeline 执行pipeline命令
+//   实例：
+//     pipe := rc.Pipeline(false).Pipe
+//     incr := pipe.Incr("pipeline_counter")
+//     pipe.Expire("pipeline_counter", time.Hour)
+//     _, err := pipe.Exec()
+//     fmt.Println(incr.Val(), err)
+//   参数
+//     isTx: 是否事务模式
+//   返回
+//     成功刊返回命令执行的结果
+func (rc *RedissCache) Pipeline(isTx bool) cache.Pipeliner {
+	return rc.getMaster().Pipeline(isTx)
+}
+
@@ -0,0 +1,113 @@
+package rediss
+
+import (
+	"testing"
+)
+
+var gConfig = `{"sentinelAddr":"127.0.0.1:26379","masterName":"mymaster","auth":"123456","dbNum":"1","dialTimeout":"5","readTimeout":"1","writeTimeout":"1","poolSize":"100","minIdleConns":"10","maxConnAge":"3600","poolTimeout":"1","idleTimeout":"300","prefix":"le_"}`
+
+func TestRedissCache(t *testing.T) {
+	var err error
+	adapter := &RedissCache{}
+	err = adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Rediss Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+
+	k1 := "k1"
+	v1 := "HelloWorld"
+	err = adapter.Set(k1, v1, 20)
+	if err != nil {
+		t.Errorf("Rediss Set failed. err: %s.", err.Error())
+		return
+	}
+
+	var v11 string
+	err, exist := adapter.Get(k1, &v11)
+	if err != nil {
+		t.Errorf("Rediss Get failed. err: %s.", err.Error())
+		return
+	} else if !exist {
+		t.Errorf("Rediss Get failed. %s is not exist.", k1)
+		return
+	} else if v11 != v1 {
+		t.Errorf("Rediss Get failed. Got %s, expected %s.", v11, v1)
+		return
+	}
+
+	n, err := adapter.Incr("n1")
+	if err != nil || n != 1 {
+		t.Errorf("Rediss Incr failed. Got %d, expected 1.", n)
+	}
+
+	_, err = adapter.HSet("h1", "f1", "hv1", 20)
+	if err != nil {
+		t.Errorf("Rediss HSet failed. err: %s.", err.Error())
+		return
+	}
+	var hv string
+	err, exist = adapter.HGet("h1", "f1", &hv)
+	if err != nil || !exist || hv != "hv1" {
+		t.Errorf("Rediss HGet failed. Got %s, expected hv1.", hv)
+	}
+
+	err = adapter.MDel(k1, "n1", "h1")
+	if err != nil {
+		t.Errorf("Rediss MDel failed. err: %s.", err.Error())
+		return
+	}
+	isExist, err := adapter.IsExist(k1)
+	if err != nil || isExist {
+		t.Error("Rediss MDel failed. Got true, expected false.")
+	}
+}
+
+func TestRedissSlave(t *testing.T) {
+	adapter := &RedissCache{}
+	err := adapter.Init(gConfig)
+	if err != nil {
+		t.Errorf("Rediss Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+
+	// 有可用从库时读操作访问从库
+	if adapter.slaveAddr == "" || adapter.getSlave() == adapter.master {
+		t.Error("Rediss slave failed. slave is not discovered.")
+	}
+}
+
+func TestRedissEvent(t *testing.T) {
+	events := []struct {
+		channel string
+		payload string
+		master  string
+	}{
+		{"+switch-master", "mymaster 127.0.0.1 6379 127.0.0.1 6380", "mymaster"},
+		{"+sdown", "slave 127.0.0.1:6381 127.0.0.1 6381 @ mymaster 127.0.0.1 6379", "mymaster"},
+		{"+slave", "slave 127.0.0.1:6382 127.0.0.1 6382 @ other 127.0.0.1 7379", "other"},
+		{"+odown", "master mymaster 127.0.0.1 6379 #quorum 2/2", "mymaster"},
+	}
+	for _, e := range events {
+		if m := eventMaster(e.channel, e.payload); m != e.master {
+			t.Errorf("Rediss eventMaster failed. Got %s, expected %s.", m, e.master)
+		}
+	}
+
+	slaves := []struct {
+		item interface{}
+		down bool
+	}{
+		{[]interface{}{"ip", "127.0.0.1", "port", "6380", "flags", "slave", "master-link-status", "ok"}, false},
+		{[]interface{}{"ip", "127.0.0.1", "port", "6381", "flags", "s_down,slave", "master-link-status", "ok"}, true},
+		{[]interface{}{"ip", "127.0.0.1", "port", "6382", "flags", "slave", "master-link-status", "err"}, true},
+		{[]interface{}{"ip", "127.0.0.1", "flags", "slave"}, true},
+	}
+	for _, s := range slaves {
+		if down := isSlaveDown(parseSlaveInfo(s.item)); down != s.down {
+			t.Errorf("Rediss isSlaveDown failed. Got %v, expected %v. item: %v", down, s.down, s.item)
+		}
+	}
+}
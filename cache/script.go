@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"github.com/go-redis/redis/v7"
+	"strings"
+	"sync"
+)
+
+// Scripter 可以执行lua脚本的redis客户端，*redis.Client、*redis.ClusterClient都实现了此接口
+type Scripter interface {
+	Eval(script string, keys []string, args ...interface{}) *redis.Cmd
+	EvalSha(sha1 string, keys []string, args ...interface{}) *redis.Cmd
+}
+
+// IncrExScript 自增并设置过期时间
+//   KEYS[1]: 自增的key
+//   ARGV[1]: 自增的量，负数时为自减
+//   ARGV[2]: 过期时间，单位秒，小于等于0时不修改过期时间
+var IncrExScript = redis.NewScript(`local n = redis.call("incrby", KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 then redis.call("expire", KEYS[1], ARGV[2]) end
+return n`)
+
+// CompareAndSwapScript 数据的sha1与版本一致时设置新值
+// 只比较数据内容，数据被修改后又改回原值(A→B→A)时sha1不变，检测不到修改
+//   KEYS[1]: key
+//   ARGV[1]: 读取时数据的sha1
+//   ARGV[2]: 新值
+//   ARGV[3]: 过期时间，单位秒，小于等于0时保留原来的过期时间
+var CompareAndSwapScript = redis.NewScript(`local v = redis.call("get", KEYS[1])
+if not v or redis.sha1hex(v) ~= ARGV[1] then return 0 end
+if tonumber(ARGV[3]) > 0 then
+	redis.call("set", KEYS[1], ARGV[2], "ex", ARGV[3])
+else
+	local ttl = redis.call("pttl", KEYS[1])
+	if ttl > 0 then
+		redis.call("set", KEYS[1], ARGV[2], "px", ttl)
+	else
+		redis.call("set", KEYS[1], ARGV[2])
+	end
+end
+return 1`)
+
+// DataSha1 返回数据的sha1，作为redis适配器GetWithVersion的版本
+func DataSha1(data string) string {
+	sum := sha1.Sum([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// SCRIPT_CACHE_SIZE 最多记录的脚本个数，超过时淘汰最久未使用的脚本
+const SCRIPT_CACHE_SIZE = 1000
+
+// scriptCache 执行过或加载过的脚本，按LRU淘汰，动态生成的脚本不会一直占用内存
+// EVALSHA返回NOSCRIPT时(redis重启、主从切换或集群新节点)用于使用EVAL重试
+type scriptCache struct {
+	lock  sync.Mutex
+	items map[string]*list.Element // key为脚本的sha1
+	ll    *list.List               // LRU链表，最近使用的在前面，值为*scriptItem
+	size  int                      // 最多记录的脚本个数
+}
+
+type scriptItem struct {
+	sha    string
+	script string
+}
+
+var scripts = &scriptCache{items: make(map[string]*list.Element), ll: list.New(), size: SCRIPT_CACHE_SIZE}
+
+// add 记录脚本，超过个数时淘汰最久未使用的脚本
+func (c *scriptCache) add(sha, script string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.items[sha]; ok {
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[sha] = c.ll.PushFront(&scriptItem{sha: sha, script: script})
+	for c.ll.Len() > c.size {
+		elem := c.ll.Back()
+		c.ll.Remove(elem)
+		delete(c.items, elem.Value.(*scriptItem).sha)
+	}
+}
+
+// get 按sha1查询脚本
+func (c *scriptCache) get(sha string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, ok := c.items[sha]
+	if !ok {
+		return "", false
+	}
+
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*scriptItem).script, true
+}
+
+// ScriptSha1 返回脚本的sha1，同时记录脚本内容，之后EvalShaScript可以在脚本未加载时重试
+// 最多记录SCRIPT_CACHE_SIZE个脚本，被淘汰的脚本EvalShaScript不再重试
+//   参数
+//     script: lua脚本
+//   返回
+//     脚本的sha1，小写十六进制
+func ScriptSha1(script string) string {
+	sha := DataSha1(script)
+	scripts.add(sha, script)
+
+	return sha
+}
+
+// EvalScript 执行lua脚本，先使用EVALSHA，脚本未加载时使用EVAL，EVAL同时会在节点上缓存脚本
+//   参数
+//     c:      redis客户端
+//     script: lua脚本
+//     keys:   脚本的KEYS，需要已经添加了key前缀
+//     args:   脚本的ARGV
+//   返回
+//     脚本的返回值，脚本返回nil时返回nil，失败返回错误信息
+func EvalScript(c Scripter, script string, keys []string, args ...interface{}) (interface{}, error) {
+	sha := ScriptSha1(script)
+	v, err := c.EvalSha(sha, keys, args...).Result()
+	if IsNoScript(err) {
+		v, err = c.Eval(script, keys, args...).Result()
+	}
+
+	return scriptResult(v, err)
+}
+
+// EvalShaScript 按sha1执行lua脚本，脚本未加载且脚本内容已知(执行过EvalScript或ScriptSha1)时使用EVAL重试
+//   参数
+//     c:    redis客户端
+//     sha1: 脚本的sha1
+//     keys: 脚本的KEYS，需要已经添加了key前缀
+//     args: 脚本的ARGV
+//   返回
+//     脚本的返回值，脚本返回nil时返回nil，失败返回错误信息
+func EvalShaScript(c Scripter, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	v, err := c.EvalSha(sha1, keys, args...).Result()
+	if IsNoScript(err) {
+		if script, ok := scripts.get(strings.ToLower(sha1)); ok {
+			v, err = c.Eval(script, keys, args...).Result()
+		}
+	}
+
+	return scriptResult(v, err)
+}
+
+// IsNoScript 判断是否为脚本未加载的错误
+func IsNoScript(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT")
+}
+
+// scriptResult 脚本返回nil时go-redis返回redis.Nil错误，转换为nil结果
+func scriptResult(v interface{}, err error) (interface{}, error) {
+	if IsMiss(err) {
+		return nil, nil
+	}
+
+	return v, RedisError(err)
+}
@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"testing"
+)
+
+// TestScriptCache 脚本按LRU淘汰
+func TestScriptCache(t *testing.T) {
+	old := scripts
+	defer func() { scripts = old }()
+	scripts = &scriptCache{items: make(map[string]*list.Element), ll: list.New(), size: 2}
+
+	sha1 := ScriptSha1("return 1")
+	sha2 := ScriptSha1("return 2")
+	if script, ok := scripts.get(sha1); !ok || script != "return 1" {
+		t.Errorf("ScriptSha1 failed. Got %s-%v, expected return 1.", script, ok)
+	}
+
+	// sha1刚被使用，淘汰sha2
+	sha3 := ScriptSha1("return 3")
+	for sha, exist := range map[string]bool{sha1: true, sha2: false, sha3: true} {
+		if _, ok := scripts.get(sha); ok != exist {
+			t.Errorf("ScriptSha1 failed. %s exist: %v, expected %v.", sha, ok, exist)
+		}
+	}
+
+	// 动态生成的脚本不会超过上限
+	for i := 0; i < 10; i++ {
+		ScriptSha1(fmt.Sprintf("return %d", i))
+	}
+	if scripts.ll.Len() != 2 || len(scripts.items) != 2 {
+		t.Errorf("ScriptSha1 failed. Got %d scripts, expected 2.", len(scripts.items))
+	}
+}
@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lixy529/gotools/utils"
+	"github.com/vmihailenco/msgpack/v4"
+	"sync"
+)
+
+const (
+	SERIALIZE_FLAG = "SER1_"
+	SERIALIZE_LEN  = 6 // SERIALIZE_FLAG加1字节的序列化类型
+)
+
+// 序列化名称
+const (
+	SerializerJson    = "json"
+	SerializerGob     = "gob"
+	SerializerMsgpack = "msgpack"
+	SerializerRaw     = "raw"
+)
+
+// Serializer 缓存值的序列化接口
+// 除json外，序列化后的数据前会加上SERIALIZE_FLAG和Id()，读取时按头信息选择序列化，与读取方的配置无关
+type Serializer interface {
+	Name() string // 名称，配置里serializer的值
+	Id() byte     // 唯一标识，写入数据头，不能重复
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// SerializerGetter 可以返回序列化配置的缓存，TagCache等包装类用它保持与适配器一致的序列化
+type SerializerGetter interface {
+	Serializer() Serializer
+}
+
+var (
+	serializerLock sync.RWMutex
+	serializers    = make(map[string]Serializer) // 名称对应的序列化
+	serializerIds  = make(map[byte]Serializer)   // Id对应的序列化
+)
+
+func init() {
+	RegisterSerializer(JsonSerializer{})
+	RegisterSerializer(GobSerializer{})
+	RegisterSerializer(MsgpackSerializer{})
+	RegisterSerializer(RawSerializer{})
+}
+
+// RegisterSerializer 注册一个序列化，名称或Id重复时会panic
+//   参数
+//     s: 序列化对象
+//   返回
+//
+func RegisterSerializer(s Serializer) {
+	serializerLock.Lock()
+	defer serializerLock.Unlock()
+
+	if s == nil {
+		panic("Cache: RegisterSerializer serializer is nil")
+	}
+	if _, dup := serializers[s.Name()]; dup {
+		panic("Cache: RegisterSerializer called twice for serializer " + s.Name())
+	}
+	if _, dup := serializerIds[s.Id()]; dup {
+		panic(fmt.Sprintf("Cache: RegisterSerializer called twice for serializer id %q", s.Id()))
+	}
+	serializers[s.Name()] = s
+	serializerIds[s.Id()] = s
+}
+
+// GetSerializer 按名称获取序列化，名称为空时返回json
+//   参数
+//     name: 序列化名称，如json、gob、msgpack、raw
+//   返回
+//     成功返回序列化对象，失败返回错误信息
+func GetSerializer(name string) (Serializer, error) {
+	if name == "" {
+		name = SerializerJson
+	}
+
+	serializerLock.RLock()
+	defer serializerLock.RUnlock()
+	s, ok := serializers[name]
+	if !ok {
+		return nil, fmt.Errorf("Cache: unknown serializer %q", name)
+	}
+
+	return s, nil
+}
+
+// serializerById 按Id获取序列化
+func serializerById(id byte) (Serializer, bool) {
+	serializerLock.RLock()
+	defer serializerLock.RUnlock()
+	s, ok := serializerIds[id]
+	return s, ok
+}
+
+// JsonSerializer json序列化，默认的序列化，数据不加头信息，兼容旧数据
+// 参数实现了IJson接口时使用参数的函数
+type JsonSerializer struct{}
+
+func (JsonSerializer) Name() string {
+	return SerializerJson
+}
+
+func (JsonSerializer) Id() byte {
+	return 'j'
+}
+
+func (JsonSerializer) Marshal(v interface{}) ([]byte, error) {
+	if inter, ok := v.(IJson); ok {
+		return inter.MarshalJSON()
+	}
+	return json.Marshal(v)
+}
+
+func (JsonSerializer) Unmarshal(data []byte, v interface{}) error {
+	if inter, ok := v.(IJson); ok {
+		return inter.UnmarshalJSON(data)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// GobSerializer gob序列化
+type GobSerializer struct{}
+
+func (GobSerializer) Name() string {
+	return SerializerGob
+}
+
+func (GobSerializer) Id() byte {
+	return 'g'
+}
+
+func (GobSerializer) Marshal(v interface{}) ([]byte, error) {
+	return utils.GobEncode(v)
+}
+
+func (GobSerializer) Unmarshal(data []byte, v interface{}) error {
+	return utils.GobDecode(data, v)
+}
+
+// MsgpackSerializer msgpack序列化
+type MsgpackSerializer struct{}
+
+func (MsgpackSerializer) Name() string {
+	return SerializerMsgpack
+}
+
+func (MsgpackSerializer) Id() byte {
+	return 'm'
+}
+
+func (MsgpackSerializer) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgpackSerializer) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+// RawSerializer 原始字节，只支持[]byte
+type RawSerializer struct{}
+
+func (RawSerializer) Name() string {
+	return SerializerRaw
+}
+
+func (RawSerializer) Id() byte {
+	return 'r'
+}
+
+func (RawSerializer) Marshal(v interface{}) ([]byte, error) {
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case *[]byte:
+		return *b, nil
+	}
+	return nil, fmt.Errorf("Cache: raw serializer does not support %T", v)
+}
+
+func (RawSerializer) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("Cache: raw serializer does not support %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// isPlain 判断是否为不需要序列化的数据，字符串和数字直接保存，保证Incr等操作可用
+func isPlain(src interface{}) bool {
+	switch src.(type) {
+	case string, *string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,280 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 标签模式
+const (
+	TagModeVersion = "version" // 标签版本号，读取时比较版本号，支持所有适配器
+	TagModeSet     = "set"     // 集合保存标签下的key，失效时直接删除，需要适配器支持集合操作(redis、memory)
+)
+
+const (
+	TAG_FLAG       = "TAG1_" // 版本号模式下带标签的数据标识
+	TAG_KEY_PREFIX = "_tag:" // 标签的版本号或集合的key前缀
+)
+
+// tagItem 版本号模式下保存的数据，写入时记录标签的版本号
+type tagItem struct {
+	Tags  map[string]int64 `json:"t"` // 标签对应的版本号
+	Value []byte           `json:"v"` // 按适配器的序列化转换后的值
+}
+
+// TagCache 带标签的缓存，可以按标签批量失效
+// 版本号模式下读取时标签的版本号有变化则按未命中处理，只有Get和Lookup会检查标签，其它读操作返回原始数据
+// 集合模式下失效时删除标签集合里的所有key
+// 序列化使用适配器的配置(适配器实现了SerializerGetter)，加密由适配器处理
+// 其它操作直接使用原适配器
+type TagCache struct {
+	Cache
+	mode string
+}
+
+// NewTagCache 新建一个TagCache对象
+//   参数
+//     adapter: Cache对象，可以是任意适配器
+//     mode:    标签模式，TagModeVersion或TagModeSet，为空时使用TagModeVersion
+//   返回
+//     TagCache对象，模式不支持时返回错误信息
+func NewTagCache(adapter Cache, mode string) (*TagCache, error) {
+	if mode == "" {
+		mode = TagModeVersion
+	}
+	if mode != TagModeVersion && mode != TagModeSet {
+		return nil, fmt.Errorf("Cache: Tag mode don't support %s", mode)
+	}
+
+	return &TagCache{Cache: adapter, mode: mode}, nil
+}
+
+// tagKey 返回标签的版本号或集合的key
+func tagKey(tag string) string {
+	return TAG_KEY_PREFIX + tag
+}
+
+// SetWithTags 向缓存设置一个值，并记录它所属的标签
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，单位秒
+//     tags:   标签，如user:42
+//     encode: 是否加密标识，同Set
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *TagCache) SetWithTags(key string, val interface{}, expire int32, tags []string, encode ...bool) error {
+	if len(tags) == 0 {
+		return c.Cache.Set(key, val, expire, encode...)
+	}
+
+	if c.mode == TagModeSet {
+		// 先加入标签集合再写入，保证写入的key都能失效
+		for _, tag := range tags {
+			if err := c.addMember(tagKey(tag), key, expire); err != nil {
+				return err
+			}
+		}
+		return c.Cache.Set(key, val, expire, encode...)
+	}
+
+	versions, err := c.tagVersions(tags, true)
+	if err != nil {
+		return err
+	}
+	data, err := InterToByte(val, c.serializer())
+	if err != nil {
+		return err
+	}
+	item, err := json.Marshal(tagItem{Tags: versions, Value: data})
+	if err != nil {
+		return err
+	}
+
+	return c.Cache.Set(key, TAG_FLAG+string(item), expire, encode...)
+}
+
+// serializer 返回适配器的序列化，适配器没有实现SerializerGetter时返回nil，使用json
+func (c *TagCache) serializer() Serializer {
+	if s, ok := c.Cache.(SerializerGetter); ok {
+		return s.Serializer()
+	}
+	return nil
+}
+
+// addMember 将key加入标签集合，集合的过期时间不早于key的过期时间
+//   参数
+//     setKey: 标签集合的key
+//     key:    key值
+//     expire: key的过期时间，单位秒
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *TagCache) addMember(setKey, key string, expire int32) error {
+	ttl, err := c.Cache.TTL(setKey)
+	if err != nil {
+		return err
+	}
+	if _, err = c.Cache.SAdd(setKey, 0, key); err != nil {
+		return err
+	}
+
+	if expire <= 0 {
+		if ttl >= 0 {
+			_, err = c.Cache.Persist(setKey)
+		}
+	} else if d := time.Duration(expire) * time.Second; ttl == TTL_NOT_EXIST || (ttl >= 0 && ttl < d) {
+		_, err = c.Cache.Expire(setKey, d)
+	}
+
+	return err
+}
+
+// Get 从缓存取一个值，版本号模式下标签已失效时按不存在处理
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     错误信息，是否存在
+func (c *TagCache) Get(key string, val interface{}) (error, bool) {
+	if c.mode == TagModeSet {
+		return c.Cache.Get(key, val)
+	}
+
+	var raw string
+	err, ok := c.Cache.Get(key, &raw)
+	if err != nil || !ok {
+		return err, ok
+	}
+	if !strings.HasPrefix(raw, TAG_FLAG) {
+		return ByteToInter([]byte(raw), val), true
+	}
+
+	item := tagItem{}
+	if err = json.Unmarshal([]byte(raw[len(TAG_FLAG):]), &item); err != nil {
+		return WrapError(ErrDecode, err), true
+	}
+
+	tags := make([]string, 0, len(item.Tags))
+	for tag := range item.Tags {
+		tags = append(tags, tag)
+	}
+	versions, err := c.tagVersions(tags, false)
+	if err != nil {
+		return err, false
+	}
+	for tag, ver := range item.Tags {
+		if versions[tag] != ver {
+			c.Cache.Del(key)
+			return nil, false
+		}
+	}
+
+	return ByteToInter(item.Value, val), true
+}
+
+// Lookup 从缓存取一个值，同Get，返回值顺序为(是否存在, 错误信息)
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     是否存在，错误信息，key不存在或标签已失效时返回false和nil
+func (c *TagCache) Lookup(key string, val interface{}) (bool, error) {
+	err, ok := c.Get(key, val)
+	return ok, err
+}
+
+// InvalidateTags 失效标签下的所有key
+// 版本号模式下递增标签的版本号，旧数据在读取时按不存在处理
+// 集合模式下先从集合删除成员再删除key，期间重新写入的key仍在集合里，最多多删除一次，不会漏删
+//   参数
+//     tags: 标签
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *TagCache) InvalidateTags(tags ...string) error {
+	for _, tag := range tags {
+		key := tagKey(tag)
+		if c.mode == TagModeVersion {
+			// 版本号不存在时数据已失效
+			if _, err := c.Cache.Incr(key); err != nil && !IsMiss(err) {
+				return err
+			}
+			continue
+		}
+
+		members, err := c.Cache.SMembers(key)
+		if err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			continue
+		}
+
+		// 先删除成员，期间SetWithTags重新加入的成员保留在集合里，下次失效时仍会删除
+		list := make([]interface{}, len(members))
+		for i, m := range members {
+			list[i] = m
+		}
+		if _, err = c.Cache.SRem(key, list...); err != nil {
+			return err
+		}
+		if err = c.Cache.MDel(members...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tagVersions 获取标签的版本号
+// 版本号不存在时，create为true则用当前纳秒时间初始化，保证被淘汰后重建的版本号与旧数据不同，否则不返回
+//   参数
+//     tags:   标签
+//     create: 版本号不存在时是否新建
+//   返回
+//     标签对应的版本号，失败返回错误信息
+func (c *TagCache) tagVersions(tags []string, create bool) (map[string]int64, error) {
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = tagKey(tag)
+	}
+	mv, err := c.Cache.MGet(keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]int64, len(tags))
+	for _, tag := range tags {
+		var s string
+		switch v := mv[tagKey(tag)].(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		}
+		if ver, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+			versions[tag] = ver
+			continue
+		}
+		if !create {
+			continue
+		}
+
+		ver := time.Now().UnixNano()
+		ok, err := c.Cache.Add(tagKey(tag), ver, 0)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// 其它进程已新建
+			if err, _ = c.Cache.Get(tagKey(tag), &ver); err != nil {
+				return nil, err
+			}
+		}
+		versions[tag] = ver
+	}
+
+	return versions, nil
+}
@@ -0,0 +1,241 @@
+package cache_test
+
+import (
+	"encoding/json"
+	"github.com/lixy529/gotools/cache"
+	"github.com/lixy529/gotools/cache/memcache"
+	"github.com/lixy529/gotools/cache/memory"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTagCache 标签失效测试
+func TestTagCache(t *testing.T) {
+	adapter, err := cache.NewCache(cache.AdapterMemory, `{"interval":"60"}`)
+	if err != nil {
+		t.Errorf("NewCache failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.(*memory.MemoryCache).Close()
+
+	if _, err = cache.NewTagCache(adapter, "list"); err == nil {
+		t.Error("NewTagCache failed. Mode list expected error.")
+		return
+	}
+
+	type user struct {
+		Uid  int32
+		Name string
+	}
+	for _, mode := range []string{cache.TagModeVersion, cache.TagModeSet} {
+		tc, err := cache.NewTagCache(adapter, mode)
+		if err != nil {
+			t.Errorf("NewTagCache failed. err: %s.", err.Error())
+			return
+		}
+
+		if err = tc.SetWithTags("user_42", user{42, "Diego"}, 60, []string{"user:42"}); err != nil {
+			t.Errorf("SetWithTags failed. mode: %s, err: %s.", mode, err.Error())
+			return
+		}
+		if err = tc.SetWithTags("posts_42", []int{1, 2, 3}, 0, []string{"user:42", "posts"}); err != nil {
+			t.Errorf("SetWithTags failed. mode: %s, err: %s.", mode, err.Error())
+			return
+		}
+		if err = tc.SetWithTags("user_43", user{43, "Lily"}, 60, []string{"user:43"}); err != nil {
+			t.Errorf("SetWithTags failed. mode: %s, err: %s.", mode, err.Error())
+			return
+		}
+
+		u := user{}
+		if err, exist := tc.Get("user_42", &u); err != nil || !exist || u.Name != "Diego" {
+			t.Errorf("Get failed. mode: %s, Got %v-%v, err: %v.", mode, u, exist, err)
+			return
+		}
+
+		if err = tc.InvalidateTags("user:42"); err != nil {
+			t.Errorf("InvalidateTags failed. mode: %s, err: %s.", mode, err.Error())
+			return
+		}
+		for _, key := range []string{"user_42", "posts_42"} {
+			if exist, err := tc.Lookup(key, &u); err != nil || exist {
+				t.Errorf("InvalidateTags failed. mode: %s, %s exist: %v, err: %v.", mode, key, exist, err)
+				return
+			}
+		}
+		if exist, err := tc.Lookup("user_43", &u); err != nil || !exist || u.Uid != 43 {
+			t.Errorf("Lookup failed. mode: %s, Got %v-%v, err: %v.", mode, u, exist, err)
+			return
+		}
+
+		// 失效后重新写入可以读取
+		if err = tc.SetWithTags("user_42", user{42, "Diego2"}, 60, []string{"user:42"}); err != nil {
+			t.Errorf("SetWithTags failed. mode: %s, err: %s.", mode, err.Error())
+			return
+		}
+		if err, exist := tc.Get("user_42", &u); err != nil || !exist || u.Name != "Diego2" {
+			t.Errorf("Get failed. mode: %s, Got %v-%v, err: %v.", mode, u, exist, err)
+			return
+		}
+
+		// 没有标签的数据
+		if err = tc.SetWithTags("plain", "hello", 60, nil); err != nil {
+			t.Errorf("SetWithTags failed. mode: %s, err: %s.", mode, err.Error())
+			return
+		}
+		s := ""
+		if err, exist := tc.Get("plain", &s); err != nil || !exist || s != "hello" {
+			t.Errorf("Get failed. mode: %s, Got %s-%v, err: %v.", mode, s, exist, err)
+			return
+		}
+
+		if err = tc.ClearAll(); err != nil {
+			t.Errorf("ClearAll failed. err: %s.", err.Error())
+			return
+		}
+	}
+
+	// 版本号被淘汰后旧数据按失效处理
+	tc, _ := cache.NewTagCache(adapter, cache.TagModeVersion)
+	tc.SetWithTags("user_42", user{42, "Diego"}, 60, []string{"user:42"})
+	adapter.Del(cache.TAG_KEY_PREFIX + "user:42")
+	u := user{}
+	if err, exist := tc.Get("user_42", &u); err != nil || exist {
+		t.Errorf("Get failed. Tag version evicted, Got %v-%v, err: %v.", u, exist, err)
+		return
+	}
+
+	// 集合的过期时间不早于key
+	tc, _ = cache.NewTagCache(adapter, cache.TagModeSet)
+	tc.SetWithTags("k1", 1, 100, []string{"t"})
+	tc.SetWithTags("k2", 2, 10, []string{"t"})
+	if ttl, err := adapter.TTL(cache.TAG_KEY_PREFIX + "t"); err != nil || ttl < 90*time.Second {
+		t.Errorf("SetWithTags failed. Tag set ttl %v, err: %v.", ttl, err)
+		return
+	}
+	tc.SetWithTags("k3", 3, 0, []string{"t"})
+	if ttl, err := adapter.TTL(cache.TAG_KEY_PREFIX + "t"); err != nil || ttl != cache.TTL_PERSIST {
+		t.Errorf("SetWithTags failed. Tag set ttl %v, err: %v.", ttl, err)
+		return
+	}
+}
+
+// encodeRecorder 记录Set收到的加密标识
+type encodeRecorder struct {
+	cache.Cache
+	encode []bool
+}
+
+func (r *encodeRecorder) Set(key string, val interface{}, expire int32, encode ...bool) error {
+	r.encode = append(r.encode, len(encode) > 0 && encode[0])
+	return r.Cache.Set(key, val, expire, encode...)
+}
+
+// TestTagCacheSerializer 使用适配器的序列化和加密标识
+func TestTagCacheSerializer(t *testing.T) {
+	adapter, err := cache.NewCache(cache.AdapterMemory, `{"interval":"60","serializer":"msgpack","encodeKey":"abcdefghij123456"}`)
+	if err != nil {
+		t.Errorf("NewCache failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.(*memory.MemoryCache).Close()
+
+	type user struct {
+		Uid  int32
+		Name string
+	}
+	tc, _ := cache.NewTagCache(adapter, cache.TagModeVersion)
+	if err = tc.SetWithTags("user_42", user{42, "Diego"}, 60, []string{"user:42"}, true); err != nil {
+		t.Errorf("SetWithTags failed. err: %s.", err.Error())
+		return
+	}
+
+	// 值按适配器配置的msgpack序列化
+	raw := ""
+	if err, exist := adapter.Get("user_42", &raw); err != nil || !exist || !strings.HasPrefix(raw, cache.TAG_FLAG) {
+		t.Errorf("Get failed. Got %s-%v, err: %v.", raw, exist, err)
+		return
+	}
+	item := struct {
+		Value []byte `json:"v"`
+	}{}
+	if err = json.Unmarshal([]byte(raw[len(cache.TAG_FLAG):]), &item); err != nil {
+		t.Errorf("Unmarshal failed. err: %s.", err.Error())
+		return
+	}
+	s, _ := cache.GetSerializer(cache.SerializerMsgpack)
+	if len(item.Value) < cache.SERIALIZE_LEN || string(item.Value[:cache.SERIALIZE_LEN]) != cache.SERIALIZE_FLAG+string(s.Id()) {
+		t.Errorf("SetWithTags failed. Value is not serialized by msgpack: %q.", item.Value)
+		return
+	}
+	u := user{}
+	if err, exist := tc.Get("user_42", &u); err != nil || !exist || u.Uid != 42 || u.Name != "Diego" {
+		t.Errorf("Get failed. Got %v-%v, err: %v.", u, exist, err)
+		return
+	}
+
+	// 加密标识传给适配器
+	for _, mode := range []string{cache.TagModeVersion, cache.TagModeSet} {
+		rec := &encodeRecorder{Cache: adapter}
+		tc, _ = cache.NewTagCache(rec, mode)
+		tc.SetWithTags("k1", "v1", 60, []string{"t_" + mode}, true)
+		tc.SetWithTags("k2", "v2", 60, nil, true)
+		tc.SetWithTags("k3", "v3", 60, []string{"t_" + mode})
+		if len(rec.encode) != 3 || !rec.encode[0] || !rec.encode[1] || rec.encode[2] {
+			t.Errorf("SetWithTags failed. mode: %s, encode %v, expected [true true false].", mode, rec.encode)
+			return
+		}
+		v := ""
+		if err, exist := tc.Get("k1", &v); err != nil || !exist || v != "v1" {
+			t.Errorf("Get failed. mode: %s, Got %s-%v, err: %v.", mode, v, exist, err)
+			return
+		}
+	}
+}
+
+// TestTagCacheMemc memcache不支持集合，使用版本号模式
+func TestTagCacheMemc(t *testing.T) {
+	adapter := &memcache.MemcCache{}
+	err := adapter.Init(`{"addr":"127.0.0.1:11211","prefix":"le_"}`)
+	if err != nil {
+		t.Errorf("Memc Init failed. err: %s.", err.Error())
+		return
+	}
+
+	tc, _ := cache.NewTagCache(adapter, cache.TagModeVersion)
+	if err = tc.SetWithTags("tag_k1", "v1", 60, []string{"user:42"}); err != nil {
+		t.Errorf("Memc SetWithTags failed. err: %s.", err.Error())
+		return
+	}
+	if err = tc.SetWithTags("tag_k2", 100, 60, []string{"user:42", "user:43"}); err != nil {
+		t.Errorf("Memc SetWithTags failed. err: %s.", err.Error())
+		return
+	}
+	n := 0
+	if err, exist := tc.Get("tag_k2", &n); err != nil || !exist || n != 100 {
+		t.Errorf("Memc Get failed. Got %d-%v, err: %v.", n, exist, err)
+		return
+	}
+
+	if err = tc.InvalidateTags("user:43"); err != nil {
+		t.Errorf("Memc InvalidateTags failed. err: %s.", err.Error())
+		return
+	}
+	if err, exist := tc.Get("tag_k2", &n); err != nil || exist {
+		t.Errorf("Memc InvalidateTags failed. exist: %v, err: %v.", exist, err)
+		return
+	}
+	v := ""
+	if err, exist := tc.Get("tag_k1", &v); err != nil || !exist || v != "v1" {
+		t.Errorf("Memc Get failed. Got %s-%v, err: %v.", v, exist, err)
+		return
+	}
+
+	// 版本号不存在时失效不报错
+	if err = tc.InvalidateTags("tag_not_exist"); err != nil {
+		t.Errorf("Memc InvalidateTags failed. err: %s.", err.Error())
+		return
+	}
+	adapter.MDel("tag_k1", "tag_k2", cache.TAG_KEY_PREFIX+"user:42", cache.TAG_KEY_PREFIX+"user:43")
+}
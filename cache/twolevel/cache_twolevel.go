@@ -0,0 +1,1071 @@
+// Two-level cache: local memory L1 in front of a remote L2
+package twolevel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/lixy529/gotools/cache"
+	"github.com/lixy529/gotools/cache/memory"
+	"github.com/lixy529/gotools/utils"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// invalidMsg 失效广播消息
+type invalidMsg struct {
+	Id   string   `json:"id"`   // 发送方实例ID，用于忽略自己发出的消息
+	Keys []string `json:"keys"` // 失效的key
+	All  bool     `json:"all"`  // 是否清空全部本地缓存
+}
+
+// TwoLevelCache 二级缓存
+// 读操作(Get、HGet、MGet)先查本地L1，未命中再查远程L2并回填L1
+// 写操作先写L2，再更新或删除L1，配置了channel时通过redis发布订阅通知其它实例删除L1
+type TwoLevelCache struct {
+	l1 *memory.MemoryCache // 本地缓存
+	l2 cache.Cache         // 远程缓存
+
+	localExpire int32 // 本地缓存过期时间，单位秒，默认60秒
+
+	channel string        // 失效通知的频道，为空时不通知
+	id      string        // 实例ID
+	client  *redis.Client // 发布订阅使用的redis连接
+	pubsub  *redis.PubSub // 订阅对象
+	wg      sync.WaitGroup
+
+	hooks []cache.Hook // 本地缓存的命令钩子，Init重建本地缓存时重新添加
+}
+
+// NewTwoLevelCache 新建一个TwoLevelCache适配器
+//   参数
+//     l2: 已初始化的远程缓存，如RediscCache
+//   返回
+//     TwoLevelCache对象，使用前需要调用Init
+func NewTwoLevelCache(l2 cache.Cache) *TwoLevelCache {
+	return &TwoLevelCache{l2: l2}
+}
+
+// Init 初始化
+//   参数
+//     config: 配置josn串
+//       {
+//         "maxEntries":"10000",
+//         "maxMemory":"67108864",
+//         "interval":"60",
+//         "localExpire":"60",
+//         "channel":"cache_invalid",
+//         "notifyAddr":"127.0.0.1:6379",
+//         "notifyAuth":"xxxxx",
+//         "notifyDbNum":"0",
+//       }
+//       maxEntries:  本地缓存最大缓存项数，超过时按LRU淘汰，默认为0不限制
+//       maxMemory:   本地缓存最大占用内存，单位字节，超过时按LRU淘汰，默认为0不限制
+//       interval:    本地缓存后台清理过期数据的间隔，单位秒，默认60秒
+//       localExpire: 本地缓存过期时间，单位秒，默认60秒，与远程缓存的过期时间取较小值
+//       channel:     失效通知的redis频道，为空时不通知其它实例
+//       notifyAddr:  发布订阅使用的redis主机和端口，channel不为空时必填
+//       notifyAuth:  发布订阅使用的redis授权密码
+//       notifyDbNum: 发布订阅使用的redis db编号，默认为0
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *TwoLevelCache) Init(config string) error {
+	if c.l2 == nil {
+		return errors.New("TwoLevelCache: L2 cache is nil")
+	}
+
+	mapCfg := make(map[string]string)
+	if config != "" {
+		err := json.Unmarshal([]byte(config), &mapCfg)
+		if err != nil {
+			return fmt.Errorf("TwoLevelCache: Unmarshal json[%s] error, %s", config, err.Error())
+		}
+	}
+
+	// 本地缓存
+	l1Cfg, _ := json.Marshal(map[string]string{
+		"maxEntries": mapCfg["maxEntries"],
+		"maxMemory":  mapCfg["maxMemory"],
+		"interval":   mapCfg["interval"],
+	})
+	c.Close()
+	c.l1 = &memory.MemoryCache{}
+	err := c.l1.Init(string(l1Cfg))
+	if err != nil {
+		return err
+	}
+	for _, hook := range c.hooks {
+		c.l1.AddHook(hook)
+	}
+
+	// 本地缓存过期时间
+	localExpire, err := strconv.Atoi(mapCfg["localExpire"])
+	if err != nil || localExpire <= 0 {
+		c.localExpire = 60
+	} else {
+		c.localExpire = int32(localExpire)
+	}
+
+	// 失效通知
+	c.channel = mapCfg["channel"]
+	if c.channel == "" {
+		return nil
+	}
+
+	addr := mapCfg["notifyAddr"]
+	if addr == "" {
+		return errors.New("TwoLevelCache: Notify addr is empty")
+	}
+	dbNum, err := strconv.Atoi(mapCfg["notifyDbNum"])
+	if err != nil {
+		dbNum = 0
+	}
+
+	c.id = utils.Guid()
+	c.client = redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: mapCfg["notifyAuth"],
+		DB:       dbNum,
+	})
+	c.pubsub = c.client.Subscribe(c.channel)
+	_, err = c.pubsub.Receive()
+	if err != nil {
+		c.Close()
+		return fmt.Errorf("TwoLevelCache: Subscribe %s error, %s", c.channel, err.Error())
+	}
+
+	c.wg.Add(1)
+	go c.subscribe(c.pubsub.Channel())
+
+	return nil
+}
+
+// Close 停止订阅，关闭本地缓存和发布订阅连接
+func (c *TwoLevelCache) Close() {
+	if c.pubsub != nil {
+		c.pubsub.Close()
+		c.wg.Wait()
+		c.pubsub = nil
+	}
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+	if c.l1 != nil {
+		c.l1.Close()
+	}
+}
+
+// subscribe 处理其它实例发来的失效通知
+//   参数
+//     ch: 订阅消息
+//   返回
+//
+func (c *TwoLevelCache) subscribe(ch <-chan *redis.Message) {
+	defer c.wg.Done()
+
+	for m := range ch {
+		msg := invalidMsg{}
+		if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil || msg.Id == c.id {
+			continue
+		}
+
+		if msg.All {
+			c.l1.ClearAll()
+		} else {
+			c.l1.MDel(msg.Keys...)
+		}
+	}
+}
+
+// publish 通知其它实例删除本地缓存
+//   参数
+//     all:  是否清空全部本地缓存
+//     keys: 失效的key
+//   返回
+//
+func (c *TwoLevelCache) publish(all bool, keys ...string) {
+	if c.client == nil {
+		return
+	}
+
+	msg, err := json.Marshal(invalidMsg{Id: c.id, Keys: keys, All: all})
+	if err != nil {
+		return
+	}
+	c.client.Publish(c.channel, string(msg))
+}
+
+// invalidate 删除本地缓存并通知其它实例
+//   参数
+//     keys: 失效的key
+//   返回
+//
+func (c *TwoLevelCache) invalidate(keys ...string) {
+	c.l1.MDel(keys...)
+	c.publish(false, keys...)
+}
+
+// localTTL 返回本地缓存的过期时间
+//   参数
+//     expire: 远程缓存的过期时间
+//   返回
+//     本地缓存的过期时间
+func (c *TwoLevelCache) localTTL(expire int32) int32 {
+	if expire > 0 && expire < c.localExpire {
+		return expire
+	}
+	return c.localExpire
+}
+
+// fillTTL 返回回填本地缓存的过期时间，取localExpire和远程缓存剩余过期时间的较小值
+// 远程缓存不支持TTL、没有过期时间或key已不存在时使用localExpire
+//   参数
+//     key: key值
+//   返回
+//     本地缓存的过期时间
+func (c *TwoLevelCache) fillTTL(key string) int32 {
+	ttl, err := c.l2.TTL(key)
+	if err != nil || ttl <= 0 {
+		return c.localExpire
+	}
+
+	// 不足1秒按1秒，0表示不过期
+	return c.localTTL(int32((ttl + time.Second - 1) / time.Second))
+}
+
+// L1 返回本地缓存
+func (c *TwoLevelCache) L1() *memory.MemoryCache {
+	return c.l1
+}
+
+// L2 返回远程缓存
+func (c *TwoLevelCache) L2() cache.Cache {
+	return c.l2
+}
+
+// AddHook 添加命令钩子，本地缓存和远程缓存的命令都会调用，通过HookInfo.Adapter区分
+//   参数
+//     hook: 钩子
+//   返回
+//
+func (c *TwoLevelCache) AddHook(hook cache.Hook) {
+	c.hooks = append(c.hooks, hook)
+	if c.l1 != nil {
+		c.l1.AddHook(hook)
+	}
+	if c.l2 != nil {
+		c.l2.AddHook(hook)
+	}
+}
+
+// Serializer 返回远程缓存的序列化，远程缓存没有实现cache.SerializerGetter时返回nil(json)
+func (c *TwoLevelCache) Serializer() cache.Serializer {
+	if s, ok := c.l2.(cache.SerializerGetter); ok {
+		return s.Serializer()
+	}
+	return nil
+}
+
+// Set 向缓存设置一个值，先写远程缓存再更新本地缓存
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 到期是缓存过期时间，以秒为单位：从现在开始的相对时间。“0”表示项目没有到期时间。
+//     encode: 是否加密标识，只对远程缓存有效
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *TwoLevelCache) Set(key string, val interface{}, expire int32, encode ...bool) error {
+	err := c.l2.Set(key, val, expire, encode...)
+	if err != nil {
+		c.l1.Del(key)
+		return err
+	}
+
+	if c.l1.Set(key, val, c.localTTL(expire)) != nil {
+		c.l1.Del(key)
+	}
+	c.publish(false, key)
+
+	return nil
+}
+
+// Get 从缓存取一个值，本地缓存未命中时查远程缓存并回填本地缓存
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     错误信息，是否存在
+func (c *TwoLevelCache) Get(key string, val interface{}) (error, bool) {
+	err, exist := c.l1.Get(key, val)
+	if err == nil && exist {
+		return nil, true
+	}
+
+	var raw string
+	err, exist = c.l2.Get(key, &raw)
+	if err != nil || !exist {
+		return err, exist
+	}
+	c.l1.Set(key, raw, c.fillTTL(key))
+
+	err = cache.ByteToInter([]byte(raw), val)
+	if err != nil {
+		return err, true
+	}
+
+	return nil, true
+}
+
+// Lookup 从缓存取一个值，同Get，返回值顺序为(是否存在, 错误信息)，先查本地缓存
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     是否存在，错误信息，key不存在时返回false和nil
+func (c *TwoLevelCache) Lookup(key string, val interface{}) (bool, error) {
+	err, exist := c.Get(key, val)
+	return exist, err
+}
+
+// Del 从缓存删除一个值
+//   参数
+//     key: key值
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *TwoLevelCache) Del(key string) error {
+	err := c.l2.Del(key)
+	c.invalidate(key)
+	return err
+}
+
+// Add key不存在时设置一个值，写远程缓存，成功后删除本地缓存
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key已存在返回false，失败返回错误信息
+func (c *TwoLevelCache) Add(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	ok, err := c.l2.Add(key, val, expire, encode...)
+	if ok {
+		c.invalidate(key)
+	}
+	return ok, err
+}
+
+// Replace key存在时设置一个值，写远程缓存，成功后删除本地缓存
+//   参数
+//     key:    key值
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，key不存在返回false，失败返回错误信息
+func (c *TwoLevelCache) Replace(key string, val interface{}, expire int32, encode ...bool) (bool, error) {
+	ok, err := c.l2.Replace(key, val, expire, encode...)
+	if ok {
+		c.invalidate(key)
+	}
+	return ok, err
+}
+
+// GetWithVersion 从远程缓存取一个值，同时返回数据的版本，不使用本地缓存
+//   参数
+//     key: key值
+//     val: 保存结果地址
+//   返回
+//     数据的版本，key不存在时返回nil，失败返回错误信息
+func (c *TwoLevelCache) GetWithVersion(key string, val interface{}) (*cache.Version, error) {
+	return c.l2.GetWithVersion(key, val)
+}
+
+// CompareAndSwap 数据的版本一致时设置新值，写远程缓存，成功后删除本地缓存
+//   参数
+//     ver:    GetWithVersion返回的版本
+//     val:    value值
+//     expire: 过期时间，以秒为单位，“0”表示保留原来的过期时间，同远程缓存的CompareAndSwap
+//     encode: 是否加密标识
+//   返回
+//     设置成功返回true，数据已被修改或删除返回false，失败返回错误信息
+func (c *TwoLevelCache) CompareAndSwap(ver *cache.Version, val interface{}, expire int32, encode ...bool) (bool, error) {
+	ok, err := c.l2.CompareAndSwap(ver, val, expire, encode...)
+	if ok {
+		c.invalidate(ver.Key)
+	}
+	return ok, err
+}
+
+// MSet 同时设置一个或多个key-value对
+//   参数
+//     mList:  key-value对
+//     expire: 到期是缓存过期时间，以秒为单位：从现在开始的相对时间。“0”表示项目没有到期时间。
+//     encode: 是否加密标识，只对远程缓存有效
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *TwoLevelCache) MSet(mList map[string]interface{}, expire int32, encode ...bool) error {
+	keys := make([]string, 0, len(mList))
+	for key := range mList {
+		keys = append(keys, key)
+	}
+
+	err := c.l2.MSet(mList, expire, encode...)
+	if err != nil {
+		c.l1.MDel(keys...)
+		return err
+	}
+
+	if c.l1.MSet(mList, c.localTTL(expire)) != nil {
+		c.l1.MDel(keys...)
+	}
+	c.publish(false, keys...)
+
+	return nil
+}
+
+// MGet 同时获取一个或多个key的value，本地缓存未命中的key查远程缓存并回填本地缓存
+// struct、map类型返回的结果需要调用方做一下json.Unmarshal处理
+//   参数
+//     keys:  要查询的key值
+//   返回
+//     成功返回查询结果，失败返回错误信息，key不存在时对应的val为nil
+func (c *TwoLevelCache) MGet(keys ...string) (map[string]interface{}, error) {
+	mList, err := c.l1.MGet(keys...)
+	if err != nil {
+		mList = make(map[string]interface{})
+	}
+
+	miss := []string{}
+	for _, key := range keys {
+		if mList[key] == nil {
+			miss = append(miss, key)
+		}
+	}
+	if len(miss) == 0 {
+		return mList, nil
+	}
+
+	l2List, err := c.l2.MGet(miss...)
+	for _, key := range miss {
+		val := l2List[key]
+		mList[key] = val
+		if str, ok := val.(string); ok {
+			c.l1.Set(key, str, c.fillTTL(key))
+		}
+	}
+
+	return mList, err
+}
+
+// MDel 同时删除一个或多个key
+//   参数
+//     keys:  要删除的key值
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *TwoLevelCache) MDel(keys ...string) error {
+	err := c.l2.MDel(keys...)
+	c.invalidate(keys...)
+	return err
+}
+
+// Incr 自增
+//   参数
+//     key:   key值
+//     delta: 自增的量，默认为1
+//   返回
+//     成功返回自增后的结果，失败返回错误信息
+func (c *TwoLevelCache) Incr(key string, delta ...uint64) (int64, error) {
+	n, err := c.l2.Incr(key, delta...)
+	c.invalidate(key)
+	return n, err
+}
+
+// Decr 自减
+//   参数
+//     key:   key值
+//     delta: 自减的量，默认为1
+//   返回
+//     成功返回自减后的结果，失败返回错误信息
+func (c *TwoLevelCache) Decr(key string, delta ...uint64) (int64, error) {
+	n, err := c.l2.Decr(key, delta...)
+	c.invalidate(key)
+	return n, err
+}
+
+// IncrEx 自增并设置过期时间
+//   参数
+//     key:    key值
+//     delta:  自增的量，负数时为自减
+//     expire: 过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     成功返回自增后的结果，失败返回错误信息
+func (c *TwoLevelCache) IncrEx(key string, delta int64, expire int32) (int64, error) {
+	n, err := c.l2.IncrEx(key, delta, expire)
+	c.invalidate(key)
+	return n, err
+}
+
+// IsExist 判断key值是否存在，直接查远程缓存
+//   参数
+//     key: key值
+//   返回
+//     存在返回true，不存在返回false，失败返回错误信息
+func (c *TwoLevelCache) IsExist(key string) (bool, error) {
+	return c.l2.IsExist(key)
+}
+
+// ClearAll 清空所有数据，同时清空所有实例的本地缓存
+//   参数
+//
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *TwoLevelCache) ClearAll() error {
+	err := c.l2.ClearAll()
+	c.l1.ClearAll()
+	c.publish(true)
+	return err
+}
+
+// TTL 查询key的剩余过期时间，直接查远程缓存
+//   参数
+//     key: key值
+//   返回
+//     剩余过期时间，没有过期时间返回cache.TTL_PERSIST，key不存在返回cache.TTL_NOT_EXIST，失败返回错误信息
+func (c *TwoLevelCache) TTL(key string) (time.Duration, error) {
+	return c.l2.TTL(key)
+}
+
+// Expire 设置key的过期时间，写远程缓存，同时删除本地缓存
+//   参数
+//     key:    key值
+//     expire: 过期时间，小于等于0时删除key
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (c *TwoLevelCache) Expire(key string, expire time.Duration) (bool, error) {
+	ok, err := c.l2.Expire(key, expire)
+	c.invalidate(key)
+	return ok, err
+}
+
+// ExpireAt 设置key在指定时间过期，写远程缓存，同时删除本地缓存
+//   参数
+//     key: key值
+//     tm:  过期的时间点，早于当前时间时删除key
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (c *TwoLevelCache) ExpireAt(key string, tm time.Time) (bool, error) {
+	ok, err := c.l2.ExpireAt(key, tm)
+	c.invalidate(key)
+	return ok, err
+}
+
+// Persist 删除key的过期时间，写远程缓存，本地缓存仍按localExpire过期
+//   参数
+//     key: key值
+//   返回
+//     删除了过期时间返回true，失败返回错误信息
+func (c *TwoLevelCache) Persist(key string) (bool, error) {
+	return c.l2.Persist(key)
+}
+
+// Touch 更新key的过期时间，写远程缓存，同时删除本地缓存
+//   参数
+//     key:    key值
+//     expire: 过期时间，以秒为单位，“0”表示没有到期时间
+//   返回
+//     key存在返回true，不存在返回false，失败返回错误信息
+func (c *TwoLevelCache) Touch(key string, expire int32) (bool, error) {
+	ok, err := c.l2.Touch(key, expire)
+	c.invalidate(key)
+	return ok, err
+}
+
+// GetEx 从远程缓存取一个值，同时更新过期时间，不使用本地缓存
+//   参数
+//     key:    key值
+//     val:    保存结果地址
+//     expire: 新的过期时间，以秒为单位，小于等于0时不修改过期时间
+//   返回
+//     错误信息，是否存在
+func (c *TwoLevelCache) GetEx(key string, val interface{}, expire int32) (error, bool) {
+	return c.l2.GetEx(key, val, expire)
+}
+
+// HSet 添加哈希表
+//   参数
+//     key:    哈希表key值
+//     field:  哈希表field值
+//     val:    哈希表value值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//   返回
+//     成功时返回添加的个数，失败返回错误信息
+func (c *TwoLevelCache) HSet(key string, field string, val interface{}, expire int32) (int64, error) {
+	n, err := c.l2.HSet(key, field, val, expire)
+	c.invalidate(key)
+	return n, err
+}
+
+// HGet 查询哈希表数据，本地缓存未命中时查远程缓存并回填本地缓存
+//   参数
+//     key:   哈希表key值
+//     field: 哈希表field值
+//     val:   保存结果地址
+//   返回
+//     错误信息，是否存在
+func (c *TwoLevelCache) HGet(key string, field string, val interface{}) (error, bool) {
+	err, exist := c.l1.HGet(key, field, val)
+	if err == nil && exist {
+		return nil, true
+	}
+
+	var raw string
+	err, exist = c.l2.HGet(key, field, &raw)
+	if err != nil || !exist {
+		return err, exist
+	}
+
+	// 哈希表第一次回填时设置过期时间，之后回填的field沿用该过期时间
+	expire := int32(0)
+	if ok, _ := c.l1.IsExist(key); !ok {
+		expire = c.fillTTL(key)
+	}
+	c.l1.HSet(key, field, raw, expire)
+
+	err = cache.ByteToInter([]byte(raw), val)
+	if err != nil {
+		return err, true
+	}
+
+	return nil, true
+}
+
+// HDel 删除哈希表数据
+//   参数
+//     key:    哈希表key值
+//     fields: 哈希表field值
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *TwoLevelCache) HDel(key string, fields ...string) error {
+	err := c.l2.HDel(key, fields...)
+	c.invalidate(key)
+	return err
+}
+
+// HGetAll 查询哈希表所有数据，直接查远程缓存
+//   参数
+//     key: 哈希表key值
+//   返回
+//     成功时返回查询结果，失败返回错误信息
+func (c *TwoLevelCache) HGetAll(key string) (map[string]interface{}, error) {
+	return c.l2.HGetAll(key)
+}
+
+// HMSet 同时设置多个哈希表数据
+//   参数
+//     key:    哈希表key值
+//     fields: 哈希表field-value对
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *TwoLevelCache) HMSet(key string, fields map[string]interface{}, expire int32) error {
+	err := c.l2.HMSet(key, fields, expire)
+	c.invalidate(key)
+	return err
+}
+
+// HMGet 同时查询多个哈希表数据，直接查远程缓存
+//   参数
+//     key:    哈希表key值
+//     fields: 哈希表field值
+//   返回
+//     成功时返回查询结果，失败返回错误信息
+func (c *TwoLevelCache) HMGet(key string, fields ...string) (map[string]interface{}, error) {
+	return c.l2.HMGet(key, fields...)
+}
+
+// HVals 查询哈希表所有的value，直接查远程缓存
+//   参数
+//     key: 哈希表key值
+//   返回
+//     成功时返回查询结果，失败返回错误信息
+func (c *TwoLevelCache) HVals(key string) ([]interface{}, error) {
+	return c.l2.HVals(key)
+}
+
+// HIncr 哈希表field自增
+//   参数
+//     key:    哈希表key值
+//     fields: 哈希表field值
+//     delta:  自增的量，默认为1
+//   返回
+//     成功返回自增后的结果，失败返回错误信息
+func (c *TwoLevelCache) HIncr(key, fields string, delta ...uint64) (int64, error) {
+	n, err := c.l2.HIncr(key, fields, delta...)
+	c.invalidate(key)
+	return n, err
+}
+
+// HDecr 哈希表field自减
+//   参数
+//     key:    哈希表key值
+//     fields: 哈希表field值
+//     delta:  自减的量，默认为1
+//   返回
+//     成功返回自减后的结果，失败返回错误信息
+func (c *TwoLevelCache) HDecr(key, fields string, delta ...uint64) (int64, error) {
+	n, err := c.l2.HDecr(key, fields, delta...)
+	c.invalidate(key)
+	return n, err
+}
+
+// ZSet 添加有序集合，直接写远程缓存
+//   参数
+//     key:    有序集合key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     val:    分数和成员，如: 1, "one", 2, "two"
+//   返回
+//     成功时返回添加的个数，失败返回错误信息
+func (c *TwoLevelCache) ZSet(key string, expire int32, val ...interface{}) (int64, error) {
+	return c.l2.ZSet(key, expire, val...)
+}
+
+// ZGet 查询有序集合，直接查远程缓存
+//   参数
+//     key:        有序集合key值
+//     start:      开始位置
+//     stop:       结束位置
+//     withScores: 是否返回分数
+//     isRev:      是否按分数从大到小排序
+//   返回
+//     成功时返回查询结果，失败返回错误信息
+func (c *TwoLevelCache) ZGet(key string, start, stop int, withScores bool, isRev bool) ([]string, error) {
+	return c.l2.ZGet(key, start, stop, withScores, isRev)
+}
+
+// ZDel 删除有序集合的成员，直接写远程缓存
+//   参数
+//     key:   有序集合key值
+//     field: 成员
+//   返回
+//     成功时返回删除的个数，失败返回错误信息
+func (c *TwoLevelCache) ZDel(key string, field ...string) (int64, error) {
+	return c.l2.ZDel(key, field...)
+}
+
+// ZCard 查询有序集合的成员数，直接查远程缓存
+//   参数
+//     key: 有序集合key值
+//   返回
+//     成功时返回成员数，失败返回错误信息
+func (c *TwoLevelCache) ZCard(key string) (int64, error) {
+	return c.l2.ZCard(key)
+}
+
+// ZRemRangeByRank 删除有序集合指定排名区间的成员，直接写远程缓存
+//   参数
+//     key:   有序集合key值
+//     start: 开始排名
+//     end:   结束排名
+//   返回
+//     成功时返回删除的个数，失败返回错误信息
+func (c *TwoLevelCache) ZRemRangeByRank(key string, start, end int64) (int64, error) {
+	return c.l2.ZRemRangeByRank(key, start, end)
+}
+
+// ZRemRangeByScore 删除有序集合指定分数区间的成员，直接写远程缓存
+//   参数
+//     key:   有序集合key值
+//     start: 开始分数
+//     end:   结束分数
+//   返回
+//     成功时返回删除的个数，失败返回错误信息
+func (c *TwoLevelCache) ZRemRangeByScore(key string, start, end string) (int64, error) {
+	return c.l2.ZRemRangeByScore(key, start, end)
+}
+
+// ZRemRangeByLex 删除有序集合指定字典区间的成员，直接写远程缓存
+//   参数
+//     key:   有序集合key值
+//     start: 开始成员
+//     end:   结束成员
+//   返回
+//     成功时返回删除的个数，失败返回错误信息
+func (c *TwoLevelCache) ZRemRangeByLex(key string, start, end string) (int64, error) {
+	return c.l2.ZRemRangeByLex(key, start, end)
+}
+
+// SetBit 设置位图，写远程缓存并删除本地缓存
+//   参数
+//     key:    位图key值
+//     offset: 偏移量
+//     value:  值，0或1
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//   返回
+//     成功时返回原来的值，失败返回错误信息
+func (c *TwoLevelCache) SetBit(key string, offset int64, value int, expire int32) (int64, error) {
+	n, err := c.l2.SetBit(key, offset, value, expire)
+	c.invalidate(key)
+	return n, err
+}
+
+// GetBit 查询位图，直接查远程缓存
+//   参数
+//     key:    位图key值
+//     offset: 偏移量
+//   返回
+//     成功时返回查询结果，失败返回错误信息
+func (c *TwoLevelCache) GetBit(key string, offset int64) (int64, error) {
+	return c.l2.GetBit(key, offset)
+}
+
+// BitCount 统计位图中1的个数，直接查远程缓存
+//   参数
+//     key:      位图key值
+//     bitCount: 统计范围，为nil时统计全部
+//   返回
+//     成功时返回统计结果，失败返回错误信息
+func (c *TwoLevelCache) BitCount(key string, bitCount *cache.BitCount) (int64, error) {
+	return c.l2.BitCount(key, bitCount)
+}
+
+// PFAdd 添加HyperLogLog元素，直接写远程缓存
+//   参数
+//     key:    HyperLogLog key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素
+//   返回
+//     成功时返回1或0，失败返回错误信息
+func (c *TwoLevelCache) PFAdd(key string, expire int32, vals ...interface{}) (int64, error) {
+	return c.l2.PFAdd(key, expire, vals...)
+}
+
+// PFCount 查询HyperLogLog的基数，直接查远程缓存
+//   参数
+//     key: HyperLogLog key值
+//   返回
+//     成功时返回基数，失败返回错误信息
+func (c *TwoLevelCache) PFCount(key string) (int64, error) {
+	return c.l2.PFCount(key)
+}
+
+// LPush 从列表头部插入元素，直接写远程缓存
+//   参数
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (c *TwoLevelCache) LPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return c.l2.LPush(key, expire, vals...)
+}
+
+// RPush 从列表尾部插入元素，直接写远程缓存
+//   参数
+//     key:    列表key值
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   元素
+//   返回
+//     成功时返回列表长度，失败返回错误信息
+func (c *TwoLevelCache) RPush(key string, expire int32, vals ...interface{}) (int64, error) {
+	return c.l2.RPush(key, expire, vals...)
+}
+
+// LPop 从列表头部取出一个元素，直接写远程缓存
+//   参数
+//     key: 列表key值
+//     val: 保存结果地址
+//   返回
+//     错误信息，是否存在
+func (c *TwoLevelCache) LPop(key string, val interface{}) (error, bool) {
+	return c.l2.LPop(key, val)
+}
+
+// BRPop 阻塞地从多个列表尾部取出一个元素，直接写远程缓存
+//   参数
+//     timeout: 阻塞时间，以秒为单位，“0”表示一直阻塞
+//     val:     保存结果地址
+//     keys:    列表key值，按顺序检查
+//   返回
+//     取到元素的列表key值，超时返回空串，错误信息
+func (c *TwoLevelCache) BRPop(timeout int32, val interface{}, keys ...string) (string, error) {
+	return c.l2.BRPop(timeout, val, keys...)
+}
+
+// LRange 查询列表指定区间的元素，直接查远程缓存
+//   参数
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
+//   返回
+//     成功时返回元素列表，失败返回错误信息
+func (c *TwoLevelCache) LRange(key string, start, stop int64) ([]interface{}, error) {
+	return c.l2.LRange(key, start, stop)
+}
+
+// LTrim 只保留列表指定区间的元素，直接写远程缓存
+//   参数
+//     key:   列表key值
+//     start: 开始位置，从0开始，负数表示从尾部开始
+//     stop:  结束位置，-1表示最后一个元素
+//   返回
+//     成功时返回nil，失败返回错误信息
+func (c *TwoLevelCache) LTrim(key string, start, stop int64) error {
+	return c.l2.LTrim(key, start, stop)
+}
+
+// SAdd 向集合添加成员，直接写远程缓存
+//   参数
+//     key:     集合key值
+//     expire:  缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     members: 成员
+//   返回
+//     成功时返回新添加的个数，失败返回错误信息
+func (c *TwoLevelCache) SAdd(key string, expire int32, members ...interface{}) (int64, error) {
+	return c.l2.SAdd(key, expire, members...)
+}
+
+// SRem 删除集合的成员，直接写远程缓存
+//   参数
+//     key:     集合key值
+//     members: 成员
+//   返回
+//     成功时返回删除的个数，失败返回错误信息
+func (c *TwoLevelCache) SRem(key string, members ...interface{}) (int64, error) {
+	return c.l2.SRem(key, members...)
+}
+
+// SIsMember 判断是否为集合的成员，直接查远程缓存
+//   参数
+//     key:    集合key值
+//     member: 成员
+//   返回
+//     是成员返回true，不是返回false，失败返回错误信息
+func (c *TwoLevelCache) SIsMember(key string, member interface{}) (bool, error) {
+	return c.l2.SIsMember(key, member)
+}
+
+// SMembers 查询集合的所有成员，直接查远程缓存
+//   参数
+//     key: 集合key值
+//   返回
+//     成功时返回所有成员，失败返回错误信息
+func (c *TwoLevelCache) SMembers(key string) ([]string, error) {
+	return c.l2.SMembers(key)
+}
+
+// SInter 返回多个集合的交集，直接查远程缓存
+//   参数
+//     keys: 集合key值
+//   返回
+//     成功时返回交集的成员，失败返回错误信息
+func (c *TwoLevelCache) SInter(keys ...string) ([]string, error) {
+	return c.l2.SInter(keys...)
+}
+
+// SUnion 返回多个集合的并集，直接查远程缓存
+//   参数
+//     keys: 集合key值
+//   返回
+//     成功时返回并集的成员，失败返回错误信息
+func (c *TwoLevelCache) SUnion(keys ...string) ([]string, error) {
+	return c.l2.SUnion(keys...)
+}
+
+// XAdd 向流添加消息，直接写远程缓存
+//   参数
+//     key:    流key值
+//     maxLen: 流的最大长度，超过时删除旧消息，为近似值，小于等于0时不限制
+//     expire: 缓存过期时间，以秒为单位：从现在开始的相对时间，“0”表示项目没有到期时间
+//     vals:   消息内容
+//   返回
+//     成功时返回消息id，失败返回错误信息
+func (c *TwoLevelCache) XAdd(key string, maxLen int64, expire int32, vals map[string]interface{}) (string, error) {
+	return c.l2.XAdd(key, maxLen, expire, vals)
+}
+
+// XRead 读取流消息，直接查远程缓存
+//   参数
+//     args: 读取参数，Streams前一半为key值，后一半为对应的开始id(不包含)，$表示只读新消息
+//   返回
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (c *TwoLevelCache) XRead(args *cache.XReadArgs) ([]cache.XStream, error) {
+	return c.l2.XRead(args)
+}
+
+// XGroupCreate 创建消费组，流不存在时自动创建，直接写远程缓存
+//   参数
+//     key:   流key值
+//     group: 消费组
+//     start: 开始读取的消息id，0表示从头开始，为空或$表示只读新消息
+//   返回
+//     成功或消费组已存在时返回nil，失败返回错误信息
+func (c *TwoLevelCache) XGroupCreate(key, group, start string) error {
+	return c.l2.XGroupCreate(key, group, start)
+}
+
+// XReadGroup 按消费组读取流消息，直接写远程缓存
+//   参数
+//     args: 读取参数，Streams前一半为key值，后一半为对应的id，>表示读取未分配给消费者的新消息
+//   返回
+//     成功时返回读取到的消息，没有消息或阻塞超时返回空，失败返回错误信息
+func (c *TwoLevelCache) XReadGroup(args *cache.XReadGroupArgs) ([]cache.XStream, error) {
+	return c.l2.XReadGroup(args)
+}
+
+// XAck 确认消费组的消息，直接写远程缓存
+//   参数
+//     key:   流key值
+//     group: 消费组
+//     ids:   消息id
+//   返回
+//     成功时返回确认的个数，失败返回错误信息
+func (c *TwoLevelCache) XAck(key, group string, ids ...string) (int64, error) {
+	return c.l2.XAck(key, group, ids...)
+}
+
+// Eval 执行lua脚本，直接在远程缓存执行，脚本的KEYS会从本地缓存删除
+//   参数
+//     script: lua脚本
+//     keys:   脚本的KEYS
+//     args:   脚本的ARGV
+//   返回
+//     脚本的返回值，失败返回错误信息
+func (c *TwoLevelCache) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	v, err := c.l2.Eval(script, keys, args...)
+	c.invalidate(keys...)
+	return v, err
+}
+
+// EvalSha 按sha1执行lua脚本，直接在远程缓存执行，脚本的KEYS会从本地缓存删除
+//   参数
+//     sha1: 脚本的sha1
+//     keys: 脚本的KEYS
+//     args: 脚本的ARGV
+//   返回
+//     脚本的返回值，失败返回错误信息
+func (c *TwoLevelCache) EvalSha(sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	v, err := c.l2.EvalSha(sha1, keys, args...)
+	c.invalidate(keys...)
+	return v, err
+}
+
+// ScriptLoad 在远程缓存加载lua脚本
+//   参数
+//     script: lua脚本
+//   返回
+//     脚本的sha1，失败返回错误信息
+func (c *TwoLevelCache) ScriptLoad(script string) (string, error) {
+	return c.l2.ScriptLoad(script)
+}
+
+// Pipeline 返回远程缓存的管道，通过管道的写操作不会更新本地缓存
+//   参数
+//     isTx: 是否为事务
+//   返回
+//     管道对象
+func (c *TwoLevelCache) Pipeline(isTx bool) cache.Pipeliner {
+	return c.l2.Pipeline(isTx)
+}
@@ -0,0 +1,204 @@
+package twolevel
+
+import (
+	"github.com/lixy529/gotools/cache"
+	"github.com/lixy529/gotools/cache/memory"
+	"testing"
+	"time"
+)
+
+var gNotifyConfig = `{"localExpire":"10","channel":"le_cache_invalid","notifyAddr":"127.0.0.1:6379","notifyAuth":"123456","notifyDbNum":"1"}`
+
+// newL2 返回一个作为远程缓存的MemoryCache
+func newL2(t *testing.T) cache.Cache {
+	l2, err := cache.NewCache(cache.AdapterMemory, "")
+	if err != nil {
+		t.Fatalf("NewCache failed. err: %s.", err.Error())
+	}
+	return l2
+}
+
+func TestTwoLevelCache(t *testing.T) {
+	l2 := newL2(t)
+	defer l2.(*memory.MemoryCache).Close()
+
+	adapter := NewTwoLevelCache(l2)
+	err := adapter.Init(`{"maxEntries":"100","localExpire":"10"}`)
+	if err != nil {
+		t.Errorf("TwoLevel Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+
+	// 写穿透
+	err = adapter.Set("k1", "HelloWorld", 20)
+	if err != nil {
+		t.Errorf("TwoLevel Set failed. err: %s.", err.Error())
+		return
+	}
+	var v1 string
+	if err, exist := adapter.L1().Get("k1", &v1); err != nil || !exist || v1 != "HelloWorld" {
+		t.Errorf("TwoLevel Set failed. L1 got %s, expected HelloWorld.", v1)
+	}
+	if err, exist := l2.Get("k1", &v1); err != nil || !exist || v1 != "HelloWorld" {
+		t.Errorf("TwoLevel Set failed. L2 got %s, expected HelloWorld.", v1)
+	}
+
+	// L1未命中时回填
+	type user struct {
+		Uid  int32
+		Name string
+	}
+	l2.Set("k2", user{Uid: 100, Name: "Diego"}, 20)
+	u := user{}
+	err, exist := adapter.Get("k2", &u)
+	if err != nil || !exist || u.Uid != 100 || u.Name != "Diego" {
+		t.Errorf("TwoLevel Get failed. Got %d-%s, expected 100-Diego.", u.Uid, u.Name)
+	}
+	if ok, _ := adapter.L1().IsExist("k2"); !ok {
+		t.Error("TwoLevel Get failed. L1 is not filled.")
+	}
+
+	// L1命中时不查L2
+	l2.Set("k2", user{Uid: 200, Name: "Lily"}, 20)
+	adapter.Get("k2", &u)
+	if u.Uid != 100 {
+		t.Errorf("TwoLevel Get failed. Got %d, expected 100 from L1.", u.Uid)
+	}
+
+	// 删除
+	err = adapter.Del("k2")
+	if err != nil {
+		t.Errorf("TwoLevel Del failed. err: %s.", err.Error())
+	}
+	if ok, _ := adapter.L1().IsExist("k2"); ok {
+		t.Error("TwoLevel Del failed. L1 is not deleted.")
+	}
+	if err, exist = adapter.Get("k2", &u); err != nil || exist {
+		t.Errorf("TwoLevel Del failed. k2 is exist.")
+	}
+
+	// MGet
+	l2.Set("k3", "v3", 20)
+	mList, err := adapter.MGet("k1", "k3", "k4")
+	if err != nil {
+		t.Errorf("TwoLevel MGet failed. err: %s.", err.Error())
+	} else if mList["k1"] != "HelloWorld" || mList["k3"] != "v3" || mList["k4"] != nil {
+		t.Errorf("TwoLevel MGet failed. Got %v.", mList)
+	}
+	if ok, _ := adapter.L1().IsExist("k3"); !ok {
+		t.Error("TwoLevel MGet failed. L1 is not filled.")
+	}
+
+	// HGet
+	adapter.HSet("h1", "f1", "hv1", 20)
+	var hv string
+	err, exist = adapter.HGet("h1", "f1", &hv)
+	if err != nil || !exist || hv != "hv1" {
+		t.Errorf("TwoLevel HGet failed. Got %s, expected hv1.", hv)
+	}
+	adapter.HSet("h1", "f1", "hv2", 20)
+	err, exist = adapter.HGet("h1", "f1", &hv)
+	if err != nil || !exist || hv != "hv2" {
+		t.Errorf("TwoLevel HGet failed. Got %s, expected hv2.", hv)
+	}
+
+	// Incr
+	adapter.Set("n1", 1, 20)
+	adapter.Get("n1", &v1)
+	n, err := adapter.Incr("n1")
+	if err != nil || n != 2 {
+		t.Errorf("TwoLevel Incr failed. Got %d, expected 2.", n)
+	}
+	adapter.Get("n1", &v1)
+	if v1 != "2" {
+		t.Errorf("TwoLevel Incr failed. Got %s, expected 2.", v1)
+	}
+}
+
+func TestTwoLevelExpire(t *testing.T) {
+	l2 := newL2(t)
+	defer l2.(*memory.MemoryCache).Close()
+
+	adapter := NewTwoLevelCache(l2)
+	err := adapter.Init(`{"localExpire":"1"}`)
+	if err != nil {
+		t.Errorf("TwoLevel Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter.Close()
+
+	adapter.Set("k1", "v1", 60)
+	l2.Set("k1", "v2", 60)
+	time.Sleep(1100 * time.Millisecond)
+
+	// 本地缓存过期后重新从L2获取
+	var v string
+	adapter.Get("k1", &v)
+	if v != "v2" {
+		t.Errorf("TwoLevel localExpire failed. Got %s, expected v2.", v)
+	}
+
+	// 回填本地缓存时使用远程缓存剩余的过期时间
+	adapter.localExpire = 60
+	l2.Set("k2", "v1", 1)
+	adapter.Get("k2", &v)
+	if ttl, _ := adapter.L1().TTL("k2"); ttl <= 0 || ttl > time.Second {
+		t.Errorf("TwoLevel fill ttl failed. Got %v, expected at most 1s.", ttl)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if exist, _ := adapter.L1().IsExist("k2"); exist {
+		t.Error("TwoLevel fill ttl failed. L1 not expired with L2.")
+	}
+}
+
+func TestTwoLevelNotify(t *testing.T) {
+	l2 := newL2(t)
+	defer l2.(*memory.MemoryCache).Close()
+
+	adapter1 := NewTwoLevelCache(l2)
+	err := adapter1.Init(gNotifyConfig)
+	if err != nil {
+		t.Errorf("TwoLevel Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter1.Close()
+
+	adapter2 := NewTwoLevelCache(l2)
+	err = adapter2.Init(gNotifyConfig)
+	if err != nil {
+		t.Errorf("TwoLevel Init failed. err: %s.", err.Error())
+		return
+	}
+	defer adapter2.Close()
+
+	var v string
+	adapter1.Set("k1", "v1", 60)
+	adapter2.Get("k1", &v)
+	if v != "v1" {
+		t.Errorf("TwoLevel Get failed. Got %s, expected v1.", v)
+	}
+
+	// adapter1写入后adapter2的本地缓存被删除
+	adapter1.Set("k1", "v2", 60)
+	time.Sleep(100 * time.Millisecond)
+	if ok, _ := adapter2.L1().IsExist("k1"); ok {
+		t.Error("TwoLevel notify failed. adapter2 L1 is not deleted.")
+	}
+	adapter2.Get("k1", &v)
+	if v != "v2" {
+		t.Errorf("TwoLevel Get failed. Got %s, expected v2.", v)
+	}
+
+	// 自己发出的消息不删除本地缓存
+	if ok, _ := adapter1.L1().IsExist("k1"); !ok {
+		t.Error("TwoLevel notify failed. adapter1 L1 is deleted.")
+	}
+
+	// ClearAll
+	adapter1.ClearAll()
+	time.Sleep(100 * time.Millisecond)
+	if ok, _ := adapter2.L1().IsExist("k1"); ok {
+		t.Error("TwoLevel notify failed. adapter2 L1 is not cleared.")
+	}
+}
@@ -4,6 +4,8 @@ go 1.13
 
 require (
 	github.com/bradfitz/gomemcache v0.0.0-20190913173617-a41fca850d0b
-	github.com/go-redis/redis v6.15.6+incompatible
+	github.com/go-redis/redis/v7 v7.4.1
 	github.com/go-sql-driver/mysql v1.5.0
+	github.com/golang/snappy v1.0.0
+	github.com/vmihailenco/msgpack/v4 v4.3.13
 )
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"errors"
+)
+
+const (
+	fastlzHashLog     = 13
+	fastlzMaxCopy     = 32   // literal run length
+	fastlzMaxLen      = 264  // level 1 max match length
+	fastlzMaxDistance = 8192 // level 1 max match distance
+	fastlzL2Distance  = 8191 // level 2 far match base distance
+)
+
+var errFastlzCorrupt = errors.New("fastlz: corrupt input")
+
+// FastlzEncode FastLZ level 1 compression, compatible with fastlz_compress used by php-memcached.
+func FastlzEncode(data []byte) []byte {
+	n := len(data)
+	out := make([]byte, 0, n+n/fastlzMaxCopy+1)
+	if n == 0 {
+		return out
+	}
+
+	// literal runs, at most fastlzMaxCopy bytes each
+	literal := func(from, to int) {
+		for from < to {
+			size := to - from
+			if size > fastlzMaxCopy {
+				size = fastlzMaxCopy
+			}
+			out = append(out, byte(size-1))
+			out = append(out, data[from:from+size]...)
+			from += size
+		}
+	}
+
+	var htab [1 << fastlzHashLog]int
+	for i := range htab {
+		htab[i] = -1
+	}
+
+	anchor, i := 0, 0
+	for i+3 <= n {
+		v := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16
+		h := (v * 2654435761) >> (32 - fastlzHashLog)
+		ref := htab[h]
+		htab[h] = i
+
+		distance := i - ref
+		if ref < 0 || distance > fastlzMaxDistance || data[ref] != data[i] || data[ref+1] != data[i+1] || data[ref+2] != data[i+2] {
+			i++
+			continue
+		}
+
+		size := 3
+		for i+size < n && size < fastlzMaxLen && data[ref+size] == data[i+size] {
+			size++
+		}
+
+		literal(anchor, i)
+		d := distance - 1
+		if m := size - 2; m < 7 {
+			out = append(out, byte(m<<5|d>>8), byte(d))
+		} else {
+			out = append(out, byte(7<<5|d>>8), byte(m-7), byte(d))
+		}
+		i += size
+		anchor = i
+	}
+	literal(anchor, n)
+
+	return out
+}
+
+// FastlzDecode FastLZ uncompression, supports level 1 and level 2.
+func FastlzDecode(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return []byte{}, nil
+	}
+
+	level := data[0]>>5 + 1
+	if level != 1 && level != 2 {
+		return nil, errors.New("fastlz: unknown compression level")
+	}
+
+	out := make([]byte, 0, len(data)*2)
+	ip := 1
+	ctrl := int(data[0] & 31)
+	for {
+		if ctrl >= 32 {
+			size := ctrl>>5 - 1
+			ofs := (ctrl & 31) << 8
+			if ip >= len(data) {
+				return nil, errFastlzCorrupt
+			}
+
+			var ref int
+			if level == 1 {
+				if size == 6 {
+					size += int(data[ip])
+					ip++
+					if ip >= len(data) {
+						return nil, errFastlzCorrupt
+					}
+				}
+				ref = len(out) - ofs - 1 - int(data[ip])
+				ip++
+			} else {
+				if size == 6 {
+					for {
+						code := data[ip]
+						ip++
+						size += int(code)
+						if ip >= len(data) {
+							return nil, errFastlzCorrupt
+						}
+						if code != 255 {
+							break
+						}
+					}
+				}
+				code := int(data[ip])
+				ip++
+				ref = len(out) - ofs - 1 - code
+
+				// match from 16-bit distance
+				if code == 255 && ofs == 31<<8 {
+					if ip+2 > len(data) {
+						return nil, errFastlzCorrupt
+					}
+					ofs = int(data[ip])<<8 | int(data[ip+1])
+					ip += 2
+					ref = len(out) - ofs - fastlzL2Distance
+				}
+			}
+
+			if ref < 0 {
+				return nil, errFastlzCorrupt
+			}
+			for i := 0; i < size+3; i++ {
+				out = append(out, out[ref+i])
+			}
+		} else {
+			ctrl++
+			if ip+ctrl > len(data) {
+				return nil, errFastlzCorrupt
+			}
+			out = append(out, data[ip:ip+ctrl]...)
+			ip += ctrl
+		}
+
+		if ip >= len(data) {
+			break
+		}
+		ctrl = int(data[ip])
+		ip++
+	}
+
+	return out, nil
+}
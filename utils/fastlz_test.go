@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// TestFastlzEncode test FastlzEncode and FastlzDecode function.
+func TestFastlzEncode(t *testing.T) {
+	random := make([]byte, 20000)
+	rand.New(rand.NewSource(1)).Read(random)
+	datas := [][]byte{
+		[]byte("a"),
+		[]byte("Hello World!"),
+		[]byte(strings.Repeat("a", 1000)),
+		[]byte(strings.Repeat("Hello World! ", 10000)),
+		random,
+	}
+
+	for i, data := range datas {
+		b := FastlzEncode(data)
+		if (i == 2 || i == 3) && len(b) > len(data)/10 {
+			t.Errorf("FastlzEncode failed. %d bytes compressed to %d bytes.", len(data), len(b))
+			return
+		}
+
+		s, err := FastlzDecode(b)
+		if err != nil {
+			t.Errorf("FastlzDecode failed. err: %s.", err.Error())
+			return
+		} else if !bytes.Equal(data, s) {
+			t.Errorf("FastlzDecode failed. Got %d bytes, expected %d bytes.", len(s), len(data))
+			return
+		}
+	}
+}
+
+// TestFastlzDecodeLevel2 test FastlzDecode function with level 2 data.
+func TestFastlzDecodeLevel2(t *testing.T) {
+	// literal "abc" and match of 6 bytes at distance 3
+	s, err := FastlzDecode([]byte{0x22, 'a', 'b', 'c', 0x80, 0x02})
+	if err != nil || string(s) != "abcabcabc" {
+		t.Errorf("FastlzDecode failed. Got %s, expected abcabcabc, err: %v.", s, err)
+		return
+	}
+
+	// match from 16-bit distance
+	data := make([]byte, 9024)
+	rand.New(rand.NewSource(2)).Read(data)
+	b := []byte{}
+	for i := 0; i < len(data); i += 32 {
+		b = append(b, 31)
+		b = append(b, data[i:i+32]...)
+	}
+	b[0] |= 1 << 5
+	ofs := len(data) - fastlzL2Distance
+	b = append(b, 1<<5|31, 255, byte(ofs>>8), byte(ofs))
+	s, err = FastlzDecode(b)
+	if err != nil || !bytes.Equal(s[:len(data)], data) || !bytes.Equal(s[len(data):], data[:3]) {
+		t.Errorf("FastlzDecode failed. Got %d bytes, err: %v.", len(s), err)
+		return
+	}
+
+	if _, err = FastlzDecode([]byte{0x22, 'a'}); err == nil {
+		t.Error("FastlzDecode failed. expected corrupt input error.")
+	}
+}